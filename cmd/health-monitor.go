@@ -52,6 +52,7 @@ type monitorOptions struct {
 	BfsPollInterval int      `config:"stats-poll-interval-sec"`
 	ProcMonInterval int      `config:"process-monitor-interval-sec"`
 	OutputPath      string   `config:"output-path"`
+	MetricsAddress  string   `config:"metrics-address"`
 }
 
 var pid string
@@ -0,0 +1,99 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-storage-fuse/v2/common"
+	"github.com/Azure/azure-storage-fuse/v2/component/file_cache"
+
+	"github.com/spf13/cobra"
+)
+
+var journalTmpPath string
+
+var journalCmd = &cobra.Command{
+	Use:        "journal",
+	Short:      "Inspect the write-back journal of a file-cache directory",
+	Long:       "Inspect the write-back journal of a file-cache directory",
+	SuggestFor: []string{"jrnl", "journl"},
+	Example:    "blobfuse2 journal list --tmp-path=/mnt/blobfuse2tmp",
+	Args:       cobra.ExactArgs(0),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cmd.Help()
+	},
+}
+
+var journalListCmd = &cobra.Command{
+	Use:        "list",
+	Short:      "List files with changes not uploaded to storage",
+	Long:       "List files left dirty by a mount which did not stop cleanly, pending upload or quarantined on recovery",
+	SuggestFor: []string{"lst", "ls"},
+	Example:    "blobfuse2 journal list --tmp-path=/mnt/blobfuse2tmp",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tmpPath := common.ExpandPath(strings.TrimSpace(journalTmpPath))
+		if tmpPath == "" {
+			return fmt.Errorf("tmp-path not given")
+		}
+
+		entries, err := file_cache.ListJournal(tmpPath)
+		if err != nil {
+			return fmt.Errorf("failed to read journal in %s [%s]", tmpPath, err.Error())
+		}
+
+		if len(entries) == 0 {
+			fmt.Println("No pending or quarantined files")
+			return nil
+		}
+
+		for i, entry := range entries {
+			fmt.Printf("%d : %s [%s] dirty since %s\n", i+1, entry.Path, entry.State, entry.DirtySince.Format("2006-01-02 15:04:05"))
+			if entry.State == file_cache.JournalStateQuarantined {
+				fmt.Printf("    reason : %s\n    data   : %s\n", entry.Reason, entry.DataPath)
+			}
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(journalCmd)
+	journalCmd.AddCommand(journalListCmd)
+
+	journalCmd.PersistentFlags().StringVar(&journalTmpPath, "tmp-path", "", "File-cache directory whose journal is to be inspected")
+	_ = journalListCmd.MarkPersistentFlagRequired("tmp-path")
+}
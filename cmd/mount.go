@@ -56,6 +56,7 @@ import (
 	"github.com/Azure/azure-storage-fuse/v2/common/config"
 	"github.com/Azure/azure-storage-fuse/v2/common/log"
 	"github.com/Azure/azure-storage-fuse/v2/internal"
+	"github.com/Azure/azure-storage-fuse/v2/internal/stats_manager"
 
 	"github.com/sevlyar/go-daemon"
 	"github.com/spf13/cobra"
@@ -702,6 +703,14 @@ func runPipeline(pipeline *internal.Pipeline, ctx context.Context) error {
 
 	go startMonitor(os.Getpid())
 
+	if options.MonitorOpt.MetricsAddress != "" {
+		err := stats_manager.StartMetricsServer(options.MonitorOpt.MetricsAddress)
+		if err != nil {
+			return fmt.Errorf("unable to start metrics server [%s]", err.Error())
+		}
+		defer stats_manager.StopMetricsServer()
+	}
+
 	err := pipeline.Start(ctx)
 	if err != nil {
 		log.Err("mount: error unable to start pipeline [%s]", err.Error())
@@ -34,7 +34,9 @@
 package common
 
 import (
+	"math"
 	"sync"
+	"syscall"
 	"time"
 )
 
@@ -46,9 +48,36 @@ type LockMapItem struct {
 	downloadTime time.Time
 }
 
+// RangeLock is a POSIX (fcntl) byte range lock held by an owner
+type RangeLock struct {
+	Owner uint64
+	Pid   int32
+	Type  int16 // syscall.F_RDLCK, syscall.F_WRLCK or syscall.F_UNLCK
+	Start int64
+	End   int64 // Inclusive, RangeLockEOF when the lock extends till end of file
+}
+
+// End offset of a range lock which covers everything beyond its start
+const RangeLockEOF int64 = math.MaxInt64
+
+// Interval at which a blocked lock request checks whether it was interrupted
+const lockWaitPollInterval = 100 * time.Millisecond
+
+// Advisory locks (flock and fcntl) held on a file by the processes using the mount
+type advisoryLocks struct {
+	mtx     sync.Mutex
+	users   int            // Calls using this entry, guarded by the mutex of the map
+	changed chan struct{}  // Closed and replaced every time a lock is dropped
+	flocks  map[uint64]int // owner -> syscall.LOCK_SH or syscall.LOCK_EX
+	ranges  []RangeLock
+}
+
 // Map holding locks for all the files
 type LockMap struct {
 	locks sync.Map
+
+	advisoryMtx sync.Mutex
+	advisory    map[string]*advisoryLocks // Only files with advisory locks held or requested have an entry
 }
 
 func NewLockMap() *LockMap {
@@ -117,3 +146,221 @@ func (l *LockMapItem) SetDownloadTime() {
 func (l *LockMapItem) DownloadTime() time.Time {
 	return l.downloadTime
 }
+
+// Advisory lock operations
+// flock and fcntl locks are independent of each other, same as on a local linux file system
+
+// Flock applies a BSD style whole file lock for the given owner.
+// op is syscall.LOCK_SH, syscall.LOCK_EX or syscall.LOCK_UN optionally combined with syscall.LOCK_NB.
+// Without LOCK_NB the call blocks until the lock can be granted or interrupted returns true (EINTR),
+// otherwise EWOULDBLOCK is returned on conflict.
+func (l *LockMap) Flock(name string, owner uint64, op int, interrupted func() bool) error {
+	a := l.acquireAdvisory(name)
+	defer l.releaseAdvisory(name, a)
+
+	lockType := op &^ syscall.LOCK_NB
+	switch lockType {
+	case syscall.LOCK_UN:
+		a.dropFlock(owner)
+		return nil
+	case syscall.LOCK_SH, syscall.LOCK_EX:
+	default:
+		return syscall.EINVAL
+	}
+
+	if a.flocks[owner] == lockType {
+		return nil
+	}
+
+	// Converting an existing lock is not atomic, the old lock is dropped before waiting for the new one
+	a.dropFlock(owner)
+	for a.flockConflict(owner, lockType) {
+		if op&syscall.LOCK_NB != 0 {
+			return syscall.EWOULDBLOCK
+		}
+		if a.wait(interrupted) {
+			return syscall.EINTR
+		}
+	}
+
+	a.flocks[owner] = lockType
+	return nil
+}
+
+// ReleaseFlock drops the flock held by the owner on this file, if any
+func (l *LockMap) ReleaseFlock(name string, owner uint64) {
+	a := l.acquireAdvisory(name)
+	defer l.releaseAdvisory(name, a)
+	a.dropFlock(owner)
+}
+
+// GetLk returns the first range lock held by another owner which conflicts with the given lock, nil if there is none
+func (l *LockMap) GetLk(name string, lk RangeLock) *RangeLock {
+	a := l.acquireAdvisory(name)
+	defer l.releaseAdvisory(name, a)
+
+	conflict := a.rangeConflict(lk)
+	if conflict == nil {
+		return nil
+	}
+
+	result := *conflict
+	return &result
+}
+
+// SetLk acquires, converts or releases (syscall.F_UNLCK) a byte range lock for the owner of the given lock.
+// When wait is set the call blocks until the lock can be granted or interrupted returns true (EINTR),
+// otherwise EAGAIN is returned on conflict.
+func (l *LockMap) SetLk(name string, lk RangeLock, wait bool, interrupted func() bool) error {
+	if lk.Start < 0 || lk.End < lk.Start {
+		return syscall.EINVAL
+	}
+
+	a := l.acquireAdvisory(name)
+	defer l.releaseAdvisory(name, a)
+
+	switch lk.Type {
+	case syscall.F_UNLCK:
+		a.dropRange(lk.Owner, lk.Start, lk.End)
+		return nil
+	case syscall.F_RDLCK, syscall.F_WRLCK:
+	default:
+		return syscall.EINVAL
+	}
+
+	for a.rangeConflict(lk) != nil {
+		if !wait {
+			return syscall.EAGAIN
+		}
+		if a.wait(interrupted) {
+			return syscall.EINTR
+		}
+	}
+
+	// Existing locks of this owner in the range are replaced by the new one
+	a.dropRange(lk.Owner, lk.Start, lk.End)
+	a.ranges = append(a.ranges, lk)
+	return nil
+}
+
+// ReleaseRangeLocks drops all byte range locks held by the owner on this file
+func (l *LockMap) ReleaseRangeLocks(name string, owner uint64) {
+	a := l.acquireAdvisory(name)
+	defer l.releaseAdvisory(name, a)
+	a.dropRange(owner, 0, RangeLockEOF)
+}
+
+// acquireAdvisory returns the locked advisory lock state of the file, creating it if needed
+func (l *LockMap) acquireAdvisory(name string) *advisoryLocks {
+	l.advisoryMtx.Lock()
+	if l.advisory == nil {
+		l.advisory = make(map[string]*advisoryLocks)
+	}
+	a, ok := l.advisory[name]
+	if !ok {
+		a = &advisoryLocks{changed: make(chan struct{}), flocks: make(map[uint64]int)}
+		l.advisory[name] = a
+	}
+	a.users++
+	l.advisoryMtx.Unlock()
+
+	a.mtx.Lock()
+	return a
+}
+
+// releaseAdvisory unlocks the advisory lock state and removes it once no lock is held or awaited on the file
+func (l *LockMap) releaseAdvisory(name string, a *advisoryLocks) {
+	a.mtx.Unlock()
+
+	l.advisoryMtx.Lock()
+	defer l.advisoryMtx.Unlock()
+
+	// Any other call has to take a reference under advisoryMtx first so the state can be checked without its lock
+	a.users--
+	if a.users == 0 && len(a.flocks) == 0 && len(a.ranges) == 0 {
+		delete(l.advisory, name)
+	}
+}
+
+// wait releases the lock state until a lock is dropped, returns true if interrupted before that
+func (a *advisoryLocks) wait(interrupted func() bool) bool {
+	changed := a.changed
+	a.mtx.Unlock()
+	defer a.mtx.Lock()
+
+	ticker := time.NewTicker(lockWaitPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-changed:
+			return false
+		case <-ticker.C:
+			if interrupted != nil && interrupted() {
+				return true
+			}
+		}
+	}
+}
+
+// notify wakes up all the calls waiting for a lock on this file
+func (a *advisoryLocks) notify() {
+	close(a.changed)
+	a.changed = make(chan struct{})
+}
+
+func (a *advisoryLocks) dropFlock(owner uint64) {
+	if _, ok := a.flocks[owner]; ok {
+		delete(a.flocks, owner)
+		a.notify()
+	}
+}
+
+func (a *advisoryLocks) flockConflict(owner uint64, lockType int) bool {
+	for o, t := range a.flocks {
+		if o != owner && (lockType == syscall.LOCK_EX || t == syscall.LOCK_EX) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *advisoryLocks) rangeConflict(lk RangeLock) *RangeLock {
+	for i := range a.ranges {
+		r := &a.ranges[i]
+		if r.Owner == lk.Owner || r.End < lk.Start || lk.End < r.Start {
+			continue
+		}
+		if r.Type == syscall.F_WRLCK || lk.Type == syscall.F_WRLCK {
+			return r
+		}
+	}
+	return nil
+}
+
+// dropRange removes [start, end] from the locks of the owner, splitting locks which only partially overlap
+func (a *advisoryLocks) dropRange(owner uint64, start int64, end int64) {
+	if len(a.ranges) == 0 {
+		return
+	}
+
+	ranges := make([]RangeLock, 0, len(a.ranges))
+	for _, r := range a.ranges {
+		if r.Owner != owner || r.End < start || end < r.Start {
+			ranges = append(ranges, r)
+			continue
+		}
+		if r.Start < start {
+			head := r
+			head.End = start - 1
+			ranges = append(ranges, head)
+		}
+		if end < r.End {
+			tail := r
+			tail.Start = end + 1
+			ranges = append(ranges, tail)
+		}
+	}
+	a.ranges = ranges
+	a.notify()
+}
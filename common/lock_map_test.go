@@ -0,0 +1,205 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package common
+
+import (
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type lockMapTestSuite struct {
+	suite.Suite
+	assert *assert.Assertions
+}
+
+func (suite *lockMapTestSuite) SetupTest() {
+	suite.assert = assert.New(suite.T())
+}
+
+func TestLockMap(t *testing.T) {
+	suite.Run(t, new(lockMapTestSuite))
+}
+
+func (suite *lockMapTestSuite) TestFlockSharedExclusive() {
+	locks := NewLockMap()
+
+	suite.assert.NoError(locks.Flock("a", 1, syscall.LOCK_SH, nil))
+	suite.assert.NoError(locks.Flock("a", 2, syscall.LOCK_SH|syscall.LOCK_NB, nil))
+	suite.assert.Equal(syscall.EWOULDBLOCK, locks.Flock("a", 3, syscall.LOCK_EX|syscall.LOCK_NB, nil))
+
+	// Locks on other files are independent
+	suite.assert.NoError(locks.Flock("b", 3, syscall.LOCK_EX|syscall.LOCK_NB, nil))
+
+	suite.assert.NoError(locks.Flock("a", 1, syscall.LOCK_UN, nil))
+	locks.ReleaseFlock("a", 2)
+	suite.assert.NoError(locks.Flock("a", 3, syscall.LOCK_EX|syscall.LOCK_NB, nil))
+	suite.assert.Equal(syscall.EWOULDBLOCK, locks.Flock("a", 1, syscall.LOCK_SH|syscall.LOCK_NB, nil))
+
+	suite.assert.Equal(syscall.EINVAL, locks.Flock("a", 1, syscall.LOCK_SH|syscall.LOCK_EX, nil))
+}
+
+func (suite *lockMapTestSuite) TestFlockWait() {
+	locks := NewLockMap()
+	suite.assert.NoError(locks.Flock("a", 1, syscall.LOCK_EX, nil))
+
+	done := make(chan error)
+	go func() {
+		done <- locks.Flock("a", 2, syscall.LOCK_EX, nil)
+	}()
+
+	select {
+	case <-done:
+		suite.assert.Fail("lock granted while held by another owner")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	locks.ReleaseFlock("a", 1)
+	suite.assert.NoError(<-done)
+}
+
+func (suite *lockMapTestSuite) TestRangeLockConflicts() {
+	locks := NewLockMap()
+
+	suite.assert.NoError(locks.SetLk("a", RangeLock{Owner: 1, Type: syscall.F_RDLCK, Start: 0, End: 99}, false, nil))
+	suite.assert.NoError(locks.SetLk("a", RangeLock{Owner: 2, Type: syscall.F_RDLCK, Start: 50, End: 149}, false, nil))
+	suite.assert.Equal(syscall.EAGAIN, locks.SetLk("a", RangeLock{Owner: 3, Type: syscall.F_WRLCK, Start: 99, End: 99}, false, nil))
+	suite.assert.NoError(locks.SetLk("a", RangeLock{Owner: 3, Type: syscall.F_WRLCK, Start: 150, End: RangeLockEOF}, false, nil))
+
+	conflict := locks.GetLk("a", RangeLock{Owner: 1, Type: syscall.F_RDLCK, Start: 200, End: 300})
+	suite.assert.NotNil(conflict)
+	suite.assert.Equal(uint64(3), conflict.Owner)
+	suite.assert.Equal(RangeLockEOF, conflict.End)
+
+	// Own locks never conflict
+	suite.assert.Nil(locks.GetLk("a", RangeLock{Owner: 3, Type: syscall.F_WRLCK, Start: 150, End: 500}))
+
+	suite.assert.Equal(syscall.EINVAL, locks.SetLk("a", RangeLock{Owner: 1, Type: syscall.F_RDLCK, Start: 10, End: 5}, false, nil))
+	suite.assert.Equal(syscall.EINVAL, locks.SetLk("a", RangeLock{Owner: 1, Type: 10, Start: 0, End: 5}, false, nil))
+}
+
+func (suite *lockMapTestSuite) TestRangeLockSplitAndConvert() {
+	locks := NewLockMap()
+
+	suite.assert.NoError(locks.SetLk("a", RangeLock{Owner: 1, Type: syscall.F_WRLCK, Start: 0, End: 299}, false, nil))
+
+	// Unlocking the middle leaves the head and tail locked
+	suite.assert.NoError(locks.SetLk("a", RangeLock{Owner: 1, Type: syscall.F_UNLCK, Start: 100, End: 199}, false, nil))
+	suite.assert.Nil(locks.GetLk("a", RangeLock{Owner: 2, Type: syscall.F_WRLCK, Start: 100, End: 199}))
+	suite.assert.NotNil(locks.GetLk("a", RangeLock{Owner: 2, Type: syscall.F_RDLCK, Start: 99, End: 99}))
+	suite.assert.NotNil(locks.GetLk("a", RangeLock{Owner: 2, Type: syscall.F_RDLCK, Start: 200, End: 200}))
+
+	// Downgrading the tail to a read lock lets other readers in
+	suite.assert.NoError(locks.SetLk("a", RangeLock{Owner: 1, Type: syscall.F_RDLCK, Start: 200, End: 299}, false, nil))
+	suite.assert.NoError(locks.SetLk("a", RangeLock{Owner: 2, Type: syscall.F_RDLCK, Start: 250, End: 260}, false, nil))
+	suite.assert.Equal(syscall.EAGAIN, locks.SetLk("a", RangeLock{Owner: 2, Type: syscall.F_RDLCK, Start: 0, End: 0}, false, nil))
+
+	locks.ReleaseRangeLocks("a", 1)
+	suite.assert.NoError(locks.SetLk("a", RangeLock{Owner: 2, Type: syscall.F_WRLCK, Start: 0, End: RangeLockEOF}, false, nil))
+}
+
+func (suite *lockMapTestSuite) TestRangeLockWait() {
+	locks := NewLockMap()
+	suite.assert.NoError(locks.SetLk("a", RangeLock{Owner: 1, Type: syscall.F_WRLCK, Start: 0, End: RangeLockEOF}, false, nil))
+
+	done := make(chan error)
+	go func() {
+		done <- locks.SetLk("a", RangeLock{Owner: 2, Type: syscall.F_RDLCK, Start: 10, End: 20}, true, nil)
+	}()
+
+	select {
+	case <-done:
+		suite.assert.Fail("lock granted while held by another owner")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	suite.assert.NoError(locks.SetLk("a", RangeLock{Owner: 1, Type: syscall.F_UNLCK, Start: 0, End: 15}, false, nil))
+	select {
+	case <-done:
+		suite.assert.Fail("lock granted while still partially held by another owner")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	suite.assert.NoError(locks.SetLk("a", RangeLock{Owner: 1, Type: syscall.F_UNLCK, Start: 0, End: RangeLockEOF}, false, nil))
+	suite.assert.NoError(<-done)
+}
+
+func (suite *lockMapTestSuite) TestLockWaitInterrupted() {
+	locks := NewLockMap()
+	suite.assert.NoError(locks.Flock("a", 1, syscall.LOCK_EX, nil))
+	suite.assert.NoError(locks.SetLk("a", RangeLock{Owner: 1, Type: syscall.F_WRLCK, Start: 0, End: RangeLockEOF}, false, nil))
+
+	var interrupted atomic.Bool
+	done := make(chan error, 2)
+	go func() {
+		done <- locks.Flock("a", 2, syscall.LOCK_SH, interrupted.Load)
+	}()
+	go func() {
+		done <- locks.SetLk("a", RangeLock{Owner: 2, Type: syscall.F_RDLCK, Start: 0, End: 0}, true, interrupted.Load)
+	}()
+
+	select {
+	case <-done:
+		suite.assert.Fail("lock granted while held by another owner")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	interrupted.Store(true)
+	suite.assert.Equal(syscall.EINTR, <-done)
+	suite.assert.Equal(syscall.EINTR, <-done)
+
+	// Interrupted requests leave nothing behind
+	suite.assert.NoError(locks.Flock("a", 3, syscall.LOCK_UN, nil))
+	suite.assert.Equal(syscall.EWOULDBLOCK, locks.Flock("a", 3, syscall.LOCK_SH|syscall.LOCK_NB, nil))
+	suite.assert.NotNil(locks.GetLk("a", RangeLock{Owner: 3, Type: syscall.F_RDLCK, Start: 0, End: 0}))
+}
+
+func (suite *lockMapTestSuite) TestAdvisoryCleanup() {
+	locks := NewLockMap()
+	suite.assert.NoError(locks.Flock("a", 1, syscall.LOCK_EX, nil))
+	suite.assert.NoError(locks.SetLk("b", RangeLock{Owner: 1, Type: syscall.F_WRLCK, Start: 0, End: 10}, false, nil))
+	suite.assert.Nil(locks.GetLk("c", RangeLock{Owner: 1, Type: syscall.F_WRLCK, Start: 0, End: 10}))
+	suite.assert.Len(locks.advisory, 2)
+
+	locks.ReleaseFlock("a", 1)
+	suite.assert.NoError(locks.SetLk("b", RangeLock{Owner: 1, Type: syscall.F_UNLCK, Start: 0, End: 5}, false, nil))
+	suite.assert.Len(locks.advisory, 1)
+
+	locks.ReleaseRangeLocks("b", 1)
+	suite.assert.Empty(locks.advisory)
+}
@@ -505,6 +505,32 @@ func (ac *AttrCache) TruncateFile(options internal.TruncateFileOptions) error {
 	return err
 }
 
+// Fallocate : Mark the file invalid as its size or content may have changed
+func (ac *AttrCache) Fallocate(options internal.FallocateOptions) error {
+	log.Trace("AttrCache::Fallocate : %s", options.Name)
+
+	err := ac.NextComponent().Fallocate(options)
+	if err == nil {
+		ac.cacheLock.RLock()
+		defer ac.cacheLock.RUnlock()
+		ac.invalidatePath(options.Name)
+	}
+	return err
+}
+
+// CopyFileRange : Mark the destination invalid as its size and content may have changed
+func (ac *AttrCache) CopyFileRange(options internal.CopyFileRangeOptions) (int64, error) {
+	log.Trace("AttrCache::CopyFileRange : %s -> %s", options.SrcName, options.DstName)
+
+	copied, err := ac.NextComponent().CopyFileRange(options)
+	if err == nil {
+		ac.cacheLock.RLock()
+		defer ac.cacheLock.RUnlock()
+		ac.invalidatePath(options.DstName)
+	}
+	return copied, err
+}
+
 // CopyFromFile : Mark the file invalid
 func (ac *AttrCache) CopyFromFile(options internal.CopyFromFileOptions) error {
 	log.Trace("AttrCache::CopyFromFile : %s", options.Name)
@@ -615,6 +641,22 @@ func (ac *AttrCache) CreateLink(options internal.CreateLinkOptions) error {
 	return err
 }
 
+// CreateHardLink : Link count of the target changes so invalidate both the paths
+func (ac *AttrCache) CreateHardLink(options internal.CreateHardLinkOptions) error {
+	log.Trace("AttrCache::CreateHardLink : Create hard link %s -> %s", options.Name, options.Target)
+
+	err := ac.NextComponent().CreateHardLink(options)
+
+	if err == nil {
+		ac.cacheLock.RLock()
+		defer ac.cacheLock.RUnlock()
+		ac.invalidatePath(options.Name)
+		ac.invalidatePath(options.Target)
+	}
+
+	return err
+}
+
 // FlushFile : flush file
 func (ac *AttrCache) FlushFile(options internal.FlushFileOptions) error {
 	log.Trace("AttrCache::FlushFile : %s", options.Handle.Path)
@@ -657,6 +699,121 @@ func (ac *AttrCache) Chown(options internal.ChownOptions) error {
 	return err
 }
 
+// getCachedItem : Get the cache entry of a path if it can still serve requests
+// Caller must hold the cache lock
+func (ac *AttrCache) getCachedItem(name string) *attrCacheItem {
+	value, found := ac.cacheMap[internal.TruncateDirName(name)]
+	if found && value.valid() && time.Since(value.cachedAt).Seconds() < float64(ac.cacheTimeout) {
+		return value
+	}
+	return nil
+}
+
+// GetXattr : Serve the extended attribute from cache, otherwise cache the value returned by next component
+func (ac *AttrCache) GetXattr(options internal.GetXattrOptions) ([]byte, error) {
+	log.Trace("AttrCache::GetXattr : %s of %s", options.Attr, options.Name)
+
+	ac.cacheLock.RLock()
+	value := ac.getCachedItem(options.Name)
+	if value != nil {
+		if value.isDeleted() {
+			ac.cacheLock.RUnlock()
+			return nil, syscall.ENOENT
+		}
+
+		data, found := value.getXattr(options.Attr)
+		if found {
+			ac.cacheLock.RUnlock()
+			log.Debug("AttrCache::GetXattr : %s of %s served from cache", options.Attr, options.Name)
+			if data == nil {
+				return nil, syscall.ENODATA
+			}
+			return data, nil
+		}
+	}
+	ac.cacheLock.RUnlock()
+
+	data, err := ac.NextComponent().GetXattr(options)
+	if err == nil || err == syscall.ENODATA {
+		ac.cacheLock.Lock()
+		defer ac.cacheLock.Unlock()
+
+		// Only cache against an existing entry, the entry itself is created by GetAttr
+		value = ac.getCachedItem(options.Name)
+		if value != nil && value.exists() {
+			if err == nil && data == nil {
+				data = []byte{}
+			}
+			value.setXattr(options.Attr, data)
+		}
+	}
+
+	return data, err
+}
+
+// SetXattr : Invalidate the path as the metadata, etag and modified time of the path change
+func (ac *AttrCache) SetXattr(options internal.SetXattrOptions) error {
+	log.Trace("AttrCache::SetXattr : %s of %s", options.Attr, options.Name)
+
+	err := ac.NextComponent().SetXattr(options)
+	if err == nil {
+		ac.cacheLock.RLock()
+		defer ac.cacheLock.RUnlock()
+		ac.invalidatePath(options.Name)
+	}
+
+	return err
+}
+
+// ListXattr : Serve the list of extended attributes from cache, otherwise cache the list returned by next component
+func (ac *AttrCache) ListXattr(options internal.ListXattrOptions) ([]string, error) {
+	log.Trace("AttrCache::ListXattr : %s", options.Name)
+
+	ac.cacheLock.RLock()
+	value := ac.getCachedItem(options.Name)
+	if value != nil {
+		if value.isDeleted() {
+			ac.cacheLock.RUnlock()
+			return nil, syscall.ENOENT
+		}
+
+		names, found := value.getXattrList()
+		if found {
+			ac.cacheLock.RUnlock()
+			log.Debug("AttrCache::ListXattr : %s served from cache", options.Name)
+			return names, nil
+		}
+	}
+	ac.cacheLock.RUnlock()
+
+	names, err := ac.NextComponent().ListXattr(options)
+	if err == nil {
+		ac.cacheLock.Lock()
+		defer ac.cacheLock.Unlock()
+
+		value = ac.getCachedItem(options.Name)
+		if value != nil && value.exists() {
+			value.setXattrList(names)
+		}
+	}
+
+	return names, err
+}
+
+// RemoveXattr : Invalidate the path as the metadata, etag and modified time of the path change
+func (ac *AttrCache) RemoveXattr(options internal.RemoveXattrOptions) error {
+	log.Trace("AttrCache::RemoveXattr : %s of %s", options.Attr, options.Name)
+
+	err := ac.NextComponent().RemoveXattr(options)
+	if err == nil {
+		ac.cacheLock.RLock()
+		defer ac.cacheLock.RUnlock()
+		ac.invalidatePath(options.Name)
+	}
+
+	return err
+}
+
 func (ac *AttrCache) CommitData(options internal.CommitDataOptions) error {
 	log.Trace("AttrCache::CommitData : %s", options.Name)
 	err := ac.NextComponent().CommitData(options)
@@ -824,6 +824,56 @@ func (suite *attrCacheTestSuite) TestTruncateFile() {
 	suite.assert.False(suite.attrCache.cacheMap[path].valid())
 }
 
+// Tests Fallocate
+func (suite *attrCacheTestSuite) TestFallocate() {
+	defer suite.cleanupTest()
+	path := "a"
+
+	options := internal.FallocateOptions{Name: path, Mode: internal.FallocPunchHole | internal.FallocKeepSize, Offset: 0, Length: 1024}
+
+	// Error
+	addPathToCache(suite.assert, suite.attrCache, path, false)
+	suite.mock.EXPECT().Fallocate(options).Return(syscall.ENOTSUP)
+
+	err := suite.attrCache.Fallocate(options)
+	suite.assert.Error(err)
+	suite.assert.True(suite.attrCache.cacheMap[path].valid())
+
+	// Success
+	suite.mock.EXPECT().Fallocate(options).Return(nil)
+
+	err = suite.attrCache.Fallocate(options)
+	suite.assert.NoError(err)
+	assertInvalid(suite, path)
+}
+
+// Tests CopyFileRange
+func (suite *attrCacheTestSuite) TestCopyFileRange() {
+	defer suite.cleanupTest()
+	src := "a"
+	dst := "b"
+
+	options := internal.CopyFileRangeOptions{SrcName: src, DstName: dst, Length: 1024}
+
+	// Error
+	addPathToCache(suite.assert, suite.attrCache, dst, false)
+	suite.mock.EXPECT().CopyFileRange(options).Return(int64(0), syscall.ENOTSUP)
+
+	_, err := suite.attrCache.CopyFileRange(options)
+	suite.assert.Error(err)
+	suite.assert.True(suite.attrCache.cacheMap[dst].valid())
+
+	// Success
+	addPathToCache(suite.assert, suite.attrCache, src, false)
+	suite.mock.EXPECT().CopyFileRange(options).Return(int64(1024), nil)
+
+	copied, err := suite.attrCache.CopyFileRange(options)
+	suite.assert.NoError(err)
+	suite.assert.EqualValues(1024, copied)
+	assertInvalid(suite, dst)
+	suite.assert.True(suite.attrCache.cacheMap[src].valid())
+}
+
 // Tests CopyFromFile
 func (suite *attrCacheTestSuite) TestCopyFromFileError() {
 	defer suite.cleanupTest()
@@ -1205,6 +1255,33 @@ func (suite *attrCacheTestSuite) TestCreateLink() {
 	assertInvalid(suite, path)
 }
 
+// Tests CreateHardLink
+func (suite *attrCacheTestSuite) TestCreateHardLink() {
+	defer suite.cleanupTest()
+	link := "b"
+	path := "a"
+
+	options := internal.CreateHardLinkOptions{Name: link, Target: path}
+
+	// Error
+	addPathToCache(suite.assert, suite.attrCache, path, false)
+	suite.mock.EXPECT().CreateHardLink(options).Return(syscall.EPERM)
+
+	err := suite.attrCache.CreateHardLink(options)
+	suite.assert.Error(err)
+	suite.assert.NotContains(suite.attrCache.cacheMap, link)
+	suite.assert.True(suite.attrCache.cacheMap[path].valid())
+
+	// Success
+	addPathToCache(suite.assert, suite.attrCache, link, false)
+	suite.mock.EXPECT().CreateHardLink(options).Return(nil)
+
+	err = suite.attrCache.CreateHardLink(options)
+	suite.assert.NoError(err)
+	assertInvalid(suite, link)
+	assertInvalid(suite, path)
+}
+
 // Tests Chmod
 func (suite *attrCacheTestSuite) TestChmod() {
 	defer suite.cleanupTest()
@@ -1250,6 +1327,98 @@ func (suite *attrCacheTestSuite) TestChmod() {
 	}
 }
 
+// Tests GetXattr
+func (suite *attrCacheTestSuite) TestGetXattr() {
+	defer suite.cleanupTest()
+	path := "a"
+	options := internal.GetXattrOptions{Name: path, Attr: "user.owner"}
+
+	// Entry Does Not Already Exist, served from next component and not cached
+	suite.mock.EXPECT().GetXattr(options).Return([]byte("team1"), nil)
+	data, err := suite.attrCache.GetXattr(options)
+	suite.assert.NoError(err)
+	suite.assert.Equal("team1", string(data))
+	suite.assert.NotContains(suite.attrCache.cacheMap, path)
+
+	// Entry Already Exists, first call goes to next component and later calls are served from cache
+	addPathToCache(suite.assert, suite.attrCache, path, true)
+	suite.mock.EXPECT().GetXattr(options).Return([]byte("team1"), nil)
+	data, err = suite.attrCache.GetXattr(options)
+	suite.assert.NoError(err)
+	suite.assert.Equal("team1", string(data))
+
+	data, err = suite.attrCache.GetXattr(options)
+	suite.assert.NoError(err)
+	suite.assert.Equal("team1", string(data))
+
+	// Absent attribute is cached as well
+	missing := internal.GetXattrOptions{Name: path, Attr: "user.missing"}
+	suite.mock.EXPECT().GetXattr(missing).Return(nil, syscall.ENODATA)
+	_, err = suite.attrCache.GetXattr(missing)
+	suite.assert.Equal(syscall.ENODATA, err)
+
+	_, err = suite.attrCache.GetXattr(missing)
+	suite.assert.Equal(syscall.ENODATA, err)
+
+	// Deleted entry
+	suite.attrCache.cacheMap[path].markDeleted(time.Now())
+	_, err = suite.attrCache.GetXattr(options)
+	suite.assert.Equal(syscall.ENOENT, err)
+}
+
+// Tests SetXattr
+func (suite *attrCacheTestSuite) TestSetXattr() {
+	defer suite.cleanupTest()
+	path := "a"
+	options := internal.SetXattrOptions{Name: path, Attr: "user.owner", Value: []byte("team2")}
+
+	addPathToCache(suite.assert, suite.attrCache, path, true)
+	suite.attrCache.cacheMap[path].setXattr("user.owner", []byte("team1"))
+
+	// Error
+	suite.mock.EXPECT().SetXattr(options).Return(errors.New("Failed to set xattr"))
+	err := suite.attrCache.SetXattr(options)
+	suite.assert.Error(err)
+	assertUntouched(suite, path)
+
+	// Success
+	suite.mock.EXPECT().SetXattr(options).Return(nil)
+	err = suite.attrCache.SetXattr(options)
+	suite.assert.NoError(err)
+	assertInvalid(suite, path)
+	_, found := suite.attrCache.cacheMap[path].getXattr("user.owner")
+	suite.assert.False(found)
+}
+
+// Tests ListXattr
+func (suite *attrCacheTestSuite) TestListXattr() {
+	defer suite.cleanupTest()
+	path := "a"
+	options := internal.ListXattrOptions{Name: path}
+
+	addPathToCache(suite.assert, suite.attrCache, path, true)
+	suite.mock.EXPECT().ListXattr(options).Return([]string{"user.owner"}, nil)
+	names, err := suite.attrCache.ListXattr(options)
+	suite.assert.NoError(err)
+	suite.assert.Equal([]string{"user.owner"}, names)
+
+	names, err = suite.attrCache.ListXattr(options)
+	suite.assert.NoError(err)
+	suite.assert.Equal([]string{"user.owner"}, names)
+
+	// Removing an attribute invalidates the list
+	remove := internal.RemoveXattrOptions{Name: path, Attr: "user.owner"}
+	suite.mock.EXPECT().RemoveXattr(remove).Return(nil)
+	err = suite.attrCache.RemoveXattr(remove)
+	suite.assert.NoError(err)
+	assertInvalid(suite, path)
+
+	suite.mock.EXPECT().ListXattr(options).Return([]string{}, nil)
+	names, err = suite.attrCache.ListXattr(options)
+	suite.assert.NoError(err)
+	suite.assert.Empty(names)
+}
+
 // Tests Chown
 func (suite *attrCacheTestSuite) TestChown() {
 	defer suite.cleanupTest()
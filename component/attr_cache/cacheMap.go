@@ -53,6 +53,10 @@ type attrCacheItem struct {
 	attr     *internal.ObjAttr
 	cachedAt time.Time
 	attrFlag common.BitMap64
+
+	// Extended attributes read so far, nil value marks an attribute known to be absent
+	xattrs    map[string][]byte
+	xattrList []string
 }
 
 func newAttrCacheItem(attr *internal.ObjAttr, exists bool, cachedAt time.Time) *attrCacheItem {
@@ -83,11 +87,13 @@ func (value *attrCacheItem) markDeleted(deletedTime time.Time) {
 	value.attrFlag.Set(AttrFlagValid)
 	value.cachedAt = deletedTime
 	value.attr = &internal.ObjAttr{}
+	value.invalidateXattrs()
 }
 
 func (value *attrCacheItem) invalidate() {
 	value.attrFlag.Clear(AttrFlagValid)
 	value.attr = &internal.ObjAttr{}
+	value.invalidateXattrs()
 }
 
 func (value *attrCacheItem) getAttr() *internal.ObjAttr {
@@ -109,3 +115,28 @@ func (value *attrCacheItem) setMode(mode os.FileMode) {
 	value.attr.Ctime = time.Now()
 	value.cachedAt = time.Now()
 }
+
+func (value *attrCacheItem) getXattr(name string) ([]byte, bool) {
+	data, found := value.xattrs[name]
+	return data, found
+}
+
+func (value *attrCacheItem) setXattr(name string, data []byte) {
+	if value.xattrs == nil {
+		value.xattrs = make(map[string][]byte)
+	}
+	value.xattrs[name] = data
+}
+
+func (value *attrCacheItem) getXattrList() ([]string, bool) {
+	return value.xattrList, value.xattrList != nil
+}
+
+func (value *attrCacheItem) setXattrList(names []string) {
+	value.xattrList = append(make([]string, 0, len(names)), names...)
+}
+
+func (value *attrCacheItem) invalidateXattrs() {
+	value.xattrs = nil
+	value.xattrList = nil
+}
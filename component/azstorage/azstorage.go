@@ -36,6 +36,9 @@ package azstorage
 import (
 	"context"
 	"fmt"
+	"os"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"syscall"
 	"time"
@@ -58,6 +61,10 @@ type AzStorage struct {
 	stConfig    AzStorageConfig
 	startTime   time.Time
 	listBlocked bool
+	links       sync.Map              // link record path to the inode holding its data
+	linkLocks   common.KeyedMutex     // serializes link count updates of an inode
+	leases      map[string]*blobLease // blobs leased by this mount when lease-locks is enabled
+	leaseLock   sync.Mutex
 }
 
 const compName = "azstorage"
@@ -171,6 +178,12 @@ func (az *AzStorage) configureAndTest(isParent bool) error {
 			log.Err("AzStorage::configureAndTest : Failed to validate credentials [%s]", err.Error())
 			return fmt.Errorf("failed to authenticate %s credentials with error [%s]", az.Name(), err.Error())
 		}
+
+		err = az.checkSnapshotsDir()
+		if err != nil {
+			log.Err("AzStorage::configureAndTest : Invalid snapshots directory [%s]", err.Error())
+			return err
+		}
 	}
 
 	return nil
@@ -192,6 +205,7 @@ func (az *AzStorage) Start(ctx context.Context) error {
 // Stop : Disconnect all running operations here
 func (az *AzStorage) Stop() error {
 	log.Trace("AzStorage::Stop : Stopping component %s", az.Name())
+	az.releaseAllLeases()
 	azStatsCollector.Destroy()
 	return nil
 }
@@ -207,6 +221,10 @@ func (az *AzStorage) ListContainers() ([]string, error) {
 func (az *AzStorage) CreateDir(options internal.CreateDirOptions) error {
 	log.Trace("AzStorage::CreateDir : %s", options.Name)
 
+	if az.isSnapshotPath(options.Name) {
+		return syscall.EROFS
+	}
+
 	err := az.storage.CreateDirectory(internal.TruncateDirName(options.Name))
 
 	if err == nil {
@@ -220,6 +238,10 @@ func (az *AzStorage) CreateDir(options internal.CreateDirOptions) error {
 func (az *AzStorage) DeleteDir(options internal.DeleteDirOptions) error {
 	log.Trace("AzStorage::DeleteDir : %s", options.Name)
 
+	if az.isSnapshotPath(options.Name) {
+		return syscall.EROFS
+	}
+
 	err := az.storage.DeleteDirectory(internal.TruncateDirName(options.Name))
 
 	if err == nil {
@@ -278,7 +300,7 @@ func (az *AzStorage) ReadDir(options internal.ReadDirOptions) ([]*internal.ObjAt
 			log.Err("AzStorage::ReadDir : Failed to read dir [%s]", err)
 			return blobList, err
 		}
-		blobList = append(blobList, new_list...)
+		blobList = append(blobList, az.resolveList(new_list)...)
 		marker = new_marker
 		iteration++
 
@@ -293,6 +315,7 @@ func (az *AzStorage) ReadDir(options internal.ReadDirOptions) ([]*internal.ObjAt
 
 func (az *AzStorage) StreamDir(options internal.StreamDirOptions) ([]*internal.ObjAttr, string, error) {
 	log.Trace("AzStorage::StreamDir : Path %s, offset %d, count %d", options.Name, options.Offset, options.Count)
+	defer azStatsCollector.TimeOperation(streamDir)()
 
 	if az.listBlocked {
 		diff := time.Since(az.startTime)
@@ -305,6 +328,10 @@ func (az *AzStorage) StreamDir(options internal.StreamDirOptions) ([]*internal.O
 		}
 	}
 
+	if rel, ok := az.snapshotPath(options.Name); ok {
+		return az.streamSnapshotDir(options, rel)
+	}
+
 	path := formatListDirName(options.Name)
 
 	new_list, new_marker, err := az.storage.List(path, &options.Token, options.Count)
@@ -314,6 +341,12 @@ func (az *AzStorage) StreamDir(options internal.StreamDirOptions) ([]*internal.O
 	}
 
 	log.Debug("AzStorage::StreamDir : Retrieved %d objects with %s marker for Path %s", len(new_list), options.Token, path)
+	new_list = az.resolveList(new_list)
+
+	// Snapshots directory is listed along with the first page of mount root
+	if path == "" && options.Token == "" && az.stConfig.snapshotsDir != "" {
+		new_list = append([]*internal.ObjAttr{snapshotDirAttr(az.stConfig.snapshotsDir)}, new_list...)
+	}
 
 	if new_marker == nil {
 		new_marker = to.Ptr("")
@@ -346,12 +379,26 @@ func (az *AzStorage) StreamDir(options internal.StreamDirOptions) ([]*internal.O
 
 func (az *AzStorage) RenameDir(options internal.RenameDirOptions) error {
 	log.Trace("AzStorage::RenameDir : %s to %s", options.Src, options.Dst)
+
+	if az.isSnapshotPath(options.Src, options.Dst) {
+		return syscall.EROFS
+	}
+
 	options.Src = internal.TruncateDirName(options.Src)
 	options.Dst = internal.TruncateDirName(options.Dst)
 
 	err := az.storage.RenameDirectory(options.Src, options.Dst)
 
 	if err == nil {
+		// Records moved along with the directory so carry their inodes over to the new paths
+		az.links.Range(func(key, value any) bool {
+			if name := key.(string); strings.HasPrefix(name, options.Src+"/") {
+				az.links.Delete(name)
+				az.links.Store(options.Dst+strings.TrimPrefix(name, options.Src), value)
+			}
+			return true
+		})
+
 		azStatsCollector.PushEvents(renameDir, options.Src, map[string]any{src: options.Src, dest: options.Dst})
 		azStatsCollector.UpdateStats(stats_manager.Increment, renameDir, (int64)(1))
 	}
@@ -361,6 +408,11 @@ func (az *AzStorage) RenameDir(options internal.RenameDirOptions) error {
 // File operations
 func (az *AzStorage) CreateFile(options internal.CreateFileOptions) (*handlemap.Handle, error) {
 	log.Trace("AzStorage::CreateFile : %s", options.Name)
+	defer azStatsCollector.TimeOperation(createFile)()
+
+	if az.isSnapshotPath(options.Name) {
+		return nil, syscall.EROFS
+	}
 
 	// Create a handle object for the file being created
 	// This handle will be added to handlemap by the first component in pipeline
@@ -374,6 +426,7 @@ func (az *AzStorage) CreateFile(options internal.CreateFileOptions) (*handlemap.
 	if err != nil {
 		return nil, err
 	}
+	az.links.Delete(options.Name)
 	handle.Mtime = time.Now()
 
 	azStatsCollector.PushEvents(createFile, options.Name, map[string]any{mode: options.Mode.String()})
@@ -386,8 +439,13 @@ func (az *AzStorage) CreateFile(options internal.CreateFileOptions) (*handlemap.
 
 func (az *AzStorage) OpenFile(options internal.OpenFileOptions) (*handlemap.Handle, error) {
 	log.Trace("AzStorage::OpenFile : %s", options.Name)
+	defer azStatsCollector.TimeOperation(openFile)()
+
+	if az.isSnapshotPath(options.Name) && options.Flags&(os.O_WRONLY|os.O_RDWR|os.O_APPEND|os.O_TRUNC) != 0 {
+		return nil, syscall.EROFS
+	}
 
-	attr, err := az.storage.GetAttr(options.Name)
+	attr, err := az.GetAttr(internal.GetAttrOptions{Name: options.Name})
 	if err != nil {
 		return nil, err
 	}
@@ -411,6 +469,13 @@ func (az *AzStorage) OpenFile(options internal.OpenFileOptions) (*handlemap.Hand
 func (az *AzStorage) ReleaseFile(options internal.ReleaseFileOptions) error {
 	log.Trace("AzStorage::ReleaseFile : %s", options.Handle.Path)
 
+	if az.stConfig.leaseLocks {
+		err := az.unlockFile(az.resolve(options.Handle.Path), options.Handle.ID)
+		if err != nil {
+			log.Err("AzStorage::ReleaseFile : Failed to release lease on %s [%s]", options.Handle.Path, err.Error())
+		}
+	}
+
 	// decrement open file handles count
 	azStatsCollector.UpdateStats(stats_manager.Decrement, openHandles, (int64)(1))
 
@@ -419,10 +484,24 @@ func (az *AzStorage) ReleaseFile(options internal.ReleaseFileOptions) error {
 
 func (az *AzStorage) DeleteFile(options internal.DeleteFileOptions) error {
 	log.Trace("AzStorage::DeleteFile : %s", options.Name)
+	defer azStatsCollector.TimeOperation(deleteFile)()
 
+	if az.isSnapshotPath(options.Name) {
+		return syscall.EROFS
+	}
+
+	inode, isRecord := az.linkInode(options.Name)
 	err := az.storage.DeleteFile(options.Name)
 
 	if err == nil {
+		az.forgetLease(options.Name)
+
+		// Data of a hard link stays alive until the last record referring to it is gone
+		if isRecord {
+			az.links.Delete(options.Name)
+			az.unlinkInode(inode)
+		}
+
 		azStatsCollector.PushEvents(deleteFile, options.Name, nil)
 		azStatsCollector.UpdateStats(stats_manager.Increment, deleteFile, (int64)(1))
 	}
@@ -432,10 +511,41 @@ func (az *AzStorage) DeleteFile(options internal.DeleteFileOptions) error {
 
 func (az *AzStorage) RenameFile(options internal.RenameFileOptions) error {
 	log.Trace("AzStorage::RenameFile : %s to %s", options.Src, options.Dst)
+	defer azStatsCollector.TimeOperation(renameFile)()
+
+	if az.isSnapshotPath(options.Src, options.Dst) {
+		return syscall.EROFS
+	}
+
+	srcInode, srcIsRecord := az.linkInode(options.Src)
+	dstInode, dstIsRecord := az.linkInode(options.Dst)
+	if srcIsRecord && dstIsRecord && srcInode == dstInode {
+		// Both names refer to the same file, rename shall do nothing in this case
+		return nil
+	}
+
+	srcAttr := options.SrcAttr
+	if srcIsRecord {
+		// Attributes given are of the inode and shall not be updated with the ones of the copied record
+		srcAttr = nil
+	}
 
-	err := az.storage.RenameFile(options.Src, options.Dst, options.SrcAttr)
+	err := az.storage.RenameFile(options.Src, options.Dst, srcAttr)
 
 	if err == nil {
+		az.forgetLease(options.Src)
+		az.links.Delete(options.Src)
+		if srcIsRecord {
+			az.links.Store(options.Dst, srcInode)
+		} else {
+			az.links.Delete(options.Dst)
+		}
+
+		// Record at destination got overwritten so drop its reference on the inode
+		if dstIsRecord {
+			az.unlinkInode(dstInode)
+		}
+
 		azStatsCollector.PushEvents(renameFile, options.Src, map[string]any{src: options.Src, dest: options.Dst})
 		azStatsCollector.UpdateStats(stats_manager.Increment, renameFile, (int64)(1))
 	}
@@ -444,11 +554,12 @@ func (az *AzStorage) RenameFile(options internal.RenameFileOptions) error {
 
 func (az *AzStorage) ReadFile(options internal.ReadFileOptions) (data []byte, err error) {
 	//log.Trace("AzStorage::ReadFile : Read %s", h.Path)
-	return az.storage.ReadBuffer(options.Handle.Path, 0, 0)
+	return az.storage.ReadBuffer(az.resolve(options.Handle.Path), 0, 0)
 }
 
 func (az *AzStorage) ReadInBuffer(options *internal.ReadInBufferOptions) (length int, err error) {
 	//log.Trace("AzStorage::ReadInBuffer : Read %s from %d offset", h.Path, offset)
+	defer azStatsCollector.TimeOperation(readInBuffer)()
 
 	var size int64
 	var path string
@@ -478,28 +589,57 @@ func (az *AzStorage) ReadInBuffer(options *internal.ReadInBufferOptions) (length
 	}
 
 	length = int(dataLen)
-	err = az.storage.ReadInBuffer(path, options.Offset, dataLen, options.Data, options.Etag)
+	if rel, ok := az.snapshotPath(path); ok {
+		err = az.readVersion(rel, options.Offset, dataLen, options.Data)
+	} else {
+		path = az.resolve(path)
+		err = az.storage.ReadInBuffer(path, options.Offset, dataLen, options.Data, options.Etag, options.Priority)
+	}
 	if err != nil {
 		log.Err("AzStorage::ReadInBuffer : Failed to read %s [%s]", path, err.Error())
 		length = 0
 	}
+	azStatsCollector.AddBytes(stats_manager.BytesRead, int64(length))
 
 	return
 }
 
 func (az *AzStorage) WriteFile(options *internal.WriteFileOptions) (int, error) {
+	defer azStatsCollector.TimeOperation(writeFile)()
+
+	if az.isSnapshotPath(options.Handle.Path) {
+		return 0, syscall.EROFS
+	}
+
+	if inode := az.resolve(options.Handle.Path); inode != options.Handle.Path {
+		// Storage writes to the path of the handle so redirect the write to the inode
+		handle := handlemap.NewHandle(inode)
+		handle.Size = atomic.LoadInt64(&options.Handle.Size)
+		options = &internal.WriteFileOptions{Handle: handle, Offset: options.Offset, Data: options.Data, Metadata: options.Metadata}
+	}
+
 	err := az.storage.Write(options)
+	if err == nil {
+		azStatsCollector.AddBytes(stats_manager.BytesWritten, int64(len(options.Data)))
+	}
 	return len(options.Data), err
 }
 
 func (az *AzStorage) GetFileBlockOffsets(options internal.GetFileBlockOffsetsOptions) (*common.BlockOffsetList, error) {
-	return az.storage.GetFileBlockOffsets(options.Name)
+	return az.storage.GetFileBlockOffsets(az.resolve(options.Name))
 
 }
 
 func (az *AzStorage) TruncateFile(options internal.TruncateFileOptions) error {
 	log.Trace("AzStorage::TruncateFile : %s to %d bytes", options.Name, options.NewSize)
-	err := az.storage.TruncateFile(options)
+
+	if az.isSnapshotPath(options.Name) {
+		return syscall.EROFS
+	}
+
+	storageOptions := options
+	storageOptions.Name = az.resolve(options.Name)
+	err := az.storage.TruncateFile(storageOptions)
 
 	if err == nil {
 		azStatsCollector.PushEvents(truncateFile, options.Name, map[string]any{size: options.NewSize})
@@ -508,22 +648,116 @@ func (az *AzStorage) TruncateFile(options internal.TruncateFileOptions) error {
 	return err
 }
 
+func (az *AzStorage) Fallocate(options internal.FallocateOptions) error {
+	log.Trace("AzStorage::Fallocate : %s mode %d, offset %d, length %d", options.Name, options.Mode, options.Offset, options.Length)
+
+	if az.isSnapshotPath(options.Name) {
+		return syscall.EROFS
+	}
+
+	switch options.Mode {
+	case 0:
+		// Storage does not reserve space upfront so only growing the file matters here
+		attr, err := az.storage.GetAttr(az.resolve(options.Name))
+		if err != nil {
+			return err
+		}
+
+		newSize := options.Offset + options.Length
+		if newSize <= attr.Size {
+			return nil
+		}
+
+		return az.TruncateFile(internal.TruncateFileOptions{Name: options.Name, OldSize: attr.Size, NewSize: newSize})
+	case internal.FallocKeepSize:
+		return nil
+	default:
+		// Holes can only be punched by a cache component which rewrites the blocks
+		return syscall.ENOTSUP
+	}
+}
+
+func (az *AzStorage) Lseek(options internal.LseekOptions) (int64, error) {
+	log.Trace("AzStorage::Lseek : %s offset %d, whence %d", options.Name, options.Offset, options.Whence)
+
+	attr, err := az.GetAttr(internal.GetAttrOptions{Name: options.Name})
+	if err != nil {
+		return 0, err
+	}
+
+	if options.Offset >= attr.Size {
+		return 0, syscall.ENXIO
+	}
+
+	// Storage does not report sparse regions so the whole blob is treated as data
+	switch options.Whence {
+	case internal.SeekData:
+		return options.Offset, nil
+	case internal.SeekHole:
+		return attr.Size, nil
+	default:
+		return 0, syscall.EINVAL
+	}
+}
+
+func (az *AzStorage) CopyFileRange(options internal.CopyFileRangeOptions) (int64, error) {
+	log.Trace("AzStorage::CopyFileRange : %s [%d] -> %s [%d], length %d", options.SrcName, options.SrcOffset,
+		options.DstName, options.DstOffset, options.Length)
+
+	if az.isSnapshotPath(options.SrcName, options.DstName) {
+		return 0, syscall.EROFS
+	}
+
+	storageOptions := options
+	storageOptions.SrcName = az.resolve(options.SrcName)
+	storageOptions.DstName = az.resolve(options.DstName)
+	copied, err := az.storage.CopyFileRange(storageOptions)
+
+	if err == nil {
+		azStatsCollector.PushEvents(copyFileRange, options.DstName, map[string]any{src: options.SrcName, size: copied})
+		azStatsCollector.UpdateStats(stats_manager.Increment, copyFileRange, (int64)(1))
+	}
+	return copied, err
+}
+
 func (az *AzStorage) CopyToFile(options internal.CopyToFileOptions) error {
 	log.Trace("AzStorage::CopyToFile : Read file %s", options.Name)
-	return az.storage.ReadToFile(options.Name, options.Offset, options.Count, options.File)
+	defer azStatsCollector.TimeOperation(copyToFile)()
+
+	if rel, ok := az.snapshotPath(options.Name); ok {
+		return az.copyVersionToFile(rel, options)
+	}
+	return az.storage.ReadToFile(az.resolve(options.Name), options.Offset, options.Count, options.File)
 }
 
 func (az *AzStorage) CopyFromFile(options internal.CopyFromFileOptions) error {
 	log.Trace("AzStorage::CopyFromFile : Upload file %s", options.Name)
-	return az.storage.WriteFromFile(options.Name, options.Metadata, options.File)
+
+	if az.isSnapshotPath(options.Name) {
+		return syscall.EROFS
+	}
+
+	defer azStatsCollector.TimeOperation(copyFromFile)()
+	name := az.resolve(options.Name)
+	err := az.storage.WriteFromFile(name, options.Metadata, options.File)
+	if err == nil {
+		az.leaseUploaded(name)
+	}
+	return err
 }
 
 // Symlink operations
 func (az *AzStorage) CreateLink(options internal.CreateLinkOptions) error {
 	log.Trace("AzStorage::CreateLink : Create symlink %s -> %s", options.Name, options.Target)
+
+	if az.isSnapshotPath(options.Name) {
+		return syscall.EROFS
+	}
+
 	err := az.storage.CreateLink(options.Name, options.Target)
 
 	if err == nil {
+		az.links.Delete(options.Name)
 		azStatsCollector.PushEvents(createLink, options.Name, map[string]any{target: options.Target})
 		azStatsCollector.UpdateStats(stats_manager.Increment, createLink, (int64)(1))
 	}
@@ -533,7 +767,7 @@ func (az *AzStorage) CreateLink(options internal.CreateLinkOptions) error {
 
 func (az *AzStorage) ReadLink(options internal.ReadLinkOptions) (string, error) {
 	log.Trace("AzStorage::ReadLink : Read symlink %s", options.Name)
-	data, err := az.storage.ReadBuffer(options.Name, 0, options.Size)
+	data, err := az.storage.ReadBuffer(az.resolve(options.Name), 0, options.Size)
 
 	if err != nil {
 		azStatsCollector.PushEvents(readLink, options.Name, nil)
@@ -546,12 +780,27 @@ func (az *AzStorage) ReadLink(options internal.ReadLinkOptions) (string, error)
 // Attribute operations
 func (az *AzStorage) GetAttr(options internal.GetAttrOptions) (attr *internal.ObjAttr, err error) {
 	//log.Trace("AzStorage::GetAttr : Get attributes of file %s", name)
-	return az.storage.GetAttr(options.Name)
+	defer azStatsCollector.TimeOperation(getAttr)()
+
+	if rel, ok := az.snapshotPath(options.Name); ok {
+		return az.getSnapshotAttr(options.Name, rel)
+	}
+
+	attr, err = az.storage.GetAttr(options.Name)
+	if err != nil {
+		return attr, err
+	}
+	return az.resolveAttr(attr), nil
 }
 
 func (az *AzStorage) Chmod(options internal.ChmodOptions) error {
 	log.Trace("AzStorage::Chmod : Change mod of file %s", options.Name)
-	err := az.storage.ChangeMod(options.Name, options.Mode)
+
+	if az.isSnapshotPath(options.Name) {
+		return syscall.EROFS
+	}
+
+	err := az.storage.ChangeMod(az.resolve(options.Name), options.Mode)
 
 	if err == nil {
 		azStatsCollector.PushEvents(chmod, options.Name, map[string]any{mode: options.Mode.String()})
@@ -563,24 +812,309 @@ func (az *AzStorage) Chmod(options internal.ChmodOptions) error {
 
 func (az *AzStorage) Chown(options internal.ChownOptions) error {
 	log.Trace("AzStorage::Chown : Change ownership of file %s to %d-%d", options.Name, options.Owner, options.Group)
-	return az.storage.ChangeOwner(options.Name, options.Owner, options.Group)
+
+	if az.isSnapshotPath(options.Name) {
+		return syscall.EROFS
+	}
+
+	return az.storage.ChangeOwner(az.resolve(options.Name), options.Owner, options.Group)
+}
+
+// Extended attribute operations
+func (az *AzStorage) GetXattr(options internal.GetXattrOptions) ([]byte, error) {
+	log.Trace("AzStorage::GetXattr : Get %s of %s", options.Attr, options.Name)
+
+	if isTagXattr(options.Attr) {
+		return az.getTagXattr(options)
+	}
+
+	key, err := xattrToMetadataKey(options.Attr)
+	if err != nil {
+		return nil, err
+	}
+
+	attr, err := az.storage.GetAttr(az.resolve(options.Name))
+	if err != nil {
+		return nil, err
+	}
+
+	k, found := findMetadataKey(attr.Metadata, key)
+	if !found {
+		return nil, syscall.ENODATA
+	}
+
+	return []byte(*attr.Metadata[k]), nil
+}
+
+func (az *AzStorage) SetXattr(options internal.SetXattrOptions) error {
+	log.Trace("AzStorage::SetXattr : Set %s of %s", options.Attr, options.Name)
+
+	if az.isSnapshotPath(options.Name) {
+		return syscall.EROFS
+	}
+
+	if isTagXattr(options.Attr) {
+		return az.setTagXattr(options)
+	}
+
+	key, err := xattrToMetadataKey(options.Attr)
+	if err != nil {
+		return err
+	}
+
+	if !isValidMetadataValue(options.Value) {
+		log.Err("AzStorage::SetXattr : Value of %s for %s can not be stored in metadata", options.Attr, options.Name)
+		return syscall.EINVAL
+	}
+
+	err = az.updateMetadata(az.resolve(options.Name), func(metadata map[string]*string) error {
+		k, found := findMetadataKey(metadata, key)
+		if found && options.Flags&internal.XattrCreate != 0 {
+			return syscall.EEXIST
+		} else if !found && options.Flags&internal.XattrReplace != 0 {
+			return syscall.ENODATA
+		}
+
+		delete(metadata, k)
+		metadata[key] = to.Ptr(string(options.Value))
+		return nil
+	})
+	if err == nil {
+		azStatsCollector.PushEvents(setXattr, options.Name, map[string]any{xattrName: options.Attr})
+		azStatsCollector.UpdateStats(stats_manager.Increment, setXattr, (int64)(1))
+	}
+
+	return err
+}
+
+func (az *AzStorage) ListXattr(options internal.ListXattrOptions) ([]string, error) {
+	log.Trace("AzStorage::ListXattr : List attributes of %s", options.Name)
+
+	attr, err := az.storage.GetAttr(az.resolve(options.Name))
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0)
+	for k, v := range attr.Metadata {
+		if v == nil || isReservedMetadataKey(k) {
+			continue
+		}
+		// Service does not preserve the case of metadata keys so report them in lower case
+		names = append(names, xattrUserPrefix+strings.ToLower(k))
+	}
+
+	// Attributes carry the tags only for tag filters so read them on demand when the mount works with tags
+	if az.stConfig.blobTags && !attr.IsDir() {
+		tags, err := az.storage.GetTags(az.resolve(options.Name))
+		if err != nil {
+			return nil, err
+		}
+		for k := range tags {
+			names = append(names, xattrTagPrefix+k)
+		}
+	}
+
+	return names, nil
+}
+
+func (az *AzStorage) RemoveXattr(options internal.RemoveXattrOptions) error {
+	log.Trace("AzStorage::RemoveXattr : Remove %s of %s", options.Attr, options.Name)
+
+	if az.isSnapshotPath(options.Name) {
+		return syscall.EROFS
+	}
+
+	if isTagXattr(options.Attr) {
+		return az.removeTagXattr(options)
+	}
+
+	key, err := xattrToMetadataKey(options.Attr)
+	if err != nil {
+		return err
+	}
+
+	err = az.updateMetadata(az.resolve(options.Name), func(metadata map[string]*string) error {
+		k, found := findMetadataKey(metadata, key)
+		if !found {
+			return syscall.ENODATA
+		}
+
+		delete(metadata, k)
+		return nil
+	})
+	if err == nil {
+		azStatsCollector.PushEvents(removeXattr, options.Name, map[string]any{xattrName: options.Attr})
+		azStatsCollector.UpdateStats(stats_manager.Increment, removeXattr, (int64)(1))
+	}
+
+	return err
+}
+
+// updateMetadata : Metadata is replaced as a whole, so read it, apply the update and write it back only if the
+// blob did not change in between. Retried a few times when another update got in first.
+func (az *AzStorage) updateMetadata(name string, update func(metadata map[string]*string) error) error {
+	var err error
+	for i := 0; i < maxMetadataUpdateAttempts; i++ {
+		var attr *internal.ObjAttr
+		attr, err = az.storage.GetAttr(name)
+		if err != nil {
+			return err
+		}
+
+		metadata := make(map[string]*string, len(attr.Metadata))
+		for k, v := range attr.Metadata {
+			metadata[k] = v
+		}
+
+		err = update(metadata)
+		if err != nil {
+			return err
+		}
+
+		err = az.storage.SetMetadata(name, metadata, attr.ETag)
+		if err != syscall.EAGAIN {
+			return err
+		}
+		log.Warn("AzStorage::updateMetadata : %s changed while updating its metadata, retrying", name)
+	}
+
+	log.Err("AzStorage::updateMetadata : Failed to update metadata of %s after %d attempts", name, maxMetadataUpdateAttempts)
+	return err
+}
+
+// getTagXattr : Blob index tags are read on demand so they are available even when listings do not carry them
+func (az *AzStorage) getTagXattr(options internal.GetXattrOptions) ([]byte, error) {
+	key, err := xattrToTagKey(options.Attr)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := az.storage.GetTags(az.resolve(options.Name))
+	if err != nil {
+		return nil, err
+	}
+
+	value, found := tags[key]
+	if !found {
+		return nil, syscall.ENODATA
+	}
+
+	return []byte(value), nil
+}
+
+func (az *AzStorage) setTagXattr(options internal.SetXattrOptions) error {
+	key, err := xattrToTagKey(options.Attr)
+	if err != nil {
+		return err
+	}
+
+	if !isValidTagValue(options.Value) {
+		log.Err("AzStorage::SetXattr : Value of %s for %s can not be stored in a blob tag", options.Attr, options.Name)
+		return syscall.EINVAL
+	}
+
+	err = az.updateTags(az.resolve(options.Name), func(tags map[string]string) error {
+		_, found := tags[key]
+		if found && options.Flags&internal.XattrCreate != 0 {
+			return syscall.EEXIST
+		} else if !found && options.Flags&internal.XattrReplace != 0 {
+			return syscall.ENODATA
+		} else if !found && len(tags) >= maxBlobTags {
+			log.Err("AzStorage::SetXattr : %s already has %d tags", options.Name, maxBlobTags)
+			return syscall.ENOSPC
+		}
+
+		tags[key] = string(options.Value)
+		return nil
+	})
+	if err == nil {
+		azStatsCollector.PushEvents(setXattr, options.Name, map[string]any{xattrName: options.Attr})
+		azStatsCollector.UpdateStats(stats_manager.Increment, setXattr, (int64)(1))
+	}
+
+	return err
+}
+
+func (az *AzStorage) removeTagXattr(options internal.RemoveXattrOptions) error {
+	key, err := xattrToTagKey(options.Attr)
+	if err != nil {
+		return err
+	}
+
+	err = az.updateTags(az.resolve(options.Name), func(tags map[string]string) error {
+		if _, found := tags[key]; !found {
+			return syscall.ENODATA
+		}
+
+		delete(tags, key)
+		return nil
+	})
+	if err == nil {
+		azStatsCollector.PushEvents(removeXattr, options.Name, map[string]any{xattrName: options.Attr})
+		azStatsCollector.UpdateStats(stats_manager.Increment, removeXattr, (int64)(1))
+	}
+
+	return err
+}
+
+// updateTags : Tags are replaced as a whole, so read them, apply the update and write them back only if the
+// tags read are still in place. Retried a few times when another update got in first.
+func (az *AzStorage) updateTags(name string, update func(tags map[string]string) error) error {
+	var err error
+	for i := 0; i < maxMetadataUpdateAttempts; i++ {
+		var current map[string]string
+		current, err = az.storage.GetTags(name)
+		if err != nil {
+			return err
+		}
+
+		tags := make(map[string]string, len(current))
+		for k, v := range current {
+			tags[k] = v
+		}
+
+		err = update(tags)
+		if err != nil {
+			return err
+		}
+
+		err = az.storage.SetTags(name, tags, current)
+		if err != syscall.EAGAIN {
+			return err
+		}
+		log.Warn("AzStorage::updateTags : %s changed while updating its tags, retrying", name)
+	}
+
+	log.Err("AzStorage::updateTags : Failed to update tags of %s after %d attempts", name, maxMetadataUpdateAttempts)
+	return err
 }
 
 func (az *AzStorage) FlushFile(options internal.FlushFileOptions) error {
 	log.Trace("AzStorage::FlushFile : Flush file %s", options.Handle.Path)
-	return az.storage.StageAndCommit(options.Handle.Path, options.Handle.CacheObj.BlockOffsetList)
+	name := az.resolve(options.Handle.Path)
+	err := az.storage.StageAndCommit(name, options.Handle.CacheObj.BlockOffsetList)
+	if err == nil {
+		az.leaseUploaded(name)
+	}
+	return err
 }
 
 func (az *AzStorage) GetCommittedBlockList(name string) (*internal.CommittedBlockList, error) {
-	return az.storage.GetCommittedBlockList(name)
+	return az.storage.GetCommittedBlockList(az.resolve(name))
 }
 
 func (az *AzStorage) StageData(opt internal.StageDataOptions) error {
-	return az.storage.StageBlock(opt.Name, opt.Data, opt.Id)
+	return az.storage.StageBlock(az.resolve(opt.Name), opt.Data, opt.Id, opt.Priority)
 }
 
 func (az *AzStorage) CommitData(opt internal.CommitDataOptions) error {
-	return az.storage.CommitBlocks(opt.Name, opt.List, opt.NewETag)
+	name := az.resolve(opt.Name)
+	err := az.storage.CommitBlocks(name, opt.List, opt.Metadata, opt.NewETag)
+	if err == nil {
+		az.leaseUploaded(name)
+	}
+	return err
 }
 
 // TODO : Below methods are pending to be implemented
@@ -595,6 +1129,7 @@ func (az *AzStorage) CommitData(opt internal.CommitDataOptions) error {
 func NewazstorageComponent() internal.Component {
 	// Init the component with default config
 	az := &AzStorage{
+		leases: make(map[string]*blobLease),
 		stConfig: AzStorageConfig{
 			blockSize:      0,
 			maxConcurrency: 32,
@@ -696,9 +1231,21 @@ func init() {
 	capMbpsRead := config.AddInt64Flag("cap-mbps-read", -1, "Limit the throughput of downloads from your storage account. Value measured in megabits per second. Default is -1 (no limit)")
 	config.BindPFlag(compName+".cap-mbps-read", capMbpsRead)
 
+	capMbpsWrite := config.AddInt64Flag("cap-mbps-write", -1, "Limit the throughput of uploads to your storage account. Value measured in megabits per second. Default is -1 (no limit)")
+	config.BindPFlag(compName+".cap-mbps-write", capMbpsWrite)
+
 	capIOps := config.AddInt64Flag("cap-iops", -1, "Limit the total storage operations per second. Default is -1 (no limit)")
 	config.BindPFlag(compName+".cap-iops", capIOps)
 
+	capIOpsList := config.AddInt64Flag("cap-iops-list", -1, "Limit the list operations per second. Default is -1 (no limit)")
+	config.BindPFlag(compName+".cap-iops-list", capIOpsList)
+
+	capIOpsMetadata := config.AddInt64Flag("cap-iops-metadata", -1, "Limit the metadata operations (properties, delete, rename, etc.) per second. Default is -1 (no limit)")
+	config.BindPFlag(compName+".cap-iops-metadata", capIOpsMetadata)
+
+	capIOpsData := config.AddInt64Flag("cap-iops-data", -1, "Limit the data operations (download, upload and commit of blocks) per second. Default is -1 (no limit)")
+	config.BindPFlag(compName+".cap-iops-data", capIOpsData)
+
 	config.RegisterFlagCompletionFunc("container-name", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		return nil, cobra.ShellCompDirectiveNoFileComp
 	})
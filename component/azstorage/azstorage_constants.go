@@ -40,17 +40,27 @@ const (
 	uploadProgress   = "UploadProgress"
 	bytesTfrd        = "Bytes Transferred"
 
-	createDir    = "CreateDir"
-	deleteDir    = "DeleteDir"
-	streamDir    = "StreamDir"
-	renameDir    = "RenameDir"
-	createFile   = "CreateFile"
-	deleteFile   = "DeleteFile"
-	renameFile   = "RenameFile"
-	truncateFile = "TruncateFile"
-	createLink   = "CreateLink"
-	readLink     = "ReadLink"
-	chmod        = "Chmod"
+	createDir      = "CreateDir"
+	deleteDir      = "DeleteDir"
+	streamDir      = "StreamDir"
+	renameDir      = "RenameDir"
+	createFile     = "CreateFile"
+	deleteFile     = "DeleteFile"
+	renameFile     = "RenameFile"
+	truncateFile   = "TruncateFile"
+	copyFileRange  = "CopyFileRange"
+	createLink     = "CreateLink"
+	createHardLink = "CreateHardLink"
+	readLink       = "ReadLink"
+	chmod          = "Chmod"
+	setXattr       = "SetXattr"
+	removeXattr    = "RemoveXattr"
+	openFile       = "OpenFile"
+	readInBuffer   = "ReadInBuffer"
+	writeFile      = "WriteFile"
+	copyToFile     = "CopyToFile"
+	copyFromFile   = "CopyFromFile"
+	getAttr        = "GetAttr"
 
 	openHandles = "OpenFileHandles"
 	mode        = "Mode"
@@ -59,6 +69,7 @@ const (
 	dest        = "Dest"
 	size        = "Size"
 	target      = "Target"
+	xattrName   = "Attr"
 )
 
 // headers which should be logged and not redacted
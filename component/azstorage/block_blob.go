@@ -45,6 +45,7 @@ import (
 	"reflect"
 	"sort"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -77,6 +78,8 @@ type BlockBlob struct {
 	downloadOptions *blob.DownloadFileOptions
 	listDetails     container.ListBlobsInclude
 	blockLocks      common.KeyedMutex
+	leases          sync.Map // blob name to id of the lease this mount holds on it
+	tagIndex        *tagIndex
 }
 
 // Verify that BlockBlob implements AzConnection interface
@@ -108,8 +111,11 @@ func (bb *BlockBlob) Configure(cfg AzStorageConfig) error {
 		Deleted:     false,
 		Snapshots:   false,
 		Permissions: false, //Added to get permissions, acl, group, owner for HNS accounts
+		Tags:        bb.Config.blobTags,
 	}
 
+	bb.tagIndex = newTagIndex(bb.Config.tagQueries)
+
 	return nil
 }
 
@@ -317,7 +323,8 @@ func (bb *BlockBlob) DeleteFile(name string) (err error) {
 
 	blobClient := bb.Container.NewBlobClient(filepath.Join(bb.Config.prefixPath, name))
 	_, err = blobClient.Delete(context.Background(), &blob.DeleteOptions{
-		DeleteSnapshots: to.Ptr(blob.DeleteSnapshotsOptionTypeInclude),
+		DeleteSnapshots:  to.Ptr(blob.DeleteSnapshotsOptionTypeInclude),
+		AccessConditions: bb.blobAccess(name),
 	})
 	if err != nil {
 		serr := storeBlobErrToErr(err)
@@ -365,7 +372,8 @@ func (bb *BlockBlob) RenameFile(source string, target string, srcAttr *internal.
 	// not specifying source blob metadata, since passing empty metadata headers copies
 	// the source blob metadata to destination blob
 	copyResponse, err := newBlobClient.StartCopyFromURL(context.Background(), blobClient.URL(), &blob.StartCopyFromURLOptions{
-		Tier: bb.Config.defaultTier,
+		Tier:             bb.Config.defaultTier,
+		AccessConditions: bb.blobAccess(target),
 	})
 
 	if err != nil {
@@ -478,7 +486,13 @@ func (bb *BlockBlob) RenameDirectory(source string, target string) error {
 }
 
 func (bb *BlockBlob) getAttrUsingRest(name string) (attr *internal.ObjAttr, err error) {
-	log.Trace("BlockBlob::getAttrUsingRest : name %s", name)
+	return bb.getBlobProperties(name, bb.Config.filterTags)
+}
+
+// getBlobProperties : Get the attributes of a blob from its properties, tags cost another call so they are
+// fetched only on request
+func (bb *BlockBlob) getBlobProperties(name string, fetchTags bool) (attr *internal.ObjAttr, err error) {
+	log.Trace("BlockBlob::getBlobProperties : name %s", name)
 
 	blobClient := bb.Container.NewBlockBlobClient(filepath.Join(bb.Config.prefixPath, name))
 	prop, err := blobClient.GetProperties(context.Background(), &blob.GetPropertiesOptions{
@@ -491,16 +505,32 @@ func (bb *BlockBlob) getAttrUsingRest(name string) (attr *internal.ObjAttr, err
 		case ErrFileNotFound:
 			return attr, syscall.ENOENT
 		case InvalidPermission:
-			log.Err("BlockBlob::getAttrUsingRest : Insufficient permissions for %s [%s]", name, err.Error())
+			log.Err("BlockBlob::getBlobProperties : Insufficient permissions for %s [%s]", name, err.Error())
 			return attr, syscall.EACCES
 		default:
-			log.Err("BlockBlob::getAttrUsingRest : Failed to get blob properties for %s [%s]", name, err.Error())
+			log.Err("BlockBlob::getBlobProperties : Failed to get blob properties for %s [%s]", name, err.Error())
 			return attr, err
 		}
 	}
 
+	attr = propertiesToAttr(name, &prop)
+
+	// Properties only carry the number of tags so fetch them only when asked for
+	if fetchTags && prop.TagCount != nil && *prop.TagCount > 0 {
+		attr.Tags, err = bb.GetTags(name)
+		if err != nil {
+			// Rest of the attributes are valid so do not fail the call
+			log.Err("BlockBlob::getBlobProperties : Failed to get tags of %s [%s]", name, err.Error())
+		}
+	}
+
+	return attr, nil
+}
+
+// propertiesToAttr : Convert the properties of a blob to its attributes
+func propertiesToAttr(name string, prop *blob.GetPropertiesResponse) *internal.ObjAttr {
 	// Since block blob does not support acls, we set mode to 0 and FlagModeDefault to true so the fuse layer can return the default permission.
-	attr = &internal.ObjAttr{
+	attr := &internal.ObjAttr{
 		Path:   name, // We don't need to strip the prefixPath here since we pass the input name
 		Name:   filepath.Base(name),
 		Size:   *prop.ContentLength,
@@ -519,7 +549,7 @@ func (bb *BlockBlob) getAttrUsingRest(name string) (attr *internal.ObjAttr, err
 	// We do not get permissions as part of this getAttr call hence setting the flag to true
 	attr.Flags.Set(internal.PropFlagModeDefault)
 
-	return attr, nil
+	return attr
 }
 
 func (bb *BlockBlob) getAttrUsingList(name string) (attr *internal.ObjAttr, err error) {
@@ -582,11 +612,17 @@ func (bb *BlockBlob) GetAttr(name string) (attr *internal.ObjAttr, err error) {
 		attr, err = bb.getAttrUsingRest(name)
 	}
 
-	if bb.Config.filter != nil && attr != nil {
+	// Directories of a tag filtered mount may exist only as path of the matching blobs
+	if err == syscall.ENOENT && bb.tagIndex != nil && bb.isTagIndexDir(name) {
+		attr, err = bb.createDirAttr(filepath.Join(bb.Config.prefixPath, name)), nil
+	}
+
+	if bb.Config.filter != nil && attr != nil && !attr.IsDir() {
 		if !bb.Config.filter.IsAcceptable(&blobfilter.BlobAttr{
 			Name:  attr.Name,
 			Mtime: attr.Mtime,
 			Size:  attr.Size,
+			Tags:  attr.Tags,
 		}) {
 			log.Debug("BlockBlob::GetAttr : Filtered out %s", name)
 			return nil, syscall.ENOENT
@@ -614,6 +650,24 @@ func (bb *BlockBlob) List(prefix string, marker *string, count int32) ([]*intern
 
 	listPath := bb.getListPath(prefix)
 
+	// Blobs matching a tag only filter are enumerated by the service, so skip listing the whole prefix.
+	// Markers tell which way a listing started, so it carries on the same way even if the index state changed.
+	if marker == nil || *marker == "" {
+		if matches, ok := bb.tagMatches(); ok {
+			return bb.listByTags(matches, listPath, marker, count)
+		}
+	} else if strings.HasPrefix(*marker, tagMarkerPrefix) {
+		matches, ok := bb.tagMatches()
+		if !ok {
+			matches = bb.tagIndex.last()
+		}
+		if matches == nil {
+			log.Err("BlockBlob::List : Tag filter changed while listing %s", prefix)
+			return nil, nil, syscall.EINVAL
+		}
+		return bb.listByTags(matches, listPath, marker, count)
+	}
+
 	// Get a result segment starting with the blob indicated by the current Marker.
 	pager := bb.Container.NewListBlobsHierarchyPager("/", &container.ListBlobsHierarchyOptions{
 		Marker:     marker,
@@ -622,7 +676,7 @@ func (bb *BlockBlob) List(prefix string, marker *string, count int32) ([]*intern
 		Include:    bb.listDetails,
 	})
 
-	listBlob, err := pager.NextPage(context.Background())
+	listBlob, err := pager.NextPage(withIOPriority(context.Background(), internal.IOPriorityListing))
 
 	// Note: Since we make a list call with a prefix, we will not fail here for a non-existent directory.
 	// The blob service will not validate for us whether or not the path exists.
@@ -685,6 +739,7 @@ func (bb *BlockBlob) processBlobItems(blobItems []*container.BlobItem) ([]*inter
 			filterAttr.Name = blobAttr.Name
 			filterAttr.Mtime = blobAttr.Mtime
 			filterAttr.Size = blobAttr.Size
+			filterAttr.Tags = blobAttr.Tags
 
 			if bb.Config.filter.IsAcceptable(&filterAttr) {
 				blobList = append(blobList, blobAttr)
@@ -725,6 +780,10 @@ func (bb *BlockBlob) getBlobAttr(blobInfo *container.BlobItem) (*internal.ObjAtt
 	}
 
 	parseMetadata(attr, blobInfo.Metadata)
+	if bb.listDetails.Tags {
+		attr.Tags = parseBlobTags(blobInfo.BlobTags)
+	}
+
 	if !bb.listDetails.Permissions {
 		// In case of HNS account do not set this flag
 		attr.Flags.Set(internal.PropFlagModeDefault)
@@ -878,6 +937,7 @@ func (bb *BlockBlob) ReadToFile(name string, offset int64, count int64, fi *os.F
 
 		// store total bytes downloaded so far
 		azStatsCollector.UpdateStats(stats_manager.Increment, bytesDownloaded, count)
+		azStatsCollector.AddBytes(stats_manager.BytesRead, count)
 	}
 
 	if bb.Config.validateMD5 {
@@ -951,7 +1011,7 @@ func (bb *BlockBlob) ReadBuffer(name string, offset int64, length int64) ([]byte
 
 // ReadInBuffer : Download specific range from a file to a user provided buffer
 // Specifying "0" len will download the entire blob.
-func (bb *BlockBlob) ReadInBuffer(name string, offset int64, length int64, data []byte, etag *string) error {
+func (bb *BlockBlob) ReadInBuffer(name string, offset int64, length int64, data []byte, etag *string, priority internal.IOPriority) error {
 	// log.Trace("BlockBlob::ReadInBuffer : name %s", name)
 	if etag != nil {
 		*etag = ""
@@ -959,7 +1019,7 @@ func (bb *BlockBlob) ReadInBuffer(name string, offset int64, length int64, data
 
 	blobClient := bb.Container.NewBlobClient(filepath.Join(bb.Config.prefixPath, name))
 
-	ctx, cancel := context.WithTimeout(context.Background(), max_context_timeout*time.Minute)
+	ctx, cancel := context.WithTimeout(withIOPriority(context.Background(), priority), max_context_timeout*time.Minute)
 	defer cancel()
 
 	opt := &blob.DownloadStreamOptions{
@@ -1010,6 +1070,146 @@ func (bb *BlockBlob) ReadInBuffer(name string, offset int64, length int64, data
 	return nil
 }
 
+// ListVersions : Get the versions and snapshots of a blob, named by their version id or snapshot time
+func (bb *BlockBlob) ListVersions(name string) ([]*internal.ObjAttr, error) {
+	log.Trace("BlockBlob::ListVersions : name %s", name)
+
+	blobName := filepath.Join(bb.Config.prefixPath, name)
+	pager := bb.Container.NewListBlobsFlatPager(&container.ListBlobsFlatOptions{
+		Prefix: &blobName,
+		Include: container.ListBlobsInclude{
+			Metadata:  true,
+			Snapshots: true,
+			Versions:  true,
+		},
+	})
+
+	versions := make([]*internal.ObjAttr, 0)
+	for pager.More() {
+		resp, err := pager.NextPage(withIOPriority(context.Background(), internal.IOPriorityListing))
+		if err != nil {
+			log.Err("BlockBlob::ListVersions : Failed to list versions of %s [%s]", name, err.Error())
+			return nil, err
+		}
+
+		for _, blobInfo := range resp.Segment.BlobItems {
+			// Prefix matches the siblings sharing the name as well
+			if *blobInfo.Name != blobName {
+				continue
+			}
+
+			var version string
+			if blobInfo.Snapshot != nil && *blobInfo.Snapshot != "" {
+				version = snapshotVersionPrefix + *blobInfo.Snapshot
+			} else if blobInfo.VersionID != nil && *blobInfo.VersionID != "" {
+				version = *blobInfo.VersionID
+			} else {
+				// Base blob of an account without versioning
+				continue
+			}
+
+			attr, err := bb.getBlobAttr(blobInfo)
+			if err != nil {
+				return nil, err
+			}
+
+			if attr.IsDir() {
+				continue
+			}
+
+			attr.Path = filepath.Join(name, version)
+			attr.Name = version
+			versions = append(versions, attr)
+		}
+	}
+
+	return versions, nil
+}
+
+// versionClient : Client addressing a version or snapshot of a blob
+func (bb *BlockBlob) versionClient(name string, version string) (*blob.Client, error) {
+	blobClient := bb.Container.NewBlobClient(filepath.Join(bb.Config.prefixPath, name))
+	if snapshot, ok := strings.CutPrefix(version, snapshotVersionPrefix); ok {
+		return blobClient.WithSnapshot(snapshot)
+	}
+	return blobClient.WithVersionID(version)
+}
+
+// GetVersionAttr : Get attributes of a version or snapshot of a blob
+func (bb *BlockBlob) GetVersionAttr(name string, version string) (*internal.ObjAttr, error) {
+	log.Trace("BlockBlob::GetVersionAttr : name %s, version %s", name, version)
+
+	blobClient, err := bb.versionClient(name, version)
+	if err != nil {
+		log.Err("BlockBlob::GetVersionAttr : Invalid version %s of %s [%s]", version, name, err.Error())
+		return nil, syscall.EINVAL
+	}
+
+	prop, err := blobClient.GetProperties(context.Background(), &blob.GetPropertiesOptions{
+		CPKInfo: bb.blobCPKOpt,
+	})
+
+	if err != nil {
+		serr := storeBlobErrToErr(err)
+		switch serr {
+		case ErrFileNotFound:
+			return nil, syscall.ENOENT
+		case InvalidPermission:
+			log.Err("BlockBlob::GetVersionAttr : Insufficient permissions for %s [%s]", name, err.Error())
+			return nil, syscall.EACCES
+		default:
+			log.Err("BlockBlob::GetVersionAttr : Failed to get properties of version %s of %s [%s]", version, name, err.Error())
+			return nil, err
+		}
+	}
+
+	attr := propertiesToAttr(filepath.Join(name, version), &prop)
+	if attr.IsDir() {
+		// Versions of directory markers are not exposed
+		return nil, syscall.ENOENT
+	}
+	return attr, nil
+}
+
+// ReadVersion : Download a range of a version or snapshot of a blob to a user provided buffer
+func (bb *BlockBlob) ReadVersion(name string, version string, offset int64, length int64, data []byte) error {
+	log.Trace("BlockBlob::ReadVersion : name %s, version %s, offset %d, length %d", name, version, offset, length)
+
+	blobClient, err := bb.versionClient(name, version)
+	if err != nil {
+		log.Err("BlockBlob::ReadVersion : Invalid version %s of %s [%s]", version, name, err.Error())
+		return syscall.EINVAL
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), max_context_timeout*time.Minute)
+	defer cancel()
+
+	_, err = blobClient.DownloadBuffer(ctx, data[:length], &blob.DownloadBufferOptions{
+		Range: blob.HTTPRange{
+			Offset: offset,
+			Count:  length,
+		},
+		BlockSize:   bb.Config.blockSize,
+		Concurrency: bb.Config.maxConcurrency,
+		CPKInfo:     bb.blobCPKOpt,
+	})
+
+	if err != nil {
+		e := storeBlobErrToErr(err)
+		switch e {
+		case ErrFileNotFound:
+			return syscall.ENOENT
+		case InvalidRange:
+			return syscall.ERANGE
+		}
+
+		log.Err("BlockBlob::ReadVersion : Failed to download version %s of %s [%s]", version, name, err.Error())
+		return err
+	}
+
+	return nil
+}
+
 func (bb *BlockBlob) calculateBlockSize(name string, fileSize int64) (blockSize int64, err error) {
 	// If bufferSize > (BlockBlobMaxStageBlockBytes * BlockBlobMaxBlocks), then error
 	if fileSize > MaxBlobSize {
@@ -1109,7 +1309,8 @@ func (bb *BlockBlob) WriteFromFile(name string, metadata map[string]*string, fi
 			BlobContentType: to.Ptr(getContentType(name)),
 			BlobContentMD5:  md5sum,
 		},
-		CPKInfo: bb.blobCPKOpt,
+		CPKInfo:          bb.blobCPKOpt,
+		AccessConditions: bb.blobAccess(name),
 	}
 	if common.MonitorBfs() && stat.Size() > 0 {
 		uploadOptions.Progress = func(bytesTransferred int64) {
@@ -1138,6 +1339,7 @@ func (bb *BlockBlob) WriteFromFile(name string, metadata map[string]*string, fi
 		// store total bytes uploaded so far
 		if stat.Size() > 0 {
 			azStatsCollector.UpdateStats(stats_manager.Increment, bytesUploaded, stat.Size())
+			azStatsCollector.AddBytes(stats_manager.BytesWritten, stat.Size())
 		}
 	}
 
@@ -1159,7 +1361,8 @@ func (bb *BlockBlob) WriteFromBuffer(name string, metadata map[string]*string, d
 		HTTPHeaders: &blob.HTTPHeaders{
 			BlobContentType: to.Ptr(getContentType(name)),
 		},
-		CPKInfo: bb.blobCPKOpt,
+		CPKInfo:          bb.blobCPKOpt,
+		AccessConditions: bb.blobAccess(name),
 	})
 
 	if err != nil {
@@ -1314,7 +1517,7 @@ func (bb *BlockBlob) createNewBlocksTruncate(blockList *common.BlockOffsetList,
 				// create the new block id for this block otherwise it would corrupt the state of the blob.
 				lastBlock.Id = common.GetBlockID(blockList.BlockIdLength)
 
-				err := bb.ReadInBuffer(options.Name, lastBlock.StartIndex, lastBlock.EndIndex-lastBlock.StartIndex, lastBlock.Data, nil)
+				err := bb.ReadInBuffer(options.Name, lastBlock.StartIndex, lastBlock.EndIndex-lastBlock.StartIndex, lastBlock.Data, nil, internal.IOPriorityForeground)
 				if err != nil {
 					log.Err("BlockBlob::createNewBlocksTruncate : Failed to adjust last block %s [%v]", options.Name, err)
 					return err
@@ -1370,7 +1573,7 @@ func (bb *BlockBlob) removeBlocksTruncate(blockList *common.BlockOffsetList, opt
 		// create the new block id for this block otherwise it would corrupt the state of the blob.
 		blk.Id = common.GetBlockID(blockList.BlockIdLength)
 
-		err := bb.ReadInBuffer(options.Name, blk.StartIndex, blk.EndIndex-blk.StartIndex, blk.Data, nil)
+		err := bb.ReadInBuffer(options.Name, blk.StartIndex, blk.EndIndex-blk.StartIndex, blk.Data, nil, internal.IOPriorityForeground)
 		if err != nil {
 			log.Err("BlockBlob::removeBlocksTruncate : Failed to remove blocks %s [%v]", options.Name, err)
 			return err
@@ -1441,7 +1644,7 @@ func (bb *BlockBlob) TruncateFileWithoutBlocks(options *internal.TruncateFileOpt
 
 	if options.OldSize > 0 {
 		// Read the file
-		err = bb.ReadInBuffer(options.Name, 0, min(options.NewSize, options.OldSize), buf, nil)
+		err = bb.ReadInBuffer(options.Name, 0, min(options.NewSize, options.OldSize), buf, nil, internal.IOPriorityForeground)
 		if err != nil {
 			log.Err("BlockBlob::TruncateFileWithoutBlocks : Failed to read small file %s[%v]", options.Name, err)
 			return err
@@ -1481,7 +1684,7 @@ func (bb *BlockBlob) TruncateFileUsingBlocks(options *internal.TruncateFileOptio
 		buf := make([]byte, options.OldSize)
 
 		// Read the file
-		err = bb.ReadInBuffer(options.Name, 0, options.OldSize, buf, nil)
+		err = bb.ReadInBuffer(options.Name, 0, options.OldSize, buf, nil, internal.IOPriorityForeground)
 		if err != nil {
 			log.Err("BlockBlob::TruncateFileUsingBlocks : Failed to read small file %s[%v]", options.Name, err)
 			return err
@@ -1605,7 +1808,7 @@ func (bb *BlockBlob) Write(options *internal.WriteFileOptions) error {
 		oldDataBuffer := make([]byte, oldDataSize+newBufferSize)
 		if !appendOnly {
 			// fetch the blocks that will be impacted by the new changes so we can overwrite them
-			err = bb.ReadInBuffer(name, fileOffsets.BlockList[index].StartIndex, oldDataSize, oldDataBuffer, nil)
+			err = bb.ReadInBuffer(name, fileOffsets.BlockList[index].StartIndex, oldDataSize, oldDataBuffer, nil, internal.IOPriorityForeground)
 			if err != nil {
 				log.Err("BlockBlob::Write : Failed to read data in buffer %s [%s]", name, err.Error())
 			}
@@ -1631,7 +1834,8 @@ func (bb *BlockBlob) stageAndCommitModifiedBlocks(name string, data []byte, offs
 				blk.Id,
 				streaming.NopCloser(bytes.NewReader(data[blockOffset:(blk.EndIndex-blk.StartIndex)+blockOffset])),
 				&blockblob.StageBlockOptions{
-					CPKInfo: bb.blobCPKOpt,
+					CPKInfo:               bb.blobCPKOpt,
+					LeaseAccessConditions: bb.leaseAccess(name),
 				})
 
 			if err != nil {
@@ -1647,8 +1851,9 @@ func (bb *BlockBlob) stageAndCommitModifiedBlocks(name string, data []byte, offs
 			HTTPHeaders: &blob.HTTPHeaders{
 				BlobContentType: to.Ptr(getContentType(name)),
 			},
-			Tier:    bb.Config.defaultTier,
-			CPKInfo: bb.blobCPKOpt,
+			Tier:             bb.Config.defaultTier,
+			CPKInfo:          bb.blobCPKOpt,
+			AccessConditions: bb.blobAccess(name),
 		})
 
 	if err != nil {
@@ -1699,7 +1904,8 @@ func (bb *BlockBlob) StageAndCommit(name string, bol *common.BlockOffsetList) er
 				blk.Id,
 				streaming.NopCloser(bytes.NewReader(data)),
 				&blockblob.StageBlockOptions{
-					CPKInfo: bb.blobCPKOpt,
+					CPKInfo:               bb.blobCPKOpt,
+					LeaseAccessConditions: bb.leaseAccess(name),
 				})
 			if err != nil {
 				log.Err("BlockBlob::StageAndCommit : Failed to stage to blob %s with ID %s at block %v [%s]", name, blk.Id, blk.StartIndex, err.Error())
@@ -1717,8 +1923,9 @@ func (bb *BlockBlob) StageAndCommit(name string, bol *common.BlockOffsetList) er
 				HTTPHeaders: &blob.HTTPHeaders{
 					BlobContentType: to.Ptr(getContentType(name)),
 				},
-				Tier:    bb.Config.defaultTier,
-				CPKInfo: bb.blobCPKOpt,
+				Tier:             bb.Config.defaultTier,
+				CPKInfo:          bb.blobCPKOpt,
+				AccessConditions: bb.blobAccess(name),
 				// AccessConditions: &blob.AccessConditions{ModifiedAccessConditions: &blob.ModifiedAccessConditions{IfMatch: bol.Etag}},
 			})
 		if err != nil {
@@ -1759,6 +1966,109 @@ func (bb *BlockBlob) ChangeOwner(name string, _ int, _ int) error {
 	return syscall.ENOTSUP
 }
 
+// SetMetadata : Replace metadata of the blob, if etag is given only when the blob has not changed since, else EAGAIN
+func (bb *BlockBlob) SetMetadata(name string, metadata map[string]*string, etag string) error {
+	log.Trace("BlockBlob::SetMetadata : name %s, etag %s", name, etag)
+
+	access := bb.blobAccess(name)
+	if etag != "" {
+		if access == nil {
+			access = &blob.AccessConditions{}
+		}
+		access.ModifiedAccessConditions = &blob.ModifiedAccessConditions{IfMatch: to.Ptr(azcore.ETag(`"` + etag + `"`))}
+	}
+
+	blobClient := bb.Container.NewBlobClient(filepath.Join(bb.Config.prefixPath, name))
+	_, err := blobClient.SetMetadata(context.Background(), metadata, &blob.SetMetadataOptions{
+		CPKInfo:          bb.blobCPKOpt,
+		AccessConditions: access,
+	})
+
+	if err != nil {
+		serr := storeBlobErrToErr(err)
+		switch serr {
+		case ErrFileNotFound:
+			return syscall.ENOENT
+		case ConditionNotMet:
+			log.Info("BlockBlob::SetMetadata : %s changed since etag %s", name, etag)
+			return syscall.EAGAIN
+		case InvalidPermission:
+			log.Err("BlockBlob::SetMetadata : Insufficient permissions for %s [%s]", name, err.Error())
+			return syscall.EACCES
+		default:
+			log.Err("BlockBlob::SetMetadata : Failed to set metadata of %s [%s]", name, err.Error())
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetTags : Get the blob index tags of a blob
+func (bb *BlockBlob) GetTags(name string) (map[string]string, error) {
+	log.Trace("BlockBlob::GetTags : name %s", name)
+
+	blobClient := bb.Container.NewBlobClient(filepath.Join(bb.Config.prefixPath, name))
+	resp, err := blobClient.GetTags(context.Background(), nil)
+
+	if err != nil {
+		serr := storeBlobErrToErr(err)
+		switch serr {
+		case ErrFileNotFound:
+			return nil, syscall.ENOENT
+		case InvalidPermission:
+			log.Err("BlockBlob::GetTags : Insufficient permissions for %s [%s]", name, err.Error())
+			return nil, syscall.EACCES
+		default:
+			log.Err("BlockBlob::GetTags : Failed to get tags of %s [%s]", name, err.Error())
+			return nil, err
+		}
+	}
+
+	tags := parseBlobTags(&resp.BlobTags)
+	if tags == nil {
+		tags = make(map[string]string)
+	}
+	return tags, nil
+}
+
+// SetTags : Replace the blob index tags of a blob, only if it still carries the tags in match
+func (bb *BlockBlob) SetTags(name string, tags map[string]string, match map[string]string) error {
+	log.Trace("BlockBlob::SetTags : name %s", name)
+
+	access := bb.blobAccess(name)
+	if condition := tagCondition(match); condition != "" {
+		if access == nil {
+			access = &blob.AccessConditions{}
+		}
+		access.ModifiedAccessConditions = &blob.ModifiedAccessConditions{IfTags: to.Ptr(condition)}
+	}
+
+	blobClient := bb.Container.NewBlobClient(filepath.Join(bb.Config.prefixPath, name))
+	_, err := blobClient.SetTags(context.Background(), tags, &blob.SetTagsOptions{
+		AccessConditions: access,
+	})
+
+	if err != nil {
+		serr := storeBlobErrToErr(err)
+		switch serr {
+		case ErrFileNotFound:
+			return syscall.ENOENT
+		case ConditionNotMet:
+			log.Info("BlockBlob::SetTags : Tags of %s changed since they were read", name)
+			return syscall.EAGAIN
+		case InvalidPermission:
+			log.Err("BlockBlob::SetTags : Insufficient permissions for %s [%s]", name, err.Error())
+			return syscall.EACCES
+		default:
+			log.Err("BlockBlob::SetTags : Failed to set tags of %s [%s]", name, err.Error())
+			return err
+		}
+	}
+
+	return nil
+}
+
 // GetCommittedBlockList : Get the list of committed blocks
 func (bb *BlockBlob) GetCommittedBlockList(name string) (*internal.CommittedBlockList, error) {
 	blobClient := bb.Container.NewBlockBlobClient(filepath.Join(bb.Config.prefixPath, name))
@@ -1791,10 +2101,10 @@ func (bb *BlockBlob) GetCommittedBlockList(name string) (*internal.CommittedBloc
 }
 
 // StageBlock : stages a block and returns its blockid
-func (bb *BlockBlob) StageBlock(name string, data []byte, id string) error {
+func (bb *BlockBlob) StageBlock(name string, data []byte, id string, priority internal.IOPriority) error {
 	log.Trace("BlockBlob::StageBlock : name %s, ID %v, length %v", name, id, len(data))
 
-	ctx, cancel := context.WithTimeout(context.Background(), max_context_timeout*time.Minute)
+	ctx, cancel := context.WithTimeout(withIOPriority(context.Background(), priority), max_context_timeout*time.Minute)
 	defer cancel()
 
 	blobClient := bb.Container.NewBlockBlobClient(filepath.Join(bb.Config.prefixPath, name))
@@ -1802,7 +2112,8 @@ func (bb *BlockBlob) StageBlock(name string, data []byte, id string) error {
 		id,
 		streaming.NopCloser(bytes.NewReader(data)),
 		&blockblob.StageBlockOptions{
-			CPKInfo: bb.blobCPKOpt,
+			CPKInfo:               bb.blobCPKOpt,
+			LeaseAccessConditions: bb.leaseAccess(name),
 		})
 
 	if err != nil {
@@ -1814,7 +2125,7 @@ func (bb *BlockBlob) StageBlock(name string, data []byte, id string) error {
 }
 
 // CommitBlocks : persists the block list
-func (bb *BlockBlob) CommitBlocks(name string, blockList []string, newEtag *string) error {
+func (bb *BlockBlob) CommitBlocks(name string, blockList []string, metadata map[string]*string, newEtag *string) error {
 	log.Trace("BlockBlob::CommitBlocks : name %s", name)
 
 	ctx, cancel := context.WithTimeout(context.Background(), max_context_timeout*time.Minute)
@@ -1827,8 +2138,10 @@ func (bb *BlockBlob) CommitBlocks(name string, blockList []string, newEtag *stri
 			HTTPHeaders: &blob.HTTPHeaders{
 				BlobContentType: to.Ptr(getContentType(name)),
 			},
-			Tier:    bb.Config.defaultTier,
-			CPKInfo: bb.blobCPKOpt,
+			Tier:             bb.Config.defaultTier,
+			Metadata:         metadata,
+			CPKInfo:          bb.blobCPKOpt,
+			AccessConditions: bb.blobAccess(name),
 		})
 
 	if err != nil {
@@ -1846,9 +2159,21 @@ func (bb *BlockBlob) CommitBlocks(name string, blockList []string, newEtag *stri
 func (bb *BlockBlob) SetFilter(filter string) error {
 	if filter == "" {
 		bb.Config.filter = nil
+		bb.Config.filterTags = false
+		bb.Config.tagQueries = nil
+		bb.tagIndex = nil
 		return nil
 	}
 
+	bb.Config.filterTags = filterUsesTags(filter)
+	if bb.Config.filterTags {
+		bb.Config.blobTags = true
+		bb.listDetails.Tags = true
+	}
+
+	bb.Config.tagQueries, _ = tagQueries(filter)
+	bb.tagIndex = newTagIndex(bb.Config.tagQueries)
+
 	bb.Config.filter = &blobfilter.BlobFilter{}
 	return bb.Config.filter.Configure(filter)
 }
@@ -2307,6 +2307,294 @@ func (s *blockBlobTestSuite) TestChownIgnore() {
 	s.assert.NoError(err)
 }
 
+func (s *blockBlobTestSuite) TestXattr() {
+	defer s.cleanupTest()
+	// Setup
+	name := generateFileName()
+	_, err := s.az.CreateFile(internal.CreateFileOptions{Name: name})
+	s.assert.NoError(err)
+
+	err = s.az.SetXattr(internal.SetXattrOptions{Name: name, Attr: "user.owner", Value: []byte("team1")})
+	s.assert.NoError(err)
+
+	value, err := s.az.GetXattr(internal.GetXattrOptions{Name: name, Attr: "user.owner"})
+	s.assert.NoError(err)
+	s.assert.Equal("team1", string(value))
+
+	err = s.az.SetXattr(internal.SetXattrOptions{Name: name, Attr: "user.owner", Value: []byte("team2"), Flags: internal.XattrCreate})
+	s.assert.EqualValues(syscall.EEXIST, err)
+
+	names, err := s.az.ListXattr(internal.ListXattrOptions{Name: name})
+	s.assert.NoError(err)
+	s.assert.Contains(names, "user.owner")
+
+	err = s.az.RemoveXattr(internal.RemoveXattrOptions{Name: name, Attr: "user.owner"})
+	s.assert.NoError(err)
+
+	_, err = s.az.GetXattr(internal.GetXattrOptions{Name: name, Attr: "user.owner"})
+	s.assert.EqualValues(syscall.ENODATA, err)
+}
+
+func (s *blockBlobTestSuite) TestXattrUnsupported() {
+	defer s.cleanupTest()
+	// Setup
+	name := generateFileName()
+	_, err := s.az.CreateFile(internal.CreateFileOptions{Name: name})
+	s.assert.NoError(err)
+
+	err = s.az.SetXattr(internal.SetXattrOptions{Name: name, Attr: "security.selinux", Value: []byte("abc")})
+	s.assert.EqualValues(syscall.ENOTSUP, err)
+
+	err = s.az.SetXattr(internal.SetXattrOptions{Name: name, Attr: "user.hdi_isfolder", Value: []byte("true")})
+	s.assert.EqualValues(syscall.EPERM, err)
+
+	err = s.az.RemoveXattr(internal.RemoveXattrOptions{Name: name, Attr: "user.missing"})
+	s.assert.EqualValues(syscall.ENODATA, err)
+}
+
+func (s *blockBlobTestSuite) TestHardLink() {
+	defer s.cleanupTest()
+	// Setup
+	name := generateFileName()
+	h, err := s.az.CreateFile(internal.CreateFileOptions{Name: name})
+	s.assert.NoError(err)
+	data := []byte("test data")
+	_, err = s.az.WriteFile(&internal.WriteFileOptions{Handle: h, Offset: 0, Data: data})
+	s.assert.NoError(err)
+
+	link := name + "_link"
+	err = s.az.CreateHardLink(internal.CreateHardLinkOptions{Name: link, Target: name})
+	s.assert.NoError(err)
+
+	err = s.az.CreateHardLink(internal.CreateHardLinkOptions{Name: link, Target: name})
+	s.assert.EqualValues(syscall.EEXIST, err)
+
+	// Both names refer to the same data
+	for _, path := range []string{name, link} {
+		attr, err := s.az.GetAttr(internal.GetAttrOptions{Name: path})
+		s.assert.NoError(err)
+		s.assert.EqualValues(len(data), attr.Size)
+		s.assert.EqualValues(2, attr.Nlink)
+		s.assert.Equal(path, attr.Path)
+	}
+
+	// Writes through one name are visible through the other
+	h, err = s.az.OpenFile(internal.OpenFileOptions{Name: link})
+	s.assert.NoError(err)
+	_, err = s.az.WriteFile(&internal.WriteFileOptions{Handle: h, Offset: 0, Data: []byte("TEST")})
+	s.assert.NoError(err)
+	h, err = s.az.OpenFile(internal.OpenFileOptions{Name: name})
+	s.assert.NoError(err)
+	output, err := s.az.ReadFile(internal.ReadFileOptions{Handle: h})
+	s.assert.NoError(err)
+	s.assert.Equal("TEST data", string(output))
+
+	// Inode store is not visible in listing
+	entries, err := s.az.ReadDir(internal.ReadDirOptions{Name: ""})
+	s.assert.NoError(err)
+	for _, entry := range entries {
+		s.assert.NotEqual(hardlinkDir, entry.Path)
+	}
+
+	// Data stays alive until the last name is gone
+	err = s.az.DeleteFile(internal.DeleteFileOptions{Name: name})
+	s.assert.NoError(err)
+	attr, err := s.az.GetAttr(internal.GetAttrOptions{Name: link})
+	s.assert.NoError(err)
+	s.assert.EqualValues(1, attr.Nlink)
+	s.assert.EqualValues(len(data), attr.Size)
+
+	inode := s.az.resolve(link)
+	s.assert.NotEqual(link, inode)
+	err = s.az.DeleteFile(internal.DeleteFileOptions{Name: link})
+	s.assert.NoError(err)
+	_, err = s.az.storage.GetAttr(inode)
+	s.assert.EqualValues(syscall.ENOENT, err)
+}
+
+func (s *blockBlobTestSuite) TestHardLinkRename() {
+	defer s.cleanupTest()
+	// Setup
+	name := generateFileName()
+	_, err := s.az.CreateFile(internal.CreateFileOptions{Name: name})
+	s.assert.NoError(err)
+	link := name + "_link"
+	err = s.az.CreateHardLink(internal.CreateHardLinkOptions{Name: link, Target: name})
+	s.assert.NoError(err)
+
+	// Rename between names of the same file does nothing
+	err = s.az.RenameFile(internal.RenameFileOptions{Src: name, Dst: link})
+	s.assert.NoError(err)
+	_, err = s.az.GetAttr(internal.GetAttrOptions{Name: name})
+	s.assert.NoError(err)
+
+	moved := name + "_moved"
+	err = s.az.RenameFile(internal.RenameFileOptions{Src: link, Dst: moved})
+	s.assert.NoError(err)
+	attr, err := s.az.GetAttr(internal.GetAttrOptions{Name: moved})
+	s.assert.NoError(err)
+	s.assert.EqualValues(2, attr.Nlink)
+
+	// Overwriting a name drops its reference
+	other := generateFileName()
+	_, err = s.az.CreateFile(internal.CreateFileOptions{Name: other})
+	s.assert.NoError(err)
+	err = s.az.RenameFile(internal.RenameFileOptions{Src: other, Dst: name})
+	s.assert.NoError(err)
+	attr, err = s.az.GetAttr(internal.GetAttrOptions{Name: moved})
+	s.assert.NoError(err)
+	s.assert.EqualValues(1, attr.Nlink)
+}
+
+func (s *blockBlobTestSuite) TestCopyFileRangeWholeFile() {
+	defer s.cleanupTest()
+	// Setup
+	src := generateFileName()
+	h, _ := s.az.CreateFile(internal.CreateFileOptions{Name: src})
+	testData := "test data for copy"
+	_, err := s.az.WriteFile(&internal.WriteFileOptions{Handle: h, Offset: 0, Data: []byte(testData)})
+	s.assert.NoError(err)
+	dst := generateFileName()
+	_, err = s.az.CreateFile(internal.CreateFileOptions{Name: dst})
+	s.assert.NoError(err)
+
+	copied, err := s.az.CopyFileRange(internal.CopyFileRangeOptions{SrcName: src, DstName: dst, Length: 1024})
+	s.assert.NoError(err)
+	s.assert.EqualValues(len(testData), copied)
+
+	output := make([]byte, len(testData))
+	err = s.az.storage.ReadInBuffer(dst, 0, int64(len(testData)), output, nil, internal.IOPriorityForeground)
+	s.assert.NoError(err)
+	s.assert.Equal(testData, string(output))
+}
+
+func (s *blockBlobTestSuite) TestCopyFileRangePartial() {
+	defer s.cleanupTest()
+	// Setup
+	src := generateFileName()
+	h, _ := s.az.CreateFile(internal.CreateFileOptions{Name: src})
+	_, err := s.az.WriteFile(&internal.WriteFileOptions{Handle: h, Offset: 0, Data: []byte("0123456789")})
+	s.assert.NoError(err)
+	dst := generateFileName()
+	h, _ = s.az.CreateFile(internal.CreateFileOptions{Name: dst})
+	_, err = s.az.WriteFile(&internal.WriteFileOptions{Handle: h, Offset: 0, Data: []byte("abcdefghij")})
+	s.assert.NoError(err)
+
+	copied, err := s.az.CopyFileRange(internal.CopyFileRangeOptions{SrcName: src, SrcOffset: 2, DstName: dst, DstOffset: 8, Length: 4, BlockSize: 4})
+	s.assert.NoError(err)
+	s.assert.EqualValues(4, copied)
+
+	attr, err := s.az.GetAttr(internal.GetAttrOptions{Name: dst})
+	s.assert.NoError(err)
+	s.assert.EqualValues(12, attr.Size)
+
+	output := make([]byte, 12)
+	err = s.az.storage.ReadInBuffer(dst, 0, 12, output, nil, internal.IOPriorityForeground)
+	s.assert.NoError(err)
+	s.assert.Equal("abcdefgh2345", string(output))
+
+	blockList, err := s.az.GetCommittedBlockList(dst)
+	s.assert.NoError(err)
+	s.assert.Len(*blockList, 3)
+}
+
+func (s *blockBlobTestSuite) TestLockFileLease() {
+	defer s.cleanupTest()
+	// Setup
+	s.tearDownTestHelper(false) // Don't delete the generated container.
+	config := fmt.Sprintf("azstorage:\n  account-name: %s\n  endpoint: https://%s.blob.core.windows.net/\n  type: block\n  account-key: %s\n  mode: key\n  container: %s\n  lease-locks: true\n  lease-duration-sec: 15\n",
+		storageTestConfigurationParameters.BlockAccount, storageTestConfigurationParameters.BlockAccount, storageTestConfigurationParameters.BlockKey, s.container)
+	s.setupTestHelper(config, s.container, true)
+
+	// A second instance on the same container stands in for another node
+	other, _ := newTestAzStorage(config)
+	defer func() { _ = other.Stop() }()
+
+	name := generateFileName()
+	h, err := s.az.CreateFile(internal.CreateFileOptions{Name: name})
+	s.assert.NoError(err)
+	h.ID = 1
+
+	err = s.az.LockFile(internal.LockFileOptions{Handle: h, Name: name})
+	s.assert.NoError(err)
+
+	otherHandle := handlemap.NewHandle(name)
+	otherHandle.ID = 2
+	err = other.LockFile(internal.LockFileOptions{Handle: otherHandle, Name: name})
+	s.assert.Equal(syscall.EWOULDBLOCK, err)
+
+	// Writes of the lease holder go through while the other node can not modify the blob
+	_, err = s.az.WriteFile(&internal.WriteFileOptions{Handle: h, Offset: 0, Data: []byte("test data")})
+	s.assert.NoError(err)
+	err = other.DeleteFile(internal.DeleteFileOptions{Name: name})
+	s.assert.Error(err)
+
+	// Lease is renewed in background beyond its duration
+	time.Sleep(20 * time.Second)
+	err = other.LockFile(internal.LockFileOptions{Handle: otherHandle, Name: name})
+	s.assert.Equal(syscall.EWOULDBLOCK, err)
+
+	err = s.az.ReleaseFile(internal.ReleaseFileOptions{Handle: h})
+	s.assert.NoError(err)
+	err = other.LockFile(internal.LockFileOptions{Handle: otherHandle, Name: name})
+	s.assert.NoError(err)
+	err = other.UnlockFile(internal.UnlockFileOptions{Handle: otherHandle, Name: name})
+	s.assert.NoError(err)
+}
+
+func (s *blockBlobTestSuite) TestLockFileLeaseAfterUpload() {
+	defer s.cleanupTest()
+	// Setup
+	s.tearDownTestHelper(false) // Don't delete the generated container.
+	config := fmt.Sprintf("azstorage:\n  account-name: %s\n  endpoint: https://%s.blob.core.windows.net/\n  type: block\n  account-key: %s\n  mode: key\n  container: %s\n  lease-locks: true\n  lease-duration-sec: 15\n",
+		storageTestConfigurationParameters.BlockAccount, storageTestConfigurationParameters.BlockAccount, storageTestConfigurationParameters.BlockKey, s.container)
+	s.setupTestHelper(config, s.container, true)
+
+	other, _ := newTestAzStorage(config)
+	defer func() { _ = other.Stop() }()
+
+	// File created locally is not in storage yet, locking it succeeds and the lease is taken on upload
+	name := generateFileName()
+	h := handlemap.NewHandle(name)
+	h.ID = 1
+	err := s.az.LockFile(internal.LockFileOptions{Handle: h, Name: name})
+	s.assert.NoError(err)
+
+	homeDir, _ := os.UserHomeDir()
+	f, _ := os.CreateTemp(homeDir, name+".tmp")
+	defer os.Remove(f.Name())
+	_, err = f.Write([]byte("test data"))
+	s.assert.NoError(err)
+	err = s.az.CopyFromFile(internal.CopyFromFileOptions{Name: name, File: f})
+	s.assert.NoError(err)
+
+	otherHandle := handlemap.NewHandle(name)
+	otherHandle.ID = 2
+	err = other.LockFile(internal.LockFileOptions{Handle: otherHandle, Name: name})
+	s.assert.Equal(syscall.EWOULDBLOCK, err)
+
+	err = s.az.UnlockFile(internal.UnlockFileOptions{Handle: h, Name: name})
+	s.assert.NoError(err)
+	err = other.LockFile(internal.LockFileOptions{Handle: otherHandle, Name: name})
+	s.assert.NoError(err)
+	err = other.UnlockFile(internal.UnlockFileOptions{Handle: otherHandle, Name: name})
+	s.assert.NoError(err)
+}
+
+func (s *blockBlobTestSuite) TestHardLinkDirectory() {
+	defer s.cleanupTest()
+	// Setup
+	name := generateDirectoryName()
+	err := s.az.CreateDir(internal.CreateDirOptions{Name: name})
+	s.assert.NoError(err)
+
+	err = s.az.CreateHardLink(internal.CreateHardLinkOptions{Name: name + "_link", Target: name})
+	s.assert.EqualValues(syscall.EPERM, err)
+
+	err = s.az.CreateHardLink(internal.CreateHardLinkOptions{Name: "missing_link", Target: "missing"})
+	s.assert.EqualValues(syscall.ENOENT, err)
+}
+
 func (s *blockBlobTestSuite) TestBlockSize() {
 	defer s.cleanupTest()
 	// Setup
@@ -2517,7 +2805,7 @@ func (s *blockBlobTestSuite) TestFlushFileUpdateChunkedFile() {
 	s.assert.NoError(err)
 
 	h.CacheObj.BlockOffsetList.BlockList[1].Data = make([]byte, blockSize)
-	err = s.az.storage.ReadInBuffer(name, int64(blockSize), int64(blockSize), h.CacheObj.BlockOffsetList.BlockList[1].Data, nil)
+	err = s.az.storage.ReadInBuffer(name, int64(blockSize), int64(blockSize), h.CacheObj.BlockOffsetList.BlockList[1].Data, nil, internal.IOPriorityForeground)
 	s.assert.NoError(err)
 	copy(h.CacheObj.BlockOffsetList.BlockList[1].Data[MB:2*MB+MB], updatedBlock)
 	h.CacheObj.BlockList[1].Flags.Set(common.DirtyBlock)
@@ -2556,7 +2844,7 @@ func (s *blockBlobTestSuite) TestFlushFileTruncateUpdateChunkedFile() {
 	// truncate block
 	h.CacheObj.BlockOffsetList.BlockList[1].Data = make([]byte, blockSize/2)
 	h.CacheObj.BlockOffsetList.BlockList[1].EndIndex = int64(blockSize + blockSize/2)
-	err = s.az.storage.ReadInBuffer(name, int64(blockSize), int64(blockSize)/2, h.CacheObj.BlockOffsetList.BlockList[1].Data, nil)
+	err = s.az.storage.ReadInBuffer(name, int64(blockSize), int64(blockSize)/2, h.CacheObj.BlockOffsetList.BlockList[1].Data, nil, internal.IOPriorityForeground)
 	s.assert.NoError(err)
 	h.CacheObj.BlockList[1].Flags.Set(common.DirtyBlock)
 
@@ -3423,7 +3711,7 @@ func (s *blockBlobTestSuite) TestDownloadBlobWithCPKEnabled() {
 	s.assert.Equal(data, fileData)
 
 	buf := make([]byte, len(data))
-	err = s.az.storage.ReadInBuffer(name, 0, int64(len(data)), buf, nil)
+	err = s.az.storage.ReadInBuffer(name, 0, int64(len(data)), buf, nil, internal.IOPriorityForeground)
 	s.assert.NoError(err)
 	s.assert.Equal(data, buf)
 
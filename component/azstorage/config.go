@@ -194,9 +194,17 @@ type AzStorageOptions struct {
 	CPKEncryptionKeySha256  string `config:"cpk-encryption-key-sha256" yaml:"cpk-encryption-key-sha256"`
 	PreserveACL             bool   `config:"preserve-acl" yaml:"preserve-acl"`
 	Filter                  string `config:"filter" yaml:"filter"`
+	BlobTags                bool   `config:"blob-tags" yaml:"blob-tags"`
 	UserAssertion           string `config:"user-assertion" yaml:"user-assertions"`
 	CapMbpsRead             int64  `config:"cap-mbps-read" yaml:"cap-mbps-read"`
+	CapMbpsWrite            int64  `config:"cap-mbps-write" yaml:"cap-mbps-write"`
 	CapIOps                 int64  `config:"cap-iops" yaml:"cap-iops"`
+	CapIOpsList             int64  `config:"cap-iops-list" yaml:"cap-iops-list"`
+	CapIOpsMetadata         int64  `config:"cap-iops-metadata" yaml:"cap-iops-metadata"`
+	CapIOpsData             int64  `config:"cap-iops-data" yaml:"cap-iops-data"`
+	LeaseLocks              bool   `config:"lease-locks" yaml:"lease-locks"`
+	LeaseDuration           int32  `config:"lease-duration-sec" yaml:"lease-duration-sec"`
+	SnapshotsDir            string `config:"snapshots-dir" yaml:"snapshots-dir"`
 
 	// v1 support
 	UseAdls        bool   `config:"use-adls" yaml:"-"`
@@ -525,6 +533,22 @@ func ParseAndValidateConfig(az *AzStorage, opt AzStorageOptions) error {
 	}
 
 	az.stConfig.preserveACL = opt.PreserveACL
+
+	az.stConfig.leaseLocks = opt.LeaseLocks
+	az.stConfig.leaseDuration = defaultLeaseDuration
+	if config.IsSet(compName + ".lease-duration-sec") {
+		if opt.LeaseDuration < minLeaseDuration || opt.LeaseDuration > maxLeaseDuration {
+			return fmt.Errorf("lease-duration-sec shall be between %d and %d", minLeaseDuration, maxLeaseDuration)
+		}
+		az.stConfig.leaseDuration = opt.LeaseDuration
+	}
+
+	az.stConfig.snapshotsDir = strings.Trim(opt.SnapshotsDir, "/")
+	if strings.Contains(az.stConfig.snapshotsDir, "/") || az.stConfig.snapshotsDir == hardlinkDir {
+		return fmt.Errorf("snapshots-dir shall be a directory name at mount root")
+	}
+
+	az.stConfig.blobTags = opt.BlobTags
 	if opt.Filter != "" {
 		err = configureBlobFilter(az, opt)
 		if err != nil {
@@ -539,8 +563,10 @@ func ParseAndValidateConfig(az *AzStorage, opt AzStorageOptions) error {
 	log.Crit("ParseAndValidateConfig : Retry Config: retry-count %d, max-timeout %d, backoff-time %d, max-delay %d, preserve-acl: %v",
 		az.stConfig.maxRetries, az.stConfig.maxTimeout, az.stConfig.backoffTime, az.stConfig.maxRetryDelay, az.stConfig.preserveACL)
 
-	log.Crit("ParseAndValidateConfig : Telemetry : %s, honour-ACL %v, cap-mbps-read %d, cap-iops %d",
-		az.stConfig.telemetry, az.stConfig.honourACL, az.stConfig.capMbpsRead, az.stConfig.capIOps)
+	log.Crit("ParseAndValidateConfig : Telemetry : %s, honour-ACL %v, cap-mbps-read %d, cap-mbps-write %d, cap-iops %d, cap-iops-list %d, cap-iops-metadata %d, cap-iops-data %d, lease-locks %v, lease-duration %d, snapshots-dir %s",
+		az.stConfig.telemetry, az.stConfig.honourACL, az.stConfig.capMbpsRead, az.stConfig.capMbpsWrite, az.stConfig.capIOps,
+		az.stConfig.capIOpsList, az.stConfig.capIOpsMetadata, az.stConfig.capIOpsData, az.stConfig.leaseLocks, az.stConfig.leaseDuration,
+		az.stConfig.snapshotsDir)
 
 	return nil
 }
@@ -560,7 +586,17 @@ func configureBlobFilter(azStorage *AzStorage, opt AzStorageOptions) error {
 		return errors.New("failed to configure blob filter")
 	}
 
-	log.Crit("configureBlobFilter : Blob filter configured %s", opt.Filter)
+	// Tag conditions can only be evaluated if the tags come along with the attributes
+	azStorage.stConfig.filterTags = filterUsesTags(opt.Filter)
+	if azStorage.stConfig.filterTags {
+		azStorage.stConfig.blobTags = true
+	}
+
+	// Filters on tags alone are resolved by the service instead of listing everything
+	azStorage.stConfig.tagQueries, _ = tagQueries(opt.Filter)
+
+	log.Crit("configureBlobFilter : Blob filter configured %s, blob-tags %v, tag-queries %v",
+		opt.Filter, azStorage.stConfig.blobTags, azStorage.stConfig.tagQueries)
 	return nil
 }
 
@@ -635,15 +671,48 @@ func ParseAndReadDynamicConfig(az *AzStorage, opt AzStorageOptions, reload bool)
 
 	// Rate limiting, default is no limit
 	az.stConfig.capMbpsRead = -1
+	az.stConfig.capMbpsWrite = -1
 	az.stConfig.capIOps = -1
+	az.stConfig.capIOpsList = -1
+	az.stConfig.capIOpsMetadata = -1
+	az.stConfig.capIOpsData = -1
 
 	if opt.CapMbpsRead > 0 {
 		az.stConfig.capMbpsRead = opt.CapMbpsRead
 	}
 
+	if opt.CapMbpsWrite > 0 {
+		az.stConfig.capMbpsWrite = opt.CapMbpsWrite
+	}
+
 	if opt.CapIOps > 0 {
 		az.stConfig.capIOps = opt.CapIOps
 	}
 
+	if opt.CapIOpsList > 0 {
+		az.stConfig.capIOpsList = opt.CapIOpsList
+	}
+
+	if opt.CapIOpsMetadata > 0 {
+		az.stConfig.capIOpsMetadata = opt.CapIOpsMetadata
+	}
+
+	if opt.CapIOpsData > 0 {
+		az.stConfig.capIOpsData = opt.CapIOpsData
+	}
+
+	// The policy is shared by all clients of the mount, on reload the new limits apply to the running pipeline.
+	// Policy is only installed when some limit is configured at mount time.
+	limits := az.stConfig.getRateLimits()
+	if az.stConfig.rateLimiter != nil {
+		az.stConfig.rateLimiter.update(limits)
+	} else if limits.limited() {
+		if reload {
+			log.Warn("ParseAndReadDynamicConfig : Rate limits were not configured at mount time, remount to apply them")
+		} else {
+			az.stConfig.rateLimiter = newRateLimitingPolicy(limits)
+		}
+	}
+
 	return nil
 }
@@ -42,6 +42,7 @@ import (
 	"github.com/Azure/azure-storage-fuse/v2/common/log"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
+	"golang.org/x/time/rate"
 )
 
 type configTestSuite struct {
@@ -135,6 +136,67 @@ func (s *configTestSuite) TestBlockSize() {
 	assert.Contains(err.Error(), "block size is too large")
 }
 
+func (s *configTestSuite) TestLeaseLocks() {
+	defer config.ResetConfig()
+	assert := assert.New(s.T())
+	az := &AzStorage{}
+	opt := AzStorageOptions{}
+	opt.AccountName = "abcd"
+	opt.Container = "abcd"
+
+	err := ParseAndValidateConfig(az, opt)
+	assert.NoError(err)
+	assert.False(az.stConfig.leaseLocks)
+	assert.EqualValues(defaultLeaseDuration, az.stConfig.leaseDuration)
+
+	opt.LeaseLocks = true
+	opt.LeaseDuration = 20
+	config.SetBool(compName+".lease-locks", true)
+	config.Set(compName+".lease-duration-sec", "20")
+	err = ParseAndValidateConfig(az, opt)
+	assert.NoError(err)
+	assert.True(az.stConfig.leaseLocks)
+	assert.EqualValues(20, az.stConfig.leaseDuration)
+
+	opt.LeaseDuration = 90
+	err = ParseAndValidateConfig(az, opt)
+	assert.Error(err)
+	assert.Contains(err.Error(), "lease-duration-sec")
+}
+
+func (s *configTestSuite) TestSnapshotsDir() {
+	defer config.ResetConfig()
+	assert := assert.New(s.T())
+	az := &AzStorage{}
+	opt := AzStorageOptions{}
+	opt.AccountName = "abcd"
+	opt.Container = "abcd"
+
+	err := ParseAndValidateConfig(az, opt)
+	assert.NoError(err)
+	assert.Empty(az.stConfig.snapshotsDir)
+	assert.False(az.isSnapshotPath(".snapshots/a.txt"))
+
+	opt.SnapshotsDir = "/.snapshots/"
+	err = ParseAndValidateConfig(az, opt)
+	assert.NoError(err)
+	assert.Equal(".snapshots", az.stConfig.snapshotsDir)
+
+	rel, ok := az.snapshotPath(".snapshots")
+	assert.True(ok)
+	assert.Empty(rel)
+	rel, ok = az.snapshotPath("/.snapshots/dir/a.txt/2024-05-01T10:00:00.1234567Z")
+	assert.True(ok)
+	assert.Equal("dir/a.txt/2024-05-01T10:00:00.1234567Z", rel)
+	assert.True(az.isSnapshotPath("dir/a.txt", ".snapshots/dir/"))
+	assert.False(az.isSnapshotPath(".snapshotsx/a.txt", "dir/.snapshots"))
+
+	opt.SnapshotsDir = "a/.snapshots"
+	err = ParseAndValidateConfig(az, opt)
+	assert.Error(err)
+	assert.Contains(err.Error(), "snapshots-dir")
+}
+
 func (s *configTestSuite) TestProtoType() {
 	defer config.ResetConfig()
 	assert := assert.New(s.T())
@@ -452,14 +514,20 @@ func (s *configTestSuite) TestRateLimitConfig() {
 	assert.NoError(err)
 	assert.Equal(int64(-1), az.stConfig.capMbpsRead)
 	assert.Equal(int64(-1), az.stConfig.capIOps)
+	assert.Nil(az.stConfig.rateLimiter)
 
-	// Test setting limits
+	// Test setting limits, policy is not added to a running pipeline on reload
 	opt.CapMbpsRead = 100
 	opt.CapIOps = 10
+	err = ParseAndReadDynamicConfig(az, opt, true)
+	assert.NoError(err)
+	assert.Nil(az.stConfig.rateLimiter)
+
 	err = ParseAndReadDynamicConfig(az, opt, false)
 	assert.NoError(err)
 	assert.Equal(int64(100), az.stConfig.capMbpsRead)
 	assert.Equal(int64(10), az.stConfig.capIOps)
+	assert.NotNil(az.stConfig.rateLimiter)
 
 	// Test setting only one limit
 	opt.CapMbpsRead = 200
@@ -469,6 +537,36 @@ func (s *configTestSuite) TestRateLimitConfig() {
 	assert.NoError(err)
 	assert.Equal(int64(200), az.stConfig.capMbpsRead)
 	assert.Equal(int64(-1), az.stConfig.capIOps)
+
+	// Test write bandwidth and per class limits, applied to the existing policy on reload
+	limiter := az.stConfig.rateLimiter
+	assert.NotNil(limiter)
+
+	opt.CapMbpsWrite = 50
+	opt.CapIOpsList = 5
+	opt.CapIOpsMetadata = 20
+	opt.CapIOpsData = 0
+	err = ParseAndReadDynamicConfig(az, opt, true)
+	assert.NoError(err)
+	assert.Equal(int64(50), az.stConfig.capMbpsWrite)
+	assert.Equal(int64(5), az.stConfig.capIOpsList)
+	assert.Equal(int64(20), az.stConfig.capIOpsMetadata)
+	assert.Equal(int64(-1), az.stConfig.capIOpsData)
+
+	assert.Same(limiter, az.stConfig.rateLimiter)
+	limiters := limiter.limiters.Load()
+	assert.Equal(rate.Limit(200*131072), limiters.ingressBandwidth.Limit())
+	assert.Equal(2000*131072, limiters.ingressBandwidth.Burst())
+	assert.Equal(rate.Limit(50*131072), limiters.egressBandwidth.Limit())
+	assert.Nil(limiters.ops)
+	assert.Equal(rate.Limit(5), limiters.listOps.Limit())
+	assert.Equal(rate.Limit(20), limiters.metadataOps.Limit())
+	assert.Nil(limiters.dataOps)
+
+	// Unchanged limits keep their limiter
+	err = ParseAndReadDynamicConfig(az, opt, true)
+	assert.NoError(err)
+	assert.Same(limiters.ingressBandwidth, limiter.limiters.Load().ingressBandwidth)
 }
 
 func TestConfigTestSuite(t *testing.T) {
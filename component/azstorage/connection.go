@@ -86,9 +86,30 @@ type AzStorageConfig struct {
 	// Blob filters
 	filter *blobfilter.BlobFilter
 
+	// Retrieve blob index tags along with the attributes
+	blobTags bool
+
+	// Blob filter has conditions on blob index tags
+	filterTags bool
+
+	// Find Blobs by Tags expressions when the filter has only tag conditions
+	tagQueries []string
+
+	// Virtual directory at mount root exposing versions and snapshots of blobs
+	snapshotsDir string
+
 	// Rate limiting
-	capMbpsRead int64
-	capIOps     int64
+	capMbpsRead     int64
+	capMbpsWrite    int64
+	capIOps         int64
+	capIOpsList     int64
+	capIOpsMetadata int64
+	capIOpsData     int64
+	rateLimiter     *rateLimitingPolicy
+
+	// Cross node locking using blob leases
+	leaseLocks    bool
+	leaseDuration int32
 }
 
 type AzStorageConnection struct {
@@ -125,7 +146,10 @@ type AzConnection interface {
 
 	ReadToFile(name string, offset int64, count int64, fi *os.File) error
 	ReadBuffer(name string, offset int64, length int64) ([]byte, error)
-	ReadInBuffer(name string, offset int64, length int64, data []byte, etag *string) error
+	ReadInBuffer(name string, offset int64, length int64, data []byte, etag *string, priority internal.IOPriority) error
+	ListVersions(name string) ([]*internal.ObjAttr, error)
+	GetVersionAttr(name string, version string) (*internal.ObjAttr, error)
+	ReadVersion(name string, version string, offset int64, length int64, data []byte) error
 
 	WriteFromFile(name string, metadata map[string]*string, fi *os.File) error
 	WriteFromBuffer(name string, metadata map[string]*string, data []byte) error
@@ -134,12 +158,20 @@ type AzConnection interface {
 
 	ChangeMod(string, os.FileMode) error
 	ChangeOwner(string, int, int) error
+	SetMetadata(name string, metadata map[string]*string, etag string) error
+	GetTags(string) (map[string]string, error)
+	SetTags(string, map[string]string, map[string]string) error
 	TruncateFile(options internal.TruncateFileOptions) error
 	StageAndCommit(name string, bol *common.BlockOffsetList) error
 
 	GetCommittedBlockList(string) (*internal.CommittedBlockList, error)
-	StageBlock(string, []byte, string) error
-	CommitBlocks(string, []string, *string) error
+	StageBlock(string, []byte, string, internal.IOPriority) error
+	CommitBlocks(string, []string, map[string]*string, *string) error
+	CopyFileRange(options internal.CopyFileRangeOptions) (int64, error)
+
+	AcquireLease(name string, duration int32) error
+	RenewLease(name string) error
+	ReleaseLease(name string) error
 
 	UpdateServiceClient(_, _ string) error
 
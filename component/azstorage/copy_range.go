@@ -0,0 +1,331 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package azstorage
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blockblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/sas"
+	"github.com/Azure/azure-storage-fuse/v2/common"
+	"github.com/Azure/azure-storage-fuse/v2/common/log"
+	"github.com/Azure/azure-storage-fuse/v2/internal"
+)
+
+// rangeCopyBlock is a block of the destination blob once a range has been copied into it
+type rangeCopyBlock struct {
+	id     string // id of an existing block of destination which is kept, empty if the block has to be staged
+	offset int64
+	size   int64
+}
+
+// planRangeCopy : Lay out blocks of the destination after copying length bytes at dstOffset. An existing block is
+// kept as is if it has the same offset and size as the new block and does not overlap the copied range.
+func planRangeCopy(dstBlocks *internal.CommittedBlockList, dstSize int64, dstOffset int64, length int64, blockSize int64) []rangeCopyBlock {
+	existing := make(map[int64]internal.CommittedBlock)
+	if dstBlocks != nil {
+		for _, blk := range *dstBlocks {
+			existing[blk.Offset] = blk
+		}
+	}
+
+	newSize := max(dstSize, dstOffset+length)
+	plan := make([]rangeCopyBlock, 0, (newSize+blockSize-1)/blockSize)
+
+	for offset := int64(0); offset < newSize; offset += blockSize {
+		blk := rangeCopyBlock{offset: offset, size: min(blockSize, newSize-offset)}
+		end := offset + blk.size
+
+		old, found := existing[offset]
+		if found && int64(old.Size) == blk.size && end <= dstSize && (end <= dstOffset || offset >= dstOffset+length) {
+			blk.id = old.Id
+		}
+
+		plan = append(plan, blk)
+	}
+
+	return plan
+}
+
+// CopyFileRange : Copy a range of one blob into another without moving data through this node.
+// If destination becomes an exact copy of source then Copy Blob is used, otherwise block list of destination
+// is rebuilt with Put Block From URL and only the blocks mixing both blobs are staged from here.
+func (bb *BlockBlob) CopyFileRange(options internal.CopyFileRangeOptions) (int64, error) {
+	log.Trace("BlockBlob::CopyFileRange : %s [%d] -> %s [%d], length %d", options.SrcName, options.SrcOffset,
+		options.DstName, options.DstOffset, options.Length)
+
+	if bb.blobCPKOpt != nil {
+		// Service can not read a source encrypted with customer provided key on its own
+		return 0, syscall.ENOTSUP
+	}
+
+	srcAttr, err := bb.GetAttr(options.SrcName)
+	if err != nil {
+		log.Err("BlockBlob::CopyFileRange : Failed to get attributes of %s [%s]", options.SrcName, err.Error())
+		return 0, err
+	}
+
+	if options.SrcOffset >= srcAttr.Size || options.Length <= 0 {
+		return 0, nil
+	}
+	length := min(options.Length, srcAttr.Size-options.SrcOffset)
+
+	dstAttr, err := bb.GetAttr(options.DstName)
+	if err != nil {
+		log.Err("BlockBlob::CopyFileRange : Failed to get attributes of %s [%s]", options.DstName, err.Error())
+		return 0, err
+	}
+
+	if options.SrcName != options.DstName && options.SrcOffset == 0 && options.DstOffset == 0 &&
+		length == srcAttr.Size && dstAttr.Size <= length && options.BlockSize == 0 {
+		// Destination ends up as an exact copy of the source
+		err = bb.copyBlob(options.SrcName, options.DstName, dstAttr.Metadata)
+		if err != nil {
+			return 0, err
+		}
+		return length, nil
+	}
+
+	blockList, err := bb.GetCommittedBlockList(options.DstName)
+	if err != nil {
+		log.Err("BlockBlob::CopyFileRange : Failed to get block list of %s [%s]", options.DstName, err.Error())
+		return 0, err
+	}
+
+	newSize := max(dstAttr.Size, options.DstOffset+length)
+	blockSize := options.BlockSize
+	if blockSize == 0 {
+		blockSize = bb.Config.blockSize
+		if blockSize == 0 {
+			blockSize = 16 * 1024 * 1024
+		}
+		// Grow the block size if the blob can not fit in allowed number of blocks
+		blockSize = max(blockSize, (newSize+blockblob.MaxBlocks-1)/blockblob.MaxBlocks)
+	}
+
+	if blockSize > blockblob.MaxStageBlockBytes || (newSize+blockSize-1)/blockSize > blockblob.MaxBlocks {
+		log.Err("BlockBlob::CopyFileRange : %s of size %d can not fit in block limit with block size %d", options.DstName, newSize, blockSize)
+		return 0, syscall.EFBIG
+	}
+
+	idLength := int64(common.BlockIDLength)
+	if blockList != nil && len(*blockList) > 0 {
+		idLength = common.GetIdLength((*blockList)[0].Id)
+	}
+
+	plan := planRangeCopy(blockList, dstAttr.Size, options.DstOffset, length, blockSize)
+	ids := make([]string, len(plan))
+
+	indices := make(chan int, len(plan))
+	for i := range plan {
+		indices <- i
+	}
+	close(indices)
+
+	var wg sync.WaitGroup
+	var errLock sync.Mutex
+	var stageErr error
+
+	for range min(max(int(bb.Config.maxConcurrency), 1), len(plan)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				id, err := bb.stageCopyBlock(options, plan[i], dstAttr.Size, length, idLength)
+
+				errLock.Lock()
+				if err != nil && stageErr == nil {
+					stageErr = err
+				}
+				failed := stageErr != nil
+				errLock.Unlock()
+
+				if failed {
+					return
+				}
+				ids[i] = id
+			}
+		}()
+	}
+	wg.Wait()
+
+	if stageErr != nil {
+		return 0, stageErr
+	}
+
+	err = bb.CommitBlocks(options.DstName, ids, nil, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(dstAttr.Metadata) > 0 {
+		// Committing a block list does not retain the metadata of the blob
+		err = bb.SetMetadata(options.DstName, dstAttr.Metadata, "")
+		if err != nil {
+			log.Err("BlockBlob::CopyFileRange : Failed to restore metadata of %s [%s]", options.DstName, err.Error())
+			return 0, err
+		}
+	}
+
+	return length, nil
+}
+
+// stageCopyBlock : Stage a block of the destination for a range copy and return its id
+func (bb *BlockBlob) stageCopyBlock(options internal.CopyFileRangeOptions, blk rangeCopyBlock, dstSize int64, length int64, idLength int64) (string, error) {
+	if blk.id != "" {
+		return blk.id, nil
+	}
+
+	id := common.GetBlockID(idLength)
+	end := blk.offset + blk.size
+	copyEnd := options.DstOffset + length
+
+	if blk.offset >= options.DstOffset && end <= copyEnd {
+		// Block is made of copied data only
+		return id, bb.stageBlockFromURL(options.DstName, options.SrcName, options.SrcOffset+blk.offset-options.DstOffset, blk.size, id)
+	}
+
+	if (end <= options.DstOffset || blk.offset >= copyEnd) && end <= dstSize {
+		// Block is made of existing data of destination only
+		return id, bb.stageBlockFromURL(options.DstName, options.DstName, blk.offset, blk.size, id)
+	}
+
+	// Block mixes existing data, copied data and zeros of a gap so it has to be assembled here
+	data := make([]byte, blk.size)
+	readPiece := func(name string, offset int64, start int64, stop int64) error {
+		if start >= stop {
+			return nil
+		}
+		return bb.ReadInBuffer(name, offset, stop-start, data[start-blk.offset:stop-blk.offset], nil, internal.IOPriorityForeground)
+	}
+
+	err := readPiece(options.DstName, blk.offset, blk.offset, min(end, options.DstOffset, dstSize))
+	if err == nil {
+		start := max(blk.offset, options.DstOffset)
+		err = readPiece(options.SrcName, options.SrcOffset+start-options.DstOffset, start, min(end, copyEnd))
+	}
+	if err == nil {
+		start := max(blk.offset, copyEnd)
+		err = readPiece(options.DstName, start, start, min(end, dstSize))
+	}
+
+	if err != nil {
+		log.Err("BlockBlob::stageCopyBlock : Failed to read block at %d for %s [%s]", blk.offset, options.DstName, err.Error())
+		return "", err
+	}
+
+	return id, bb.StageBlock(options.DstName, data, id, internal.IOPriorityForeground)
+}
+
+// stageBlockFromURL : Stage a block of name with a range of source blob read by the service itself
+func (bb *BlockBlob) stageBlockFromURL(name string, source string, offset int64, count int64, id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), max_context_timeout*time.Minute)
+	defer cancel()
+
+	blobClient := bb.Container.NewBlockBlobClient(filepath.Join(bb.Config.prefixPath, name))
+	srcClient := bb.Container.NewBlockBlobClient(filepath.Join(bb.Config.prefixPath, source))
+
+	_, err := blobClient.StageBlockFromURL(ctx, id, bb.getCopySourceURL(srcClient), &blockblob.StageBlockFromURLOptions{
+		Range:                 blob.HTTPRange{Offset: offset, Count: count},
+		LeaseAccessConditions: bb.leaseAccess(name),
+	})
+
+	if err != nil {
+		log.Err("BlockBlob::stageBlockFromURL : Failed to stage %s [%d, %d] to %s [%s]", source, offset, count, name, err.Error())
+		if storeBlobErrToErr(err) == ErrFileNotFound {
+			return syscall.ENOENT
+		}
+		// Mostly the service is not authorized to read the source, caller shall fall back to read and write
+		return syscall.ENOTSUP
+	}
+
+	return nil
+}
+
+// getCopySourceURL : Url of the blob which the service is authorized to read as a copy source
+func (bb *BlockBlob) getCopySourceURL(client *blockblob.Client) string {
+	// Shared key credential can sign a short lived sas, otherwise rely on the sas in url if there is one
+	sasURL, err := client.GetSASURL(sas.BlobPermissions{Read: true}, time.Now().Add(time.Hour), nil)
+	if err == nil {
+		return sasURL
+	}
+	return client.URL()
+}
+
+// copyBlob : Copy source blob over target and wait for the copy to finish
+func (bb *BlockBlob) copyBlob(source string, target string, metadata map[string]*string) error {
+	srcClient := bb.Container.NewBlockBlobClient(filepath.Join(bb.Config.prefixPath, source))
+	dstClient := bb.Container.NewBlockBlobClient(filepath.Join(bb.Config.prefixPath, target))
+
+	// Metadata of target is retained if it has any, otherwise the service copies metadata of source
+	copyResponse, err := dstClient.StartCopyFromURL(context.Background(), srcClient.URL(), &blob.StartCopyFromURLOptions{
+		Metadata:         metadata,
+		Tier:             bb.Config.defaultTier,
+		AccessConditions: bb.blobAccess(target),
+	})
+
+	if err != nil {
+		log.Err("BlockBlob::copyBlob : Failed to start copy of %s to %s [%s]", source, target, err.Error())
+		if storeBlobErrToErr(err) == ErrFileNotFound {
+			return syscall.ENOENT
+		}
+		return err
+	}
+
+	copyStatus := copyResponse.CopyStatus
+	for copyStatus != nil && *copyStatus == blob.CopyStatusTypePending {
+		time.Sleep(time.Second * 1)
+		prop, err := dstClient.GetProperties(context.Background(), &blob.GetPropertiesOptions{
+			CPKInfo: bb.blobCPKOpt,
+		})
+		if err != nil {
+			log.Err("BlockBlob::copyBlob : Failed to get properties of %s [%s]", target, err.Error())
+			return err
+		}
+		copyStatus = prop.CopyStatus
+	}
+
+	if copyStatus != nil && *copyStatus != blob.CopyStatusTypeSuccess {
+		log.Err("BlockBlob::copyBlob : Copy of %s to %s ended with status %s", source, target, *copyStatus)
+		return fmt.Errorf("copy of %s to %s ended with status %s", source, target, *copyStatus)
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package azstorage
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-storage-fuse/v2/internal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type copyRangeTestSuite struct {
+	suite.Suite
+}
+
+func (s *copyRangeTestSuite) TestPlanRangeCopyReusesBlocks() {
+	assert := assert.New(s.T())
+
+	dstBlocks := &internal.CommittedBlockList{
+		{Id: "a", Offset: 0, Size: 4},
+		{Id: "b", Offset: 4, Size: 4},
+		{Id: "c", Offset: 8, Size: 4},
+		{Id: "d", Offset: 12, Size: 2},
+	}
+
+	// copy 3 bytes into the middle of second block
+	plan := planRangeCopy(dstBlocks, 14, 5, 3, 4)
+	assert.Equal([]rangeCopyBlock{
+		{id: "a", offset: 0, size: 4},
+		{id: "", offset: 4, size: 4},
+		{id: "c", offset: 8, size: 4},
+		{id: "d", offset: 12, size: 2},
+	}, plan)
+}
+
+func (s *copyRangeTestSuite) TestPlanRangeCopyExtendsFile() {
+	assert := assert.New(s.T())
+
+	dstBlocks := &internal.CommittedBlockList{
+		{Id: "a", Offset: 0, Size: 4},
+		{Id: "b", Offset: 4, Size: 2},
+	}
+
+	// partial last block is restaged as the file grows past it
+	plan := planRangeCopy(dstBlocks, 6, 10, 5, 4)
+	assert.Equal([]rangeCopyBlock{
+		{id: "a", offset: 0, size: 4},
+		{id: "", offset: 4, size: 4},
+		{id: "", offset: 8, size: 4},
+		{id: "", offset: 12, size: 3},
+	}, plan)
+}
+
+func (s *copyRangeTestSuite) TestPlanRangeCopyUnalignedList() {
+	assert := assert.New(s.T())
+
+	dstBlocks := &internal.CommittedBlockList{
+		{Id: "a", Offset: 0, Size: 3},
+		{Id: "b", Offset: 3, Size: 5},
+	}
+
+	plan := planRangeCopy(dstBlocks, 8, 0, 2, 4)
+	assert.Equal([]rangeCopyBlock{
+		{id: "", offset: 0, size: 4},
+		{id: "", offset: 4, size: 4},
+	}, plan)
+
+	// blob without blocks is laid out from scratch
+	plan = planRangeCopy(nil, 0, 0, 9, 4)
+	assert.Len(plan, 3)
+	assert.Equal(int64(1), plan[2].size)
+}
+
+func TestCopyRangeTestSuite(t *testing.T) {
+	suite.Run(t, new(copyRangeTestSuite))
+}
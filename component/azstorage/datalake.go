@@ -297,7 +297,15 @@ func (dl *Datalake) CreateLink(source string, target string) error {
 func (dl *Datalake) DeleteFile(name string) (err error) {
 	log.Trace("Datalake::DeleteFile : name %s", name)
 	fileClient := dl.Filesystem.NewFileClient(filepath.Join(dl.Config.prefixPath, name))
-	_, err = fileClient.Delete(context.Background(), nil)
+
+	var deleteOptions *file.DeleteOptions
+	if lac := dl.BlockBlob.leaseAccess(name); lac != nil {
+		deleteOptions = &file.DeleteOptions{
+			AccessConditions: &file.AccessConditions{LeaseAccessConditions: &file.LeaseAccessConditions{LeaseID: lac.LeaseID}},
+		}
+	}
+
+	_, err = fileClient.Delete(context.Background(), deleteOptions)
 	if err != nil {
 		serr := storeDatalakeErrToErr(err)
 		switch serr {
@@ -449,11 +457,21 @@ func (dl *Datalake) GetAttr(name string) (blobAttr *internal.ObjAttr, err error)
 		}
 	}
 
-	if dl.Config.filter != nil {
+	// Path properties do not carry the tags so fetch them from the blob endpoint when the filter needs them
+	if dl.Config.filterTags && !blobAttr.IsDir() {
+		blobAttr.Tags, err = dl.BlockBlob.GetTags(name)
+		if err != nil {
+			// Rest of the attributes are valid so do not fail the call
+			log.Err("Datalake::GetAttr : Failed to get tags of %s [%s]", name, err.Error())
+		}
+	}
+
+	if dl.Config.filter != nil && !blobAttr.IsDir() {
 		if !dl.Config.filter.IsAcceptable(&blobfilter.BlobAttr{
 			Name:  blobAttr.Name,
 			Mtime: blobAttr.Mtime,
 			Size:  blobAttr.Size,
+			Tags:  blobAttr.Tags,
 		}) {
 			log.Debug("Datalake::GetAttr : Filtered out %s", name)
 			return nil, syscall.ENOENT
@@ -470,6 +488,21 @@ func (dl *Datalake) List(prefix string, marker *string, count int32) ([]*interna
 	return dl.BlockBlob.List(prefix, marker, count)
 }
 
+// ListVersions : Get the versions and snapshots of a file
+func (dl *Datalake) ListVersions(name string) ([]*internal.ObjAttr, error) {
+	return dl.BlockBlob.ListVersions(name)
+}
+
+// GetVersionAttr : Get attributes of a version or snapshot of a file
+func (dl *Datalake) GetVersionAttr(name string, version string) (*internal.ObjAttr, error) {
+	return dl.BlockBlob.GetVersionAttr(name, version)
+}
+
+// ReadVersion : Download a range of a version or snapshot of a file to a user provided buffer
+func (dl *Datalake) ReadVersion(name string, version string, offset int64, length int64, data []byte) error {
+	return dl.BlockBlob.ReadVersion(name, version, offset, length, data)
+}
+
 // ReadToFile : Download a file to a local file
 func (dl *Datalake) ReadToFile(name string, offset int64, count int64, fi *os.File) (err error) {
 	return dl.BlockBlob.ReadToFile(name, offset, count, fi)
@@ -481,8 +514,8 @@ func (dl *Datalake) ReadBuffer(name string, offset int64, length int64) ([]byte,
 }
 
 // ReadInBuffer : Download specific range from a file to a user provided buffer
-func (dl *Datalake) ReadInBuffer(name string, offset int64, length int64, data []byte, etag *string) error {
-	return dl.BlockBlob.ReadInBuffer(name, offset, length, data, etag)
+func (dl *Datalake) ReadInBuffer(name string, offset int64, length int64, data []byte, etag *string, priority internal.IOPriority) error {
+	return dl.BlockBlob.ReadInBuffer(name, offset, length, data, etag, priority)
 }
 
 // WriteFromFile : Upload local file to file
@@ -610,25 +643,66 @@ func (dl *Datalake) ChangeOwner(name string, _ int, _ int) error {
 	return syscall.ENOTSUP
 }
 
+// GetTags : Get the blob index tags of a path
+func (dl *Datalake) GetTags(name string) (map[string]string, error) {
+	return dl.BlockBlob.GetTags(name)
+}
+
+// SetTags : Replace the blob index tags of a path, only if it still carries the tags in match
+func (dl *Datalake) SetTags(name string, tags map[string]string, match map[string]string) error {
+	return dl.BlockBlob.SetTags(name, tags, match)
+}
+
+// SetMetadata : Replace the user defined metadata of a path
+func (dl *Datalake) SetMetadata(name string, metadata map[string]*string, etag string) error {
+	return dl.BlockBlob.SetMetadata(name, metadata, etag)
+}
+
 // GetCommittedBlockList : Get the list of committed blocks
 func (dl *Datalake) GetCommittedBlockList(name string) (*internal.CommittedBlockList, error) {
 	return dl.BlockBlob.GetCommittedBlockList(name)
 }
 
 // StageBlock : stages a block and returns its blockid
-func (dl *Datalake) StageBlock(name string, data []byte, id string) error {
-	return dl.BlockBlob.StageBlock(name, data, id)
+func (dl *Datalake) StageBlock(name string, data []byte, id string, priority internal.IOPriority) error {
+	return dl.BlockBlob.StageBlock(name, data, id, priority)
 }
 
 // CommitBlocks : persists the block list
-func (dl *Datalake) CommitBlocks(name string, blockList []string, newEtag *string) error {
-	return dl.BlockBlob.CommitBlocks(name, blockList, newEtag)
+func (dl *Datalake) CommitBlocks(name string, blockList []string, metadata map[string]*string, newEtag *string) error {
+	return dl.BlockBlob.CommitBlocks(name, blockList, metadata, newEtag)
+}
+
+// CopyFileRange : copies a range of one file to another within storage
+func (dl *Datalake) CopyFileRange(options internal.CopyFileRangeOptions) (int64, error) {
+	return dl.BlockBlob.CopyFileRange(options)
+}
+
+// AcquireLease : Lease the file for this mount
+func (dl *Datalake) AcquireLease(name string, duration int32) error {
+	return dl.BlockBlob.AcquireLease(name, duration)
+}
+
+// RenewLease : Renew the lease this mount holds on the file
+func (dl *Datalake) RenewLease(name string) error {
+	return dl.BlockBlob.RenewLease(name)
+}
+
+// ReleaseLease : Release the lease this mount holds on the file
+func (dl *Datalake) ReleaseLease(name string) error {
+	return dl.BlockBlob.ReleaseLease(name)
 }
 
 func (dl *Datalake) SetFilter(filter string) error {
 	if filter == "" {
 		dl.Config.filter = nil
+		dl.Config.filterTags = false
 	} else {
+		dl.Config.filterTags = filterUsesTags(filter)
+		if dl.Config.filterTags {
+			dl.Config.blobTags = true
+		}
+
 		dl.Config.filter = &blobfilter.BlobFilter{}
 		err := dl.Config.filter.Configure(filter)
 		if err != nil {
@@ -0,0 +1,298 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package azstorage
+
+import (
+	"encoding/hex"
+	"path"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-storage-fuse/v2/common"
+	"github.com/Azure/azure-storage-fuse/v2/common/log"
+	"github.com/Azure/azure-storage-fuse/v2/internal"
+	"github.com/Azure/azure-storage-fuse/v2/internal/stats_manager"
+)
+
+// Hard links are emulated using link records. On the first link the blob holding the data is moved
+// into a hidden inode store and every name referring to it, including the original one, is replaced by
+// an empty record blob whose metadata points to the inode. Number of records referring to an inode is
+// kept on a separate count blob, as uploading data to the inode replaces its metadata.
+const (
+	hardlinkDir     = ".blobfuse2_links" // hidden directory at mount root holding the inodes
+	hardlinkKey     = "hardlink_inode"   // metadata key on a record holding path of its inode
+	linkCountKey    = "link_count"       // metadata key on the count blob holding number of records
+	linkCountSuffix = ".nlink"
+)
+
+// isHardlinkStore : Check whether path belongs to the hidden inode store
+func isHardlinkStore(name string) bool {
+	name = internal.TruncateDirName(name)
+	return name == hardlinkDir || strings.HasPrefix(name, hardlinkDir+"/")
+}
+
+// getHardlinkInode : Get the inode a link record points to
+func getHardlinkInode(metadata map[string]*string) (string, bool) {
+	k, found := findMetadataKey(metadata, hardlinkKey)
+	if !found || *metadata[k] == "" {
+		return "", false
+	}
+	return *metadata[k], true
+}
+
+// resolve : Get the path of the blob holding data of the given file
+func (az *AzStorage) resolve(name string) string {
+	if inode, ok := az.links.Load(name); ok {
+		return inode.(string)
+	}
+	return name
+}
+
+// linkInode : Get the inode a record points to. Records are cached only once listed or looked up through this
+// mount, so on a miss the metadata of the blob is read from storage.
+func (az *AzStorage) linkInode(name string) (string, bool) {
+	if inode, ok := az.links.Load(name); ok {
+		return inode.(string), true
+	}
+
+	attr, err := az.storage.GetAttr(name)
+	if err != nil {
+		return "", false
+	}
+
+	inode, isRecord := getHardlinkInode(attr.Metadata)
+	if isRecord {
+		az.links.Store(name, inode)
+	}
+	return inode, isRecord
+}
+
+// resolveAttr : Replace attributes of a link record with the attributes of its inode
+func (az *AzStorage) resolveAttr(attr *internal.ObjAttr) *internal.ObjAttr {
+	inode, isRecord := getHardlinkInode(attr.Metadata)
+	if !isRecord {
+		az.links.Delete(attr.Path)
+		return attr
+	}
+
+	inodeAttr, err := az.storage.GetAttr(inode)
+	if err != nil {
+		// Leave the dangling record visible so that user can still delete it
+		log.Err("AzStorage::resolveAttr : Failed to get inode %s of %s [%s]", inode, attr.Path, err.Error())
+		az.links.Delete(attr.Path)
+		return attr
+	}
+	az.links.Store(attr.Path, inode)
+
+	count, err := az.getLinkCount(inode)
+	if err != nil {
+		log.Err("AzStorage::resolveAttr : Failed to get link count of %s [%s]", inode, err.Error())
+	}
+
+	resolved := *inodeAttr
+	resolved.Path = attr.Path
+	resolved.Name = attr.Name
+	resolved.Nlink = count
+	return &resolved
+}
+
+// resolveList : Resolve link records in a listing and hide the inode store
+func (az *AzStorage) resolveList(list []*internal.ObjAttr) []*internal.ObjAttr {
+	resolved := list[:0]
+	for _, attr := range list {
+		if attr.Path == hardlinkDir {
+			continue
+		}
+		resolved = append(resolved, az.resolveAttr(attr))
+	}
+	return resolved
+}
+
+// getLinkCount : Get number of records referring to the inode
+func (az *AzStorage) getLinkCount(inode string) (uint64, error) {
+	attr, err := az.storage.GetAttr(inode + linkCountSuffix)
+	if err != nil {
+		return 0, err
+	}
+
+	k, found := findMetadataKey(attr.Metadata, linkCountKey)
+	if !found {
+		return 0, syscall.EINVAL
+	}
+	return strconv.ParseUint(*attr.Metadata[k], 10, 64)
+}
+
+// setLinkCount : Save number of records referring to the inode, the inode is deleted once it drops to zero
+func (az *AzStorage) setLinkCount(inode string, count uint64) error {
+	if count == 0 {
+		err := az.storage.DeleteFile(inode)
+		if err != nil && err != syscall.ENOENT {
+			log.Err("AzStorage::setLinkCount : Failed to delete inode %s [%s]", inode, err.Error())
+			return err
+		}
+		return az.storage.DeleteFile(inode + linkCountSuffix)
+	}
+
+	metadata := map[string]*string{linkCountKey: to.Ptr(strconv.FormatUint(count, 10))}
+	return az.storage.WriteFromBuffer(inode+linkCountSuffix, metadata, nil)
+}
+
+// writeLinkRecord : Create a record with the given name pointing to the inode
+func (az *AzStorage) writeLinkRecord(name string, inode string) error {
+	metadata := map[string]*string{hardlinkKey: to.Ptr(inode)}
+	err := az.storage.WriteFromBuffer(name, metadata, nil)
+	if err != nil {
+		return err
+	}
+	az.links.Store(name, inode)
+	return nil
+}
+
+// createInode : Move data of the file into the inode store and leave a record in its place
+func (az *AzStorage) createInode(name string, attr *internal.ObjAttr) (string, error) {
+	if _, err := az.storage.GetAttr(hardlinkDir); err == syscall.ENOENT {
+		err = az.storage.CreateDirectory(hardlinkDir)
+		if err != nil {
+			log.Err("AzStorage::createInode : Failed to create %s [%s]", hardlinkDir, err.Error())
+			return "", err
+		}
+	}
+
+	inode := path.Join(hardlinkDir, hex.EncodeToString(common.NewUUID().Bytes()))
+	err := az.storage.RenameFile(name, inode, attr)
+	if err != nil {
+		log.Err("AzStorage::createInode : Failed to move %s to %s [%s]", name, inode, err.Error())
+		return "", err
+	}
+
+	err = az.setLinkCount(inode, 1)
+	if err == nil {
+		err = az.writeLinkRecord(name, inode)
+	}
+	if err != nil {
+		log.Err("AzStorage::createInode : Failed to create record for %s [%s]", name, err.Error())
+		_ = az.storage.RenameFile(inode, name, nil)
+		_ = az.storage.DeleteFile(inode + linkCountSuffix)
+		return "", err
+	}
+
+	return inode, nil
+}
+
+// unlinkInode : Drop one reference of the inode after its record was removed
+func (az *AzStorage) unlinkInode(inode string) {
+	mtx := az.linkLocks.GetLock(inode)
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	count, err := az.getLinkCount(inode)
+	if err != nil {
+		log.Err("AzStorage::unlinkInode : Failed to get link count of %s [%s]", inode, err.Error())
+		return
+	}
+
+	if count > 0 {
+		count--
+	}
+
+	err = az.setLinkCount(inode, count)
+	if err != nil {
+		log.Err("AzStorage::unlinkInode : Failed to update link count of %s [%s]", inode, err.Error())
+	}
+}
+
+// CreateHardLink : Create a new record pointing to the inode of target, creating the inode on first link
+func (az *AzStorage) CreateHardLink(options internal.CreateHardLinkOptions) error {
+	log.Trace("AzStorage::CreateHardLink : Create hard link %s -> %s", options.Name, options.Target)
+
+	if isHardlinkStore(options.Name) || isHardlinkStore(options.Target) {
+		return syscall.EPERM
+	}
+
+	_, err := az.storage.GetAttr(options.Name)
+	if err == nil {
+		return syscall.EEXIST
+	} else if err != syscall.ENOENT {
+		return err
+	}
+
+	attr, err := az.storage.GetAttr(options.Target)
+	if err != nil {
+		return err
+	}
+
+	if attr.IsDir() {
+		log.Err("AzStorage::CreateHardLink : %s is a directory", options.Target)
+		return syscall.EPERM
+	}
+
+	inode, isRecord := getHardlinkInode(attr.Metadata)
+	if !isRecord {
+		inode, err = az.createInode(options.Target, attr)
+		if err != nil {
+			return err
+		}
+	}
+
+	mtx := az.linkLocks.GetLock(inode)
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	count, err := az.getLinkCount(inode)
+	if err != nil {
+		log.Err("AzStorage::CreateHardLink : Failed to get link count of %s [%s]", inode, err.Error())
+		return err
+	}
+
+	// Bump the count before creating the record, a failure in between leaks the inode instead of losing data
+	err = az.setLinkCount(inode, count+1)
+	if err != nil {
+		log.Err("AzStorage::CreateHardLink : Failed to update link count of %s [%s]", inode, err.Error())
+		return err
+	}
+
+	err = az.writeLinkRecord(options.Name, inode)
+	if err != nil {
+		log.Err("AzStorage::CreateHardLink : Failed to create record %s [%s]", options.Name, err.Error())
+		_ = az.setLinkCount(inode, count)
+		return err
+	}
+
+	azStatsCollector.PushEvents(createHardLink, options.Name, map[string]any{target: options.Target})
+	azStatsCollector.UpdateStats(stats_manager.Increment, createHardLink, (int64)(1))
+
+	return nil
+}
@@ -0,0 +1,166 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package azstorage
+
+import (
+	"path"
+	"syscall"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-storage-fuse/v2/internal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// linkStorage : Storage keeping blobs and their metadata in memory
+type linkStorage struct {
+	AzConnection
+	blobs map[string]map[string]*string
+}
+
+func (ls *linkStorage) GetAttr(name string) (*internal.ObjAttr, error) {
+	metadata, ok := ls.blobs[name]
+	if !ok {
+		return nil, syscall.ENOENT
+	}
+	return &internal.ObjAttr{Path: name, Name: path.Base(name), Metadata: metadata, Flags: internal.NewFileBitMap()}, nil
+}
+
+func (ls *linkStorage) DeleteFile(name string) error {
+	if _, ok := ls.blobs[name]; !ok {
+		return syscall.ENOENT
+	}
+	delete(ls.blobs, name)
+	return nil
+}
+
+func (ls *linkStorage) RenameFile(src string, dst string, _ *internal.ObjAttr) error {
+	metadata, ok := ls.blobs[src]
+	if !ok {
+		return syscall.ENOENT
+	}
+	delete(ls.blobs, src)
+	ls.blobs[dst] = metadata
+	return nil
+}
+
+func (ls *linkStorage) CreateDirectory(name string) error {
+	ls.blobs[name] = map[string]*string{}
+	return nil
+}
+
+func (ls *linkStorage) WriteFromBuffer(name string, metadata map[string]*string, _ []byte) error {
+	ls.blobs[name] = metadata
+	return nil
+}
+
+type hardlinkTestSuite struct {
+	suite.Suite
+}
+
+func (s *hardlinkTestSuite) TestIsHardlinkStore() {
+	assert := assert.New(s.T())
+
+	assert.True(isHardlinkStore(hardlinkDir))
+	assert.True(isHardlinkStore(hardlinkDir + "/"))
+	assert.True(isHardlinkStore(hardlinkDir + "/abcd"))
+	assert.False(isHardlinkStore(hardlinkDir + "x/abcd"))
+	assert.False(isHardlinkStore("dir/" + hardlinkDir))
+	assert.False(isHardlinkStore("file"))
+}
+
+func (s *hardlinkTestSuite) TestGetHardlinkInode() {
+	assert := assert.New(s.T())
+
+	inode, found := getHardlinkInode(map[string]*string{"Hardlink_inode": to.Ptr(hardlinkDir + "/abcd")})
+	assert.True(found)
+	assert.Equal(hardlinkDir+"/abcd", inode)
+
+	_, found = getHardlinkInode(map[string]*string{"Hardlink_inode": to.Ptr("")})
+	assert.False(found)
+
+	_, found = getHardlinkInode(map[string]*string{"owner": to.Ptr("abc")})
+	assert.False(found)
+
+	_, found = getHardlinkInode(nil)
+	assert.False(found)
+}
+
+func (s *hardlinkTestSuite) TestHardlinkKeyReserved() {
+	assert := assert.New(s.T())
+
+	_, err := xattrToMetadataKey("user." + hardlinkKey)
+	assert.Error(err)
+}
+
+func (s *hardlinkTestSuite) TestUnlinkAfterRemount() {
+	assert := assert.New(s.T())
+
+	storage := &linkStorage{blobs: map[string]map[string]*string{"a": {}}}
+	az := &AzStorage{storage: storage}
+	assert.NoError(az.CreateHardLink(internal.CreateHardLinkOptions{Name: "b", Target: "a"}))
+	assert.NoError(az.CreateHardLink(internal.CreateHardLinkOptions{Name: "c", Target: "a"}))
+	inode, _ := getHardlinkInode(storage.blobs["a"])
+
+	count, err := az.getLinkCount(inode)
+	assert.NoError(err)
+	assert.EqualValues(3, count)
+
+	// A new mount knows none of the records until it reads them
+	az = &AzStorage{storage: storage}
+	assert.NoError(az.DeleteFile(internal.DeleteFileOptions{Name: "a"}))
+	count, err = az.getLinkCount(inode)
+	assert.NoError(err)
+	assert.EqualValues(2, count)
+
+	az = &AzStorage{storage: storage}
+	storage.blobs["d"] = map[string]*string{}
+	assert.NoError(az.RenameFile(internal.RenameFileOptions{Src: "d", Dst: "b"}))
+	count, err = az.getLinkCount(inode)
+	assert.NoError(err)
+	assert.EqualValues(1, count)
+
+	// Inode goes away with the last record
+	az = &AzStorage{storage: storage}
+	assert.NoError(az.DeleteFile(internal.DeleteFileOptions{Name: "c"}))
+	assert.NotContains(storage.blobs, inode)
+	assert.NotContains(storage.blobs, inode+linkCountSuffix)
+	assert.Contains(storage.blobs, "b")
+	assert.Len(storage.blobs, 2)
+}
+
+func TestHardlinkTestSuite(t *testing.T) {
+	suite.Run(t, new(hardlinkTestSuite))
+}
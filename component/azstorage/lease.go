@@ -0,0 +1,325 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package azstorage
+
+import (
+	"context"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/lease"
+	"github.com/Azure/azure-storage-fuse/v2/common/log"
+	"github.com/Azure/azure-storage-fuse/v2/internal"
+	"github.com/Azure/azure-storage-fuse/v2/internal/handlemap"
+)
+
+// Cross node locking is done with blob leases when lease-locks is enabled. A blob is leased once per mount
+// however many handles lock it, the lease is renewed in background until the last of those handles unlocks
+// it or is released. While the lease is held every write request of this mount on the blob carries its id,
+// so other nodes fail to lock or modify the blob. A file created on this node is not in storage till its first
+// upload, so locking it is recorded and the blob is leased right after that upload. Till then other nodes are
+// not kept from creating the blob.
+const (
+	defaultLeaseDuration = 30 // seconds
+	minLeaseDuration     = 15
+	maxLeaseDuration     = 60
+)
+
+// blobLease tracks the handles of this mount holding the lease of a blob
+type blobLease struct {
+	holders map[handlemap.HandleID]struct{}
+	stop    chan struct{}
+	pending bool // blob did not exist when locked, it is leased on its first upload
+}
+
+// LockFile : Lease the blob for the handle, fails with EWOULDBLOCK if another node holds the lease
+func (az *AzStorage) LockFile(options internal.LockFileOptions) error {
+	if !az.stConfig.leaseLocks {
+		return nil
+	}
+
+	if options.Handle == nil {
+		return syscall.EINVAL
+	}
+
+	name := az.resolve(options.Name)
+	log.Trace("AzStorage::LockFile : %s, handle %d", name, options.Handle.ID)
+
+	az.leaseLock.Lock()
+	defer az.leaseLock.Unlock()
+
+	if l, ok := az.leases[name]; ok {
+		l.holders[options.Handle.ID] = struct{}{}
+		return nil
+	}
+
+	err := az.storage.AcquireLease(name, az.stConfig.leaseDuration)
+	if err != nil && err != syscall.ENOENT {
+		return err
+	}
+
+	l := &blobLease{
+		holders: map[handlemap.HandleID]struct{}{options.Handle.ID: {}},
+		stop:    make(chan struct{}),
+		pending: err == syscall.ENOENT,
+	}
+	az.leases[name] = l
+	if l.pending {
+		log.Info("AzStorage::LockFile : %s is not in storage yet, it is leased once uploaded", name)
+	} else {
+		go az.renewLease(name, l)
+	}
+
+	return nil
+}
+
+// leaseUploaded : Lease a blob which was locked before it existed, now that it got uploaded
+func (az *AzStorage) leaseUploaded(name string) {
+	if !az.stConfig.leaseLocks {
+		return
+	}
+
+	az.leaseLock.Lock()
+	defer az.leaseLock.Unlock()
+
+	l, ok := az.leases[name]
+	if !ok || !l.pending {
+		return
+	}
+
+	err := az.storage.AcquireLease(name, az.stConfig.leaseDuration)
+	if err != nil {
+		// Tried again on the next upload
+		log.Err("AzStorage::leaseUploaded : Failed to lease %s, other nodes may modify it [%s]", name, err.Error())
+		return
+	}
+
+	l.pending = false
+	go az.renewLease(name, l)
+}
+
+// UnlockFile : Drop the hold of the handle on the lease of the blob, lease is released once nobody holds it
+func (az *AzStorage) UnlockFile(options internal.UnlockFileOptions) error {
+	if !az.stConfig.leaseLocks || options.Handle == nil {
+		return nil
+	}
+
+	name := az.resolve(options.Name)
+	log.Trace("AzStorage::UnlockFile : %s, handle %d", name, options.Handle.ID)
+
+	return az.unlockFile(name, options.Handle.ID)
+}
+
+func (az *AzStorage) unlockFile(name string, id handlemap.HandleID) error {
+	az.leaseLock.Lock()
+	defer az.leaseLock.Unlock()
+
+	l, ok := az.leases[name]
+	if !ok {
+		return nil
+	}
+
+	if _, held := l.holders[id]; !held {
+		return nil
+	}
+
+	delete(l.holders, id)
+	if len(l.holders) > 0 {
+		return nil
+	}
+
+	delete(az.leases, name)
+	close(l.stop)
+	if l.pending {
+		return nil
+	}
+	return az.storage.ReleaseLease(name)
+}
+
+// forgetLease : Stop tracking the lease of a blob which no longer exists under this name
+func (az *AzStorage) forgetLease(name string) {
+	az.leaseLock.Lock()
+	defer az.leaseLock.Unlock()
+
+	l, ok := az.leases[name]
+	if !ok {
+		return
+	}
+
+	delete(az.leases, name)
+	close(l.stop)
+	if !l.pending {
+		_ = az.storage.ReleaseLease(name)
+	}
+}
+
+// releaseAllLeases : Release every lease held by this mount
+func (az *AzStorage) releaseAllLeases() {
+	az.leaseLock.Lock()
+	defer az.leaseLock.Unlock()
+
+	for name, l := range az.leases {
+		delete(az.leases, name)
+		close(l.stop)
+		if !l.pending {
+			_ = az.storage.ReleaseLease(name)
+		}
+	}
+}
+
+// renewLease : Keep the lease of the blob alive until it is released
+func (az *AzStorage) renewLease(name string, l *blobLease) {
+	ticker := time.NewTicker(time.Duration(az.stConfig.leaseDuration) * time.Second / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			err := az.storage.RenewLease(name)
+			if err == nil {
+				continue
+			}
+
+			select {
+			case <-l.stop:
+				// Released while the renewal was in flight
+			default:
+				log.Err("AzStorage::renewLease : Lost lease on %s, other nodes may modify it now [%s]", name, err.Error())
+			}
+			return
+		}
+	}
+}
+
+// AcquireLease : Lease the blob, write requests of this mount on the blob carry the lease id from then on
+func (bb *BlockBlob) AcquireLease(name string, duration int32) error {
+	log.Trace("BlockBlob::AcquireLease : name %s, duration %d", name, duration)
+
+	blobClient := bb.Container.NewBlobClient(filepath.Join(bb.Config.prefixPath, name))
+	leaseClient, err := lease.NewBlobClient(blobClient, nil)
+	if err != nil {
+		log.Err("BlockBlob::AcquireLease : Failed to create lease client for %s [%s]", name, err.Error())
+		return err
+	}
+
+	resp, err := leaseClient.AcquireLease(context.Background(), duration, nil)
+	if err != nil {
+		serr := storeBlobErrToErr(err)
+		switch serr {
+		case ErrFileNotFound:
+			log.Err("BlockBlob::AcquireLease : %s does not exist", name)
+			return syscall.ENOENT
+		case BlobLeaseConflict:
+			log.Info("BlockBlob::AcquireLease : %s is leased by another client", name)
+			return syscall.EWOULDBLOCK
+		default:
+			log.Err("BlockBlob::AcquireLease : Failed to acquire lease on %s [%s]", name, err.Error())
+			return err
+		}
+	}
+
+	bb.leases.Store(name, *resp.LeaseID)
+	return nil
+}
+
+// RenewLease : Renew the lease this mount holds on the blob
+func (bb *BlockBlob) RenewLease(name string) error {
+	leaseClient, err := bb.getLeaseClient(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = leaseClient.RenewLease(context.Background(), nil)
+	if err != nil {
+		log.Err("BlockBlob::RenewLease : Failed to renew lease on %s [%s]", name, err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// ReleaseLease : Release the lease this mount holds on the blob
+func (bb *BlockBlob) ReleaseLease(name string) error {
+	log.Trace("BlockBlob::ReleaseLease : name %s", name)
+
+	leaseClient, err := bb.getLeaseClient(name)
+	if err != nil {
+		return nil
+	}
+	bb.leases.Delete(name)
+
+	_, err = leaseClient.ReleaseLease(context.Background(), nil)
+	if err != nil {
+		if storeBlobErrToErr(err) == ErrFileNotFound {
+			// Lease is gone along with the blob
+			return nil
+		}
+		log.Err("BlockBlob::ReleaseLease : Failed to release lease on %s [%s]", name, err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// getLeaseClient : Lease client for the lease this mount holds on the blob
+func (bb *BlockBlob) getLeaseClient(name string) (*lease.BlobClient, error) {
+	id, ok := bb.leases.Load(name)
+	if !ok {
+		return nil, syscall.ENOLCK
+	}
+
+	blobClient := bb.Container.NewBlobClient(filepath.Join(bb.Config.prefixPath, name))
+	return lease.NewBlobClient(blobClient, &lease.BlobClientOptions{LeaseID: to.Ptr(id.(string))})
+}
+
+// leaseAccess : Lease conditions for write requests on the blob, nil when this mount holds no lease on it
+func (bb *BlockBlob) leaseAccess(name string) *blob.LeaseAccessConditions {
+	if id, ok := bb.leases.Load(name); ok {
+		return &blob.LeaseAccessConditions{LeaseID: to.Ptr(id.(string))}
+	}
+	return nil
+}
+
+// blobAccess : Access conditions for write requests on the blob, nil when this mount holds no lease on it
+func (bb *BlockBlob) blobAccess(name string) *blob.AccessConditions {
+	if lac := bb.leaseAccess(name); lac != nil {
+		return &blob.AccessConditions{LeaseAccessConditions: lac}
+	}
+	return nil
+}
@@ -34,16 +34,20 @@
 package azstorage
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"net/http"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"golang.org/x/time/rate"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 	"github.com/Azure/azure-storage-fuse/v2/common"
 	"github.com/Azure/azure-storage-fuse/v2/common/log"
+	"github.com/Azure/azure-storage-fuse/v2/internal"
 )
 
 // blobfuseTelemetryPolicy is a custom pipeline policy to prepend the blobfuse user agent string to the one coming from SDK.
@@ -87,13 +91,164 @@ func (r *serviceVersionPolicy) Do(req *policy.Request) (*http.Response, error) {
 
 // ---------------------------------------------------------------------------------------------------------------------------------------------------
 // Policy to limit the rate of requests
+//
+// Policy is shared by all the clients of a mount. On config change a new set of limiters is swapped in,
+// so requests never see a limiter whose rate and burst size are half updated.
 type rateLimitingPolicy struct {
-	ingressBandwidthLimiter *rate.Limiter
-	opsLimiter              *rate.Limiter
+	limiters atomic.Pointer[rateLimiters]
+
+	// Holds back requests while requests of a higher priority wait for tokens
+	gate priorityGate
+}
+
+// rateLimiters : Limiters enforcing one set of limits, nil for a limit that is not configured
+type rateLimiters struct {
+	ingressBandwidth *rate.Limiter
+	egressBandwidth  *rate.Limiter
+	ops              *rate.Limiter
+
+	// Budgets of each class of operation, applied on top of the total ops limit
+	listOps     *rate.Limiter
+	metadataOps *rate.Limiter
+	dataOps     *rate.Limiter
+}
+
+// Key of the request priority in the context of a request
+type ioPriorityKey struct{}
+
+// withIOPriority : Attach the priority of a request to its context
+func withIOPriority(ctx context.Context, priority internal.IOPriority) context.Context {
+	return context.WithValue(ctx, ioPriorityKey{}, priority)
+}
+
+// getIOPriority : Priority of a request, foreground if none was attached
+func getIOPriority(ctx context.Context) internal.IOPriority {
+	if priority, ok := ctx.Value(ioPriorityKey{}).(internal.IOPriority); ok {
+		return max(internal.IOPriorityForeground, min(priority, internal.IOPriorityUpload))
+	}
+	return internal.IOPriorityForeground
+}
+
+// priorityGate lets a request wait for rate limiter tokens only when no request of a higher priority is waiting.
+// Tokens taken by a request are not given back, so without the gate a burst of prefetches or uploads would push
+// every foreground read behind them.
+type priorityGate struct {
+	sync.Mutex
+	waiting [internal.IOPriorityUpload + 1]int
+	changed chan struct{} // Closed and replaced each time a request leaves the gate
+}
+
+// enter : Block until no request of a higher priority is waiting, the caller must call leave once done with the limiters
+func (g *priorityGate) enter(ctx context.Context, priority internal.IOPriority) error {
+	g.Lock()
+	defer g.Unlock()
+
+	g.waiting[priority]++
+	for g.higherWaiting(priority) {
+		if g.changed == nil {
+			g.changed = make(chan struct{})
+		}
+		changed := g.changed
+
+		g.Unlock()
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			g.Lock()
+			g.release(priority)
+			return ctx.Err()
+		}
+		g.Lock()
+	}
+
+	return nil
+}
+
+// leave : Wake up the requests held back by this one
+func (g *priorityGate) leave(priority internal.IOPriority) {
+	g.Lock()
+	defer g.Unlock()
+	g.release(priority)
+}
+
+func (g *priorityGate) release(priority internal.IOPriority) {
+	g.waiting[priority]--
+	if g.changed != nil {
+		close(g.changed)
+		g.changed = nil
+	}
 }
 
-func newRateLimitingPolicy(readBytesPerSec int64, opsPerSec int64) policy.Policy {
+func (g *priorityGate) higherWaiting(priority internal.IOPriority) bool {
+	for p := internal.IOPriorityForeground; p < priority; p++ {
+		if g.waiting[p] > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// rateLimits : Limits enforced by the rate limiting policy, zero or negative value means no limit
+type rateLimits struct {
+	readBytesPerSec   int64
+	writeBytesPerSec  int64
+	opsPerSec         int64
+	listOpsPerSec     int64
+	metadataOpsPerSec int64
+	dataOpsPerSec     int64
+}
+
+// limited : Check whether any limit is configured
+func (l rateLimits) limited() bool {
+	return l.readBytesPerSec > 0 || l.writeBytesPerSec > 0 || l.opsPerSec > 0 ||
+		l.listOpsPerSec > 0 || l.metadataOpsPerSec > 0 || l.dataOpsPerSec > 0
+}
+
+// Class of a storage operation for the per class ops budgets
+type opClass int
+
+const (
+	// Download, upload and commit of blob contents
+	opClassData opClass = iota
+	// Listing of blobs or paths
+	opClassList
+	// Everything else, e.g. get/set properties, metadata, delete, rename and copy
+	opClassMetadata
+)
+
+func newRateLimitingPolicy(limits rateLimits) *rateLimitingPolicy {
 	p := &rateLimitingPolicy{}
+	p.update(limits)
+	return p
+}
+
+// update : Swap in limiters for the new limits, limiters of unchanged limits are kept along with their tokens.
+// Requests already waiting for tokens finish their wait on the old limiters.
+func (p *rateLimitingPolicy) update(limits rateLimits) {
+	old := p.limiters.Load()
+	if old == nil {
+		old = &rateLimiters{}
+	}
+
+	p.limiters.Store(&rateLimiters{
+		ingressBandwidth: newLimiter(old.ingressBandwidth, limits.readBytesPerSec, "Bandwidth", "bytes"),
+		egressBandwidth:  newLimiter(old.egressBandwidth, limits.writeBytesPerSec, "Upload bandwidth", "bytes"),
+		ops:              newLimiter(old.ops, limits.opsPerSec, "Ops", "ops"),
+		listOps:          newLimiter(old.listOps, limits.listOpsPerSec, "List ops", "ops"),
+		metadataOps:      newLimiter(old.metadataOps, limits.metadataOpsPerSec, "Metadata ops", "ops"),
+		dataOps:          newLimiter(old.dataOps, limits.dataOpsPerSec, "Data ops", "ops"),
+	})
+}
+
+// newLimiter : Limiter with the rate and burst size of the given limit, nil if zero or negative rate removes the limit.
+// Existing limiter is returned as is when the limit does not change.
+func newLimiter(existing *rate.Limiter, perSec int64, name string, unit string) *rate.Limiter {
+	if perSec <= 0 {
+		if existing != nil {
+			log.Info("RateLimitingPolicy : %s limit removed", name)
+		}
+		return nil
+	}
 
 	// Use 10 second window for burst size calculation for rate limiter.
 	// This allows for short bursts while still enforcing the average rate over a reasonable time period.
@@ -108,51 +263,139 @@ func newRateLimitingPolicy(readBytesPerSec int64, opsPerSec int64) policy.Policy
 	// while setting a lower opsPerSec value will limit the number of operations per second.
 	const windowSize = 10
 
-	if readBytesPerSec > 0 {
-		ingressBandwidthBurstSize := readBytesPerSec * int64(windowSize)
-		burst := int(ingressBandwidthBurstSize)
-		// On 32-bit systems, int is 32-bit. If bandwidthBurstSize > MaxInt, we need to clamp it.
-		// math.MaxInt is platform dependent.
-		if ingressBandwidthBurstSize > int64(math.MaxInt) {
-			burst = math.MaxInt
+	burstSize := perSec * int64(windowSize)
+	burst := int(burstSize)
+	// On 32-bit systems, int is 32-bit. If burstSize > MaxInt, we need to clamp it.
+	// math.MaxInt is platform dependent.
+	if burstSize > int64(math.MaxInt) {
+		burst = math.MaxInt
+	}
+
+	if existing != nil && existing.Limit() == rate.Limit(perSec) && existing.Burst() == burst {
+		return existing
+	}
+
+	log.Info("RateLimitingPolicy : %s limit set to %d %s/sec with burst size of %d %s",
+		name, perSec, unit, burst, unit)
+	return rate.NewLimiter(rate.Limit(perSec), burst)
+}
+
+// waitN : Wait for n tokens of the limiter, nil limiter does not limit anything.
+// A single wait can not ask for more than the burst size, so large payloads are admitted in burst sized chunks.
+func waitN(ctx context.Context, limiter *rate.Limiter, n int64) error {
+	if limiter == nil {
+		return nil
+	}
+
+	for n > 0 {
+		chunk := min(n, int64(max(limiter.Burst(), 1)))
+		err := limiter.WaitN(ctx, int(chunk))
+		if err != nil {
+			return err
 		}
+		n -= chunk
+	}
 
-		p.ingressBandwidthLimiter = rate.NewLimiter(rate.Limit(readBytesPerSec), burst)
-		log.Info("RateLimitingPolicy : Bandwidth limit set to %d bytes/sec with burst size of %d bytes",
-			readBytesPerSec, burst)
+	return nil
+}
+
+// getHeader : Get a request header, the SDK stores some headers in lower case instead of the canonical form
+func getHeader(req *http.Request, key string) string {
+	// NOTE: using strings.ToLower to ignore the lint error regarding canonicalized headers.
+	if val := req.Header[strings.ToLower(key)]; len(val) > 0 {
+		return val[0]
 	}
+	return req.Header.Get(key)
+}
 
-	if opsPerSec > 0 {
-		opsBurstSize := opsPerSec * int64(windowSize)
-		burst := int(opsBurstSize)
-		if opsBurstSize > int64(math.MaxInt) {
-			burst = math.MaxInt
+// classifyRequest : Find the class of operation a request belongs to
+func classifyRequest(req *http.Request) opClass {
+	query := req.URL.Query()
+	comp := query.Get("comp")
+
+	switch req.Method {
+	case http.MethodGet:
+		switch {
+		case comp == "list":
+			return opClassList
+		case query.Get("resource") != "":
+			// Datalake list paths on the filesystem
+			return opClassList
+		case comp == "":
+			return opClassData
 		}
 
-		p.opsLimiter = rate.NewLimiter(rate.Limit(opsPerSec), burst)
-		log.Info("RateLimitingPolicy : Ops limit set to %d ops/sec with burst size of %d ops",
-			opsPerSec, burst)
+	case http.MethodPut:
+		switch comp {
+		case "block", "blocklist", "appendblock", "page":
+			return opClassData
+		case "":
+			// Put blob carries the blob type, copy blob carries the source instead of a body
+			if getHeader(req, "x-ms-blob-type") != "" && getHeader(req, "x-ms-copy-source") == "" {
+				return opClassData
+			}
+		}
+
+	case http.MethodPatch:
+		// Datalake append and flush
+		action := query.Get("action")
+		if action == "append" || action == "flush" {
+			return opClassData
+		}
 	}
 
-	return p
+	return opClassMetadata
 }
 
 func (p *rateLimitingPolicy) Do(req *policy.Request) (*http.Response, error) {
 	ctx := req.Raw().Context()
 
+	priority := getIOPriority(ctx)
+	err := p.gate.enter(ctx, priority)
+	if err != nil {
+		log.Err("RateLimitingPolicy : Priority wait failed [%s]", err.Error())
+		return nil, err
+	}
+
+	err = p.waitForTokens(req)
+	p.gate.leave(priority)
+	if err != nil {
+		return nil, err
+	}
+
+	return req.Next()
+}
+
+// waitForTokens : Wait till the request fits in all the limits that apply to it
+func (p *rateLimitingPolicy) waitForTokens(req *policy.Request) error {
+	ctx := req.Raw().Context()
+	limiters := p.limiters.Load()
+
 	// Limit operations per second
-	if p.opsLimiter != nil {
-		// Wait for 1 token
-		err := p.opsLimiter.Wait(ctx)
-		if err != nil {
-			log.Err("RateLimitingPolicy : Ops limit wait failed [%s]", err.Error())
-			return nil, err
-		}
+	err := waitN(ctx, limiters.ops, 1)
+	if err != nil {
+		log.Err("RateLimitingPolicy : Ops limit wait failed [%s]", err.Error())
+		return err
+	}
+
+	// Limit operations per second of this class of operation
+	classLimiter := limiters.dataOps
+	switch classifyRequest(req.Raw()) {
+	case opClassList:
+		classLimiter = limiters.listOps
+	case opClassMetadata:
+		classLimiter = limiters.metadataOps
+	}
+
+	err = waitN(ctx, classLimiter, 1)
+	if err != nil {
+		log.Err("RateLimitingPolicy : Class ops limit wait failed [%s]", err.Error())
+		return err
 	}
 
 	// Limit ingress bandwidth for blob downloads (Azure egress: data leaving Azure Storage).
-	// This policy intentionally applies only to GET requests, which represent download operations.
-	if p.ingressBandwidthLimiter != nil && req.Raw().Method == http.MethodGet {
+	// This limit intentionally applies only to GET requests, which represent download operations.
+	if req.Raw().Method == http.MethodGet && limiters.ingressBandwidth != nil {
 		// Check for x-ms-range header
 		// We are not using req.Raw().Header.Get() as it canonicalizes the header name.
 		// Whereas SDK stores the header in the request is stored in lower case.
@@ -167,18 +410,28 @@ func (p *rateLimitingPolicy) Do(req *policy.Request) (*http.Response, error) {
 			size, err := parseRangeHeader(rangeHeader[0])
 			if err == nil && size > 0 {
 				// Wait for tokens equal to size.
-				// NOTE: range size is guaranteed to be within int range by parseRangeHeader.
-				err := p.ingressBandwidthLimiter.WaitN(ctx, int(size))
+				err := waitN(ctx, limiters.ingressBandwidth, size)
 				if err != nil {
 					log.Err("RateLimitingPolicy : Bandwidth limit wait failed [%s]", err.Error())
-					return nil, err
+					return err
 				}
 			} else if err != nil {
 				log.Err("RateLimitingPolicy : Failed to parse Range header %s: [%s]", rangeHeader[0], err.Error())
-				return nil, err
+				return err
 			}
 		}
 	}
 
-	return req.Next()
+	// Limit egress bandwidth for uploads (Azure ingress: data sent to Azure Storage).
+	// Applies to the body of PUT and PATCH requests, i.e. put block, put blob and datalake append.
+	method := req.Raw().Method
+	if (method == http.MethodPut || method == http.MethodPatch) && req.Raw().ContentLength > 0 {
+		err := waitN(ctx, limiters.egressBandwidth, req.Raw().ContentLength)
+		if err != nil {
+			log.Err("RateLimitingPolicy : Upload bandwidth limit wait failed [%s]", err.Error())
+			return err
+		}
+	}
+
+	return nil
 }
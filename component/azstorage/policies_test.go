@@ -34,6 +34,7 @@
 package azstorage
 
 import (
+	"bytes"
 	"context"
 	"net/http"
 	"testing"
@@ -41,8 +42,10 @@ import (
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/streaming"
 	"github.com/Azure/azure-storage-fuse/v2/common"
 	"github.com/Azure/azure-storage-fuse/v2/common/log"
+	"github.com/Azure/azure-storage-fuse/v2/internal"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 )
@@ -73,7 +76,7 @@ func (s *policiesTestSuite) TestRateLimitingPolicy_OpsLimit() {
 	assert := assert.New(s.T())
 
 	// Limit to 1 op/sec. Burst will be 1 * 10 = 10 ops.
-	p := newRateLimitingPolicy(-1, 1)
+	p := newRateLimitingPolicy(rateLimits{readBytesPerSec: -1, opsPerSec: 1})
 	pipeline := runtime.NewPipeline("test", "v1", runtime.PipelineOptions{
 		PerRetry: []policy.Policy{p},
 	}, &policy.ClientOptions{Transport: &mockTransport{}})
@@ -101,7 +104,7 @@ func (s *policiesTestSuite) TestRateLimitingPolicy_BandwidthLimit() {
 	assert := assert.New(s.T())
 
 	// Limit 100 bytes/sec. Burst will be 100 * 10 = 1000 bytes.
-	p := newRateLimitingPolicy(100, -1)
+	p := newRateLimitingPolicy(rateLimits{readBytesPerSec: 100, opsPerSec: -1})
 	pipeline := runtime.NewPipeline("test", "v1", runtime.PipelineOptions{
 		PerRetry: []policy.Policy{p},
 	}, &policy.ClientOptions{Transport: &mockTransport{}})
@@ -129,7 +132,7 @@ func (s *policiesTestSuite) TestRateLimitingPolicy_BandwidthLimit() {
 func (s *policiesTestSuite) TestRateLimitingPolicy_NoLimit() {
 	assert := assert.New(s.T())
 
-	p := newRateLimitingPolicy(-1, -1)
+	p := newRateLimitingPolicy(rateLimits{readBytesPerSec: -1, opsPerSec: -1})
 	pipeline := runtime.NewPipeline("test", "v1", runtime.PipelineOptions{
 		PerRetry: []policy.Policy{p},
 	}, &policy.ClientOptions{Transport: &mockTransport{}})
@@ -152,7 +155,7 @@ func (s *policiesTestSuite) TestRateLimitingPolicy_BandwidthLimit_XMsRange() {
 	assert := assert.New(s.T())
 
 	// Limit 100 bytes/sec. Burst 1000 bytes.
-	p := newRateLimitingPolicy(100, -1)
+	p := newRateLimitingPolicy(rateLimits{readBytesPerSec: 100, opsPerSec: -1})
 	pipeline := runtime.NewPipeline("test", "v1", runtime.PipelineOptions{
 		PerRetry: []policy.Policy{p},
 	}, &policy.ClientOptions{Transport: &mockTransport{}})
@@ -179,7 +182,7 @@ func (s *policiesTestSuite) TestRateLimitingPolicy_BandwidthLimit_SkipNonGet() {
 	assert := assert.New(s.T())
 
 	// Limit 100 bytes/sec. burst 1000.
-	p := newRateLimitingPolicy(100, -1)
+	p := newRateLimitingPolicy(rateLimits{readBytesPerSec: 100, opsPerSec: -1})
 	pipeline := runtime.NewPipeline("test", "v1", runtime.PipelineOptions{
 		PerRetry: []policy.Policy{p},
 	}, &policy.ClientOptions{Transport: &mockTransport{}})
@@ -207,6 +210,243 @@ func (s *policiesTestSuite) TestRateLimitingPolicy_BandwidthLimit_SkipNonGet() {
 	}
 }
 
+func (s *policiesTestSuite) TestRateLimitingPolicy_WriteBandwidthLimit() {
+	assert := assert.New(s.T())
+
+	// Limit 100 bytes/sec. Burst 1000 bytes.
+	p := newRateLimitingPolicy(rateLimits{writeBytesPerSec: 100})
+	pipeline := runtime.NewPipeline("test", "v1", runtime.PipelineOptions{
+		PerRetry: []policy.Policy{p},
+	}, &policy.ClientOptions{Transport: &mockTransport{}})
+
+	newReq := func(method string, size int) *policy.Request {
+		req, err := runtime.NewRequest(context.Background(), method, "http://localhost?comp=block")
+		assert.NoError(err)
+		err = req.SetBody(streaming.NopCloser(bytes.NewReader(make([]byte, size))), "application/octet-stream")
+		assert.NoError(err)
+		return req
+	}
+
+	// Consume burst, downloads are not counted against the upload limit
+	_, err := pipeline.Do(newReq(http.MethodPut, 1000))
+	assert.NoError(err)
+
+	get, _ := runtime.NewRequest(context.Background(), http.MethodGet, "http://localhost")
+	get.Raw().Header["Range"] = []string{"bytes=0-999"}
+	start := time.Now()
+	_, err = pipeline.Do(get)
+	assert.NoError(err)
+	assert.Less(time.Since(start), 100*time.Millisecond)
+
+	// Next upload of 100 bytes should be delayed by ~1 sec
+	start = time.Now()
+	_, err = pipeline.Do(newReq(http.MethodPatch, 100))
+	assert.NoError(err)
+	duration := time.Since(start)
+	assert.GreaterOrEqual(duration, 900*time.Millisecond, "Expected delay of ~1s, got %v", duration)
+}
+
+func (s *policiesTestSuite) TestRateLimitingPolicy_LargerThanBurst() {
+	assert := assert.New(s.T())
+
+	// Limit 100 bytes/sec. Burst 1000 bytes, a body of 1100 bytes is admitted in two waits.
+	p := newRateLimitingPolicy(rateLimits{writeBytesPerSec: 100})
+	pipeline := runtime.NewPipeline("test", "v1", runtime.PipelineOptions{
+		PerRetry: []policy.Policy{p},
+	}, &policy.ClientOptions{Transport: &mockTransport{}})
+
+	req, _ := runtime.NewRequest(context.Background(), http.MethodPut, "http://localhost?comp=block")
+	err := req.SetBody(streaming.NopCloser(bytes.NewReader(make([]byte, 1100))), "application/octet-stream")
+	assert.NoError(err)
+
+	start := time.Now()
+	_, err = pipeline.Do(req)
+	assert.NoError(err)
+	duration := time.Since(start)
+	assert.GreaterOrEqual(duration, 900*time.Millisecond, "Expected delay of ~1s, got %v", duration)
+}
+
+func (s *policiesTestSuite) TestClassifyRequest() {
+	assert := assert.New(s.T())
+
+	newReq := func(method string, url string, headers ...string) *http.Request {
+		req, err := http.NewRequest(method, url, nil)
+		assert.NoError(err)
+		for i := 0; i+1 < len(headers); i += 2 {
+			req.Header[headers[i]] = []string{headers[i+1]}
+		}
+		return req
+	}
+
+	assert.Equal(opClassList, classifyRequest(newReq(http.MethodGet, "http://localhost/cnt?restype=container&comp=list")))
+	assert.Equal(opClassList, classifyRequest(newReq(http.MethodGet, "http://localhost/fs?resource=filesystem&recursive=false")))
+	assert.Equal(opClassData, classifyRequest(newReq(http.MethodGet, "http://localhost/cnt/blob")))
+	assert.Equal(opClassData, classifyRequest(newReq(http.MethodPut, "http://localhost/cnt/blob?comp=block&blockid=abc")))
+	assert.Equal(opClassData, classifyRequest(newReq(http.MethodPut, "http://localhost/cnt/blob?comp=blocklist")))
+	assert.Equal(opClassData, classifyRequest(newReq(http.MethodPut, "http://localhost/cnt/blob", "x-ms-blob-type", "BlockBlob")))
+	assert.Equal(opClassData, classifyRequest(newReq(http.MethodPatch, "http://localhost/fs/file?action=append&position=0")))
+	assert.Equal(opClassMetadata, classifyRequest(newReq(http.MethodPut, "http://localhost/cnt/blob", "x-ms-blob-type", "BlockBlob", "x-ms-copy-source", "http://localhost/cnt/src")))
+	assert.Equal(opClassMetadata, classifyRequest(newReq(http.MethodHead, "http://localhost/cnt/blob")))
+	assert.Equal(opClassMetadata, classifyRequest(newReq(http.MethodGet, "http://localhost/cnt/blob?comp=blocklist")))
+	assert.Equal(opClassMetadata, classifyRequest(newReq(http.MethodPut, "http://localhost/cnt/blob?comp=metadata")))
+	assert.Equal(opClassMetadata, classifyRequest(newReq(http.MethodDelete, "http://localhost/cnt/blob")))
+	assert.Equal(opClassMetadata, classifyRequest(newReq(http.MethodPut, "http://localhost/fs/dir?resource=directory")))
+	assert.Equal(opClassMetadata, classifyRequest(newReq(http.MethodPatch, "http://localhost/fs/file?action=setAccessControl")))
+}
+
+func (s *policiesTestSuite) TestRateLimitingPolicy_ClassOpsLimit() {
+	assert := assert.New(s.T())
+
+	// Limit list to 1 op/sec. Burst 10 ops, data and metadata are not limited.
+	p := newRateLimitingPolicy(rateLimits{listOpsPerSec: 1})
+	pipeline := runtime.NewPipeline("test", "v1", runtime.PipelineOptions{
+		PerRetry: []policy.Policy{p},
+	}, &policy.ClientOptions{Transport: &mockTransport{}})
+
+	list, _ := runtime.NewRequest(context.Background(), http.MethodGet, "http://localhost/cnt?restype=container&comp=list")
+	for i := 0; i < 10; i++ {
+		_, err := pipeline.Do(list)
+		assert.NoError(err)
+	}
+
+	start := time.Now()
+	for _, method := range []string{http.MethodGet, http.MethodHead, http.MethodDelete} {
+		req, _ := runtime.NewRequest(context.Background(), method, "http://localhost/cnt/blob")
+		for i := 0; i < 20; i++ {
+			_, err := pipeline.Do(req)
+			assert.NoError(err)
+		}
+	}
+	assert.Less(time.Since(start), 100*time.Millisecond)
+
+	start = time.Now()
+	_, err := pipeline.Do(list)
+	assert.NoError(err)
+	duration := time.Since(start)
+	assert.GreaterOrEqual(duration, 900*time.Millisecond, "Expected delay of ~1s, got %v", duration)
+}
+
+func (s *policiesTestSuite) TestRateLimitingPolicy_Update() {
+	assert := assert.New(s.T())
+
+	p := newRateLimitingPolicy(rateLimits{})
+	pipeline := runtime.NewPipeline("test", "v1", runtime.PipelineOptions{
+		PerRetry: []policy.Policy{p},
+	}, &policy.ClientOptions{Transport: &mockTransport{}})
+
+	req, _ := runtime.NewRequest(context.Background(), http.MethodGet, "http://localhost/cnt/blob")
+	req.Raw().Header["Range"] = []string{"bytes=0-99"}
+
+	// Limit added at runtime: 1 data op/sec with a burst of 10 ops
+	p.update(rateLimits{dataOpsPerSec: 1})
+	for i := 0; i < 10; i++ {
+		_, err := pipeline.Do(req)
+		assert.NoError(err)
+	}
+
+	start := time.Now()
+	_, err := pipeline.Do(req)
+	assert.NoError(err)
+	assert.GreaterOrEqual(time.Since(start), 900*time.Millisecond)
+
+	// Limit removed at runtime
+	p.update(rateLimits{})
+	start = time.Now()
+	for i := 0; i < 20; i++ {
+		_, err := pipeline.Do(req)
+		assert.NoError(err)
+	}
+	assert.Less(time.Since(start), 100*time.Millisecond)
+}
+
+func (s *policiesTestSuite) TestIOPriority() {
+	assert := assert.New(s.T())
+
+	assert.Equal(internal.IOPriorityForeground, getIOPriority(context.Background()))
+	assert.Equal(internal.IOPriorityPrefetch, getIOPriority(withIOPriority(context.Background(), internal.IOPriorityPrefetch)))
+	assert.Equal(internal.IOPriorityUpload, getIOPriority(withIOPriority(context.Background(), internal.IOPriority(10))))
+}
+
+func (s *policiesTestSuite) TestPriorityGate() {
+	assert := assert.New(s.T())
+
+	g := &priorityGate{}
+	ctx := context.Background()
+
+	// Foreground request waiting for tokens
+	assert.NoError(g.enter(ctx, internal.IOPriorityForeground))
+
+	// Requests of the same priority are not held back
+	assert.NoError(g.enter(ctx, internal.IOPriorityForeground))
+	g.leave(internal.IOPriorityForeground)
+
+	entered := make(chan internal.IOPriority, 2)
+	for _, priority := range []internal.IOPriority{internal.IOPriorityPrefetch, internal.IOPriorityUpload} {
+		go func() {
+			_ = g.enter(ctx, priority)
+			entered <- priority
+			g.leave(priority)
+		}()
+	}
+
+	// Lower priority requests wait till the foreground request is done with the limiters
+	time.Sleep(100 * time.Millisecond)
+	assert.Empty(entered)
+
+	g.leave(internal.IOPriorityForeground)
+	for range 2 {
+		select {
+		case <-entered:
+		case <-time.After(5 * time.Second):
+			assert.Fail("request held back after foreground request left")
+		}
+	}
+
+	// Cancelled request gives up its place
+	assert.NoError(g.enter(ctx, internal.IOPriorityListing))
+	cancelCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	assert.Error(g.enter(cancelCtx, internal.IOPriorityUpload))
+	assert.Equal(0, g.waiting[internal.IOPriorityUpload])
+	g.leave(internal.IOPriorityListing)
+}
+
+func (s *policiesTestSuite) TestRateLimitingPolicy_ForegroundFirst() {
+	assert := assert.New(s.T())
+
+	// Limit to 2 ops/sec, once the burst is consumed every request waits for its token
+	p := newRateLimitingPolicy(rateLimits{opsPerSec: 2})
+	pipeline := runtime.NewPipeline("test", "v1", runtime.PipelineOptions{
+		PerRetry: []policy.Policy{p},
+	}, &policy.ClientOptions{Transport: &mockTransport{}})
+
+	burst, _ := runtime.NewRequest(context.Background(), http.MethodGet, "http://localhost/cnt/blob")
+	for range 20 {
+		_, err := pipeline.Do(burst)
+		assert.NoError(err)
+	}
+
+	// While foreground reads wait for tokens the prefetch is held back, so a foreground read
+	// arriving after the prefetch still gets its token first
+	done := make(chan string, 3)
+	send := func(name string, priority internal.IOPriority) {
+		req, _ := runtime.NewRequest(withIOPriority(context.Background(), priority), http.MethodGet, "http://localhost/cnt/"+name)
+		_, err := pipeline.Do(req)
+		assert.NoError(err)
+		done <- name
+	}
+
+	go send("read1", internal.IOPriorityForeground)
+	time.Sleep(50 * time.Millisecond)
+	go send("prefetch", internal.IOPriorityPrefetch)
+	time.Sleep(50 * time.Millisecond)
+	go send("read2", internal.IOPriorityForeground)
+
+	assert.Equal("read1", <-done)
+	assert.Equal("read2", <-done)
+	assert.Equal("prefetch", <-done)
+}
+
 func TestPoliciesSuite(t *testing.T) {
 	suite.Run(t, new(policiesTestSuite))
 }
@@ -0,0 +1,348 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package azstorage
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	"github.com/Azure/azure-storage-fuse/v2/common/log"
+	"github.com/Azure/azure-storage-fuse/v2/internal"
+)
+
+// A filter made only of blob index tag equality conditions can be evaluated by the service. Instead of listing
+// the whole prefix and dropping blobs client side, names of the matching blobs are fetched with the Find Blobs
+// by Tags API and the directory tree is synthesized from them. Matching names are refreshed periodically as the
+// mount is read-only and a short staleness is acceptable.
+const tagIndexRefreshInterval = 60 * time.Second
+
+// tagConditionRegex : Condition on a tag as parsed by blobfilter, which does not allow ':' or '=' in key or value
+var tagConditionRegex = regexp.MustCompile(`^tag=([^:=]+):([^:=]*)$`)
+
+// Listings over the matching names hand out markers with this prefix, as opposed to the opaque service markers
+const tagMarkerPrefix = "tags:"
+
+// tagIndex : Names of the blobs matching a tag only filter
+type tagIndex struct {
+	sync.Mutex
+	refresh  sync.Mutex   // held while fetching the matching names, the index itself is locked only to swap them
+	queries  []string     // Find Blobs by Tags expressions, one per OR'ed group of the filter
+	matches  *tagMatchSet // last fetched matching blobs, kept when disabled for listings already paginating over them
+	expiry   time.Time    // matches are fetched again after this
+	disabled bool         // service rejected the query so fall back to list and filter
+}
+
+// tagMatchSet : Blobs matching the filter as returned by one refresh
+type tagMatchSet struct {
+	names []string                     // sorted names of the matching blobs, relative to the container
+	tags  map[string]map[string]string // tags of each blob returned along with the match
+}
+
+func newTagIndex(queries []string) *tagIndex {
+	if len(queries) == 0 {
+		return nil
+	}
+	return &tagIndex{queries: queries}
+}
+
+// tagQueries : Convert a blob filter to Find Blobs by Tags expressions, returns false if the service can not
+// evaluate it. The service supports only AND in an expression so each OR'ed group becomes its own query.
+func tagQueries(filter string) ([]string, bool) {
+	if filter == "" {
+		return nil, false
+	}
+
+	queries := make([]string, 0)
+	for _, group := range strings.Split(filter, "||") {
+		conditions := make([]string, 0)
+		for _, condition := range strings.Split(group, "&&") {
+			match := tagConditionRegex.FindStringSubmatch(strings.TrimSpace(condition))
+			if match == nil {
+				return nil, false
+			}
+
+			key, value := match[1], match[2]
+			if len(key) > maxBlobTagKeyLen || !isValidTagString(key) || !isValidTagValue([]byte(value)) {
+				return nil, false
+			}
+			conditions = append(conditions, "\""+key+"\" = '"+value+"'")
+		}
+		queries = append(queries, strings.Join(conditions, " AND "))
+	}
+
+	return queries, true
+}
+
+// tagListEntry : Child of a directory synthesized from the matching blob names
+type tagListEntry struct {
+	name  string // name relative to the container, directories end with '/'
+	isDir bool
+}
+
+// tagListEntries : Emulate a hierarchical listing with '/' delimiter over the sorted blob names. Entries after the
+// marker are returned, if more than count entries remain the name of the last returned entry is the next marker.
+func tagListEntries(names []string, listPath string, marker string, count int32) ([]tagListEntry, string) {
+	entries := make([]tagListEntry, 0)
+	last := ""
+
+	for i := sort.SearchStrings(names, listPath); i < len(names) && strings.HasPrefix(names[i], listPath); i++ {
+		entry := tagListEntry{name: names[i]}
+		rest := names[i][len(listPath):]
+		if idx := strings.Index(rest, "/"); idx >= 0 {
+			entry = tagListEntry{name: listPath + rest[:idx+1], isDir: true}
+		}
+
+		// Names under a directory are contiguous once sorted so a directory repeats only back to back
+		if entry.name == last || (marker != "" && entry.name <= marker) {
+			continue
+		}
+
+		if int32(len(entries)) == count {
+			return entries, last
+		}
+
+		entries = append(entries, entry)
+		last = entry.name
+	}
+
+	return entries, ""
+}
+
+// tagIndexHasDir : Check whether any matching blob lives under the directory
+func tagIndexHasDir(names []string, dir string) bool {
+	dir = strings.TrimSuffix(dir, "/") + "/"
+	i := sort.SearchStrings(names, dir)
+	return i < len(names) && strings.HasPrefix(names[i], dir)
+}
+
+// state : Snapshot of the index, whether the matches are fresh and whether the index is disabled
+func (index *tagIndex) state() (*tagMatchSet, bool, bool) {
+	index.Lock()
+	defer index.Unlock()
+	return index.matches, time.Now().Before(index.expiry), index.disabled
+}
+
+// last : Matches of the last successful refresh, even when the index got disabled since
+func (index *tagIndex) last() *tagMatchSet {
+	if index == nil {
+		return nil
+	}
+
+	index.Lock()
+	defer index.Unlock()
+	return index.matches
+}
+
+// isTagQueryRejected : Check whether the service will never evaluate the tag query for this mount, i.e. the
+// credentials lack the filter permission or the account does not support blob index tags
+func isTagQueryRejected(err error) bool {
+	var respErr *azcore.ResponseError
+	if !errors.As(err, &respErr) {
+		return false
+	}
+
+	switch respErr.StatusCode {
+	case http.StatusForbidden, http.StatusBadRequest, http.StatusNotImplemented:
+		return true
+	}
+	return strings.Contains(respErr.ErrorCode, "NotSupported")
+}
+
+// tagMatches : Blobs matching the filter, false if the listing has to fall back to list and filter
+func (bb *BlockBlob) tagMatches() (*tagMatchSet, bool) {
+	index := bb.tagIndex
+	if index == nil {
+		return nil, false
+	}
+
+	matches, fresh, disabled := index.state()
+	if disabled {
+		return nil, false
+	} else if fresh {
+		return matches, true
+	}
+
+	// Only one caller refreshes, the rest keep using the previous matches meanwhile
+	if !index.refresh.TryLock() {
+		if matches != nil {
+			return matches, true
+		}
+		index.refresh.Lock()
+	}
+	defer index.refresh.Unlock()
+
+	// Another caller may have refreshed while this one waited
+	matches, fresh, disabled = index.state()
+	if disabled {
+		return nil, false
+	} else if fresh {
+		return matches, true
+	}
+
+	fetched := &tagMatchSet{tags: make(map[string]map[string]string)}
+	var err error
+	for _, query := range index.queries {
+		err = bb.findBlobsByTags(query, fetched.tags)
+		if err != nil {
+			break
+		}
+	}
+
+	index.Lock()
+	defer index.Unlock()
+
+	if err != nil {
+		if isTagQueryRejected(err) {
+			// Listing and filtering is slow but still correct
+			log.Warn("BlockBlob::tagMatches : Find blobs by tags rejected, falling back to list and filter [%s]", err.Error())
+			index.disabled = true
+			return nil, false
+		}
+
+		// Expiry stays in the past so the next listing tries again
+		log.Err("BlockBlob::tagMatches : Find blobs by tags failed, retrying on next listing [%s]", err.Error())
+		return index.matches, index.matches != nil
+	}
+
+	fetched.names = make([]string, 0, len(fetched.tags))
+	for name := range fetched.tags {
+		fetched.names = append(fetched.names, name)
+	}
+	sort.Strings(fetched.names)
+
+	index.matches = fetched
+	index.expiry = time.Now().Add(tagIndexRefreshInterval)
+	log.Debug("BlockBlob::tagMatches : %d blobs match the tag filter", len(fetched.names))
+
+	return fetched, true
+}
+
+// findBlobsByTags : Add all the blobs in the container matching the expression along with their tags
+func (bb *BlockBlob) findBlobsByTags(query string, matches map[string]map[string]string) error {
+	log.Trace("BlockBlob::findBlobsByTags : query %s", query)
+
+	ctx := withIOPriority(context.Background(), internal.IOPriorityListing)
+	var marker *string
+	for {
+		resp, err := bb.Container.FilterBlobs(ctx, query, &container.FilterBlobsOptions{
+			Marker: marker,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, item := range resp.Blobs {
+			if item.Name != nil {
+				matches[*item.Name] = parseBlobTags(item.Tags)
+			}
+		}
+
+		if resp.NextMarker == nil || *resp.NextMarker == "" {
+			return nil
+		}
+		marker = resp.NextMarker
+	}
+}
+
+// listByTags : List the children of a directory synthesized from the blobs matching the tag filter
+func (bb *BlockBlob) listByTags(matches *tagMatchSet, listPath string, marker *string, count int32) ([]*internal.ObjAttr, *string, error) {
+	start := ""
+	if marker != nil {
+		start = strings.TrimPrefix(*marker, tagMarkerPrefix)
+	}
+
+	entries, next := tagListEntries(matches.names, listPath, start, count)
+
+	// Matching blobs come with names and tags only, so fetch properties of the files in parallel
+	attrs := make([]*internal.ObjAttr, len(entries))
+	errs := make([]error, len(entries))
+	workers := make(chan struct{}, max(bb.Config.maxConcurrency, 1))
+	var wg sync.WaitGroup
+
+	for i, entry := range entries {
+		if entry.isDir {
+			attrs[i] = bb.createDirAttr(entry.name)
+			continue
+		}
+
+		wg.Add(1)
+		workers <- struct{}{}
+		go func(i int, name string) {
+			defer wg.Done()
+			attrs[i], errs[i] = bb.getBlobProperties(removePrefixPath(bb.Config.prefixPath, name), false)
+			<-workers
+		}(i, entry.name)
+	}
+	wg.Wait()
+
+	blobList := make([]*internal.ObjAttr, 0, len(entries))
+	for i, attr := range attrs {
+		if errs[i] == syscall.ENOENT {
+			// Deleted since the matching names were fetched
+			continue
+		} else if errs[i] != nil {
+			log.Err("BlockBlob::listByTags : Failed to get properties of %s [%s]", entries[i].name, errs[i].Error())
+			return nil, nil, errs[i]
+		}
+
+		// Service already matched the tags, changes since then are picked up on the next refresh
+		if !attr.IsDir() {
+			attr.Tags = matches.tags[entries[i].name]
+		}
+
+		blobList = append(blobList, attr)
+	}
+
+	if next == "" {
+		return blobList, nil, nil
+	}
+	next = tagMarkerPrefix + next
+	return blobList, &next, nil
+}
+
+// isTagIndexDir : Check whether a path without marker blob is a directory synthesized from the matching blobs
+func (bb *BlockBlob) isTagIndexDir(name string) bool {
+	matches, ok := bb.tagMatches()
+	return ok && tagIndexHasDir(matches.names, filepath.Join(bb.Config.prefixPath, name))
+}
@@ -43,8 +43,11 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
@@ -53,6 +56,7 @@ import (
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/service"
 	serviceBfs "github.com/Azure/azure-sdk-for-go/sdk/storage/azdatalake/service"
 	"github.com/Azure/azure-storage-fuse/v2/common"
@@ -118,10 +122,9 @@ func getAzStorageClientOptions(conf *AzStorageConfig) (azcore.ClientOptions, err
 	}
 
 	perRetryPolicies := []policy.Policy{}
-	if conf.capMbpsRead > 0 || conf.capIOps > 0 {
-		// Convert Mbps to Bytes/sec: 1 Mbps = (1024* 1024) / 8 = 131072 Bytes/sec
-		bytesPerSec := conf.capMbpsRead * 131072
-		perRetryPolicies = append(perRetryPolicies, newRateLimitingPolicy(bytesPerSec, conf.capIOps))
+	// Rate limiting policy only exists when some limit is configured
+	if conf.rateLimiter != nil {
+		perRetryPolicies = append(perRetryPolicies, conf.rateLimiter)
 	}
 
 	return azcore.ClientOptions{
@@ -133,6 +136,19 @@ func getAzStorageClientOptions(conf *AzStorageConfig) (azcore.ClientOptions, err
 	}, err
 }
 
+// getRateLimits : Limits of the rate limiting policy based on the config
+func (conf *AzStorageConfig) getRateLimits() rateLimits {
+	// Convert Mbps to Bytes/sec: 1 Mbps = (1024* 1024) / 8 = 131072 Bytes/sec
+	return rateLimits{
+		readBytesPerSec:   max(conf.capMbpsRead, 0) * 131072,
+		writeBytesPerSec:  max(conf.capMbpsWrite, 0) * 131072,
+		opsPerSec:         conf.capIOps,
+		listOpsPerSec:     conf.capIOpsList,
+		metadataOpsPerSec: conf.capIOpsMetadata,
+		dataOpsPerSec:     conf.capIOpsData,
+	}
+}
+
 // getAzBlobServiceClientOptions : Create azblob service client options based on the config
 func getAzBlobServiceClientOptions(conf *AzStorageConfig) (*service.ClientOptions, error) {
 	opts, err := getAzStorageClientOptions(conf)
@@ -240,6 +256,8 @@ const (
 	InvalidRange
 	BlobIsUnderLease
 	InvalidPermission
+	BlobLeaseConflict
+	ConditionNotMet
 )
 
 // For detailed error list refer below link,
@@ -257,10 +275,14 @@ func storeBlobErrToErr(err error) uint16 {
 			return ErrFileNotFound
 		case bloberror.InvalidRange:
 			return InvalidRange
-		case bloberror.LeaseIDMissing:
+		case bloberror.LeaseIDMissing, bloberror.LeaseIDMismatchWithBlobOperation:
 			return BlobIsUnderLease
+		case bloberror.LeaseAlreadyPresent:
+			return BlobLeaseConflict
 		case bloberror.InsufficientAccountPermissions, bloberror.AuthorizationPermissionMismatch:
 			return InvalidPermission
+		case bloberror.ConditionNotMet:
+			return ConditionNotMet
 		default:
 			return ErrUnknown
 		}
@@ -311,6 +333,131 @@ func parseMetadata(attr *internal.ObjAttr, metadata map[string]*string) {
 	}
 }
 
+// Only the "user." xattr namespace is stored in blob metadata, with the prefix stripped from the key
+const xattrUserPrefix = "user."
+
+// Attempts of a conditional metadata update before giving up on concurrent writers
+const maxMetadataUpdateAttempts = 3
+
+// isReservedMetadataKey : Metadata keys used by blobfuse itself can not be read or changed as xattrs
+func isReservedMetadataKey(key string) bool {
+	key = strings.ToLower(key)
+	return key == folderKey || key == symlinkKey || key == hardlinkKey
+}
+
+// isValidMetadataKey : Metadata keys have to be valid C# identifiers
+func isValidMetadataKey(key string) bool {
+	if key == "" || (key[0] >= '0' && key[0] <= '9') {
+		return false
+	}
+
+	for _, c := range key {
+		if !(c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')) {
+			return false
+		}
+	}
+	return true
+}
+
+// isValidMetadataValue : Metadata values travel as http headers so only printable ascii is allowed
+func isValidMetadataValue(value []byte) bool {
+	for _, c := range value {
+		if c < 0x20 || c > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// xattrToMetadataKey : Convert the name of an extended attribute to the metadata key storing it
+func xattrToMetadataKey(name string) (string, error) {
+	if !strings.HasPrefix(name, xattrUserPrefix) {
+		return "", syscall.ENOTSUP
+	}
+
+	key := strings.TrimPrefix(name, xattrUserPrefix)
+	if !isValidMetadataKey(key) {
+		return "", syscall.ENOTSUP
+	}
+
+	if isReservedMetadataKey(key) {
+		return "", syscall.EPERM
+	}
+
+	return key, nil
+}
+
+// findMetadataKey : Metadata keys are case insensitive, find the key as returned by the service
+func findMetadataKey(metadata map[string]*string, key string) (string, bool) {
+	for k, v := range metadata {
+		if v != nil && strings.EqualFold(k, key) {
+			return k, true
+		}
+	}
+	return "", false
+}
+
+// Blob index tags are exposed as "user.tag.<key>" xattrs, metadata keys can not contain a '.' so the names do not clash
+const xattrTagPrefix = xattrUserPrefix + "tag."
+
+const (
+	maxBlobTags        = 10
+	maxBlobTagKeyLen   = 128
+	maxBlobTagValueLen = 256
+)
+
+// isValidTagString : Tag keys and values allow alphanumerics, space and + - . / : = _
+func isValidTagString(str string) bool {
+	for _, c := range str {
+		if !((c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || strings.ContainsRune(" +-./:=_", c)) {
+			return false
+		}
+	}
+	return true
+}
+
+// isTagXattr : Check whether the extended attribute is a blob index tag
+func isTagXattr(name string) bool {
+	return strings.HasPrefix(name, xattrTagPrefix)
+}
+
+// xattrToTagKey : Convert the name of an extended attribute to the blob index tag storing it
+func xattrToTagKey(name string) (string, error) {
+	key := strings.TrimPrefix(name, xattrTagPrefix)
+	if key == "" || len(key) > maxBlobTagKeyLen || !isValidTagString(key) {
+		return "", syscall.EINVAL
+	}
+	return key, nil
+}
+
+// isValidTagValue : Check the value of an extended attribute can be stored in a blob index tag
+func isValidTagValue(value []byte) bool {
+	return len(value) <= maxBlobTagValueLen && isValidTagString(string(value))
+}
+
+// filterUsesTags : Check whether a blob filter has a condition on blob index tags
+func filterUsesTags(filter string) bool {
+	return tagFilterRegex.MatchString(filter)
+}
+
+// tagCondition : Build an x-ms-if-tags expression requiring the blob to carry exactly the given tag values.
+// Returns an empty string for no tags, the service has no way to express the absence of tags.
+func tagCondition(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	conditions := make([]string, 0, len(keys))
+	for _, k := range keys {
+		conditions = append(conditions, fmt.Sprintf("\"%s\" = '%s'", k, tags[k]))
+	}
+	return strings.Join(conditions, " AND ")
+}
+
+var tagFilterRegex = regexp.MustCompile(`(^|&&|\|\|)\s*tag\s*!?=`)
+
 //    ----------- Content-type handling  ---------------
 
 // ContentTypeMap : Store file extension to content-type mapping
@@ -640,20 +787,22 @@ func parseRangeHeader(rangeHeader string) (int64, error) {
 	return end - start + 1, nil
 }
 
-// func parseBlobTags(tags *container.BlobTags) map[string]string {
-
-// 	if tags == nil {
-// 		return nil
-// 	}
+// parseBlobTags : Convert blob index tags returned by the service to a map
+func parseBlobTags(tags *container.BlobTags) map[string]string {
+	if tags == nil {
+		return nil
+	}
 
-// 	blobtags := make(map[string]string)
-// 	for _, tag := range tags.BlobTagSet {
-// 		if tag != nil {
-// 			if tag.Key != nil {
-// 				blobtags[*tag.Key] = *tag.Value
-// 			}
-// 		}
-// 	}
+	blobtags := make(map[string]string)
+	for _, tag := range tags.BlobTagSet {
+		if tag != nil && tag.Key != nil {
+			value := ""
+			if tag.Value != nil {
+				value = *tag.Value
+			}
+			blobtags[*tag.Key] = value
+		}
+	}
 
-// 	return blobtags
-// }
+	return blobtags
+}
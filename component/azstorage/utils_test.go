@@ -34,14 +34,20 @@
 package azstorage
 
 import (
+	"errors"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
 	"github.com/Azure/azure-storage-fuse/v2/common"
 	"github.com/Azure/azure-storage-fuse/v2/common/log"
 	"github.com/stretchr/testify/assert"
@@ -542,6 +548,264 @@ func (s *utilsTestSuite) TestParseRangeHeader() {
 	}
 }
 
+func (s *utilsTestSuite) TestXattrToMetadataKey() {
+	assert := assert.New(s.T())
+
+	tests := []struct {
+		name string
+		key  string
+		err  error
+	}{
+		{"user.owner", "owner", nil},
+		{"user.Build_Id2", "Build_Id2", nil},
+		{"security.selinux", "", syscall.ENOTSUP},
+		{"trusted.abc", "", syscall.ENOTSUP},
+		{"user.", "", syscall.ENOTSUP},
+		{"user.1abc", "", syscall.ENOTSUP},
+		{"user.xdg.origin.url", "", syscall.ENOTSUP},
+		{"user.hdi_isfolder", "", syscall.EPERM},
+		{"user.IS_SYMLINK", "", syscall.EPERM},
+	}
+
+	for _, test := range tests {
+		key, err := xattrToMetadataKey(test.name)
+		assert.Equal(test.err, err, test.name)
+		assert.Equal(test.key, key, test.name)
+	}
+}
+
+func (s *utilsTestSuite) TestIsValidMetadataValue() {
+	assert := assert.New(s.T())
+
+	assert.True(isValidMetadataValue([]byte("some value 123")))
+	assert.True(isValidMetadataValue([]byte{}))
+	assert.False(isValidMetadataValue([]byte("line\nbreak")))
+	assert.False(isValidMetadataValue([]byte{0xff, 0x01}))
+}
+
+func (s *utilsTestSuite) TestParseBlobTags() {
+	assert := assert.New(s.T())
+
+	assert.Nil(parseBlobTags(nil))
+
+	tags := parseBlobTags(&container.BlobTags{BlobTagSet: []*blob.Tags{
+		{Key: to.Ptr("project"), Value: to.Ptr("alpha")},
+		{Key: to.Ptr("Empty"), Value: nil},
+		{Key: nil, Value: to.Ptr("orphan")},
+		nil,
+	}})
+	assert.Equal(map[string]string{"project": "alpha", "Empty": ""}, tags)
+}
+
+func (s *utilsTestSuite) TestXattrToTagKey() {
+	assert := assert.New(s.T())
+
+	tests := []struct {
+		name string
+		key  string
+		err  error
+	}{
+		{"user.tag.project", "project", nil},
+		{"user.tag.Team-A/sub:x=y_z.1 2", "Team-A/sub:x=y_z.1 2", nil},
+		{"user.tag.", "", syscall.EINVAL},
+		{"user.tag.a*b", "", syscall.EINVAL},
+		{"user.tag." + strings.Repeat("k", 129), "", syscall.EINVAL},
+	}
+
+	for _, test := range tests {
+		assert.True(isTagXattr(test.name), test.name)
+		key, err := xattrToTagKey(test.name)
+		assert.Equal(test.err, err, test.name)
+		assert.Equal(test.key, key, test.name)
+	}
+
+	assert.False(isTagXattr("user.tagged"))
+	assert.False(isTagXattr("user.owner"))
+}
+
+func (s *utilsTestSuite) TestTagCondition() {
+	assert := assert.New(s.T())
+
+	assert.Equal("", tagCondition(nil))
+	assert.Equal("\"project\" = 'alpha'", tagCondition(map[string]string{"project": "alpha"}))
+	assert.Equal("\"Empty\" = '' AND \"project\" = 'alpha' AND \"team\" = 'a b'",
+		tagCondition(map[string]string{"team": "a b", "project": "alpha", "Empty": ""}))
+}
+
+func (s *utilsTestSuite) TestIsValidTagValue() {
+	assert := assert.New(s.T())
+
+	assert.True(isValidTagValue([]byte("alpha")))
+	assert.True(isValidTagValue([]byte{}))
+	assert.True(isValidTagValue([]byte(strings.Repeat("v", 256))))
+	assert.False(isValidTagValue([]byte(strings.Repeat("v", 257))))
+	assert.False(isValidTagValue([]byte("a,b")))
+	assert.False(isValidTagValue([]byte("line\nbreak")))
+}
+
+func (s *utilsTestSuite) TestFilterUsesTags() {
+	assert := assert.New(s.T())
+
+	assert.True(filterUsesTags("tag=project:alpha"))
+	assert.True(filterUsesTags("size > 1000 && tag != project:alpha"))
+	assert.True(filterUsesTags("tier=hot || tag=project:alpha"))
+	assert.False(filterUsesTags("name=^tag=.*"))
+	assert.False(filterUsesTags("size > 1000 && tier=hot"))
+}
+
+func (s *utilsTestSuite) TestTagFilterOnList() {
+	assert := assert.New(s.T())
+
+	bb := &BlockBlob{}
+	assert.NoError(bb.SetFilter("tag=project:alpha"))
+	assert.True(bb.Config.blobTags)
+	assert.True(bb.listDetails.Tags)
+
+	newItem := func(name string, tags ...string) *container.BlobItem {
+		item := &container.BlobItem{
+			Name: to.Ptr(name),
+			Properties: &container.BlobProperties{
+				ContentLength: to.Ptr(int64(10)),
+				LastModified:  to.Ptr(time.Now()),
+			},
+		}
+		if len(tags) > 0 {
+			item.BlobTags = &container.BlobTags{}
+			for i := 0; i+1 < len(tags); i += 2 {
+				item.BlobTags.BlobTagSet = append(item.BlobTags.BlobTagSet, &blob.Tags{Key: to.Ptr(tags[i]), Value: to.Ptr(tags[i+1])})
+			}
+		}
+		return item
+	}
+
+	list, _, err := bb.processBlobItems([]*container.BlobItem{
+		newItem("a.txt", "project", "alpha", "owner", "x"),
+		newItem("b.txt", "project", "beta"),
+		newItem("c.txt"),
+	})
+	assert.NoError(err)
+	assert.Len(list, 1)
+	assert.Equal("a.txt", list[0].Name)
+	assert.Equal(map[string]string{"project": "alpha", "owner": "x"}, list[0].Tags)
+}
+
+func (s *utilsTestSuite) TestTagQueries() {
+	assert := assert.New(s.T())
+
+	queries, ok := tagQueries("tag=project:alpha")
+	assert.True(ok)
+	assert.Equal([]string{`"project" = 'alpha'`}, queries)
+
+	queries, ok = tagQueries("tag=project:alpha && tag=owner:x || tag=project:beta")
+	assert.True(ok)
+	assert.Equal([]string{`"project" = 'alpha' AND "owner" = 'x'`, `"project" = 'beta'`}, queries)
+
+	// Conditions the service can not evaluate
+	for _, filter := range []string{"", "tag!=project:alpha", "tag=project:alpha && size > 1000", "tier=hot || tag=project:alpha", "tag=pro'ject:alpha"} {
+		_, ok = tagQueries(filter)
+		assert.False(ok, filter)
+	}
+
+	bb := &BlockBlob{}
+	assert.NoError(bb.SetFilter("tag=project:alpha"))
+	assert.NotNil(bb.tagIndex)
+	assert.NoError(bb.SetFilter("tag=project:alpha && size > 1000"))
+	assert.Nil(bb.tagIndex)
+}
+
+func (s *utilsTestSuite) TestTagListEntries() {
+	assert := assert.New(s.T())
+
+	names := []string{"a.txt", "dir/b.txt", "dir/sub/c.txt", "dir/sub/d.txt", "dir2/e.txt", "dirx.txt"}
+	entryNames := func(entries []tagListEntry) []string {
+		list := make([]string, 0)
+		for _, e := range entries {
+			list = append(list, e.name)
+		}
+		return list
+	}
+
+	entries, next := tagListEntries(names, "", "", 100)
+	assert.Equal([]string{"a.txt", "dir/", "dir2/", "dirx.txt"}, entryNames(entries))
+	assert.True(entries[1].isDir)
+	assert.False(entries[3].isDir)
+	assert.Empty(next)
+
+	entries, next = tagListEntries(names, "dir/", "", 100)
+	assert.Equal([]string{"dir/b.txt", "dir/sub/"}, entryNames(entries))
+	assert.Empty(next)
+
+	// Prefix without trailing slash as used while looking up a single path
+	entries, _ = tagListEntries(names, "dir/sub", "", 100)
+	assert.Equal([]string{"dir/sub/"}, entryNames(entries))
+
+	// Paginate with the marker
+	entries, next = tagListEntries(names, "", "", 2)
+	assert.Equal([]string{"a.txt", "dir/"}, entryNames(entries))
+	assert.Equal("dir/", next)
+	entries, next = tagListEntries(names, "", next, 2)
+	assert.Equal([]string{"dir2/", "dirx.txt"}, entryNames(entries))
+	assert.Empty(next)
+
+	assert.True(tagIndexHasDir(names, "dir/sub"))
+	assert.True(tagIndexHasDir(names, "dir2/"))
+	assert.False(tagIndexHasDir(names, "dirx.txt"))
+	assert.False(tagIndexHasDir(names, "di"))
+}
+
+func (s *utilsTestSuite) TestTagIndexState() {
+	assert := assert.New(s.T())
+
+	matches := &tagMatchSet{names: []string{"dir/a.txt"}, tags: map[string]map[string]string{"dir/a.txt": {"project": "alpha"}}}
+	bb := &BlockBlob{}
+	assert.Nil(bb.tagIndex.last())
+
+	// Fresh matches are served without going to the service
+	bb.tagIndex = newTagIndex([]string{"\"project\" = 'alpha'"})
+	bb.tagIndex.matches = matches
+	bb.tagIndex.expiry = time.Now().Add(time.Minute)
+	got, ok := bb.tagMatches()
+	assert.True(ok)
+	assert.Equal(matches, got)
+	assert.True(bb.isTagIndexDir("dir"))
+
+	// Disabled index falls back to listing but keeps the matches for listings already paginating over them
+	bb.tagIndex.disabled = true
+	_, ok = bb.tagMatches()
+	assert.False(ok)
+	assert.Equal(matches, bb.tagIndex.last())
+}
+
+func (s *utilsTestSuite) TestIsTagQueryRejected() {
+	assert := assert.New(s.T())
+
+	assert.False(isTagQueryRejected(errors.New("connection reset")))
+	assert.True(isTagQueryRejected(&azcore.ResponseError{StatusCode: http.StatusForbidden, ErrorCode: "AuthorizationPermissionMismatch"}))
+	assert.True(isTagQueryRejected(&azcore.ResponseError{StatusCode: http.StatusConflict, ErrorCode: "FeatureNotSupportedForAccount"}))
+	assert.False(isTagQueryRejected(&azcore.ResponseError{StatusCode: http.StatusServiceUnavailable, ErrorCode: "ServerBusy"}))
+	assert.False(isTagQueryRejected(&azcore.ResponseError{StatusCode: http.StatusInternalServerError, ErrorCode: "InternalError"}))
+}
+
+func (s *utilsTestSuite) TestFindMetadataKey() {
+	assert := assert.New(s.T())
+
+	metadata := map[string]*string{
+		"Owner":        to.Ptr("abc"),
+		"Hdi_isfolder": to.Ptr("true"),
+		"Empty":        nil,
+	}
+
+	key, found := findMetadataKey(metadata, "owner")
+	assert.True(found)
+	assert.Equal("Owner", key)
+
+	_, found = findMetadataKey(metadata, "empty")
+	assert.False(found)
+
+	_, found = findMetadataKey(metadata, "missing")
+	assert.False(found)
+}
+
 func TestUtilsTestSuite(t *testing.T) {
 	suite.Run(t, new(utilsTestSuite))
 }
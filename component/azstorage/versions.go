@@ -0,0 +1,255 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package azstorage
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Azure/azure-storage-fuse/v2/common/log"
+	"github.com/Azure/azure-storage-fuse/v2/internal"
+)
+
+// When snapshots-dir is configured, versions and snapshots of blobs are exposed under a read-only virtual
+// directory at mount root. Below it the directories of the container are mirrored and every blob turns into
+// a directory holding its versions, named by version id, and its snapshots, named by snapshot time with the
+// prefix below. Blobs deleted while versioning is enabled are not listed but can be reached by their path.
+const (
+	snapshotVersionPrefix = "snapshot-"
+	versionCopyChunk      = 16 * 1024 * 1024 // bytes downloaded at once while copying a version to a file
+)
+
+// snapshotPath : Get the path relative to the snapshots directory, false if the path is outside of it
+func (az *AzStorage) snapshotPath(name string) (string, bool) {
+	if az.stConfig.snapshotsDir == "" {
+		return "", false
+	}
+
+	name = strings.TrimPrefix(internal.TruncateDirName(name), "/")
+	if name == az.stConfig.snapshotsDir {
+		return "", true
+	}
+	return strings.CutPrefix(name, az.stConfig.snapshotsDir+"/")
+}
+
+// isSnapshotPath : Check whether any of the paths lies in the read-only snapshots directory
+func (az *AzStorage) isSnapshotPath(names ...string) bool {
+	for _, name := range names {
+		if _, ok := az.snapshotPath(name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSnapshotsDir : Snapshots directory hides the path of the same name in the container, refuse to mount
+// when such a path exists
+func (az *AzStorage) checkSnapshotsDir() error {
+	if az.stConfig.snapshotsDir == "" {
+		return nil
+	}
+
+	_, err := az.storage.GetAttr(az.stConfig.snapshotsDir)
+	if err == nil {
+		return fmt.Errorf("snapshots-dir %s already exists in the container", az.stConfig.snapshotsDir)
+	} else if err != syscall.ENOENT {
+		log.Warn("AzStorage::checkSnapshotsDir : Failed to check %s in the container [%s]", az.stConfig.snapshotsDir, err.Error())
+		return nil
+	}
+
+	// Directories without a marker blob exist only as prefix of their blobs
+	children, _, err := az.storage.List(formatListDirName(az.stConfig.snapshotsDir), nil, 1)
+	if err != nil {
+		log.Warn("AzStorage::checkSnapshotsDir : Failed to list %s in the container [%s]", az.stConfig.snapshotsDir, err.Error())
+		return nil
+	} else if len(children) > 0 {
+		return fmt.Errorf("snapshots-dir %s already exists in the container", az.stConfig.snapshotsDir)
+	}
+
+	return nil
+}
+
+// snapshotDirAttr : Attributes of a directory in the snapshots tree
+func snapshotDirAttr(name string) *internal.ObjAttr {
+	attr := &internal.ObjAttr{
+		Path:  name,
+		Name:  path.Base(name),
+		Size:  4096,
+		Mode:  os.ModeDir,
+		Mtime: time.Now(),
+		Flags: internal.NewDirBitMap(),
+	}
+	attr.Atime = attr.Mtime
+	attr.Crtime = attr.Mtime
+	attr.Ctime = attr.Mtime
+	attr.Flags.Set(internal.PropFlagModeDefault)
+	return attr
+}
+
+// isVersionName : Version ids and snapshots are both named by a time, anything else can not be a version
+func isVersionName(version string) bool {
+	_, err := time.Parse(time.RFC3339Nano, strings.TrimPrefix(version, snapshotVersionPrefix))
+	return err == nil
+}
+
+// getVersionAttr : Get attributes of a version or snapshot of the blob
+func (az *AzStorage) getVersionAttr(name string, version string) (*internal.ObjAttr, error) {
+	if name == "." || !isVersionName(version) {
+		return nil, syscall.ENOENT
+	}
+
+	return az.storage.GetVersionAttr(name, version)
+}
+
+// getSnapshotAttr : Get attributes of a path in the snapshots directory
+func (az *AzStorage) getSnapshotAttr(name string, rel string) (*internal.ObjAttr, error) {
+	name = internal.TruncateDirName(name)
+	if rel == "" {
+		return snapshotDirAttr(name), nil
+	}
+
+	// Directories and blobs of the container are both directories here
+	_, err := az.storage.GetAttr(rel)
+	if err == nil {
+		return snapshotDirAttr(name), nil
+	} else if err != syscall.ENOENT {
+		return nil, err
+	}
+
+	attr, err := az.getVersionAttr(path.Dir(rel), path.Base(rel))
+	if err == nil {
+		attr.Path = name
+		return attr, nil
+	} else if err != syscall.ENOENT {
+		return nil, err
+	}
+
+	// Blob is gone but its versions are still around
+	versions, err := az.storage.ListVersions(rel)
+	if err == nil && len(versions) > 0 {
+		return snapshotDirAttr(name), nil
+	}
+	return nil, syscall.ENOENT
+}
+
+// streamSnapshotDir : List directories of the container or versions of a blob in the snapshots directory
+func (az *AzStorage) streamSnapshotDir(options internal.StreamDirOptions, rel string) ([]*internal.ObjAttr, string, error) {
+	log.Trace("AzStorage::streamSnapshotDir : Path %s, token %s", options.Name, options.Token)
+
+	isDir := rel == ""
+	if !isDir {
+		attr, err := az.storage.GetAttr(rel)
+		if err != nil && err != syscall.ENOENT {
+			return nil, "", err
+		}
+		isDir = err == nil && attr.IsDir()
+	}
+
+	if !isDir {
+		versions, err := az.storage.ListVersions(rel)
+		if err != nil {
+			log.Err("AzStorage::streamSnapshotDir : Failed to list versions of %s [%s]", rel, err.Error())
+			return nil, "", err
+		}
+
+		for _, attr := range versions {
+			attr.Path = path.Join(az.stConfig.snapshotsDir, attr.Path)
+		}
+		return versions, "", nil
+	}
+
+	// Every child of the directory, blob or not, is a directory here
+	list := make([]*internal.ObjAttr, 0)
+	marker := &options.Token
+	for len(list) == 0 {
+		children, next, err := az.storage.List(formatListDirName(rel), marker, options.Count)
+		if err != nil {
+			log.Err("AzStorage::streamSnapshotDir : Failed to read dir %s [%s]", rel, err.Error())
+			return nil, "", err
+		}
+
+		for _, attr := range children {
+			if attr.Path != hardlinkDir {
+				list = append(list, snapshotDirAttr(path.Join(az.stConfig.snapshotsDir, attr.Path)))
+			}
+		}
+
+		if next == nil || *next == "" {
+			return list, "", nil
+		}
+		marker = next
+	}
+
+	return list, *marker, nil
+}
+
+// readVersion : Read a range of the version or snapshot at the path relative to the snapshots directory
+func (az *AzStorage) readVersion(rel string, offset int64, length int64, data []byte) error {
+	return az.storage.ReadVersion(path.Dir(rel), path.Base(rel), offset, length, data)
+}
+
+// copyVersionToFile : Download the version or snapshot at the path relative to the snapshots directory
+func (az *AzStorage) copyVersionToFile(rel string, options internal.CopyToFileOptions) error {
+	count := options.Count
+	if count == 0 {
+		attr, err := az.getVersionAttr(path.Dir(rel), path.Base(rel))
+		if err != nil {
+			return err
+		}
+		count = max(attr.Size-options.Offset, 0)
+	}
+
+	data := make([]byte, min(count, versionCopyChunk))
+	for done := int64(0); done < count; {
+		length := min(count-done, int64(len(data)))
+		err := az.readVersion(rel, options.Offset+done, length, data)
+		if err != nil {
+			log.Err("AzStorage::copyVersionToFile : Failed to read %s [%s]", rel, err.Error())
+			return err
+		}
+
+		_, err = options.File.WriteAt(data[:length], done)
+		if err != nil {
+			log.Err("AzStorage::copyVersionToFile : Failed to write %s to file [%s]", rel, err.Error())
+			return err
+		}
+		done += length
+	}
+
+	return options.File.Truncate(count)
+}
@@ -0,0 +1,214 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package azstorage
+
+import (
+	"os"
+	"path"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/Azure/azure-storage-fuse/v2/internal"
+	"github.com/Azure/azure-storage-fuse/v2/internal/handlemap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// versionStorage : Storage serving the calls made for the snapshots directory from memory
+type versionStorage struct {
+	AzConnection
+	blobs    map[string]int64 // current blobs and their size
+	versions map[string][]string
+	lookups  int // versions looked up by name
+	read     string
+}
+
+func (vs *versionStorage) GetAttr(name string) (*internal.ObjAttr, error) {
+	if size, ok := vs.blobs[name]; ok {
+		return &internal.ObjAttr{Path: name, Name: path.Base(name), Size: size, Flags: internal.NewFileBitMap()}, nil
+	}
+	for blob := range vs.blobs {
+		if strings.HasPrefix(blob, name+"/") {
+			return &internal.ObjAttr{Path: name, Name: path.Base(name), Mode: os.ModeDir, Flags: internal.NewDirBitMap()}, nil
+		}
+	}
+	return nil, syscall.ENOENT
+}
+
+func (vs *versionStorage) List(prefix string, marker *string, count int32) ([]*internal.ObjAttr, *string, error) {
+	list := make([]*internal.ObjAttr, 0)
+	for blob := range vs.blobs {
+		if path.Dir(blob) == strings.TrimSuffix(prefix, "/") || (prefix == "" && !strings.Contains(blob, "/")) {
+			list = append(list, &internal.ObjAttr{Path: blob, Name: path.Base(blob), Flags: internal.NewFileBitMap()})
+		}
+	}
+	return list, nil, nil
+}
+
+func (vs *versionStorage) ListVersions(name string) ([]*internal.ObjAttr, error) {
+	list := make([]*internal.ObjAttr, 0)
+	for _, version := range vs.versions[name] {
+		list = append(list, &internal.ObjAttr{Path: path.Join(name, version), Name: version, Size: 10, Flags: internal.NewFileBitMap()})
+	}
+	return list, nil
+}
+
+func (vs *versionStorage) GetVersionAttr(name string, version string) (*internal.ObjAttr, error) {
+	vs.lookups++
+	for _, v := range vs.versions[name] {
+		if v == version {
+			return &internal.ObjAttr{Path: path.Join(name, version), Name: version, Size: 10, Flags: internal.NewFileBitMap()}, nil
+		}
+	}
+	return nil, syscall.ENOENT
+}
+
+func (vs *versionStorage) ReadVersion(name string, version string, offset int64, length int64, data []byte) error {
+	vs.read = name + "@" + version
+	return nil
+}
+
+type versionsTestSuite struct {
+	suite.Suite
+	az      *AzStorage
+	storage *versionStorage
+}
+
+func (s *versionsTestSuite) SetupTest() {
+	s.storage = &versionStorage{
+		blobs: map[string]int64{"a.txt": 5, "dir/b.txt": 7},
+		versions: map[string][]string{
+			"a.txt":        {"2024-05-01T10:00:00.0000000Z", snapshotVersionPrefix + "2024-05-02T10:00:00.0000000Z"},
+			"dir/gone.txt": {"2024-05-03T10:00:00.0000000Z"},
+		},
+	}
+	s.az = &AzStorage{storage: s.storage}
+	s.az.stConfig.snapshotsDir = ".snapshots"
+}
+
+func (s *versionsTestSuite) TestGetAttr() {
+	assert := assert.New(s.T())
+
+	for _, name := range []string{".snapshots", ".snapshots/dir", ".snapshots/a.txt", ".snapshots/dir/gone.txt"} {
+		attr, err := s.az.GetAttr(internal.GetAttrOptions{Name: name})
+		assert.NoError(err, name)
+		assert.True(attr.IsDir(), name)
+		assert.Equal(name, attr.Path)
+	}
+
+	attr, err := s.az.GetAttr(internal.GetAttrOptions{Name: ".snapshots/a.txt/2024-05-01T10:00:00.0000000Z"})
+	assert.NoError(err)
+	assert.False(attr.IsDir())
+	assert.Equal(".snapshots/a.txt/2024-05-01T10:00:00.0000000Z", attr.Path)
+	assert.EqualValues(10, attr.Size)
+
+	_, err = s.az.GetAttr(internal.GetAttrOptions{Name: ".snapshots/a.txt/2020-01-01T10:00:00.0000000Z"})
+	assert.Equal(syscall.ENOENT, err)
+	_, err = s.az.GetAttr(internal.GetAttrOptions{Name: ".snapshots/missing"})
+	assert.Equal(syscall.ENOENT, err)
+
+	// Names that are not a time are never looked up as versions
+	lookups := s.storage.lookups
+	_, err = s.az.GetAttr(internal.GetAttrOptions{Name: ".snapshots/a.txt/.hidden"})
+	assert.Equal(syscall.ENOENT, err)
+	assert.Equal(lookups, s.storage.lookups)
+}
+
+func (s *versionsTestSuite) TestCheckSnapshotsDir() {
+	assert := assert.New(s.T())
+
+	assert.NoError(s.az.checkSnapshotsDir())
+
+	s.storage.blobs[".snapshots/c.txt"] = 3
+	assert.Error(s.az.checkSnapshotsDir())
+	delete(s.storage.blobs, ".snapshots/c.txt")
+
+	s.storage.blobs[".snapshots"] = 0
+	assert.Error(s.az.checkSnapshotsDir())
+
+	s.az.stConfig.snapshotsDir = ""
+	assert.NoError(s.az.checkSnapshotsDir())
+}
+
+func (s *versionsTestSuite) TestStreamDir() {
+	assert := assert.New(s.T())
+
+	list, token, err := s.az.streamSnapshotDir(internal.StreamDirOptions{Name: ".snapshots/a.txt"}, "a.txt")
+	assert.NoError(err)
+	assert.Empty(token)
+	assert.Len(list, 2)
+	assert.Equal(".snapshots/a.txt/2024-05-01T10:00:00.0000000Z", list[0].Path)
+	assert.Equal(snapshotVersionPrefix+"2024-05-02T10:00:00.0000000Z", list[1].Name)
+	assert.False(list[0].IsDir())
+
+	list, _, err = s.az.streamSnapshotDir(internal.StreamDirOptions{Name: ".snapshots/dir"}, "dir")
+	assert.NoError(err)
+	assert.Len(list, 1)
+	assert.Equal(".snapshots/dir/b.txt", list[0].Path)
+	assert.True(list[0].IsDir())
+}
+
+func (s *versionsTestSuite) TestReadOnly() {
+	assert := assert.New(s.T())
+	name := ".snapshots/a.txt/2024-05-01T10:00:00.0000000Z"
+
+	_, err := s.az.CreateFile(internal.CreateFileOptions{Name: ".snapshots/a.txt/new"})
+	assert.Equal(syscall.EROFS, err)
+	assert.Equal(syscall.EROFS, s.az.DeleteFile(internal.DeleteFileOptions{Name: name}))
+	assert.Equal(syscall.EROFS, s.az.RenameFile(internal.RenameFileOptions{Src: name, Dst: "a.txt"}))
+	assert.Equal(syscall.EROFS, s.az.CreateDir(internal.CreateDirOptions{Name: ".snapshots/new"}))
+	assert.Equal(syscall.EROFS, s.az.TruncateFile(internal.TruncateFileOptions{Name: name}))
+	_, err = s.az.OpenFile(internal.OpenFileOptions{Name: name, Flags: os.O_RDWR})
+	assert.Equal(syscall.EROFS, err)
+
+	handle, err := s.az.OpenFile(internal.OpenFileOptions{Name: name, Flags: os.O_RDONLY})
+	assert.NoError(err)
+	assert.EqualValues(10, handle.Size)
+}
+
+func (s *versionsTestSuite) TestReadVersion() {
+	assert := assert.New(s.T())
+
+	handle := handlemap.NewHandle(".snapshots/a.txt/" + snapshotVersionPrefix + "2024-05-02T10:00:00.0000000Z")
+	handle.Size = 10
+	length, err := s.az.ReadInBuffer(&internal.ReadInBufferOptions{Handle: handle, Data: make([]byte, 4)})
+	assert.NoError(err)
+	assert.Equal(4, length)
+	assert.Equal("a.txt@"+snapshotVersionPrefix+"2024-05-02T10:00:00.0000000Z", s.storage.read)
+}
+
+func TestVersionsTestSuite(t *testing.T) {
+	suite.Run(t, new(versionsTestSuite))
+}
@@ -34,8 +34,11 @@
 package block_cache
 
 import (
+	"bytes"
 	"container/list"
+	"encoding/base64"
 	"fmt"
+	"sync/atomic"
 	"syscall"
 
 	"github.com/Azure/azure-storage-fuse/v2/common"
@@ -67,6 +70,7 @@ type Block struct {
 	flags  common.BitMap64 // Various states of the block
 	data   []byte          // Data read from blob
 	node   *list.Element   // node representation of this block in the list inside handle
+	refs   atomic.Int32    // Number of holders of a shared block, see BlockPool.Acquire
 }
 
 type blockInfo struct {
@@ -75,6 +79,22 @@ type blockInfo struct {
 	size      uint64 // length of data in block
 }
 
+// Prefix of the id of a block staged with only zeros, this lets holes be identified from the block list later
+var zeroBlockIDPrefix = []byte("bfzerobk")
+
+// newZeroBlockID generates a random block id carrying the zero block prefix
+func newZeroBlockID(length int64) string {
+	id := common.NewUUIDWithLength(length)
+	copy(id, zeroBlockIDPrefix)
+	return base64.StdEncoding.EncodeToString(id)
+}
+
+// isZeroBlockID checks whether the block id was generated for a zero block
+func isZeroBlockID(id string) bool {
+	decoded, err := base64.StdEncoding.DecodeString(id)
+	return err == nil && bytes.HasPrefix(decoded, zeroBlockIDPrefix)
+}
+
 // AllocateBlock creates a new memory mapped buffer for the given size
 func AllocateBlock(size uint64) (*Block, error) {
 	if size == 0 {
@@ -54,6 +54,7 @@ import (
 	"github.com/Azure/azure-storage-fuse/v2/common/log"
 	"github.com/Azure/azure-storage-fuse/v2/internal"
 	"github.com/Azure/azure-storage-fuse/v2/internal/handlemap"
+	"github.com/Azure/azure-storage-fuse/v2/internal/stats_manager"
 	"github.com/vibhansa-msft/tlru"
 )
 
@@ -88,6 +89,13 @@ type BlockCache struct {
 	stream          *Stream
 	lazyWrite       bool           // Flag to indicate if lazy write is enabled
 	fileCloseOpt    sync.WaitGroup // Wait group to wait for all async close operations to complete
+	persistCache    bool           // Flag to indicate if disk blocks are retained across remounts
+	retainDisk      bool           // Flag to indicate disk policy is being stopped and evicted blocks stay on disk
+	sharedCache     bool           // Flag to indicate downloaded blocks are shared across handles
+	sharedPath      string         // Directory where blocks are shared with other mounts on this node
+	sharedSize      uint64         // Size of the shared directory
+	sharedStore     *sharedStore   // Store of blocks shared across handles
+	zeroPerOffset   bool           // Flag to indicate each hole gets a zero block of its own instead of sharing one
 }
 
 // Structure defining your config parameters
@@ -102,6 +110,10 @@ type BlockCacheOptions struct {
 	PrefetchOnOpen bool    `config:"prefetch-on-open" yaml:"prefetch-on-open,omitempty"`
 	Consistency    bool    `config:"consistency" yaml:"consistency,omitempty"`
 	CleanupOnStart bool    `config:"cleanup-on-start" yaml:"cleanup-on-start,omitempty"`
+	PersistCache   bool    `config:"persist-cache" yaml:"persist-cache,omitempty"`
+	SharedCache    bool    `config:"shared-cache" yaml:"shared-cache,omitempty"`
+	SharedPath     string  `config:"shared-path" yaml:"shared-path,omitempty"`
+	SharedSizeMB   uint64  `config:"shared-size-mb" yaml:"shared-size-mb,omitempty"`
 }
 
 const (
@@ -117,9 +129,22 @@ const (
 	MAX_BLOCKS              = 50000
 )
 
+const (
+	poolUsage     = "Block Pool Usage Percent"
+	poolMaxBlocks = "Block Pool Max Blocks"
+	sharedServed  = "Blocks served from shared store"
+
+	openFile     = "OpenFile"
+	readInBuffer = "ReadInBuffer"
+	writeFile    = "WriteFile"
+	flushFile    = "FlushFile"
+)
+
 // Verification to check satisfaction criteria with Component Interface
 var _ internal.Component = &BlockCache{}
 
+var blockCacheStatsCollector *stats_manager.StatsCollector
+
 func (bc *BlockCache) Name() string {
 	return compName
 }
@@ -150,6 +175,16 @@ func (bc *BlockCache) Start(ctx context.Context) error {
 		return fmt.Errorf("config error in %s [failed to init thread pool]", bc.Name())
 	}
 
+	// A quarter of the pool can be held by the shared store, rest is left for the handles
+	if bc.sharedCache {
+		var account, container, prefixPath string
+		_ = config.UnmarshalKey("azstorage.account-name", &account)
+		_ = config.UnmarshalKey("azstorage.container", &container)
+		_ = config.UnmarshalKey("azstorage.subdirectory", &prefixPath)
+		bc.sharedStore = newSharedStore(bc.blockPool, account+"/"+container, strings.Trim(prefixPath, "/"), bc.blockSize,
+			max(1, int(bc.blockPool.maxBlocks/4)), bc.sharedPath, bc.sharedSize)
+	}
+
 	// Start the thread pool and keep it ready for download
 	log.Debug("BlockCache::Start : Starting thread pool")
 	bc.threadPool.Start()
@@ -161,8 +196,17 @@ func (bc *BlockCache) Start(ctx context.Context) error {
 			log.Err("BlockCache::Start : failed to start diskpolicy [%s]", err.Error())
 			return fmt.Errorf("failed to start  disk-policy for block-cache")
 		}
+
+		if bc.persistCache {
+			bc.reloadDiskCache()
+		}
 	}
 
+	// create stats collector for block cache, pool usage is read whenever metrics are scraped
+	blockCacheStatsCollector = stats_manager.NewStatsCollector(bc.Name())
+	blockCacheStatsCollector.GaugeFunc(poolUsage, func() int64 { return int64(bc.blockPool.Usage()) })
+	blockCacheStatsCollector.GaugeFunc(poolMaxBlocks, func() int64 { return int64(bc.blockPool.maxBlocks) })
+
 	return nil
 }
 
@@ -179,10 +223,20 @@ func (bc *BlockCache) Stop() error {
 	// Wait for thread pool to stop
 	bc.threadPool.Stop()
 
-	// Clear the disk cache on exit
+	if bc.sharedStore != nil {
+		bc.sharedStore.purge()
+	}
+
+	blockCacheStatsCollector.Destroy()
+
+	// Clear the disk cache on exit, unless it is meant to be reused by the next mount
 	if bc.tmpPath != "" {
+		// Stopping the disk policy evicts every node, keep the blocks if they are meant for the next mount
+		bc.retainDisk = bc.persistCache
 		_ = bc.diskPolicy.Stop()
-		_ = common.TempCacheCleanup(bc.tmpPath)
+		if !bc.persistCache {
+			_ = common.TempCacheCleanup(bc.tmpPath)
+		}
 	}
 
 	return nil
@@ -312,7 +366,8 @@ func (bc *BlockCache) Configure(_ bool) error {
 			}
 		}
 
-		if !common.IsDirectoryEmpty(bc.tmpPath) {
+		bc.persistCache = conf.PersistCache
+		if !common.IsDirectoryEmpty(bc.tmpPath) && !bc.persistCache {
 			log.Err("BlockCache: config error %s directory is not empty", bc.tmpPath)
 			return fmt.Errorf("config error in %s [%s]", bc.Name(), "temp directory not empty")
 		}
@@ -323,6 +378,32 @@ func (bc *BlockCache) Configure(_ bool) error {
 		}
 	}
 
+	// Directory shared with other mounts implies sharing within this mount as well
+	bc.sharedPath = common.ExpandPath(conf.SharedPath)
+	bc.sharedCache = conf.SharedCache || bc.sharedPath != ""
+	if bc.sharedPath != "" {
+		if bc.sharedPath == bc.tmpPath {
+			log.Err("BlockCache: config error [shared-path is same as disk cache path]")
+			return fmt.Errorf("config error in %s error [shared-path is same as disk cache path]", bc.Name())
+		}
+
+		err = os.MkdirAll(bc.sharedPath, os.FileMode(0777))
+		if err != nil {
+			log.Err("BlockCache: config error creating shared path [%s]", err.Error())
+			return fmt.Errorf("config error in %s [%s]", bc.Name(), err.Error())
+		}
+
+		bc.sharedSize = bc.getDefaultDiskSize(bc.sharedPath)
+		if config.IsSet(compName + ".shared-size-mb") {
+			bc.sharedSize = conf.SharedSizeMB * _1MB
+		}
+	}
+
+	// Encryption binds each sealed block to its offset, so one staged zero block can not fill multiple holes
+	var components []string
+	_ = config.UnmarshalKey("components", &components)
+	bc.zeroPerOffset = slices.Contains(components, "encryption")
+
 	if (uint64(bc.prefetch) * uint64(bc.blockSize)) > bc.memSize {
 		log.Err("BlockCache::Configure : config error [memory limit too low for configured prefetch]")
 		return fmt.Errorf("config error in %s [memory limit too low for configured prefetch]", bc.Name())
@@ -337,9 +418,11 @@ func (bc *BlockCache) Configure(_ bool) error {
 	}
 
 	log.Crit("BlockCache::Configure : block size %v, mem size %v, worker %v, prefetch %v, disk path %v, max size %v, "+
-		"disk timeout %v, prefetch-on-open %t, maxDiskUsageHit %v, noPrefetch %v, consistency %v, lazy-write: %v, cleanup-on-start %t",
+		"disk timeout %v, prefetch-on-open %t, maxDiskUsageHit %v, noPrefetch %v, consistency %v, lazy-write: %v, cleanup-on-start %t, persist-cache %t, "+
+		"shared-cache %t, shared-path %v, shared-size %v",
 		bc.blockSize, bc.memSize, bc.workers, bc.prefetch, bc.tmpPath, bc.diskSize,
-		bc.diskTimeout, bc.prefetchOnOpen, bc.maxDiskUsageHit, bc.noPrefetch, bc.consistency, bc.lazyWrite, conf.CleanupOnStart)
+		bc.diskTimeout, bc.prefetchOnOpen, bc.maxDiskUsageHit, bc.noPrefetch, bc.consistency, bc.lazyWrite, conf.CleanupOnStart, bc.persistCache,
+		bc.sharedCache, bc.sharedPath, bc.sharedSize)
 
 	return nil
 }
@@ -392,6 +475,7 @@ func (bc *BlockCache) CreateFile(options internal.CreateFileOptions) (*handlemap
 func (bc *BlockCache) OpenFile(options internal.OpenFileOptions) (*handlemap.Handle, error) {
 	log.Trace("BlockCache::OpenFile : name=%s, flags=%s, mode=%s",
 		options.Name, common.PrettyOpenFlags(options.Flags), options.Mode)
+	defer blockCacheStatsCollector.TimeOperation(openFile)()
 
 	attr, err := bc.NextComponent().GetAttr(internal.GetAttrOptions{Name: options.Name})
 	if err != nil {
@@ -483,11 +567,15 @@ func (bc *BlockCache) prepareHandleForBlockCache(handle *handlemap.Handle) {
 	// Set next offset to download as 0
 	// We may not download this if first read starts with some other offset
 	handle.SetValue("#", (uint64)(0))
+
+	// Access history to adapt prefetching to the way this handle is read
+	handle.SetValue("readahead", newReadAhead(bc.prefetch))
 }
 
 // FlushFile: Flush the local file to storage
 func (bc *BlockCache) FlushFile(options internal.FlushFileOptions) error {
 	log.Trace("BlockCache::FlushFile : handle=%d, path=%s", options.Handle.ID, options.Handle.Path)
+	defer blockCacheStatsCollector.TimeOperation(flushFile)()
 
 	if bc.lazyWrite && !options.CloseInProgress {
 		// As lazy-write is enable, upload will be scheduled when file is closed.
@@ -577,6 +665,8 @@ func (bc *BlockCache) getBlockSize(fileSize uint64, block *Block) uint64 {
 
 // ReadInBuffer: Read the file into a buffer
 func (bc *BlockCache) ReadInBuffer(options *internal.ReadInBufferOptions) (int, error) {
+	defer blockCacheStatsCollector.TimeOperation(readInBuffer)()
+
 	if options.Offset >= options.Handle.Size {
 		// EOF reached so early exit
 		return 0, io.EOF
@@ -590,6 +680,8 @@ func (bc *BlockCache) ReadInBuffer(options *internal.ReadInBufferOptions) (int,
 
 	// Keep getting next blocks until you read the request amount of data
 	dataRead := int(0)
+	defer func() { blockCacheStatsCollector.AddBytes(stats_manager.BytesRead, int64(dataRead)) }()
+
 	for dataRead < len(options.Data) {
 		block, err := bc.getBlock(options.Handle, uint64(options.Offset))
 		if err != nil {
@@ -658,7 +750,13 @@ func (bc *BlockCache) getBlock(handle *handlemap.Handle, readoffset uint64) (*Bl
 
 	// Check the given block index is already available or not
 	index := bc.getBlockIndex(readoffset)
+	ra := getReadAhead(handle)
+	if ra != nil {
+		ra.observe(int64(index))
+	}
+
 	node, found := handle.GetValue(fmt.Sprintf("%v", index))
+	blockCacheStatsCollector.CacheLookup(found)
 	if !found {
 
 		// block is not present in the buffer list, check if it is uncommitted
@@ -708,7 +806,10 @@ func (bc *BlockCache) getBlock(handle *handlemap.Handle, readoffset uint64) (*Bl
 			}
 		} else {
 			// This is a case of random read so increment the random read count
-			handle.OptCnt++
+			// unless the reads follow a stride or go backwards, prefetching follows those instead
+			if ra == nil || !ra.predictable() {
+				handle.OptCnt++
+			}
 
 			log.Debug("BlockCache::getBlock : Unable to get block %v=>%s (offset %v, index %v) Random %v", handle.ID, handle.Path, readoffset, index, handle.OptCnt)
 
@@ -745,10 +846,14 @@ func (bc *BlockCache) getBlock(handle *handlemap.Handle, readoffset uint64) (*Bl
 
 			// Download complete and you are first reader of this block
 			if !bc.noPrefetch && handle.OptCnt <= MIN_RANDREAD {
-				// So far this file has been read sequentially so prefetch more
+				// So far this file has been read in a predictable way so prefetch more
 				val, _ := handle.GetValue("#")
-				if int64(val.(uint64)*bc.blockSize) < handle.Size {
-					_ = bc.startPrefetch(handle, val.(uint64), true)
+				next, ok := val.(uint64), true
+				if ra != nil {
+					next, ok = ra.next(next)
+				}
+				if ok && int64(next*bc.blockSize) < handle.Size {
+					_ = bc.startPrefetch(handle, next, true)
 				}
 			}
 
@@ -855,8 +960,18 @@ func (bc *BlockCache) startPrefetch(handle *handlemap.Handle, index uint64, pref
 		cnt = 1
 	} else {
 		// This handle is having sequential reads so far
-		// Allocate more buffers if required until we hit the prefetch count limit
-		for ; currentCnt < int(bc.prefetch) && cnt < MIN_PREFETCH; currentCnt++ {
+		// Allocate more buffers if required until we hit the prefetch window
+		window := bc.prefetch
+		if ra := getReadAhead(handle); ra != nil {
+			window = ra.adjust(bc.blockPool.Usage())
+		}
+
+		if currentCnt > int(window) && prefetch {
+			// Window has shrunk due to pool pressure, give back the blocks which are already consumed
+			currentCnt -= bc.releaseCookedBlocks(handle, currentCnt-int(window))
+		}
+
+		for ; currentCnt < int(window) && cnt < MIN_PREFETCH; currentCnt++ {
 			block := bc.blockPool.TryGet()
 			if block != nil {
 				block.node = handle.Buffers.Cooked.PushFront(block)
@@ -894,7 +1009,16 @@ func (bc *BlockCache) startPrefetch(handle *handlemap.Handle, index uint64, pref
 			if err != nil {
 				return err
 			}
-			index++
+
+			next, ok := index+1, true
+			if ra := getReadAhead(handle); ra != nil {
+				next, ok = ra.step(index)
+			}
+			if !ok {
+				// Pattern runs past the start of the file
+				return nil
+			}
+			index = next
 		}
 	}
 
@@ -953,6 +1077,9 @@ func (bc *BlockCache) refreshBlock(handle *handlemap.Handle, index uint64, prefe
 		// Add this entry to handle map so that others can refer to the same block if required
 		handle.SetValue(fmt.Sprintf("%v", index), block)
 		handle.SetValue("#", (index + 1))
+		if ra := getReadAhead(handle); ra != nil {
+			ra.setCursor(index)
+		}
 
 		bc.lineupDownload(handle, block, prefetch)
 	}
@@ -967,13 +1094,21 @@ func (bc *BlockCache) lineupDownload(handle *handlemap.Handle, block *Block, pre
 	if found {
 		Etag = IEtag.(string)
 	}
+	priority := internal.IOPriorityForeground
+	if prefetch {
+		priority = internal.IOPriorityPrefetch
+	}
+
 	item := &workItem{
 		handle:   handle,
 		block:    block,
 		prefetch: prefetch,
+		priority: priority,
 		failCnt:  0,
 		upload:   false,
 		ETag:     Etag,
+		queued:   time.Now(),
+		ra:       getReadAhead(handle),
 	}
 
 	// Remove this block from free block list and add to in-process list
@@ -982,12 +1117,12 @@ func (bc *BlockCache) lineupDownload(handle *handlemap.Handle, block *Block, pre
 	block.flags.Set(BlockFlagDownloading)
 
 	// Send the work item to worker pool to schedule download
-	bc.threadPool.Schedule(!prefetch, item)
+	bc.threadPool.Schedule(item)
 }
 
 // download : Method to download the given amount of data
 func (bc *BlockCache) download(item *workItem) {
-	fileName := fmt.Sprintf("%s::%v", item.handle.Path, item.block.id)
+	fileName := diskBlockName(item.handle.Path, item.block.id)
 
 	// filename_blockindex is the key for the lock
 	// this ensure that at a given time a block from a file is downloaded only once across all open handles
@@ -1013,7 +1148,11 @@ func (bc *BlockCache) download(item *workItem) {
 		localPath = filepath.Join(bc.tmpPath, fileName)
 		_, err := os.Stat(localPath)
 
-		if err == nil {
+		if err == nil && bc.isBlockStale(localPath, item.ETag) {
+			// Block on disk belongs to an older version of the blob or another block size, discard it and go to the container
+			log.Debug("BlockCache::download : Discarding stale disk block %s", fileName)
+			_ = os.Remove(localPath)
+		} else if err == nil {
 			// If file exists then read the block from the local file
 			f, err := os.Open(localPath)
 			if err != nil {
@@ -1044,6 +1183,8 @@ func (bc *BlockCache) download(item *workItem) {
 					// We have read the data from disk so there is no need to go over network
 					// Just mark the block that download is complete
 					if successfulRead {
+						bc.touchDiskBlock(localPath, diskNode.(*list.Element))
+						item.reportDownload()
 						item.block.Ready(BlockStatusDownloaded)
 						return
 					}
@@ -1052,13 +1193,44 @@ func (bc *BlockCache) download(item *workItem) {
 		}
 	}
 
+	// Another handle, or another mount sharing the directory, may already have this block
+	var sb *sharedBlock
+	if bc.sharedStore != nil && item.ETag != "" {
+		var owner bool
+		key := bc.sharedStore.key(item.handle.Path, item.ETag, item.block.id)
+		sb, owner = bc.sharedStore.lookup(key)
+
+		if !owner {
+			// Someone else is downloading or has downloaded this block, just copy it
+			n, ok := bc.sharedStore.wait(sb, item.block.data)
+			sb = nil
+			if ok && n > 0 {
+				bc.servedShared(item)
+				return
+			}
+		} else if n, ok := bc.sharedStore.readDisk(key, item.block.data[:bc.getBlockSize(uint64(item.handle.Size), item.block)]); ok {
+			bc.sharedStore.publish(sb, item.block.data[:n])
+			bc.servedShared(item)
+			return
+		}
+	}
+
+	published := false
+	defer func() {
+		// Download did not succeed so let the waiters fetch the block on their own
+		if sb != nil && !published {
+			bc.sharedStore.abandon(sb)
+		}
+	}()
+
 	var etag string
 	// If file does not exists then download the block from the container
 	n, err := bc.NextComponent().ReadInBuffer(&internal.ReadInBufferOptions{
-		Handle: item.handle,
-		Offset: int64(item.block.offset),
-		Data:   item.block.data,
-		Etag:   &etag,
+		Handle:   item.handle,
+		Offset:   int64(item.block.offset),
+		Data:     item.block.data,
+		Etag:     &etag,
+		Priority: item.priority,
 	})
 
 	if item.failCnt > MAX_FAIL_CNT {
@@ -1073,13 +1245,13 @@ func (bc *BlockCache) download(item *workItem) {
 		// Fail to read the data so just reschedule this request
 		log.Err("BlockCache::download : Failed to read %v=>%s from offset %v [%s]", item.handle.ID, item.handle.Path, item.block.id, err.Error())
 		item.failCnt++
-		bc.threadPool.Schedule(false, item)
+		bc.threadPool.Reschedule(item)
 		return
 	} else if n == 0 {
 		// No data read so just reschedule this request
 		log.Err("BlockCache::download : Failed to read %v=>%s from offset %v [0 bytes read]", item.handle.ID, item.handle.Path, item.block.id)
 		item.failCnt++
-		bc.threadPool.Schedule(false, item)
+		bc.threadPool.Reschedule(item)
 		return
 	}
 
@@ -1093,6 +1265,11 @@ func (bc *BlockCache) download(item *workItem) {
 		}
 	}
 
+	if sb != nil {
+		bc.sharedStore.publish(sb, item.block.data[:n])
+		published = true
+	}
+
 	if bc.tmpPath != "" {
 		err := os.MkdirAll(filepath.Dir(localPath), 0777)
 		if err != nil {
@@ -1112,6 +1289,11 @@ func (bc *BlockCache) download(item *workItem) {
 			f.Close()
 			bc.diskPolicy.Refresh(diskNode.(*list.Element))
 
+			if etag == "" {
+				etag = item.ETag
+			}
+			bc.setBlockETag(localPath, etag)
+
 			// If user has enabled consistency check then compute the md5sum and save it in xattr
 			if bc.consistency {
 				hash := common.GetCRC64(item.block.data, n)
@@ -1124,6 +1306,7 @@ func (bc *BlockCache) download(item *workItem) {
 	}
 
 	// Just mark the block that download is complete
+	item.reportDownload()
 	item.block.Ready(BlockStatusDownloaded)
 }
 
@@ -1153,6 +1336,7 @@ func checkBlockConsistency(blockCache *BlockCache, item *workItem, numberOfBytes
 
 // WriteFile: Write to the local file
 func (bc *BlockCache) WriteFile(options *internal.WriteFileOptions) (int, error) {
+	defer blockCacheStatsCollector.TimeOperation(writeFile)()
 	// log.Debug("BlockCache::WriteFile : Writing %v bytes from %s", len(options.Data), options.Handle.Path)
 
 	options.Handle.Lock()
@@ -1162,6 +1346,8 @@ func (bc *BlockCache) WriteFile(options *internal.WriteFileOptions) (int, error)
 
 	// Keep getting next blocks until you read the request amount of data
 	dataWritten := int(0)
+	defer func() { blockCacheStatsCollector.AddBytes(stats_manager.BytesWritten, int64(dataWritten)) }()
+
 	for dataWritten < len(options.Data) {
 		block, err := bc.getOrCreateBlock(options.Handle, uint64(options.Offset))
 		if err != nil {
@@ -1418,6 +1604,7 @@ func (bc *BlockCache) lineupUpload(handle *handlemap.Handle, block *Block, listM
 		handle:   handle,
 		block:    block,
 		prefetch: false,
+		priority: internal.IOPriorityUpload,
 		failCnt:  0,
 		upload:   true,
 		blockId:  id,
@@ -1430,8 +1617,8 @@ func (bc *BlockCache) lineupUpload(handle *handlemap.Handle, block *Block, listM
 	// Remove this block from free block list and add to in-process list
 	bc.addToCooked(handle, block)
 
-	// Send the work item to worker pool to schedule download
-	bc.threadPool.Schedule(false, item)
+	// Send the work item to worker pool to schedule upload
+	bc.threadPool.Schedule(item)
 }
 
 func (bc *BlockCache) waitAndFreeUploadedBlocks(handle *handlemap.Handle, cnt int) {
@@ -1479,7 +1666,7 @@ func (bc *BlockCache) waitAndFreeUploadedBlocks(handle *handlemap.Handle, cnt in
 
 // upload : Method to stage the given amount of data
 func (bc *BlockCache) upload(item *workItem) {
-	fileName := fmt.Sprintf("%s::%v", item.handle.Path, item.block.id)
+	fileName := diskBlockName(item.handle.Path, item.block.id)
 
 	// filename_blockindex is the key for the lock
 	// this ensure that at a given time a block from a file is downloaded only once across all open handles
@@ -1489,10 +1676,11 @@ func (bc *BlockCache) upload(item *workItem) {
 	blockSize := bc.getBlockSize(uint64(item.handle.Size), item.block)
 	// This block is updated so we need to stage it now
 	err := bc.NextComponent().StageData(internal.StageDataOptions{
-		Name:   item.handle.Path,
-		Data:   item.block.data[0:blockSize],
-		Offset: uint64(item.block.offset),
-		Id:     item.blockId})
+		Name:     item.handle.Path,
+		Data:     item.block.data[0:blockSize],
+		Offset:   uint64(item.block.offset),
+		Id:       item.blockId,
+		Priority: item.priority})
 	if err != nil {
 		// Fail to write the data so just reschedule this request
 		log.Err("BlockCache::upload : Failed to write %v=>%s from offset %v [%s]", item.handle.ID, item.handle.Path, item.block.id, err.Error())
@@ -1506,7 +1694,7 @@ func (bc *BlockCache) upload(item *workItem) {
 			return
 		}
 
-		bc.threadPool.Schedule(false, item)
+		bc.threadPool.Reschedule(item)
 		return
 	}
 
@@ -1609,6 +1797,7 @@ func (bc *BlockCache) commitBlocks(handle *handlemap.Handle) error {
 	// Lock was already acquired on the handle.
 	if newEtag != "" {
 		handle.SetValue("ETAG", newEtag)
+		bc.tagDiskBlocks(handle, newEtag)
 	}
 
 	// set all the blocks as committed
@@ -1685,9 +1874,10 @@ func (bc *BlockCache) getBlockIDList(handle *handlemap.Handle) ([]string, []stri
 
 				log.Debug("BlockCache::getBlockIDList : Staging semi zero block for %v=>%v offset %v, size %v", handle.ID, handle.Path, fillerOffset, fillerSize)
 				err := bc.NextComponent().StageData(internal.StageDataOptions{
-					Name: handle.Path,
-					Data: bc.blockPool.zeroBlock.data[:fillerSize],
-					Id:   id,
+					Name:   handle.Path,
+					Data:   bc.blockPool.zeroBlock.data[:fillerSize],
+					Offset: fillerOffset,
+					Id:     id,
 				})
 
 				if err != nil {
@@ -1716,8 +1906,15 @@ func (bc *BlockCache) getBlockIDList(handle *handlemap.Handle) ([]string, []stri
 			}
 		} else {
 			for index < offsets[i] {
-				if !zeroBlockStaged {
-					id, err := bc.stageZeroBlock(handle, 1)
+				if bc.zeroPerOffset {
+					id, err := bc.stageZeroBlock(handle, uint64(index)*bc.blockSize, 1)
+					if err != nil {
+						return nil, nil, err
+					}
+
+					zeroBlockID = id
+				} else if !zeroBlockStaged {
+					id, err := bc.getZeroBlockID(handle, listMap)
 					if err != nil {
 						return nil, nil, err
 					}
@@ -1742,25 +1939,45 @@ func (bc *BlockCache) getBlockIDList(handle *handlemap.Handle) ([]string, []stri
 	return blockIDList, restageId, nil
 }
 
-func (bc *BlockCache) stageZeroBlock(handle *handlemap.Handle, tryCnt int) (string, error) {
+// getZeroBlockID : Reuse the zero block staged earlier for this handle as long as some block in the list still refers to it
+func (bc *BlockCache) getZeroBlockID(handle *handlemap.Handle, listMap map[int64]*blockInfo) (string, error) {
+	if val, found := handle.GetValue("ZERO_BLOCK"); found {
+		for _, info := range listMap {
+			if info.id == val.(string) {
+				return info.id, nil
+			}
+		}
+	}
+
+	id, err := bc.stageZeroBlock(handle, 0, 1)
+	if err != nil {
+		return "", err
+	}
+
+	handle.SetValue("ZERO_BLOCK", id)
+	return id, nil
+}
+
+func (bc *BlockCache) stageZeroBlock(handle *handlemap.Handle, offset uint64, tryCnt int) (string, error) {
 	if tryCnt > MAX_FAIL_CNT {
 		// If we failed to write the data 3 times then just give up
 		log.Err("BlockCache::stageZeroBlock : 3 attempts to upload zero block have failed %v=>%v", handle.ID, handle.Path)
 		return "", fmt.Errorf("3 attempts to upload zero block have failed for %v=>%v", handle.ID, handle.Path)
 	}
 
-	id := common.GetBlockID(common.BlockIDLength)
+	id := newZeroBlockID(common.BlockIDLength)
 
 	log.Debug("BlockCache::stageZeroBlock : Staging zero block for %v=>%v, try = %v", handle.ID, handle.Path, tryCnt)
 	err := bc.NextComponent().StageData(internal.StageDataOptions{
-		Name: handle.Path,
-		Data: bc.blockPool.zeroBlock.data[:],
-		Id:   id,
+		Name:   handle.Path,
+		Data:   bc.blockPool.zeroBlock.data[:],
+		Offset: offset,
+		Id:     id,
 	})
 
 	if err != nil {
 		log.Err("BlockCache::stageZeroBlock : Failed to write zero block for %v=>%v, try %v [%v]", handle.ID, handle.Path, tryCnt, err.Error())
-		return bc.stageZeroBlock(handle, tryCnt+1)
+		return bc.stageZeroBlock(handle, offset, tryCnt+1)
 	}
 
 	log.Debug("BlockCache::stageZeroBlock : Zero block id for %v=>%v = %v", handle.ID, handle.Path, id)
@@ -1784,6 +2001,10 @@ func (bc *BlockCache) diskEvict(node *list.Element) {
 
 	bc.fileNodeMap.Delete(fileName)
 
+	if bc.retainDisk {
+		return
+	}
+
 	localPath := filepath.Join(bc.tmpPath, fileName)
 	_ = os.Remove(localPath)
 }
@@ -1938,6 +2159,311 @@ func (bc *BlockCache) TruncateFile(options internal.TruncateFileOptions) error {
 	return nil
 }
 
+// Fallocate: Preallocate space or punch a hole in a file opened through block cache
+func (bc *BlockCache) Fallocate(options internal.FallocateOptions) error {
+	log.Trace("BlockCache::Fallocate : path=%s, mode=%d, offset=%d, length=%d", options.Name, options.Mode, options.Offset, options.Length)
+
+	if options.Handle == nil || options.Handle.Buffers == nil {
+		// File is not open through block cache so there is no cached data to update
+		return bc.NextComponent().Fallocate(options)
+	}
+
+	handle := options.Handle
+	handle.Lock()
+	defer handle.Unlock()
+
+	var err error
+	switch options.Mode {
+	case 0:
+		err = bc.growFile(handle, uint64(options.Offset+options.Length))
+	case internal.FallocKeepSize:
+		// Storage does not reserve space upfront so there is nothing to do if size does not change
+		return nil
+	case internal.FallocPunchHole | internal.FallocKeepSize:
+		err = bc.punchHole(handle, uint64(options.Offset), uint64(options.Offset+options.Length))
+	default:
+		log.Err("BlockCache::Fallocate : Mode %d is not supported for %s", options.Mode, options.Name)
+		return syscall.ENOTSUP
+	}
+
+	if err != nil {
+		log.Err("BlockCache::Fallocate : Failed to allocate %s [%s]", options.Name, err.Error())
+		return err
+	}
+
+	// Commit right away so that the new range reads back the same from the container
+	if handle.Dirty() {
+		err = bc.commitBlocks(handle)
+		if err != nil {
+			log.Err("BlockCache::Fallocate : Failed to commit blocks for %s [%s]", options.Name, err.Error())
+			return err
+		}
+	}
+
+	return nil
+}
+
+// growFile: Extend the file to the given size, blocks in the gap are committed as the shared zero block
+func (bc *BlockCache) growFile(handle *handlemap.Handle, size uint64) error {
+	if size <= uint64(handle.Size) {
+		return nil
+	}
+
+	// Only the new last block needs a buffer as it decides the size of the file
+	block, err := bc.getOrCreateBlock(handle, size-1)
+	if err != nil {
+		return err
+	}
+
+	block.Dirty()
+	handle.Size = int64(size)
+	handle.Flags.Set(handlemap.HandleFlagDirty)
+	return nil
+}
+
+// punchHole: Zero out the given range of the file without changing its size
+func (bc *BlockCache) punchHole(handle *handlemap.Handle, start uint64, end uint64) error {
+	end = min(end, uint64(handle.Size))
+	if start >= end {
+		return nil
+	}
+
+	lst, _ := handle.GetValue("blockList")
+	listMap := lst.(map[int64]*blockInfo)
+	lastIndex := bc.getBlockIndex(uint64(handle.Size) - 1)
+
+	for index := bc.getBlockIndex(start); index <= bc.getBlockIndex(end-1); index++ {
+		blockStart := index * bc.blockSize
+		holeStart := max(start, blockStart)
+		holeEnd := min(end, blockStart+bc.blockSize)
+
+		if holeStart == blockStart && holeEnd == blockStart+bc.blockSize && index != lastIndex {
+			// Whole block is punched so drop it, commit fills the gap with the shared zero block
+			bc.dropBlock(handle, index)
+			delete(listMap, int64(index))
+		} else {
+			// Partially punched block, or the last block which decides the file size, is zeroed in memory
+			block, err := bc.getOrCreateBlock(handle, holeStart)
+			if err != nil {
+				return err
+			}
+
+			clear(block.data[holeStart-blockStart : holeEnd-blockStart])
+			block.Dirty()
+		}
+
+		handle.Flags.Set(handlemap.HandleFlagDirty)
+	}
+
+	return nil
+}
+
+// dropBlock: Remove the cached copy of a block from memory and local disk
+func (bc *BlockCache) dropBlock(handle *handlemap.Handle, index uint64) {
+	node, found := handle.GetValue(fmt.Sprintf("%v", index))
+	if found {
+		block := node.(*Block)
+		if block.flags.IsSet(BlockFlagDownloading) || block.flags.IsSet(BlockFlagUploading) {
+			// Wait for the transfer in flight to finish before the block goes back to pool
+			_, ok := <-block.state
+			if ok {
+				block.Unblock()
+			}
+		}
+
+		// Same cleanup as a failed download, the block is removed from handle and returned to pool
+		bc.releaseDownloadFailedBlock(handle, block)
+	}
+
+	if bc.tmpPath != "" {
+		fileName := fmt.Sprintf("%s::%v", handle.Path, index)
+		flock := bc.fileLocks.Get(fileName)
+		flock.Lock()
+		_ = os.Remove(filepath.Join(bc.tmpPath, fileName))
+		flock.Unlock()
+	}
+}
+
+// CopyFileRange: Copy a range within storage keeping the block layout of block cache, then refresh destination handle
+func (bc *BlockCache) CopyFileRange(options internal.CopyFileRangeOptions) (int64, error) {
+	log.Trace("BlockCache::CopyFileRange : %s [%d] -> %s [%d], length %d", options.SrcName, options.SrcOffset,
+		options.DstName, options.DstOffset, options.Length)
+
+	if options.SrcHandle != nil && options.SrcHandle.Buffers != nil && options.SrcHandle.Dirty() {
+		// Storage does not have the latest data of source so let the kernel copy it through read and write
+		log.Info("BlockCache::CopyFileRange : %s has pending writes, falling back to read and write", options.SrcName)
+		return 0, syscall.ENOTSUP
+	}
+
+	if options.DstHandle == nil || options.DstHandle.Buffers == nil {
+		return bc.NextComponent().CopyFileRange(options)
+	}
+
+	handle := options.DstHandle
+	handle.Lock()
+	defer handle.Unlock()
+
+	if handle.Dirty() {
+		// Copy is applied over the blob in storage so it needs the pending writes of destination first
+		err := bc.commitBlocks(handle)
+		if err != nil {
+			log.Err("BlockCache::CopyFileRange : Failed to commit blocks for %s [%s]", options.DstName, err.Error())
+			return 0, err
+		}
+	}
+
+	options.BlockSize = int64(bc.blockSize)
+	copied, err := bc.NextComponent().CopyFileRange(options)
+	if err != nil || copied == 0 {
+		return copied, err
+	}
+
+	err = bc.refreshCopiedRange(handle, uint64(options.DstOffset), uint64(options.DstOffset+copied))
+	if err != nil {
+		log.Err("BlockCache::CopyFileRange : Failed to refresh %s after copy [%s]", options.DstName, err.Error())
+		return 0, err
+	}
+
+	return copied, nil
+}
+
+// refreshCopiedRange: Drop cached blocks of the range copied in storage and load the new block list of the file
+func (bc *BlockCache) refreshCopiedRange(handle *handlemap.Handle, start uint64, end uint64) error {
+	for index := bc.getBlockIndex(start); index <= bc.getBlockIndex(end-1); index++ {
+		bc.dropBlock(handle, index)
+	}
+
+	attr, err := bc.NextComponent().GetAttr(internal.GetAttrOptions{Name: handle.Path})
+	if err != nil {
+		return err
+	}
+
+	blockList, err := bc.NextComponent().GetCommittedBlockList(handle.Path)
+	if err != nil || blockList == nil {
+		return fmt.Errorf("failed to retrieve block list for %s [%v]", handle.Path, err)
+	}
+
+	lst, _ := handle.GetValue("blockList")
+	clear(lst.(map[int64]*blockInfo))
+	if !bc.validateBlockList(handle, internal.OpenFileOptions{Name: handle.Path}, blockList) {
+		return fmt.Errorf("block size mismatch for %s", handle.Path)
+	}
+
+	handle.Size = attr.Size
+	handle.Mtime = attr.Mtime
+	if attr.ETag != "" {
+		handle.SetValue("ETAG", attr.ETag)
+	}
+
+	return nil
+}
+
+// Lseek: Find data or hole in a file opened through block cache using its block list
+func (bc *BlockCache) Lseek(options internal.LseekOptions) (int64, error) {
+	log.Trace("BlockCache::Lseek : path=%s, offset=%d, whence=%d", options.Name, options.Offset, options.Whence)
+
+	if options.Handle == nil || options.Handle.Buffers == nil {
+		return bc.NextComponent().Lseek(options)
+	}
+
+	handle := options.Handle
+	handle.Lock()
+	defer handle.Unlock()
+
+	if options.Offset >= handle.Size {
+		return 0, syscall.ENXIO
+	}
+
+	holes, err := bc.getHoles(handle)
+	if err != nil {
+		log.Err("BlockCache::Lseek : Failed to get holes of %s [%s]", options.Name, err.Error())
+		return 0, err
+	}
+
+	offset := options.Offset
+	switch options.Whence {
+	case internal.SeekData:
+		for _, hole := range holes {
+			if hole.start <= offset && offset < hole.end {
+				offset = hole.end
+			}
+		}
+
+		if offset >= handle.Size {
+			return 0, syscall.ENXIO
+		}
+		return offset, nil
+
+	case internal.SeekHole:
+		for _, hole := range holes {
+			if offset < hole.end {
+				return max(offset, hole.start), nil
+			}
+		}
+
+		// There is always an implicit hole at the end of file
+		return handle.Size, nil
+
+	default:
+		return 0, syscall.EINVAL
+	}
+}
+
+// holeRange is a range of the file which reads back as zeros
+type holeRange struct {
+	start int64
+	end   int64
+}
+
+// getHoles: List the sorted and merged holes of a file, these are blocks not staged yet and zero blocks
+func (bc *BlockCache) getHoles(handle *handlemap.Handle) ([]holeRange, error) {
+	holes := make([]holeRange, 0)
+	addHole := func(start int64, end int64) {
+		if n := len(holes); n > 0 && holes[n-1].end == start {
+			holes[n-1].end = end
+		} else {
+			holes = append(holes, holeRange{start: start, end: end})
+		}
+	}
+
+	lst, _ := handle.GetValue("blockList")
+	listMap := lst.(map[int64]*blockInfo)
+
+	if len(listMap) == 0 && !handle.Dirty() {
+		// Handle opened in read-only mode does not hold the block list so get it from the container
+		blockList, err := bc.NextComponent().GetCommittedBlockList(handle.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		if blockList != nil {
+			for _, block := range *blockList {
+				if isZeroBlockID(block.Id) {
+					addHole(block.Offset, block.Offset+int64(block.Size))
+				}
+			}
+		}
+		return holes, nil
+	}
+
+	lastIndex := int64(bc.getBlockIndex(uint64(handle.Size) - 1))
+	for index := int64(0); index <= lastIndex; index++ {
+		node, found := handle.GetValue(fmt.Sprintf("%v", index))
+		if found && node.(*Block).IsDirty() {
+			// Block holds data which is not staged yet
+			continue
+		}
+
+		info, found := listMap[index]
+		if !found || isZeroBlockID(info.id) {
+			start := index * int64(bc.blockSize)
+			addHole(start, min(start+int64(bc.blockSize), handle.Size))
+		}
+	}
+
+	return holes, nil
+}
+
 func (bc *BlockCache) StatFs() (*syscall.Statfs_t, bool, error) {
 	var maxCacheSize uint64
 	if bc.diskSize > 0 {
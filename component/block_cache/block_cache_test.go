@@ -56,6 +56,7 @@ import (
 	"github.com/Azure/azure-storage-fuse/v2/common/log"
 	"github.com/Azure/azure-storage-fuse/v2/component/loopback"
 	"github.com/Azure/azure-storage-fuse/v2/internal"
+	"github.com/Azure/azure-storage-fuse/v2/internal/handlemap"
 	"github.com/pbnjay/memory"
 
 	"github.com/stretchr/testify/assert"
@@ -744,6 +745,88 @@ func (suite *blockCacheTestSuite) TestFileReadRandom() {
 	suite.assert.Nil(h.Buffers.Cooking)
 }
 
+func (suite *blockCacheTestSuite) TestFileReadReverse() {
+	tobj, err := setupPipeline("")
+	defer tobj.cleanupPipeline()
+
+	suite.assert.NoError(err)
+	suite.assert.NotNil(tobj.blockCache)
+
+	fileName := getTestFileName(suite.T().Name())
+	storagePath := filepath.Join(tobj.fake_storage_path, fileName)
+	fileSize := 30 * _1MB
+	data := make([]byte, fileSize)
+	_, _ = r.Read(data)
+	err = os.WriteFile(storagePath, data, 0777)
+	suite.assert.NoError(err)
+
+	options := internal.OpenFileOptions{Name: fileName}
+	h, err := tobj.blockCache.OpenFile(options)
+	suite.assert.NoError(err)
+	suite.assert.NotNil(h)
+
+	// Read the file from the end, one block at a time
+	buf := make([]byte, _1MB)
+	for i := int64(29); i >= 0; i-- {
+		n, err := tobj.blockCache.ReadInBuffer(&internal.ReadInBufferOptions{Handle: h, Offset: i * int64(_1MB), Data: buf})
+		suite.assert.True(err == nil || err == io.EOF)
+		suite.assert.Equal(int(_1MB), n)
+		suite.assert.Equal(data[i*int64(_1MB):(i+1)*int64(_1MB)], buf)
+	}
+
+	// Backward reads are prefetched instead of being treated as random reads
+	ra := getReadAhead(h)
+	suite.assert.NotNil(ra)
+	suite.assert.Equal(patternReverse, ra.pattern)
+	suite.assert.LessOrEqual(h.OptCnt, uint64(MIN_RANDREAD))
+
+	err = tobj.blockCache.ReleaseFile(internal.ReleaseFileOptions{Handle: h})
+	suite.assert.NoError(err)
+}
+
+func (suite *blockCacheTestSuite) TestSharedCacheAcrossHandles() {
+	cfg := "read-only: true\n\nblock_cache:\n  block-size-mb: 1\n  mem-size-mb: 40\n  prefetch: 12\n  parallelism: 10\n  shared-cache: true"
+	tobj, err := setupPipeline(cfg)
+	defer tobj.cleanupPipeline()
+
+	suite.assert.NoError(err)
+	suite.assert.True(tobj.blockCache.sharedCache)
+	suite.assert.NotNil(tobj.blockCache.sharedStore)
+
+	fileName := getTestFileName(suite.T().Name())
+	storagePath := filepath.Join(tobj.fake_storage_path, fileName)
+	data := make([]byte, 5*_1MB)
+	_, _ = r.Read(data)
+	err = os.WriteFile(storagePath, data, 0777)
+	suite.assert.NoError(err)
+
+	handles := make([]*handlemap.Handle, 3)
+	for i := range handles {
+		handles[i], err = tobj.blockCache.OpenFile(internal.OpenFileOptions{Name: fileName})
+		suite.assert.NoError(err)
+	}
+
+	// All handles see the same data while the blocks are downloaded once and shared
+	buf := make([]byte, 5*_1MB)
+	for _, h := range handles {
+		n, err := tobj.blockCache.ReadInBuffer(&internal.ReadInBufferOptions{Handle: h, Offset: 0, Data: buf})
+		suite.assert.True(err == nil || err == io.EOF)
+		suite.assert.Equal(len(data), n)
+		suite.assert.Equal(data, buf)
+	}
+
+	etag, _ := handles[0].GetValue("ETAG")
+	for i := range int64(5) {
+		_, found := tobj.blockCache.sharedStore.entries[tobj.blockCache.sharedStore.key(fileName, etag.(string), i)]
+		suite.assert.True(found)
+	}
+
+	for _, h := range handles {
+		err = tobj.blockCache.ReleaseFile(internal.ReleaseFileOptions{Handle: h})
+		suite.assert.NoError(err)
+	}
+}
+
 func (suite *blockCacheTestSuite) TestFileReadRandomNoPrefetch() {
 	tobj, err := setupPipeline("")
 	defer tobj.cleanupPipeline()
@@ -839,6 +922,76 @@ func (suite *blockCacheTestSuite) TestDiskUsageCheck() {
 	}
 }
 
+func (suite *blockCacheTestSuite) TestPersistDiskCache() {
+	disk_cache_path := getFakeStoragePath("fake_storage")
+	defer os.RemoveAll(disk_cache_path)
+	cfg := fmt.Sprintf("read-only: true\n\nblock_cache:\n  block-size-mb: 1\n  mem-size-mb: 20\n  prefetch: 12\n  parallelism: 10\n  path: %s\n  disk-size-mb: 50\n  disk-timeout-sec: 3600\n  persist-cache: true", disk_cache_path)
+	tobj, err := setupPipeline(cfg)
+	defer tobj.cleanupPipeline()
+
+	suite.assert.NoError(err)
+	suite.assert.True(tobj.blockCache.persistCache)
+
+	path := getTestFileName(suite.T().Name())
+	storagePath := filepath.Join(tobj.fake_storage_path, path)
+	err = os.WriteFile(storagePath, dataBuff[:3*_1MB], 0777)
+	suite.assert.NoError(err)
+
+	h, err := tobj.blockCache.OpenFile(internal.OpenFileOptions{Name: path, Flags: os.O_RDONLY})
+	suite.assert.NoError(err)
+	data := make([]byte, _1MB)
+	for i := range 3 {
+		n, err := tobj.blockCache.ReadInBuffer(&internal.ReadInBufferOptions{Handle: h, Offset: int64(i) * int64(_1MB), Data: data})
+		suite.assert.True(err == nil || err == io.EOF)
+		suite.assert.Equal(int(_1MB), n)
+	}
+	err = tobj.blockCache.ReleaseFile(internal.ReleaseFileOptions{Handle: h})
+	suite.assert.NoError(err)
+
+	// Blocks are retained on disk along with the ETag of the blob
+	err = tobj.blockCache.Stop()
+	suite.assert.NoError(err)
+	for i := range 3 {
+		localPath := filepath.Join(disk_cache_path, diskBlockName(path, int64(i)))
+		suite.assert.FileExists(localPath)
+		suite.assert.NotEmpty(getBlockETag(localPath))
+		suite.assert.Equal(tobj.blockCache.blockSize, getDiskBlockSize(localPath))
+	}
+
+	// Unverifiable files and blocks cut with another block size are not adopted on remount
+	_ = os.WriteFile(filepath.Join(disk_cache_path, "junk"), []byte("junk"), 0777)
+	resized := filepath.Join(disk_cache_path, diskBlockName(path, 2))
+	err = syscall.Setxattr(resized, blockSizeXattr, []byte(fmt.Sprint(2*_1MB)), 0)
+	suite.assert.NoError(err)
+
+	tobj.blockCache = NewBlockCacheComponent().(*BlockCache)
+	tobj.blockCache.SetNextComponent(tobj.loopback)
+	err = tobj.blockCache.Configure(true)
+	suite.assert.NoError(err)
+	err = tobj.blockCache.Start(context.Background())
+	suite.assert.NoError(err)
+
+	suite.assert.NoFileExists(filepath.Join(disk_cache_path, "junk"))
+	suite.assert.NoFileExists(resized)
+	for i := range 3 {
+		_, found := tobj.blockCache.fileNodeMap.Load(diskBlockName(path, int64(i)))
+		suite.assert.Equal(i != 2, found)
+	}
+
+	// Blob changes while unmounted, stale blocks on disk must not be served
+	err = os.WriteFile(storagePath, dataBuff[2*_1MB:5*_1MB], 0777)
+	suite.assert.NoError(err)
+
+	h, err = tobj.blockCache.OpenFile(internal.OpenFileOptions{Name: path, Flags: os.O_RDONLY})
+	suite.assert.NoError(err)
+	n, err := tobj.blockCache.ReadInBuffer(&internal.ReadInBufferOptions{Handle: h, Offset: 0, Data: data})
+	suite.assert.NoError(err)
+	suite.assert.Equal(int(_1MB), n)
+	suite.assert.Equal(dataBuff[2*_1MB:3*_1MB], data)
+	err = tobj.blockCache.ReleaseFile(internal.ReleaseFileOptions{Handle: h})
+	suite.assert.NoError(err)
+}
+
 // Block-cache Writer related test cases
 func (suite *blockCacheTestSuite) TestCreateFile() {
 	tobj, err := setupPipeline("")
@@ -1351,6 +1504,290 @@ func (suite *blockCacheTestSuite) TestRandomWriteSparseFile() {
 	suite.assert.Equal(l, r)
 }
 
+func (suite *blockCacheTestSuite) TestFallocatePunchHole() {
+	cfg := "block_cache:\n  block-size-mb: 1\n  mem-size-mb: 20\n  prefetch: 12\n  parallelism: 10"
+	tobj, err := setupPipeline(cfg)
+	defer tobj.cleanupPipeline()
+
+	suite.assert.NoError(err)
+	suite.assert.NotNil(tobj.blockCache)
+
+	path := getTestFileName(suite.T().Name())
+	storagePath := filepath.Join(tobj.fake_storage_path, path)
+
+	h, err := tobj.blockCache.CreateFile(internal.CreateFileOptions{Name: path, Mode: 0777})
+	suite.assert.NoError(err)
+	suite.assert.NotNil(h)
+
+	n, err := tobj.blockCache.WriteFile(&internal.WriteFileOptions{Handle: h, Offset: 0, Data: dataBuff[:5*_1MB]})
+	suite.assert.NoError(err)
+	suite.assert.Equal(n, int(5*_1MB))
+
+	// punch a hole covering half of block 0, all of block 1 and half of block 2
+	err = tobj.blockCache.Fallocate(internal.FallocateOptions{Handle: h, Name: path, Mode: internal.FallocPunchHole | internal.FallocKeepSize, Offset: int64(_1MB / 2), Length: int64(2 * _1MB)})
+	suite.assert.NoError(err)
+	suite.assert.Equal(int64(5*_1MB), h.Size)
+
+	zeroID, found := h.GetValue("ZERO_BLOCK")
+	suite.assert.True(found)
+
+	// punching another full block reuses the zero block staged earlier
+	err = tobj.blockCache.Fallocate(internal.FallocateOptions{Handle: h, Name: path, Mode: internal.FallocPunchHole | internal.FallocKeepSize, Offset: int64(3 * _1MB), Length: int64(_1MB)})
+	suite.assert.NoError(err)
+
+	lst, _ := h.GetValue("blockList")
+	listMap := lst.(map[int64]*blockInfo)
+	suite.assert.Equal(zeroID, listMap[1].id)
+	suite.assert.Equal(zeroID, listMap[3].id)
+
+	err = tobj.blockCache.ReleaseFile(internal.ReleaseFileOptions{Handle: h})
+	suite.assert.NoError(err)
+
+	data, err := os.ReadFile(storagePath)
+	suite.assert.NoError(err)
+	suite.assert.Len(data, int(5*_1MB))
+	suite.assert.True(bytes.Equal(dataBuff[:_1MB/2], data[:_1MB/2]))
+	suite.assert.True(bytes.Equal(make([]byte, 2*_1MB), data[_1MB/2:5*_1MB/2]))
+	suite.assert.True(bytes.Equal(dataBuff[5*_1MB/2:3*_1MB], data[5*_1MB/2:3*_1MB]))
+	suite.assert.True(bytes.Equal(make([]byte, _1MB), data[3*_1MB:4*_1MB]))
+	suite.assert.True(bytes.Equal(dataBuff[4*_1MB:5*_1MB], data[4*_1MB:]))
+}
+
+func (suite *blockCacheTestSuite) TestFallocatePunchHolePerOffset() {
+	cfg := "block_cache:\n  block-size-mb: 1\n  mem-size-mb: 20\n  prefetch: 12\n  parallelism: 10"
+	tobj, err := setupPipeline(cfg)
+	defer tobj.cleanupPipeline()
+
+	suite.assert.NoError(err)
+	suite.assert.NotNil(tobj.blockCache)
+
+	// Blocks sealed with their offset can not share one zero block
+	tobj.blockCache.zeroPerOffset = true
+
+	path := getTestFileName(suite.T().Name())
+	storagePath := filepath.Join(tobj.fake_storage_path, path)
+
+	h, err := tobj.blockCache.CreateFile(internal.CreateFileOptions{Name: path, Mode: 0777})
+	suite.assert.NoError(err)
+
+	_, err = tobj.blockCache.WriteFile(&internal.WriteFileOptions{Handle: h, Offset: 0, Data: dataBuff[:5*_1MB]})
+	suite.assert.NoError(err)
+
+	err = tobj.blockCache.Fallocate(internal.FallocateOptions{Handle: h, Name: path, Mode: internal.FallocPunchHole | internal.FallocKeepSize, Offset: int64(_1MB), Length: int64(_1MB)})
+	suite.assert.NoError(err)
+	err = tobj.blockCache.Fallocate(internal.FallocateOptions{Handle: h, Name: path, Mode: internal.FallocPunchHole | internal.FallocKeepSize, Offset: int64(3 * _1MB), Length: int64(_1MB)})
+	suite.assert.NoError(err)
+
+	lst, _ := h.GetValue("blockList")
+	listMap := lst.(map[int64]*blockInfo)
+	suite.assert.True(isZeroBlockID(listMap[1].id))
+	suite.assert.True(isZeroBlockID(listMap[3].id))
+	suite.assert.NotEqual(listMap[1].id, listMap[3].id)
+
+	err = tobj.blockCache.ReleaseFile(internal.ReleaseFileOptions{Handle: h})
+	suite.assert.NoError(err)
+
+	data, err := os.ReadFile(storagePath)
+	suite.assert.NoError(err)
+	suite.assert.Len(data, int(5*_1MB))
+	suite.assert.True(bytes.Equal(make([]byte, _1MB), data[_1MB:2*_1MB]))
+	suite.assert.True(bytes.Equal(dataBuff[2*_1MB:3*_1MB], data[2*_1MB:3*_1MB]))
+	suite.assert.True(bytes.Equal(make([]byte, _1MB), data[3*_1MB:4*_1MB]))
+}
+
+func (suite *blockCacheTestSuite) TestFallocateGrowFile() {
+	cfg := "block_cache:\n  block-size-mb: 1\n  mem-size-mb: 20\n  prefetch: 12\n  parallelism: 10"
+	tobj, err := setupPipeline(cfg)
+	defer tobj.cleanupPipeline()
+
+	suite.assert.NoError(err)
+	suite.assert.NotNil(tobj.blockCache)
+
+	path := getTestFileName(suite.T().Name())
+	storagePath := filepath.Join(tobj.fake_storage_path, path)
+
+	h, err := tobj.blockCache.CreateFile(internal.CreateFileOptions{Name: path, Mode: 0777})
+	suite.assert.NoError(err)
+
+	_, err = tobj.blockCache.WriteFile(&internal.WriteFileOptions{Handle: h, Offset: 0, Data: dataBuff[:_1MB]})
+	suite.assert.NoError(err)
+
+	// keep size only reserves space which storage does not need
+	err = tobj.blockCache.Fallocate(internal.FallocateOptions{Handle: h, Name: path, Mode: internal.FallocKeepSize, Offset: 0, Length: int64(4 * _1MB)})
+	suite.assert.NoError(err)
+	suite.assert.Equal(int64(_1MB), h.Size)
+
+	// modes other than keep size and punch hole are not supported
+	err = tobj.blockCache.Fallocate(internal.FallocateOptions{Handle: h, Name: path, Mode: internal.FallocPunchHole, Offset: 0, Length: int64(_1MB)})
+	suite.assert.Equal(syscall.ENOTSUP, err)
+
+	err = tobj.blockCache.Fallocate(internal.FallocateOptions{Handle: h, Name: path, Mode: 0, Offset: int64(_1MB), Length: int64(2*_1MB + 100)})
+	suite.assert.NoError(err)
+	suite.assert.Equal(int64(3*_1MB+100), h.Size)
+
+	err = tobj.blockCache.ReleaseFile(internal.ReleaseFileOptions{Handle: h})
+	suite.assert.NoError(err)
+
+	data, err := os.ReadFile(storagePath)
+	suite.assert.NoError(err)
+	suite.assert.Len(data, int(3*_1MB+100))
+	suite.assert.True(bytes.Equal(dataBuff[:_1MB], data[:_1MB]))
+	suite.assert.True(bytes.Equal(make([]byte, 2*_1MB+100), data[_1MB:]))
+}
+
+func (suite *blockCacheTestSuite) TestLseekHoles() {
+	cfg := "block_cache:\n  block-size-mb: 1\n  mem-size-mb: 20\n  prefetch: 12\n  parallelism: 10"
+	tobj, err := setupPipeline(cfg)
+	defer tobj.cleanupPipeline()
+
+	suite.assert.NoError(err)
+	suite.assert.NotNil(tobj.blockCache)
+
+	path := getTestFileName(suite.T().Name())
+	h, err := tobj.blockCache.CreateFile(internal.CreateFileOptions{Name: path, Mode: 0777})
+	suite.assert.NoError(err)
+
+	// blocks 0 to 2 are never written so they are a hole
+	_, err = tobj.blockCache.WriteFile(&internal.WriteFileOptions{Handle: h, Offset: int64(3 * _1MB), Data: dataBuff[:2*_1MB]})
+	suite.assert.NoError(err)
+
+	seek := func(offset uint64, whence int) (int64, error) {
+		return tobj.blockCache.Lseek(internal.LseekOptions{Handle: h, Name: path, Offset: int64(offset), Whence: whence})
+	}
+
+	offset, err := seek(0, internal.SeekData)
+	suite.assert.NoError(err)
+	suite.assert.Equal(int64(3*_1MB), offset)
+
+	offset, err = seek(_1MB, internal.SeekHole)
+	suite.assert.NoError(err)
+	suite.assert.Equal(int64(_1MB), offset)
+
+	offset, err = seek(3*_1MB, internal.SeekHole)
+	suite.assert.NoError(err)
+	suite.assert.Equal(int64(5*_1MB), offset)
+
+	_, err = seek(5*_1MB, internal.SeekData)
+	suite.assert.Equal(syscall.ENXIO, err)
+
+	err = tobj.blockCache.ReleaseFile(internal.ReleaseFileOptions{Handle: h})
+	suite.assert.NoError(err)
+
+	// the gap got committed as zero blocks so a read-only handle finds the same hole from the block list
+	h, err = tobj.blockCache.OpenFile(internal.OpenFileOptions{Name: path, Flags: os.O_RDONLY})
+	suite.assert.NoError(err)
+
+	offset, err = seek(_1MB/2, internal.SeekData)
+	suite.assert.NoError(err)
+	suite.assert.Equal(int64(3*_1MB), offset)
+
+	offset, err = seek(4*_1MB, internal.SeekHole)
+	suite.assert.NoError(err)
+	suite.assert.Equal(int64(5*_1MB), offset)
+
+	err = tobj.blockCache.ReleaseFile(internal.ReleaseFileOptions{Handle: h})
+	suite.assert.NoError(err)
+}
+
+func (suite *blockCacheTestSuite) TestLseekPunchedHole() {
+	cfg := "block_cache:\n  block-size-mb: 1\n  mem-size-mb: 20\n  prefetch: 12\n  parallelism: 10"
+	tobj, err := setupPipeline(cfg)
+	defer tobj.cleanupPipeline()
+
+	suite.assert.NoError(err)
+	suite.assert.NotNil(tobj.blockCache)
+
+	path := getTestFileName(suite.T().Name())
+	h, err := tobj.blockCache.CreateFile(internal.CreateFileOptions{Name: path, Mode: 0777})
+	suite.assert.NoError(err)
+
+	_, err = tobj.blockCache.WriteFile(&internal.WriteFileOptions{Handle: h, Offset: 0, Data: dataBuff[:5*_1MB]})
+	suite.assert.NoError(err)
+
+	err = tobj.blockCache.Fallocate(internal.FallocateOptions{Handle: h, Name: path, Mode: internal.FallocPunchHole | internal.FallocKeepSize, Offset: int64(_1MB), Length: int64(2 * _1MB)})
+	suite.assert.NoError(err)
+
+	offset, err := tobj.blockCache.Lseek(internal.LseekOptions{Handle: h, Name: path, Offset: 0, Whence: internal.SeekHole})
+	suite.assert.NoError(err)
+	suite.assert.Equal(int64(_1MB), offset)
+
+	offset, err = tobj.blockCache.Lseek(internal.LseekOptions{Handle: h, Name: path, Offset: int64(_1MB + 10), Whence: internal.SeekData})
+	suite.assert.NoError(err)
+	suite.assert.Equal(int64(3*_1MB), offset)
+
+	// a partially punched block is still data
+	err = tobj.blockCache.Fallocate(internal.FallocateOptions{Handle: h, Name: path, Mode: internal.FallocPunchHole | internal.FallocKeepSize, Offset: int64(3 * _1MB), Length: 10})
+	suite.assert.NoError(err)
+
+	offset, err = tobj.blockCache.Lseek(internal.LseekOptions{Handle: h, Name: path, Offset: int64(3 * _1MB), Whence: internal.SeekHole})
+	suite.assert.NoError(err)
+	suite.assert.Equal(int64(5*_1MB), offset)
+
+	err = tobj.blockCache.ReleaseFile(internal.ReleaseFileOptions{Handle: h})
+	suite.assert.NoError(err)
+}
+
+func (suite *blockCacheTestSuite) TestCopyFileRange() {
+	cfg := "block_cache:\n  block-size-mb: 1\n  mem-size-mb: 20\n  prefetch: 12\n  parallelism: 10"
+	tobj, err := setupPipeline(cfg)
+	defer tobj.cleanupPipeline()
+
+	suite.assert.NoError(err)
+	suite.assert.NotNil(tobj.blockCache)
+
+	src := getTestFileName(suite.T().Name() + "_src")
+	dst := getTestFileName(suite.T().Name() + "_dst")
+
+	h, err := tobj.blockCache.CreateFile(internal.CreateFileOptions{Name: src, Mode: 0777})
+	suite.assert.NoError(err)
+	_, err = tobj.blockCache.WriteFile(&internal.WriteFileOptions{Handle: h, Offset: 0, Data: dataBuff[:4*_1MB]})
+	suite.assert.NoError(err)
+	err = tobj.blockCache.ReleaseFile(internal.ReleaseFileOptions{Handle: h})
+	suite.assert.NoError(err)
+
+	srcHandle, err := tobj.blockCache.OpenFile(internal.OpenFileOptions{Name: src, Flags: os.O_RDONLY})
+	suite.assert.NoError(err)
+
+	// destination has pending writes which get committed before the copy
+	dstHandle, err := tobj.blockCache.CreateFile(internal.CreateFileOptions{Name: dst, Mode: 0777})
+	suite.assert.NoError(err)
+	_, err = tobj.blockCache.WriteFile(&internal.WriteFileOptions{Handle: dstHandle, Offset: 0, Data: dataBuff[3*_1MB : 5*_1MB]})
+	suite.assert.NoError(err)
+
+	copied, err := tobj.blockCache.CopyFileRange(internal.CopyFileRangeOptions{
+		SrcHandle: srcHandle, SrcName: src, SrcOffset: int64(_1MB),
+		DstHandle: dstHandle, DstName: dst, DstOffset: int64(_1MB), Length: int64(2 * _1MB)})
+	suite.assert.NoError(err)
+	suite.assert.Equal(int64(2*_1MB), copied)
+	suite.assert.Equal(int64(3*_1MB), dstHandle.Size)
+	suite.assert.False(dstHandle.Dirty())
+
+	data := make([]byte, 3*_1MB)
+	n, err := tobj.blockCache.ReadInBuffer(&internal.ReadInBufferOptions{Handle: dstHandle, Offset: 0, Data: data})
+	suite.assert.True(err == nil || err == io.EOF)
+	suite.assert.Equal(int(3*_1MB), n)
+	suite.assert.True(bytes.Equal(dataBuff[3*_1MB:4*_1MB], data[:_1MB]))
+	suite.assert.True(bytes.Equal(dataBuff[_1MB:3*_1MB], data[_1MB:]))
+
+	// source with pending writes can not be copied in storage
+	_, err = tobj.blockCache.WriteFile(&internal.WriteFileOptions{Handle: dstHandle, Offset: 0, Data: dataBuff[:10]})
+	suite.assert.NoError(err)
+	_, err = tobj.blockCache.CopyFileRange(internal.CopyFileRangeOptions{
+		SrcHandle: dstHandle, SrcName: dst, DstHandle: dstHandle, DstName: dst, DstOffset: int64(3 * _1MB), Length: 10})
+	suite.assert.Equal(syscall.ENOTSUP, err)
+
+	err = tobj.blockCache.ReleaseFile(internal.ReleaseFileOptions{Handle: dstHandle})
+	suite.assert.NoError(err)
+	err = tobj.blockCache.ReleaseFile(internal.ReleaseFileOptions{Handle: srcHandle})
+	suite.assert.NoError(err)
+
+	stored, err := os.ReadFile(filepath.Join(tobj.fake_storage_path, dst))
+	suite.assert.NoError(err)
+	suite.assert.Len(stored, int(3*_1MB))
+	suite.assert.True(bytes.Equal(dataBuff[:10], stored[:10]))
+	suite.assert.True(bytes.Equal(dataBuff[_1MB:3*_1MB], stored[_1MB:]))
+}
+
 func (suite *blockCacheTestSuite) TestRandomWriteSparseFileWithPartialBlock() {
 	cfg := "block_cache:\n  block-size-mb: 4\n  mem-size-mb: 100\n  prefetch: 12\n  parallelism: 10"
 	tobj, err := setupPipeline(cfg)
@@ -39,6 +39,8 @@ package block_cache
 import (
 	"testing"
 
+	"github.com/Azure/azure-storage-fuse/v2/common"
+
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 )
@@ -239,6 +241,18 @@ func (suite *blockTestSuite) TestWriter() {
 	_ = b.Delete()
 }
 
+func (suite *blockTestSuite) TestZeroBlockID() {
+	suite.assert = assert.New(suite.T())
+
+	id := newZeroBlockID(common.BlockIDLength)
+	suite.assert.True(isZeroBlockID(id))
+	suite.assert.EqualValues(common.BlockIDLength, common.GetIdLength(id))
+	suite.assert.NotEqual(id, newZeroBlockID(common.BlockIDLength))
+
+	suite.assert.False(isZeroBlockID(common.GetBlockID(common.BlockIDLength)))
+	suite.assert.False(isZeroBlockID("not-base64"))
+}
+
 func TestBlockSuite(t *testing.T) {
 	suite.Run(t, new(blockTestSuite))
 }
@@ -182,8 +182,19 @@ func (pool *BlockPool) TryGet() *Block {
 	return block
 }
 
+// Acquire adds a reference to a block shared by multiple readers.
+// Block goes back to the pool only when every reference has been released.
+func (pool *BlockPool) Acquire(b *Block) {
+	b.refs.Add(1)
+}
+
 // Release back the Block to the pool
 func (pool *BlockPool) Release(b *Block) {
+	if b.refs.Load() > 0 && b.refs.Add(-1) > 0 {
+		// Block is still referenced by someone else
+		return
+	}
+
 	select {
 	case pool.resetBlockCh <- b:
 		break
@@ -0,0 +1,194 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package block_cache
+
+import (
+	"container/list"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Azure/azure-storage-fuse/v2/common/log"
+	"github.com/Azure/azure-storage-fuse/v2/internal/handlemap"
+)
+
+// Extended attribute on each disk block holding the ETag of the blob the block belongs to
+const blockETagXattr = "user.etag"
+
+// Extended attribute on each disk block holding the block size it was cut with, block index means nothing without it
+const blockSizeXattr = "user.blocksize"
+
+type diskBlock struct {
+	name  string
+	mtime time.Time
+}
+
+// setBlockETag : Record the blob ETag this disk block was read from or committed as, along with the block size
+func (bc *BlockCache) setBlockETag(localPath string, etag string) {
+	if etag == "" {
+		return
+	}
+
+	err := syscall.Setxattr(localPath, blockSizeXattr, []byte(strconv.FormatUint(bc.blockSize, 10)), 0)
+	if err != nil {
+		log.Err("BlockCache::setBlockETag : Failed to set block size for file %s [%v]", localPath, err.Error())
+		return
+	}
+
+	err = syscall.Setxattr(localPath, blockETagXattr, []byte(etag), 0)
+	if err != nil {
+		log.Err("BlockCache::setBlockETag : Failed to set etag for file %s [%v]", localPath, err.Error())
+	}
+}
+
+// getDiskBlockSize : Get the block size recorded on this disk block, 0 if there is none
+func getDiskBlockSize(localPath string) uint64 {
+	size := make([]byte, 32)
+	n, err := syscall.Getxattr(localPath, blockSizeXattr, size)
+	if err != nil || n <= 0 {
+		return 0
+	}
+
+	blockSize, err := strconv.ParseUint(string(size[:n]), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return blockSize
+}
+
+// getBlockETag : Get the blob ETag recorded on this disk block, empty if there is none
+func getBlockETag(localPath string) string {
+	etag := make([]byte, 256)
+	n, err := syscall.Getxattr(localPath, blockETagXattr, etag)
+	if err != nil || n <= 0 {
+		return ""
+	}
+
+	return string(etag[:n])
+}
+
+// isBlockStale : Disk block belongs to an older version of the blob than the one the handle has opened,
+// or was cut with a different block size
+func (bc *BlockCache) isBlockStale(localPath string, etag string) bool {
+	if size := getDiskBlockSize(localPath); size != 0 && size != bc.blockSize {
+		return true
+	}
+
+	if etag == "" {
+		return false
+	}
+
+	blockETag := getBlockETag(localPath)
+	return blockETag != "" && blockETag != etag
+}
+
+// tagDiskBlocks : Once blocks are committed, blocks of this handle on disk represent the new version of the blob
+func (bc *BlockCache) tagDiskBlocks(handle *handlemap.Handle, etag string) {
+	if bc.tmpPath == "" || etag == "" {
+		return
+	}
+
+	list, _ := handle.GetValue("blockList")
+	listMap := list.(map[int64]*blockInfo)
+
+	for index := range listMap {
+		fileName := diskBlockName(handle.Path, index)
+		flock := bc.fileLocks.Get(fileName)
+		flock.Lock()
+
+		localPath := filepath.Join(bc.tmpPath, fileName)
+		if _, err := os.Stat(localPath); err == nil {
+			bc.setBlockETag(localPath, etag)
+		}
+
+		flock.Unlock()
+	}
+}
+
+// diskBlockName : Name of the file holding given block of the blob in disk cache
+func diskBlockName(path string, index int64) string {
+	return fmt.Sprintf("%s::%v", path, index)
+}
+
+// reloadDiskCache : Adopt the blocks left on disk by the previous mount into the disk policy.
+// Blocks which can not be tied to a blob version or were cut with another block size are removed,
+// the rest are validated lazily on read.
+func (bc *BlockCache) reloadDiskCache() {
+	blocks := make([]diskBlock, 0)
+
+	_ = filepath.WalkDir(bc.tmpPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil || !strings.Contains(d.Name(), "::") || getBlockETag(path) == "" ||
+			getDiskBlockSize(path) != bc.blockSize {
+			log.Debug("BlockCache::reloadDiskCache : Removing unverifiable block %s", path)
+			_ = os.Remove(path)
+			return nil
+		}
+
+		name, _ := filepath.Rel(bc.tmpPath, path)
+		blocks = append(blocks, diskBlock{name: name, mtime: info.ModTime()})
+		return nil
+	})
+
+	// Least recently used blocks are added first so they remain first in line for eviction
+	sort.Slice(blocks, func(i, j int) bool {
+		return blocks[i].mtime.Before(blocks[j].mtime)
+	})
+
+	for _, block := range blocks {
+		bc.fileNodeMap.Store(block.name, bc.diskPolicy.Add(block.name))
+	}
+
+	log.Info("BlockCache::reloadDiskCache : Reloaded %d blocks from %s", len(blocks), bc.tmpPath)
+}
+
+// touchDiskBlock : Keep the last access of a persisted block so eviction order survives a remount
+func (bc *BlockCache) touchDiskBlock(localPath string, node *list.Element) {
+	bc.diskPolicy.Refresh(node)
+
+	if bc.persistCache {
+		now := time.Now()
+		_ = os.Chtimes(localPath, now, now)
+	}
+}
@@ -0,0 +1,284 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package block_cache
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-storage-fuse/v2/common/log"
+	"github.com/Azure/azure-storage-fuse/v2/internal/handlemap"
+)
+
+// Access pattern observed on a handle
+type accessPattern int
+
+const (
+	patternUnknown accessPattern = iota
+	patternSequential
+	patternStrided
+	patternReverse
+	patternRandom
+)
+
+func (p accessPattern) String() string {
+	switch p {
+	case patternSequential:
+		return "sequential"
+	case patternStrided:
+		return "strided"
+	case patternReverse:
+		return "reverse"
+	case patternRandom:
+		return "random"
+	}
+	return "unknown"
+}
+
+const (
+	// Number of same sized jumps needed before a stride is trusted
+	MIN_STRIDE_STREAK = 3
+
+	// Weight given to the latest sample in moving averages
+	raSmoothing = 0.25
+)
+
+// readAhead : Per handle access history used to decide what and how much to prefetch
+type readAhead struct {
+	sync.Mutex
+
+	lastIndex int64         // Last block index read by the application
+	lastRead  time.Time     // Time application moved to the last block
+	stride    int64         // Distance between the last two blocks read
+	streak    int           // Number of consecutive reads with same stride
+	pattern   accessPattern // Pattern detected so far
+	cursor    int64         // Next block to prefetch for strided and reverse patterns, -1 if none
+
+	interval float64 // Moving average of seconds application spends on a block
+	latency  float64 // Moving average of seconds taken to download a block
+
+	window    uint32 // Current number of blocks to keep in flight
+	base      uint32 // Configured prefetch count, window settles back here without pressure
+	maxWindow uint32 // Upper bound for the window
+	minWindow uint32 // Lower bound for the window
+}
+
+func newReadAhead(prefetch uint32) *readAhead {
+	return &readAhead{
+		lastIndex: -1,
+		cursor:    -1,
+		window:    prefetch,
+		base:      prefetch,
+		maxWindow: 2 * prefetch,
+		minWindow: min(MIN_PREFETCH, prefetch),
+	}
+}
+
+// getReadAhead : Get the read-ahead state attached to this handle
+func getReadAhead(handle *handlemap.Handle) *readAhead {
+	ra, found := handle.GetValue("readahead")
+	if !found {
+		return nil
+	}
+	return ra.(*readAhead)
+}
+
+// observe : Record that application read given block and update the detected pattern
+func (ra *readAhead) observe(index int64) {
+	ra.Lock()
+	defer ra.Unlock()
+
+	if index == ra.lastIndex {
+		return
+	}
+
+	now := time.Now()
+	if ra.lastIndex >= 0 {
+		ra.interval = smooth(ra.interval, now.Sub(ra.lastRead).Seconds())
+
+		delta := index - ra.lastIndex
+		if delta == ra.stride {
+			ra.streak++
+		} else {
+			ra.stride = delta
+			ra.streak = 1
+		}
+
+		switch {
+		case ra.streak < MIN_STRIDE_STREAK:
+			ra.pattern = patternRandom
+		case ra.stride == 1:
+			ra.pattern = patternSequential
+		case ra.stride < 0:
+			ra.pattern = patternReverse
+		default:
+			ra.pattern = patternStrided
+		}
+	}
+
+	ra.lastIndex = index
+	ra.lastRead = now
+}
+
+// predictable : Pattern is known well enough to prefetch along it
+func (ra *readAhead) predictable() bool {
+	ra.Lock()
+	defer ra.Unlock()
+	return ra.pattern == patternSequential || ra.pattern == patternStrided || ra.pattern == patternReverse
+}
+
+// step : Block expected to be read after given block, false if pattern runs out of the file
+func (ra *readAhead) step(index uint64) (uint64, bool) {
+	ra.Lock()
+	defer ra.Unlock()
+
+	if ra.pattern != patternStrided && ra.pattern != patternReverse {
+		return index + 1, true
+	}
+
+	next := int64(index) + ra.stride
+	if next < 0 {
+		return 0, false
+	}
+	return uint64(next), true
+}
+
+// setCursor : Remember where prefetch along a strided or reverse pattern shall continue
+func (ra *readAhead) setCursor(index uint64) {
+	next, ok := ra.step(index)
+
+	ra.Lock()
+	defer ra.Unlock()
+	if ok {
+		ra.cursor = int64(next)
+	} else {
+		ra.cursor = -1
+	}
+}
+
+// next : Block from which prefetching shall continue, false if there is nothing to prefetch
+func (ra *readAhead) next(sequential uint64) (uint64, bool) {
+	ra.Lock()
+	defer ra.Unlock()
+
+	if ra.pattern != patternStrided && ra.pattern != patternReverse {
+		return sequential, true
+	}
+	if ra.cursor < 0 {
+		return 0, false
+	}
+	return uint64(ra.cursor), true
+}
+
+// downloaded : Record time taken to bring a block in
+func (ra *readAhead) downloaded(took time.Duration) {
+	ra.Lock()
+	defer ra.Unlock()
+	ra.latency = smooth(ra.latency, took.Seconds())
+}
+
+// adjust : Scale the window as per download throughput and block pool pressure
+func (ra *readAhead) adjust(poolUsage uint32) uint32 {
+	ra.Lock()
+	defer ra.Unlock()
+
+	// Blocks that must be in flight so that download of a block completes before application reaches it
+	need := ra.window
+	if ra.interval > 0 {
+		need = uint32(ra.latency/ra.interval) + 1
+	}
+
+	window := ra.window
+	switch {
+	case poolUsage >= MAX_POOL_USAGE:
+		// Pool is running dry, give blocks back so other handles can progress
+		window = max(ra.minWindow, window/2)
+	case poolUsage < MIN_POOL_USAGE && need > window:
+		// Downloads are not keeping up with the application and there is room to go further ahead
+		window = min(ra.maxWindow, need)
+	case poolUsage < MIN_POOL_USAGE && window < ra.base:
+		window = min(ra.base, 2*window)
+	}
+
+	if window != ra.window {
+		log.Debug("readAhead::adjust : window %v -> %v (pattern %v, need %v, pool usage %v%%)", ra.window, window, ra.pattern, need, poolUsage)
+		ra.window = window
+	}
+
+	return ra.window
+}
+
+func smooth(avg float64, sample float64) float64 {
+	if avg == 0 {
+		return sample
+	}
+	return avg + raSmoothing*(sample-avg)
+}
+
+// reportDownload : Feed time taken by this download to read-ahead state of the handle
+func (item *workItem) reportDownload() {
+	if item.ra != nil && !item.queued.IsZero() {
+		item.ra.downloaded(time.Since(item.queued))
+	}
+}
+
+// releaseCookedBlocks : Return up to cnt already consumed blocks of this handle back to the pool
+func (bc *BlockCache) releaseCookedBlocks(handle *handlemap.Handle, cnt int) int {
+	released := 0
+	nodeList := handle.Buffers.Cooked
+
+	for node := nodeList.Front(); node != nil && released < cnt; {
+		block := node.Value.(*Block)
+		next := node.Next()
+
+		if !block.IsDirty() {
+			_ = nodeList.Remove(node)
+
+			// Remove entry of this block from map so that no one can find it
+			if block.id != -1 {
+				handle.RemoveValue(fmt.Sprintf("%v", block.id))
+			}
+			block.node = nil
+
+			block.ReUse()
+			bc.blockPool.Release(block)
+			released++
+		}
+
+		node = next
+	}
+
+	return released
+}
@@ -0,0 +1,151 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package block_cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type readAheadTestSuite struct {
+	suite.Suite
+	assert *assert.Assertions
+}
+
+func (suite *readAheadTestSuite) SetupTest() {
+	suite.assert = assert.New(suite.T())
+}
+
+func (suite *readAheadTestSuite) TestPatternDetection() {
+	tests := []struct {
+		name    string
+		indices []int64
+		pattern accessPattern
+		stride  int64
+	}{
+		{"sequential", []int64{0, 1, 2, 3}, patternSequential, 1},
+		{"strided", []int64{0, 4, 8, 12}, patternStrided, 4},
+		{"reverse", []int64{20, 19, 18, 17}, patternReverse, -1},
+		{"reverse strided", []int64{20, 17, 14, 11}, patternReverse, -3},
+		{"random", []int64{5, 1, 9, 2}, patternRandom, 0},
+		{"too short", []int64{0, 1, 2}, patternRandom, 0},
+	}
+
+	for _, tt := range tests {
+		suite.Run(tt.name, func() {
+			ra := newReadAhead(12)
+			for _, index := range tt.indices {
+				ra.observe(index)
+				// Repeated reads from same block do not change the pattern
+				ra.observe(index)
+			}
+
+			suite.assert.Equal(tt.pattern, ra.pattern)
+			if tt.pattern != patternRandom {
+				suite.assert.Equal(tt.stride, ra.stride)
+				suite.assert.True(ra.predictable())
+			} else {
+				suite.assert.False(ra.predictable())
+			}
+		})
+	}
+}
+
+func (suite *readAheadTestSuite) TestStep() {
+	ra := newReadAhead(12)
+	next, ok := ra.step(5)
+	suite.assert.True(ok)
+	suite.assert.EqualValues(6, next)
+
+	for _, index := range []int64{10, 8, 6, 4} {
+		ra.observe(index)
+	}
+	next, ok = ra.step(4)
+	suite.assert.True(ok)
+	suite.assert.EqualValues(2, next)
+
+	_, ok = ra.step(1)
+	suite.assert.False(ok)
+
+	// Prefetch continues from the cursor and stops at the start of the file
+	ra.setCursor(2)
+	next, ok = ra.next(100)
+	suite.assert.True(ok)
+	suite.assert.EqualValues(0, next)
+
+	ra.setCursor(0)
+	_, ok = ra.next(100)
+	suite.assert.False(ok)
+}
+
+func (suite *readAheadTestSuite) TestAdjustWindow() {
+	ra := newReadAhead(12)
+	suite.assert.EqualValues(12, ra.window)
+
+	// Pool pressure halves the window down to the minimum
+	suite.assert.EqualValues(6, ra.adjust(MAX_POOL_USAGE))
+	suite.assert.EqualValues(MIN_PREFETCH, ra.adjust(MAX_POOL_USAGE+10))
+	suite.assert.EqualValues(MIN_PREFETCH, ra.adjust(100))
+
+	// Once pressure is gone window goes back to configured prefetch
+	suite.assert.EqualValues(10, ra.adjust(0))
+	suite.assert.EqualValues(12, ra.adjust(0))
+	suite.assert.EqualValues(12, ra.adjust(0))
+
+	// Downloads slower than the application push the window further ahead
+	ra.interval = (10 * time.Millisecond).Seconds()
+	ra.downloaded(150 * time.Millisecond)
+	suite.assert.EqualValues(16, ra.adjust(0))
+
+	// but never past twice the configured prefetch
+	ra.downloaded(time.Second)
+	suite.assert.EqualValues(24, ra.adjust(0))
+
+	// and not while pool is moderately used
+	ra.window = 12
+	suite.assert.EqualValues(12, ra.adjust(MIN_POOL_USAGE))
+}
+
+func (suite *readAheadTestSuite) TestNoPrefetch() {
+	ra := newReadAhead(0)
+	suite.assert.EqualValues(0, ra.adjust(100))
+	suite.assert.EqualValues(0, ra.adjust(0))
+}
+
+func TestReadAheadSuite(t *testing.T) {
+	suite.Run(t, new(readAheadTestSuite))
+}
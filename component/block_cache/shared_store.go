@@ -0,0 +1,322 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package block_cache
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-storage-fuse/v2/common"
+	"github.com/Azure/azure-storage-fuse/v2/common/log"
+	"github.com/Azure/azure-storage-fuse/v2/internal/stats_manager"
+)
+
+// Number of writes to the shared disk region after which its size is checked
+const sharedDiskCheckInterval = 64
+
+// sharedBlock : One block of a blob version held in the shared store
+type sharedBlock struct {
+	key   string
+	block *Block        // Block from pool holding the data, nil until published
+	size  int           // Valid bytes in the block
+	ready chan struct{} // Closed once the download completes or is abandoned
+	node  *list.Element // Position in lru, nil while download is in flight
+}
+
+// sharedStore : Node wide store of downloaded blocks, shared by all handles of the mount
+// and optionally by other mounts through a common disk directory.
+// Blocks are keyed by container, blob name, ETag, block size and block index so a changed blob never hits stale data
+// and mounts of different subdirectories or block sizes never mix up their blocks.
+type sharedStore struct {
+	sync.Mutex
+
+	pool       *BlockPool
+	prefix     string // account/container this mount serves
+	prefixPath string // Subdirectory of the container this mount serves
+	blockSize  uint64
+	entries    map[string]*sharedBlock
+	lru        *list.List // Published entries, most recent at front
+	maxBlocks  int        // Maximum pool blocks the store may keep
+
+	diskPath   string // Directory shared across mounts, empty if disabled
+	diskSize   uint64 // Size limit of the shared directory in bytes
+	diskWrites int
+}
+
+func newSharedStore(pool *BlockPool, prefix string, prefixPath string, blockSize uint64, maxBlocks int, diskPath string, diskSize uint64) *sharedStore {
+	return &sharedStore{
+		pool:       pool,
+		prefix:     prefix,
+		prefixPath: prefixPath,
+		blockSize:  blockSize,
+		entries:    make(map[string]*sharedBlock),
+		lru:        list.New(),
+		maxBlocks:  maxBlocks,
+		diskPath:   diskPath,
+		diskSize:   diskSize,
+	}
+}
+
+// key : Identity of a block of a blob version, path is relative to the mounted subdirectory
+func (ss *sharedStore) key(path string, etag string, index int64) string {
+	return fmt.Sprintf("%s/%s/%s/%v/%v", ss.prefix, filepath.Join(ss.prefixPath, path), etag, ss.blockSize, index)
+}
+
+// lookup : Find the block for this key. When nobody has it yet an in-flight entry is created and
+// caller becomes its owner, responsible for calling publish or abandon once download is over.
+func (ss *sharedStore) lookup(key string) (*sharedBlock, bool) {
+	ss.Lock()
+	defer ss.Unlock()
+
+	sb, found := ss.entries[key]
+	if found {
+		if sb.node != nil {
+			ss.lru.MoveToFront(sb.node)
+		}
+		return sb, false
+	}
+
+	sb = &sharedBlock{key: key, ready: make(chan struct{})}
+	ss.entries[key] = sb
+	return sb, true
+}
+
+// wait : Wait for the owner to download the block and copy it out, false if owner could not get it
+func (ss *sharedStore) wait(sb *sharedBlock, data []byte) (int, bool) {
+	<-sb.ready
+
+	ss.Lock()
+	block := sb.block
+	size := sb.size
+	if block != nil {
+		// Hold a reference so block does not go back to pool if evicted while copying
+		ss.pool.Acquire(block)
+	}
+	ss.Unlock()
+
+	if block == nil {
+		return 0, false
+	}
+
+	n := copy(data, block.data[:size])
+	ss.pool.Release(block)
+	return n, true
+}
+
+// publish : Make the downloaded data available to others and wake up the waiters
+func (ss *sharedStore) publish(sb *sharedBlock, data []byte) {
+	defer close(sb.ready)
+
+	ss.writeDisk(sb.key, data)
+
+	ss.Lock()
+	defer ss.Unlock()
+
+	if ss.lru.Len() >= ss.maxBlocks {
+		ss.evict(ss.lru.Len() - ss.maxBlocks + 1)
+	}
+
+	block := ss.pool.TryGet()
+	if block == nil {
+		// Pool is busy serving handles, waiters will copy nothing and download on their own
+		delete(ss.entries, sb.key)
+		return
+	}
+
+	// Store holds the first reference on the block
+	ss.pool.Acquire(block)
+	sb.size = copy(block.data, data)
+	sb.block = block
+	sb.node = ss.lru.PushFront(sb)
+}
+
+// abandon : Owner failed to get the block, let the waiters try on their own
+func (ss *sharedStore) abandon(sb *sharedBlock) {
+	ss.Lock()
+	delete(ss.entries, sb.key)
+	ss.Unlock()
+
+	close(sb.ready)
+}
+
+// evict : Drop least recently used blocks, caller shall hold the lock
+func (ss *sharedStore) evict(cnt int) {
+	for ; cnt > 0 && ss.lru.Len() > 0; cnt-- {
+		sb := ss.lru.Remove(ss.lru.Back()).(*sharedBlock)
+		delete(ss.entries, sb.key)
+		sb.node = nil
+
+		// Readers still copying hold their own reference, block returns to pool after the last one
+		ss.pool.Release(sb.block)
+		sb.block = nil
+	}
+}
+
+// purge : Drop everything held in memory
+func (ss *sharedStore) purge() {
+	ss.Lock()
+	defer ss.Unlock()
+	ss.evict(ss.lru.Len())
+}
+
+// diskFile : Name of the file holding this block in shared directory
+func (ss *sharedStore) diskFile(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(ss.diskPath, hex.EncodeToString(sum[:]))
+}
+
+// readDisk : Get the block from the directory shared with other mounts, data is sized to the expected length of the block
+func (ss *sharedStore) readDisk(key string, data []byte) (int, bool) {
+	if ss.diskPath == "" {
+		return 0, false
+	}
+
+	path := ss.diskFile(key)
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+
+	// Anything but the exact length of the block is a broken or foreign file, never serve it
+	info, err := f.Stat()
+	if err != nil || info.Size() != int64(len(data)) || len(data) == 0 {
+		log.Warn("sharedStore::readDisk : Discarding %s, does not match block length %d", path, len(data))
+		_ = os.Remove(path)
+		return 0, false
+	}
+
+	n, err := io.ReadFull(f, data)
+	if err != nil {
+		log.Err("sharedStore::readDisk : Failed to read %s [%s]", path, err.Error())
+		return 0, false
+	}
+
+	// Keep recently used blocks from being trimmed
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+	return n, true
+}
+
+// writeDisk : Save the block in the directory shared with other mounts
+func (ss *sharedStore) writeDisk(key string, data []byte) {
+	if ss.diskPath == "" {
+		return
+	}
+
+	path := ss.diskFile(key)
+	if _, err := os.Stat(path); err == nil {
+		return
+	}
+
+	// Other mounts may read this file at any time, so make it visible only once fully written
+	tmp := fmt.Sprintf("%s.%d.tmp", path, os.Getpid())
+	err := os.WriteFile(tmp, data, 0644)
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		log.Err("sharedStore::writeDisk : Failed to write %s [%s]", path, err.Error())
+		_ = os.Remove(tmp)
+		return
+	}
+
+	ss.Lock()
+	ss.diskWrites++
+	check := ss.diskWrites%sharedDiskCheckInterval == 0
+	ss.Unlock()
+
+	if check {
+		ss.trimDisk()
+	}
+}
+
+// trimDisk : Remove least recently used files once shared directory grows beyond its limit
+func (ss *sharedStore) trimDisk() {
+	usage, err := common.GetUsage(ss.diskPath)
+	if err != nil || uint64(usage*float64(_1MB)) <= ss.diskSize {
+		return
+	}
+
+	entries, err := os.ReadDir(ss.diskPath)
+	if err != nil {
+		return
+	}
+
+	type diskEntry struct {
+		name  string
+		size  int64
+		mtime time.Time
+	}
+
+	files := make([]diskEntry, 0, len(entries))
+	for _, e := range entries {
+		info, err := e.Info()
+		if err == nil && info.Mode().IsRegular() {
+			files = append(files, diskEntry{e.Name(), info.Size(), info.ModTime()})
+		}
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].mtime.Before(files[j].mtime)
+	})
+
+	// Bring usage down to low watermark so trimming does not kick in on every write
+	excess := int64(usage*float64(_1MB)) - int64(ss.diskSize*uint64(MIN_POOL_USAGE)/100)
+	for _, f := range files {
+		if excess <= 0 {
+			break
+		}
+		if os.Remove(filepath.Join(ss.diskPath, f.name)) == nil {
+			excess -= f.size
+		}
+	}
+
+	log.Info("sharedStore::trimDisk : Trimmed shared directory %s", ss.diskPath)
+}
+
+// servedShared : Mark the block downloaded when its data came from the shared store
+func (bc *BlockCache) servedShared(item *workItem) {
+	blockCacheStatsCollector.UpdateStats(stats_manager.Increment, sharedServed, (int64)(1))
+	item.reportDownload()
+	item.block.Ready(BlockStatusDownloaded)
+}
@@ -0,0 +1,187 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package block_cache
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type sharedStoreTestSuite struct {
+	suite.Suite
+	assert *assert.Assertions
+	pool   *BlockPool
+}
+
+func (suite *sharedStoreTestSuite) SetupTest() {
+	suite.assert = assert.New(suite.T())
+	suite.pool = NewBlockPool(_1MB, 10*_1MB)
+	suite.assert.NotNil(suite.pool)
+}
+
+func (suite *sharedStoreTestSuite) TearDownTest() {
+	suite.pool.Terminate()
+}
+
+func (suite *sharedStoreTestSuite) TestSingleDownload() {
+	ss := newSharedStore(suite.pool, "account/container", "", _1MB, 4, "", 0)
+	key := ss.key("dir/file", "etag1", 3)
+	suite.assert.Equal("account/container/dir/file/etag1/1048576/3", key)
+
+	sb, owner := ss.lookup(key)
+	suite.assert.True(owner)
+
+	// Everyone else asking for the same block waits for the owner
+	var wg sync.WaitGroup
+	results := make([][]byte, 5)
+	for i := range results {
+		other, owner := ss.lookup(key)
+		suite.assert.False(owner)
+		suite.assert.Same(sb, other)
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = make([]byte, _1MB)
+			n, ok := ss.wait(other, results[i])
+			suite.assert.True(ok)
+			results[i] = results[i][:n]
+		}(i)
+	}
+
+	ss.publish(sb, []byte("block data"))
+	wg.Wait()
+
+	for _, data := range results {
+		suite.assert.Equal([]byte("block data"), data)
+	}
+
+	// Different version of the blob does not share the block
+	_, owner = ss.lookup(ss.key("dir/file", "etag2", 3))
+	suite.assert.True(owner)
+}
+
+func (suite *sharedStoreTestSuite) TestAbandon() {
+	ss := newSharedStore(suite.pool, "account/container", "", _1MB, 4, "", 0)
+	key := ss.key("file", "etag", 0)
+
+	sb, _ := ss.lookup(key)
+	other, _ := ss.lookup(key)
+	ss.abandon(sb)
+
+	data := make([]byte, _1MB)
+	_, ok := ss.wait(other, data)
+	suite.assert.False(ok)
+
+	// Next reader becomes the owner and retries the download
+	_, owner := ss.lookup(key)
+	suite.assert.True(owner)
+}
+
+func (suite *sharedStoreTestSuite) TestEvictionRefCount() {
+	ss := newSharedStore(suite.pool, "account/container", "", _1MB, 2, "", 0)
+	for i := range 3 {
+		sb, owner := ss.lookup(ss.key("file", "etag", int64(i)))
+		suite.assert.True(owner)
+		ss.publish(sb, []byte("data"))
+	}
+
+	// Store never keeps more than its share of the pool
+	suite.assert.Equal(2, ss.lru.Len())
+	_, owner := ss.lookup(ss.key("file", "etag", 0))
+	suite.assert.True(owner)
+
+	// A reader holding a block keeps it out of the pool even after eviction
+	sb := ss.entries[ss.key("file", "etag", 1)]
+	block := sb.block
+	suite.pool.Acquire(block)
+	ss.purge()
+	suite.assert.EqualValues(1, block.refs.Load())
+	suite.pool.Release(block)
+	suite.assert.EqualValues(0, block.refs.Load())
+	suite.assert.Equal(0, ss.lru.Len())
+}
+
+func (suite *sharedStoreTestSuite) TestSharedDisk() {
+	dir := filepath.Join(os.TempDir(), "bc_shared_"+randomString(8))
+	defer os.RemoveAll(dir)
+	suite.assert.NoError(os.MkdirAll(dir, 0777))
+
+	// Two stores stand for two mounts using the same directory
+	first := newSharedStore(suite.pool, "account/container", "", _1MB, 4, dir, 100*_1MB)
+	second := newSharedStore(suite.pool, "account/container", "", _1MB, 4, dir, 100*_1MB)
+	key := first.key("file", "etag", 0)
+
+	sb, _ := first.lookup(key)
+	first.publish(sb, []byte("block data"))
+
+	data := make([]byte, _1MB)
+	n, ok := second.readDisk(key, data[:10])
+	suite.assert.True(ok)
+	suite.assert.Equal([]byte("block data"), data[:n])
+
+	_, ok = second.readDisk(first.key("file", "etag2", 0), data[:10])
+	suite.assert.False(ok)
+
+	// Other containers do not see the block
+	third := newSharedStore(suite.pool, "account/other", "", _1MB, 4, dir, 100*_1MB)
+	_, ok = third.readDisk(third.key("file", "etag", 0), data[:10])
+	suite.assert.False(ok)
+
+	// Mount of a subdirectory sees the same blob, unless it cuts blocks of another size
+	subdir := newSharedStore(suite.pool, "account/container", "dir", _1MB, 4, dir, 100*_1MB)
+	first.writeDisk(first.key("dir/file", "etag", 0), []byte("block data"))
+	n, ok = subdir.readDisk(subdir.key("file", "etag", 0), data[:10])
+	suite.assert.True(ok)
+	suite.assert.Equal([]byte("block data"), data[:n])
+
+	resized := newSharedStore(suite.pool, "account/container", "", 2*_1MB, 4, dir, 100*_1MB)
+	_, ok = resized.readDisk(resized.key("file", "etag", 0), data[:10])
+	suite.assert.False(ok)
+
+	// File which does not match the length of the block is never served
+	_, ok = second.readDisk(key, data[:20])
+	suite.assert.False(ok)
+	_, ok = second.readDisk(key, data[:10])
+	suite.assert.False(ok)
+}
+
+func TestSharedStoreSuite(t *testing.T) {
+	suite.Run(t, new(sharedStoreTestSuite))
+}
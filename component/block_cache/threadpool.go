@@ -35,7 +35,9 @@ package block_cache
 
 import (
 	"sync"
+	"time"
 
+	"github.com/Azure/azure-storage-fuse/v2/internal"
 	"github.com/Azure/azure-storage-fuse/v2/internal/handlemap"
 )
 
@@ -50,9 +52,10 @@ type ThreadPool struct {
 	// Wait group to wait for all workers to finish
 	wg sync.WaitGroup
 
-	// Channel to hold pending requests
-	priorityCh chan *workItem
-	normalCh   chan *workItem
+	// Channel to hold pending requests, workers drain them in this order
+	priorityCh chan *workItem // Reads a user is waiting on
+	prefetchCh chan *workItem // Read ahead of the user
+	normalCh   chan *workItem // Background uploads
 
 	// Reader method that will actually read the data
 	reader func(*workItem)
@@ -63,13 +66,16 @@ type ThreadPool struct {
 
 // One workitem to be scheduled
 type workItem struct {
-	handle   *handlemap.Handle // Handle to which this item belongs
-	block    *Block            // Block to hold data for this item
-	prefetch bool              // Flag marking this is a prefetch request or not
-	failCnt  int32             // How many times this item has failed to download
-	upload   bool              // Flag marking this is a upload request or not
-	blockId  string            // BlockId of the block
-	ETag     string            // Etag of the file before scheduling.
+	handle   *handlemap.Handle   // Handle to which this item belongs
+	block    *Block              // Block to hold data for this item
+	prefetch bool                // Flag marking this is a prefetch request or not
+	priority internal.IOPriority // Queue of the item in the pool and priority of its storage request
+	failCnt  int32               // How many times this item has failed to download
+	upload   bool                // Flag marking this is a upload request or not
+	blockId  string              // BlockId of the block
+	ETag     string              // Etag of the file before scheduling.
+	queued   time.Time           // Time this item was scheduled
+	ra       *readAhead          // Read-ahead state of the handle, to report download time
 }
 
 // Reason for storing Etag in workitem struct:
@@ -93,6 +99,7 @@ func newThreadPool(count uint32, reader func(*workItem), writer func(*workItem))
 		writer:     writer,
 		close:      make(chan int, count),
 		priorityCh: make(chan *workItem, count*2),
+		prefetchCh: make(chan *workItem, count*5000),
 		normalCh:   make(chan *workItem, count*5000),
 	}
 }
@@ -118,15 +125,36 @@ func (t *ThreadPool) Stop() {
 
 	close(t.close)
 	close(t.priorityCh)
+	close(t.prefetchCh)
 	close(t.normalCh)
 }
 
-// Schedule the download of a block
-func (t *ThreadPool) Schedule(urgent bool, item *workItem) {
-	// urgent specifies the priority of this task.
-	// true means high priority and false means low priority
-	if urgent {
+// Schedule the download or upload of a block in the queue of its priority
+func (t *ThreadPool) Schedule(item *workItem) {
+	switch item.priority {
+	case internal.IOPriorityForeground:
 		t.priorityCh <- item
+	case internal.IOPriorityPrefetch:
+		t.prefetchCh <- item
+	default:
+		t.normalCh <- item
+	}
+}
+
+// Reschedule an item which has failed.
+// Workers calling this also drain the high priority channel, so a foreground retry that does not fit in it
+// is queued as a background item instead of blocking the worker.
+func (t *ThreadPool) Reschedule(item *workItem) {
+	if item.priority == internal.IOPriorityForeground {
+		select {
+		case t.priorityCh <- item:
+			return
+		default:
+		}
+	}
+
+	if item.priority == internal.IOPriorityPrefetch {
+		t.prefetchCh <- item
 	} else {
 		t.normalCh <- item
 	}
@@ -136,39 +164,54 @@ func (t *ThreadPool) Schedule(urgent bool, item *workItem) {
 func (t *ThreadPool) Do(priority bool) {
 	defer t.wg.Done()
 
+	for {
+		item := t.next(priority)
+		if item == nil {
+			return
+		}
+
+		if item.upload {
+			t.writer(item)
+		} else {
+			t.reader(item)
+		}
+	}
+}
+
+// next waits for the next item to process, nil when the pool is stopped.
+// Select picks randomly among the ready channels, so the channels are polled in priority order before
+// blocking on all of them. This way a foreground read never waits behind queued prefetches or uploads.
+func (t *ThreadPool) next(priority bool) *workItem {
+	select {
+	case item := <-t.priorityCh:
+		return item
+	default:
+	}
+
 	if priority {
 		// This thread will work only on high priority channel
-		for {
-			select {
-			case item := <-t.priorityCh:
-				if item.upload {
-					t.writer(item)
-				} else {
-					t.reader(item)
-				}
-			case <-t.close:
-				return
-			}
-		}
-	} else {
-		// This thread will work only on both high and low priority channel
-		for {
-			select {
-			case item := <-t.priorityCh:
-				if item.upload {
-					t.writer(item)
-				} else {
-					t.reader(item)
-				}
-			case item := <-t.normalCh:
-				if item.upload {
-					t.writer(item)
-				} else {
-					t.reader(item)
-				}
-			case <-t.close:
-				return
-			}
+		select {
+		case item := <-t.priorityCh:
+			return item
+		case <-t.close:
+			return nil
 		}
 	}
+
+	select {
+	case item := <-t.prefetchCh:
+		return item
+	default:
+	}
+
+	select {
+	case item := <-t.priorityCh:
+		return item
+	case item := <-t.prefetchCh:
+		return item
+	case item := <-t.normalCh:
+		return item
+	case <-t.close:
+		return nil
+	}
 }
@@ -41,6 +41,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/Azure/azure-storage-fuse/v2/internal"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 )
@@ -103,8 +104,9 @@ func (suite *threadPoolTestSuite) TestSchedule() {
 	suite.assert.NotNil(tp.priorityCh)
 	suite.assert.NotNil(tp.normalCh)
 
-	tp.Schedule(false, &workItem{failCnt: 1})
-	tp.Schedule(true, &workItem{failCnt: 1})
+	tp.Schedule(&workItem{failCnt: 1, priority: internal.IOPriorityUpload})
+	tp.Schedule(&workItem{failCnt: 1, priority: internal.IOPriorityPrefetch})
+	tp.Schedule(&workItem{failCnt: 1})
 
 	time.Sleep(1 * time.Second)
 	tp.Stop()
@@ -128,7 +130,11 @@ func (suite *threadPoolTestSuite) TestPrioritySchedule() {
 	suite.assert.NotNil(tp.normalCh)
 
 	for i := range 100 {
-		tp.Schedule(i < 20, &workItem{failCnt: 5})
+		priority := internal.IOPriorityPrefetch
+		if i < 20 {
+			priority = internal.IOPriorityForeground
+		}
+		tp.Schedule(&workItem{failCnt: 5, priority: priority})
 	}
 
 	time.Sleep(1 * time.Second)
@@ -160,7 +166,11 @@ func (suite *threadPoolTestSuite) TestPriorityScheduleWithWriter() {
 	suite.assert.NotNil(tp.normalCh)
 
 	for i := range 100 {
-		tp.Schedule(i < 20, &workItem{failCnt: 5, upload: true, blockId: "test"})
+		priority := internal.IOPriorityUpload
+		if i < 20 {
+			priority = internal.IOPriorityForeground
+		}
+		tp.Schedule(&workItem{failCnt: 5, upload: true, blockId: "test", priority: priority})
 	}
 
 	time.Sleep(1 * time.Second)
@@ -169,6 +179,78 @@ func (suite *threadPoolTestSuite) TestPriorityScheduleWithWriter() {
 	tp.Stop()
 }
 
+func (suite *threadPoolTestSuite) TestScheduleOrder() {
+	suite.assert = assert.New(suite.T())
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	order := make(chan internal.IOPriority, 10)
+	r := func(i *workItem) {
+		if i.failCnt == 1 {
+			// Hold the only worker till all the other items are queued
+			close(started)
+			<-release
+			return
+		}
+		order <- i.priority
+	}
+
+	tp := newThreadPool(1, r, r)
+	suite.assert.NotNil(tp)
+	tp.Start()
+
+	tp.Schedule(&workItem{failCnt: 1})
+	<-started
+
+	tp.Schedule(&workItem{priority: internal.IOPriorityUpload, upload: true})
+	tp.Schedule(&workItem{priority: internal.IOPriorityPrefetch})
+	tp.Schedule(&workItem{priority: internal.IOPriorityUpload, upload: true})
+	tp.Schedule(&workItem{priority: internal.IOPriorityPrefetch})
+	tp.Schedule(&workItem{priority: internal.IOPriorityForeground})
+	close(release)
+
+	expected := []internal.IOPriority{
+		internal.IOPriorityForeground,
+		internal.IOPriorityPrefetch,
+		internal.IOPriorityPrefetch,
+		internal.IOPriorityUpload,
+		internal.IOPriorityUpload,
+	}
+	for _, priority := range expected {
+		select {
+		case p := <-order:
+			suite.assert.Equal(priority, p)
+		case <-time.After(5 * time.Second):
+			suite.assert.Fail("item not processed")
+		}
+	}
+
+	tp.Stop()
+}
+
+func (suite *threadPoolTestSuite) TestReschedule() {
+	suite.assert = assert.New(suite.T())
+
+	tp := newThreadPool(1, func(*workItem) {}, nil)
+	suite.assert.NotNil(tp)
+
+	// Without workers the high priority channel fills up and the retry goes to the background channel
+	for range cap(tp.priorityCh) {
+		tp.Reschedule(&workItem{})
+	}
+	suite.assert.Len(tp.priorityCh, cap(tp.priorityCh))
+	suite.assert.Empty(tp.normalCh)
+
+	tp.Reschedule(&workItem{})
+	suite.assert.Len(tp.normalCh, 1)
+
+	tp.Reschedule(&workItem{priority: internal.IOPriorityPrefetch})
+	suite.assert.Len(tp.prefetchCh, 1)
+
+	tp.Start()
+	tp.Stop()
+}
+
 func TestThreadPoolSuite(t *testing.T) {
 	suite.Run(t, new(threadPoolTestSuite))
 }
@@ -0,0 +1,254 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package compression
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Layout of a compressed blob:
+//
+//	Logical data is split in blocks and each block is compressed on its own, so a read only needs to
+//	download and decompress the blocks it touches. Blob is the concatenation of the compressed blocks.
+//
+//	Block index lives in the metadata of the blob: the algorithm, the logical size of the file, the
+//	logical size of the blocks and the stored size of each block, as a base64 encoded list of varints.
+//	When the blocks are not of the same logical size, except the last one, the index holds the logical
+//	and stored size of each block and the block size is recorded as 0.
+
+const (
+	metaPrefix    = "bfcmp_"
+	metaAlgorithm = metaPrefix + "algo"
+	metaSize      = metaPrefix + "size"
+	metaBlockSize = metaPrefix + "block"
+	metaIndex     = metaPrefix + "index"
+)
+
+// Service limits all the metadata of a blob to 8KB, rest of it is left for other metadata
+const maxIndexLength = 6 * 1024
+
+var errIndexTooLarge = errors.New("block index does not fit in metadata")
+
+// maxIndexBlocks : Number of blocks of the given size the index is sure to hold, stored blocks are never larger than
+// their data and all blocks but the last one are described by their stored size alone
+func maxIndexBlocks(blockSize int64) int64 {
+	entry := int64(len(binary.AppendUvarint(nil, uint64(blockSize))))
+	return (int64(base64.RawStdEncoding.DecodedLen(maxIndexLength)) - entry) / entry
+}
+
+// blockEntry : Position of one block in the file and in the blob
+type blockEntry struct {
+	offset    int64 // logical offset in the file
+	size      int64 // logical size
+	rawOffset int64 // offset of the stored block in the blob
+	rawSize   int64 // stored size
+}
+
+// blockIndex : Blocks of a compressed blob
+type blockIndex struct {
+	algo   string
+	size   int64
+	blocks []blockEntry
+}
+
+// newBlockIndex : Lay out the blocks given by their logical and stored sizes
+func newBlockIndex(algo string, sizes [][2]int64) *blockIndex {
+	idx := &blockIndex{algo: algo, blocks: make([]blockEntry, 0, len(sizes))}
+
+	rawOffset := int64(0)
+	for _, s := range sizes {
+		idx.blocks = append(idx.blocks, blockEntry{offset: idx.size, size: s[0], rawOffset: rawOffset, rawSize: s[1]})
+		idx.size += s[0]
+		rawOffset += s[1]
+	}
+
+	return idx
+}
+
+// rawSize : Size of the blob holding the stored blocks
+func (idx *blockIndex) rawSize() int64 {
+	if len(idx.blocks) == 0 {
+		return 0
+	}
+	last := idx.blocks[len(idx.blocks)-1]
+	return last.rawOffset + last.rawSize
+}
+
+// find : Index of the block holding the given logical offset
+func (idx *blockIndex) find(offset int64) int {
+	return sort.Search(len(idx.blocks), func(i int) bool {
+		return idx.blocks[i].offset+idx.blocks[i].size > offset
+	})
+}
+
+// uniformSize : Logical size shared by all blocks except the last one, 0 if there is no such size
+func (idx *blockIndex) uniformSize() int64 {
+	if len(idx.blocks) == 0 {
+		return 0
+	}
+
+	size := idx.blocks[0].size
+	for i, block := range idx.blocks {
+		if block.size != size && (i != len(idx.blocks)-1 || block.size > size) {
+			return 0
+		}
+	}
+	return size
+}
+
+// metadata : Encode the index in the metadata keys of the blob
+func (idx *blockIndex) metadata() (map[string]*string, error) {
+	blockSize := idx.uniformSize()
+
+	buf := make([]byte, 0, len(idx.blocks)*6)
+	for i, block := range idx.blocks {
+		if blockSize == 0 || i == len(idx.blocks)-1 {
+			// Last block is always described completely as it may be shorter
+			buf = binary.AppendUvarint(buf, uint64(block.size))
+		}
+		buf = binary.AppendUvarint(buf, uint64(block.rawSize))
+	}
+
+	index := base64.RawStdEncoding.EncodeToString(buf)
+	if len(index) > maxIndexLength {
+		return nil, errIndexTooLarge
+	}
+
+	algo := idx.algo
+	size := strconv.FormatInt(idx.size, 10)
+	block := strconv.FormatInt(blockSize, 10)
+	return map[string]*string{
+		metaAlgorithm: &algo,
+		metaSize:      &size,
+		metaBlockSize: &block,
+		metaIndex:     &index,
+	}, nil
+}
+
+// metaValue : Metadata keys are case insensitive in the service
+func metaValue(metadata map[string]*string, key string) (string, bool) {
+	for k, v := range metadata {
+		if v != nil && strings.EqualFold(k, key) {
+			return *v, true
+		}
+	}
+	return "", false
+}
+
+// isCompressed : Check whether the metadata of a blob marks it as written by this component
+func isCompressed(metadata map[string]*string) bool {
+	_, found := metaValue(metadata, metaAlgorithm)
+	return found
+}
+
+// isCompressionMetaKey : Metadata keys owned by this component
+func isCompressionMetaKey(key string) bool {
+	return strings.HasPrefix(strings.ToLower(key), metaPrefix)
+}
+
+// logicalSize : Size of the file held by a compressed blob
+func logicalSize(metadata map[string]*string) (int64, bool) {
+	value, found := metaValue(metadata, metaSize)
+	if !found {
+		return 0, false
+	}
+
+	size, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return size, true
+}
+
+// parseBlockIndex : Decode the block index from the metadata of a blob
+func parseBlockIndex(metadata map[string]*string) (*blockIndex, error) {
+	algo, _ := metaValue(metadata, metaAlgorithm)
+	size, ok := logicalSize(metadata)
+	if !ok {
+		return nil, fmt.Errorf("invalid %s", metaSize)
+	}
+
+	value, _ := metaValue(metadata, metaBlockSize)
+	blockSize, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s [%s]", metaBlockSize, err.Error())
+	}
+
+	value, _ = metaValue(metadata, metaIndex)
+	buf, err := base64.RawStdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s [%s]", metaIndex, err.Error())
+	}
+
+	values := make([]int64, 0)
+	for len(buf) > 0 {
+		v, n := binary.Uvarint(buf)
+		if n <= 0 {
+			return nil, fmt.Errorf("invalid %s", metaIndex)
+		}
+		values = append(values, int64(v))
+		buf = buf[n:]
+	}
+
+	sizes := make([][2]int64, 0)
+	if blockSize == 0 {
+		if len(values)%2 != 0 {
+			return nil, fmt.Errorf("invalid %s", metaIndex)
+		}
+		for i := 0; i < len(values); i += 2 {
+			sizes = append(sizes, [2]int64{values[i], values[i+1]})
+		}
+	} else if len(values) > 0 {
+		if len(values) < 2 {
+			return nil, fmt.Errorf("invalid %s", metaIndex)
+		}
+		for _, v := range values[:len(values)-2] {
+			sizes = append(sizes, [2]int64{blockSize, v})
+		}
+		sizes = append(sizes, [2]int64{values[len(values)-2], values[len(values)-1]})
+	}
+
+	idx := newBlockIndex(algo, sizes)
+	if idx.size != size {
+		return nil, fmt.Errorf("block index covers %d bytes, file has %d", idx.size, size)
+	}
+
+	return idx, nil
+}
@@ -0,0 +1,223 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package compression
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+)
+
+// Algorithms supported to compress the blocks of a file
+const (
+	algoZstd = "zstd"
+	algoGzip = "gzip"
+	algoLz4  = "lz4"
+)
+
+var errCorrupt = errors.New("compressed block is corrupt")
+
+// codec : Compress and decompress one block of a file at a time
+type codec interface {
+	compress(src []byte) ([]byte, error)
+	decompress(src []byte, size int) ([]byte, error)
+}
+
+// newCodec : Create the codec of an algorithm, level 0 selects the default level of the algorithm
+func newCodec(algo string, level int) (codec, error) {
+	switch algo {
+	case algoZstd:
+		return newZstdCodec(level)
+	case algoGzip:
+		if level == 0 {
+			level = gzip.DefaultCompression
+		} else if level < gzip.BestSpeed || level > gzip.BestCompression {
+			return nil, fmt.Errorf("gzip level shall be between %d and %d", gzip.BestSpeed, gzip.BestCompression)
+		}
+		return &gzipCodec{level: level}, nil
+	case algoLz4:
+		if level < 0 || level > 9 {
+			return nil, fmt.Errorf("lz4 level shall be between 0 and 9")
+		}
+		return &lz4Codec{level: level}, nil
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %s", algo)
+	}
+}
+
+// packBlock : Compress a block, it is stored as is when compression does not make it smaller.
+// A block whose stored size equals its logical size is therefore never compressed.
+func packBlock(c codec, data []byte) ([]byte, error) {
+	packed, err := c.compress(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(packed) >= len(data) {
+		return data, nil
+	}
+	return packed, nil
+}
+
+// unpackBlock : Restore the logical data of a stored block
+func unpackBlock(c codec, stored []byte, size int) ([]byte, error) {
+	if len(stored) == size {
+		return stored, nil
+	}
+
+	data, err := c.decompress(stored, size)
+	if err != nil || len(data) != size {
+		return nil, errCorrupt
+	}
+	return data, nil
+}
+
+// ------------------------- zstd -------------------------------------------
+
+type zstdCodec struct {
+	encoder *zstd.Encoder
+	decoder *zstd.Decoder
+}
+
+func newZstdCodec(level int) (*zstdCodec, error) {
+	encLevel := zstd.SpeedDefault
+	if level != 0 {
+		if level < 1 || level > 22 {
+			return nil, fmt.Errorf("zstd level shall be between 1 and 22")
+		}
+		encLevel = zstd.EncoderLevelFromZstd(level)
+	}
+
+	// Encoder and decoder are only used through EncodeAll and DecodeAll which are safe for concurrent use
+	encoder, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(encLevel))
+	if err != nil {
+		return nil, err
+	}
+
+	decoder, err := zstd.NewReader(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &zstdCodec{encoder: encoder, decoder: decoder}, nil
+}
+
+func (z *zstdCodec) compress(src []byte) ([]byte, error) {
+	return z.encoder.EncodeAll(src, make([]byte, 0, len(src)/2)), nil
+}
+
+func (z *zstdCodec) decompress(src []byte, size int) ([]byte, error) {
+	return z.decoder.DecodeAll(src, make([]byte, 0, size))
+}
+
+// ------------------------- gzip -------------------------------------------
+
+type gzipCodec struct {
+	level int
+}
+
+func (g *gzipCodec) compress(src []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := gzip.NewWriterLevel(&buf, g.level)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = w.Write(src)
+	if err != nil {
+		return nil, err
+	}
+
+	err = w.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (g *gzipCodec) decompress(src []byte, size int) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	data := make([]byte, size)
+	_, err = io.ReadFull(r, data)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// ------------------------- lz4 -------------------------------------------
+
+type lz4Codec struct {
+	level int
+}
+
+func (l *lz4Codec) compress(src []byte) ([]byte, error) {
+	dst := make([]byte, lz4.CompressBlockBound(len(src)))
+
+	var n int
+	var err error
+	if l.level == 0 {
+		n, err = lz4.CompressBlock(src, dst, nil)
+	} else {
+		n, err = lz4.CompressBlockHC(src, dst, lz4.CompressionLevel(1<<(8+l.level)), nil, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if n == 0 {
+		// Data is not compressible, caller stores it as is
+		return src, nil
+	}
+	return dst[:n], nil
+}
+
+func (l *lz4Codec) decompress(src []byte, size int) ([]byte, error) {
+	data := make([]byte, size)
+	n, err := lz4.UncompressBlock(src, data)
+	if err != nil {
+		return nil, err
+	}
+	return data[:n], nil
+}
@@ -0,0 +1,999 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package compression
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/Azure/azure-storage-fuse/v2/common"
+	"github.com/Azure/azure-storage-fuse/v2/common/config"
+	"github.com/Azure/azure-storage-fuse/v2/common/log"
+	"github.com/Azure/azure-storage-fuse/v2/internal"
+	"github.com/Azure/azure-storage-fuse/v2/internal/handlemap"
+)
+
+// Common structure for Compression Component
+type Compression struct {
+	internal.BaseComponent
+	algo      string
+	level     int
+	blockSize int64 // logical size of blocks for whole file uploads
+	workers   int
+
+	codecs    map[string]codec // codec per algorithm, blobs are read with the algorithm they were written with
+	codecLock sync.Mutex
+
+	indexes   map[string]*blockIndex  // block index of compressed blobs
+	staged    map[string]*stagedBlobs // blocks staged but not committed yet
+	indexLock sync.RWMutex
+}
+
+// stagedBlobs : Sizes of the blocks staged for a blob, the algorithm is fixed by the first block
+type stagedBlobs struct {
+	algo   string
+	blocks map[string][2]int64
+}
+
+// Structure defining your config parameters
+type CompressionOptions struct {
+	Algorithm   string  `config:"algorithm" yaml:"algorithm,omitempty"`
+	Level       int     `config:"level" yaml:"level,omitempty"`
+	BlockSizeMB float64 `config:"block-size-mb" yaml:"block-size-mb,omitempty"`
+	Workers     int     `config:"upload-workers" yaml:"upload-workers,omitempty"`
+}
+
+const compName = "compression"
+
+const (
+	defaultAlgorithm   = algoZstd
+	defaultBlockSizeMB = 16
+	defaultWorkers     = 4
+)
+
+// Verification to check satisfaction criteria with Component Interface
+var _ internal.Component = &Compression{}
+
+func (c *Compression) Name() string {
+	return compName
+}
+
+func (c *Compression) SetName(name string) {
+	c.BaseComponent.SetName(name)
+}
+
+func (c *Compression) SetNextComponent(nc internal.Component) {
+	c.BaseComponent.SetNextComponent(nc)
+}
+
+func (c *Compression) Priority() internal.ComponentPriority {
+	return internal.EComponentPriority.LevelThree()
+}
+
+// Start : Pipeline calls this method to start the component functionality
+//
+//	this shall not block the call otherwise pipeline will not start
+func (c *Compression) Start(ctx context.Context) error {
+	log.Trace("Compression::Start : Starting component %s", c.Name())
+	return nil
+}
+
+// Stop : Stop the component functionality and kill all threads started
+func (c *Compression) Stop() error {
+	log.Trace("Compression::Stop : Stopping component %s", c.Name())
+
+	c.indexLock.Lock()
+	clear(c.indexes)
+	clear(c.staged)
+	c.indexLock.Unlock()
+
+	return nil
+}
+
+// GenConfig : Generate the default config for the component
+func (c *Compression) GenConfig() string {
+	log.Info("Compression::Configure : config generation started")
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "\n%s:", c.Name())
+	fmt.Fprintf(&sb, "\n  algorithm: %v", defaultAlgorithm)
+	fmt.Fprintf(&sb, "\n  block-size-mb: %v", defaultBlockSizeMB)
+
+	return sb.String()
+}
+
+// Configure : Pipeline will call this method after constructor so that you can read config and initialize yourself
+//
+//	Return failure if any config is not valid to exit the process
+func (c *Compression) Configure(_ bool) error {
+	log.Trace("Compression::Configure : %s", c.Name())
+
+	conf := CompressionOptions{}
+	err := config.UnmarshalKey(c.Name(), &conf)
+	if err != nil {
+		log.Err("Compression::Configure : config error [invalid config attributes]")
+		return fmt.Errorf("config error in %s [%s]", c.Name(), err.Error())
+	}
+
+	c.algo = defaultAlgorithm
+	if config.IsSet(compName + ".algorithm") {
+		c.algo = strings.ToLower(conf.Algorithm)
+	}
+	c.level = conf.Level
+
+	codec, err := newCodec(c.algo, c.level)
+	if err != nil {
+		log.Err("Compression::Configure : %s", err.Error())
+		return fmt.Errorf("config error in %s [%s]", c.Name(), err.Error())
+	}
+	c.codecs[c.algo] = codec
+
+	c.blockSize = defaultBlockSizeMB * common.MbToBytes
+	if config.IsSet(compName + ".block-size-mb") {
+		c.blockSize = int64(conf.BlockSizeMB * float64(common.MbToBytes))
+		if c.blockSize <= 0 {
+			log.Err("Compression::Configure : block-size-mb shall be greater than 0")
+			return fmt.Errorf("config error in %s [block-size-mb shall be greater than 0]", c.Name())
+		}
+	}
+
+	c.workers = defaultWorkers
+	if config.IsSet(compName+".upload-workers") && conf.Workers > 0 {
+		c.workers = conf.Workers
+	}
+
+	err = c.validatePipeline()
+	if err != nil {
+		log.Err("Compression::Configure : %s", err.Error())
+		return fmt.Errorf("config error in %s [%s]", c.Name(), err.Error())
+	}
+
+	log.Crit("Compression::Configure : algorithm %s, level %d, block-size %d, upload-workers %d",
+		c.algo, c.level, c.blockSize, c.workers)
+
+	return nil
+}
+
+// validatePipeline : Blocks are only written through a caching component. Encryption relies on blocks made of whole
+// chunks, which compressed blocks are not.
+func (c *Compression) validatePipeline() error {
+	var components []string
+	_ = config.UnmarshalKey("components", &components)
+
+	cached := false
+	for _, name := range components {
+		switch name {
+		case "file_cache", "block_cache", "stream":
+			cached = true
+		case "encryption":
+			return fmt.Errorf("compression can not be used along with encryption")
+		}
+	}
+
+	if !cached {
+		return fmt.Errorf("file_cache or block_cache is required in the pipeline")
+	}
+	return nil
+}
+
+// ------------------------- Block index -------------------------------------------
+
+// getCodec : Codec to read blocks compressed with the given algorithm
+func (c *Compression) getCodec(algo string) (codec, error) {
+	c.codecLock.Lock()
+	defer c.codecLock.Unlock()
+
+	if cd, found := c.codecs[algo]; found {
+		return cd, nil
+	}
+
+	cd, err := newCodec(algo, 0)
+	if err != nil {
+		return nil, err
+	}
+	c.codecs[algo] = cd
+	return cd, nil
+}
+
+// getIndex : Block index of a blob, nil if the blob is not compressed
+func (c *Compression) getIndex(name string) (*blockIndex, error) {
+	c.indexLock.RLock()
+	idx := c.indexes[name]
+	c.indexLock.RUnlock()
+	if idx != nil {
+		return idx, nil
+	}
+
+	attr, err := c.NextComponent().GetAttr(internal.GetAttrOptions{Name: name})
+	if err != nil {
+		return nil, err
+	}
+
+	return c.loadIndex(name, attr)
+}
+
+// loadIndex : Decode and remember the block index of a blob from its attributes
+func (c *Compression) loadIndex(name string, attr *internal.ObjAttr) (*blockIndex, error) {
+	if !isCompressed(attr.Metadata) {
+		return nil, nil
+	}
+
+	idx, err := parseBlockIndex(attr.Metadata)
+	if err != nil {
+		log.Err("Compression::loadIndex : Invalid block index of %s [%s]", name, err.Error())
+		return nil, syscall.EIO
+	}
+
+	c.indexLock.Lock()
+	c.indexes[name] = idx
+	c.indexLock.Unlock()
+
+	return idx, nil
+}
+
+// forget : Drop the block index and staged blocks of a file
+func (c *Compression) forget(name string) {
+	c.indexLock.Lock()
+	defer c.indexLock.Unlock()
+	delete(c.indexes, name)
+	delete(c.staged, name)
+}
+
+// forgetDir : Drop the block index and staged blocks of all files under a directory
+func (c *Compression) forgetDir(dir string) {
+	dir = internal.ExtendDirName(dir)
+
+	c.indexLock.Lock()
+	defer c.indexLock.Unlock()
+	for name := range c.indexes {
+		if strings.HasPrefix(name, dir) {
+			delete(c.indexes, name)
+		}
+	}
+	for name := range c.staged {
+		if strings.HasPrefix(name, dir) {
+			delete(c.staged, name)
+		}
+	}
+}
+
+// blobMetadata : Metadata to store with a blob, existing keys are retained and the block index is refreshed
+func blobMetadata(existing map[string]*string, idx *blockIndex) (map[string]*string, error) {
+	metadata, err := idx.metadata()
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range existing {
+		if v != nil && !isCompressionMetaKey(k) {
+			metadata[k] = v
+		}
+	}
+	return metadata, nil
+}
+
+// toLogicalAttr : Report the size of the file held by compressed blobs
+func toLogicalAttr(attr *internal.ObjAttr) {
+	if attr == nil || attr.IsDir() {
+		return
+	}
+
+	if size, ok := logicalSize(attr.Metadata); ok {
+		attr.Size = size
+	}
+}
+
+// isCompressionXattr : Metadata of this component is exposed as user xattrs by storage, it can not be touched by users
+func isCompressionXattr(name string) bool {
+	return strings.HasPrefix(strings.ToLower(name), "user."+metaPrefix)
+}
+
+// ------------------------- Data path -------------------------------------------
+
+// read : Read logical data of a compressed blob, retrying once with a fresh index if the blob was rewritten by someone else
+func (c *Compression) read(name string, offset int64, data []byte, etag *string, priority internal.IOPriority) (int, error) {
+	idx, err := c.getIndex(name)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := c.readBlocks(name, idx, offset, data, etag, priority)
+	if err == errCorrupt {
+		c.forget(name)
+		idx, err = c.getIndex(name)
+		if err != nil {
+			return 0, err
+		}
+		n, err = c.readBlocks(name, idx, offset, data, etag, priority)
+	}
+
+	if err == errCorrupt {
+		log.Err("Compression::read : Failed to decompress %s at offset %d [%s]", name, offset, err.Error())
+		return 0, syscall.EIO
+	}
+
+	return n, err
+}
+
+// readBlocks : Download the stored blocks covering the requested range in one go and decompress them
+func (c *Compression) readBlocks(name string, idx *blockIndex, offset int64, data []byte, etag *string, priority internal.IOPriority) (int, error) {
+	if idx == nil {
+		return 0, errCorrupt
+	}
+
+	if offset > idx.size {
+		return 0, syscall.ERANGE
+	}
+
+	end := min(offset+int64(len(data)), idx.size)
+	if end <= offset {
+		return 0, nil
+	}
+
+	cd, err := c.getCodec(idx.algo)
+	if err != nil {
+		log.Err("Compression::readBlocks : %s of %s", err.Error(), name)
+		return 0, syscall.EIO
+	}
+
+	first, last := idx.find(offset), idx.find(end-1)
+	rawStart := idx.blocks[first].rawOffset
+	raw := make([]byte, idx.blocks[last].rawOffset+idx.blocks[last].rawSize-rawStart)
+
+	n, err := c.NextComponent().ReadInBuffer(&internal.ReadInBufferOptions{
+		Path:     name,
+		Size:     idx.rawSize(),
+		Offset:   rawStart,
+		Data:     raw,
+		Etag:     etag,
+		Priority: priority,
+	})
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+	if n != len(raw) {
+		return 0, errCorrupt
+	}
+
+	copied := 0
+	for i := first; i <= last; i++ {
+		block := idx.blocks[i]
+		plain, err := unpackBlock(cd, raw[block.rawOffset-rawStart:block.rawOffset-rawStart+block.rawSize], int(block.size))
+		if err != nil {
+			return 0, err
+		}
+
+		from := max(offset, block.offset) - block.offset
+		to := min(end, block.offset+block.size) - block.offset
+		copied += copy(data[copied:], plain[from:to])
+	}
+
+	return copied, nil
+}
+
+// ReadInBuffer : Decompress the blocks holding the requested range, uncompressed blobs are read as is
+func (c *Compression) ReadInBuffer(options *internal.ReadInBufferOptions) (int, error) {
+	name := options.Path
+	if options.Handle != nil {
+		name = options.Handle.Path
+	}
+
+	idx, err := c.getIndex(name)
+	if err != nil {
+		return 0, err
+	}
+
+	if idx == nil {
+		return c.NextComponent().ReadInBuffer(options)
+	}
+
+	return c.read(name, options.Offset, options.Data, options.Etag, options.Priority)
+}
+
+func (c *Compression) ReadFile(options internal.ReadFileOptions) ([]byte, error) {
+	log.Trace("Compression::ReadFile : %s", options.Handle.Path)
+
+	idx, err := c.getIndex(options.Handle.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	if idx == nil {
+		return c.NextComponent().ReadFile(options)
+	}
+
+	data := make([]byte, atomic.LoadInt64(&options.Handle.Size))
+	n, err := c.read(options.Handle.Path, 0, data, nil, internal.IOPriorityForeground)
+	return data[:n], err
+}
+
+// CopyToFile : Download and decompress the blob block by block into the given file
+func (c *Compression) CopyToFile(options internal.CopyToFileOptions) error {
+	log.Trace("Compression::CopyToFile : %s", options.Name)
+
+	idx, err := c.getIndex(options.Name)
+	if err != nil {
+		return err
+	}
+
+	if idx == nil {
+		return c.NextComponent().CopyToFile(options)
+	}
+
+	end := idx.size
+	if options.Count > 0 {
+		end = min(end, options.Offset+options.Count)
+	}
+
+	for offset := options.Offset; offset < end; {
+		block := idx.blocks[idx.find(offset)]
+		buf := make([]byte, min(block.offset+block.size, end)-offset)
+
+		n, err := c.read(options.Name, offset, buf, nil, internal.IOPriorityForeground)
+		if err != nil {
+			log.Err("Compression::CopyToFile : Failed to read %s at offset %d [%s]", options.Name, offset, err.Error())
+			return err
+		}
+
+		_, err = options.File.WriteAt(buf[:n], offset-options.Offset)
+		if err != nil {
+			log.Err("Compression::CopyToFile : Failed to write %s at offset %d [%s]", options.Name, offset, err.Error())
+			return err
+		}
+
+		if n == 0 {
+			break
+		}
+		offset += int64(n)
+	}
+
+	return nil
+}
+
+// CopyFromFile : Compress the file block by block and commit the blocks along with the block index
+func (c *Compression) CopyFromFile(options internal.CopyFromFileOptions) error {
+	log.Trace("Compression::CopyFromFile : %s", options.Name)
+
+	info, err := options.File.Stat()
+	if err != nil {
+		return err
+	}
+
+	cd, err := c.getCodec(c.algo)
+	if err != nil {
+		return err
+	}
+
+	count := (info.Size() + c.blockSize - 1) / c.blockSize
+	if count > maxIndexBlocks(c.blockSize) {
+		// Fail before any block is uploaded, index of this many blocks would not fit in metadata on commit
+		log.Err("Compression::CopyFromFile : %s has too many blocks for the block index, increase block-size-mb", options.Name)
+		return syscall.EFBIG
+	}
+
+	ids := make([]string, count)
+	sizes := make([][2]int64, count)
+
+	var wg sync.WaitGroup
+	var uploadErr atomic.Value
+	slots := make(chan struct{}, c.workers)
+
+	for i := range count {
+		slots <- struct{}{}
+		if uploadErr.Load() != nil {
+			<-slots
+			break
+		}
+
+		wg.Add(1)
+		go func(i int64) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+
+			data := make([]byte, min(c.blockSize, info.Size()-i*c.blockSize))
+			_, err := options.File.ReadAt(data, i*c.blockSize)
+			if err != nil && err != io.EOF {
+				uploadErr.CompareAndSwap(nil, err)
+				return
+			}
+
+			stored, err := packBlock(cd, data)
+			if err != nil {
+				uploadErr.CompareAndSwap(nil, err)
+				return
+			}
+
+			ids[i] = common.GetBlockID(common.BlockIDLength)
+			sizes[i] = [2]int64{int64(len(data)), int64(len(stored))}
+
+			err = c.NextComponent().StageData(internal.StageDataOptions{Name: options.Name, Id: ids[i], Data: stored, Offset: uint64(i * c.blockSize)})
+			if err != nil {
+				uploadErr.CompareAndSwap(nil, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if err, ok := uploadErr.Load().(error); ok && err != nil {
+		log.Err("Compression::CopyFromFile : Failed to upload %s [%s]", options.Name, err.Error())
+		return err
+	}
+
+	idx := newBlockIndex(c.algo, sizes)
+	metadata, err := blobMetadata(options.Metadata, idx)
+	if err != nil {
+		log.Err("Compression::CopyFromFile : %s has too many blocks for the block index, increase block-size-mb", options.Name)
+		return syscall.EFBIG
+	}
+
+	err = c.NextComponent().CommitData(internal.CommitDataOptions{Name: options.Name, List: ids, Metadata: metadata})
+	if err != nil {
+		log.Err("Compression::CopyFromFile : Failed to commit %s [%s]", options.Name, err.Error())
+		c.forget(options.Name)
+		return err
+	}
+
+	c.indexLock.Lock()
+	c.indexes[options.Name] = idx
+	c.indexLock.Unlock()
+
+	return nil
+}
+
+// StageData : Compress the block before staging and remember its sizes for the block index
+func (c *Compression) StageData(options internal.StageDataOptions) error {
+	algo, err := c.stagingAlgorithm(options.Name)
+	if err != nil {
+		return err
+	}
+
+	cd, err := c.getCodec(algo)
+	if err != nil {
+		return err
+	}
+
+	stored, err := packBlock(cd, options.Data)
+	if err != nil {
+		log.Err("Compression::StageData : Failed to compress block %s of %s [%s]", options.Id, options.Name, err.Error())
+		return err
+	}
+
+	c.indexLock.Lock()
+	if s := c.staged[options.Name]; s != nil {
+		s.blocks[options.Id] = [2]int64{int64(len(options.Data)), int64(len(stored))}
+	}
+	c.indexLock.Unlock()
+
+	options.Data = stored
+	return c.NextComponent().StageData(options)
+}
+
+// stagingAlgorithm : Blocks added to a compressed blob use its algorithm, new blobs use the configured one
+func (c *Compression) stagingAlgorithm(name string) (string, error) {
+	c.indexLock.RLock()
+	s := c.staged[name]
+	c.indexLock.RUnlock()
+	if s != nil {
+		return s.algo, nil
+	}
+
+	algo := c.algo
+	idx, err := c.getIndex(name)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	} else if idx != nil {
+		algo = idx.algo
+	}
+
+	c.indexLock.Lock()
+	defer c.indexLock.Unlock()
+	if s = c.staged[name]; s == nil {
+		s = &stagedBlobs{algo: algo, blocks: make(map[string][2]int64)}
+		c.staged[name] = s
+	}
+	return s.algo, nil
+}
+
+// committedSizes : Logical and stored size of the committed blocks of a blob by their id
+func (c *Compression) committedSizes(name string, idx *blockIndex) (map[string][2]int64, error) {
+	sizes := make(map[string][2]int64)
+	if idx == nil || len(idx.blocks) == 0 {
+		return sizes, nil
+	}
+
+	list, err := c.NextComponent().GetCommittedBlockList(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if list == nil || !alignedToIndex(*list, idx) {
+		return nil, fmt.Errorf("blocks of %s are not aligned to the block index", name)
+	}
+
+	for i, block := range *list {
+		sizes[block.Id] = [2]int64{idx.blocks[i].size, idx.blocks[i].rawSize}
+	}
+	return sizes, nil
+}
+
+// alignedToIndex : Each committed block shall hold exactly one compressed block
+func alignedToIndex(list internal.CommittedBlockList, idx *blockIndex) bool {
+	if len(list) != len(idx.blocks) {
+		return false
+	}
+
+	for i, block := range list {
+		if int64(block.Size) != idx.blocks[i].rawSize {
+			return false
+		}
+	}
+	return true
+}
+
+// CommitData : Commit the compressed blocks along with the block index, metadata of the blob is carried over
+func (c *Compression) CommitData(options internal.CommitDataOptions) error {
+	log.Trace("Compression::CommitData : %s", options.Name)
+
+	metadata := make(map[string]*string)
+	var idx *blockIndex
+	attr, err := c.NextComponent().GetAttr(internal.GetAttrOptions{Name: options.Name})
+	if err == nil {
+		for k, v := range attr.Metadata {
+			metadata[k] = v
+		}
+		idx, err = c.loadIndex(options.Name, attr)
+		if err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	committed, err := c.committedSizes(options.Name, idx)
+	if err != nil {
+		log.Err("Compression::CommitData : %s", err.Error())
+		return err
+	}
+
+	algo := c.algo
+	if idx != nil {
+		algo = idx.algo
+	}
+
+	c.indexLock.RLock()
+	staged := c.staged[options.Name]
+	if staged != nil {
+		algo = staged.algo
+	}
+
+	sizes := make([][2]int64, 0, len(options.List))
+	for _, id := range options.List {
+		if s, found := staged.lookup(id); found {
+			sizes = append(sizes, s)
+		} else if s, found := committed[id]; found {
+			sizes = append(sizes, s)
+		} else {
+			c.indexLock.RUnlock()
+			log.Err("Compression::CommitData : Sizes of block %s of %s are not known", id, options.Name)
+			return syscall.EINVAL
+		}
+	}
+	c.indexLock.RUnlock()
+
+	newIdx := newBlockIndex(algo, sizes)
+	for k, v := range options.Metadata {
+		metadata[k] = v
+	}
+
+	options.Metadata, err = blobMetadata(metadata, newIdx)
+	if err != nil {
+		log.Err("Compression::CommitData : %s has too many blocks for the block index, increase block size", options.Name)
+		return syscall.EFBIG
+	}
+	options.BlockSize = 0
+
+	err = c.NextComponent().CommitData(options)
+	if err != nil {
+		c.forget(options.Name)
+		return err
+	}
+
+	c.indexLock.Lock()
+	c.indexes[options.Name] = newIdx
+	delete(c.staged, options.Name)
+	c.indexLock.Unlock()
+
+	return nil
+}
+
+func (s *stagedBlobs) lookup(id string) ([2]int64, bool) {
+	if s == nil {
+		return [2]int64{}, false
+	}
+	sizes, found := s.blocks[id]
+	return sizes, found
+}
+
+// GetCommittedBlockList : Report the blocks by the logical data they hold
+func (c *Compression) GetCommittedBlockList(name string) (*internal.CommittedBlockList, error) {
+	list, err := c.NextComponent().GetCommittedBlockList(name)
+	if err != nil || list == nil || len(*list) == 0 {
+		return list, err
+	}
+
+	idx, err := c.getIndex(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if idx == nil {
+		// Compressed blocks can not be mixed with the plain data of this blob
+		log.Err("Compression::GetCommittedBlockList : %s is not compressed", name)
+		return nil, fmt.Errorf("blob %s is not compressed", name)
+	}
+
+	if !alignedToIndex(*list, idx) {
+		log.Err("Compression::GetCommittedBlockList : Blocks of %s are not aligned to the block index", name)
+		return nil, fmt.Errorf("blocks of %s are not aligned to the block index", name)
+	}
+
+	blocks := make(internal.CommittedBlockList, 0, len(*list))
+	for i, block := range *list {
+		blocks = append(blocks, internal.CommittedBlock{Id: block.Id, Offset: idx.blocks[i].offset, Size: uint64(idx.blocks[i].size)})
+	}
+
+	return &blocks, nil
+}
+
+// TruncateFile : Compressed blocks can not be cut in storage, so the file is rewritten with the new size
+func (c *Compression) TruncateFile(options internal.TruncateFileOptions) error {
+	log.Trace("Compression::TruncateFile : %s to %d", options.Name, options.NewSize)
+
+	attr, err := c.NextComponent().GetAttr(internal.GetAttrOptions{Name: options.Name})
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp("", "blobfuse2_cmp_*")
+	if err != nil {
+		log.Err("Compression::TruncateFile : Failed to create temp file for %s [%s]", options.Name, err.Error())
+		return err
+	}
+	defer func() {
+		f.Close()
+		_ = os.Remove(f.Name())
+	}()
+
+	if options.NewSize > 0 {
+		err = c.CopyToFile(internal.CopyToFileOptions{Name: options.Name, File: f})
+		if err != nil {
+			return err
+		}
+	}
+
+	err = f.Truncate(options.NewSize)
+	if err != nil {
+		return err
+	}
+
+	c.forget(options.Name)
+	return c.CopyFromFile(internal.CopyFromFileOptions{Name: options.Name, File: f, Metadata: attr.Metadata})
+}
+
+// Fallocate : Growing a file writes compressed zeros, holes can only be punched by a cache component
+func (c *Compression) Fallocate(options internal.FallocateOptions) error {
+	log.Trace("Compression::Fallocate : %s mode %d, offset %d, length %d", options.Name, options.Mode, options.Offset, options.Length)
+
+	switch options.Mode {
+	case 0:
+		attr, err := c.GetAttr(internal.GetAttrOptions{Name: options.Name})
+		if err != nil {
+			return err
+		}
+
+		newSize := options.Offset + options.Length
+		if newSize <= attr.Size {
+			return nil
+		}
+
+		return c.TruncateFile(internal.TruncateFileOptions{Name: options.Name, OldSize: attr.Size, NewSize: newSize})
+	case internal.FallocKeepSize:
+		return nil
+	default:
+		return syscall.ENOTSUP
+	}
+}
+
+// Lseek : Compressed blobs have no holes, whole logical size of the file is data
+func (c *Compression) Lseek(options internal.LseekOptions) (int64, error) {
+	log.Trace("Compression::Lseek : %s offset %d, whence %d", options.Name, options.Offset, options.Whence)
+
+	attr, err := c.NextComponent().GetAttr(internal.GetAttrOptions{Name: options.Name})
+	if err != nil {
+		return 0, err
+	}
+
+	if !isCompressed(attr.Metadata) {
+		return c.NextComponent().Lseek(options)
+	}
+	toLogicalAttr(attr)
+
+	if options.Offset >= attr.Size {
+		return 0, syscall.ENXIO
+	}
+
+	switch options.Whence {
+	case internal.SeekData:
+		return options.Offset, nil
+	case internal.SeekHole:
+		return attr.Size, nil
+	default:
+		return 0, syscall.EINVAL
+	}
+}
+
+// CopyFileRange : Server side copy would move compressed blocks of one file into another, let the caller copy the data
+func (c *Compression) CopyFileRange(options internal.CopyFileRangeOptions) (int64, error) {
+	log.Trace("Compression::CopyFileRange : %s to %s", options.SrcName, options.DstName)
+	return 0, syscall.ENOTSUP
+}
+
+// WriteFile : Only whole blocks can be compressed, writes have to go through a caching component
+func (c *Compression) WriteFile(options *internal.WriteFileOptions) (int, error) {
+	log.Err("Compression::WriteFile : Direct write to %s is not supported", options.Handle.Path)
+	return 0, syscall.ENOTSUP
+}
+
+// ------------------------- Attributes -------------------------------------------
+
+func (c *Compression) GetAttr(options internal.GetAttrOptions) (*internal.ObjAttr, error) {
+	attr, err := c.NextComponent().GetAttr(options)
+	if err == nil {
+		toLogicalAttr(attr)
+	}
+	return attr, err
+}
+
+func (c *Compression) ReadDir(options internal.ReadDirOptions) ([]*internal.ObjAttr, error) {
+	list, err := c.NextComponent().ReadDir(options)
+	for _, attr := range list {
+		toLogicalAttr(attr)
+	}
+	return list, err
+}
+
+func (c *Compression) StreamDir(options internal.StreamDirOptions) ([]*internal.ObjAttr, string, error) {
+	list, token, err := c.NextComponent().StreamDir(options)
+	for _, attr := range list {
+		toLogicalAttr(attr)
+	}
+	return list, token, err
+}
+
+func (c *Compression) GetXattr(options internal.GetXattrOptions) ([]byte, error) {
+	if isCompressionXattr(options.Attr) {
+		return nil, syscall.ENODATA
+	}
+	return c.NextComponent().GetXattr(options)
+}
+
+func (c *Compression) SetXattr(options internal.SetXattrOptions) error {
+	if isCompressionXattr(options.Attr) {
+		return syscall.EPERM
+	}
+	return c.NextComponent().SetXattr(options)
+}
+
+func (c *Compression) ListXattr(options internal.ListXattrOptions) ([]string, error) {
+	names, err := c.NextComponent().ListXattr(options)
+	if err != nil {
+		return nil, err
+	}
+
+	visible := make([]string, 0, len(names))
+	for _, name := range names {
+		if !isCompressionXattr(name) {
+			visible = append(visible, name)
+		}
+	}
+	return visible, nil
+}
+
+func (c *Compression) RemoveXattr(options internal.RemoveXattrOptions) error {
+	if isCompressionXattr(options.Attr) {
+		return syscall.EPERM
+	}
+	return c.NextComponent().RemoveXattr(options)
+}
+
+// ------------------------- Namespace -------------------------------------------
+
+func (c *Compression) CreateFile(options internal.CreateFileOptions) (*handlemap.Handle, error) {
+	// New blob carries no block index
+	c.forget(options.Name)
+	return c.NextComponent().CreateFile(options)
+}
+
+func (c *Compression) DeleteFile(options internal.DeleteFileOptions) error {
+	err := c.NextComponent().DeleteFile(options)
+	c.forget(options.Name)
+	return err
+}
+
+func (c *Compression) RenameFile(options internal.RenameFileOptions) error {
+	err := c.NextComponent().RenameFile(options)
+	c.forget(options.Src)
+	c.forget(options.Dst)
+	return err
+}
+
+func (c *Compression) DeleteDir(options internal.DeleteDirOptions) error {
+	err := c.NextComponent().DeleteDir(options)
+	c.forgetDir(options.Name)
+	return err
+}
+
+func (c *Compression) RenameDir(options internal.RenameDirOptions) error {
+	err := c.NextComponent().RenameDir(options)
+	c.forgetDir(options.Src)
+	c.forgetDir(options.Dst)
+	return err
+}
+
+// ------------------------- Factory -------------------------------------------
+
+// Pipeline will call this method to create your object, initialize your variables here
+// << DO NOT DELETE ANY AUTO GENERATED CODE HERE >>
+func NewCompressionComponent() internal.Component {
+	comp := &Compression{
+		codecs:  make(map[string]codec),
+		indexes: make(map[string]*blockIndex),
+		staged:  make(map[string]*stagedBlobs),
+	}
+	comp.SetName(compName)
+	return comp
+}
+
+// On init register this component to pipeline and supply your constructor
+func init() {
+	internal.AddComponent(compName, NewCompressionComponent)
+}
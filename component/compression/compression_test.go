@@ -0,0 +1,455 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package compression
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/Azure/azure-storage-fuse/v2/common"
+	"github.com/Azure/azure-storage-fuse/v2/common/config"
+	"github.com/Azure/azure-storage-fuse/v2/common/log"
+	"github.com/Azure/azure-storage-fuse/v2/component/file_cache"
+	"github.com/Azure/azure-storage-fuse/v2/component/loopback"
+	"github.com/Azure/azure-storage-fuse/v2/internal"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+var home_dir, _ = os.UserHomeDir()
+
+type compressionTestSuite struct {
+	suite.Suite
+	assert      *assert.Assertions
+	compression *Compression
+	loopback    internal.Component
+	storagePath string
+	cachePath   string
+	blockSize   int
+}
+
+func randomData(size int) []byte {
+	data := make([]byte, size)
+	_, _ = rand.Read(data)
+	return data
+}
+
+// textData : Log like data which compresses well
+func textData(size int) []byte {
+	var buf bytes.Buffer
+	for i := 0; buf.Len() < size; i++ {
+		fmt.Fprintf(&buf, "2026-01-01T00:00:%02d INFO request %d served in %d ms\n", i%60, i, i%97)
+	}
+	return buf.Bytes()[:size]
+}
+
+func (suite *compressionTestSuite) SetupTest() {
+	err := log.SetDefaultLogger("silent", common.LogConfig{Level: common.ELogLevel.LOG_DEBUG()})
+	if err != nil {
+		panic("Unable to set silent logger as default.")
+	}
+
+	suffix := fmt.Sprintf("%x", randomData(4))
+	suite.storagePath = filepath.Join(home_dir, "cmp_storage"+suffix)
+	suite.cachePath = filepath.Join(home_dir, "cmp_cache"+suffix)
+	suite.blockSize = 64 * 1024
+	_ = os.MkdirAll(suite.storagePath, 0777)
+
+	suite.setupTestHelper(algoZstd)
+}
+
+func (suite *compressionTestSuite) setupTestHelper(algo string) {
+	suite.assert = assert.New(suite.T())
+
+	configuration := fmt.Sprintf("components:\n  - file_cache\n  - compression\n  - loopbackfs\n\ncompression:\n  algorithm: %s\n  block-size-mb: 0.0625\n\nfile_cache:\n  path: %s\n  timeout-sec: 0\n\nloopbackfs:\n  path: %s",
+		algo, suite.cachePath, suite.storagePath)
+	err := config.ReadConfigFromReader(strings.NewReader(configuration))
+	suite.assert.NoError(err)
+
+	suite.loopback = loopback.NewLoopbackFSComponent()
+	_ = suite.loopback.Configure(true)
+
+	comp := NewCompressionComponent()
+	comp.SetNextComponent(suite.loopback)
+	err = comp.Configure(true)
+	suite.assert.NoError(err)
+	suite.compression = comp.(*Compression)
+
+	suite.assert.NoError(suite.loopback.Start(context.Background()))
+	suite.assert.NoError(suite.compression.Start(context.Background()))
+}
+
+func (suite *compressionTestSuite) TearDownTest() {
+	_ = suite.compression.Stop()
+	_ = suite.loopback.Stop()
+	os.RemoveAll(suite.storagePath)
+	os.RemoveAll(suite.cachePath)
+}
+
+func (suite *compressionTestSuite) upload(name string, data []byte) {
+	f, err := os.CreateTemp("", "cmp_src_*")
+	suite.assert.NoError(err)
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	_, err = f.Write(data)
+	suite.assert.NoError(err)
+
+	err = suite.compression.CopyFromFile(internal.CopyFromFileOptions{Name: name, File: f})
+	suite.assert.NoError(err)
+}
+
+func (suite *compressionTestSuite) read(name string, offset int64, length int) ([]byte, error) {
+	data := make([]byte, length)
+	n, err := suite.compression.ReadInBuffer(&internal.ReadInBufferOptions{Path: name, Offset: offset, Data: data})
+	return data[:n], err
+}
+
+func (suite *compressionTestSuite) TestCodecs() {
+	text := textData(100 * 1024)
+	random := randomData(10 * 1024)
+
+	for _, algo := range []string{algoZstd, algoGzip, algoLz4} {
+		for _, level := range []int{0, 1} {
+			cd, err := newCodec(algo, level)
+			suite.assert.NoError(err)
+
+			stored, err := packBlock(cd, text)
+			suite.assert.NoError(err)
+			suite.assert.Less(len(stored), len(text)/4, algo)
+
+			data, err := unpackBlock(cd, stored, len(text))
+			suite.assert.NoError(err)
+			suite.assert.Equal(text, data)
+
+			// Incompressible data is stored as is
+			stored, err = packBlock(cd, random)
+			suite.assert.NoError(err)
+			suite.assert.Equal(random, stored)
+
+			_, err = unpackBlock(cd, []byte("garbage"), len(text))
+			suite.assert.Equal(errCorrupt, err)
+		}
+	}
+
+	_, err := newCodec("brotli", 0)
+	suite.assert.Error(err)
+	_, err = newCodec(algoGzip, 12)
+	suite.assert.Error(err)
+}
+
+func (suite *compressionTestSuite) TestBlockIndex() {
+	uniform := newBlockIndex(algoLz4, [][2]int64{{100, 10}, {100, 20}, {50, 50}})
+	metadata, err := uniform.metadata()
+	suite.assert.NoError(err)
+	suite.assert.Equal("100", *metadata[metaBlockSize])
+
+	idx, err := parseBlockIndex(metadata)
+	suite.assert.NoError(err)
+	suite.assert.Equal(uniform, idx)
+	suite.assert.EqualValues(250, idx.size)
+	suite.assert.EqualValues(80, idx.rawSize())
+	suite.assert.Equal(1, idx.find(150))
+
+	mixed := newBlockIndex(algoZstd, [][2]int64{{100, 10}, {30, 5}, {100, 20}})
+	metadata, err = mixed.metadata()
+	suite.assert.NoError(err)
+	suite.assert.Equal("0", *metadata[metaBlockSize])
+
+	idx, err = parseBlockIndex(metadata)
+	suite.assert.NoError(err)
+	suite.assert.Equal(mixed, idx)
+
+	empty := newBlockIndex(algoGzip, nil)
+	metadata, err = empty.metadata()
+	suite.assert.NoError(err)
+	idx, err = parseBlockIndex(metadata)
+	suite.assert.NoError(err)
+	suite.assert.EqualValues(0, idx.size)
+
+	sizes := make([][2]int64, 0)
+	for range 10000 {
+		sizes = append(sizes, [2]int64{16 * 1024 * 1024, 1024 * 1024})
+	}
+	_, err = newBlockIndex(algoZstd, sizes).metadata()
+	suite.assert.Equal(errIndexTooLarge, err)
+
+	// Index of as many blocks as allowed fits even when no block compresses
+	suite.assert.EqualValues(1535, maxIndexBlocks(1024*1024))
+	for _, blockSize := range []int64{64 * 1024, 1024 * 1024, 16 * 1024 * 1024} {
+		sizes = sizes[:0]
+		for range maxIndexBlocks(blockSize) {
+			sizes = append(sizes, [2]int64{blockSize, blockSize})
+		}
+		_, err = newBlockIndex(algoZstd, sizes).metadata()
+		suite.assert.NoError(err, blockSize)
+	}
+}
+
+func (suite *compressionTestSuite) TestTooManyBlocks() {
+	f, err := os.CreateTemp("", "cmp_src_*")
+	suite.assert.NoError(err)
+	defer os.Remove(f.Name())
+	defer f.Close()
+	suite.assert.NoError(f.Truncate((maxIndexBlocks(int64(suite.blockSize)) + 1) * int64(suite.blockSize)))
+
+	// File is refused before any of its blocks is uploaded
+	err = suite.compression.CopyFromFile(internal.CopyFromFileOptions{Name: "large.log", File: f})
+	suite.assert.Equal(syscall.EFBIG, err)
+
+	entries, err := os.ReadDir(suite.storagePath)
+	suite.assert.NoError(err)
+	suite.assert.Empty(entries)
+}
+
+func (suite *compressionTestSuite) TestConfigureInvalid() {
+	configs := []string{
+		"components:\n  - file_cache\n  - compression\n\ncompression:\n  algorithm: brotli\n",
+		"components:\n  - compression\n  - loopbackfs\n",
+		"components:\n  - block_cache\n  - compression\n  - encryption\n",
+		"components:\n  - file_cache\n  - compression\n\ncompression:\n  block-size-mb: 0\n",
+	}
+
+	for _, configuration := range configs {
+		suite.assert.NoError(config.ReadConfigFromReader(strings.NewReader(configuration)))
+		comp := NewCompressionComponent()
+		suite.assert.Error(comp.Configure(true), configuration)
+	}
+}
+
+func (suite *compressionTestSuite) TestCopyToFromFile() {
+	for _, algo := range []string{algoZstd, algoGzip, algoLz4} {
+		_ = suite.compression.Stop()
+		suite.setupTestHelper(algo)
+
+		name := "copy_" + algo + ".log"
+		data := textData(5*suite.blockSize + 1234)
+		suite.upload(name, data)
+
+		// Storage holds much less than the file
+		info, err := os.Stat(filepath.Join(suite.storagePath, name))
+		suite.assert.NoError(err)
+		suite.assert.Less(info.Size(), int64(len(data)/4))
+
+		attr, err := suite.compression.GetAttr(internal.GetAttrOptions{Name: name})
+		suite.assert.NoError(err)
+		suite.assert.EqualValues(len(data), attr.Size)
+
+		for _, r := range [][2]int{{0, len(data)}, {10, 10}, {suite.blockSize - 5, 10}, {2*suite.blockSize + 7, 2 * suite.blockSize}, {len(data) - 10, 100}} {
+			read, err := suite.read(name, int64(r[0]), r[1])
+			suite.assert.NoError(err)
+			suite.assert.Equal(data[r[0]:min(r[0]+r[1], len(data))], read, algo)
+		}
+
+		f, err := os.CreateTemp("", "cmp_dst_*")
+		suite.assert.NoError(err)
+		err = suite.compression.CopyToFile(internal.CopyToFileOptions{Name: name, Offset: 1000, Count: int64(suite.blockSize), File: f})
+		suite.assert.NoError(err)
+		f.Close()
+
+		read, err := os.ReadFile(f.Name())
+		suite.assert.NoError(err)
+		suite.assert.Equal(data[1000:1000+suite.blockSize], read)
+		os.Remove(f.Name())
+	}
+}
+
+func (suite *compressionTestSuite) TestStageCommit() {
+	name := "blocks.log"
+	data := textData(3*suite.blockSize + 100)
+
+	ids := make([]string, 0)
+	for offset := 0; offset < len(data); offset += suite.blockSize {
+		id := common.GetBlockID(common.BlockIDLength)
+		err := suite.compression.StageData(internal.StageDataOptions{Name: name, Id: id, Data: data[offset:min(offset+suite.blockSize, len(data))]})
+		suite.assert.NoError(err)
+		ids = append(ids, id)
+	}
+	err := suite.compression.CommitData(internal.CommitDataOptions{Name: name, List: ids, BlockSize: uint64(suite.blockSize)})
+	suite.assert.NoError(err)
+
+	list, err := suite.compression.GetCommittedBlockList(name)
+	suite.assert.NoError(err)
+	suite.assert.Len(*list, 4)
+	suite.assert.EqualValues(2*suite.blockSize, (*list)[2].Offset)
+	suite.assert.EqualValues(100, (*list)[3].Size)
+
+	// Another mount replaces the second block only, other blocks are learnt from the committed list
+	other := NewCompressionComponent().(*Compression)
+	other.SetNextComponent(suite.loopback)
+	suite.assert.NoError(other.Configure(true))
+
+	update := bytes.Repeat([]byte("x"), suite.blockSize)
+	ids[1] = common.GetBlockID(common.BlockIDLength)
+	err = other.StageData(internal.StageDataOptions{Name: name, Id: ids[1], Data: update})
+	suite.assert.NoError(err)
+	err = other.CommitData(internal.CommitDataOptions{Name: name, List: ids, BlockSize: uint64(suite.blockSize)})
+	suite.assert.NoError(err)
+
+	// Stale block index of this mount is refreshed on read
+	copy(data[suite.blockSize:], update)
+	read, err := suite.read(name, 0, len(data))
+	suite.assert.NoError(err)
+	suite.assert.Equal(data, read)
+
+	// Unknown block can not be committed
+	err = suite.compression.CommitData(internal.CommitDataOptions{Name: name, List: []string{"unknown"}})
+	suite.assert.Equal(syscall.EINVAL, err)
+}
+
+func (suite *compressionTestSuite) TestCorrupt() {
+	name := "corrupt.log"
+	data := textData(2 * suite.blockSize)
+	suite.upload(name, data)
+
+	path := filepath.Join(suite.storagePath, name)
+	raw, err := os.ReadFile(path)
+	suite.assert.NoError(err)
+	for i := len(raw) - 20; i < len(raw); i++ {
+		raw[i] ^= 0xff
+	}
+	suite.assert.NoError(os.WriteFile(path, raw, 0666))
+
+	// First block is untouched and can still be read
+	read, err := suite.read(name, 0, 100)
+	suite.assert.NoError(err)
+	suite.assert.Equal(data[:100], read)
+
+	_, err = suite.read(name, int64(suite.blockSize), 100)
+	suite.assert.Equal(syscall.EIO, err)
+}
+
+func (suite *compressionTestSuite) TestUncompressedPassthrough() {
+	name := "plain.txt"
+	data := append([]byte("written before compression was enabled"), make([]byte, 2*common.MbToBytes)...)
+	suite.assert.NoError(os.WriteFile(filepath.Join(suite.storagePath, name), data, 0666))
+
+	attr, err := suite.compression.GetAttr(internal.GetAttrOptions{Name: name})
+	suite.assert.NoError(err)
+	suite.assert.EqualValues(len(data), attr.Size)
+
+	read, err := suite.read(name, 8, 6)
+	suite.assert.NoError(err)
+	suite.assert.Equal([]byte("before"), read)
+
+	_, err = suite.compression.GetCommittedBlockList(name)
+	suite.assert.Error(err)
+}
+
+func (suite *compressionTestSuite) TestTruncate() {
+	name := "truncate.log"
+	data := textData(2*suite.blockSize + 10)
+	suite.upload(name, data)
+
+	err := suite.compression.TruncateFile(internal.TruncateFileOptions{Name: name, NewSize: 5000})
+	suite.assert.NoError(err)
+	read, err := suite.read(name, 0, len(data))
+	suite.assert.NoError(err)
+	suite.assert.Equal(data[:5000], read)
+
+	err = suite.compression.Fallocate(internal.FallocateOptions{Name: name, Length: 6000})
+	suite.assert.NoError(err)
+	read, err = suite.read(name, 0, len(data))
+	suite.assert.NoError(err)
+	suite.assert.Equal(append(data[:5000:5000], make([]byte, 1000)...), read)
+
+	offset, err := suite.compression.Lseek(internal.LseekOptions{Name: name, Offset: 10, Whence: internal.SeekHole})
+	suite.assert.NoError(err)
+	suite.assert.EqualValues(6000, offset)
+}
+
+func (suite *compressionTestSuite) TestXattr() {
+	name := "xattr.log"
+	suite.upload(name, textData(100))
+
+	err := suite.compression.SetXattr(internal.SetXattrOptions{Name: name, Attr: "user.owner", Value: []byte("team1")})
+	suite.assert.NoError(err)
+
+	names, err := suite.compression.ListXattr(internal.ListXattrOptions{Name: name})
+	suite.assert.NoError(err)
+	suite.assert.Equal([]string{"user.owner"}, names)
+
+	err = suite.compression.SetXattr(internal.SetXattrOptions{Name: name, Attr: "user." + metaIndex, Value: []byte("x")})
+	suite.assert.Equal(syscall.EPERM, err)
+
+	// User metadata survives a rewrite of the blob
+	err = suite.compression.TruncateFile(internal.TruncateFileOptions{Name: name, NewSize: 10})
+	suite.assert.NoError(err)
+	value, err := suite.compression.GetXattr(internal.GetXattrOptions{Name: name, Attr: "user.owner"})
+	suite.assert.NoError(err)
+	suite.assert.Equal([]byte("team1"), value)
+}
+
+func (suite *compressionTestSuite) TestFileCache() {
+	fc := file_cache.NewFileCacheComponent()
+	fc.SetNextComponent(suite.compression)
+	suite.assert.NoError(fc.Configure(true))
+	suite.assert.NoError(fc.Start(context.Background()))
+	defer fc.Stop()
+
+	name := "cached.csv"
+	h, err := fc.CreateFile(internal.CreateFileOptions{Name: name, Mode: 0777})
+	suite.assert.NoError(err)
+	data := textData(3*suite.blockSize + 10)
+	_, err = fc.WriteFile(&internal.WriteFileOptions{Handle: h, Offset: 0, Data: data})
+	suite.assert.NoError(err)
+	suite.assert.NoError(fc.ReleaseFile(internal.ReleaseFileOptions{Handle: h}))
+
+	attr, err := fc.GetAttr(internal.GetAttrOptions{Name: name})
+	suite.assert.NoError(err)
+	suite.assert.EqualValues(len(data), attr.Size)
+
+	// Drop the local copy so that the file is downloaded and decompressed again
+	suite.assert.NoError(os.RemoveAll(filepath.Join(suite.cachePath, name)))
+	h, err = fc.OpenFile(internal.OpenFileOptions{Name: name, Flags: os.O_RDONLY})
+	suite.assert.NoError(err)
+	read := make([]byte, len(data))
+	n, err := fc.ReadInBuffer(&internal.ReadInBufferOptions{Handle: h, Offset: 0, Data: read})
+	suite.assert.NoError(err)
+	suite.assert.Equal(len(data), n)
+	suite.assert.Equal(data, read)
+	suite.assert.NoError(fc.ReleaseFile(internal.ReleaseFileOptions{Handle: h}))
+}
+
+func TestCompression(t *testing.T) {
+	suite.Run(t, new(compressionTestSuite))
+}
@@ -0,0 +1,334 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/azure-storage-fuse/v2/common"
+)
+
+// Layout of an encrypted blob:
+//
+//	Plain data is split in chunks of a fixed size and each chunk is sealed on its own using AES-256-GCM
+//	with the data key of the file. A sealed chunk is stored as nonce || ciphertext || tag, so every chunk
+//	can be located, authenticated and decrypted without reading the rest of the blob.
+//	Last chunk of the file may be shorter than the chunk size.
+//
+//	Each chunk is bound to its index in the file, so chunks can not be swapped or moved. Blob always ends with an
+//	end marker, an empty chunk sealed with the index following the last data chunk and the last flag set, so
+//	a blob cut short or extended at a chunk boundary fails to authenticate when its end is read.
+//
+//	Data key of the file is wrapped by the master key and kept in the metadata of the blob along with
+//	the scheme, id of the master key and the chunk size used.
+
+const (
+	nonceSize     = 12
+	tagSize       = 16
+	chunkOverhead = nonceSize + tagSize
+	dataKeySize   = 32
+
+	encryptionScheme = "aes256gcm-chunked-v1"
+
+	metaPrefix = "bfenc_"
+	metaScheme = metaPrefix + "scheme"
+	metaKey    = metaPrefix + "key"
+	metaKeyID  = metaPrefix + "kid"
+	metaChunk  = metaPrefix + "chunk"
+)
+
+var errAuthFailed = errors.New("authentication of encrypted chunk failed")
+
+// fileKey : Data key of one file and the chunk size its blob is written with
+type fileKey struct {
+	aead    cipher.AEAD
+	wrapped string // data key wrapped by the master key, as stored in metadata
+	chunk   uint64
+}
+
+// chunkAAD : Additional data authenticated with a chunk, binds the chunk to its index and marks the end of the blob
+func chunkAAD(index uint64, last bool) []byte {
+	aad := make([]byte, len(encryptionScheme)+9)
+	n := copy(aad, encryptionScheme)
+	binary.BigEndian.PutUint64(aad[n:], index)
+	if last {
+		aad[n+8] = 1
+	}
+	return aad
+}
+
+// chunkCount : Number of chunks holding the given amount of plain data, which is also the index of the end marker
+func chunkCount(plain uint64, chunk uint64) uint64 {
+	return (plain + chunk - 1) / chunk
+}
+
+// sealedSize : Size of the sealed chunks holding the given amount of plain data
+func sealedSize(plain uint64, chunk uint64) uint64 {
+	return plain + chunkCount(plain, chunk)*chunkOverhead
+}
+
+// openedSize : Size of the plain data held by sealed chunks of given size
+func openedSize(raw uint64, chunk uint64) uint64 {
+	overhead := ((raw + chunk + chunkOverhead - 1) / (chunk + chunkOverhead)) * chunkOverhead
+	if overhead > raw {
+		return 0
+	}
+	return raw - overhead
+}
+
+// rawSize : Size of the encrypted blob holding the given amount of plain data, including the end marker
+func rawSize(plain uint64, chunk uint64) uint64 {
+	return sealedSize(plain, chunk) + chunkOverhead
+}
+
+// plainSize : Size of the plain data held by an encrypted blob of given size
+func plainSize(raw uint64, chunk uint64) uint64 {
+	if raw < chunkOverhead {
+		return 0
+	}
+	return openedSize(raw-chunkOverhead, chunk)
+}
+
+// keyID : Identify the master key without revealing it, so that a mount with the wrong key fails clearly
+func keyID(master []byte) string {
+	sum := sha256.Sum256(master)
+	return hex.EncodeToString(sum[:8])
+}
+
+// parseMasterKey : Master key is a base64 encoded 256 bit key, a key file may also hold the raw bytes
+func parseMasterKey(value []byte) ([]byte, error) {
+	if len(value) == dataKeySize {
+		return value, nil
+	}
+
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(value)))
+	if err != nil {
+		return nil, fmt.Errorf("key is not base64 encoded [%s]", err.Error())
+	}
+
+	if len(key) != dataKeySize {
+		return nil, fmt.Errorf("key shall be %d bytes long, found %d", dataKeySize, len(key))
+	}
+	return key, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// newFileKey : Generate a new data key and wrap it with the master key
+func newFileKey(master []byte, chunk uint64) (*fileKey, error) {
+	dek := make([]byte, dataKeySize)
+	if _, err := io.ReadFull(rand.Reader, dek); err != nil {
+		return nil, err
+	}
+
+	wrapped, err := common.EncryptData(dek, master)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newAEAD(dek)
+	if err != nil {
+		return nil, err
+	}
+
+	return &fileKey{
+		aead:    aead,
+		wrapped: base64.StdEncoding.EncodeToString(wrapped),
+		chunk:   chunk,
+	}, nil
+}
+
+// metaValue : Metadata keys are case insensitive in the service
+func metaValue(metadata map[string]*string, key string) (string, bool) {
+	for k, v := range metadata {
+		if v != nil && strings.EqualFold(k, key) {
+			return *v, true
+		}
+	}
+	return "", false
+}
+
+// isEncrypted : Check whether the metadata of a blob marks it as written by this component
+func isEncrypted(metadata map[string]*string) bool {
+	_, found := metaValue(metadata, metaScheme)
+	return found
+}
+
+// isEncryptionMetaKey : Metadata keys owned by this component
+func isEncryptionMetaKey(key string) bool {
+	return strings.HasPrefix(strings.ToLower(key), metaPrefix)
+}
+
+// chunkOf : Chunk size of an encrypted blob, 0 if metadata does not carry a valid one
+func chunkOf(metadata map[string]*string) uint64 {
+	value, _ := metaValue(metadata, metaChunk)
+	chunk, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return chunk
+}
+
+// openFileKey : Unwrap the data key stored in metadata of a blob using the master key
+func openFileKey(master []byte, metadata map[string]*string) (*fileKey, error) {
+	scheme, _ := metaValue(metadata, metaScheme)
+	if scheme != encryptionScheme {
+		return nil, fmt.Errorf("unsupported encryption scheme %s", scheme)
+	}
+
+	kid, _ := metaValue(metadata, metaKeyID)
+	if kid != keyID(master) {
+		return nil, fmt.Errorf("blob is encrypted with key %s, configured key is %s", kid, keyID(master))
+	}
+
+	chunk := chunkOf(metadata)
+	if chunk == 0 {
+		return nil, errors.New("chunk size missing in metadata")
+	}
+
+	wrapped, _ := metaValue(metadata, metaKey)
+	data, err := base64.StdEncoding.DecodeString(wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("invalid wrapped key [%s]", err.Error())
+	}
+
+	dek, err := common.DecryptData(data, master)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap key [%s]", err.Error())
+	}
+
+	aead, err := newAEAD(dek)
+	if err != nil {
+		return nil, err
+	}
+
+	return &fileKey{
+		aead:    aead,
+		wrapped: wrapped,
+		chunk:   chunk,
+	}, nil
+}
+
+// metadata : Encryption metadata to store along with a blob sealed by this key
+func (k *fileKey) metadata(kid string) map[string]*string {
+	scheme, wrapped, chunk := encryptionScheme, k.wrapped, strconv.FormatUint(k.chunk, 10)
+	return map[string]*string{
+		metaScheme: &scheme,
+		metaKey:    &wrapped,
+		metaKeyID:  &kid,
+		metaChunk:  &chunk,
+	}
+}
+
+// seal : Encrypt plain data chunk by chunk, data shall start at the chunk of given index in the file
+func (k *fileKey) seal(data []byte, index uint64) ([]byte, error) {
+	out := make([]byte, sealedSize(uint64(len(data)), k.chunk))
+
+	pos := 0
+	for off := 0; off < len(data); off += int(k.chunk) {
+		end := min(off+int(k.chunk), len(data))
+
+		nonce := out[pos : pos+nonceSize]
+		if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+			return nil, err
+		}
+
+		sealed := k.aead.Seal(out[pos+nonceSize:pos+nonceSize], nonce, data[off:end], chunkAAD(index, false))
+		pos += nonceSize + len(sealed)
+		index++
+	}
+
+	return out[:pos], nil
+}
+
+// open : Decrypt sealed chunks in place starting at the chunk of given index in the file, returns the plain data
+func (k *fileKey) open(raw []byte, index uint64) ([]byte, error) {
+	out := raw[:0]
+
+	for off := 0; off < len(raw); off += int(k.chunk) + chunkOverhead {
+		end := min(off+int(k.chunk)+chunkOverhead, len(raw))
+		if end-off <= chunkOverhead {
+			return nil, errAuthFailed
+		}
+
+		plain, err := k.aead.Open(raw[off+nonceSize:off+nonceSize], raw[off:off+nonceSize], raw[off+nonceSize:end], chunkAAD(index, false))
+		if err != nil {
+			return nil, errAuthFailed
+		}
+		out = append(out, plain...)
+		index++
+	}
+
+	return out, nil
+}
+
+// sealEnd : End marker of a blob holding given number of chunks
+func (k *fileKey) sealEnd(count uint64) ([]byte, error) {
+	out := make([]byte, nonceSize, chunkOverhead)
+	if _, err := io.ReadFull(rand.Reader, out); err != nil {
+		return nil, err
+	}
+
+	return k.aead.Seal(out, out, nil, chunkAAD(count, true)), nil
+}
+
+// openEnd : Verify the end marker of a blob holding given number of chunks
+func (k *fileKey) openEnd(raw []byte, count uint64) error {
+	if len(raw) != chunkOverhead {
+		return errAuthFailed
+	}
+
+	_, err := k.aead.Open(nil, raw[:nonceSize], raw[nonceSize:], chunkAAD(count, true))
+	if err != nil {
+		return errAuthFailed
+	}
+	return nil
+}
@@ -0,0 +1,952 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package encryption
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/Azure/azure-storage-fuse/v2/common"
+	"github.com/Azure/azure-storage-fuse/v2/common/config"
+	"github.com/Azure/azure-storage-fuse/v2/common/log"
+	"github.com/Azure/azure-storage-fuse/v2/internal"
+	"github.com/Azure/azure-storage-fuse/v2/internal/handlemap"
+)
+
+// Common structure for Encryption Component
+type Encryption struct {
+	internal.BaseComponent
+	master    []byte // key wrapping the data keys of files
+	kid       string // id of the master key stored along with the blobs
+	chunkSize uint64 // size of plain data sealed together for new files
+	keys      map[string]*fileKey
+	keyLock   sync.RWMutex
+	pathLocks *common.LockMap
+	staged    map[string]map[string]uint64 // plain size of blocks staged but not committed yet, by file and block id
+	stageLock sync.Mutex
+}
+
+// Structure defining your config parameters
+type EncryptionOptions struct {
+	Key         string `config:"key" yaml:"key,omitempty"`
+	KeyFile     string `config:"key-file" yaml:"key-file,omitempty"`
+	ChunkSizeKB uint64 `config:"chunk-size-kb" yaml:"chunk-size-kb,omitempty"`
+}
+
+const compName = "encryption"
+
+// By default data is sealed in chunks of 64KB
+const defaultChunkSizeKB = 64
+
+// Master key is read from this environment variable when not given in config
+const keyEnvVar = "BLOBFUSE2_ENCRYPTION_KEY"
+
+// Number of chunks handled in one go while copying whole files
+const copySegmentChunks = 64
+
+// Verification to check satisfaction criteria with Component Interface
+var _ internal.Component = &Encryption{}
+
+func (e *Encryption) Name() string {
+	return compName
+}
+
+func (e *Encryption) SetName(name string) {
+	e.BaseComponent.SetName(name)
+}
+
+func (e *Encryption) SetNextComponent(nc internal.Component) {
+	e.BaseComponent.SetNextComponent(nc)
+}
+
+func (e *Encryption) Priority() internal.ComponentPriority {
+	return internal.EComponentPriority.LevelFour()
+}
+
+// Start : Pipeline calls this method to start the component functionality
+//
+//	this shall not block the call otherwise pipeline will not start
+func (e *Encryption) Start(ctx context.Context) error {
+	log.Trace("Encryption::Start : Starting component %s", e.Name())
+	return nil
+}
+
+// Stop : Stop the component functionality and kill all threads started
+func (e *Encryption) Stop() error {
+	log.Trace("Encryption::Stop : Stopping component %s", e.Name())
+
+	e.keyLock.Lock()
+	clear(e.keys)
+	e.keyLock.Unlock()
+
+	e.stageLock.Lock()
+	clear(e.staged)
+	e.stageLock.Unlock()
+
+	return nil
+}
+
+// GenConfig : Generate the default config for the component
+func (e *Encryption) GenConfig() string {
+	log.Info("Encryption::Configure : config generation started")
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "\n%s:", e.Name())
+	fmt.Fprintf(&sb, "\n  chunk-size-kb: %v", defaultChunkSizeKB)
+
+	return sb.String()
+}
+
+// Configure : Pipeline will call this method after constructor so that you can read config and initialize yourself
+//
+//	Return failure if any config is not valid to exit the process
+func (e *Encryption) Configure(_ bool) error {
+	log.Trace("Encryption::Configure : %s", e.Name())
+
+	conf := EncryptionOptions{}
+	err := config.UnmarshalKey(e.Name(), &conf)
+	if err != nil {
+		log.Err("Encryption::Configure : config error [invalid config attributes]")
+		return fmt.Errorf("config error in %s [%s]", e.Name(), err.Error())
+	}
+
+	var keyData []byte
+	if conf.Key != "" {
+		keyData = []byte(conf.Key)
+	} else if conf.KeyFile != "" {
+		keyData, err = os.ReadFile(conf.KeyFile)
+		if err != nil {
+			log.Err("Encryption::Configure : failed to read key file %s [%s]", conf.KeyFile, err.Error())
+			return fmt.Errorf("config error in %s [failed to read key file: %s]", e.Name(), err.Error())
+		}
+	} else {
+		keyData = []byte(os.Getenv(keyEnvVar))
+	}
+
+	if len(keyData) == 0 {
+		log.Err("Encryption::Configure : master key not provided")
+		return fmt.Errorf("config error in %s [key, key-file or %s is required]", e.Name(), keyEnvVar)
+	}
+
+	e.master, err = parseMasterKey(keyData)
+	if err != nil {
+		log.Err("Encryption::Configure : invalid master key [%s]", err.Error())
+		return fmt.Errorf("config error in %s [%s]", e.Name(), err.Error())
+	}
+	e.kid = keyID(e.master)
+
+	e.chunkSize = defaultChunkSizeKB * 1024
+	if config.IsSet(compName + ".chunk-size-kb") {
+		if conf.ChunkSizeKB == 0 {
+			log.Err("Encryption::Configure : chunk-size-kb can not be zero")
+			return fmt.Errorf("config error in %s [chunk-size-kb can not be zero]", e.Name())
+		}
+		e.chunkSize = conf.ChunkSizeKB * 1024
+	}
+
+	err = e.validatePipeline()
+	if err != nil {
+		log.Err("Encryption::Configure : %s", err.Error())
+		return fmt.Errorf("config error in %s [%s]", e.Name(), err.Error())
+	}
+
+	log.Crit("Encryption::Configure : key-id %s, chunk-size %d", e.kid, e.chunkSize)
+
+	return nil
+}
+
+// validatePipeline : Data reaches this component in blocks only through a caching component, the blocks of block cache
+// shall hold whole chunks so that each of them can be sealed independently
+func (e *Encryption) validatePipeline() error {
+	var components []string
+	_ = config.UnmarshalKey("components", &components)
+
+	for _, name := range components {
+		switch name {
+		case "file_cache":
+			return nil
+		case "block_cache", "stream":
+			blockSizeMB := float64(16)
+			if config.IsSet("block_cache.block-size-mb") {
+				_ = config.UnmarshalKey("block_cache.block-size-mb", &blockSizeMB)
+			}
+
+			if uint64(blockSizeMB*float64(common.MbToBytes))%e.chunkSize != 0 {
+				return fmt.Errorf("block-size-mb of block_cache shall be a multiple of chunk-size-kb")
+			}
+			return nil
+		}
+	}
+
+	return fmt.Errorf("file_cache or block_cache is required in the pipeline")
+}
+
+// ------------------------- Key management -------------------------------------------
+
+// getKey : Get the data key of a file, loading it from the metadata of its blob when not known yet.
+// Returns nil if the blob is not encrypted and a new key is not asked for.
+func (e *Encryption) getKey(name string, create bool) (*fileKey, error) {
+	e.keyLock.RLock()
+	key := e.keys[name]
+	e.keyLock.RUnlock()
+	if key != nil {
+		return key, nil
+	}
+
+	flock := e.pathLocks.Get(name)
+	flock.Lock()
+	defer flock.Unlock()
+
+	// Key might have been loaded while waiting for the lock
+	e.keyLock.RLock()
+	key = e.keys[name]
+	e.keyLock.RUnlock()
+	if key != nil {
+		return key, nil
+	}
+
+	attr, err := e.NextComponent().GetAttr(internal.GetAttrOptions{Name: name})
+	if err == nil && isEncrypted(attr.Metadata) {
+		key, err = openFileKey(e.master, attr.Metadata)
+		if err != nil {
+			log.Err("Encryption::getKey : Failed to get key of %s [%s]", name, err.Error())
+			return nil, syscall.EACCES
+		}
+	} else if err != nil && !os.IsNotExist(err) {
+		log.Err("Encryption::getKey : Failed to get attributes of %s [%s]", name, err.Error())
+		return nil, err
+	} else if !create {
+		return nil, nil
+	} else {
+		// Blob does not exist or is not encrypted yet, it gets a key of its own on first write
+		key, err = newFileKey(e.master, e.chunkSize)
+		if err != nil {
+			log.Err("Encryption::getKey : Failed to generate key for %s [%s]", name, err.Error())
+			return nil, err
+		}
+	}
+
+	e.keyLock.Lock()
+	e.keys[name] = key
+	e.keyLock.Unlock()
+
+	return key, nil
+}
+
+// dropKey : Forget the key of a file, it is loaded again from metadata on next use
+func (e *Encryption) dropKey(name string) {
+	e.keyLock.Lock()
+	defer e.keyLock.Unlock()
+	delete(e.keys, name)
+}
+
+// dropKeys : Forget the keys of all files under a directory
+func (e *Encryption) dropKeys(dir string) {
+	dir = internal.ExtendDirName(dir)
+
+	e.keyLock.Lock()
+	defer e.keyLock.Unlock()
+	for name := range e.keys {
+		if strings.HasPrefix(name, dir) {
+			delete(e.keys, name)
+		}
+	}
+}
+
+// addStaged : Remember the plain size of a staged block, the end marker of the blob is placed by it on commit
+func (e *Encryption) addStaged(name string, id string, size uint64) {
+	e.stageLock.Lock()
+	defer e.stageLock.Unlock()
+
+	if e.staged[name] == nil {
+		e.staged[name] = make(map[string]uint64)
+	}
+	e.staged[name][id] = size
+}
+
+// dropStaged : Forget the blocks staged for a file
+func (e *Encryption) dropStaged(name string) {
+	e.stageLock.Lock()
+	defer e.stageLock.Unlock()
+	delete(e.staged, name)
+}
+
+// listSize : Plain size of the file made of the given blocks, blocks not staged by this mount shall be committed already
+func (e *Encryption) listSize(name string, list []string, key *fileKey) (uint64, error) {
+	e.stageLock.Lock()
+	staged := maps.Clone(e.staged[name])
+	e.stageLock.Unlock()
+
+	var committed map[string]uint64
+	size := uint64(0)
+	for _, id := range list {
+		if n, found := staged[id]; found {
+			size += n
+			continue
+		}
+
+		if committed == nil {
+			blocks, err := e.NextComponent().GetCommittedBlockList(name)
+			if err != nil {
+				log.Err("Encryption::listSize : Failed to get block list of %s [%s]", name, err.Error())
+				return 0, err
+			}
+
+			committed = make(map[string]uint64)
+			if blocks != nil {
+				for _, block := range *blocks {
+					committed[block.Id] = openedSize(block.Size, key.chunk)
+				}
+			}
+		}
+
+		n, found := committed[id]
+		if !found {
+			log.Err("Encryption::listSize : Block %s of %s is neither staged nor committed", id, name)
+			return 0, fmt.Errorf("block %s of %s not found", id, name)
+		}
+		size += n
+	}
+
+	return size, nil
+}
+
+// blobMetadata : Metadata to store with a blob, existing keys are retained and encryption keys are refreshed
+func (e *Encryption) blobMetadata(existing map[string]*string, key *fileKey) map[string]*string {
+	metadata := key.metadata(e.kid)
+	for k, v := range existing {
+		if v != nil && !isEncryptionMetaKey(k) {
+			metadata[k] = v
+		}
+	}
+	return metadata
+}
+
+// toPlainAttr : Report the size of plain data for encrypted blobs
+func toPlainAttr(attr *internal.ObjAttr) {
+	if attr == nil || attr.IsDir() || !isEncrypted(attr.Metadata) {
+		return
+	}
+
+	if chunk := chunkOf(attr.Metadata); chunk != 0 {
+		attr.Size = int64(plainSize(uint64(attr.Size), chunk))
+	}
+}
+
+// isEncryptionXattr : Metadata of this component is exposed as user xattrs by storage, it can not be touched by users
+func isEncryptionXattr(name string) bool {
+	return strings.HasPrefix(strings.ToLower(name), "user."+metaPrefix)
+}
+
+// ------------------------- Data path -------------------------------------------
+
+// read : Read plain data of an encrypted blob, retrying once with a fresh key if the blob was rewritten by someone else
+func (e *Encryption) read(name string, size int64, offset int64, data []byte, etag *string, priority internal.IOPriority) (int, error) {
+	key, err := e.getKey(name, false)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := e.readChunks(name, size, key, offset, data, etag, priority)
+	if err == errAuthFailed {
+		e.dropKey(name)
+		key, err = e.getKey(name, false)
+		if err != nil {
+			return 0, err
+		}
+		n, err = e.readChunks(name, size, key, offset, data, etag, priority)
+	}
+
+	if err == errAuthFailed {
+		log.Err("Encryption::read : Failed to decrypt %s at offset %d [%s]", name, offset, err.Error())
+		return 0, syscall.EIO
+	}
+
+	return n, err
+}
+
+// readChunks : Download the chunks covering the requested range and decrypt them
+func (e *Encryption) readChunks(name string, size int64, key *fileKey, offset int64, data []byte, etag *string, priority internal.IOPriority) (int, error) {
+	if key == nil {
+		return 0, errAuthFailed
+	}
+
+	if offset > size {
+		return 0, syscall.ERANGE
+	}
+
+	end := min(offset+int64(len(data)), size)
+	if end <= offset {
+		return 0, nil
+	}
+
+	chunk := int64(key.chunk)
+	unit := chunk + chunkOverhead
+	first, last := offset/chunk, (end-1)/chunk
+	sealed := int64(sealedSize(uint64(size), key.chunk))
+	total := sealed + chunkOverhead
+
+	rawOffset := first * unit
+	rawEnd := min((last+1)*unit, sealed)
+	if rawEnd == sealed {
+		// Read reaches the end of the file, so the end marker is verified as well to catch a blob cut short
+		rawEnd = total
+	}
+	raw := make([]byte, rawEnd-rawOffset)
+
+	n, err := e.NextComponent().ReadInBuffer(&internal.ReadInBufferOptions{
+		Path:     name,
+		Size:     total,
+		Offset:   rawOffset,
+		Data:     raw,
+		Etag:     etag,
+		Priority: priority,
+	})
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+
+	raw = raw[:n]
+	if rawEnd == total {
+		if int64(n) != total-rawOffset {
+			return 0, errAuthFailed
+		}
+
+		err = key.openEnd(raw[sealed-rawOffset:], chunkCount(uint64(size), key.chunk))
+		if err != nil {
+			return 0, err
+		}
+		raw = raw[:sealed-rawOffset]
+	}
+
+	plain, err := key.open(raw, uint64(first))
+	if err != nil {
+		return 0, err
+	}
+
+	skip := offset - first*chunk
+	if skip >= int64(len(plain)) {
+		return 0, nil
+	}
+
+	return copy(data[:end-offset], plain[skip:]), nil
+}
+
+// ReadInBuffer : Decrypt the chunks holding the requested range, unencrypted blobs are read as is
+func (e *Encryption) ReadInBuffer(options *internal.ReadInBufferOptions) (int, error) {
+	name, size := options.Path, options.Size
+	if options.Handle != nil {
+		name, size = options.Handle.Path, atomic.LoadInt64(&options.Handle.Size)
+	}
+
+	key, err := e.getKey(name, false)
+	if err != nil {
+		return 0, err
+	}
+
+	if key == nil {
+		return e.NextComponent().ReadInBuffer(options)
+	}
+
+	return e.read(name, size, options.Offset, options.Data, options.Etag, options.Priority)
+}
+
+func (e *Encryption) ReadFile(options internal.ReadFileOptions) ([]byte, error) {
+	log.Trace("Encryption::ReadFile : %s", options.Handle.Path)
+
+	key, err := e.getKey(options.Handle.Path, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if key == nil {
+		return e.NextComponent().ReadFile(options)
+	}
+
+	data := make([]byte, atomic.LoadInt64(&options.Handle.Size))
+	n, err := e.read(options.Handle.Path, int64(len(data)), 0, data, nil, internal.IOPriorityForeground)
+	return data[:n], err
+}
+
+// CopyToFile : Download and decrypt the blob into the given file
+func (e *Encryption) CopyToFile(options internal.CopyToFileOptions) error {
+	log.Trace("Encryption::CopyToFile : %s", options.Name)
+
+	key, err := e.getKey(options.Name, false)
+	if err != nil {
+		return err
+	}
+
+	if key == nil {
+		return e.NextComponent().CopyToFile(options)
+	}
+
+	attr, err := e.GetAttr(internal.GetAttrOptions{Name: options.Name})
+	if err != nil {
+		return err
+	}
+
+	end := attr.Size
+	if options.Count > 0 {
+		end = min(end, options.Offset+options.Count)
+	}
+
+	buf := make([]byte, int64(key.chunk)*copySegmentChunks)
+	for offset := options.Offset; offset < end; offset += int64(len(buf)) {
+		n, err := e.read(options.Name, attr.Size, offset, buf[:min(int64(len(buf)), end-offset)], nil, internal.IOPriorityForeground)
+		if err != nil {
+			log.Err("Encryption::CopyToFile : Failed to read %s at offset %d [%s]", options.Name, offset, err.Error())
+			return err
+		}
+
+		_, err = options.File.WriteAt(buf[:n], offset-options.Offset)
+		if err != nil {
+			log.Err("Encryption::CopyToFile : Failed to write %s at offset %d [%s]", options.Name, offset, err.Error())
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CopyFromFile : Encrypt the given file into a temporary file and upload that instead
+func (e *Encryption) CopyFromFile(options internal.CopyFromFileOptions) error {
+	log.Trace("Encryption::CopyFromFile : %s", options.Name)
+
+	key, err := e.getKey(options.Name, true)
+	if err != nil {
+		return err
+	}
+
+	sealed, err := os.CreateTemp("", "blobfuse2_enc_*")
+	if err != nil {
+		log.Err("Encryption::CopyFromFile : Failed to create temp file for %s [%s]", options.Name, err.Error())
+		return err
+	}
+	defer func() {
+		sealed.Close()
+		_ = os.Remove(sealed.Name())
+	}()
+
+	buf := make([]byte, int64(key.chunk)*copySegmentChunks)
+	size := uint64(0)
+	for offset := int64(0); ; offset += int64(len(buf)) {
+		n, rerr := options.File.ReadAt(buf, offset)
+		if n > 0 {
+			data, err := key.seal(buf[:n], uint64(offset)/key.chunk)
+			if err != nil {
+				return err
+			}
+
+			_, err = sealed.Write(data)
+			if err != nil {
+				log.Err("Encryption::CopyFromFile : Failed to write temp file for %s [%s]", options.Name, err.Error())
+				return err
+			}
+			size += uint64(n)
+		}
+
+		if rerr == io.EOF {
+			break
+		} else if rerr != nil {
+			log.Err("Encryption::CopyFromFile : Failed to read %s at offset %d [%s]", options.Name, offset, rerr.Error())
+			return rerr
+		}
+	}
+
+	end, err := key.sealEnd(chunkCount(size, key.chunk))
+	if err != nil {
+		return err
+	}
+
+	_, err = sealed.Write(end)
+	if err != nil {
+		log.Err("Encryption::CopyFromFile : Failed to write temp file for %s [%s]", options.Name, err.Error())
+		return err
+	}
+
+	_, err = sealed.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	options.File = sealed
+	options.Metadata = e.blobMetadata(options.Metadata, key)
+	return e.NextComponent().CopyFromFile(options)
+}
+
+// StageData : Seal the block before staging, blocks always start at a chunk boundary of the file
+func (e *Encryption) StageData(options internal.StageDataOptions) error {
+	key, err := e.getKey(options.Name, true)
+	if err != nil {
+		return err
+	}
+
+	size := uint64(len(options.Data))
+	options.Data, err = key.seal(options.Data, options.Offset/key.chunk)
+	if err != nil {
+		log.Err("Encryption::StageData : Failed to encrypt block %s of %s [%s]", options.Id, options.Name, err.Error())
+		return err
+	}
+	options.Offset = sealedSize(options.Offset, key.chunk)
+
+	err = e.NextComponent().StageData(options)
+	if err == nil {
+		e.addStaged(options.Name, options.Id, size)
+	}
+	return err
+}
+
+// CommitData : Commit the sealed blocks along with the wrapped key, metadata of the blob is carried over
+func (e *Encryption) CommitData(options internal.CommitDataOptions) error {
+	log.Trace("Encryption::CommitData : %s", options.Name)
+
+	key, err := e.getKey(options.Name, true)
+	if err != nil {
+		return err
+	}
+
+	metadata := make(map[string]*string)
+	attr, err := e.NextComponent().GetAttr(internal.GetAttrOptions{Name: options.Name})
+	if err == nil {
+		for k, v := range attr.Metadata {
+			metadata[k] = v
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	for k, v := range options.Metadata {
+		metadata[k] = v
+	}
+
+	options.Metadata = e.blobMetadata(metadata, key)
+	if options.BlockSize != 0 {
+		options.BlockSize = sealedSize(options.BlockSize, key.chunk)
+	}
+
+	// End marker goes in a block of its own after the data, it is never reported back in the block list
+	size, err := e.listSize(options.Name, options.List, key)
+	if err != nil {
+		return err
+	}
+
+	end, err := key.sealEnd(chunkCount(size, key.chunk))
+	if err != nil {
+		return err
+	}
+
+	idLength := int64(common.BlockIDLength)
+	if len(options.List) > 0 {
+		idLength = common.GetIdLength(options.List[0])
+	}
+
+	id := common.GetBlockID(idLength)
+	err = e.NextComponent().StageData(internal.StageDataOptions{Name: options.Name, Id: id, Data: end, Offset: sealedSize(size, key.chunk)})
+	if err != nil {
+		log.Err("Encryption::CommitData : Failed to stage end marker of %s [%s]", options.Name, err.Error())
+		return err
+	}
+	options.List = append(slices.Clone(options.List), id)
+
+	err = e.NextComponent().CommitData(options)
+	if err == nil {
+		e.dropStaged(options.Name)
+	}
+	return err
+}
+
+// GetCommittedBlockList : Report the blocks by the plain data they hold
+func (e *Encryption) GetCommittedBlockList(name string) (*internal.CommittedBlockList, error) {
+	list, err := e.NextComponent().GetCommittedBlockList(name)
+	if err != nil || list == nil || len(*list) == 0 {
+		return list, err
+	}
+
+	key, err := e.getKey(name, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if key == nil {
+		// Encrypted blocks can not be mixed with the plain data of this blob
+		log.Err("Encryption::GetCommittedBlockList : %s is not encrypted", name)
+		return nil, fmt.Errorf("blob %s is not encrypted", name)
+	}
+
+	// Blob committed in blocks ends with a block holding just the end marker, which is added again on next commit
+	data := (*list)[:len(*list)-1]
+	if (*list)[len(*list)-1].Size != chunkOverhead {
+		log.Err("Encryption::GetCommittedBlockList : Last block of %s is not an end marker", name)
+		return nil, fmt.Errorf("blocks of %s are not aligned to encryption chunks", name)
+	}
+
+	unit := key.chunk + chunkOverhead
+	blocks := make(internal.CommittedBlockList, 0, len(data))
+	offset := int64(0)
+	for i, block := range data {
+		if i < len(data)-1 && block.Size%unit != 0 {
+			log.Err("Encryption::GetCommittedBlockList : Block %s of %s is not aligned to chunks", block.Id, name)
+			return nil, fmt.Errorf("blocks of %s are not aligned to encryption chunks", name)
+		}
+
+		size := openedSize(block.Size, key.chunk)
+		blocks = append(blocks, internal.CommittedBlock{Id: block.Id, Offset: offset, Size: size})
+		offset += int64(size)
+	}
+
+	return &blocks, nil
+}
+
+// TruncateFile : Sealed chunks can not be cut in storage, so the file is rewritten with the new size
+func (e *Encryption) TruncateFile(options internal.TruncateFileOptions) error {
+	log.Trace("Encryption::TruncateFile : %s to %d", options.Name, options.NewSize)
+
+	attr, err := e.NextComponent().GetAttr(internal.GetAttrOptions{Name: options.Name})
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp("", "blobfuse2_enc_*")
+	if err != nil {
+		log.Err("Encryption::TruncateFile : Failed to create temp file for %s [%s]", options.Name, err.Error())
+		return err
+	}
+	defer func() {
+		f.Close()
+		_ = os.Remove(f.Name())
+	}()
+
+	if options.NewSize > 0 {
+		err = e.CopyToFile(internal.CopyToFileOptions{Name: options.Name, File: f})
+		if err != nil {
+			return err
+		}
+	}
+
+	err = f.Truncate(options.NewSize)
+	if err != nil {
+		return err
+	}
+
+	return e.CopyFromFile(internal.CopyFromFileOptions{Name: options.Name, File: f, Metadata: attr.Metadata})
+}
+
+// Fallocate : Growing a file writes sealed zeros, holes can only be punched by a cache component
+func (e *Encryption) Fallocate(options internal.FallocateOptions) error {
+	log.Trace("Encryption::Fallocate : %s mode %d, offset %d, length %d", options.Name, options.Mode, options.Offset, options.Length)
+
+	switch options.Mode {
+	case 0:
+		attr, err := e.GetAttr(internal.GetAttrOptions{Name: options.Name})
+		if err != nil {
+			return err
+		}
+
+		newSize := options.Offset + options.Length
+		if newSize <= attr.Size {
+			return nil
+		}
+
+		return e.TruncateFile(internal.TruncateFileOptions{Name: options.Name, OldSize: attr.Size, NewSize: newSize})
+	case internal.FallocKeepSize:
+		return nil
+	default:
+		return syscall.ENOTSUP
+	}
+}
+
+// Lseek : Encrypted blobs have no holes, whole plain size of the file is data
+func (e *Encryption) Lseek(options internal.LseekOptions) (int64, error) {
+	log.Trace("Encryption::Lseek : %s offset %d, whence %d", options.Name, options.Offset, options.Whence)
+
+	attr, err := e.NextComponent().GetAttr(internal.GetAttrOptions{Name: options.Name})
+	if err != nil {
+		return 0, err
+	}
+
+	if !isEncrypted(attr.Metadata) {
+		return e.NextComponent().Lseek(options)
+	}
+	toPlainAttr(attr)
+
+	if options.Offset >= attr.Size {
+		return 0, syscall.ENXIO
+	}
+
+	switch options.Whence {
+	case internal.SeekData:
+		return options.Offset, nil
+	case internal.SeekHole:
+		return attr.Size, nil
+	default:
+		return 0, syscall.EINVAL
+	}
+}
+
+// CopyFileRange : Server side copy would move sealed chunks of one file into another, let the caller copy the data
+func (e *Encryption) CopyFileRange(options internal.CopyFileRangeOptions) (int64, error) {
+	log.Trace("Encryption::CopyFileRange : %s to %s", options.SrcName, options.DstName)
+	return 0, syscall.ENOTSUP
+}
+
+// WriteFile : Only whole blocks can be sealed, writes have to go through a caching component
+func (e *Encryption) WriteFile(options *internal.WriteFileOptions) (int, error) {
+	log.Err("Encryption::WriteFile : Direct write to %s is not supported", options.Handle.Path)
+	return 0, syscall.ENOTSUP
+}
+
+// ------------------------- Attributes -------------------------------------------
+
+func (e *Encryption) GetAttr(options internal.GetAttrOptions) (*internal.ObjAttr, error) {
+	attr, err := e.NextComponent().GetAttr(options)
+	if err == nil {
+		toPlainAttr(attr)
+	}
+	return attr, err
+}
+
+func (e *Encryption) ReadDir(options internal.ReadDirOptions) ([]*internal.ObjAttr, error) {
+	list, err := e.NextComponent().ReadDir(options)
+	for _, attr := range list {
+		toPlainAttr(attr)
+	}
+	return list, err
+}
+
+func (e *Encryption) StreamDir(options internal.StreamDirOptions) ([]*internal.ObjAttr, string, error) {
+	list, token, err := e.NextComponent().StreamDir(options)
+	for _, attr := range list {
+		toPlainAttr(attr)
+	}
+	return list, token, err
+}
+
+func (e *Encryption) GetXattr(options internal.GetXattrOptions) ([]byte, error) {
+	if isEncryptionXattr(options.Attr) {
+		return nil, syscall.ENODATA
+	}
+	return e.NextComponent().GetXattr(options)
+}
+
+func (e *Encryption) SetXattr(options internal.SetXattrOptions) error {
+	if isEncryptionXattr(options.Attr) {
+		return syscall.EPERM
+	}
+	return e.NextComponent().SetXattr(options)
+}
+
+func (e *Encryption) ListXattr(options internal.ListXattrOptions) ([]string, error) {
+	names, err := e.NextComponent().ListXattr(options)
+	if err != nil {
+		return nil, err
+	}
+
+	visible := make([]string, 0, len(names))
+	for _, name := range names {
+		if !isEncryptionXattr(name) {
+			visible = append(visible, name)
+		}
+	}
+	return visible, nil
+}
+
+func (e *Encryption) RemoveXattr(options internal.RemoveXattrOptions) error {
+	if isEncryptionXattr(options.Attr) {
+		return syscall.EPERM
+	}
+	return e.NextComponent().RemoveXattr(options)
+}
+
+// ------------------------- Namespace -------------------------------------------
+
+func (e *Encryption) CreateFile(options internal.CreateFileOptions) (*handlemap.Handle, error) {
+	// New blob carries no key, a fresh one is generated on first write
+	e.dropKey(options.Name)
+	return e.NextComponent().CreateFile(options)
+}
+
+func (e *Encryption) DeleteFile(options internal.DeleteFileOptions) error {
+	err := e.NextComponent().DeleteFile(options)
+	e.dropKey(options.Name)
+	e.dropStaged(options.Name)
+	return err
+}
+
+func (e *Encryption) RenameFile(options internal.RenameFileOptions) error {
+	err := e.NextComponent().RenameFile(options)
+	e.dropKey(options.Src)
+	e.dropKey(options.Dst)
+	return err
+}
+
+func (e *Encryption) DeleteDir(options internal.DeleteDirOptions) error {
+	err := e.NextComponent().DeleteDir(options)
+	e.dropKeys(options.Name)
+	return err
+}
+
+func (e *Encryption) RenameDir(options internal.RenameDirOptions) error {
+	err := e.NextComponent().RenameDir(options)
+	e.dropKeys(options.Src)
+	e.dropKeys(options.Dst)
+	return err
+}
+
+// ------------------------- Factory -------------------------------------------
+
+// Pipeline will call this method to create your object, initialize your variables here
+// << DO NOT DELETE ANY AUTO GENERATED CODE HERE >>
+func NewEncryptionComponent() internal.Component {
+	comp := &Encryption{
+		keys:      make(map[string]*fileKey),
+		pathLocks: common.NewLockMap(),
+		staged:    make(map[string]map[string]uint64),
+	}
+	comp.SetName(compName)
+	return comp
+}
+
+// On init register this component to pipeline and supply your constructor
+func init() {
+	internal.AddComponent(compName, NewEncryptionComponent)
+}
@@ -0,0 +1,473 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/Azure/azure-storage-fuse/v2/common"
+	"github.com/Azure/azure-storage-fuse/v2/common/config"
+	"github.com/Azure/azure-storage-fuse/v2/common/log"
+	"github.com/Azure/azure-storage-fuse/v2/component/file_cache"
+	"github.com/Azure/azure-storage-fuse/v2/component/loopback"
+	"github.com/Azure/azure-storage-fuse/v2/internal"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+var home_dir, _ = os.UserHomeDir()
+
+type encryptionTestSuite struct {
+	suite.Suite
+	assert      *assert.Assertions
+	encryption  *Encryption
+	loopback    internal.Component
+	storagePath string
+	cachePath   string
+	masterKey   string
+	chunkSize   int
+	blockSize   int
+}
+
+func newKey() string {
+	key := make([]byte, dataKeySize)
+	_, _ = rand.Read(key)
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+func randomData(size int) []byte {
+	data := make([]byte, size)
+	_, _ = rand.Read(data)
+	return data
+}
+
+func (suite *encryptionTestSuite) SetupTest() {
+	err := log.SetDefaultLogger("silent", common.LogConfig{Level: common.ELogLevel.LOG_DEBUG()})
+	if err != nil {
+		panic("Unable to set silent logger as default.")
+	}
+
+	suffix := fmt.Sprintf("%x", randomData(4))
+	suite.storagePath = filepath.Join(home_dir, "enc_storage"+suffix)
+	suite.cachePath = filepath.Join(home_dir, "enc_cache"+suffix)
+	suite.masterKey = newKey()
+	suite.chunkSize = 4 * 1024
+	suite.blockSize = 4 * suite.chunkSize
+	_ = os.MkdirAll(suite.storagePath, 0777)
+
+	suite.setupTestHelper(suite.masterKey)
+}
+
+func (suite *encryptionTestSuite) config(key string) string {
+	return fmt.Sprintf("components:\n  - file_cache\n  - encryption\n  - loopbackfs\n\nencryption:\n  key: %s\n  chunk-size-kb: 4\n\nfile_cache:\n  path: %s\n  timeout-sec: 0\n\nloopbackfs:\n  path: %s",
+		key, suite.cachePath, suite.storagePath)
+}
+
+func (suite *encryptionTestSuite) setupTestHelper(key string) {
+	suite.assert = assert.New(suite.T())
+
+	err := config.ReadConfigFromReader(strings.NewReader(suite.config(key)))
+	suite.assert.NoError(err)
+
+	suite.loopback = loopback.NewLoopbackFSComponent()
+	_ = suite.loopback.Configure(true)
+
+	comp := NewEncryptionComponent()
+	comp.SetNextComponent(suite.loopback)
+	err = comp.Configure(true)
+	suite.assert.NoError(err)
+	suite.encryption = comp.(*Encryption)
+
+	suite.assert.NoError(suite.loopback.Start(context.Background()))
+	suite.assert.NoError(suite.encryption.Start(context.Background()))
+}
+
+func (suite *encryptionTestSuite) TearDownTest() {
+	_ = suite.encryption.Stop()
+	_ = suite.loopback.Stop()
+	os.RemoveAll(suite.storagePath)
+	os.RemoveAll(suite.cachePath)
+}
+
+// writeBlocks : Stage the data in blocks the way block cache does and commit them
+func (suite *encryptionTestSuite) writeBlocks(name string, data []byte) {
+	ids := make([]string, 0)
+	for offset := 0; offset < len(data); offset += suite.blockSize {
+		id := common.GetBlockID(common.BlockIDLength)
+		err := suite.encryption.StageData(internal.StageDataOptions{
+			Name:   name,
+			Id:     id,
+			Data:   data[offset:min(offset+suite.blockSize, len(data))],
+			Offset: uint64(offset),
+		})
+		suite.assert.NoError(err)
+		ids = append(ids, id)
+	}
+
+	err := suite.encryption.CommitData(internal.CommitDataOptions{Name: name, List: ids, BlockSize: uint64(suite.blockSize)})
+	suite.assert.NoError(err)
+}
+
+func (suite *encryptionTestSuite) read(name string, offset int64, length int) ([]byte, error) {
+	attr, err := suite.encryption.GetAttr(internal.GetAttrOptions{Name: name})
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, length)
+	n, err := suite.encryption.ReadInBuffer(&internal.ReadInBufferOptions{Path: name, Size: attr.Size, Offset: offset, Data: data})
+	return data[:n], err
+}
+
+func (suite *encryptionTestSuite) TestSizeMapping() {
+	chunk := uint64(suite.chunkSize)
+	for _, size := range []uint64{0, 1, chunk - 1, chunk, chunk + 1, 3*chunk + 17, 10 * chunk} {
+		raw := rawSize(size, chunk)
+		suite.assert.Equal(size, plainSize(raw, chunk), "size %d", size)
+	}
+	suite.assert.EqualValues(2*chunk+3*chunkOverhead, rawSize(2*chunk, chunk))
+	suite.assert.EqualValues(chunkOverhead, rawSize(0, chunk))
+}
+
+func (suite *encryptionTestSuite) TestChunkBinding() {
+	key, err := newFileKey(randomData(dataKeySize), uint64(suite.chunkSize))
+	suite.assert.NoError(err)
+
+	data := randomData(3 * suite.chunkSize)
+	raw, err := key.seal(data, 5)
+	suite.assert.NoError(err)
+
+	plain, err := key.open(bytes.Clone(raw), 5)
+	suite.assert.NoError(err)
+	suite.assert.Equal(data, plain)
+
+	// Chunks do not open at another position of the file
+	_, err = key.open(bytes.Clone(raw), 6)
+	suite.assert.Equal(errAuthFailed, err)
+
+	unit := suite.chunkSize + chunkOverhead
+	swapped := append(bytes.Clone(raw[unit:2*unit]), raw[:unit]...)
+	_, err = key.open(swapped, 5)
+	suite.assert.Equal(errAuthFailed, err)
+
+	// End marker only opens for the chunk count it was sealed with
+	end, err := key.sealEnd(8)
+	suite.assert.NoError(err)
+	suite.assert.Len(end, chunkOverhead)
+	suite.assert.NoError(key.openEnd(end, 8))
+	suite.assert.Equal(errAuthFailed, key.openEnd(end, 7))
+
+	// Data chunk can not stand in for the end marker
+	empty, err := key.seal(nil, 8)
+	suite.assert.NoError(err)
+	suite.assert.Empty(empty)
+}
+
+func (suite *encryptionTestSuite) TestConfigureWithoutKey() {
+	configuration := "components:\n  - block_cache\n  - encryption\n  - loopbackfs\n\nencryption:\n  chunk-size-kb: 4\n"
+	suite.assert.NoError(config.ReadConfigFromReader(strings.NewReader(configuration)))
+
+	comp := NewEncryptionComponent()
+	err := comp.Configure(true)
+	suite.assert.Error(err)
+	suite.assert.Contains(err.Error(), keyEnvVar)
+
+	suite.T().Setenv(keyEnvVar, newKey())
+	suite.assert.NoError(comp.Configure(true))
+}
+
+func (suite *encryptionTestSuite) TestConfigureInvalid() {
+	configs := []string{
+		// key of wrong length
+		fmt.Sprintf("components:\n  - file_cache\n  - encryption\n\nencryption:\n  key: %s\n", base64.StdEncoding.EncodeToString([]byte("short"))),
+		// no caching component
+		fmt.Sprintf("components:\n  - encryption\n  - loopbackfs\n\nencryption:\n  key: %s\n", newKey()),
+		// block size not made of whole chunks
+		fmt.Sprintf("components:\n  - block_cache\n  - encryption\n\nencryption:\n  key: %s\n  chunk-size-kb: 3\n", newKey()),
+	}
+
+	for _, configuration := range configs {
+		suite.assert.NoError(config.ReadConfigFromReader(strings.NewReader(configuration)))
+		comp := NewEncryptionComponent()
+		suite.assert.Error(comp.Configure(true), configuration)
+	}
+}
+
+func (suite *encryptionTestSuite) TestStageCommitRead() {
+	name := "blocks.bin"
+	data := randomData(3*suite.blockSize + 1000)
+	suite.writeBlocks(name, data)
+
+	// Storage holds sealed chunks along with the wrapped key
+	raw, err := os.ReadFile(filepath.Join(suite.storagePath, name))
+	suite.assert.NoError(err)
+	suite.assert.EqualValues(rawSize(uint64(len(data)), uint64(suite.chunkSize)), len(raw))
+	suite.assert.False(bytes.Contains(raw, data[:64]))
+
+	attr, err := suite.encryption.GetAttr(internal.GetAttrOptions{Name: name})
+	suite.assert.NoError(err)
+	suite.assert.EqualValues(len(data), attr.Size)
+	suite.assert.True(isEncrypted(attr.Metadata))
+
+	// Random reads only need the chunks they touch
+	for _, r := range [][2]int{{0, len(data)}, {1, 10}, {suite.chunkSize - 5, 10}, {suite.blockSize + 100, 2 * suite.chunkSize}, {len(data) - 10, 100}} {
+		read, err := suite.read(name, int64(r[0]), r[1])
+		suite.assert.NoError(err)
+		suite.assert.Equal(data[r[0]:min(r[0]+r[1], len(data))], read)
+	}
+
+	// Block list is reported in terms of plain data
+	list, err := suite.encryption.GetCommittedBlockList(name)
+	suite.assert.NoError(err)
+	suite.assert.NotNil(list)
+}
+
+func (suite *encryptionTestSuite) TestCopyToFromFile() {
+	name := "copy.bin"
+	data := randomData(5*suite.chunkSize*copySegmentChunks/4 + 77)
+
+	src, err := os.CreateTemp("", "enc_src_*")
+	suite.assert.NoError(err)
+	defer os.Remove(src.Name())
+	_, err = src.Write(data)
+	suite.assert.NoError(err)
+
+	user := "team1"
+	err = suite.encryption.CopyFromFile(internal.CopyFromFileOptions{Name: name, File: src, Metadata: map[string]*string{"owner": &user}})
+	suite.assert.NoError(err)
+	src.Close()
+
+	dst, err := os.CreateTemp("", "enc_dst_*")
+	suite.assert.NoError(err)
+	defer os.Remove(dst.Name())
+	err = suite.encryption.CopyToFile(internal.CopyToFileOptions{Name: name, File: dst})
+	suite.assert.NoError(err)
+	dst.Close()
+
+	read, err := os.ReadFile(dst.Name())
+	suite.assert.NoError(err)
+	suite.assert.Equal(data, read)
+
+	// Partial download lands at the start of the file
+	part, err := os.CreateTemp("", "enc_part_*")
+	suite.assert.NoError(err)
+	defer os.Remove(part.Name())
+	err = suite.encryption.CopyToFile(internal.CopyToFileOptions{Name: name, Offset: 1000, Count: 5000, File: part})
+	suite.assert.NoError(err)
+	part.Close()
+
+	read, err = os.ReadFile(part.Name())
+	suite.assert.NoError(err)
+	suite.assert.Equal(data[1000:6000], read)
+
+	// User metadata is kept and encryption metadata is hidden from xattrs
+	names, err := suite.encryption.ListXattr(internal.ListXattrOptions{Name: name})
+	suite.assert.NoError(err)
+	suite.assert.Equal([]string{"user.owner"}, names)
+
+	_, err = suite.encryption.GetXattr(internal.GetXattrOptions{Name: name, Attr: "user." + metaKey})
+	suite.assert.Equal(syscall.ENODATA, err)
+	err = suite.encryption.SetXattr(internal.SetXattrOptions{Name: name, Attr: "user." + metaKeyID, Value: []byte("x")})
+	suite.assert.Equal(syscall.EPERM, err)
+}
+
+func (suite *encryptionTestSuite) TestTamperDetected() {
+	name := "tamper.bin"
+	data := randomData(2 * suite.blockSize)
+	suite.writeBlocks(name, data)
+
+	path := filepath.Join(suite.storagePath, name)
+	f, err := os.OpenFile(path, os.O_RDWR, 0666)
+	suite.assert.NoError(err)
+	b := make([]byte, 1)
+	_, err = f.ReadAt(b, int64(suite.chunkSize+chunkOverhead+nonceSize+5))
+	suite.assert.NoError(err)
+	b[0] ^= 0xff
+	_, err = f.WriteAt(b, int64(suite.chunkSize+chunkOverhead+nonceSize+5))
+	suite.assert.NoError(err)
+	f.Close()
+
+	// First chunk is untouched and can still be read
+	read, err := suite.read(name, 0, suite.chunkSize)
+	suite.assert.NoError(err)
+	suite.assert.Equal(data[:suite.chunkSize], read)
+
+	_, err = suite.read(name, int64(suite.chunkSize), 10)
+	suite.assert.Equal(syscall.EIO, err)
+}
+
+func (suite *encryptionTestSuite) TestTruncationDetected() {
+	name := "cut.bin"
+	data := randomData(2 * suite.blockSize)
+	suite.writeBlocks(name, data)
+
+	path := filepath.Join(suite.storagePath, name)
+	raw, err := os.ReadFile(path)
+	suite.assert.NoError(err)
+
+	// Blob cut at a chunk boundary, whole chunks are there but the end marker is gone
+	unit := suite.chunkSize + chunkOverhead
+	suite.assert.NoError(os.Truncate(path, int64(4*unit)))
+
+	read, err := suite.read(name, 0, 10)
+	suite.assert.NoError(err)
+	suite.assert.Equal(data[:10], read)
+
+	_, err = suite.read(name, 0, len(data))
+	suite.assert.Equal(syscall.EIO, err)
+
+	// End marker of the full blob does not fit a shorter one either
+	suite.assert.NoError(os.WriteFile(path, append(raw[:4*unit:4*unit], raw[len(raw)-chunkOverhead:]...), 0666))
+	_, err = suite.read(name, 0, len(data))
+	suite.assert.Equal(syscall.EIO, err)
+
+	// Committed block list does not report the end marker
+	suite.assert.NoError(os.WriteFile(path, raw, 0666))
+	list, err := suite.encryption.GetCommittedBlockList(name)
+	suite.assert.NoError(err)
+	suite.assert.Len(*list, 2)
+	suite.assert.EqualValues(suite.blockSize, (*list)[1].Size)
+}
+
+func (suite *encryptionTestSuite) TestWrongKey() {
+	name := "wrongkey.bin"
+	suite.writeBlocks(name, randomData(100))
+
+	_ = suite.encryption.Stop()
+	suite.setupTestHelper(newKey())
+
+	_, err := suite.read(name, 0, 100)
+	suite.assert.Equal(syscall.EACCES, err)
+}
+
+func (suite *encryptionTestSuite) TestUnencryptedPassthrough() {
+	name := "plain.txt"
+	data := append([]byte("plain text written before encryption was enabled"), make([]byte, 2*common.MbToBytes)...)
+	suite.assert.NoError(os.WriteFile(filepath.Join(suite.storagePath, name), data, 0666))
+
+	read, err := suite.read(name, 6, 4)
+	suite.assert.NoError(err)
+	suite.assert.Equal([]byte("text"), read)
+
+	// Encrypted blocks can not be added to it
+	_, err = suite.encryption.GetCommittedBlockList(name)
+	suite.assert.Error(err)
+}
+
+func (suite *encryptionTestSuite) TestBlobRewritten() {
+	name := "rewritten.bin"
+	suite.writeBlocks(name, randomData(1000))
+	_, err := suite.read(name, 0, 10)
+	suite.assert.NoError(err)
+
+	// Another mount replaces the blob with a new data key
+	other := NewEncryptionComponent().(*Encryption)
+	other.SetNextComponent(suite.loopback)
+	suite.assert.NoError(other.Configure(true))
+	data := randomData(2000)
+	id := common.GetBlockID(common.BlockIDLength)
+	suite.assert.NoError(other.StageData(internal.StageDataOptions{Name: name, Id: id, Data: data}))
+	suite.assert.NoError(other.CommitData(internal.CommitDataOptions{Name: name, List: []string{id}, BlockSize: uint64(suite.blockSize)}))
+
+	read, err := suite.read(name, 0, len(data))
+	suite.assert.NoError(err)
+	suite.assert.Equal(data, read)
+}
+
+func (suite *encryptionTestSuite) TestTruncate() {
+	name := "truncate.bin"
+	data := randomData(suite.blockSize + 300)
+	suite.writeBlocks(name, data)
+
+	err := suite.encryption.TruncateFile(internal.TruncateFileOptions{Name: name, NewSize: 5000})
+	suite.assert.NoError(err)
+	read, err := suite.read(name, 0, len(data))
+	suite.assert.NoError(err)
+	suite.assert.Equal(data[:5000], read)
+
+	err = suite.encryption.Fallocate(internal.FallocateOptions{Name: name, Offset: 0, Length: 6000})
+	suite.assert.NoError(err)
+	read, err = suite.read(name, 0, len(data))
+	suite.assert.NoError(err)
+	suite.assert.Equal(append(data[:5000:5000], make([]byte, 1000)...), read)
+
+	offset, err := suite.encryption.Lseek(internal.LseekOptions{Name: name, Offset: 10, Whence: internal.SeekHole})
+	suite.assert.NoError(err)
+	suite.assert.EqualValues(6000, offset)
+}
+
+func (suite *encryptionTestSuite) TestFileCache() {
+	fc := file_cache.NewFileCacheComponent()
+	fc.SetNextComponent(suite.encryption)
+	suite.assert.NoError(fc.Configure(true))
+	suite.assert.NoError(fc.Start(context.Background()))
+	defer fc.Stop()
+
+	name := "cached.txt"
+	h, err := fc.CreateFile(internal.CreateFileOptions{Name: name, Mode: 0777})
+	suite.assert.NoError(err)
+	data := randomData(3*suite.chunkSize + 10)
+	_, err = fc.WriteFile(&internal.WriteFileOptions{Handle: h, Offset: 0, Data: data})
+	suite.assert.NoError(err)
+	suite.assert.NoError(fc.ReleaseFile(internal.ReleaseFileOptions{Handle: h}))
+
+	raw, err := os.ReadFile(filepath.Join(suite.storagePath, name))
+	suite.assert.NoError(err)
+	suite.assert.NotEqual(data, raw)
+
+	// Drop the local copy so that the file is downloaded and decrypted again
+	suite.assert.NoError(os.RemoveAll(filepath.Join(suite.cachePath, name)))
+	h, err = fc.OpenFile(internal.OpenFileOptions{Name: name, Flags: os.O_RDONLY})
+	suite.assert.NoError(err)
+	read := make([]byte, len(data))
+	n, err := fc.ReadInBuffer(&internal.ReadInBufferOptions{Handle: h, Offset: 0, Data: read})
+	suite.assert.NoError(err)
+	suite.assert.Equal(len(data), n)
+	suite.assert.Equal(data, read)
+	suite.assert.NoError(fc.ReleaseFile(internal.ReleaseFileOptions{Handle: h}))
+}
+
+func TestEncryption(t *testing.T) {
+	suite.Run(t, new(encryptionTestSuite))
+}
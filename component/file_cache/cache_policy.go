@@ -36,6 +36,7 @@ package file_cache
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/Azure/azure-storage-fuse/v2/common"
 	"github.com/Azure/azure-storage-fuse/v2/common/log"
@@ -73,6 +74,22 @@ type cachePolicy interface {
 	Name() string // The name of the policy
 }
 
+// NewCachePolicy : Create the eviction policy of the given name, lru when no name is given
+func NewCachePolicy(name string, cfg cachePolicyConfig) (cachePolicy, error) {
+	switch strings.ToLower(name) {
+	case "", "lru":
+		return NewLRUPolicy(cfg), nil
+	case "lfu":
+		return NewLFUPolicy(cfg), nil
+	case "2q":
+		return New2QPolicy(cfg), nil
+	case "gdsf":
+		return NewGDSFPolicy(cfg), nil
+	default:
+		return nil, fmt.Errorf("invalid cache policy %s, supported policies are lru|lfu|2q|gdsf", name)
+	}
+}
+
 // getUsagePercentage:  The current cache usage as a percentage of the maxSize
 func getUsagePercentage(path string, maxSizeMB float64) float64 {
 	var curSize float64
@@ -103,6 +103,21 @@ func (suite *cachePolicyTestSuite) TestDeleteFile() {
 	f.Close()
 }
 
+func (suite *cachePolicyTestSuite) TestNewCachePolicy() {
+	defer suite.cleanupTest()
+	cfg := cachePolicyConfig{tmpPath: cache_path, fileLocks: &common.LockMap{}}
+
+	for name, expected := range map[string]string{"": "lru", "lru": "lru", "LFU": "lfu", "2q": "2q", "gdsf": "gdsf"} {
+		policy, err := NewCachePolicy(name, cfg)
+		suite.assert.NoError(err)
+		suite.assert.Equal(expected, policy.Name())
+	}
+
+	policy, err := NewCachePolicy("mru", cfg)
+	suite.assert.Error(err)
+	suite.assert.Nil(policy)
+}
+
 func TestCachePolicyTestSuite(t *testing.T) {
 	suite.Run(t, new(cachePolicyTestSuite))
 }
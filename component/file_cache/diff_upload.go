@@ -0,0 +1,268 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package file_cache
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/Azure/azure-storage-fuse/v2/common"
+	"github.com/Azure/azure-storage-fuse/v2/common/log"
+	"github.com/Azure/azure-storage-fuse/v2/internal"
+	"github.com/Azure/azure-storage-fuse/v2/internal/handlemap"
+)
+
+const (
+	dirtyRangesKey = "dirtyRanges"
+
+	// Beyond this many disjoint ranges the handle is treated as fully rewritten
+	maxDirtyRanges = 4096
+)
+
+type byteRange struct {
+	start int64
+	end   int64
+}
+
+// dirtyRanges tracks the byte ranges of a file modified through a handle since its last upload
+type dirtyRanges struct {
+	sync.Mutex
+	ranges []byteRange
+	full   bool
+}
+
+// add merges [start, end) into the sorted list of ranges
+func (d *dirtyRanges) add(start int64, end int64) {
+	d.Lock()
+	defer d.Unlock()
+
+	if d.full || start >= end {
+		return
+	}
+
+	i := sort.Search(len(d.ranges), func(i int) bool { return d.ranges[i].end >= start })
+	j := i
+	for j < len(d.ranges) && d.ranges[j].start <= end {
+		start = min(start, d.ranges[j].start)
+		end = max(end, d.ranges[j].end)
+		j++
+	}
+	d.ranges = slices.Replace(d.ranges, i, j, byteRange{start: start, end: end})
+
+	if len(d.ranges) > maxDirtyRanges {
+		d.setFull()
+	}
+}
+
+func (d *dirtyRanges) setFull() {
+	d.full = true
+	d.ranges = nil
+}
+
+// overlaps checks whether any modified byte falls in [start, end)
+func (d *dirtyRanges) overlaps(start int64, end int64) bool {
+	i := sort.Search(len(d.ranges), func(i int) bool { return d.ranges[i].end > start })
+	return i < len(d.ranges) && d.ranges[i].start < end
+}
+
+// reset forgets all ranges once the file is uploaded
+func (d *dirtyRanges) reset() {
+	d.Lock()
+	defer d.Unlock()
+
+	d.full = false
+	d.ranges = nil
+}
+
+// getDirtyRanges returns the ranges tracked for the handle, nil when differential upload is off
+func getDirtyRanges(handle *handlemap.Handle) *dirtyRanges {
+	val, found := handle.GetValue(dirtyRangesKey)
+	if !found {
+		return nil
+	}
+	return val.(*dirtyRanges)
+}
+
+// trackDirtyRanges attaches range tracking to a new handle, full marks the whole file as modified
+func (fc *FileCache) trackDirtyRanges(handle *handlemap.Handle, full bool) {
+	if !fc.diffUpload {
+		return
+	}
+
+	d := &dirtyRanges{}
+	if full {
+		d.setFull()
+	}
+	handle.SetValue(dirtyRangesKey, d)
+}
+
+// addDirtyRange records that [offset, offset+length) was modified through the handle
+func addDirtyRange(handle *handlemap.Handle, offset int64, length int64) {
+	if d := getDirtyRanges(handle); d != nil {
+		d.add(offset, offset+length)
+	}
+}
+
+// uploadFile writes the local copy to storage, only uploading the modified blocks when possible
+func (fc *FileCache) uploadFile(handle *handlemap.Handle, f *os.File) error {
+	d := getDirtyRanges(handle)
+	if d != nil {
+		err := fc.uploadDiff(handle.Path, d, f)
+		if err == nil {
+			d.reset()
+			return nil
+		}
+		log.Debug("FileCache::uploadFile : uploading entire %s [%s]", handle.Path, err.Error())
+	}
+
+	err := fc.NextComponent().CopyFromFile(internal.CopyFromFileOptions{
+		Name:     handle.Path,
+		File:     f,
+		Metadata: fc.existingMetadata(handle.Path),
+	})
+	if err == nil && d != nil {
+		d.reset()
+	}
+	return err
+}
+
+// existingMetadata : Metadata of the blob to carry over when its content is uploaded again, nil if the blob does not exist.
+// Upload replaces the metadata as a whole, so without it the xattrs of the file would be lost.
+func (fc *FileCache) existingMetadata(name string) map[string]*string {
+	attr, err := fc.NextComponent().GetAttr(internal.GetAttrOptions{Name: name})
+	if err != nil || attr == nil {
+		return nil
+	}
+	return attr.Metadata
+}
+
+// uploadDiff stages the blocks touched by the dirty ranges and commits them along with the untouched committed blocks.
+// Error means the blob cannot be patched and has to be uploaded in full.
+func (fc *FileCache) uploadDiff(name string, d *dirtyRanges, f *os.File) error {
+	d.Lock()
+	defer d.Unlock()
+
+	if d.full {
+		return errors.New("file is rewritten")
+	}
+
+	// Committed blocks are only valid for the local copy if nobody changed the blob since it was downloaded
+	etag, found := fc.cachedETags.Load(name)
+	if !found {
+		return errors.New("ETag of local copy unknown")
+	}
+
+	attr, err := fc.NextComponent().GetAttr(internal.GetAttrOptions{Name: name})
+	if err != nil {
+		return err
+	}
+	if attr.ETag != etag.(string) {
+		return fmt.Errorf("blob changed in container [%s : %s]", attr.ETag, etag.(string))
+	}
+
+	blockList, err := fc.NextComponent().GetCommittedBlockList(name)
+	if err != nil {
+		return err
+	}
+	if blockList == nil || len(*blockList) == 0 {
+		return errors.New("blob has no committed blocks")
+	}
+
+	// Only a list of equal sized blocks, except the last one, can be patched in place
+	blocks := *blockList
+	blockSize := int64(blocks[0].Size)
+	if blockSize <= 0 {
+		return errors.New("blob starts with an empty block")
+	}
+	for i, block := range blocks {
+		if block.Offset != int64(i)*blockSize || (i < len(blocks)-1 && int64(block.Size) != blockSize) {
+			return errors.New("blob has blocks of different sizes")
+		}
+	}
+	last := blocks[len(blocks)-1]
+	if last.Offset+int64(last.Size) != attr.Size {
+		return errors.New("block list does not cover the blob")
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	newSize := info.Size()
+
+	idLength := common.GetIdLength(blocks[0].Id)
+	if idLength == 0 {
+		idLength = common.BlockIDLength
+	}
+
+	count := (newSize + blockSize - 1) / blockSize
+	ids := make([]string, 0, count)
+	staged := 0
+	for i := range count {
+		offset := i * blockSize
+		size := min(blockSize, newSize-offset)
+
+		if i < int64(len(blocks)) && int64(blocks[i].Size) == size && !d.overlaps(offset, offset+size) {
+			ids = append(ids, blocks[i].Id)
+			continue
+		}
+
+		data := make([]byte, size)
+		_, err = f.ReadAt(data, offset)
+		if err != nil {
+			return err
+		}
+
+		id := common.GetBlockID(idLength)
+		err = fc.NextComponent().StageData(internal.StageDataOptions{Name: name, Id: id, Data: data, Offset: uint64(offset)})
+		if err != nil {
+			return err
+		}
+		ids = append(ids, id)
+		staged++
+	}
+
+	// Committing a block list replaces the metadata of the blob, so carry over the existing one
+	err = fc.NextComponent().CommitData(internal.CommitDataOptions{Name: name, List: ids, BlockSize: uint64(blockSize), Metadata: attr.Metadata})
+	if err != nil {
+		return err
+	}
+
+	log.Info("FileCache::uploadDiff : %s uploaded %d of %d blocks", name, staged, len(ids))
+	return nil
+}
@@ -54,6 +54,7 @@ import (
 	"github.com/Azure/azure-storage-fuse/v2/internal/stats_manager"
 
 	"github.com/spf13/cobra"
+	"golang.org/x/sys/unix"
 )
 
 // Common structure for Component
@@ -69,6 +70,7 @@ type FileCache struct {
 	cacheTimeout    float64
 	policyTrace     bool
 	missedChmodList sync.Map
+	missedXattrList sync.Map
 	mountPath       string
 	allowOther      bool
 	offloadIO       bool
@@ -84,6 +86,15 @@ type FileCache struct {
 
 	lazyWrite    bool
 	fileCloseOpt sync.WaitGroup
+
+	persistCache bool
+	cachedETags  sync.Map
+
+	journal         *writeJournal
+	journalRecovery string
+
+	uploadQueue *uploadQueue
+	diffUpload  bool
 }
 
 // Structure defining your config parameters
@@ -114,6 +125,17 @@ type FileCacheOptions struct {
 
 	RefreshSec uint32 `config:"refresh-sec" yaml:"refresh-sec,omitempty"`
 	HardLimit  bool   `config:"hard-limit" yaml:"hard-limit,omitempty"`
+
+	PersistCache bool `config:"persist-cache" yaml:"persist-cache,omitempty"`
+
+	WriteJournal    bool   `config:"write-journal" yaml:"write-journal,omitempty"`
+	JournalRecovery string `config:"journal-recovery" yaml:"journal-recovery,omitempty"`
+
+	AsyncUpload   bool   `config:"async-upload" yaml:"async-upload,omitempty"`
+	UploadWorkers uint32 `config:"upload-workers" yaml:"upload-workers,omitempty"`
+	UploadRetries uint32 `config:"upload-retries" yaml:"upload-retries,omitempty"`
+
+	DiffUpload bool `config:"differential-upload" yaml:"differential-upload,omitempty"`
 }
 
 const (
@@ -165,6 +187,19 @@ func (fc *FileCache) Start(ctx context.Context) error {
 	// create stats collector for file cache
 	fileCacheStatsCollector = stats_manager.NewStatsCollector(fc.Name())
 
+	// Dirty files of a crashed mount are dealt with before adoption, which removes files the index does not know
+	if fc.journal != nil {
+		fc.recoverJournal()
+	}
+
+	if fc.persistCache {
+		fc.adoptCachedFiles()
+	}
+
+	if fc.uploadQueue != nil {
+		fc.uploadQueue.start()
+	}
+
 	return nil
 }
 
@@ -173,13 +208,26 @@ func (fc *FileCache) Stop() error {
 	log.Trace("Stopping component : %s", fc.Name())
 
 	// Wait for all async upload to complete if any
-	if fc.lazyWrite {
+	if fc.lazyWrite || fc.uploadQueue != nil {
 		log.Info("FileCache::Stop : Waiting for async close to complete")
 		fc.fileCloseOpt.Wait()
 	}
 
+	if fc.uploadQueue != nil {
+		fc.uploadQueue.stop()
+	}
+
 	_ = fc.policy.ShutdownPolicy()
-	_ = common.TempCacheCleanup(fc.tmpPath)
+
+	if fc.persistCache {
+		err := fc.saveCacheIndex()
+		if err != nil {
+			log.Err("FileCache::Stop : failed to save cache index, cleaning up cache [%s]", err.Error())
+			fc.cleanupCache()
+		}
+	} else {
+		fc.cleanupCache()
+	}
 
 	fileCacheStatsCollector.Destroy()
 
@@ -251,6 +299,8 @@ func (fc *FileCache) Configure(_ bool) error {
 	fc.syncToDelete = !conf.SyncNoOp
 	fc.refreshSec = conf.RefreshSec
 	fc.hardLimit = conf.HardLimit
+	fc.persistCache = conf.PersistCache
+	fc.diffUpload = conf.DiffUpload
 
 	err = config.UnmarshalKey("lazy-write", &fc.lazyWrite)
 	if err != nil {
@@ -303,7 +353,8 @@ func (fc *FileCache) Configure(_ bool) error {
 		return fmt.Errorf("config error in %s error [max-size-mb: %f must be greater than 0]", fc.Name(), fc.maxCacheSizeMB)
 	}
 
-	if !isLocalDirEmpty(fc.tmpPath) && !fc.allowNonEmpty {
+	// A persistent cache is expected to find the files of the previous mount in its directory
+	if !isLocalDirEmpty(fc.tmpPath) && !fc.allowNonEmpty && !fc.persistCache && !conf.WriteJournal {
 		log.Err("FileCache: config error %s directory is not empty", fc.tmpPath)
 		return fmt.Errorf("config error in %s [%s]", fc.Name(), "temp directory not empty")
 	}
@@ -320,12 +371,48 @@ func (fc *FileCache) Configure(_ bool) error {
 		fc.defaultPermission = common.DefaultFilePermissionBits
 	}
 
-	cacheConfig := fc.GetPolicyConfig(conf)
-	fc.policy = NewLRUPolicy(cacheConfig)
+	if conf.WriteJournal {
+		fc.journalRecovery = journalRecoveryUpload
+		if conf.JournalRecovery != "" {
+			fc.journalRecovery = conf.JournalRecovery
+		}
+		if fc.journalRecovery != journalRecoveryUpload && fc.journalRecovery != journalRecoveryQuarantine {
+			log.Err("FileCache: config error [invalid journal-recovery %s]", fc.journalRecovery)
+			return fmt.Errorf("config error in %s [invalid journal-recovery %s]", fc.Name(), fc.journalRecovery)
+		}
 
-	if fc.policy == nil {
-		log.Err("FileCache::Configure : failed to create cache eviction policy")
-		return fmt.Errorf("config error in %s [%s]", fc.Name(), "failed to create cache policy")
+		fc.journal, err = newWriteJournal(fc.tmpPath)
+		if err != nil {
+			log.Err("FileCache: config error [failed to create write journal %s]", err.Error())
+			return fmt.Errorf("config error in %s [%s]", fc.Name(), err.Error())
+		}
+	}
+
+	if conf.AsyncUpload {
+		// Changes of a handle whose upload is given up are recovered only from the journal
+		if !conf.WriteJournal {
+			log.Err("FileCache: config error [async-upload requires write-journal]")
+			return fmt.Errorf("config error in %s [async-upload requires write-journal]", fc.Name())
+		}
+
+		workers := uint32(defaultUploadWorkers)
+		if conf.UploadWorkers > 0 {
+			workers = conf.UploadWorkers
+		}
+
+		retries := uint32(defaultUploadRetries)
+		if config.IsSet(compName + ".upload-retries") {
+			retries = conf.UploadRetries
+		}
+
+		fc.uploadQueue = newUploadQueue(workers, retries, fc.uploadQueued, fc.releaseQueued, fc.reopenQueued, fc.dropQueued)
+	}
+
+	cacheConfig := fc.GetPolicyConfig(conf)
+	fc.policy, err = NewCachePolicy(conf.Policy, cacheConfig)
+	if err != nil {
+		log.Err("FileCache::Configure : failed to create cache eviction policy [%s]", err.Error())
+		return fmt.Errorf("config error in %s [%s]", fc.Name(), err.Error())
 	}
 
 	if config.IsSet(compName + ".background-download") {
@@ -349,11 +436,11 @@ func (fc *FileCache) Configure(_ bool) error {
 	log.Crit("FileCache::Configure : create-empty %t, cache-timeout %d, tmp-path %s, max-size-mb %d, high-mark %d, "+
 		"low-mark %d, refresh-sec %v, max-eviction %v, hard-limit %v, policy %s, allow-non-empty-temp %t, "+
 		"cleanup-on-start %t, policy-trace %t, offload-io %t, sync-to-flush %t, ignore-sync %t, defaultPermission %v, "+
-		"diskHighWaterMark %v, maxCacheSize %v, lazy-write %v, mountPath %v",
+		"diskHighWaterMark %v, maxCacheSize %v, lazy-write %v, mountPath %v, persist-cache %v, write-journal %v, journal-recovery %s, async-upload %v, differential-upload %v",
 		fc.createEmptyFile, int(fc.cacheTimeout), fc.tmpPath, int(fc.maxCacheSizeMB), int(cacheConfig.highThreshold),
 		int(cacheConfig.lowThreshold), fc.refreshSec, cacheConfig.maxEviction, fc.hardLimit, conf.Policy, fc.allowNonEmpty,
 		conf.CleanupOnStart, fc.policyTrace, fc.offloadIO, fc.syncToFlush, fc.syncToDelete, fc.defaultPermission,
-		fc.diskHighWaterMark, fc.maxCacheSizeMB, fc.lazyWrite, fc.mountPath)
+		fc.diskHighWaterMark, fc.maxCacheSizeMB, fc.lazyWrite, fc.mountPath, fc.persistCache, conf.WriteJournal, fc.journalRecovery, conf.AsyncUpload, fc.diffUpload)
 
 	return nil
 }
@@ -661,7 +748,10 @@ func (fc *FileCache) deleteEmptyDirs(options internal.DeleteDirOptions) (bool, e
 	}
 
 	for _, entry := range entries {
-		if entry.IsDir() {
+		if entry.IsDir() && isJournalDir(fc.tmpPath, filepath.Join(localPath, entry.Name())) {
+			// Journal is not part of the namespace and must survive
+			continue
+		} else if entry.IsDir() {
 			val, err := fc.deleteEmptyDirs(internal.DeleteDirOptions{
 				Name: filepath.Join(localPath, entry.Name()),
 			})
@@ -703,7 +793,7 @@ func (fc *FileCache) RenameDir(options internal.RenameDirOptions) error {
 
 // CreateFile: Create the file in local cache.
 func (fc *FileCache) CreateFile(options internal.CreateFileOptions) (*handlemap.Handle, error) {
-	//defer exectime.StatTimeCurrentBlock("FileCache::CreateFile")()
+	defer fileCacheStatsCollector.TimeOperation(createFile)()
 	log.Trace("FileCache::CreateFile : name=%s, mode=%d", options.Name, options.Mode)
 
 	flock := fc.fileLocks.Get(options.Name)
@@ -748,6 +838,7 @@ func (fc *FileCache) CreateFile(options internal.CreateFileOptions) (*handlemap.
 
 	handle := handlemap.NewHandle(options.Name)
 	handle.UnixFD = uint64(f.Fd())
+	fc.trackDirtyRanges(handle, true)
 
 	if !fc.offloadIO {
 		handle.Flags.Set(handlemap.HandleFlagCached)
@@ -758,7 +849,7 @@ func (fc *FileCache) CreateFile(options internal.CreateFileOptions) (*handlemap.
 
 	// If an empty file is created in storage then there is no need to upload if FlushFile is called immediately after CreateFile.
 	if !fc.createEmptyFile {
-		handle.Flags.Set(handlemap.HandleFlagDirty)
+		fc.markDirty(handle, true)
 	}
 
 	return handle, nil
@@ -822,6 +913,11 @@ func (fc *FileCache) DeleteFile(options internal.DeleteFileOptions) error {
 	}
 
 	fc.policy.CachePurge(localPath)
+	fc.missedXattrList.Delete(options.Name)
+	fc.forgetETag(options.Name)
+	if fc.journal != nil {
+		fc.journal.drop(options.Name)
+	}
 
 	return nil
 }
@@ -911,6 +1007,7 @@ func (fc *FileCache) isDownloadRequired(localPath string, blobPath string, flock
 func (fc *FileCache) OpenFile(options internal.OpenFileOptions) (*handlemap.Handle, error) {
 	log.Trace("FileCache::OpenFile : name=%s, flags=%s, mode=%s",
 		options.Name, common.PrettyOpenFlags(options.Flags), options.Mode)
+	defer fileCacheStatsCollector.TimeOperation(openFile)()
 
 	localPath := filepath.Join(fc.tmpPath, options.Name)
 	var f *os.File
@@ -1003,6 +1100,7 @@ func (fc *FileCache) OpenFile(options internal.OpenFileOptions) (*handlemap.Hand
 
 		// Update the last download time of this file
 		flock.SetDownloadTime()
+		fc.recordETag(options.Name, attr)
 
 		log.Debug("FileCache::OpenFile : Download of %s is complete", options.Name)
 		f.Close()
@@ -1029,9 +1127,11 @@ func (fc *FileCache) OpenFile(options internal.OpenFileOptions) (*handlemap.Hand
 		}
 
 		fileCacheStatsCollector.UpdateStats(stats_manager.Increment, dlFiles, (int64)(1))
+		fileCacheStatsCollector.CacheLookup(false)
 	} else {
 		log.Debug("FileCache::OpenFile : %s will be served from cache", options.Name)
 		fileCacheStatsCollector.UpdateStats(stats_manager.Increment, cacheServed, (int64)(1))
+		fileCacheStatsCollector.CacheLookup(true)
 	}
 
 	// Open the file and grab a shared lock to prevent deletion by the cache policy.
@@ -1045,8 +1145,9 @@ func (fc *FileCache) OpenFile(options internal.OpenFileOptions) (*handlemap.Hand
 	flock.Inc()
 
 	handle := handlemap.NewHandle(options.Name)
+	fc.trackDirtyRanges(handle, options.Flags&os.O_TRUNC != 0)
 	if options.Flags&os.O_TRUNC != 0 {
-		handle.Flags.Set(handlemap.HandleFlagDirty)
+		fc.markDirty(handle, false)
 	}
 	inf, err := f.Stat()
 	if err == nil {
@@ -1073,12 +1174,28 @@ func (fc *FileCache) ReleaseFile(options internal.ReleaseFileOptions) error {
 	// Async close is called so schedule the upload and return here
 	fc.fileCloseOpt.Add(1)
 
+	if fc.uploadQueue != nil && options.Handle.Dirty() {
+		// Workers upload and close the handle, file stays locked till then
+		fc.beginAsyncRelease(options.Handle)
+		if fc.uploadQueue.schedule(&uploadItem{options: options, flock: flock}) {
+			return nil
+		}
+
+		// Queue is stopped as the file system is being unmounted, upload right away
+		defer fc.endAsyncRelease(options.Handle)
+		return fc.releaseFileInternal(options, flock)
+	}
+
 	if !fc.lazyWrite {
 		// Sync close is called so wait till the upload completes
 		return fc.releaseFileInternal(options, flock)
 	}
 
-	go fc.releaseFileInternal(options, flock) //nolint
+	fc.beginAsyncRelease(options.Handle)
+	go func() {
+		_ = fc.releaseFileInternal(options, flock)
+		fc.endAsyncRelease(options.Handle)
+	}()
 	return nil
 }
 
@@ -1091,35 +1208,44 @@ func (fc *FileCache) releaseFileInternal(options internal.ReleaseFileOptions, fl
 	defer flock.Unlock()
 	defer fc.fileCloseOpt.Done()
 
-	localPath := filepath.Join(fc.tmpPath, options.Handle.Path)
-
 	err := fc.FlushFile(internal.FlushFileOptions{Handle: options.Handle, CloseInProgress: true}) //nolint
 	if err != nil {
 		log.Err("FileCache::releaseFileInternal : failed to flush file %s", options.Handle.Path)
-		return err
 	}
 
+	return fc.closeReleased(options, flock, err)
+}
+
+// closeReleased: Close the local file of a released handle, uploadErr tells why its changes could not be uploaded
+func (fc *FileCache) closeReleased(options internal.ReleaseFileOptions, flock *common.LockMapItem, uploadErr error) error {
+	localPath := filepath.Join(fc.tmpPath, options.Handle.Path)
+
 	f := options.Handle.GetFileObject()
 	if f == nil {
-		log.Err("FileCache::releaseFileInternal : error [missing fd in handle object] %s", options.Handle.Path)
+		log.Err("FileCache::closeReleased : error [missing fd in handle object] %s", options.Handle.Path)
 		return syscall.EBADF
 	}
 
-	err = f.Close()
+	err := f.Close()
+	flock.Dec()
 	if err != nil {
-		log.Err("FileCache::releaseFileInternal : error closing file %s(%d) [%s]", options.Handle.Path, int(f.Fd()), err.Error())
+		log.Err("FileCache::closeReleased : error closing file %s(%d) [%s]", options.Handle.Path, int(f.Fd()), err.Error())
 		return err
 	}
-	flock.Dec()
+
+	if uploadErr != nil {
+		// Local copy holds changes which are not in storage, keep it cached while the journal tracks them
+		fc.policy.CacheValid(localPath)
+		return uploadErr
+	}
 
 	// If it is an fsync op then purge the file
 	if options.Handle.Fsynced() {
-		log.Trace("FileCache::releaseFileInternal : fsync/sync op, purging %s", options.Handle.Path)
-		localPath := filepath.Join(fc.tmpPath, options.Handle.Path)
+		log.Trace("FileCache::closeReleased : fsync/sync op, purging %s", options.Handle.Path)
 
 		err = deleteFile(localPath)
 		if err != nil && !os.IsNotExist(err) {
-			log.Err("FileCache::releaseFileInternal : failed to delete local file %s [%s]", localPath, err.Error())
+			log.Err("FileCache::closeReleased : failed to delete local file %s [%s]", localPath, err.Error())
 		}
 
 		fc.policy.CachePurge(localPath)
@@ -1161,7 +1287,7 @@ func (fc *FileCache) ReadFile(options internal.ReadFileOptions) ([]byte, error)
 
 // ReadInBuffer: Read the local file into a buffer
 func (fc *FileCache) ReadInBuffer(options *internal.ReadInBufferOptions) (int, error) {
-	//defer exectime.StatTimeCurrentBlock("FileCache::ReadInBuffer")()
+	defer fileCacheStatsCollector.TimeOperation(readInBuffer)()
 	// The file should already be in the cache since CreateFile/OpenFile was called before and a shared lock was acquired.
 	// log.Debug("FileCache::ReadInBuffer : Reading %v bytes from %s", len(options.Data), options.Handle.Path)
 
@@ -1181,12 +1307,15 @@ func (fc *FileCache) ReadInBuffer(options *internal.ReadInBufferOptions) (int, e
 
 	// Removing f.ReadAt as it involves lot of house keeping and then calls syscall.Pread
 	// Instead we will call syscall directly for better perf
-	return syscall.Pread(options.Handle.FD(), options.Data, options.Offset)
+	bytesRead, err := syscall.Pread(options.Handle.FD(), options.Data, options.Offset)
+	fileCacheStatsCollector.AddBytes(stats_manager.BytesRead, int64(bytesRead))
+
+	return bytesRead, err
 }
 
 // WriteFile: Write to the local file
 func (fc *FileCache) WriteFile(options *internal.WriteFileOptions) (int, error) {
-	//defer exectime.StatTimeCurrentBlock("FileCache::WriteFile")()
+	defer fileCacheStatsCollector.TimeOperation(writeFile)()
 	// The file should already be in the cache since CreateFile/OpenFile was called before and a shared lock was acquired.
 	//log.Debug("FileCache::WriteFile : Writing %v bytes from %s", len(options.Data), options.Handle.Path)
 
@@ -1222,7 +1351,9 @@ func (fc *FileCache) WriteFile(options *internal.WriteFileOptions) (int, error)
 
 	if err == nil {
 		// Mark the handle dirty so the file is written back to storage on FlushFile.
-		options.Handle.Flags.Set(handlemap.HandleFlagDirty)
+		fc.markDirty(options.Handle, false)
+		addDirtyRange(options.Handle, options.Offset, int64(bytesWritten))
+		fileCacheStatsCollector.AddBytes(stats_manager.BytesWritten, int64(bytesWritten))
 	} else {
 		log.Err("FileCache::WriteFile : failed to write %s [%s]", options.Handle.Path, err.Error())
 	}
@@ -1230,9 +1361,146 @@ func (fc *FileCache) WriteFile(options *internal.WriteFileOptions) (int, error)
 	return bytesWritten, err
 }
 
+// Fallocate: Allocate space or punch a hole in the local copy, storage gets updated when the file is flushed
+func (fc *FileCache) Fallocate(options internal.FallocateOptions) error {
+	log.Trace("FileCache::Fallocate : %s mode %d, offset %d, length %d", options.Name, options.Mode, options.Offset, options.Length)
+
+	if options.Handle == nil || options.Handle.GetFileObject() == nil {
+		log.Err("FileCache::Fallocate : error [couldn't find fd in handle] %s", options.Name)
+		return syscall.EBADF
+	}
+
+	if fc.diskHighWaterMark != 0 && options.Mode&internal.FallocPunchHole == 0 {
+		currSize, err := common.GetUsage(fc.tmpPath)
+		if err != nil {
+			log.Err("FileCache::Fallocate : error getting current usage of cache [%s]", err.Error())
+		} else if (currSize + float64(options.Length)) > fc.diskHighWaterMark {
+			log.Err("FileCache::Fallocate : cache size limit reached [%f] failed to allocate %s", fc.maxCacheSizeMB, options.Handle.Path)
+			return syscall.ENOSPC
+		}
+	}
+
+	err := syscall.Fallocate(options.Handle.FD(), options.Mode, options.Offset, options.Length)
+	if err != nil {
+		log.Err("FileCache::Fallocate : failed to allocate %s [%s]", options.Handle.Path, err.Error())
+		return err
+	}
+
+	if options.Mode != internal.FallocKeepSize {
+		// Size or content of the file changed so it needs to be written back to storage on FlushFile.
+		fc.markDirty(options.Handle, false)
+		addDirtyRange(options.Handle, options.Offset, options.Length)
+	}
+
+	return nil
+}
+
+// Lseek: Find data or hole in the local copy which holds the latest content of the file
+func (fc *FileCache) Lseek(options internal.LseekOptions) (int64, error) {
+	log.Trace("FileCache::Lseek : %s offset %d, whence %d", options.Name, options.Offset, options.Whence)
+
+	if options.Handle == nil || options.Handle.GetFileObject() == nil {
+		log.Err("FileCache::Lseek : error [couldn't find fd in handle] %s", options.Name)
+		return 0, syscall.EBADF
+	}
+
+	// Reads and writes go through pread/pwrite so moving the offset of fd has no side effect
+	offset, err := syscall.Seek(options.Handle.FD(), options.Offset, options.Whence)
+	if err != nil && err != syscall.ENXIO {
+		log.Err("FileCache::Lseek : failed to seek %s [%s]", options.Handle.Path, err.Error())
+	}
+
+	return offset, err
+}
+
+// CopyFileRange: Copy the range within the local copies and storage so destination needs no upload
+func (fc *FileCache) CopyFileRange(options internal.CopyFileRangeOptions) (int64, error) {
+	log.Trace("FileCache::CopyFileRange : %s [%d] -> %s [%d], length %d", options.SrcName, options.SrcOffset,
+		options.DstName, options.DstOffset, options.Length)
+
+	if options.SrcHandle == nil || options.DstHandle == nil ||
+		options.SrcHandle.GetFileObject() == nil || options.DstHandle.GetFileObject() == nil {
+		log.Err("FileCache::CopyFileRange : error [couldn't find fd in handle] %s -> %s", options.SrcName, options.DstName)
+		return 0, syscall.EBADF
+	}
+
+	if options.SrcHandle.Dirty() {
+		// Storage does not have the latest data of source so let the kernel copy it through read and write
+		log.Info("FileCache::CopyFileRange : %s has local changes, falling back to read and write", options.SrcName)
+		return 0, syscall.ENOTSUP
+	}
+
+	if fc.diskHighWaterMark != 0 {
+		currSize, err := common.GetUsage(fc.tmpPath)
+		if err != nil {
+			log.Err("FileCache::CopyFileRange : error getting current usage of cache [%s]", err.Error())
+		} else if (currSize + float64(options.Length)) > fc.diskHighWaterMark {
+			log.Err("FileCache::CopyFileRange : cache size limit reached [%f] failed to copy to %s", fc.maxCacheSizeMB, options.DstName)
+			return 0, syscall.ENOSPC
+		}
+	}
+
+	if options.DstHandle.Dirty() {
+		// Copy is applied over the blob in storage so it needs the local changes of destination first
+		err := fc.FlushFile(internal.FlushFileOptions{Handle: options.DstHandle, CloseInProgress: true}) //nolint
+		if err != nil {
+			log.Err("FileCache::CopyFileRange : failed to flush %s [%s]", options.DstName, err.Error())
+			return 0, err
+		}
+	}
+
+	// Local copy of source is complete and matches storage so only the part within it can be copied
+	info, err := options.SrcHandle.GetFileObject().Stat()
+	if err != nil {
+		log.Err("FileCache::CopyFileRange : failed to stat local copy of %s [%s]", options.SrcName, err.Error())
+		return 0, syscall.EIO
+	}
+	length := max(0, min(options.Length, info.Size()-options.SrcOffset))
+	if length == 0 {
+		return 0, nil
+	}
+
+	// Copy is applied to the local copy of destination first, so whatever happens in storage later
+	// the local copy holds the result and an upload on flush can bring storage back in sync
+	err = copyLocalRange(options.SrcHandle.FD(), options.SrcOffset, options.DstHandle.FD(), options.DstOffset, length)
+	if err != nil {
+		// Part of the range may already be written to the local copy
+		log.Err("FileCache::CopyFileRange : failed to copy %s to local copy of %s [%s]", options.SrcName, options.DstName, err.Error())
+		fc.markDirty(options.DstHandle, false)
+		addDirtyRange(options.DstHandle, options.DstOffset, length)
+		return 0, syscall.EIO
+	}
+
+	options.Length = length
+	copied, err := fc.NextComponent().CopyFileRange(options)
+	if err != nil || copied != length {
+		log.Info("FileCache::CopyFileRange : copy in storage to %s incomplete, uploading on flush instead [%v]", options.DstName, err)
+		fc.markDirty(options.DstHandle, false)
+		addDirtyRange(options.DstHandle, options.DstOffset, length)
+	}
+
+	return length, nil
+}
+
+// copyLocalRange: Copy a range between two local files without moving data to user space
+func copyLocalRange(srcFd int, srcOffset int64, dstFd int, dstOffset int64, length int64) error {
+	for length > 0 {
+		n, err := unix.CopyFileRange(srcFd, &srcOffset, dstFd, &dstOffset, int(min(length, math.MaxInt32)), 0)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return io.ErrUnexpectedEOF
+		}
+		length -= int64(n)
+	}
+	return nil
+}
+
 func (fc *FileCache) SyncFile(options internal.SyncFileOptions) error {
 	log.Trace("FileCache::SyncFile : handle=%d, path=%s", options.Handle.ID, options.Handle.Path)
-	if fc.syncToFlush {
+	// With async upload close does not wait for the upload, so fsync is how an application makes its data durable
+	if fc.syncToFlush || fc.uploadQueue != nil {
 		err := fc.FlushFile(internal.FlushFileOptions{Handle: options.Handle, CloseInProgress: true}) //nolint
 		if err != nil {
 			log.Err("FileCache::SyncFile : failed to flush file %s", options.Handle.Path)
@@ -1269,7 +1537,7 @@ func (fc *FileCache) SyncFile(options internal.SyncFileOptions) error {
 
 // FlushFile: Flush the local file to storage
 func (fc *FileCache) FlushFile(options internal.FlushFileOptions) error {
-	//defer exectime.StatTimeCurrentBlock("FileCache::FlushFile")()
+	defer fileCacheStatsCollector.TimeOperation(flushFile)()
 	log.Trace("FileCache::FlushFile : handle=%d, path=%s", options.Handle.ID, options.Handle.Path)
 
 	// The file should already be in the cache since CreateFile/OpenFile was called before and a shared lock was acquired.
@@ -1277,8 +1545,8 @@ func (fc *FileCache) FlushFile(options internal.FlushFileOptions) error {
 	fc.policy.CacheValid(localPath)
 	// if our handle is dirty then that means we wrote to the file
 	if options.Handle.Dirty() {
-		if fc.lazyWrite && !options.CloseInProgress {
-			// As lazy-write is enable, upload will be scheduled when file is closed.
+		if (fc.lazyWrite || fc.uploadQueue != nil) && !options.CloseInProgress {
+			// As lazy-write or async-upload is enabled, upload will be scheduled when file is closed.
 			log.Info("FileCache::FlushFile : %s will be flushed when handle %d is closed", options.Handle.Path, options.Handle.ID)
 			return nil
 		}
@@ -1330,12 +1598,7 @@ func (fc *FileCache) FlushFile(options internal.FlushFileOptions) error {
 				return err
 			}
 		}
-		err = fc.NextComponent().CopyFromFile(
-			internal.CopyFromFileOptions{
-				Name: options.Handle.Path,
-				File: uploadHandle,
-			})
-
+		err = fc.uploadFile(options.Handle, uploadHandle)
 		uploadHandle.Close()
 
 		if modeChanged {
@@ -1351,6 +1614,19 @@ func (fc *FileCache) FlushFile(options internal.FlushFileOptions) error {
 		}
 
 		options.Handle.Flags.Clear(handlemap.HandleFlagDirty)
+		if fc.journal != nil {
+			fc.journal.done(options.Handle)
+		}
+
+		if fc.trackETags() {
+			// Upload changed the ETag of the blob, remember the new one so the file can be adopted on remount
+			// and later changes are journaled against it
+			fc.forgetETag(options.Handle.Path)
+			attr, err := fc.NextComponent().GetAttr(internal.GetAttrOptions{Name: options.Handle.Path})
+			if err == nil {
+				fc.recordETag(options.Handle.Path, attr)
+			}
+		}
 
 		// If chmod was done on the file before it was uploaded to container then setting up mode would have been missed
 		// Such file names are added to this map and here post upload we try to set the mode correctly
@@ -1373,6 +1649,17 @@ func (fc *FileCache) FlushFile(options internal.FlushFileOptions) error {
 				}
 			}
 		}
+
+		// Extended attributes set before the file was uploaded to container are applied now
+		pending, found := fc.missedXattrList.LoadAndDelete(options.Handle.Path)
+		if found {
+			for name, value := range pending.(*pendingXattrs).snapshot() {
+				err = fc.NextComponent().SetXattr(internal.SetXattrOptions{Name: options.Handle.Path, Attr: name, Value: value})
+				if err != nil {
+					log.Err("FileCache::FlushFile : %s failed to set xattr %s [%s]", options.Handle.Path, name, err.Error())
+				}
+			}
+		}
 	}
 
 	return nil
@@ -1449,6 +1736,8 @@ func (fc *FileCache) RenameFile(options internal.RenameFileOptions) error {
 	defer dflock.Unlock()
 
 	err := fc.NextComponent().RenameFile(options)
+	fc.forgetETag(options.Src)
+	fc.forgetETag(options.Dst)
 	err = fc.validateStorageError(options.Src, err, "RenameFile", false)
 	if err != nil {
 		log.Err("FileCache::RenameFile : %s failed to rename file [%s]", options.Src, err.Error())
@@ -1497,6 +1786,45 @@ func (fc *FileCache) RenameFile(options internal.RenameFileOptions) error {
 	return nil
 }
 
+// CreateHardLink: Create a hard link in storage. A target which is not yet uploaded to container is uploaded first.
+func (fc *FileCache) CreateHardLink(options internal.CreateHardLinkOptions) error {
+	log.Trace("FileCache::CreateHardLink : name=%s, target=%s", options.Name, options.Target)
+
+	tflock := fc.fileLocks.Get(options.Target)
+	tflock.Lock()
+	defer tflock.Unlock()
+
+	nflock := fc.fileLocks.Get(options.Name)
+	nflock.Lock()
+	defer nflock.Unlock()
+
+	err := fc.NextComponent().CreateHardLink(options)
+	if err != nil && fc.isLocalOnly(options.Target, err) {
+		localPath := filepath.Join(fc.tmpPath, options.Target)
+		log.Info("FileCache::CreateHardLink : %s is not yet uploaded, uploading it from %s", options.Target, localPath)
+
+		var uploadHandle *os.File
+		uploadHandle, err = os.Open(localPath)
+		if err != nil {
+			log.Err("FileCache::CreateHardLink : error [unable to open upload handle] %s [%s]", options.Target, err.Error())
+			return err
+		}
+
+		err = fc.NextComponent().CopyFromFile(internal.CopyFromFileOptions{Name: options.Target, File: uploadHandle})
+		uploadHandle.Close()
+		if err == nil {
+			err = fc.NextComponent().CreateHardLink(options)
+		}
+	}
+
+	if err != nil {
+		log.Err("FileCache::CreateHardLink : %s failed to link to %s [%s]", options.Name, options.Target, err.Error())
+		return err
+	}
+
+	return nil
+}
+
 // TruncateFile: Update the file with its new size.
 func (fc *FileCache) TruncateFile(options internal.TruncateFileOptions) error {
 	log.Trace("FileCache::TruncateFile : name=%s, size=%d", options.Name, options.NewSize)
@@ -1528,7 +1856,9 @@ func (fc *FileCache) TruncateFile(options internal.TruncateFileOptions) error {
 			return err
 		}
 
-		options.Handle.Flags.Set(handlemap.HandleFlagDirty)
+		fc.markDirty(options.Handle, false)
+		// Everything from the new end changes, including data which shows up as zeros if the file grows again
+		addDirtyRange(options.Handle, options.NewSize, math.MaxInt64-options.NewSize)
 
 		return nil
 	}
@@ -1538,6 +1868,7 @@ func (fc *FileCache) TruncateFile(options internal.TruncateFileOptions) error {
 	defer flock.Unlock()
 
 	err := fc.NextComponent().TruncateFile(options)
+	fc.forgetETag(options.Name)
 	err = fc.validateStorageError(options.Name, err, "TruncateFile", true)
 	if err != nil {
 		log.Err("FileCache::TruncateFile : %s failed to truncate [%s]", options.Name, err.Error())
@@ -1624,6 +1955,123 @@ func (fc *FileCache) Chown(options internal.ChownOptions) error {
 	return nil
 }
 
+// pendingXattrs : Extended attributes set on a file which is not yet uploaded to container
+type pendingXattrs struct {
+	sync.Mutex
+	attrs map[string][]byte
+}
+
+func (p *pendingXattrs) snapshot() map[string][]byte {
+	p.Lock()
+	defer p.Unlock()
+
+	attrs := make(map[string][]byte, len(p.attrs))
+	for name, value := range p.attrs {
+		attrs[name] = value
+	}
+	return attrs
+}
+
+// isLocalOnly : Check whether a path missing in storage exists in the local cache, i.e. it is not yet uploaded
+func (fc *FileCache) isLocalOnly(name string, err error) bool {
+	if err != syscall.ENOENT && !os.IsNotExist(err) {
+		return false
+	}
+
+	info, err := os.Stat(filepath.Join(fc.tmpPath, name))
+	return err == nil && !info.IsDir()
+}
+
+// GetXattr : Get the extended attribute from storage, or from the pending list if file is not yet uploaded
+func (fc *FileCache) GetXattr(options internal.GetXattrOptions) ([]byte, error) {
+	log.Trace("FileCache::GetXattr : %s of %s", options.Attr, options.Name)
+
+	data, err := fc.NextComponent().GetXattr(options)
+	if err != nil && fc.isLocalOnly(options.Name, err) {
+		pending, found := fc.missedXattrList.Load(options.Name)
+		if found {
+			if value, ok := pending.(*pendingXattrs).snapshot()[options.Attr]; ok {
+				return value, nil
+			}
+		}
+		return nil, syscall.ENODATA
+	}
+
+	return data, err
+}
+
+// SetXattr : Set the extended attribute in storage, if file is not yet uploaded it is applied on flush
+func (fc *FileCache) SetXattr(options internal.SetXattrOptions) error {
+	log.Trace("FileCache::SetXattr : %s of %s", options.Attr, options.Name)
+
+	err := fc.NextComponent().SetXattr(options)
+	if err != nil && fc.isLocalOnly(options.Name, err) {
+		log.Info("FileCache::SetXattr : %s not uploaded yet, %s will be set on flush", options.Name, options.Attr)
+		value, _ := fc.missedXattrList.LoadOrStore(options.Name, &pendingXattrs{attrs: make(map[string][]byte)})
+		pending := value.(*pendingXattrs)
+
+		pending.Lock()
+		defer pending.Unlock()
+
+		_, exists := pending.attrs[options.Attr]
+		if exists && options.Flags&internal.XattrCreate != 0 {
+			return syscall.EEXIST
+		} else if !exists && options.Flags&internal.XattrReplace != 0 {
+			return syscall.ENODATA
+		}
+
+		pending.attrs[options.Attr] = append([]byte{}, options.Value...)
+		return nil
+	}
+
+	if err != nil {
+		log.Err("FileCache::SetXattr : %s failed to set %s [%s]", options.Name, options.Attr, err.Error())
+	}
+	return err
+}
+
+// ListXattr : List the extended attributes from storage, or from the pending list if file is not yet uploaded
+func (fc *FileCache) ListXattr(options internal.ListXattrOptions) ([]string, error) {
+	log.Trace("FileCache::ListXattr : %s", options.Name)
+
+	names, err := fc.NextComponent().ListXattr(options)
+	if err != nil && fc.isLocalOnly(options.Name, err) {
+		names = make([]string, 0)
+		pending, found := fc.missedXattrList.Load(options.Name)
+		if found {
+			for name := range pending.(*pendingXattrs).snapshot() {
+				names = append(names, name)
+			}
+		}
+		return names, nil
+	}
+
+	return names, err
+}
+
+// RemoveXattr : Remove the extended attribute from storage, or from the pending list if file is not yet uploaded
+func (fc *FileCache) RemoveXattr(options internal.RemoveXattrOptions) error {
+	log.Trace("FileCache::RemoveXattr : %s of %s", options.Attr, options.Name)
+
+	err := fc.NextComponent().RemoveXattr(options)
+	if err != nil && fc.isLocalOnly(options.Name, err) {
+		pending, found := fc.missedXattrList.Load(options.Name)
+		if found {
+			p := pending.(*pendingXattrs)
+			p.Lock()
+			defer p.Unlock()
+
+			if _, ok := p.attrs[options.Attr]; ok {
+				delete(p.attrs, options.Attr)
+				return nil
+			}
+		}
+		return syscall.ENODATA
+	}
+
+	return err
+}
+
 func (fc *FileCache) FileUsed(name string) error {
 	// Update the owner and group of the file in the local cache
 	localPath := filepath.Join(fc.tmpPath, name)
@@ -1687,7 +2135,7 @@ func init() {
 	config.BindPFlag(compName+".upload-modified-only", uploadModifiedOnly)
 	uploadModifiedOnly.Hidden = true
 
-	cachePolicy := config.AddStringFlag("file-cache-policy", "lru", "Cache eviction policy.")
+	cachePolicy := config.AddStringFlag("file-cache-policy", "lru", "Cache eviction policy. Allowed values are lru|lfu|2q|gdsf.")
 	config.BindPFlag(compName+".policy", cachePolicy)
 	cachePolicy.Hidden = true
 
@@ -34,8 +34,20 @@
 package file_cache
 
 const (
-	cacheUsage  = "Cache Usage"
-	usgPer      = "Usage Percent"
-	dlFiles     = "Files Downloaded"
-	cacheServed = "Files served from cache"
+	cacheUsage       = "Cache Usage"
+	usgPer           = "Usage Percent"
+	dlFiles          = "Files Downloaded"
+	cacheServed      = "Files served from cache"
+	adoptedFiles     = "Files adopted on mount"
+	recoveredFiles   = "Files recovered from journal"
+	quarantinedFiles = "Files quarantined by journal"
+	uploadQueueDepth = "Upload queue depth"
+	uploadRetries    = "Upload retries"
+	uploadFailures   = "Upload failures"
+
+	createFile   = "CreateFile"
+	openFile     = "OpenFile"
+	readInBuffer = "ReadInBuffer"
+	writeFile    = "WriteFile"
+	flushFile    = "FlushFile"
 )
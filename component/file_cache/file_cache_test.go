@@ -195,6 +195,42 @@ func (suite *fileCacheTestSuite) TestConfig() {
 	suite.assert.Equal(int(suite.fileCache.cacheTimeout), cacheTimeout)
 }
 
+func (suite *fileCacheTestSuite) TestConfigPolicy() {
+	defer suite.cleanupTest()
+	suite.cleanupTest() // teardown the default file cache generated
+	config := fmt.Sprintf("file_cache:\n  path: %s\n  policy: gdsf\n  timeout-sec: 30\n  max-eviction: 10", suite.cache_path)
+	suite.setupTestHelper(config)
+
+	suite.assert.Equal("gdsf", suite.fileCache.policy.Name())
+	suite.assert.EqualValues(30, suite.fileCache.policy.(*rankedPolicy).cacheTimeout)
+	suite.assert.EqualValues(10, suite.fileCache.policy.(*rankedPolicy).maxEviction)
+}
+
+func (suite *fileCacheTestSuite) TestInvalidPolicy() {
+	configStr := fmt.Sprintf("file_cache:\n  path: %s\n  policy: mru\n", suite.cache_path)
+
+	err := config.ReadConfigFromReader(strings.NewReader(configStr))
+	suite.assert.NoError(err)
+
+	fc := NewFileCacheComponent()
+	err = fc.Configure(true)
+	suite.assert.Error(err)
+	suite.assert.Contains(err.Error(), "invalid cache policy mru")
+}
+
+func (suite *fileCacheTestSuite) TestAsyncUploadWithoutJournal() {
+	// Changes of an upload given up on are recovered only from the journal
+	configStr := fmt.Sprintf("file_cache:\n  path: %s\n  async-upload: true\n", suite.cache_path)
+
+	err := config.ReadConfigFromReader(strings.NewReader(configStr))
+	suite.assert.NoError(err)
+
+	fc := NewFileCacheComponent()
+	err = fc.Configure(true)
+	suite.assert.Error(err)
+	suite.assert.Contains(err.Error(), "async-upload requires write-journal")
+}
+
 func (suite *fileCacheTestSuite) TestNegativeCacheSize() {
 	var cacheSize float64 = -100
 
@@ -1193,6 +1229,159 @@ func (suite *fileCacheTestSuite) TestWriteFileErrorBadFd() {
 	suite.assert.Equal(0, bytesWritten)
 }
 
+func (suite *fileCacheTestSuite) TestFallocatePunchHole() {
+	defer suite.cleanupTest()
+	// Setup
+	file := "file_fallocate"
+	handle, _ := suite.fileCache.CreateFile(internal.CreateFileOptions{Name: file, Mode: 0777})
+	data := []byte("test data for fallocate")
+	_, err := suite.fileCache.WriteFile(&internal.WriteFileOptions{Handle: handle, Offset: 0, Data: data})
+	suite.assert.NoError(err)
+
+	// Reserving space without changing the size keeps the handle clean
+	handle.Flags.Clear(handlemap.HandleFlagDirty)
+	err = suite.fileCache.Fallocate(internal.FallocateOptions{Handle: handle, Name: file, Mode: internal.FallocKeepSize, Offset: 0, Length: 4096})
+	suite.assert.NoError(err)
+	suite.assert.False(handle.Dirty())
+
+	err = suite.fileCache.Fallocate(internal.FallocateOptions{Handle: handle, Name: file, Mode: internal.FallocPunchHole | internal.FallocKeepSize, Offset: 5, Length: 4})
+	suite.assert.NoError(err)
+	suite.assert.True(handle.Dirty())
+
+	d, _ := os.ReadFile(suite.cache_path + "/" + file)
+	suite.assert.Len(d, len(data))
+	suite.assert.Equal(make([]byte, 4), d[5:9])
+	suite.assert.Equal(data[:5], d[:5])
+	suite.assert.Equal(data[9:], d[9:])
+
+	// Flushing the file should push the hole to storage
+	err = suite.fileCache.FlushFile(internal.FlushFileOptions{Handle: handle})
+	suite.assert.NoError(err)
+	d, _ = os.ReadFile(suite.fake_storage_path + "/" + file)
+	suite.assert.Equal(make([]byte, 4), d[5:9])
+}
+
+func (suite *fileCacheTestSuite) TestFallocateErrorBadFd() {
+	defer suite.cleanupTest()
+	// Setup
+	file := "file_fallocate_badfd"
+	handle := handlemap.NewHandle(file)
+	err := suite.fileCache.Fallocate(internal.FallocateOptions{Handle: handle, Name: file, Offset: 0, Length: 10})
+	suite.assert.Error(err)
+	suite.assert.EqualValues(syscall.EBADF, err)
+}
+
+func (suite *fileCacheTestSuite) TestLseek() {
+	defer suite.cleanupTest()
+	// Setup
+	file := "file_lseek"
+	handle, _ := suite.fileCache.CreateFile(internal.CreateFileOptions{Name: file, Mode: 0777})
+	data := []byte("test data")
+	_, err := suite.fileCache.WriteFile(&internal.WriteFileOptions{Handle: handle, Offset: 0, Data: data})
+	suite.assert.NoError(err)
+
+	offset, err := suite.fileCache.Lseek(internal.LseekOptions{Handle: handle, Name: file, Offset: 2, Whence: internal.SeekData})
+	suite.assert.NoError(err)
+	suite.assert.EqualValues(2, offset)
+
+	offset, err = suite.fileCache.Lseek(internal.LseekOptions{Handle: handle, Name: file, Offset: 2, Whence: internal.SeekHole})
+	suite.assert.NoError(err)
+	suite.assert.EqualValues(len(data), offset)
+
+	_, err = suite.fileCache.Lseek(internal.LseekOptions{Handle: handle, Name: file, Offset: int64(len(data)), Whence: internal.SeekData})
+	suite.assert.Equal(syscall.ENXIO, err)
+
+	// Seeking must not disturb the offset used by writes
+	_, err = suite.fileCache.WriteFile(&internal.WriteFileOptions{Handle: handle, Offset: 0, Data: []byte("T")})
+	suite.assert.NoError(err)
+	d, _ := os.ReadFile(suite.cache_path + "/" + file)
+	suite.assert.Equal([]byte("Test data"), d)
+}
+
+func (suite *fileCacheTestSuite) TestLseekErrorBadFd() {
+	defer suite.cleanupTest()
+	// Setup
+	file := "file_lseek_badfd"
+	handle := handlemap.NewHandle(file)
+	_, err := suite.fileCache.Lseek(internal.LseekOptions{Handle: handle, Name: file, Offset: 0, Whence: internal.SeekData})
+	suite.assert.EqualValues(syscall.EBADF, err)
+}
+
+func (suite *fileCacheTestSuite) TestCopyFileRange() {
+	defer suite.cleanupTest()
+	// Setup
+	src := "file_copy_src"
+	dst := "file_copy_dst"
+	handle, _ := suite.fileCache.CreateFile(internal.CreateFileOptions{Name: src, Mode: 0777})
+	_, err := suite.fileCache.WriteFile(&internal.WriteFileOptions{Handle: handle, Offset: 0, Data: []byte("0123456789")})
+	suite.assert.NoError(err)
+	err = suite.fileCache.ReleaseFile(internal.ReleaseFileOptions{Handle: handle})
+	suite.assert.NoError(err)
+
+	srcHandle, err := suite.fileCache.OpenFile(internal.OpenFileOptions{Name: src, Flags: os.O_RDONLY, Mode: 0777})
+	suite.assert.NoError(err)
+	dstHandle, _ := suite.fileCache.CreateFile(internal.CreateFileOptions{Name: dst, Mode: 0777})
+	_, err = suite.fileCache.WriteFile(&internal.WriteFileOptions{Handle: dstHandle, Offset: 0, Data: []byte("abcd")})
+	suite.assert.NoError(err)
+
+	copied, err := suite.fileCache.CopyFileRange(internal.CopyFileRangeOptions{
+		SrcHandle: srcHandle, SrcName: src, SrcOffset: 2,
+		DstHandle: dstHandle, DstName: dst, DstOffset: 2, Length: 5})
+	suite.assert.NoError(err)
+	suite.assert.EqualValues(5, copied)
+	// Local copy is kept in sync with storage so nothing is left to upload
+	suite.assert.False(dstHandle.Dirty())
+
+	d, _ := os.ReadFile(suite.cache_path + "/" + dst)
+	suite.assert.Equal([]byte("ab23456"), d)
+	d, _ = os.ReadFile(suite.fake_storage_path + "/" + dst)
+	suite.assert.Equal([]byte("ab23456"), d)
+}
+
+func (suite *fileCacheTestSuite) TestCopyFileRangeStorageFailure() {
+	defer suite.cleanupTest()
+	// Setup
+	src := "file_copy_fail_src"
+	dst := "file_copy_fail_dst"
+	handle, _ := suite.fileCache.CreateFile(internal.CreateFileOptions{Name: src, Mode: 0777})
+	_, err := suite.fileCache.WriteFile(&internal.WriteFileOptions{Handle: handle, Offset: 0, Data: []byte("0123456789")})
+	suite.assert.NoError(err)
+	err = suite.fileCache.ReleaseFile(internal.ReleaseFileOptions{Handle: handle})
+	suite.assert.NoError(err)
+
+	srcHandle, err := suite.fileCache.OpenFile(internal.OpenFileOptions{Name: src, Flags: os.O_RDONLY, Mode: 0777})
+	suite.assert.NoError(err)
+	dstHandle, _ := suite.fileCache.CreateFile(internal.CreateFileOptions{Name: dst, Mode: 0777})
+
+	// Copy in storage fails once source is gone from it
+	os.Remove(suite.fake_storage_path + "/" + src)
+
+	copied, err := suite.fileCache.CopyFileRange(internal.CopyFileRangeOptions{
+		SrcHandle: srcHandle, SrcName: src, SrcOffset: 4,
+		DstHandle: dstHandle, DstName: dst, Length: 10})
+	suite.assert.NoError(err)
+	suite.assert.EqualValues(6, copied)
+	suite.assert.True(dstHandle.Dirty())
+
+	err = suite.fileCache.FlushFile(internal.FlushFileOptions{Handle: dstHandle})
+	suite.assert.NoError(err)
+	d, _ := os.ReadFile(suite.fake_storage_path + "/" + dst)
+	suite.assert.Equal([]byte("456789"), d)
+}
+
+func (suite *fileCacheTestSuite) TestCopyFileRangeDirtySource() {
+	defer suite.cleanupTest()
+	// Setup
+	src := "file_copy_dirty_src"
+	dst := "file_copy_dirty_dst"
+	srcHandle, _ := suite.fileCache.CreateFile(internal.CreateFileOptions{Name: src, Mode: 0777})
+	dstHandle, _ := suite.fileCache.CreateFile(internal.CreateFileOptions{Name: dst, Mode: 0777})
+
+	_, err := suite.fileCache.CopyFileRange(internal.CopyFileRangeOptions{
+		SrcHandle: srcHandle, SrcName: src, DstHandle: dstHandle, DstName: dst, Length: 5})
+	suite.assert.Equal(syscall.ENOTSUP, err)
+}
+
 func (suite *fileCacheTestSuite) TestFlushFileEmpty() {
 	defer suite.cleanupTest()
 	// Setup
@@ -1711,6 +1900,117 @@ func (suite *fileCacheTestSuite) TestChmodCase2() {
 	suite.assert.Equal(attr.Mode, newMode)
 }
 
+func (suite *fileCacheTestSuite) TestXattrInStorage() {
+	defer suite.cleanupTest()
+	path := "file_xattr1"
+	handle, _ := suite.fileCache.CreateFile(internal.CreateFileOptions{Name: path, Mode: 0777})
+	err := suite.fileCache.ReleaseFile(internal.ReleaseFileOptions{Handle: handle})
+	suite.assert.NoError(err)
+
+	err = suite.fileCache.SetXattr(internal.SetXattrOptions{Name: path, Attr: "user.owner", Value: []byte("team1")})
+	suite.assert.NoError(err)
+
+	// Attribute should be set in fake storage
+	buf := make([]byte, 16)
+	n, err := syscall.Getxattr(suite.fake_storage_path+"/"+path, "user.owner", buf)
+	suite.assert.NoError(err)
+	suite.assert.Equal("team1", string(buf[:n]))
+
+	data, err := suite.fileCache.GetXattr(internal.GetXattrOptions{Name: path, Attr: "user.owner"})
+	suite.assert.NoError(err)
+	suite.assert.Equal("team1", string(data))
+
+	err = suite.fileCache.RemoveXattr(internal.RemoveXattrOptions{Name: path, Attr: "user.owner"})
+	suite.assert.NoError(err)
+
+	_, err = suite.fileCache.GetXattr(internal.GetXattrOptions{Name: path, Attr: "user.owner"})
+	suite.assert.Equal(syscall.ENODATA, err)
+}
+
+func (suite *fileCacheTestSuite) TestXattrNotUploaded() {
+	defer suite.cleanupTest()
+	// Default is to not create empty files on create file to support immutable storage.
+	path := "file_xattr2"
+	createHandle, err := suite.fileCache.CreateFile(internal.CreateFileOptions{Name: path, Mode: 0777})
+	suite.assert.NoError(err)
+
+	err = suite.fileCache.SetXattr(internal.SetXattrOptions{Name: path, Attr: "user.owner", Value: []byte("team1")})
+	suite.assert.NoError(err)
+	err = suite.fileCache.SetXattr(internal.SetXattrOptions{Name: path, Attr: "user.owner", Value: []byte("team2"), Flags: internal.XattrCreate})
+	suite.assert.Equal(syscall.EEXIST, err)
+	err = suite.fileCache.SetXattr(internal.SetXattrOptions{Name: path, Attr: "user.tmp", Value: []byte("abc")})
+	suite.assert.NoError(err)
+	err = suite.fileCache.RemoveXattr(internal.RemoveXattrOptions{Name: path, Attr: "user.tmp"})
+	suite.assert.NoError(err)
+
+	// Served from the pending list as file is not in storage yet
+	data, err := suite.fileCache.GetXattr(internal.GetXattrOptions{Name: path, Attr: "user.owner"})
+	suite.assert.NoError(err)
+	suite.assert.Equal("team1", string(data))
+
+	names, err := suite.fileCache.ListXattr(internal.ListXattrOptions{Name: path})
+	suite.assert.NoError(err)
+	suite.assert.Equal([]string{"user.owner"}, names)
+
+	err = suite.fileCache.FlushFile(internal.FlushFileOptions{Handle: createHandle})
+	suite.assert.NoError(err)
+
+	// Attribute should be applied in fake storage after upload
+	buf := make([]byte, 16)
+	n, err := syscall.Getxattr(suite.fake_storage_path+"/"+path, "user.owner", buf)
+	suite.assert.NoError(err)
+	suite.assert.Equal("team1", string(buf[:n]))
+
+	_, found := suite.fileCache.missedXattrList.Load(path)
+	suite.assert.False(found)
+
+	err = suite.fileCache.ReleaseFile(internal.ReleaseFileOptions{Handle: createHandle})
+	suite.assert.NoError(err)
+}
+
+func (suite *fileCacheTestSuite) TestCreateHardLink() {
+	defer suite.cleanupTest()
+	// Setup
+	path := "file_hardlink1"
+	link := "file_hardlink1_link"
+	handle, _ := suite.fileCache.CreateFile(internal.CreateFileOptions{Name: path, Mode: 0777})
+	err := suite.fileCache.ReleaseFile(internal.ReleaseFileOptions{Handle: handle})
+	suite.assert.NoError(err)
+
+	err = suite.fileCache.CreateHardLink(internal.CreateHardLinkOptions{Name: link, Target: path})
+	suite.assert.NoError(err)
+
+	attr, err := suite.fileCache.GetAttr(internal.GetAttrOptions{Name: link})
+	suite.assert.NoError(err)
+	suite.assert.EqualValues(2, attr.Nlink)
+
+	err = suite.fileCache.CreateHardLink(internal.CreateHardLinkOptions{Name: link, Target: "missing"})
+	suite.assert.Error(err)
+}
+
+func (suite *fileCacheTestSuite) TestCreateHardLinkNotUploaded() {
+	defer suite.cleanupTest()
+	// Default is to not create empty files on create file to support immutable storage.
+	path := "file_hardlink2"
+	link := "file_hardlink2_link"
+	createHandle, err := suite.fileCache.CreateFile(internal.CreateFileOptions{Name: path, Mode: 0777})
+	suite.assert.NoError(err)
+	data := []byte("hello")
+	_, err = suite.fileCache.WriteFile(&internal.WriteFileOptions{Handle: createHandle, Offset: 0, Data: data})
+	suite.assert.NoError(err)
+
+	// Target gets uploaded before the link is created
+	err = suite.fileCache.CreateHardLink(internal.CreateHardLinkOptions{Name: link, Target: path})
+	suite.assert.NoError(err)
+
+	info, err := os.Stat(suite.fake_storage_path + "/" + link)
+	suite.assert.NoError(err)
+	suite.assert.EqualValues(len(data), info.Size())
+
+	err = suite.fileCache.ReleaseFile(internal.ReleaseFileOptions{Handle: createHandle})
+	suite.assert.NoError(err)
+}
+
 func (suite *fileCacheTestSuite) TestChownNotInCache() {
 	defer suite.cleanupTest()
 	// Setup
@@ -2245,6 +2545,534 @@ func (suite *fileCacheTestSuite) TestOpenFileDownloadFailure() {
 
 // In order for 'go test' to run this suite, we need to create
 // a normal test function and pass our suite to suite.Run
+// remount stops the components without touching their directories and starts them again with the given config
+func (suite *fileCacheTestSuite) remount(configuration string) {
+	err := suite.fileCache.Stop()
+	suite.assert.NoError(err)
+	err = suite.loopback.Stop()
+	suite.assert.NoError(err)
+	suite.setupTestHelper(configuration)
+}
+
+func (suite *fileCacheTestSuite) TestPersistCacheAdopt() {
+	defer suite.cleanupTest()
+	config := fmt.Sprintf("file_cache:\n  path: %s\n  offload-io: true\n  timeout-sec: 3600\n  persist-cache: true\n\nloopbackfs:\n  path: %s",
+		suite.cache_path, suite.fake_storage_path)
+	suite.cleanupTest()
+	suite.setupTestHelper(config)
+	suite.assert.True(suite.fileCache.persistCache)
+
+	path := "dir/file"
+	err := os.MkdirAll(filepath.Join(suite.fake_storage_path, "dir"), 0777)
+	suite.assert.NoError(err)
+	err = os.WriteFile(filepath.Join(suite.fake_storage_path, path), []byte("test data"), 0777)
+	suite.assert.NoError(err)
+
+	handle, err := suite.fileCache.OpenFile(internal.OpenFileOptions{Name: path, Flags: os.O_RDONLY, Mode: 0777})
+	suite.assert.NoError(err)
+	err = suite.fileCache.ReleaseFile(internal.ReleaseFileOptions{Handle: handle})
+	suite.assert.NoError(err)
+
+	// Files without an entry in the index are not trusted on remount
+	err = os.WriteFile(filepath.Join(suite.cache_path, "orphan"), []byte("orphan"), 0777)
+	suite.assert.NoError(err)
+
+	suite.remount(config)
+
+	localPath := filepath.Join(suite.cache_path, path)
+	suite.assert.FileExists(localPath)
+	suite.assert.NoFileExists(filepath.Join(suite.cache_path, "orphan"))
+	suite.assert.NoFileExists(filepath.Join(suite.cache_path, cacheIndexFile))
+	suite.assert.Eventually(func() bool { return suite.fileCache.policy.IsCached(localPath) }, 5*time.Second, 10*time.Millisecond)
+
+	// Adopted file is served from cache without downloading it again
+	downloadRequired, _, _, err := suite.fileCache.isDownloadRequired(localPath, path, suite.fileCache.fileLocks.Get(path))
+	suite.assert.NoError(err)
+	suite.assert.False(downloadRequired)
+}
+
+func (suite *fileCacheTestSuite) TestPersistCacheStale() {
+	defer suite.cleanupTest()
+	config := fmt.Sprintf("file_cache:\n  path: %s\n  offload-io: true\n  timeout-sec: 3600\n  persist-cache: true\n\nloopbackfs:\n  path: %s",
+		suite.cache_path, suite.fake_storage_path)
+	suite.cleanupTest()
+	suite.setupTestHelper(config)
+
+	path := "file"
+	err := os.WriteFile(filepath.Join(suite.fake_storage_path, path), []byte("test data"), 0777)
+	suite.assert.NoError(err)
+
+	handle, err := suite.fileCache.OpenFile(internal.OpenFileOptions{Name: path, Flags: os.O_RDONLY, Mode: 0777})
+	suite.assert.NoError(err)
+	err = suite.fileCache.ReleaseFile(internal.ReleaseFileOptions{Handle: handle})
+	suite.assert.NoError(err)
+
+	err = suite.fileCache.Stop()
+	suite.assert.NoError(err)
+	suite.assert.FileExists(filepath.Join(suite.cache_path, cacheIndexFile))
+
+	// Blob changes while the cache is not mounted
+	err = os.WriteFile(filepath.Join(suite.fake_storage_path, path), []byte("new test data"), 0777)
+	suite.assert.NoError(err)
+
+	err = suite.loopback.Stop()
+	suite.assert.NoError(err)
+	suite.setupTestHelper(config)
+
+	suite.assert.NoFileExists(filepath.Join(suite.cache_path, path))
+}
+
+func (suite *fileCacheTestSuite) TestWriteJournalRecord() {
+	defer suite.cleanupTest()
+	config := fmt.Sprintf("file_cache:\n  path: %s\n  offload-io: true\n  timeout-sec: 3600\n  write-journal: true\n\nloopbackfs:\n  path: %s",
+		suite.cache_path, suite.fake_storage_path)
+	suite.cleanupTest()
+	suite.setupTestHelper(config)
+	suite.assert.NotNil(suite.fileCache.journal)
+	suite.assert.Equal(journalRecoveryUpload, suite.fileCache.journalRecovery)
+
+	path := "file"
+	handle, err := suite.fileCache.CreateFile(internal.CreateFileOptions{Name: path, Mode: 0777})
+	suite.assert.NoError(err)
+	_, err = suite.fileCache.WriteFile(&internal.WriteFileOptions{Handle: handle, Offset: 0, Data: []byte("test data")})
+	suite.assert.NoError(err)
+
+	entries, err := ListJournal(suite.cache_path)
+	suite.assert.NoError(err)
+	suite.assert.Len(entries, 1)
+	suite.assert.Equal(path, entries[0].Path)
+	suite.assert.Equal(JournalStatePending, entries[0].State)
+	suite.assert.True(entries[0].New)
+
+	err = suite.fileCache.FlushFile(internal.FlushFileOptions{Handle: handle})
+	suite.assert.NoError(err)
+
+	entries, err = ListJournal(suite.cache_path)
+	suite.assert.NoError(err)
+	suite.assert.Empty(entries)
+
+	err = suite.fileCache.ReleaseFile(internal.ReleaseFileOptions{Handle: handle})
+	suite.assert.NoError(err)
+}
+
+func (suite *fileCacheTestSuite) TestWriteJournalRecoverUpload() {
+	defer suite.cleanupTest()
+	config := fmt.Sprintf("file_cache:\n  path: %s\n  offload-io: true\n  timeout-sec: 3600\n  write-journal: true\n\nloopbackfs:\n  path: %s",
+		suite.cache_path, suite.fake_storage_path)
+	suite.cleanupTest()
+	suite.setupTestHelper(config)
+
+	path := "file"
+	err := os.WriteFile(filepath.Join(suite.fake_storage_path, path), []byte("test data"), 0777)
+	suite.assert.NoError(err)
+
+	handle, err := suite.fileCache.OpenFile(internal.OpenFileOptions{Name: path, Flags: os.O_RDWR, Mode: 0777})
+	suite.assert.NoError(err)
+	_, err = suite.fileCache.WriteFile(&internal.WriteFileOptions{Handle: handle, Offset: 0, Data: []byte("new")})
+	suite.assert.NoError(err)
+
+	// Mount goes away without flushing the handle
+	err = suite.loopback.Stop()
+	suite.assert.NoError(err)
+	suite.setupTestHelper(config)
+
+	data, err := os.ReadFile(filepath.Join(suite.fake_storage_path, path))
+	suite.assert.NoError(err)
+	suite.assert.Equal("newt data", string(data))
+	suite.assert.NoFileExists(filepath.Join(suite.cache_path, path))
+
+	entries, err := ListJournal(suite.cache_path)
+	suite.assert.NoError(err)
+	suite.assert.Empty(entries)
+}
+
+func (suite *fileCacheTestSuite) TestWriteJournalRecoverConflict() {
+	defer suite.cleanupTest()
+	config := fmt.Sprintf("file_cache:\n  path: %s\n  offload-io: true\n  timeout-sec: 3600\n  write-journal: true\n\nloopbackfs:\n  path: %s",
+		suite.cache_path, suite.fake_storage_path)
+	suite.cleanupTest()
+	suite.setupTestHelper(config)
+
+	path := "file"
+	err := os.WriteFile(filepath.Join(suite.fake_storage_path, path), []byte("test data"), 0777)
+	suite.assert.NoError(err)
+
+	handle, err := suite.fileCache.OpenFile(internal.OpenFileOptions{Name: path, Flags: os.O_RDWR, Mode: 0777})
+	suite.assert.NoError(err)
+	_, err = suite.fileCache.WriteFile(&internal.WriteFileOptions{Handle: handle, Offset: 0, Data: []byte("new")})
+	suite.assert.NoError(err)
+
+	// Blob changes in container while the mount is down
+	err = suite.loopback.Stop()
+	suite.assert.NoError(err)
+	err = os.WriteFile(filepath.Join(suite.fake_storage_path, path), []byte("other writer"), 0777)
+	suite.assert.NoError(err)
+	suite.setupTestHelper(config)
+
+	data, err := os.ReadFile(filepath.Join(suite.fake_storage_path, path))
+	suite.assert.NoError(err)
+	suite.assert.Equal("other writer", string(data))
+	suite.assert.NoFileExists(filepath.Join(suite.cache_path, path))
+
+	entries, err := ListJournal(suite.cache_path)
+	suite.assert.NoError(err)
+	suite.assert.Len(entries, 1)
+	suite.assert.Equal(JournalStateQuarantined, entries[0].State)
+	suite.assert.Contains(entries[0].Reason, "modified in container")
+
+	data, err = os.ReadFile(entries[0].DataPath)
+	suite.assert.NoError(err)
+	suite.assert.Equal("newt data", string(data))
+}
+
+func (suite *fileCacheTestSuite) TestWriteJournalKeepsPendingOnStop() {
+	defer suite.cleanupTest()
+	config := fmt.Sprintf("file_cache:\n  path: %s\n  offload-io: true\n  timeout-sec: 3600\n  write-journal: true\n  journal-recovery: quarantine\n\nloopbackfs:\n  path: %s",
+		suite.cache_path, suite.fake_storage_path)
+	suite.cleanupTest()
+	suite.setupTestHelper(config)
+
+	path := "dir/file"
+	err := suite.fileCache.CreateDir(internal.CreateDirOptions{Name: "dir", Mode: 0777})
+	suite.assert.NoError(err)
+	handle, err := suite.fileCache.CreateFile(internal.CreateFileOptions{Name: path, Mode: 0777})
+	suite.assert.NoError(err)
+	_, err = suite.fileCache.WriteFile(&internal.WriteFileOptions{Handle: handle, Offset: 0, Data: []byte("test data")})
+	suite.assert.NoError(err)
+
+	err = suite.fileCache.Stop()
+	suite.assert.NoError(err)
+	suite.assert.FileExists(filepath.Join(suite.cache_path, path))
+
+	err = suite.loopback.Stop()
+	suite.assert.NoError(err)
+	suite.setupTestHelper(config)
+
+	// Recovery policy keeps the file out of the container
+	suite.assert.NoFileExists(filepath.Join(suite.fake_storage_path, path))
+	entries, err := ListJournal(suite.cache_path)
+	suite.assert.NoError(err)
+	suite.assert.Len(entries, 1)
+	suite.assert.Equal(JournalStateQuarantined, entries[0].State)
+	suite.assert.FileExists(entries[0].DataPath)
+}
+
+func (suite *fileCacheTestSuite) TestAsyncUpload() {
+	defer suite.cleanupTest()
+	config := fmt.Sprintf("file_cache:\n  path: %s\n  offload-io: true\n  timeout-sec: 3600\n  write-journal: true\n  async-upload: true\n  upload-workers: 2\n\nloopbackfs:\n  path: %s",
+		suite.cache_path, suite.fake_storage_path)
+	suite.cleanupTest()
+	suite.setupTestHelper(config)
+	suite.assert.NotNil(suite.fileCache.uploadQueue)
+	suite.assert.EqualValues(2, suite.fileCache.uploadQueue.workers)
+	suite.assert.EqualValues(defaultUploadRetries, suite.fileCache.uploadQueue.retries)
+
+	path := "file"
+	handle, err := suite.fileCache.CreateFile(internal.CreateFileOptions{Name: path, Mode: 0777})
+	suite.assert.NoError(err)
+	_, err = suite.fileCache.WriteFile(&internal.WriteFileOptions{Handle: handle, Offset: 0, Data: []byte("test data")})
+	suite.assert.NoError(err)
+
+	// Flush before close is left to the upload queue
+	err = suite.fileCache.FlushFile(internal.FlushFileOptions{Handle: handle})
+	suite.assert.NoError(err)
+	suite.assert.True(handle.Dirty())
+
+	err = suite.fileCache.ReleaseFile(internal.ReleaseFileOptions{Handle: handle})
+	suite.assert.NoError(err)
+
+	suite.assert.Eventually(func() bool {
+		data, err := os.ReadFile(filepath.Join(suite.fake_storage_path, path))
+		return err == nil && string(data) == "test data"
+	}, 5*time.Second, 10*time.Millisecond)
+	suite.assert.Eventually(func() bool { return suite.fileCache.uploadQueue.depth.Load() == 0 }, 5*time.Second, 10*time.Millisecond)
+	suite.assert.False(handle.Dirty())
+}
+
+func (suite *fileCacheTestSuite) TestAsyncUploadSyncFile() {
+	defer suite.cleanupTest()
+	config := fmt.Sprintf("file_cache:\n  path: %s\n  offload-io: true\n  timeout-sec: 3600\n  write-journal: true\n  async-upload: true\n\nloopbackfs:\n  path: %s",
+		suite.cache_path, suite.fake_storage_path)
+	suite.cleanupTest()
+	suite.setupTestHelper(config)
+
+	path := "file"
+	handle, err := suite.fileCache.CreateFile(internal.CreateFileOptions{Name: path, Mode: 0777})
+	suite.assert.NoError(err)
+	_, err = suite.fileCache.WriteFile(&internal.WriteFileOptions{Handle: handle, Offset: 0, Data: []byte("test data")})
+	suite.assert.NoError(err)
+
+	// fsync makes the data durable without waiting for close
+	err = suite.fileCache.SyncFile(internal.SyncFileOptions{Handle: handle})
+	suite.assert.NoError(err)
+	suite.assert.False(handle.Dirty())
+
+	data, err := os.ReadFile(filepath.Join(suite.fake_storage_path, path))
+	suite.assert.NoError(err)
+	suite.assert.Equal("test data", string(data))
+
+	err = suite.fileCache.ReleaseFile(internal.ReleaseFileOptions{Handle: handle})
+	suite.assert.NoError(err)
+}
+
+func (suite *fileCacheTestSuite) TestAsyncUploadRetry() {
+	defer suite.cleanupTest()
+
+	failures := 1
+	uploaded, released := make(chan bool, 1), make(chan bool, 1)
+	q := newUploadQueue(1, 2,
+		func(_ *uploadItem) error {
+			if failures > 0 {
+				failures--
+				return syscall.EIO
+			}
+			uploaded <- true
+			return nil
+		},
+		func(_ *uploadItem, err error) { released <- err == nil },
+		func(_ *uploadItem) error { return nil },
+		func(_ *uploadItem) {})
+	q.start()
+
+	q.schedule(&uploadItem{options: internal.ReleaseFileOptions{Handle: handlemap.NewHandle("file")}})
+	q.stop()
+
+	suite.assert.Len(uploaded, 1)
+	suite.assert.True(<-released)
+	suite.assert.Zero(failures)
+	suite.assert.EqualValues(0, q.depth.Load())
+}
+
+func (suite *fileCacheTestSuite) TestAsyncUploadRequeue() {
+	defer suite.cleanupTest()
+
+	failures := 2
+	released := make(chan error, 2)
+	reopened, dropped := 0, 0
+	q := newUploadQueue(1, 1,
+		func(_ *uploadItem) error {
+			if failures > 0 {
+				failures--
+				return syscall.EIO
+			}
+			return nil
+		},
+		func(_ *uploadItem, err error) { released <- err },
+		func(_ *uploadItem) error { reopened++; return nil },
+		func(_ *uploadItem) { dropped++ })
+	q.requeueDelay = 10 * time.Millisecond
+	q.start()
+
+	// Handle is closed once retries run out and is uploaded when it comes back to the queue
+	q.schedule(&uploadItem{options: internal.ReleaseFileOptions{Handle: handlemap.NewHandle("file")}})
+	suite.assert.Eventually(func() bool { return len(released) == 2 }, 5*time.Second, 10*time.Millisecond)
+	q.stop()
+
+	suite.assert.Equal(syscall.EIO, <-released)
+	suite.assert.NoError(<-released)
+	suite.assert.Equal(1, reopened)
+	suite.assert.Zero(dropped)
+
+	// Uploads still waiting for another try are given up on stop
+	q = newUploadQueue(1, 0,
+		func(_ *uploadItem) error { return syscall.EIO },
+		func(_ *uploadItem, _ error) {},
+		func(_ *uploadItem) error { reopened++; return nil },
+		func(_ *uploadItem) { dropped++ })
+	q.start()
+	q.schedule(&uploadItem{options: internal.ReleaseFileOptions{Handle: handlemap.NewHandle("file")}})
+	q.stop()
+
+	suite.assert.Equal(1, reopened)
+	suite.assert.Equal(1, dropped)
+	suite.assert.Empty(q.waiting)
+}
+
+func (suite *fileCacheTestSuite) TestAsyncUploadFailure() {
+	defer suite.cleanupTest()
+	config := fmt.Sprintf("file_cache:\n  path: %s\n  offload-io: true\n  timeout-sec: 3600\n  write-journal: true\n  async-upload: true\n  upload-retries: 0\n\nloopbackfs:\n  path: %s",
+		suite.cache_path, suite.fake_storage_path)
+	suite.cleanupTest()
+	suite.setupTestHelper(config)
+	suite.fileCache.uploadQueue.requeueDelay = 100 * time.Millisecond
+
+	path := "dir/file"
+	suite.assert.NoError(os.MkdirAll(filepath.Join(suite.cache_path, "dir"), 0777))
+	handle, err := suite.fileCache.CreateFile(internal.CreateFileOptions{Name: path, Mode: 0777})
+	suite.assert.NoError(err)
+	_, err = suite.fileCache.WriteFile(&internal.WriteFileOptions{Handle: handle, Offset: 0, Data: []byte("test data")})
+	suite.assert.NoError(err)
+
+	// Upload fails as long as the directory is missing in storage
+	err = suite.fileCache.ReleaseFile(internal.ReleaseFileOptions{Handle: handle})
+	suite.assert.NoError(err)
+
+	// Cross node lock is dropped only once the upload is over
+	err = suite.fileCache.UnlockFile(internal.UnlockFileOptions{Handle: handle, Name: path})
+	suite.assert.NoError(err)
+	val, _ := handle.GetValue(asyncReleaseKey)
+	suite.assert.True(val.(*asyncRelease).unlocked)
+
+	// Failed handle is closed but the file stays pinned so eviction can not drop the changes meanwhile
+	flock := suite.fileCache.fileLocks.Get(path)
+	suite.assert.Eventually(func() bool { return suite.fileCache.uploadQueue.depth.Load() == 0 }, 5*time.Second, 10*time.Millisecond)
+	flock.Lock()
+	suite.assert.EqualValues(1, flock.Count())
+	suite.assert.True(handle.Dirty())
+	flock.Unlock()
+	suite.fileCache.policy.CachePurge(filepath.Join(suite.cache_path, path))
+	suite.assert.FileExists(filepath.Join(suite.cache_path, path))
+
+	suite.assert.NoError(os.MkdirAll(filepath.Join(suite.fake_storage_path, "dir"), 0777))
+	suite.assert.Eventually(func() bool {
+		data, err := os.ReadFile(filepath.Join(suite.fake_storage_path, path))
+		return err == nil && string(data) == "test data"
+	}, 5*time.Second, 10*time.Millisecond)
+	suite.assert.Eventually(func() bool {
+		val.(*asyncRelease).Lock()
+		defer val.(*asyncRelease).Unlock()
+		return val.(*asyncRelease).done
+	}, 5*time.Second, 10*time.Millisecond)
+	flock.Lock()
+	suite.assert.Zero(flock.Count())
+	flock.Unlock()
+}
+
+func (suite *fileCacheTestSuite) TestDirtyRanges() {
+	defer suite.cleanupTest()
+
+	d := &dirtyRanges{}
+	d.add(10, 20)
+	d.add(30, 40)
+	d.add(20, 25)
+	d.add(5, 8)
+	suite.assert.Equal([]byteRange{{5, 8}, {10, 25}, {30, 40}}, d.ranges)
+
+	// A range spanning several merges them
+	d.add(7, 35)
+	suite.assert.Equal([]byteRange{{5, 40}}, d.ranges)
+	suite.assert.True(d.overlaps(0, 6))
+	suite.assert.False(d.overlaps(40, 50))
+	suite.assert.False(d.overlaps(0, 5))
+
+	d.setFull()
+	d.add(100, 200)
+	suite.assert.True(d.full)
+	suite.assert.Empty(d.ranges)
+
+	d.reset()
+	suite.assert.False(d.full)
+}
+
+func (suite *fileCacheTestSuite) TestDiffUpload() {
+	defer suite.cleanupTest()
+	config := fmt.Sprintf("file_cache:\n  path: %s\n  offload-io: true\n  timeout-sec: 3600\n  differential-upload: true\n\nloopbackfs:\n  path: %s",
+		suite.cache_path, suite.fake_storage_path)
+	suite.cleanupTest()
+	suite.setupTestHelper(config)
+	suite.assert.True(suite.fileCache.diffUpload)
+
+	path := "file"
+	data := make([]byte, 4*MB)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	err := os.WriteFile(filepath.Join(suite.fake_storage_path, path), data, 0777)
+	suite.assert.NoError(err)
+
+	handle, err := suite.fileCache.OpenFile(internal.OpenFileOptions{Name: path, Flags: os.O_RDWR, Mode: 0777})
+	suite.assert.NoError(err)
+	_, err = suite.fileCache.WriteFile(&internal.WriteFileOptions{Handle: handle, Offset: MB + MB/2, Data: []byte("new data")})
+	suite.assert.NoError(err)
+	err = suite.fileCache.FlushFile(internal.FlushFileOptions{Handle: handle})
+	suite.assert.NoError(err)
+
+	copy(data[MB+MB/2:], "new data")
+	stored, err := os.ReadFile(filepath.Join(suite.fake_storage_path, path))
+	suite.assert.NoError(err)
+	suite.assert.Equal(data, stored)
+
+	// Only the modified block is replaced
+	blockList, err := suite.loopback.GetCommittedBlockList(path)
+	suite.assert.NoError(err)
+	suite.assert.Len(*blockList, 4)
+	suite.assert.Equal("0", (*blockList)[0].Id)
+	suite.assert.NotEqual("1", (*blockList)[1].Id)
+	suite.assert.Equal("2", (*blockList)[2].Id)
+	suite.assert.Equal("3", (*blockList)[3].Id)
+
+	err = suite.fileCache.ReleaseFile(internal.ReleaseFileOptions{Handle: handle})
+	suite.assert.NoError(err)
+}
+
+func (suite *fileCacheTestSuite) TestDiffUploadBlobChanged() {
+	defer suite.cleanupTest()
+	config := fmt.Sprintf("file_cache:\n  path: %s\n  offload-io: true\n  timeout-sec: 3600\n  differential-upload: true\n\nloopbackfs:\n  path: %s",
+		suite.cache_path, suite.fake_storage_path)
+	suite.cleanupTest()
+	suite.setupTestHelper(config)
+
+	path := "file"
+	err := os.WriteFile(filepath.Join(suite.fake_storage_path, path), make([]byte, 2*MB), 0777)
+	suite.assert.NoError(err)
+
+	handle, err := suite.fileCache.OpenFile(internal.OpenFileOptions{Name: path, Flags: os.O_RDWR, Mode: 0777})
+	suite.assert.NoError(err)
+	_, err = suite.fileCache.WriteFile(&internal.WriteFileOptions{Handle: handle, Offset: 0, Data: []byte("new data")})
+	suite.assert.NoError(err)
+
+	// Another writer replaces the blob, committed blocks no longer match the local copy
+	err = os.WriteFile(filepath.Join(suite.fake_storage_path, path), []byte("other writer"), 0777)
+	suite.assert.NoError(err)
+
+	err = suite.fileCache.FlushFile(internal.FlushFileOptions{Handle: handle})
+	suite.assert.NoError(err)
+
+	expected := make([]byte, 2*MB)
+	copy(expected, "new data")
+	stored, err := os.ReadFile(filepath.Join(suite.fake_storage_path, path))
+	suite.assert.NoError(err)
+	suite.assert.Equal(expected, stored)
+
+	err = suite.fileCache.ReleaseFile(internal.ReleaseFileOptions{Handle: handle})
+	suite.assert.NoError(err)
+}
+
+func (suite *fileCacheTestSuite) TestDiffUploadEmptyFirstBlock() {
+	defer suite.cleanupTest()
+	config := fmt.Sprintf("file_cache:\n  path: %s\n  offload-io: true\n  timeout-sec: 3600\n  differential-upload: true\n\nloopbackfs:\n  path: %s",
+		suite.cache_path, suite.fake_storage_path)
+	suite.cleanupTest()
+	suite.setupTestHelper(config)
+
+	// Other tools may commit an empty block ahead of the data
+	path := "file"
+	err := suite.loopback.StageData(internal.StageDataOptions{Name: path, Id: "empty", Data: []byte{}})
+	suite.assert.NoError(err)
+	err = suite.loopback.StageData(internal.StageDataOptions{Name: path, Id: "data", Data: make([]byte, 2*MB)})
+	suite.assert.NoError(err)
+	err = suite.loopback.CommitData(internal.CommitDataOptions{Name: path, List: []string{"empty", "data"}})
+	suite.assert.NoError(err)
+
+	handle, err := suite.fileCache.OpenFile(internal.OpenFileOptions{Name: path, Flags: os.O_RDWR, Mode: 0777})
+	suite.assert.NoError(err)
+	_, err = suite.fileCache.WriteFile(&internal.WriteFileOptions{Handle: handle, Offset: MB, Data: []byte("new data")})
+	suite.assert.NoError(err)
+
+	// Differential upload is not possible so the whole file is uploaded
+	err = suite.fileCache.FlushFile(internal.FlushFileOptions{Handle: handle})
+	suite.assert.NoError(err)
+
+	expected := make([]byte, 2*MB)
+	copy(expected[MB:], "new data")
+	stored, err := os.ReadFile(filepath.Join(suite.fake_storage_path, path))
+	suite.assert.NoError(err)
+	suite.assert.Equal(expected, stored)
+
+	err = suite.fileCache.ReleaseFile(internal.ReleaseFileOptions{Handle: handle})
+	suite.assert.NoError(err)
+}
+
 func TestFileCacheTestSuite(t *testing.T) {
 	suite.Run(t, new(fileCacheTestSuite))
 }
@@ -0,0 +1,68 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package file_cache
+
+// Files smaller than this are ranked as if they were of this size
+const gdsfMinSize = 4096
+
+// gdsfRanker implements Greedy Dual Size Frequency. A file is ranked by its references per MB plus an inflation value,
+// which is raised to the rank of every evicted file so that files not referenced for long age out eventually.
+// Small frequently referenced files are kept over large files used once.
+type gdsfRanker struct {
+	inflation float64
+}
+
+func NewGDSFPolicy(cfg cachePolicyConfig) cachePolicy {
+	return newRankedPolicy(cfg, &gdsfRanker{}, true)
+}
+
+func (r *gdsfRanker) name() string {
+	return "gdsf"
+}
+
+func (r *gdsfRanker) touch(node *rankedNode) {
+	size := max(node.size, gdsfMinSize)
+	node.priority = r.inflation + float64(node.hits)*MB/float64(size)
+}
+
+func (r *gdsfRanker) less(a, b *rankedNode) bool {
+	if a.priority != b.priority {
+		return a.priority < b.priority
+	}
+	return a.lastAccess.Before(b.lastAccess)
+}
+
+func (r *gdsfRanker) evicted(node *rankedNode) {
+	r.inflation = max(r.inflation, node.priority)
+}
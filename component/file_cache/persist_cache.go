@@ -0,0 +1,259 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package file_cache
+
+import (
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Azure/azure-storage-fuse/v2/common/log"
+	"github.com/Azure/azure-storage-fuse/v2/internal"
+	"github.com/Azure/azure-storage-fuse/v2/internal/stats_manager"
+)
+
+const (
+	// cacheIndexFile is written inside the cache directory on unmount when persist-cache is enabled
+	cacheIndexFile    = ".blobfuse2_cache_index.json"
+	cacheIndexVersion = 1
+	cacheAdoptWorkers = 16
+)
+
+// cacheIndexEntry describes one file left in the local cache at unmount
+type cacheIndexEntry struct {
+	Path       string    `json:"path"`
+	ETag       string    `json:"etag"`
+	Size       int64     `json:"size"`
+	Mtime      time.Time `json:"mtime"`
+	LastAccess time.Time `json:"last_access"`
+}
+
+type cacheIndex struct {
+	Version int               `json:"version"`
+	Entries []cacheIndexEntry `json:"entries"`
+}
+
+// trackETags tells whether ETags of cached files are needed for the index, to detect conflicts on recovery
+// or to know that the committed blocks still match the local copy
+func (fc *FileCache) trackETags() bool {
+	return fc.persistCache || fc.journal != nil || fc.diffUpload
+}
+
+// recordETag remembers the ETag a cached file was downloaded or uploaded with
+func (fc *FileCache) recordETag(name string, attr *internal.ObjAttr) {
+	if !fc.trackETags() || attr == nil || attr.ETag == "" {
+		return
+	}
+	fc.cachedETags.Store(name, attr.ETag)
+}
+
+// forgetETag drops the ETag of a file whose cached copy no longer matches the container
+func (fc *FileCache) forgetETag(name string) {
+	if fc.trackETags() {
+		fc.cachedETags.Delete(name)
+	}
+}
+
+// saveCacheIndex writes the index of files that can be adopted on the next mount
+func (fc *FileCache) saveCacheIndex() error {
+	index := cacheIndex{Version: cacheIndexVersion}
+
+	// Files with changes not in storage are left to the journal
+	pending := make(map[string]bool)
+	if fc.journal != nil {
+		pending = fc.journal.pendingPaths()
+	}
+
+	fc.cachedETags.Range(func(key, value any) bool {
+		name := key.(string)
+		if pending[name] {
+			return true
+		}
+		info, err := os.Stat(filepath.Join(fc.tmpPath, name))
+		if err != nil || !info.Mode().IsRegular() {
+			return true
+		}
+
+		stat := info.Sys().(*syscall.Stat_t)
+		index.Entries = append(index.Entries, cacheIndexEntry{
+			Path:       name,
+			ETag:       value.(string),
+			Size:       info.Size(),
+			Mtime:      info.ModTime(),
+			LastAccess: time.Unix(int64(stat.Atim.Sec), int64(stat.Atim.Nsec)),
+		})
+		return true
+	})
+
+	data, err := json.Marshal(&index)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a crash never leaves a half written index behind
+	indexPath := filepath.Join(fc.tmpPath, cacheIndexFile)
+	err = os.WriteFile(indexPath+".tmp", data, 0600)
+	if err != nil {
+		return err
+	}
+
+	log.Info("FileCache::saveCacheIndex : %d files recorded in %s", len(index.Entries), indexPath)
+	return os.Rename(indexPath+".tmp", indexPath)
+}
+
+// loadCacheIndex reads and removes the index left by the previous mount
+func (fc *FileCache) loadCacheIndex() (*cacheIndex, error) {
+	indexPath := filepath.Join(fc.tmpPath, cacheIndexFile)
+	defer os.Remove(indexPath)
+
+	data, err := os.ReadFile(indexPath)
+	if err != nil {
+		return nil, err
+	}
+
+	index := &cacheIndex{}
+	err = json.Unmarshal(data, index)
+	if err != nil {
+		return nil, err
+	}
+
+	if index.Version != cacheIndexVersion {
+		log.Warn("FileCache::loadCacheIndex : ignoring index with unsupported version %d", index.Version)
+		return &cacheIndex{}, nil
+	}
+
+	return index, nil
+}
+
+// validCacheEntry checks that the local copy is untouched and still matches the blob in the container
+func (fc *FileCache) validCacheEntry(entry *cacheIndexEntry) bool {
+	info, err := os.Stat(filepath.Join(fc.tmpPath, entry.Path))
+	if err != nil || info.Size() != entry.Size || !info.ModTime().Equal(entry.Mtime) {
+		log.Debug("FileCache::validCacheEntry : local copy of %s changed since unmount", entry.Path)
+		return false
+	}
+
+	attr, err := fc.NextComponent().GetAttr(internal.GetAttrOptions{Name: entry.Path})
+	if err != nil {
+		log.Debug("FileCache::validCacheEntry : failed to get attr of %s [%s]", entry.Path, err.Error())
+		return false
+	}
+
+	if attr.ETag != entry.ETag || attr.Size != entry.Size {
+		log.Debug("FileCache::validCacheEntry : %s modified in container [%s : %s]", entry.Path, attr.ETag, entry.ETag)
+		return false
+	}
+
+	return true
+}
+
+// adoptCachedFiles revalidates the files left by the previous mount and hands the valid ones back to the cache policy.
+// Files which are stale or missing from the index are removed from the cache directory.
+func (fc *FileCache) adoptCachedFiles() {
+	index, err := fc.loadCacheIndex()
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Err("FileCache::adoptCachedFiles : failed to load cache index [%s]", err.Error())
+		}
+		index = &cacheIndex{}
+	}
+
+	valid := make([]bool, len(index.Entries))
+	work := make(chan int, len(index.Entries))
+	for i := range index.Entries {
+		work <- i
+	}
+	close(work)
+
+	var wg sync.WaitGroup
+	for range min(cacheAdoptWorkers, len(index.Entries)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				valid[i] = fc.validCacheEntry(&index.Entries[i])
+			}
+		}()
+	}
+	wg.Wait()
+
+	adopted := make(map[string]bool)
+	entries := make([]*cacheIndexEntry, 0, len(index.Entries))
+	for i := range index.Entries {
+		if valid[i] {
+			entries = append(entries, &index.Entries[i])
+			adopted[filepath.Join(fc.tmpPath, index.Entries[i].Path)] = true
+		}
+	}
+
+	// Remove everything the index could not vouch for
+	_ = filepath.WalkDir(fc.tmpPath, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && d.IsDir() && isJournalDir(fc.tmpPath, path) {
+			return filepath.SkipDir
+		}
+		if err != nil || d.IsDir() || adopted[path] {
+			return nil
+		}
+		log.Debug("FileCache::adoptCachedFiles : removing stale cached file %s", path)
+		_ = deleteFile(path)
+		return nil
+	})
+
+	// Feed the policy in order of last access so the eviction order survives the remount
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].LastAccess.Before(entries[j].LastAccess)
+	})
+
+	for _, entry := range entries {
+		localPath := filepath.Join(fc.tmpPath, entry.Path)
+
+		// Reset the times so the last change time reflects the revalidation and the file is not treated as expired
+		err = os.Chtimes(localPath, entry.LastAccess, entry.Mtime)
+		if err != nil {
+			log.Err("FileCache::adoptCachedFiles : failed to change times of %s [%s]", entry.Path, err.Error())
+		}
+
+		fc.cachedETags.Store(entry.Path, entry.ETag)
+		fc.fileLocks.Get(entry.Path).SetDownloadTime()
+		fc.policy.CacheValid(localPath)
+	}
+
+	log.Info("FileCache::adoptCachedFiles : adopted %d of %d files from %s", len(entries), len(index.Entries), fc.tmpPath)
+	fileCacheStatsCollector.UpdateStats(stats_manager.Replace, adoptedFiles, int64(len(entries)))
+}
@@ -0,0 +1,410 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package file_cache
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-storage-fuse/v2/common"
+	"github.com/Azure/azure-storage-fuse/v2/common/log"
+)
+
+// Accesses to a file closer than this to the previous one are part of the same reference, so scanning a large
+// file once does not look like a frequently used file
+const correlatedRefPeriod = 10 * time.Second
+
+type rankedNode struct {
+	name       string
+	hits       uint64    // number of uncorrelated references to the file
+	lastAccess time.Time // time of the last access to the file
+	size       int64     // size of the local file on last access, tracked only for size aware policies
+	priority   float64   // policy specific value, see the ranker
+	deleted    bool
+}
+
+// evictionRanker decides the order in which a rankedPolicy evicts files, all calls are made with the policy locked
+type evictionRanker interface {
+	name() string
+	touch(node *rankedNode)     // file was referenced, hits and lastAccess are already updated
+	less(a, b *rankedNode) bool // whether a shall be evicted before b
+	evicted(node *rankedNode)   // file is being evicted on timeout or cache pressure
+}
+
+// rankedPolicy evicts files in the order given by its ranker. Unlike lruPolicy it does not keep files in access
+// order, victims are picked by sorting the cached files when the timeout or the high threshold is hit.
+type rankedPolicy struct {
+	sync.Mutex
+
+	// wait group for stopping the go-routines gracefully.
+	wg sync.WaitGroup
+
+	cachePolicyConfig
+
+	ranker    evictionRanker
+	trackSize bool
+
+	nodes map[string]*rankedNode
+
+	// Channel to close main channel select loop
+	closeSignal         chan int
+	closeSignalValidate chan int
+
+	// Channel to contain files that needs to be deleted immediately
+	deleteEvent chan string
+
+	// Channel to contain files that are in use so their rank is updated
+	validateChan chan string
+
+	// Channel to check disk usage is within the limits configured or not
+	diskUsageMonitor <-chan time.Time
+
+	// Channel to check for file eviction based on file-cache timeout
+	cacheTimeoutMonitor <-chan time.Time
+
+	// DU utility was found on the path or not
+	duPresent bool
+}
+
+var _ cachePolicy = &rankedPolicy{}
+
+func newRankedPolicy(cfg cachePolicyConfig, ranker evictionRanker, trackSize bool) *rankedPolicy {
+	return &rankedPolicy{
+		cachePolicyConfig: cfg,
+		ranker:            ranker,
+		trackSize:         trackSize,
+		nodes:             make(map[string]*rankedNode),
+	}
+}
+
+func (p *rankedPolicy) StartPolicy() error {
+	log.Trace("%sPolicy::StartPolicy", p.Name())
+
+	p.closeSignal = make(chan int)
+	p.closeSignalValidate = make(chan int)
+
+	p.deleteEvent = make(chan string, 1000)
+	p.validateChan = make(chan string, 10000)
+
+	_, err := common.GetUsage(p.tmpPath)
+	if err == nil {
+		p.duPresent = true
+	} else {
+		log.Err("%sPolicy::StartPolicy : 'du' command not found, disabling disk usage checks", p.Name())
+	}
+
+	if p.duPresent {
+		p.diskUsageMonitor = time.Tick(time.Duration(DiskUsageCheckInterval * time.Minute))
+	}
+
+	// If timeout is zero files are deleted on invalidate so there is nothing to expire
+	log.Info("%sPolicy::StartPolicy : Policy set with %v timeout", p.Name(), p.cacheTimeout)
+
+	if p.cacheTimeout != 0 {
+		p.cacheTimeoutMonitor = time.Tick(time.Duration(time.Duration(p.cacheTimeout) * time.Second))
+	}
+
+	p.wg.Add(2)
+	go p.clearCache()
+	go p.asyncCacheValid()
+
+	return nil
+}
+
+func (p *rankedPolicy) ShutdownPolicy() error {
+	log.Trace("%sPolicy::ShutdownPolicy", p.Name())
+	p.closeSignal <- 1
+	p.closeSignalValidate <- 1
+	// wait for all go-routines to stop.
+	p.wg.Wait()
+	return nil
+}
+
+func (p *rankedPolicy) UpdateConfig(c cachePolicyConfig) error {
+	log.Trace("%sPolicy::UpdateConfig", p.Name())
+	p.Lock()
+	defer p.Unlock()
+
+	p.maxSizeMB = c.maxSizeMB
+	p.highThreshold = c.highThreshold
+	p.lowThreshold = c.lowThreshold
+	p.maxEviction = c.maxEviction
+	p.policyTrace = c.policyTrace
+	return nil
+}
+
+func (p *rankedPolicy) CacheValid(name string) {
+	p.Lock()
+	_, found := p.nodes[name]
+	p.Unlock()
+
+	if !found {
+		p.cacheValidate(name)
+	} else {
+		p.validateChan <- name
+	}
+}
+
+func (p *rankedPolicy) CacheInvalidate(name string) {
+	log.Trace("%sPolicy::CacheInvalidate : %s", p.Name(), name)
+
+	// Same as lru, when the file is no longer tracked the last handle is being closed so try deleting it again
+	p.Lock()
+	_, found := p.nodes[name]
+	p.Unlock()
+
+	if p.cacheTimeout == 0 || !found {
+		p.CachePurge(name)
+	}
+}
+
+func (p *rankedPolicy) CachePurge(name string) {
+	log.Trace("%sPolicy::CachePurge : %s", p.Name(), name)
+
+	p.removeNode(name)
+	p.deleteEvent <- name
+}
+
+func (p *rankedPolicy) IsCached(name string) bool {
+	p.Lock()
+	defer p.Unlock()
+
+	node, found := p.nodes[name]
+	return found && !node.deleted
+}
+
+func (p *rankedPolicy) Name() string {
+	return p.ranker.name()
+}
+
+// On validate name of the file was pushed on this channel so now update its rank
+func (p *rankedPolicy) asyncCacheValid() {
+	defer p.wg.Done()
+	for {
+		select {
+		case name := <-p.validateChan:
+			p.cacheValidate(name)
+
+		case <-p.closeSignalValidate:
+			return
+		}
+	}
+}
+
+func (p *rankedPolicy) cacheValidate(name string) {
+	size := int64(0)
+	if p.trackSize {
+		if info, err := os.Stat(name); err == nil {
+			size = info.Size()
+		}
+	}
+
+	now := time.Now()
+
+	p.Lock()
+	defer p.Unlock()
+
+	node, found := p.nodes[name]
+	if !found {
+		node = &rankedNode{name: name}
+		p.nodes[name] = node
+	}
+
+	if node.hits == 0 || now.Sub(node.lastAccess) > correlatedRefPeriod {
+		node.hits++
+	}
+	node.lastAccess = now
+	node.size = size
+	node.deleted = false
+
+	p.ranker.touch(node)
+}
+
+// For all other timer based activities we check the stuff here
+func (p *rankedPolicy) clearCache() {
+	log.Trace("%sPolicy::ClearCache", p.Name())
+	defer p.wg.Done()
+
+	for {
+		select {
+		case name := <-p.deleteEvent:
+			// we are asked to delete file explicitly
+			p.deleteItem(name)
+
+		case <-p.cacheTimeoutMonitor:
+			// File cache timeout has hit so delete all files unused for the timeout
+			p.printNodes()
+			p.evictNodes(true)
+
+		case <-p.diskUsageMonitor:
+			cleanupCount := 0
+			pUsage := getUsagePercentage(p.tmpPath, p.maxSizeMB)
+			if pUsage > p.highThreshold {
+				continueDeletion := true
+				for continueDeletion {
+					log.Info("%sPolicy::ClearCache : High threshold reached %f > %f", p.Name(), pUsage, p.highThreshold)
+
+					cleanupCount++
+					p.printNodes()
+					p.evictNodes(false)
+
+					pUsage := getUsagePercentage(p.tmpPath, p.maxSizeMB)
+					if pUsage < p.lowThreshold || cleanupCount >= 3 {
+						log.Info("%sPolicy::ClearCache : Threshold stabilized %f > %f", p.Name(), pUsage, p.lowThreshold)
+						continueDeletion = false
+					}
+				}
+			}
+
+		case <-p.closeSignal:
+			return
+		}
+	}
+}
+
+// rankedNodes : Files in the order they shall be evicted, only the ones unused for the timeout if expiredOnly is set
+func (p *rankedPolicy) rankedNodes(expiredOnly bool) []*rankedNode {
+	now := time.Now()
+	timeout := time.Duration(p.cacheTimeout) * time.Second
+
+	nodes := make([]*rankedNode, 0, len(p.nodes))
+	for _, node := range p.nodes {
+		if node.deleted || (expiredOnly && now.Sub(node.lastAccess) < timeout) {
+			continue
+		}
+		nodes = append(nodes, node)
+	}
+
+	sort.Slice(nodes, func(i, j int) bool {
+		return p.ranker.less(nodes[i], nodes[j])
+	})
+
+	return nodes
+}
+
+// evictNodes : Delete up to max-eviction files with the lowest rank
+func (p *rankedPolicy) evictNodes(expiredOnly bool) {
+	log.Debug("%sPolicy::evictNodes : Starts", p.Name())
+
+	p.Lock()
+	delItems := p.rankedNodes(expiredOnly)
+	if uint32(len(delItems)) > p.maxEviction {
+		log.Debug("%sPolicy::evictNodes : Max deletion count hit", p.Name())
+		delItems = delItems[:p.maxEviction]
+	}
+
+	for _, node := range delItems {
+		node.deleted = true
+		p.ranker.evicted(node)
+	}
+	p.Unlock()
+
+	log.Debug("%sPolicy::evictNodes : List generated %d items", p.Name(), len(delItems))
+
+	for _, item := range delItems {
+		p.deleteItem(item.name)
+	}
+
+	log.Debug("%sPolicy::evictNodes : Ends", p.Name())
+}
+
+func (p *rankedPolicy) removeNode(name string) {
+	p.Lock()
+	defer p.Unlock()
+
+	if node, found := p.nodes[name]; found {
+		node.deleted = true
+		delete(p.nodes, name)
+	}
+}
+
+func (p *rankedPolicy) deleteItem(name string) {
+	log.Trace("%sPolicy::deleteItem : Deleting %s", p.Name(), name)
+
+	azPath := strings.TrimPrefix(name, p.tmpPath)
+	if azPath == "" {
+		log.Err("%sPolicy::DeleteItem : Empty file name formed name : %s, tmpPath : %s", p.Name(), name, p.tmpPath)
+		return
+	}
+
+	if azPath[0] == '/' {
+		azPath = azPath[1:]
+	}
+
+	// A file in use stays cached and keeps its history
+	flock := p.fileLocks.Get(azPath)
+	if p.fileLocks.Locked(azPath) {
+		log.Warn("%sPolicy::DeleteItem : File in under download %s", p.Name(), azPath)
+		p.CacheValid(name)
+		return
+	}
+
+	flock.Lock()
+	defer flock.Unlock()
+
+	// Check if there are any open handles to this file or not
+	if flock.Count() > 0 {
+		log.Warn("%sPolicy::DeleteItem : File in use %s", p.Name(), name)
+		p.CacheValid(name)
+		return
+	}
+
+	p.removeNode(name)
+
+	// There are no open handles for this file so its safe to remove this
+	err := deleteFile(name)
+	if err != nil && !os.IsNotExist(err) {
+		log.Err("%sPolicy::DeleteItem : failed to delete local file %s [%s]", p.Name(), name, err.Error())
+	}
+}
+
+func (p *rankedPolicy) printNodes() {
+	if !p.policyTrace {
+		return
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	log.Debug("%sPolicy::printNodes : Starts", p.Name())
+
+	for count, node := range p.rankedNodes(false) {
+		log.Debug(" ==> (%d) %s hits %d, size %d, priority %f", count, node.name, node.hits, node.size, node.priority)
+	}
+
+	log.Debug("%sPolicy::printNodes : Ends", p.Name())
+}
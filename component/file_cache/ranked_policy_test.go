@@ -0,0 +1,287 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package file_cache
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-storage-fuse/v2/common"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type rankedPolicyTestSuite struct {
+	suite.Suite
+	assert *assert.Assertions
+	policy *rankedPolicy
+}
+
+func (suite *rankedPolicyTestSuite) SetupTest() {
+	suite.assert = assert.New(suite.T())
+
+	err := os.Mkdir(cache_path, fs.FileMode(0777))
+	suite.assert.NoError(err)
+}
+
+func (suite *rankedPolicyTestSuite) setupTestHelper(policy string, config cachePolicyConfig) {
+	p, err := NewCachePolicy(policy, config)
+	suite.assert.NoError(err)
+	suite.policy = p.(*rankedPolicy)
+
+	err = suite.policy.StartPolicy()
+	suite.assert.NoError(err)
+}
+
+func (suite *rankedPolicyTestSuite) cleanupTest() {
+	err := suite.policy.ShutdownPolicy()
+	suite.assert.NoError(err)
+
+	os.RemoveAll(cache_path)
+}
+
+func defaultRankedConfig() cachePolicyConfig {
+	return cachePolicyConfig{
+		tmpPath:       cache_path,
+		cacheTimeout:  0,
+		maxEviction:   defaultMaxEviction,
+		maxSizeMB:     0,
+		highThreshold: defaultMaxThreshold,
+		lowThreshold:  defaultMinThreshold,
+		fileLocks:     &common.LockMap{},
+	}
+}
+
+// createCachedFile : Create a local file of given size and let the policy track it
+func (suite *rankedPolicyTestSuite) createCachedFile(name string, size int) string {
+	path := filepath.Join(cache_path, name)
+	err := os.WriteFile(path, make([]byte, size), 0777)
+	suite.assert.NoError(err)
+
+	suite.policy.cacheValidate(path)
+	return path
+}
+
+// reference : Access the file again after the correlated reference period
+func (suite *rankedPolicyTestSuite) reference(path string) {
+	suite.policy.Lock()
+	suite.policy.nodes[path].lastAccess = time.Now().Add(-2 * correlatedRefPeriod)
+	suite.policy.Unlock()
+
+	suite.policy.cacheValidate(path)
+}
+
+func (suite *rankedPolicyTestSuite) evictionOrder() []string {
+	suite.policy.Lock()
+	defer suite.policy.Unlock()
+
+	names := make([]string, 0)
+	for _, node := range suite.policy.rankedNodes(false) {
+		names = append(names, filepath.Base(node.name))
+	}
+	return names
+}
+
+func (suite *rankedPolicyTestSuite) TestUpdateConfig() {
+	suite.setupTestHelper("lfu", defaultRankedConfig())
+	defer suite.cleanupTest()
+
+	config := defaultRankedConfig()
+	config.cacheTimeout = 120
+	config.maxEviction = 100
+	config.maxSizeMB = 10
+	config.highThreshold = 70
+	config.lowThreshold = 20
+	err := suite.policy.UpdateConfig(config)
+	suite.assert.NoError(err)
+
+	suite.assert.EqualValues(0, suite.policy.cacheTimeout) // cacheTimeout does not change
+	suite.assert.EqualValues(100, suite.policy.maxEviction)
+	suite.assert.Equal(10, int(suite.policy.maxSizeMB))
+	suite.assert.Equal(70, int(suite.policy.highThreshold))
+	suite.assert.Equal(20, int(suite.policy.lowThreshold))
+}
+
+func (suite *rankedPolicyTestSuite) TestCacheValidAndPurge() {
+	suite.setupTestHelper("2q", defaultRankedConfig())
+	defer suite.cleanupTest()
+
+	suite.assert.False(suite.policy.IsCached("temp"))
+	suite.policy.CacheValid("temp")
+	suite.assert.True(suite.policy.IsCached("temp"))
+
+	// Accesses close to each other are one reference
+	suite.policy.CacheValid("temp")
+	suite.policy.cacheValidate("temp")
+	suite.assert.EqualValues(1, suite.policy.nodes["temp"].hits)
+
+	suite.policy.CachePurge("temp")
+	suite.assert.False(suite.policy.IsCached("temp"))
+}
+
+func (suite *rankedPolicyTestSuite) TestCacheInvalidate() {
+	suite.setupTestHelper("gdsf", defaultRankedConfig())
+	defer suite.cleanupTest()
+
+	path := suite.createCachedFile("temp", 10)
+	suite.policy.CacheInvalidate(path) // this is equivalent to purge since timeout=0
+	suite.assert.False(suite.policy.IsCached(path))
+
+	time.Sleep(100 * time.Millisecond)
+	_, err := os.Stat(path)
+	suite.assert.True(os.IsNotExist(err))
+}
+
+func (suite *rankedPolicyTestSuite) TestLFUOrder() {
+	suite.setupTestHelper("lfu", defaultRankedConfig())
+	defer suite.cleanupTest()
+
+	hot := suite.createCachedFile("hot", 10)
+	suite.createCachedFile("old", 10)
+	suite.createCachedFile("new", 10)
+	suite.reference(hot)
+	suite.reference(hot)
+
+	suite.assert.Equal([]string{"old", "new", "hot"}, suite.evictionOrder())
+}
+
+func (suite *rankedPolicyTestSuite) Test2QScanResistance() {
+	suite.setupTestHelper("2q", defaultRankedConfig())
+	defer suite.cleanupTest()
+
+	hot := suite.createCachedFile("hot", 10)
+	suite.reference(hot)
+
+	// Files scanned once after the hot file was used are still evicted first
+	for i := range 3 {
+		suite.createCachedFile(fmt.Sprintf("scan%d", i), 10)
+	}
+	suite.assert.Equal([]string{"scan0", "scan1", "scan2", "hot"}, suite.evictionOrder())
+
+	// An evicted scan file is protected if it comes back
+	config := defaultRankedConfig()
+	config.maxEviction = 1
+	_ = suite.policy.UpdateConfig(config)
+	suite.policy.evictNodes(false)
+	suite.assert.False(suite.policy.IsCached(filepath.Join(cache_path, "scan0")))
+
+	suite.createCachedFile("scan0", 10)
+	suite.assert.Equal([]string{"scan1", "scan2", "hot", "scan0"}, suite.evictionOrder())
+}
+
+func (suite *rankedPolicyTestSuite) TestGDSFOrder() {
+	suite.setupTestHelper("gdsf", defaultRankedConfig())
+	defer suite.cleanupTest()
+
+	large := suite.createCachedFile("large", 4*int(MB))
+	suite.createCachedFile("small", int(MB))
+	medium := suite.createCachedFile("medium", 2*int(MB))
+	suite.assert.Equal([]string{"large", "medium", "small"}, suite.evictionOrder())
+
+	// More references make up for a larger size
+	suite.reference(medium)
+	suite.reference(medium)
+	suite.assert.Equal([]string{"large", "small", "medium"}, suite.evictionOrder())
+
+	// Evicting raises the rank of files referenced afterwards
+	config := defaultRankedConfig()
+	config.maxEviction = 1
+	_ = suite.policy.UpdateConfig(config)
+	suite.policy.evictNodes(false)
+	suite.assert.False(suite.policy.IsCached(large))
+	suite.assert.InDelta(0.25, suite.policy.ranker.(*gdsfRanker).inflation, 0.001)
+
+	suite.createCachedFile("large", 4*int(MB))
+	suite.assert.InDelta(0.5, suite.policy.nodes[large].priority, 0.001)
+}
+
+func (suite *rankedPolicyTestSuite) TestFileInUse() {
+	suite.setupTestHelper("lfu", defaultRankedConfig())
+	defer suite.cleanupTest()
+
+	path := suite.createCachedFile("inuse", 10)
+	suite.reference(path)
+
+	flock := suite.policy.fileLocks.Get("inuse")
+	flock.Inc()
+	defer flock.Dec()
+
+	suite.policy.evictNodes(false)
+	time.Sleep(100 * time.Millisecond)
+
+	suite.assert.True(suite.policy.IsCached(path))
+	suite.assert.EqualValues(2, suite.policy.nodes[path].hits)
+	_, err := os.Stat(path)
+	suite.assert.NoError(err)
+}
+
+func (suite *rankedPolicyTestSuite) TestTimeout() {
+	config := defaultRankedConfig()
+	config.cacheTimeout = 1
+	suite.setupTestHelper("2q", config)
+	defer suite.cleanupTest()
+
+	for i := range 10 {
+		suite.policy.CacheValid(fmt.Sprintf("temp%d", i))
+	}
+
+	time.Sleep(5 * time.Second) // Wait for time > cacheTimeout, the files should no longer be cached
+
+	for i := range 10 {
+		suite.assert.False(suite.policy.IsCached(fmt.Sprintf("temp%d", i)))
+	}
+}
+
+func (suite *rankedPolicyTestSuite) TestMaxEviction() {
+	config := defaultRankedConfig()
+	config.maxEviction = 2
+	suite.setupTestHelper("lfu", config)
+	defer suite.cleanupTest()
+
+	for i := range 5 {
+		suite.createCachedFile(fmt.Sprintf("temp%d", i), 10)
+	}
+
+	suite.policy.evictNodes(false)
+	suite.assert.Len(suite.evictionOrder(), 3)
+}
+
+func TestRankedPolicyTestSuite(t *testing.T) {
+	suite.Run(t, new(rankedPolicyTestSuite))
+}
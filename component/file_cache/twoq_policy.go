@@ -0,0 +1,102 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package file_cache
+
+import (
+	"container/list"
+)
+
+// Number of evicted files remembered by 2q so that they are protected when referenced again
+const twoQMaxGhosts = 10000
+
+const (
+	twoQProbation = 0 // referenced once, evicted first
+	twoQProtected = 1 // referenced again, or referenced soon after being evicted
+)
+
+// twoQRanker keeps files referenced only once in a probation queue which is evicted before the protected queue, so a
+// scan of many files used once does not push out the hot working set. Evicted probation files are remembered as
+// ghosts and go straight to the protected queue when they come back.
+type twoQRanker struct {
+	ghosts    map[string]*list.Element
+	ghostList *list.List
+}
+
+func New2QPolicy(cfg cachePolicyConfig) cachePolicy {
+	return newRankedPolicy(cfg, &twoQRanker{
+		ghosts:    make(map[string]*list.Element),
+		ghostList: list.New(),
+	}, false)
+}
+
+func (r *twoQRanker) name() string {
+	return "2q"
+}
+
+func (r *twoQRanker) touch(node *rankedNode) {
+	if elem, ok := r.ghosts[node.name]; ok {
+		r.ghostList.Remove(elem)
+		delete(r.ghosts, node.name)
+		node.priority = twoQProtected
+		return
+	}
+
+	if node.hits > 1 {
+		node.priority = twoQProtected
+	}
+}
+
+func (r *twoQRanker) less(a, b *rankedNode) bool {
+	if a.priority != b.priority {
+		return a.priority < b.priority
+	}
+	return a.lastAccess.Before(b.lastAccess)
+}
+
+func (r *twoQRanker) evicted(node *rankedNode) {
+	if node.priority != twoQProbation {
+		return
+	}
+
+	if _, ok := r.ghosts[node.name]; ok {
+		return
+	}
+
+	r.ghosts[node.name] = r.ghostList.PushBack(node.name)
+	if r.ghostList.Len() > twoQMaxGhosts {
+		oldest := r.ghostList.Front()
+		r.ghostList.Remove(oldest)
+		delete(r.ghosts, oldest.Value.(string))
+	}
+}
@@ -0,0 +1,333 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package file_cache
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/Azure/azure-storage-fuse/v2/common"
+	"github.com/Azure/azure-storage-fuse/v2/common/log"
+	"github.com/Azure/azure-storage-fuse/v2/internal"
+	"github.com/Azure/azure-storage-fuse/v2/internal/handlemap"
+	"github.com/Azure/azure-storage-fuse/v2/internal/stats_manager"
+)
+
+const (
+	defaultUploadWorkers = 8
+	defaultUploadRetries = 3
+	uploadRetryBackoff   = time.Second
+	uploadRequeueDelay   = time.Minute
+
+	asyncReleaseKey = "asyncRelease"
+)
+
+// uploadItem is a closed handle whose changes are waiting to be uploaded
+type uploadItem struct {
+	options internal.ReleaseFileOptions
+	flock   *common.LockMapItem
+	queued  time.Time
+}
+
+// asyncRelease tracks a handle whose close completes in background, the cross node lock of the handle
+// is held till then so no other node changes the blob before the upload is over
+type asyncRelease struct {
+	sync.Mutex
+	done     bool
+	unlocked bool // UnlockFile was called before the close completed
+}
+
+// uploadQueue uploads closed dirty handles in background with a bounded number of workers
+type uploadQueue struct {
+	workers uint32
+	retries uint32
+	items   chan *uploadItem
+	wg      sync.WaitGroup
+	depth   atomic.Int64
+
+	closeLock sync.RWMutex
+	stopped   bool
+
+	waitLock     sync.Mutex
+	waiting      map[*uploadItem]*time.Timer // Failed uploads waiting to be queued again
+	requeueDelay time.Duration
+
+	// Method which uploads the handle and returns error if it has to be retried
+	upload func(*uploadItem) error
+
+	// Method which closes the handle, err is set when retries are exhausted and changes are not uploaded.
+	// In that case the file stays pinned in the cache till the handle is uploaded or dropped.
+	release func(item *uploadItem, err error)
+
+	// Method which opens the handle again so its upload can be retried
+	reopen func(*uploadItem) error
+
+	// Method which gives up on the handle and unpins the file, changes which are not uploaded stay in the journal
+	drop func(*uploadItem)
+}
+
+func newUploadQueue(workers uint32, retries uint32, upload func(*uploadItem) error, release func(*uploadItem, error),
+	reopen func(*uploadItem) error, drop func(*uploadItem)) *uploadQueue {
+	return &uploadQueue{
+		workers:      workers,
+		retries:      retries,
+		items:        make(chan *uploadItem, workers*1000),
+		waiting:      make(map[*uploadItem]*time.Timer),
+		requeueDelay: uploadRequeueDelay,
+		upload:       upload,
+		release:      release,
+		reopen:       reopen,
+		drop:         drop,
+	}
+}
+
+// start the workers
+func (q *uploadQueue) start() {
+	for range q.workers {
+		q.wg.Add(1)
+		go q.do()
+	}
+}
+
+// stop the workers once all queued items are processed, uploads waiting to be retried are given up
+func (q *uploadQueue) stop() {
+	q.closeLock.Lock()
+	q.stopped = true
+	close(q.items)
+	q.closeLock.Unlock()
+
+	q.wg.Wait()
+
+	q.waitLock.Lock()
+	defer q.waitLock.Unlock()
+	for item, timer := range q.waiting {
+		if timer.Stop() {
+			q.drop(item)
+		}
+		delete(q.waiting, item)
+	}
+}
+
+// schedule the upload of a closed handle, blocks when the queue is full. Returns false once the queue is stopped.
+func (q *uploadQueue) schedule(item *uploadItem) bool {
+	q.closeLock.RLock()
+	defer q.closeLock.RUnlock()
+
+	if q.stopped {
+		return false
+	}
+
+	item.queued = time.Now()
+	depth := q.depth.Add(1)
+	fileCacheStatsCollector.UpdateStats(stats_manager.Replace, uploadQueueDepth, depth)
+
+	q.items <- item
+	return true
+}
+
+// requeue a handle whose retries are exhausted, it goes back to the queue once the requeue delay is over
+func (q *uploadQueue) requeue(item *uploadItem) {
+	q.waitLock.Lock()
+	defer q.waitLock.Unlock()
+
+	q.waiting[item] = time.AfterFunc(q.requeueDelay, func() {
+		q.waitLock.Lock()
+		_, found := q.waiting[item]
+		delete(q.waiting, item)
+		q.waitLock.Unlock()
+
+		if !found {
+			// Queue is stopped and has given up on it already
+			return
+		}
+
+		path := item.options.Handle.Path
+		err := q.reopen(item)
+		if err != nil {
+			log.Err("uploadQueue::requeue : failed to reopen %s, giving up its upload [%s]", path, err.Error())
+			q.drop(item)
+			return
+		}
+
+		if !q.schedule(item) {
+			log.Warn("uploadQueue::requeue : queue is stopped, giving up upload of %s", path)
+			q.release(item, syscall.ECANCELED)
+			q.drop(item)
+		}
+	})
+}
+
+func (q *uploadQueue) do() {
+	defer q.wg.Done()
+
+	for item := range q.items {
+		path := item.options.Handle.Path
+
+		err := q.upload(item)
+		for attempt := uint32(1); err != nil && attempt <= q.retries; attempt++ {
+			log.Warn("uploadQueue::do : upload of %s failed, retry %d of %d [%s]", path, attempt, q.retries, err.Error())
+			fileCacheStatsCollector.UpdateStats(stats_manager.Increment, uploadRetries, int64(1))
+			time.Sleep(uploadRetryBackoff * time.Duration(attempt))
+			err = q.upload(item)
+		}
+
+		if err != nil {
+			log.Err("uploadQueue::do : upload of %s failed after %d retries, requeued [%s]", path, q.retries, err.Error())
+			fileCacheStatsCollector.UpdateStats(stats_manager.Increment, uploadFailures, int64(1))
+		} else {
+			log.Debug("uploadQueue::do : uploaded %s, %v after close", path, time.Since(item.queued))
+		}
+
+		// Handle is closed either way so no descriptor is held while its upload waits for another try
+		q.release(item, err)
+		if err != nil {
+			q.requeue(item)
+		}
+
+		depth := q.depth.Add(-1)
+		fileCacheStatsCollector.UpdateStats(stats_manager.Replace, uploadQueueDepth, depth)
+	}
+}
+
+// uploadQueued uploads the changes of a closed handle
+func (fc *FileCache) uploadQueued(item *uploadItem) error {
+	return fc.FlushFile(internal.FlushFileOptions{Handle: item.options.Handle, CloseInProgress: true}) //nolint
+}
+
+// releaseQueued closes a handle taken out of the upload queue, changes which could not be uploaded stay dirty and journaled
+func (fc *FileCache) releaseQueued(item *uploadItem, err error) {
+	if err == nil {
+		_ = fc.releaseFileInternal(item.options, item.flock)
+		fc.endAsyncRelease(item.options.Handle)
+		return
+	}
+
+	defer item.flock.Unlock()
+	defer fc.fileCloseOpt.Done()
+
+	// Local copy is the only one holding the changes, so the open count is kept and eviction leaves the file
+	// alone till the requeued upload is over
+	f := item.options.Handle.GetFileObject()
+	if f == nil {
+		log.Err("FileCache::releaseQueued : error [missing fd in handle object] %s", item.options.Handle.Path)
+		return
+	}
+
+	if cerr := f.Close(); cerr != nil {
+		log.Err("FileCache::releaseQueued : error closing file %s [%s]", item.options.Handle.Path, cerr.Error())
+	}
+}
+
+// reopenQueued opens the local copy of a handle again and locks the file, same as ReleaseFile does before scheduling it.
+// The file is still pinned by releaseQueued so the open count is not raised again.
+func (fc *FileCache) reopenQueued(item *uploadItem) error {
+	handle := item.options.Handle
+	flock := fc.fileLocks.Get(handle.Path)
+	flock.Lock()
+
+	f, err := os.Open(filepath.Join(fc.tmpPath, handle.Path))
+	if err != nil {
+		flock.Unlock()
+		return err
+	}
+
+	handle.SetFileObject(f)
+	handle.UnixFD = uint64(f.Fd())
+	item.flock = flock
+
+	fc.fileCloseOpt.Add(1)
+	return nil
+}
+
+// dropQueued gives up the upload of a handle and unpins the file, the journal keeps its changes for recovery by the next mount
+func (fc *FileCache) dropQueued(item *uploadItem) {
+	log.Err("FileCache::dropQueued : changes of %s are not uploaded", item.options.Handle.Path)
+
+	flock := fc.fileLocks.Get(item.options.Handle.Path)
+	flock.Lock()
+	flock.Dec()
+	fc.policy.CacheValid(filepath.Join(fc.tmpPath, item.options.Handle.Path))
+	flock.Unlock()
+
+	fc.endAsyncRelease(item.options.Handle)
+}
+
+// beginAsyncRelease marks the handle to be closed in background
+func (fc *FileCache) beginAsyncRelease(handle *handlemap.Handle) {
+	handle.SetValue(asyncReleaseKey, &asyncRelease{})
+}
+
+// endAsyncRelease marks the background close of the handle complete and drops its cross node lock if asked for meanwhile
+func (fc *FileCache) endAsyncRelease(handle *handlemap.Handle) {
+	val, found := handle.GetValue(asyncReleaseKey)
+	if !found {
+		return
+	}
+
+	r := val.(*asyncRelease)
+	r.Lock()
+	r.done = true
+	unlocked := r.unlocked
+	r.Unlock()
+
+	if unlocked {
+		err := fc.NextComponent().UnlockFile(internal.UnlockFileOptions{Handle: handle, Name: handle.Path})
+		if err != nil {
+			log.Err("FileCache::endAsyncRelease : failed to unlock %s [%s]", handle.Path, err.Error())
+		}
+	}
+}
+
+// UnlockFile: Handle closed in background keeps its cross node lock till its upload is over
+func (fc *FileCache) UnlockFile(options internal.UnlockFileOptions) error {
+	if options.Handle != nil {
+		if val, found := options.Handle.GetValue(asyncReleaseKey); found {
+			r := val.(*asyncRelease)
+			r.Lock()
+			defer r.Unlock()
+
+			if !r.done {
+				log.Debug("FileCache::UnlockFile : %s is unlocked once its upload is over", options.Name)
+				r.unlocked = true
+				return nil
+			}
+		}
+	}
+
+	return fc.NextComponent().UnlockFile(options)
+}
@@ -0,0 +1,412 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package file_cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Azure/azure-storage-fuse/v2/common"
+	"github.com/Azure/azure-storage-fuse/v2/common/log"
+	"github.com/Azure/azure-storage-fuse/v2/internal"
+	"github.com/Azure/azure-storage-fuse/v2/internal/handlemap"
+	"github.com/Azure/azure-storage-fuse/v2/internal/stats_manager"
+)
+
+const (
+	// journalDir is kept inside the cache directory and holds one record per file with changes not yet in storage
+	journalDir           = ".blobfuse2_journal"
+	journalPendingDir    = "pending"
+	journalQuarantineDir = "quarantine"
+
+	JournalStatePending     = "pending"
+	JournalStateQuarantined = "quarantined"
+
+	journalRecoveryUpload     = "upload"
+	journalRecoveryQuarantine = "quarantine"
+)
+
+// JournalEntry describes a file whose local changes were not uploaded to storage
+type JournalEntry struct {
+	Path       string    `json:"path"`
+	ETag       string    `json:"etag,omitempty"`
+	New        bool      `json:"new,omitempty"`
+	DirtySince time.Time `json:"dirty_since"`
+	State      string    `json:"state"`
+	Reason     string    `json:"reason,omitempty"`
+	DataPath   string    `json:"data_path,omitempty"`
+}
+
+// writeJournal tracks the dirty handles of each file and keeps a record on disk while any of them is dirty
+type writeJournal struct {
+	sync.Mutex
+	dir   string
+	dirty map[string]map[*handlemap.Handle]bool
+}
+
+func newWriteJournal(tmpPath string) (*writeJournal, error) {
+	j := &writeJournal{
+		dir:   filepath.Join(tmpPath, journalDir),
+		dirty: make(map[string]map[*handlemap.Handle]bool),
+	}
+
+	for _, dir := range []string{journalPendingDir, journalQuarantineDir} {
+		err := os.MkdirAll(filepath.Join(j.dir, dir), 0700)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return j, nil
+}
+
+// isJournalDir checks whether path is the journal directory of the cache at tmpPath
+func isJournalDir(tmpPath string, path string) bool {
+	return path == filepath.Join(tmpPath, journalDir)
+}
+
+// journalRecordName gives a flat file name to the record of a path
+func journalRecordName(name string) string {
+	sum := sha256.Sum256([]byte(name))
+	return hex.EncodeToString(sum[:16])
+}
+
+func writeJournalRecord(path string, entry *JournalEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a crash never leaves a half written record behind
+	err = os.WriteFile(path+".tmp", data, 0600)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(path+".tmp", path)
+}
+
+func (j *writeJournal) pendingRecord(name string) string {
+	return filepath.Join(j.dir, journalPendingDir, journalRecordName(name)+".json")
+}
+
+// add records that the handle has changes which are not yet uploaded
+func (j *writeJournal) add(handle *handlemap.Handle, etag string, isNew bool) {
+	j.Lock()
+	defer j.Unlock()
+
+	handles, found := j.dirty[handle.Path]
+	if !found {
+		handles = make(map[*handlemap.Handle]bool)
+		j.dirty[handle.Path] = handles
+
+		err := writeJournalRecord(j.pendingRecord(handle.Path), &JournalEntry{
+			Path:       handle.Path,
+			ETag:       etag,
+			New:        isNew,
+			DirtySince: time.Now(),
+			State:      JournalStatePending,
+		})
+		if err != nil {
+			log.Err("writeJournal::add : failed to record %s [%s]", handle.Path, err.Error())
+		}
+	}
+
+	handles[handle] = true
+}
+
+// done records that the changes of the handle are uploaded, the record goes once no handle of the file is dirty
+func (j *writeJournal) done(handle *handlemap.Handle) {
+	j.Lock()
+	defer j.Unlock()
+
+	handles, found := j.dirty[handle.Path]
+	if !found {
+		return
+	}
+
+	delete(handles, handle)
+	if len(handles) == 0 {
+		delete(j.dirty, handle.Path)
+		j.removeRecord(handle.Path)
+	}
+}
+
+// drop forgets all changes of a file which is deleted
+func (j *writeJournal) drop(name string) {
+	j.Lock()
+	defer j.Unlock()
+
+	delete(j.dirty, name)
+	j.removeRecord(name)
+}
+
+func (j *writeJournal) removeRecord(name string) {
+	err := os.Remove(j.pendingRecord(name))
+	if err != nil && !os.IsNotExist(err) {
+		log.Err("writeJournal::removeRecord : failed to remove record of %s [%s]", name, err.Error())
+	}
+}
+
+// pendingPaths returns the files which still have changes to upload
+func (j *writeJournal) pendingPaths() map[string]bool {
+	j.Lock()
+	defer j.Unlock()
+
+	pending := make(map[string]bool, len(j.dirty))
+	for name := range j.dirty {
+		pending[name] = true
+	}
+	return pending
+}
+
+// quarantine moves the local copy of a file out of the cache and keeps it along with the reason it was not uploaded
+func (j *writeJournal) quarantine(entry *JournalEntry, localPath string, reason string) error {
+	id := fmt.Sprintf("%s-%d", journalRecordName(entry.Path), time.Now().UnixNano())
+	dataPath := filepath.Join(j.dir, journalQuarantineDir, id+".data")
+
+	err := os.Rename(localPath, dataPath)
+	if err != nil {
+		return err
+	}
+
+	quarantined := *entry
+	quarantined.State = JournalStateQuarantined
+	quarantined.Reason = reason
+	quarantined.DataPath = dataPath
+
+	err = writeJournalRecord(filepath.Join(j.dir, journalQuarantineDir, id+".json"), &quarantined)
+	if err != nil {
+		return err
+	}
+
+	j.removeRecord(entry.Path)
+	return nil
+}
+
+func readJournalRecords(dir string) ([]*JournalEntry, error) {
+	dirents, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*JournalEntry, 0, len(dirents))
+	for _, dirent := range dirents {
+		if !strings.HasSuffix(dirent.Name(), ".json") {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, dirent.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		entry := &JournalEntry{}
+		err = json.Unmarshal(data, entry)
+		if err != nil {
+			log.Warn("readJournalRecords : ignoring corrupt record %s [%s]", dirent.Name(), err.Error())
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// ListJournal returns the pending and quarantined files recorded in the journal of the cache at tmpPath
+func ListJournal(tmpPath string) ([]*JournalEntry, error) {
+	dir := filepath.Join(tmpPath, journalDir)
+	if _, err := os.Stat(dir); err != nil {
+		return nil, err
+	}
+
+	var entries []*JournalEntry
+	for _, sub := range []string{journalPendingDir, journalQuarantineDir} {
+		records, err := readJournalRecords(filepath.Join(dir, sub))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		entries = append(entries, records...)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].DirtySince.Before(entries[j].DirtySince)
+	})
+
+	return entries, nil
+}
+
+// markDirty flags the handle to be uploaded on flush and journals it the first time it gets dirty
+func (fc *FileCache) markDirty(handle *handlemap.Handle, isNew bool) {
+	if handle.Dirty() {
+		return
+	}
+	handle.Flags.Set(handlemap.HandleFlagDirty)
+
+	if fc.journal != nil {
+		etag, _ := fc.cachedETags.Load(handle.Path)
+		etagStr, _ := etag.(string)
+		fc.journal.add(handle, etagStr, isNew)
+	}
+}
+
+// recoverJournal uploads or quarantines the files left dirty by a mount which did not stop cleanly
+func (fc *FileCache) recoverJournal() {
+	entries, err := readJournalRecords(filepath.Join(fc.journal.dir, journalPendingDir))
+	if err != nil {
+		log.Err("FileCache::recoverJournal : failed to read journal [%s]", err.Error())
+		return
+	}
+
+	recovered, quarantined := 0, 0
+	for _, entry := range entries {
+		localPath := filepath.Join(fc.tmpPath, entry.Path)
+		if _, err := os.Stat(localPath); err != nil {
+			log.Warn("FileCache::recoverJournal : local copy of %s is gone, nothing to recover", entry.Path)
+			fc.journal.removeRecord(entry.Path)
+			continue
+		}
+
+		if fc.journalRecovery == journalRecoveryUpload {
+			err = fc.recoverUpload(entry, localPath)
+			if err == nil {
+				log.Info("FileCache::recoverJournal : uploaded %s dirty since %v", entry.Path, entry.DirtySince)
+				fc.journal.removeRecord(entry.Path)
+				_ = deleteFile(localPath)
+				recovered++
+				continue
+			}
+		} else {
+			err = errors.New("recovery policy is quarantine")
+		}
+
+		log.Warn("FileCache::recoverJournal : quarantining %s [%s]", entry.Path, err.Error())
+		qerr := fc.journal.quarantine(entry, localPath, err.Error())
+		if qerr != nil {
+			log.Err("FileCache::recoverJournal : failed to quarantine %s [%s]", entry.Path, qerr.Error())
+			continue
+		}
+		quarantined++
+	}
+
+	log.Info("FileCache::recoverJournal : %d files uploaded, %d quarantined out of %d", recovered, quarantined, len(entries))
+	fileCacheStatsCollector.UpdateStats(stats_manager.Replace, recoveredFiles, int64(recovered))
+	fileCacheStatsCollector.UpdateStats(stats_manager.Replace, quarantinedFiles, int64(quarantined))
+}
+
+// recoverUpload uploads the local copy of a journaled file unless the blob changed since the copy was taken
+func (fc *FileCache) recoverUpload(entry *JournalEntry, localPath string) error {
+	attr, err := fc.NextComponent().GetAttr(internal.GetAttrOptions{Name: entry.Path})
+	exists := err == nil
+	if err != nil && err != syscall.ENOENT && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to get attributes [%s]", err.Error())
+	}
+
+	switch {
+	case exists && entry.New:
+		return errors.New("blob was created in container by another writer")
+	case exists && entry.ETag != "" && attr.ETag != entry.ETag:
+		return fmt.Errorf("blob was modified in container [%s : %s]", attr.ETag, entry.ETag)
+	case !exists && entry.ETag != "":
+		return errors.New("blob was deleted from container")
+	}
+
+	f, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var metadata map[string]*string
+	if exists {
+		metadata = attr.Metadata
+	}
+
+	err = fc.NextComponent().CopyFromFile(internal.CopyFromFileOptions{Name: entry.Path, File: f, Metadata: metadata})
+	if err != nil {
+		return fmt.Errorf("upload failed [%s]", err.Error())
+	}
+
+	return nil
+}
+
+// cleanupCache removes the cached files on unmount, except the journal and the files it still holds for upload
+func (fc *FileCache) cleanupCache() {
+	if fc.journal == nil {
+		_ = common.TempCacheCleanup(fc.tmpPath)
+		return
+	}
+
+	pending := fc.journal.pendingPaths()
+	var dirs []string
+
+	_ = filepath.WalkDir(fc.tmpPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || path == fc.tmpPath {
+			return nil
+		}
+		if d.IsDir() {
+			if isJournalDir(fc.tmpPath, path) {
+				return filepath.SkipDir
+			}
+			dirs = append(dirs, path)
+			return nil
+		}
+
+		name, _ := filepath.Rel(fc.tmpPath, path)
+		if !pending[name] {
+			_ = deleteFile(path)
+		}
+		return nil
+	})
+
+	// Remove the directories left empty, deepest first
+	for i := len(dirs) - 1; i >= 0; i-- {
+		_ = os.Remove(dirs[i])
+	}
+
+	if len(pending) > 0 {
+		log.Warn("FileCache::cleanupCache : %d files with changes not in storage are kept for recovery in %s", len(pending), fc.tmpPath)
+	}
+}
@@ -37,11 +37,13 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"syscall"
 
 	"github.com/Azure/azure-storage-fuse/v2/common"
 	"github.com/Azure/azure-storage-fuse/v2/common/config"
 	"github.com/Azure/azure-storage-fuse/v2/common/log"
 	"github.com/Azure/azure-storage-fuse/v2/internal"
+	"github.com/Azure/azure-storage-fuse/v2/internal/handlemap"
 	"github.com/Azure/azure-storage-fuse/v2/internal/stats_manager"
 )
 
@@ -76,6 +78,7 @@ type Libfuse struct {
 	directIO              bool
 	umask                 uint32
 	disableKernelCache    bool
+	locks                 *common.LockMap
 }
 
 // To support pagination in readdir calls this structure holds a block of items for a given directory
@@ -354,12 +357,53 @@ func (lf *Libfuse) Configure(_ bool) error {
 	return nil
 }
 
+// lockFile takes the cross node lock of the file for the handle. Storage leases a file created on this node only
+// once it is uploaded, a lower layer without such support reports ENOENT and the file stays unlocked till then.
+func (lf *Libfuse) lockFile(handle *handlemap.Handle) error {
+	err := lf.NextComponent().LockFile(internal.LockFileOptions{Handle: handle, Name: handle.Path})
+	if err == syscall.ENOENT {
+		return nil
+	}
+	return err
+}
+
+// lockOpenFile takes the cross node lock of a file opened for write, the handle is closed if the lock is not granted
+func (lf *Libfuse) lockOpenFile(handle *handlemap.Handle) error {
+	err := lf.lockFile(handle)
+	if err != nil {
+		if err != syscall.EWOULDBLOCK {
+			log.Err("Libfuse::lockOpenFile : Failed to lock %s [%s]", handle.Path, err.Error())
+		}
+		_ = lf.NextComponent().ReleaseFile(internal.ReleaseFileOptions{Handle: handle})
+		handlemap.Delete(handle.ID)
+		return err
+	}
+
+	handle.Flags.Set(handlemap.HandleFlagLocked)
+	return nil
+}
+
+// flockFile mirrors a flock on the handle to the cross node lock of the file, only an exclusive flock holds it.
+// A handle opened for write keeps holding the lock till it is released whatever its flock is.
+func (lf *Libfuse) flockFile(handle *handlemap.Handle, lockType int) error {
+	if handle.Flags.IsSet(handlemap.HandleFlagLocked) {
+		return nil
+	}
+
+	if lockType == syscall.LOCK_EX {
+		return lf.lockFile(handle)
+	}
+	return lf.NextComponent().UnlockFile(internal.UnlockFileOptions{Handle: handle, Name: handle.Path})
+}
+
 // ------------------------- Factory -------------------------------------------
 
 // Pipeline will call this method to create your object, initialize your variables here
 // << DO NOT DELETE ANY AUTO GENERATED CODE HERE >>
 func NewLibfuseComponent() internal.Component {
-	comp := &Libfuse{}
+	comp := &Libfuse{
+		locks: common.NewLockMap(),
+	}
 	comp.SetName(compName)
 	return comp
 }
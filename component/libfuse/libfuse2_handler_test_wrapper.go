@@ -199,6 +199,7 @@ func testCreate(suite *libfuseTestSuite) {
 	info := &C.fuse_file_info_t{}
 	options := internal.CreateFileOptions{Name: name, Mode: mode}
 	suite.mock.EXPECT().CreateFile(options).Return(&handlemap.Handle{}, nil)
+	suite.mock.EXPECT().LockFile(gomock.Any()).Return(nil)
 
 	err := libfuse_create(path, 0775, info)
 	suite.assert.Equal(C.int(0), err)
@@ -237,6 +238,7 @@ func testOpen(suite *libfuseTestSuite) {
 	info.flags = C.O_RDWR
 	options := internal.OpenFileOptions{Name: name, Flags: flags, Mode: mode}
 	suite.mock.EXPECT().OpenFile(options).Return(&handlemap.Handle{}, nil)
+	suite.mock.EXPECT().LockFile(gomock.Any()).Return(nil)
 
 	err := libfuse_open(path, info)
 	suite.assert.Equal(C.int(0), err)
@@ -253,6 +255,7 @@ func testOpenSyncDirectFlag(suite *libfuseTestSuite) {
 	info.flags = C.O_RDWR | C.O_SYNC | C.__O_DIRECT
 	options := internal.OpenFileOptions{Name: name, Flags: flags, Mode: mode}
 	suite.mock.EXPECT().OpenFile(options).Return(&handlemap.Handle{}, nil)
+	suite.mock.EXPECT().LockFile(gomock.Any()).Return(nil)
 
 	err := libfuse_open(path, info)
 	suite.assert.Equal(C.int(0), err)
@@ -273,6 +276,7 @@ func testOpenAppendFlagDefault(suite *libfuseTestSuite) {
 	info.flags = C.O_RDWR | C.O_APPEND
 	options := internal.OpenFileOptions{Name: name, Flags: flags, Mode: mode}
 	suite.mock.EXPECT().OpenFile(options).Return(&handlemap.Handle{}, nil)
+	suite.mock.EXPECT().LockFile(gomock.Any()).Return(nil)
 
 	err := libfuse_open(path, info)
 	suite.assert.Equal(C.int(0), err)
@@ -282,6 +286,7 @@ func testOpenAppendFlagDefault(suite *libfuseTestSuite) {
 	info.flags = C.O_WRONLY | C.O_APPEND
 	options = internal.OpenFileOptions{Name: name, Flags: flags, Mode: mode}
 	suite.mock.EXPECT().OpenFile(options).Return(&handlemap.Handle{}, nil)
+	suite.mock.EXPECT().LockFile(gomock.Any()).Return(nil)
 
 	err = libfuse_open(path, info)
 	suite.assert.Equal(C.int(0), err)
@@ -303,6 +308,7 @@ func testOpenAppendFlagDisableWritebackCache(suite *libfuseTestSuite) {
 	info.flags = C.O_RDWR | C.O_APPEND
 	options := internal.OpenFileOptions{Name: name, Flags: flags, Mode: mode}
 	suite.mock.EXPECT().OpenFile(options).Return(&handlemap.Handle{}, nil)
+	suite.mock.EXPECT().LockFile(gomock.Any()).Return(nil)
 
 	err := libfuse_open(path, info)
 	suite.assert.Equal(C.int(0), err)
@@ -312,6 +318,7 @@ func testOpenAppendFlagDisableWritebackCache(suite *libfuseTestSuite) {
 	info.flags = C.O_WRONLY | C.O_APPEND
 	options = internal.OpenFileOptions{Name: name, Flags: flags, Mode: mode}
 	suite.mock.EXPECT().OpenFile(options).Return(&handlemap.Handle{}, nil)
+	suite.mock.EXPECT().LockFile(gomock.Any()).Return(nil)
 
 	err = libfuse_open(path, info)
 	suite.assert.Equal(C.int(0), err)
@@ -333,6 +340,7 @@ func testOpenAppendFlagIgnoreAppendFlag(suite *libfuseTestSuite) {
 	info.flags = C.O_RDWR | C.O_APPEND
 	options := internal.OpenFileOptions{Name: name, Flags: flags, Mode: mode}
 	suite.mock.EXPECT().OpenFile(options).Return(&handlemap.Handle{}, nil)
+	suite.mock.EXPECT().LockFile(gomock.Any()).Return(nil)
 
 	err := libfuse_open(path, info)
 	suite.assert.Equal(C.int(0), err)
@@ -342,6 +350,7 @@ func testOpenAppendFlagIgnoreAppendFlag(suite *libfuseTestSuite) {
 	info.flags = C.O_WRONLY | C.O_APPEND
 	options = internal.OpenFileOptions{Name: name, Flags: flags, Mode: mode}
 	suite.mock.EXPECT().OpenFile(options).Return(&handlemap.Handle{}, nil)
+	suite.mock.EXPECT().LockFile(gomock.Any()).Return(nil)
 
 	err = libfuse_open(path, info)
 	suite.assert.Equal(C.int(0), err)
@@ -351,6 +360,7 @@ func testOpenAppendFlagIgnoreAppendFlag(suite *libfuseTestSuite) {
 	info.flags = C.O_WRONLY
 	options = internal.OpenFileOptions{Name: name, Flags: flags, Mode: mode}
 	suite.mock.EXPECT().OpenFile(options).Return(&handlemap.Handle{}, nil)
+	suite.mock.EXPECT().LockFile(gomock.Any()).Return(nil)
 
 	err = libfuse_open(path, info)
 	suite.assert.Equal(C.int(0), err)
@@ -541,6 +551,211 @@ func testReadLinkError(suite *libfuseTestSuite) {
 	suite.assert.NotEqual("target", C.GoString(buf))
 }
 
+func testLink(suite *libfuseTestSuite) {
+	defer suite.cleanupTest()
+	name := "path"
+	target := "target"
+	path := C.CString("/" + name)
+	defer C.free(unsafe.Pointer(path))
+	t := C.CString("/" + target)
+	defer C.free(unsafe.Pointer(t))
+	options := internal.CreateHardLinkOptions{Name: name, Target: target}
+	suite.mock.EXPECT().CreateHardLink(options).Return(nil)
+
+	err := libfuse_link(t, path)
+	suite.assert.Equal(C.int(0), err)
+
+	option := internal.GetAttrOptions{Name: name}
+	suite.mock.EXPECT().GetAttr(option).Return(&internal.ObjAttr{Nlink: 2}, nil)
+	stbuf := &C.stat_t{}
+	err = libfuse2_getattr(path, stbuf)
+	suite.assert.Equal(C.int(0), err)
+	suite.assert.EqualValues(2, stbuf.st_nlink)
+}
+
+func testLinkError(suite *libfuseTestSuite) {
+	defer suite.cleanupTest()
+	name := "path"
+	target := "target"
+	path := C.CString("/" + name)
+	defer C.free(unsafe.Pointer(path))
+	t := C.CString("/" + target)
+	defer C.free(unsafe.Pointer(t))
+	options := internal.CreateHardLinkOptions{Name: name, Target: target}
+	suite.mock.EXPECT().CreateHardLink(options).Return(syscall.EEXIST)
+
+	err := libfuse_link(t, path)
+	suite.assert.Equal(C.int(-C.EEXIST), err)
+
+	suite.mock.EXPECT().CreateHardLink(options).Return(errors.New("failed to create link"))
+	err = libfuse_link(t, path)
+	suite.assert.Equal(C.int(-C.EIO), err)
+}
+
+func testFallocate(suite *libfuseTestSuite) {
+	defer suite.cleanupTest()
+	name := "path"
+	path := C.CString("/" + name)
+	defer C.free(unsafe.Pointer(path))
+	options := internal.FallocateOptions{Name: name, Mode: internal.FallocPunchHole | internal.FallocKeepSize, Offset: 4096, Length: 8192}
+	suite.mock.EXPECT().Fallocate(options).Return(nil)
+
+	err := libfuse_fallocate(path, C.int(internal.FallocPunchHole|internal.FallocKeepSize), 4096, 8192, &C.fuse_file_info_t{})
+	suite.assert.Equal(C.int(0), err)
+
+	err = libfuse_fallocate(path, 0, 0, 0, &C.fuse_file_info_t{})
+	suite.assert.Equal(C.int(-C.EINVAL), err)
+}
+
+func testFallocateError(suite *libfuseTestSuite) {
+	defer suite.cleanupTest()
+	name := "path"
+	path := C.CString("/" + name)
+	defer C.free(unsafe.Pointer(path))
+	options := internal.FallocateOptions{Name: name, Mode: internal.FallocPunchHole, Offset: 0, Length: 10}
+	suite.mock.EXPECT().Fallocate(options).Return(syscall.ENOTSUP)
+
+	err := libfuse_fallocate(path, C.int(internal.FallocPunchHole), 0, 10, &C.fuse_file_info_t{})
+	suite.assert.Equal(C.int(-C.ENOTSUP), err)
+}
+
+func testLock(suite *libfuseTestSuite) {
+	defer suite.cleanupTest()
+	name := "path"
+	path := C.CString("/" + name)
+	defer C.free(unsafe.Pointer(path))
+
+	fi1 := C.fuse_file_info_t{lock_owner: 1}
+	fi2 := C.fuse_file_info_t{lock_owner: 2}
+
+	// Owner 1 write locks bytes [100, 199]
+	lock := C.struct_flock{l_type: C.F_WRLCK, l_whence: C.SEEK_SET, l_start: 100, l_len: 100, l_pid: 10}
+	err := libfuse_lock(path, &fi1, C.F_SETLK, &lock)
+	suite.assert.Equal(C.int(0), err)
+
+	// Owner 2 conflicts inside the range and is free outside it
+	lock = C.struct_flock{l_type: C.F_RDLCK, l_whence: C.SEEK_SET, l_start: 150, l_len: 0}
+	err = libfuse_lock(path, &fi2, C.F_SETLK, &lock)
+	suite.assert.Equal(C.int(-C.EAGAIN), err)
+
+	err = libfuse_lock(path, &fi2, C.F_GETLK, &lock)
+	suite.assert.Equal(C.int(0), err)
+	suite.assert.EqualValues(C.F_WRLCK, lock.l_type)
+	suite.assert.EqualValues(100, lock.l_start)
+	suite.assert.EqualValues(100, lock.l_len)
+	suite.assert.EqualValues(10, lock.l_pid)
+
+	lock = C.struct_flock{l_type: C.F_WRLCK, l_whence: C.SEEK_SET, l_start: 200, l_len: 50}
+	err = libfuse_lock(path, &fi2, C.F_SETLK, &lock)
+	suite.assert.Equal(C.int(0), err)
+
+	// Once owner 1 unlocks, owner 2 can take the whole file
+	lock = C.struct_flock{l_type: C.F_UNLCK, l_whence: C.SEEK_SET, l_start: 0, l_len: 0}
+	err = libfuse_lock(path, &fi1, C.F_SETLK, &lock)
+	suite.assert.Equal(C.int(0), err)
+
+	lock = C.struct_flock{l_type: C.F_WRLCK, l_whence: C.SEEK_SET, l_start: 0, l_len: 0}
+	err = libfuse_lock(path, &fi2, C.F_SETLKW, &lock)
+	suite.assert.Equal(C.int(0), err)
+
+	lock = C.struct_flock{l_type: C.F_RDLCK, l_whence: C.SEEK_SET, l_start: 0, l_len: 10}
+	err = libfuse_lock(path, &fi1, C.F_GETLK, &lock)
+	suite.assert.Equal(C.int(0), err)
+	suite.assert.EqualValues(C.F_WRLCK, lock.l_type)
+	suite.assert.EqualValues(0, lock.l_len)
+
+	// Relative offsets are converted by libfuse, so anything else is rejected
+	lock = C.struct_flock{l_type: C.F_RDLCK, l_whence: C.SEEK_CUR, l_start: 0, l_len: 10}
+	err = libfuse_lock(path, &fi1, C.F_SETLK, &lock)
+	suite.assert.Equal(C.int(-C.EINVAL), err)
+}
+
+func testFlock(suite *libfuseTestSuite) {
+	defer suite.cleanupTest()
+	name := "path"
+	path := C.CString("/" + name)
+	defer C.free(unsafe.Pointer(path))
+
+	handle := handlemap.NewHandle(name)
+	ret_val := C.allocate_native_file_object(C.uint64_t(handle.UnixFD), C.uint64_t(uintptr(unsafe.Pointer(handle))), C.uint64_t(handle.Size))
+	fi1 := C.fuse_file_info_t{}
+	fi1.fh = C.uint64_t(uintptr(unsafe.Pointer(ret_val)))
+	fi2 := C.fuse_file_info_t{lock_owner: 2}
+
+	// Only an exclusive flock holds the cross node lock
+	suite.mock.EXPECT().UnlockFile(internal.UnlockFileOptions{Handle: handle, Name: name}).Return(nil)
+	err := libfuse_flock(path, &fi1, C.LOCK_SH)
+	suite.assert.Equal(C.int(0), err)
+	err = libfuse_flock(path, &fi2, C.LOCK_SH)
+	suite.assert.Equal(C.int(0), err)
+
+	err = libfuse_flock(path, &fi2, C.LOCK_EX|C.LOCK_NB)
+	suite.assert.Equal(C.int(-C.EWOULDBLOCK), err)
+
+	// Releasing the handle drops its lock
+	suite.mock.EXPECT().ReleaseFile(internal.ReleaseFileOptions{Handle: handle}).Return(nil)
+	suite.mock.EXPECT().UnlockFile(internal.UnlockFileOptions{Handle: handle, Name: name}).Return(nil)
+	err = libfuse_release(path, &fi1)
+	suite.assert.Equal(C.int(0), err)
+
+	err = libfuse_flock(path, &fi2, C.LOCK_EX|C.LOCK_NB)
+	suite.assert.Equal(C.int(0), err)
+	err = libfuse_flock(path, &fi2, C.LOCK_UN)
+	suite.assert.Equal(C.int(0), err)
+
+	err = libfuse_flock(path, &fi2, 0)
+	suite.assert.Equal(C.int(-C.EINVAL), err)
+}
+
+func testFlockLockedByOtherNode(suite *libfuseTestSuite) {
+	defer suite.cleanupTest()
+	name := "path"
+	path := C.CString("/" + name)
+	defer C.free(unsafe.Pointer(path))
+
+	handle := handlemap.NewHandle(name)
+	ret_val := C.allocate_native_file_object(C.uint64_t(handle.UnixFD), C.uint64_t(uintptr(unsafe.Pointer(handle))), C.uint64_t(handle.Size))
+	fi1 := C.fuse_file_info_t{}
+	fi1.fh = C.uint64_t(uintptr(unsafe.Pointer(ret_val)))
+	fi2 := C.fuse_file_info_t{lock_owner: 2}
+
+	suite.mock.EXPECT().LockFile(internal.LockFileOptions{Handle: handle, Name: name}).Return(syscall.EWOULDBLOCK)
+	err := libfuse_flock(path, &fi1, C.LOCK_EX)
+	suite.assert.Equal(C.int(-C.EWOULDBLOCK), err)
+
+	// Lock is not kept on this node either
+	err = libfuse_flock(path, &fi2, C.LOCK_EX|C.LOCK_NB)
+	suite.assert.Equal(C.int(0), err)
+}
+
+func testOpenLockedByOtherNode(suite *libfuseTestSuite) {
+	defer suite.cleanupTest()
+	name := "path"
+	path := C.CString("/" + name)
+	defer C.free(unsafe.Pointer(path))
+	mode := fs.FileMode(fuseFS.filePermission)
+	flags := C.O_RDWR & 0xffffffff
+	info := &C.fuse_file_info_t{}
+	info.flags = C.O_RDWR
+	handle := &handlemap.Handle{Path: name}
+	options := internal.OpenFileOptions{Name: name, Flags: flags, Mode: mode}
+	suite.mock.EXPECT().OpenFile(options).Return(handle, nil)
+	suite.mock.EXPECT().LockFile(internal.LockFileOptions{Handle: handle, Name: name}).Return(syscall.EWOULDBLOCK)
+	suite.mock.EXPECT().ReleaseFile(internal.ReleaseFileOptions{Handle: handle}).Return(nil)
+
+	err := libfuse_open(path, info)
+	suite.assert.Equal(C.int(-C.EWOULDBLOCK), err)
+
+	// Read only open does not lock
+	flags = C.O_RDONLY & 0xffffffff
+	info.flags = C.O_RDONLY
+	options = internal.OpenFileOptions{Name: name, Flags: flags, Mode: mode}
+	suite.mock.EXPECT().OpenFile(options).Return(&handlemap.Handle{Path: name}, nil)
+
+	err = libfuse_open(path, info)
+	suite.assert.Equal(C.int(0), err)
+}
+
 func testFsync(suite *libfuseTestSuite) {
 	defer suite.cleanupTest()
 	name := "path"
@@ -553,6 +768,7 @@ func testFsync(suite *libfuseTestSuite) {
 	handle := &handlemap.Handle{}
 	openOptions := internal.OpenFileOptions{Name: name, Flags: flags, Mode: mode}
 	suite.mock.EXPECT().OpenFile(openOptions).Return(handle, nil)
+	suite.mock.EXPECT().LockFile(gomock.Any()).Return(nil)
 	libfuse_open(path, info)
 	suite.assert.NotEqual(C.ulong(0), info.fh)
 
@@ -593,6 +809,7 @@ func testFsyncError(suite *libfuseTestSuite) {
 
 	openOptions := internal.OpenFileOptions{Name: name, Flags: flags, Mode: mode}
 	suite.mock.EXPECT().OpenFile(openOptions).Return(handle, nil)
+	suite.mock.EXPECT().LockFile(gomock.Any()).Return(nil)
 	libfuse_open(path, info)
 	suite.assert.NotEqual(C.ulong(0), info.fh)
 
@@ -692,3 +909,117 @@ func testUtimens(suite *libfuseTestSuite) {
 	err := libfuse2_utimens(path, nil)
 	suite.assert.Equal(C.int(0), err)
 }
+
+func testGetXattr(suite *libfuseTestSuite) {
+	defer suite.cleanupTest()
+	name := "path"
+	path := C.CString("/" + name)
+	defer C.free(unsafe.Pointer(path))
+	attr := C.CString("user.owner")
+	defer C.free(unsafe.Pointer(attr))
+	options := internal.GetXattrOptions{Name: name, Attr: "user.owner"}
+	suite.mock.EXPECT().GetXattr(options).Return([]byte("team1"), nil).Times(3)
+
+	// Query the size of the value
+	err := libfuse_getxattr(path, attr, nil, 0)
+	suite.assert.Equal(C.int(5), err)
+
+	buf := (*C.char)(C.malloc(16))
+	defer C.free(unsafe.Pointer(buf))
+	err = libfuse_getxattr(path, attr, buf, 16)
+	suite.assert.Equal(C.int(5), err)
+	suite.assert.Equal("team1", C.GoStringN(buf, 5))
+
+	err = libfuse_getxattr(path, attr, buf, 2)
+	suite.assert.Equal(C.int(-C.ERANGE), err)
+}
+
+func testGetXattrError(suite *libfuseTestSuite) {
+	defer suite.cleanupTest()
+	name := "path"
+	path := C.CString("/" + name)
+	defer C.free(unsafe.Pointer(path))
+	attr := C.CString("user.owner")
+	defer C.free(unsafe.Pointer(attr))
+	options := internal.GetXattrOptions{Name: name, Attr: "user.owner"}
+
+	suite.mock.EXPECT().GetXattr(options).Return(nil, syscall.ENODATA)
+	err := libfuse_getxattr(path, attr, nil, 0)
+	suite.assert.Equal(C.int(-C.ENODATA), err)
+
+	suite.mock.EXPECT().GetXattr(options).Return(nil, errors.New("failed to get xattr"))
+	err = libfuse_getxattr(path, attr, nil, 0)
+	suite.assert.Equal(C.int(-C.EIO), err)
+}
+
+func testSetXattr(suite *libfuseTestSuite) {
+	defer suite.cleanupTest()
+	name := "path"
+	path := C.CString("/" + name)
+	defer C.free(unsafe.Pointer(path))
+	attr := C.CString("user.owner")
+	defer C.free(unsafe.Pointer(attr))
+	value := C.CString("team1")
+	defer C.free(unsafe.Pointer(value))
+	options := internal.SetXattrOptions{Name: name, Attr: "user.owner", Value: []byte("team1"), Flags: internal.XattrCreate}
+	suite.mock.EXPECT().SetXattr(options).Return(nil)
+
+	err := libfuse_setxattr(path, attr, value, 5, C.int(internal.XattrCreate))
+	suite.assert.Equal(C.int(0), err)
+}
+
+func testSetXattrError(suite *libfuseTestSuite) {
+	defer suite.cleanupTest()
+	name := "path"
+	path := C.CString("/" + name)
+	defer C.free(unsafe.Pointer(path))
+	attr := C.CString("security.selinux")
+	defer C.free(unsafe.Pointer(attr))
+	value := C.CString("abc")
+	defer C.free(unsafe.Pointer(value))
+	options := internal.SetXattrOptions{Name: name, Attr: "security.selinux", Value: []byte("abc")}
+	suite.mock.EXPECT().SetXattr(options).Return(syscall.ENOTSUP)
+
+	err := libfuse_setxattr(path, attr, value, 3, 0)
+	suite.assert.Equal(C.int(-C.ENOTSUP), err)
+}
+
+func testListXattr(suite *libfuseTestSuite) {
+	defer suite.cleanupTest()
+	name := "path"
+	path := C.CString("/" + name)
+	defer C.free(unsafe.Pointer(path))
+	options := internal.ListXattrOptions{Name: name}
+	suite.mock.EXPECT().ListXattr(options).Return([]string{"user.a", "user.bc"}, nil).Times(3)
+
+	// Query the size of the list
+	err := libfuse_listxattr(path, nil, 0)
+	suite.assert.Equal(C.int(15), err)
+
+	buf := (*C.char)(C.malloc(32))
+	defer C.free(unsafe.Pointer(buf))
+	err = libfuse_listxattr(path, buf, 32)
+	suite.assert.Equal(C.int(15), err)
+	suite.assert.Equal("user.a\x00user.bc\x00", C.GoStringN(buf, 15))
+
+	err = libfuse_listxattr(path, buf, 4)
+	suite.assert.Equal(C.int(-C.ERANGE), err)
+}
+
+func testRemoveXattr(suite *libfuseTestSuite) {
+	defer suite.cleanupTest()
+	name := "path"
+	path := C.CString("/" + name)
+	defer C.free(unsafe.Pointer(path))
+	attr := C.CString("user.owner")
+	defer C.free(unsafe.Pointer(attr))
+	options := internal.RemoveXattrOptions{Name: name, Attr: "user.owner"}
+
+	suite.mock.EXPECT().RemoveXattr(options).Return(nil)
+	err := libfuse_removexattr(path, attr)
+	suite.assert.Equal(C.int(0), err)
+
+	suite.mock.EXPECT().RemoveXattr(options).Return(syscall.ENOENT)
+	err = libfuse_removexattr(path, attr)
+	suite.assert.Equal(C.int(-C.ENOENT), err)
+}
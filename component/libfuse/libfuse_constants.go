@@ -34,18 +34,24 @@
 package libfuse
 
 const (
-	createDir    = "CreateDir"
-	deleteDir    = "DeleteDir"
-	createFile   = "CreateFile"
-	truncateFile = "TruncateFile"
-	deleteFile   = "DeleteFile"
-	renameDir    = "RenameDir"
-	renameFile   = "RenameFile"
-	createLink   = "CreateLink"
-	readLink     = "ReadLink"
-	syncFile     = "SyncFile"
-	syncDir      = "SyncDir"
-	chmod        = "Chmod"
+	createDir      = "CreateDir"
+	deleteDir      = "DeleteDir"
+	createFile     = "CreateFile"
+	truncateFile   = "TruncateFile"
+	fallocate      = "Fallocate"
+	lseek          = "Lseek"
+	copyFileRange  = "CopyFileRange"
+	deleteFile     = "DeleteFile"
+	renameDir      = "RenameDir"
+	renameFile     = "RenameFile"
+	createLink     = "CreateLink"
+	readLink       = "ReadLink"
+	createHardLink = "CreateHardLink"
+	syncFile       = "SyncFile"
+	syncDir        = "SyncDir"
+	chmod          = "Chmod"
+	setXattr       = "SetXattr"
+	removeXattr    = "RemoveXattr"
 
 	openHandles = "OpenFileHandles"
 	md          = "Mode"
@@ -53,4 +59,5 @@ const (
 	source      = "Src"
 	dest        = "Dest"
 	trgt        = "Target"
+	xattrName   = "Attr"
 )
@@ -350,6 +350,9 @@ func (lf *Libfuse) fillStat(attr *internal.ObjAttr, stbuf *C.stat_t) {
 	(*stbuf).st_uid = C.uint(lf.ownerUID)
 	(*stbuf).st_gid = C.uint(lf.ownerGID)
 	(*stbuf).st_nlink = 1
+	if attr.Nlink > 1 {
+		(*stbuf).st_nlink = C.nlink_t(attr.Nlink)
+	}
 	(*stbuf).st_size = C.off_t(attr.Size)
 
 	// Populate mode
@@ -655,6 +658,13 @@ func libfuse_create(path *C.char, mode C.mode_t, fi *C.fuse_file_info_t) C.int {
 	}
 
 	handlemap.Add(handle)
+
+	// New file is open for write, so it takes the cross node lock of the file till release
+	err = fuseFS.lockOpenFile(handle)
+	if err != nil {
+		return storageErrno(err)
+	}
+
 	ret_val := C.allocate_native_file_object(0, C.uint64_t(uintptr(unsafe.Pointer(handle))), 0)
 	if !handle.Cached() {
 		ret_val.fd = 0
@@ -725,6 +735,15 @@ func libfuse_open(path *C.char, fi *C.fuse_file_info_t) C.int {
 	}
 
 	handlemap.Add(handle)
+
+	// Opening for write takes the cross node lock of the file, which is held till release
+	if fi.flags&C.O_ACCMODE != C.O_RDONLY {
+		err = fuseFS.lockOpenFile(handle)
+		if err != nil {
+			return storageErrno(err)
+		}
+	}
+
 	//fi.fh = C.ulong(uintptr(unsafe.Pointer(handle)))
 	ret_val := C.allocate_native_file_object(C.uint64_t(handle.UnixFD), C.uint64_t(uintptr(unsafe.Pointer(handle))), C.uint64_t(handle.Size))
 	if !handle.Cached() {
@@ -849,12 +868,22 @@ func libfuse_release(path *C.char, fi *C.fuse_file_info_t) C.int {
 
 	log.Trace("Libfuse::libfuse_release : %s, handle: %d", handle.Path, handle.ID)
 
+	// flock locks are tied to the handle, so they go away with it
+	fuseFS.locks.ReleaseFlock(handle.Path, uint64(handle.ID))
+
 	// If the file handle is dirty then file-cache needs to flush this file
 	if fileHandle.dirty != 0 {
 		handle.Flags.Set(handlemap.HandleFlagDirty)
 	}
 
 	err := fuseFS.NextComponent().ReleaseFile(internal.ReleaseFileOptions{Handle: handle})
+
+	// Cross node lock is dropped only once the data is uploaded on release
+	uerr := fuseFS.NextComponent().UnlockFile(internal.UnlockFileOptions{Handle: handle, Name: handle.Path})
+	if uerr != nil {
+		log.Err("Libfuse::libfuse_release : error unlocking file %s, handle: %d [%s]", handle.Path, handle.ID, uerr.Error())
+	}
+
 	if err != nil {
 		log.Err("Libfuse::libfuse_release : error closing file %s, handle: %d [%s]", handle.Path, handle.ID, err.Error())
 		switch err {
@@ -876,6 +905,113 @@ func libfuse_release(path *C.char, fi *C.fuse_file_info_t) C.int {
 	return 0
 }
 
+// libfuse_lock handles POSIX (fcntl) byte range locks, these are tracked by the mount across all its handles.
+// libfuse unlocks the locks of an owner on every flush, which gives the release on close semantics of POSIX locks.
+// https://man7.org/linux/man-pages/man2/fcntl.2.html
+//
+//export libfuse_lock
+func libfuse_lock(path *C.char, fi *C.fuse_file_info_t, cmd C.int, lock *C.struct_flock) C.int {
+	name := trimFusePath(path)
+	name = common.NormalizeObjectName(name)
+	log.Trace("Libfuse::libfuse_lock : %s, cmd: %d, type: %d, start: %d, len: %d", name, cmd, lock.l_type, lock.l_start, lock.l_len)
+
+	if lock.l_whence != C.SEEK_SET || lock.l_start < 0 || lock.l_len < 0 {
+		return -C.EINVAL
+	}
+
+	lk := common.RangeLock{
+		Owner: uint64(fi.lock_owner),
+		Pid:   int32(lock.l_pid),
+		Type:  int16(lock.l_type),
+		Start: int64(lock.l_start),
+		End:   common.RangeLockEOF,
+	}
+	if lock.l_len > 0 {
+		lk.End = lk.Start + int64(lock.l_len) - 1
+	}
+
+	switch cmd {
+	case C.F_GETLK:
+		conflict := fuseFS.locks.GetLk(name, lk)
+		if conflict == nil {
+			lock.l_type = C.F_UNLCK
+			return 0
+		}
+
+		lock.l_type = C.short(conflict.Type)
+		lock.l_pid = C.pid_t(conflict.Pid)
+		lock.l_start = C.__off64_t(conflict.Start)
+		lock.l_len = 0
+		if conflict.End != common.RangeLockEOF {
+			lock.l_len = C.__off64_t(conflict.End - conflict.Start + 1)
+		}
+
+	case C.F_SETLK, C.F_SETLKW:
+		err := fuseFS.locks.SetLk(name, lk, cmd == C.F_SETLKW, fuseInterrupted)
+		if err != nil {
+			// Conflicts and interrupted waits are expected, applications retry or wait on these
+			if err != syscall.EAGAIN && err != syscall.EINTR {
+				log.Err("Libfuse::libfuse_lock : error locking file %s [%s]", name, err.Error())
+			}
+			return storageErrno(err)
+		}
+
+	default:
+		return -C.EINVAL
+	}
+
+	return 0
+}
+
+// libfuse_flock handles BSD style whole file locks, these belong to the open handle and are dropped when it is released
+// https://man7.org/linux/man-pages/man2/flock.2.html
+//
+//export libfuse_flock
+func libfuse_flock(path *C.char, fi *C.fuse_file_info_t, op C.int) C.int {
+	name := trimFusePath(path)
+	name = common.NormalizeObjectName(name)
+	log.Trace("Libfuse::libfuse_flock : %s, op: %d", name, op)
+
+	handle := fileInfoHandle(fi)
+	owner := uint64(fi.lock_owner)
+	if handle != nil {
+		owner = uint64(handle.ID)
+	}
+
+	err := fuseFS.locks.Flock(name, owner, int(op), fuseInterrupted)
+	if err == nil && handle != nil {
+		// Exclusive flock also locks the file for other nodes
+		err = fuseFS.flockFile(handle, int(op)&^syscall.LOCK_NB)
+		if err != nil {
+			_ = fuseFS.locks.Flock(name, owner, syscall.LOCK_UN, nil)
+		}
+	}
+
+	if err != nil {
+		if err != syscall.EWOULDBLOCK && err != syscall.EINTR {
+			log.Err("Libfuse::libfuse_flock : error locking file %s [%s]", name, err.Error())
+		}
+		return storageErrno(err)
+	}
+
+	return 0
+}
+
+// fuseInterrupted checks whether the request served by the calling thread was interrupted, e.g. the process waiting
+// for a lock got a signal. libfuse keeps this state per thread and a callback stays on its thread till it returns.
+func fuseInterrupted() bool {
+	return C.fuse_interrupted() != 0
+}
+
+// fileInfoHandle returns the open handle of the file info, nil if there is none
+func fileInfoHandle(fi *C.fuse_file_info_t) *handlemap.Handle {
+	if fi == nil || fi.fh == 0 {
+		return nil
+	}
+	fileHandle := (*C.file_handle_t)(unsafe.Pointer(uintptr(fi.fh)))
+	return (*handlemap.Handle)(unsafe.Pointer(uintptr(fileHandle.obj)))
+}
+
 // libfuse_fsync synchronizes file contents
 //
 //export libfuse_fsync
@@ -949,6 +1085,134 @@ func libfuse_truncate(path *C.char, off C.off_t, fi *C.fuse_file_info_t) C.int {
 	return 0
 }
 
+// libfuse_fallocate allocates or deallocates space for a file
+// https://man7.org/linux/man-pages/man2/fallocate.2.html
+//
+//export libfuse_fallocate
+func libfuse_fallocate(path *C.char, mode C.int, off C.off_t, length C.off_t, fi *C.fuse_file_info_t) C.int {
+	name := trimFusePath(path)
+	name = common.NormalizeObjectName(name)
+
+	var handle *handlemap.Handle
+	if fi != nil && fi.fh != 0 {
+		fileHandle := (*C.file_handle_t)(unsafe.Pointer(uintptr(fi.fh)))
+		handle = (*handlemap.Handle)(unsafe.Pointer(uintptr(fileHandle.obj)))
+	}
+	log.Trace("Libfuse::libfuse_fallocate : %s, mode: %d, offset: %d, length: %d", name, mode, off, length)
+
+	if off < 0 || length <= 0 {
+		return -C.EINVAL
+	}
+
+	err := fuseFS.NextComponent().Fallocate(
+		internal.FallocateOptions{
+			Handle: handle,
+			Name:   name,
+			Mode:   uint32(mode),
+			Offset: int64(off),
+			Length: int64(length),
+		})
+
+	if err != nil {
+		log.Err("Libfuse::libfuse_fallocate : error allocating file %s [%s]", name, err.Error())
+		return storageErrno(err)
+	}
+
+	libfuseStatsCollector.PushEvents(fallocate, name, map[string]interface{}{md: int(mode), size: int64(length)})
+	libfuseStatsCollector.UpdateStats(stats_manager.Increment, fallocate, (int64)(1))
+
+	return 0
+}
+
+// libfuse_lseek finds the next data or hole in a file, kernel handles all other whence values itself
+// https://man7.org/linux/man-pages/man2/lseek.2.html
+//
+//export libfuse_lseek
+func libfuse_lseek(path *C.char, off C.off_t, whence C.int, fi *C.fuse_file_info_t) C.off_t {
+	name := trimFusePath(path)
+	name = common.NormalizeObjectName(name)
+
+	var handle *handlemap.Handle
+	if fi != nil && fi.fh != 0 {
+		fileHandle := (*C.file_handle_t)(unsafe.Pointer(uintptr(fi.fh)))
+		handle = (*handlemap.Handle)(unsafe.Pointer(uintptr(fileHandle.obj)))
+	}
+	log.Trace("Libfuse::libfuse_lseek : %s, offset: %d, whence: %d", name, off, whence)
+
+	if off < 0 || (whence != internal.SeekData && whence != internal.SeekHole) {
+		return -C.EINVAL
+	}
+
+	offset, err := fuseFS.NextComponent().Lseek(
+		internal.LseekOptions{
+			Handle: handle,
+			Name:   name,
+			Offset: int64(off),
+			Whence: int(whence),
+		})
+
+	if err != nil {
+		if err != syscall.ENXIO {
+			log.Err("Libfuse::libfuse_lseek : error seeking file %s [%s]", name, err.Error())
+		}
+		return C.off_t(storageErrno(err))
+	}
+
+	libfuseStatsCollector.UpdateStats(stats_manager.Increment, lseek, (int64)(1))
+
+	return C.off_t(offset)
+}
+
+// libfuse_copy_file_range copies a range of one file to another without passing data through the kernel
+// https://man7.org/linux/man-pages/man2/copy_file_range.2.html
+//
+//export libfuse_copy_file_range
+func libfuse_copy_file_range(pathIn *C.char, fiIn *C.fuse_file_info_t, offIn C.off_t, pathOut *C.char, fiOut *C.fuse_file_info_t, offOut C.off_t, length C.size_t, flags C.int) C.ssize_t {
+	srcName := common.NormalizeObjectName(trimFusePath(pathIn))
+	dstName := common.NormalizeObjectName(trimFusePath(pathOut))
+
+	var srcHandle, dstHandle *handlemap.Handle
+	if fiIn != nil && fiIn.fh != 0 {
+		fileHandle := (*C.file_handle_t)(unsafe.Pointer(uintptr(fiIn.fh)))
+		srcHandle = (*handlemap.Handle)(unsafe.Pointer(uintptr(fileHandle.obj)))
+	}
+	if fiOut != nil && fiOut.fh != 0 {
+		fileHandle := (*C.file_handle_t)(unsafe.Pointer(uintptr(fiOut.fh)))
+		dstHandle = (*handlemap.Handle)(unsafe.Pointer(uintptr(fileHandle.obj)))
+	}
+	log.Trace("Libfuse::libfuse_copy_file_range : %s [%d] -> %s [%d], length: %d", srcName, offIn, dstName, offOut, length)
+
+	if flags != 0 || offIn < 0 || offOut < 0 {
+		return -C.EINVAL
+	}
+
+	copied, err := fuseFS.NextComponent().CopyFileRange(
+		internal.CopyFileRangeOptions{
+			SrcHandle: srcHandle,
+			SrcName:   srcName,
+			SrcOffset: int64(offIn),
+			DstHandle: dstHandle,
+			DstName:   dstName,
+			DstOffset: int64(offOut),
+			Length:    int64(length),
+		})
+
+	if err != nil {
+		if err == syscall.ENOTSUP {
+			// Kernel falls back to copy the data through read and write
+			log.Debug("Libfuse::libfuse_copy_file_range : %s -> %s can not be copied in storage", srcName, dstName)
+		} else {
+			log.Err("Libfuse::libfuse_copy_file_range : error copying %s -> %s [%s]", srcName, dstName, err.Error())
+		}
+		return C.ssize_t(storageErrno(err))
+	}
+
+	libfuseStatsCollector.PushEvents(copyFileRange, srcName, map[string]interface{}{source: srcName, dest: dstName, size: copied})
+	libfuseStatsCollector.UpdateStats(stats_manager.Increment, copyFileRange, (int64)(1))
+
+	return C.ssize_t(copied)
+}
+
 // libfuse_unlink removes a file
 //
 //export libfuse_unlink
@@ -1121,6 +1385,28 @@ func libfuse_readlink(path *C.char, buf *C.char, size C.size_t) C.int {
 	return 0
 }
 
+// libfuse_link creates a hard link
+//
+//export libfuse_link
+func libfuse_link(target *C.char, link *C.char) C.int {
+	name := trimFusePath(link)
+	name = common.NormalizeObjectName(name)
+	targetPath := trimFusePath(target)
+	targetPath = common.NormalizeObjectName(targetPath)
+	log.Trace("Libfuse::libfuse_link : Received for %s -> %s", name, targetPath)
+
+	err := fuseFS.NextComponent().CreateHardLink(internal.CreateHardLinkOptions{Name: name, Target: targetPath})
+	if err != nil {
+		log.Err("Libfuse::libfuse_link : error linking file %s -> %s [%s]", name, targetPath, err.Error())
+		return storageErrno(err)
+	}
+
+	libfuseStatsCollector.PushEvents(createHardLink, name, map[string]interface{}{trgt: targetPath})
+	libfuseStatsCollector.UpdateStats(stats_manager.Increment, createHardLink, (int64)(1))
+
+	return 0
+}
+
 // libfuse_fsyncdir synchronizes directory contents
 //
 //export libfuse_fsyncdir
@@ -1198,6 +1484,134 @@ func libfuse_utimens(path *C.char, tv *C.timespec_t, fi *C.fuse_file_info_t) C.i
 	return 0
 }
 
+// storageErrno maps the error returned by the pipeline to the errno returned to kernel
+func storageErrno(err error) C.int {
+	var errno syscall.Errno
+	if errors.As(err, &errno) {
+		return -C.int(errno)
+	} else if os.IsNotExist(err) {
+		return -C.ENOENT
+	} else if os.IsPermission(err) {
+		return -C.EACCES
+	}
+	return -C.EIO
+}
+
+// libfuse_getxattr gets the value of an extended attribute
+// https://man7.org/linux/man-pages/man2/getxattr.2.html
+//
+//export libfuse_getxattr
+func libfuse_getxattr(path *C.char, name *C.char, value *C.char, size C.size_t) C.int {
+	fileName := trimFusePath(path)
+	fileName = common.NormalizeObjectName(fileName)
+	attrName := C.GoString(name)
+	log.Trace("Libfuse::libfuse_getxattr : %s of %s", attrName, fileName)
+
+	data, err := fuseFS.NextComponent().GetXattr(internal.GetXattrOptions{Name: fileName, Attr: attrName})
+	if err != nil {
+		if err != syscall.ENODATA && err != syscall.ENOTSUP {
+			log.Err("Libfuse::libfuse_getxattr : error getting %s of %s [%s]", attrName, fileName, err.Error())
+		}
+		return storageErrno(err)
+	}
+
+	// Size zero is a query for the size of the value
+	if size == 0 {
+		return C.int(len(data))
+	} else if int(size) < len(data) {
+		return -C.ERANGE
+	}
+
+	buf := (*[1 << 30]byte)(unsafe.Pointer(value))
+	copy(buf[:size], data)
+	return C.int(len(data))
+}
+
+// libfuse_setxattr sets the value of an extended attribute
+// https://man7.org/linux/man-pages/man2/setxattr.2.html
+//
+//export libfuse_setxattr
+func libfuse_setxattr(path *C.char, name *C.char, value *C.char, size C.size_t, flags C.int) C.int {
+	fileName := trimFusePath(path)
+	fileName = common.NormalizeObjectName(fileName)
+	attrName := C.GoString(name)
+	log.Trace("Libfuse::libfuse_setxattr : %s of %s", attrName, fileName)
+
+	err := fuseFS.NextComponent().SetXattr(
+		internal.SetXattrOptions{
+			Name:  fileName,
+			Attr:  attrName,
+			Value: C.GoBytes(unsafe.Pointer(value), C.int(size)),
+			Flags: int(flags),
+		})
+	if err != nil {
+		log.Err("Libfuse::libfuse_setxattr : error setting %s of %s [%s]", attrName, fileName, err.Error())
+		return storageErrno(err)
+	}
+
+	libfuseStatsCollector.PushEvents(setXattr, fileName, map[string]interface{}{xattrName: attrName})
+	libfuseStatsCollector.UpdateStats(stats_manager.Increment, setXattr, (int64)(1))
+
+	return 0
+}
+
+// libfuse_listxattr lists the names of extended attributes as a sequence of null terminated strings
+// https://man7.org/linux/man-pages/man2/listxattr.2.html
+//
+//export libfuse_listxattr
+func libfuse_listxattr(path *C.char, list *C.char, size C.size_t) C.int {
+	fileName := trimFusePath(path)
+	fileName = common.NormalizeObjectName(fileName)
+	log.Trace("Libfuse::libfuse_listxattr : %s", fileName)
+
+	names, err := fuseFS.NextComponent().ListXattr(internal.ListXattrOptions{Name: fileName})
+	if err != nil {
+		log.Err("Libfuse::libfuse_listxattr : error listing attributes of %s [%s]", fileName, err.Error())
+		return storageErrno(err)
+	}
+
+	data := make([]byte, 0)
+	for _, attrName := range names {
+		data = append(data, attrName...)
+		data = append(data, 0)
+	}
+
+	// Size zero is a query for the size of the list
+	if size == 0 {
+		return C.int(len(data))
+	} else if int(size) < len(data) {
+		return -C.ERANGE
+	}
+
+	buf := (*[1 << 30]byte)(unsafe.Pointer(list))
+	copy(buf[:size], data)
+	return C.int(len(data))
+}
+
+// libfuse_removexattr removes an extended attribute
+// https://man7.org/linux/man-pages/man2/removexattr.2.html
+//
+//export libfuse_removexattr
+func libfuse_removexattr(path *C.char, name *C.char) C.int {
+	fileName := trimFusePath(path)
+	fileName = common.NormalizeObjectName(fileName)
+	attrName := C.GoString(name)
+	log.Trace("Libfuse::libfuse_removexattr : %s of %s", attrName, fileName)
+
+	err := fuseFS.NextComponent().RemoveXattr(internal.RemoveXattrOptions{Name: fileName, Attr: attrName})
+	if err != nil {
+		if err != syscall.ENODATA {
+			log.Err("Libfuse::libfuse_removexattr : error removing %s of %s [%s]", attrName, fileName, err.Error())
+		}
+		return storageErrno(err)
+	}
+
+	libfuseStatsCollector.PushEvents(removeXattr, fileName, map[string]interface{}{xattrName: attrName})
+	libfuseStatsCollector.UpdateStats(stats_manager.Increment, removeXattr, (int64)(1))
+
+	return 0
+}
+
 // blobfuse_cache_update refresh the file-cache policy for this file
 //
 //export blobfuse_cache_update
@@ -305,6 +305,38 @@ func (suite *libfuseTestSuite) TestReadLinkError() {
 	testReadLinkError(suite)
 }
 
+func (suite *libfuseTestSuite) TestLink() {
+	testLink(suite)
+}
+
+func (suite *libfuseTestSuite) TestLinkError() {
+	testLinkError(suite)
+}
+
+func (suite *libfuseTestSuite) TestFallocate() {
+	testFallocate(suite)
+}
+
+func (suite *libfuseTestSuite) TestFallocateError() {
+	testFallocateError(suite)
+}
+
+func (suite *libfuseTestSuite) TestLock() {
+	testLock(suite)
+}
+
+func (suite *libfuseTestSuite) TestFlock() {
+	testFlock(suite)
+}
+
+func (suite *libfuseTestSuite) TestFlockLockedByOtherNode() {
+	testFlockLockedByOtherNode(suite)
+}
+
+func (suite *libfuseTestSuite) TestOpenLockedByOtherNode() {
+	testOpenLockedByOtherNode(suite)
+}
+
 func (suite *libfuseTestSuite) TestFsync() {
 	testFsync(suite)
 }
@@ -349,6 +381,30 @@ func (suite *libfuseTestSuite) TestUtimens() {
 	testUtimens(suite)
 }
 
+func (suite *libfuseTestSuite) TestGetXattr() {
+	testGetXattr(suite)
+}
+
+func (suite *libfuseTestSuite) TestGetXattrError() {
+	testGetXattrError(suite)
+}
+
+func (suite *libfuseTestSuite) TestSetXattr() {
+	testSetXattr(suite)
+}
+
+func (suite *libfuseTestSuite) TestSetXattrError() {
+	testSetXattrError(suite)
+}
+
+func (suite *libfuseTestSuite) TestListXattr() {
+	testListXattr(suite)
+}
+
+func (suite *libfuseTestSuite) TestRemoveXattr() {
+	testRemoveXattr(suite)
+}
+
 // In order for 'go test' to run this suite, we need to create
 // a normal test function and pass our suite to suite.Run
 func TestLibfuseTestSuite(t *testing.T) {
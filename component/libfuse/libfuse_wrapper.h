@@ -84,15 +84,25 @@ static int populate_callbacks(fuse_operations_t *opt)
     #endif
     
     opt->release    = (int (*)(const char *path, fuse_file_info_t *fi))libfuse_release;
+    opt->lock       = (int (*)(const char *path, fuse_file_info_t *fi, int cmd, struct flock *lock))libfuse_lock;
+    opt->flock      = (int (*)(const char *path, fuse_file_info_t *fi, int op))libfuse_flock;
 
     opt->unlink     = (int (*)(const char *path))libfuse_unlink;
 
     opt->symlink    = (int (*)(const char *from, const char *to))libfuse_symlink;
     opt->readlink   = (int (*)(const char *path, char *buf, size_t size))libfuse_readlink;
+    opt->link       = (int (*)(const char *from, const char *to))libfuse_link;
+
+    opt->fallocate  = (int (*)(const char *path, int mode, off_t offset, off_t length, fuse_file_info_t *fi))libfuse_fallocate;
 
     opt->fsync      = (int (*)(const char *path, int, fuse_file_info_t *fi))libfuse_fsync;
     opt->fsyncdir   = (int (*)(const char *path, int, fuse_file_info_t *))libfuse_fsyncdir;
 
+    opt->setxattr   = (int (*)(const char *path, const char *name, const char *value, size_t size, int flags))libfuse_setxattr;
+    opt->getxattr   = (int (*)(const char *path, const char *name, char *value, size_t size))libfuse_getxattr;
+    opt->listxattr  = (int (*)(const char *path, char *list, size_t size))libfuse_listxattr;
+    opt->removexattr = (int (*)(const char *path, const char *name))libfuse_removexattr;
+
 
     #ifdef __FUSE2__
     opt->init       = (void *(*)(fuse_conn_info_t *))libfuse2_init;
@@ -113,6 +123,9 @@ static int populate_callbacks(fuse_operations_t *opt)
     opt->chmod      = (int (*)(const char *path, mode_t mode, fuse_file_info_t *fi))libfuse_chmod;
     opt->chown      = (int (*)(const char *path, uid_t uid, gid_t gid, fuse_file_info_t *fi))libfuse_chown;
     opt->utimens    = (int (*)(const char *path, const timespec_t tv[2], fuse_file_info_t *fi))libfuse_utimens;
+    opt->lseek      = (off_t (*)(const char *path, off_t off, int whence, fuse_file_info_t *fi))libfuse_lseek;
+    opt->copy_file_range = (ssize_t (*)(const char *path_in, fuse_file_info_t *fi_in, off_t off_in, const char *path_out, 
+                                        fuse_file_info_t *fi_out, off_t off_out, size_t size, int flags))libfuse_copy_file_range;
     #endif
 
     return 0;
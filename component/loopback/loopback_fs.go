@@ -40,6 +40,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"syscall"
 
 	"github.com/Azure/azure-storage-fuse/v2/common"
@@ -62,6 +63,15 @@ type LoopbackFS struct {
 
 	path        string
 	consistency bool
+	committed   sync.Map // name to the blocks of its last commit
+	leases      map[string]*fileLease
+	leaseLock   sync.Mutex
+}
+
+// fileLease stands in for a blob lease, flock held on the file conflicts with the one of another instance
+type fileLease struct {
+	file    *os.File
+	holders map[handlemap.HandleID]struct{}
 }
 
 var _ internal.Component = &LoopbackFS{}
@@ -155,6 +165,7 @@ func (lfs *LoopbackFS) ReadDir(options internal.ReadDirOptions) ([]*internal.Obj
 			Mtime: info.ModTime(),
 		}
 		attr.Flags.Set(internal.PropFlagModeDefault)
+		attr.Metadata = readMetadata(filepath.Join(path, file.Name()))
 
 		if file.IsDir() {
 			attr.Flags.Set(internal.PropFlagIsDir)
@@ -200,6 +211,7 @@ func (lfs *LoopbackFS) StreamDir(options internal.StreamDirOptions) ([]*internal
 			MD5:   md5,
 		}
 		attr.Flags.Set(internal.PropFlagModeDefault)
+		attr.Metadata = readMetadata(filepath.Join(path, file.Name()))
 
 		if file.IsDir() {
 			attr.Flags.Set(internal.PropFlagIsDir)
@@ -258,6 +270,62 @@ func (lfs *LoopbackFS) CreateLink(options internal.CreateLinkOptions) error {
 	return err
 }
 
+func (lfs *LoopbackFS) CreateHardLink(options internal.CreateHardLinkOptions) error {
+	log.Trace("LoopbackFS::CreateHardLink : name=%s", options.Name)
+	path := filepath.Join(lfs.path, options.Name)
+	target := filepath.Join(lfs.path, options.Target)
+
+	return os.Link(target, path)
+}
+
+func (lfs *LoopbackFS) Fallocate(options internal.FallocateOptions) error {
+	log.Trace("LoopbackFS::Fallocate : name=%s, mode=%d", options.Name, options.Mode)
+	path := filepath.Join(lfs.path, options.Name)
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return syscall.Fallocate(int(f.Fd()), options.Mode, options.Offset, options.Length)
+}
+
+func (lfs *LoopbackFS) Lseek(options internal.LseekOptions) (int64, error) {
+	log.Trace("LoopbackFS::Lseek : name=%s, offset=%d, whence=%d", options.Name, options.Offset, options.Whence)
+	path := filepath.Join(lfs.path, options.Name)
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	return syscall.Seek(int(f.Fd()), options.Offset, options.Whence)
+}
+
+func (lfs *LoopbackFS) CopyFileRange(options internal.CopyFileRangeOptions) (int64, error) {
+	log.Trace("LoopbackFS::CopyFileRange : %s [%d] -> %s [%d], length=%d", options.SrcName, options.SrcOffset, options.DstName, options.DstOffset, options.Length)
+	src, err := os.Open(filepath.Join(lfs.path, options.SrcName))
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(filepath.Join(lfs.path, options.DstName), os.O_RDWR, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer dst.Close()
+
+	copied, err := io.Copy(io.NewOffsetWriter(dst, options.DstOffset), io.NewSectionReader(src, options.SrcOffset, options.Length))
+	if err != nil {
+		return 0, err
+	}
+
+	// Block list recorded for destination does not describe its data anymore
+	lfs.committed.Delete(options.DstName)
+	return copied, nil
+}
+
 func (lfs *LoopbackFS) DeleteFile(options internal.DeleteFileOptions) error {
 	log.Trace("LoopbackFS::DeleteFile : name=%s", options.Name)
 	path := filepath.Join(lfs.path, options.Name)
@@ -280,6 +348,7 @@ func (lfs *LoopbackFS) OpenFile(options internal.OpenFileOptions) (*handlemap.Ha
 
 func (lfs *LoopbackFS) ReleaseFile(options internal.ReleaseFileOptions) error {
 	log.Trace("LoopbackFS::ReleaseFile : name=%s", options.Handle.Path)
+	_ = lfs.UnlockFile(internal.UnlockFileOptions{Handle: options.Handle, Name: options.Handle.Path})
 
 	f := options.Handle.GetFileObject()
 	if f == nil {
@@ -290,6 +359,56 @@ func (lfs *LoopbackFS) ReleaseFile(options internal.ReleaseFileOptions) error {
 	return f.Close()
 }
 
+func (lfs *LoopbackFS) LockFile(options internal.LockFileOptions) error {
+	log.Trace("LoopbackFS::LockFile : name=%s", options.Name)
+	lfs.leaseLock.Lock()
+	defer lfs.leaseLock.Unlock()
+
+	if l, ok := lfs.leases[options.Name]; ok {
+		l.holders[options.Handle.ID] = struct{}{}
+		return nil
+	}
+
+	f, err := os.Open(filepath.Join(lfs.path, options.Name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return syscall.ENOENT
+		}
+		return err
+	}
+
+	err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	lfs.leases[options.Name] = &fileLease{
+		file:    f,
+		holders: map[handlemap.HandleID]struct{}{options.Handle.ID: {}},
+	}
+	return nil
+}
+
+func (lfs *LoopbackFS) UnlockFile(options internal.UnlockFileOptions) error {
+	log.Trace("LoopbackFS::UnlockFile : name=%s", options.Name)
+	lfs.leaseLock.Lock()
+	defer lfs.leaseLock.Unlock()
+
+	l, ok := lfs.leases[options.Name]
+	if !ok {
+		return nil
+	}
+
+	delete(l.holders, options.Handle.ID)
+	if len(l.holders) > 0 {
+		return nil
+	}
+
+	delete(lfs.leases, options.Name)
+	return l.file.Close()
+}
+
 func (lfs *LoopbackFS) RenameFile(options internal.RenameFileOptions) error {
 	log.Trace("LoopbackFS::RenameFile : %s -> %s", options.Src, options.Dst)
 	oldPath := filepath.Join(lfs.path, options.Src)
@@ -435,12 +554,14 @@ func (lfs *LoopbackFS) CopyFromFile(options internal.CopyFromFileOptions) error
 		log.Err("LoopbackFS::CopyFromFile : error opening [%s]", err)
 		return err
 	}
+	defer fdst.Close()
+
 	_, err = io.Copy(fdst, options.File)
 	if err != nil {
 		log.Err("LoopbackFS::CopyFromFile : error copying [%s]", err)
 		return err
 	}
-	return nil
+	return writeMetadata(path, options.Metadata)
 }
 
 func (lfs *LoopbackFS) GetAttr(options internal.GetAttrOptions) (*internal.ObjAttr, error) {
@@ -458,7 +579,13 @@ func (lfs *LoopbackFS) GetAttr(options internal.GetAttrOptions) (*internal.ObjAt
 		Mode:  info.Mode(),
 		Mtime: info.ModTime(),
 	}
+	// Emulate an ETag which changes whenever the content of the file changes
+	attr.ETag = fmt.Sprintf("%x-%x", info.ModTime().UnixNano(), info.Size())
 	attr.Flags.Set(internal.PropFlagModeDefault)
+	attr.Metadata = readMetadata(path)
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		attr.Nlink = uint64(stat.Nlink)
+	}
 
 	if info.Mode()&os.ModeSymlink != 0 {
 		_, err := os.Readlink(path)
@@ -485,14 +612,166 @@ func (lfs *LoopbackFS) Chown(options internal.ChownOptions) error {
 	return os.Chown(path, options.Owner, options.Group)
 }
 
+func (lfs *LoopbackFS) GetXattr(options internal.GetXattrOptions) ([]byte, error) {
+	log.Trace("LoopbackFS::GetXattr : name=%s, attr=%s", options.Name, options.Attr)
+	path := filepath.Join(lfs.path, options.Name)
+	size, err := syscall.Getxattr(path, options.Attr, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	value := make([]byte, size)
+	size, err = syscall.Getxattr(path, options.Attr, value)
+	if err != nil {
+		return nil, err
+	}
+	return value[:size], nil
+}
+
+func (lfs *LoopbackFS) SetXattr(options internal.SetXattrOptions) error {
+	log.Trace("LoopbackFS::SetXattr : name=%s, attr=%s", options.Name, options.Attr)
+	path := filepath.Join(lfs.path, options.Name)
+	return syscall.Setxattr(path, options.Attr, options.Value, options.Flags)
+}
+
+func (lfs *LoopbackFS) ListXattr(options internal.ListXattrOptions) ([]string, error) {
+	log.Trace("LoopbackFS::ListXattr : name=%s", options.Name)
+	path := filepath.Join(lfs.path, options.Name)
+	size, err := syscall.Listxattr(path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, size)
+	size, err = syscall.Listxattr(path, buf)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0)
+	for _, name := range strings.Split(string(buf[:size]), "\x00") {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+func (lfs *LoopbackFS) RemoveXattr(options internal.RemoveXattrOptions) error {
+	log.Trace("LoopbackFS::RemoveXattr : name=%s, attr=%s", options.Name, options.Attr)
+	path := filepath.Join(lfs.path, options.Name)
+	return syscall.Removexattr(path, options.Attr)
+}
+
+// Blob metadata is emulated by extended attributes of the user namespace, the way azstorage exposes it
+const metadataXattrPrefix = "user."
+
+// readMetadata : Collect the emulated blob metadata of a path, nil if there is none
+func readMetadata(path string) map[string]*string {
+	size, err := syscall.Listxattr(path, nil)
+	if err != nil || size == 0 {
+		return nil
+	}
+
+	buf := make([]byte, size)
+	size, err = syscall.Listxattr(path, buf)
+	if err != nil {
+		return nil
+	}
+
+	var metadata map[string]*string
+	for _, name := range strings.Split(string(buf[:size]), "\x00") {
+		if !strings.HasPrefix(name, metadataXattrPrefix) {
+			continue
+		}
+
+		vsize, err := syscall.Getxattr(path, name, nil)
+		if err != nil {
+			continue
+		}
+		value := make([]byte, vsize)
+		vsize, err = syscall.Getxattr(path, name, value)
+		if err != nil {
+			continue
+		}
+
+		if metadata == nil {
+			metadata = make(map[string]*string)
+		}
+		v := string(value[:vsize])
+		metadata[strings.TrimPrefix(name, metadataXattrPrefix)] = &v
+	}
+
+	return metadata
+}
+
+// writeMetadata : Replace the emulated blob metadata of a path, nil metadata leaves the existing one untouched
+func writeMetadata(path string, metadata map[string]*string) error {
+	if metadata == nil {
+		return nil
+	}
+
+	for key := range readMetadata(path) {
+		_ = syscall.Removexattr(path, metadataXattrPrefix+key)
+	}
+
+	for key, value := range metadata {
+		if value == nil {
+			continue
+		}
+		err := syscall.Setxattr(path, metadataXattrPrefix+key, []byte(*value), 0)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (lfs *LoopbackFS) StageData(options internal.StageDataOptions) error {
 	log.Trace("LoopbackFS::StageData : name=%s, id=%s", options.Name, options.Id)
 	path := fmt.Sprintf("%s_%s", filepath.Join(lfs.path, options.Name), strings.ReplaceAll(options.Id, "/", "_"))
 	return os.WriteFile(path, options.Data, 0777)
 }
 
+// committedBlock : Position of a block in the blob as of its last commit
+type committedBlock struct {
+	id     string
+	offset int64
+	size   int64
+}
+
+// Blobs not written through CommitData are reported as a list of 1MB blocks
+const defaultLoopbackBlockSize = int64(1 * 1024 * 1024)
+
+// lastCommit : Blocks making up the blob, as recorded by the last commit if it still describes the data
+func (lfs *LoopbackFS) lastCommit(name string, size int64) []committedBlock {
+	if val, found := lfs.committed.Load(name); found {
+		blocks := val.([]committedBlock)
+		if len(blocks) > 0 && blocks[len(blocks)-1].offset+blocks[len(blocks)-1].size == size {
+			return blocks
+		}
+	}
+
+	blocks := make([]committedBlock, 0)
+	for offset := int64(0); offset < size; offset += defaultLoopbackBlockSize {
+		blocks = append(blocks, committedBlock{
+			id:     fmt.Sprintf("%d", offset/defaultLoopbackBlockSize),
+			offset: offset,
+			size:   min(defaultLoopbackBlockSize, size-offset),
+		})
+	}
+	return blocks
+}
+
+func (lfs *LoopbackFS) stagedPath(name string, id string) string {
+	return fmt.Sprintf("%s_%s", filepath.Join(lfs.path, name), strings.ReplaceAll(id, "/", "_"))
+}
+
+// CommitData : Like the service the blob becomes the concatenation of the listed blocks, which are either
+// staged or part of the current block list
 func (lfs *LoopbackFS) CommitData(options internal.CommitDataOptions) error {
-	log.Trace("LoopbackFS::StageData : name=%s", options.Name)
+	log.Trace("LoopbackFS::CommitData : name=%s", options.Name)
 
 	mainFilepath := filepath.Join(lfs.path, options.Name)
 
@@ -501,56 +780,106 @@ func (lfs *LoopbackFS) CommitData(options internal.CommitDataOptions) error {
 		log.Err("LoopbackFS::CommitData : error opening [%s]", err)
 		return err
 	}
+	defer blob.Close()
 
-	if len(options.List) == 0 {
-		err = blob.Truncate(0)
-		if err != nil {
-			return err
+	info, err := blob.Stat()
+	if err != nil {
+		return err
+	}
+
+	last := make(map[string]committedBlock)
+	for _, block := range lfs.lastCommit(options.Name, info.Size()) {
+		if _, found := last[block.id]; !found {
+			last[block.id] = block
 		}
 	}
 
-	for idx, id := range options.List {
-		path := fmt.Sprintf("%s_%s", filepath.Join(lfs.path, options.Name), strings.ReplaceAll(id, "/", "_"))
-		info, err := os.Lstat(path)
-		if err == nil {
-			block, err := os.OpenFile(path, os.O_RDONLY, os.FileMode(0666))
-			if err != nil {
-				return err
-			}
+	// Lay out the new list, staged blocks take precedence over the committed ones
+	blocks := make([]committedBlock, 0, len(options.List))
+	staged := make(map[string]bool)
+	offset := int64(0)
+	for _, id := range options.List {
+		size := int64(0)
+		if stat, err := os.Lstat(lfs.stagedPath(options.Name, id)); err == nil {
+			staged[id] = true
+			size = stat.Size()
+		} else if block, found := last[id]; found {
+			size = block.size
+		} else {
+			log.Err("LoopbackFS::CommitData : block %s of %s is neither staged nor committed", id, options.Name)
+			return syscall.EINVAL
+		}
 
-			data := make([]byte, info.Size())
-			n, err := block.Read(data)
-			if int64(n) != info.Size() {
-				log.Err("LoopbackFS::CommitData : error [could not read entire file]")
-				return err
-			}
+		blocks = append(blocks, committedBlock{id: id, offset: offset, size: size})
+		offset += size
+	}
 
-			n, err = blob.WriteAt(data, int64(idx*(int)(options.BlockSize)))
-			if err != nil {
-				return err
-			}
-			if int64(n) != info.Size() {
-				log.Err("LoopbackFS::CommitData : error [could not write file]")
-				return err
-			}
+	moved, err := lfs.readMovedBlocks(blob, blocks, last, staged)
+	if err != nil {
+		log.Err("LoopbackFS::CommitData : error reading committed blocks [%s]", err)
+		return err
+	}
 
-			err = block.Close()
+	for _, block := range blocks {
+		var data []byte
+		if staged[block.id] {
+			data, err = os.ReadFile(lfs.stagedPath(options.Name, block.id))
 			if err != nil {
 				return err
 			}
-		} else if !os.IsNotExist(err) {
+		} else if data = moved[block.id]; data == nil {
+			// Block is already in place
+			continue
+		}
+
+		_, err = blob.WriteAt(data, block.offset)
+		if err != nil {
 			return err
 		}
 	}
 
+	// Committed list replaces the blob, so drop any data beyond the last block
+	err = blob.Truncate(offset)
+	if err != nil {
+		return err
+	}
+
+	lfs.committed.Store(options.Name, blocks)
+
+	err = writeMetadata(mainFilepath, options.Metadata)
+	if err != nil {
+		log.Err("LoopbackFS::CommitData : error setting metadata [%s]", err)
+		return err
+	}
+
 	// delete the staged files
-	for _, id := range options.List {
-		path := fmt.Sprintf("%s_%s", filepath.Join(lfs.path, options.Name), strings.ReplaceAll(id, "/", "_"))
-		_ = os.Remove(path)
+	for id := range staged {
+		_ = os.Remove(lfs.stagedPath(options.Name, id))
 	}
 
-	err = blob.Close()
-	return err
+	return nil
+}
+
+// readMovedBlocks : Read blocks which are committed again at a different position than their last commit,
+// before the blob gets overwritten by the new block list
+func (lfs *LoopbackFS) readMovedBlocks(blob *os.File, blocks []committedBlock, last map[string]committedBlock, staged map[string]bool) (map[string][]byte, error) {
+	moved := make(map[string][]byte)
+
+	for _, block := range blocks {
+		old, found := last[block.id]
+		if staged[block.id] || !found || old.offset == block.offset || moved[block.id] != nil {
+			continue
+		}
+
+		data := make([]byte, old.size)
+		_, err := blob.ReadAt(data, old.offset)
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		moved[block.id] = data
+	}
+
+	return moved, nil
 }
 
 func (lfs *LoopbackFS) GetCommittedBlockList(name string) (*internal.CommittedBlockList, error) {
@@ -561,15 +890,12 @@ func (lfs *LoopbackFS) GetCommittedBlockList(name string) (*internal.CommittedBl
 		return nil, err
 	}
 
-	blockSize := uint64(1 * 1024 * 1024)
-	blocks := info.Size() / (int64)(blockSize)
 	list := make(internal.CommittedBlockList, 0)
-
-	for i := range blocks {
+	for _, block := range lfs.lastCommit(name, info.Size()) {
 		list = append(list, internal.CommittedBlock{
-			Id:     fmt.Sprintf("%d", i),
-			Offset: i * (int64)(blockSize),
-			Size:   blockSize,
+			Id:     block.id,
+			Offset: block.offset,
+			Size:   uint64(block.size),
 		})
 	}
 
@@ -577,7 +903,9 @@ func (lfs *LoopbackFS) GetCommittedBlockList(name string) (*internal.CommittedBl
 }
 
 func NewLoopbackFSComponent() internal.Component {
-	lfs := &LoopbackFS{}
+	lfs := &LoopbackFS{
+		leases: make(map[string]*fileLease),
+	}
 	lfs.SetName(compName)
 	return lfs
 }
@@ -38,10 +38,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"syscall"
 	"testing"
 
 	"github.com/Azure/azure-storage-fuse/v2/common"
 	"github.com/Azure/azure-storage-fuse/v2/internal"
+	"github.com/Azure/azure-storage-fuse/v2/internal/handlemap"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
@@ -285,6 +287,136 @@ func (suite *LoopbackFSTestSuite) TestGetAttr() {
 	assert.Equal(attr.IsDir(), info.IsDir())
 }
 
+func (suite *LoopbackFSTestSuite) TestCreateHardLink() {
+	defer suite.cleanupTest()
+	assert := assert.New(suite.T())
+
+	err := suite.lfs.CreateHardLink(internal.CreateHardLinkOptions{Name: "lorem_link", Target: fileLorem})
+	assert.NoError(err, "CreateHardLink: Failed")
+
+	attr, err := suite.lfs.GetAttr(internal.GetAttrOptions{Name: fileLorem})
+	assert.NoError(err)
+	assert.EqualValues(2, attr.Nlink)
+
+	attr, err = suite.lfs.GetAttr(internal.GetAttrOptions{Name: "lorem_link"})
+	assert.NoError(err)
+	assert.EqualValues(2, attr.Nlink)
+
+	err = suite.lfs.CreateHardLink(internal.CreateHardLinkOptions{Name: "lorem_link", Target: fileLorem})
+	assert.True(os.IsExist(err))
+}
+
+func (suite *LoopbackFSTestSuite) TestFallocate() {
+	defer suite.cleanupTest()
+	assert := assert.New(suite.T())
+
+	before, err := os.ReadFile(filepath.Join(suite.lfs.path, fileLorem))
+	assert.NoError(err)
+
+	err = suite.lfs.Fallocate(internal.FallocateOptions{Name: fileLorem, Mode: internal.FallocPunchHole | internal.FallocKeepSize, Offset: 0, Length: 5})
+	assert.NoError(err, "Fallocate: Failed")
+
+	after, err := os.ReadFile(filepath.Join(suite.lfs.path, fileLorem))
+	assert.NoError(err)
+	assert.Len(after, len(before))
+	assert.Equal(make([]byte, 5), after[:5])
+	assert.Equal(before[5:], after[5:])
+}
+
+func (suite *LoopbackFSTestSuite) TestLseek() {
+	defer suite.cleanupTest()
+	assert := assert.New(suite.T())
+
+	offset, err := suite.lfs.Lseek(internal.LseekOptions{Name: fileLorem, Offset: 0, Whence: internal.SeekHole})
+	assert.NoError(err, "Lseek: Failed")
+	assert.EqualValues(len(loremText), offset)
+
+	_, err = suite.lfs.Lseek(internal.LseekOptions{Name: fileLorem, Offset: int64(len(loremText)), Whence: internal.SeekData})
+	assert.Equal(syscall.ENXIO, err)
+}
+
+func (suite *LoopbackFSTestSuite) TestCopyFileRange() {
+	defer suite.cleanupTest()
+	assert := assert.New(suite.T())
+
+	_, err := suite.lfs.CreateFile(internal.CreateFileOptions{Name: "lorem_copy", Mode: 0777})
+	assert.NoError(err)
+
+	copied, err := suite.lfs.CopyFileRange(internal.CopyFileRangeOptions{SrcName: fileLorem, SrcOffset: 6, DstName: "lorem_copy", DstOffset: 2, Length: 10})
+	assert.NoError(err, "CopyFileRange: Failed")
+	assert.EqualValues(10, copied)
+
+	data, err := os.ReadFile(filepath.Join(suite.lfs.path, "lorem_copy"))
+	assert.NoError(err)
+	assert.Equal(make([]byte, 2), data[:2])
+	assert.Equal([]byte(loremText[6:16]), data[2:])
+
+	// Copy stops at the end of source
+	copied, err = suite.lfs.CopyFileRange(internal.CopyFileRangeOptions{SrcName: fileLorem, SrcOffset: int64(len(loremText) - 4), DstName: "lorem_copy", Length: 10})
+	assert.NoError(err)
+	assert.EqualValues(4, copied)
+}
+
+func (suite *LoopbackFSTestSuite) TestLockFile() {
+	defer suite.cleanupTest()
+	assert := assert.New(suite.T())
+
+	// A second instance on the same path stands in for another node
+	other := NewLoopbackFSComponent().(*LoopbackFS)
+	other.path = testPath
+
+	h1 := handlemap.NewHandle(fileLorem)
+	h2 := handlemap.NewHandle(fileLorem)
+	h3 := handlemap.NewHandle(fileLorem)
+	h1.ID, h2.ID, h3.ID = 1, 2, 3
+
+	err := suite.lfs.LockFile(internal.LockFileOptions{Handle: h1, Name: fileLorem})
+	assert.NoError(err)
+	err = suite.lfs.LockFile(internal.LockFileOptions{Handle: h2, Name: fileLorem})
+	assert.NoError(err)
+
+	err = other.LockFile(internal.LockFileOptions{Handle: h3, Name: fileLorem})
+	assert.Equal(syscall.EWOULDBLOCK, err)
+
+	// Lock stays while any handle of the instance holds it
+	err = suite.lfs.UnlockFile(internal.UnlockFileOptions{Handle: h1, Name: fileLorem})
+	assert.NoError(err)
+	err = other.LockFile(internal.LockFileOptions{Handle: h3, Name: fileLorem})
+	assert.Equal(syscall.EWOULDBLOCK, err)
+
+	err = suite.lfs.UnlockFile(internal.UnlockFileOptions{Handle: h2, Name: fileLorem})
+	assert.NoError(err)
+	err = other.LockFile(internal.LockFileOptions{Handle: h3, Name: fileLorem})
+	assert.NoError(err)
+	err = other.UnlockFile(internal.UnlockFileOptions{Handle: h3, Name: fileLorem})
+	assert.NoError(err)
+
+	err = suite.lfs.LockFile(internal.LockFileOptions{Handle: h1, Name: "missing"})
+	assert.Equal(syscall.ENOENT, err)
+}
+
+func (suite *LoopbackFSTestSuite) TestXattr() {
+	defer suite.cleanupTest()
+	assert := assert.New(suite.T())
+
+	err := suite.lfs.SetXattr(internal.SetXattrOptions{Name: fileLorem, Attr: "user.owner", Value: []byte("team1")})
+	assert.NoError(err)
+
+	value, err := suite.lfs.GetXattr(internal.GetXattrOptions{Name: fileLorem, Attr: "user.owner"})
+	assert.NoError(err)
+	assert.Equal("team1", string(value))
+
+	names, err := suite.lfs.ListXattr(internal.ListXattrOptions{Name: fileLorem})
+	assert.NoError(err)
+	assert.Contains(names, "user.owner")
+
+	err = suite.lfs.RemoveXattr(internal.RemoveXattrOptions{Name: fileLorem, Attr: "user.owner"})
+	assert.NoError(err)
+
+	_, err = suite.lfs.GetXattr(internal.GetXattrOptions{Name: fileLorem, Attr: "user.owner"})
+	assert.Equal(syscall.ENODATA, err)
+}
+
 func (suite *LoopbackFSTestSuite) TestStageAndCommitData() {
 	defer suite.cleanupTest()
 	assert := assert.New(suite.T())
@@ -103,15 +103,14 @@ func (rdm *remoteDataManager) Stop() {
 func (rdm *remoteDataManager) Process(item *WorkItem) (int, error) {
 	select {
 	case <-item.Ctx.Done(): // listen for cancellation signal
-		log.Err("remoteDataManager::Process : Cancelling download for offset %v of %v", item.Block.Offset, item.Path)
-		return 0, fmt.Errorf("cancelling download for offset %v of %v", item.Block.Offset, item.Path)
+		log.Err("remoteDataManager::Process : Cancelling transfer for offset %v of %v", item.Block.Offset, item.Path)
+		return 0, fmt.Errorf("cancelling transfer for offset %v of %v", item.Block.Offset, item.Path)
 
 	default:
 		if item.Download {
 			return rdm.ReadData(item)
 		} else {
-			// return rdm.WriteData(item)
-			return 0, fmt.Errorf("uploads are currently not supported, path %v", item.Path)
+			return rdm.WriteData(item)
 		}
 	}
 }
@@ -133,18 +132,16 @@ func (rdm *remoteDataManager) ReadData(item *WorkItem) (int, error) {
 	return bytesTransferred, err
 }
 
-// uncomment this when the support for upload is added
-/*
 // WriteData writes data to the data manager
 func (rdm *remoteDataManager) WriteData(item *WorkItem) (int, error) {
-	// log.Debug("remoteDataManager::WriteData : Scheduling upload for %s offset %v", item.path, item.block.offset)
+	// log.Debug("remoteDataManager::WriteData : Scheduling upload for %s offset %v", item.Path, item.Block.Offset)
 
 	bytesTransferred := int(item.Block.Length)
 	err := rdm.GetRemote().StageData(internal.StageDataOptions{
-		Name: item.Path,
-		Data: item.Block.Data[0:item.Block.Length],
-		// Offset: uint64(item.block.offset),
-		Id: item.Block.Id,
+		Name:   item.Path,
+		Data:   item.Block.Data[0:item.Block.Length],
+		Offset: uint64(item.Block.Offset),
+		Id:     item.Block.Id,
 	})
 	if err != nil {
 		log.Err("remoteDataManager::WriteData : upload failed for %s offset %v [%v]", item.Path, item.Block.Offset, err.Error())
@@ -156,7 +153,6 @@ func (rdm *remoteDataManager) WriteData(item *WorkItem) (int, error) {
 
 	return bytesTransferred, err
 }
-*/
 
 // send stats to stats manager
 func (rdm *remoteDataManager) sendStats(path string, isDownload bool, bytesTransferred uint64, isSuccess bool) {
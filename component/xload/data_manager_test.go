@@ -77,13 +77,24 @@ func (suite *dataManagerTestSuite) TestNewRemoteDataManager() {
 }
 
 func (suite *dataManagerTestSuite) TestProcessErrors() {
-	rdm := &remoteDataManager{}
+	remote := loopback.NewLoopbackFSComponent()
+	statsMgr, err := NewStatsManager(1, false, nil)
+	suite.assert.NoError(err)
+	statsMgr.Start()
+	defer statsMgr.Stop()
+
+	rdm, err := newRemoteDataManager(&remoteDataManagerOptions{
+		workerCount: 1,
+		remote:      remote,
+		statsMgr:    statsMgr,
+	})
+	suite.assert.NoError(err)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	item := &WorkItem{
 		CompName: DATA_MANAGER,
-		Path:     "test",
-		Block:    &Block{},
+		Path:     "dir_not_present/test",
+		Block:    &Block{Data: make([]byte, 1), Length: 1},
 		Download: false,
 		Ctx:      ctx,
 	}
@@ -34,12 +34,16 @@
 package xload
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"syscall"
 	"time"
 
+	"github.com/Azure/azure-storage-fuse/v2/common"
 	"github.com/Azure/azure-storage-fuse/v2/common/config"
 	"github.com/Azure/azure-storage-fuse/v2/common/log"
 	"github.com/Azure/azure-storage-fuse/v2/internal"
@@ -48,9 +52,12 @@ import (
 // verify that the below types implement the xcomponent interfaces
 var _ XComponent = &lister{}
 var _ XComponent = &remoteLister{}
+var _ XComponent = &localLister{}
+var _ XComponent = &syncLister{}
 
 // verify that the below types implement the xenumerator interfaces
 var _ enumerator = &remoteLister{}
+var _ enumerator = &localLister{}
 
 type lister struct {
 	XBase
@@ -247,3 +254,592 @@ func (rl *remoteLister) mkdir(name string) error {
 	})
 	return err
 }
+
+// --------------------------------------------------------------------------------------------------------
+
+type localLister struct {
+	lister
+}
+
+type localListerOptions struct {
+	path              string
+	workerCount       uint32
+	defaultPermission os.FileMode
+	remote            internal.Component
+	statsMgr          *StatsManager
+}
+
+func newLocalLister(opts *localListerOptions) (*localLister, error) {
+	if opts == nil || opts.path == "" || opts.remote == nil || opts.statsMgr == nil || opts.workerCount == 0 {
+		log.Err("lister::NewLocalLister : invalid parameters sent to create local lister")
+		return nil, fmt.Errorf("invalid parameters sent to create local lister")
+	}
+
+	log.Debug("lister::NewLocalLister : create new local lister for %s, default permission %v, workers %v", opts.path, opts.defaultPermission, opts.workerCount)
+
+	ll := &localLister{
+		lister: lister{
+			path:              opts.path,
+			defaultPermission: opts.defaultPermission,
+		},
+	}
+
+	ll.SetName(LISTER)
+	ll.SetWorkerCount(opts.workerCount)
+	ll.SetRemote(opts.remote)
+	ll.SetStatsManager(opts.statsMgr)
+	ll.Init()
+	return ll, nil
+}
+
+func (ll *localLister) Init() {
+	ll.SetThreadPool(NewThreadPool(ll.GetWorkerCount(), ll.Process))
+	if ll.GetThreadPool() == nil {
+		log.Err("localLister::Init : fail to init thread pool")
+	}
+}
+
+func (ll *localLister) Start(ctx context.Context) {
+	log.Debug("localLister::Start : start local lister for %s", ll.path)
+	ll.GetThreadPool().Start(ctx)
+	_ = ll.Schedule(&WorkItem{CompName: ll.GetName()})
+}
+
+func (ll *localLister) Stop() {
+	log.Debug("localLister::Stop : stop local lister for %s", ll.path)
+	if ll.GetThreadPool() != nil {
+		ll.GetThreadPool().Stop()
+	}
+	log.Debug("localLister::Stop : stop successful")
+}
+
+func (ll *localLister) Process(item *WorkItem) (int, error) {
+	relPath := item.Path
+	localPath := filepath.Join(ll.path, relPath)
+
+	log.Debug("localLister::Process : Reading local dir %s", localPath)
+
+	entries, err := os.ReadDir(localPath)
+	if err != nil {
+		log.Err("localLister::Process : Local listing failed for %s [%s]", localPath, err.Error())
+		return 0, err
+	}
+
+	// only directories and regular files are uploaded, symlinks, pipes and sockets are skipped
+	eligible := make([]os.DirEntry, 0, len(entries))
+	for _, entry := range entries {
+		if relPath == "" && entry.Name() == SYNC_STATE_FILE {
+			continue
+		}
+
+		if entry.IsDir() || entry.Type().IsRegular() {
+			eligible = append(eligible, entry)
+		} else {
+			log.Debug("localLister::Process : Skipping non regular file %s", filepath.Join(relPath, entry.Name()))
+		}
+	}
+
+	// send number of items listed in this directory to stats manager
+	ll.GetStatsManager().AddStats(&StatsItem{
+		Component:   LISTER,
+		Name:        relPath,
+		ListerCount: uint64(len(eligible)),
+	})
+
+	for _, entry := range eligible {
+		name := filepath.Join(relPath, entry.Name())
+		log.Debug("localLister::Process : Iterating: %s, Is directory: %v", name, entry.IsDir())
+
+		if entry.IsDir() {
+			// create directory in the container before listing it, so that the files
+			// inside it are uploaded only once their parent exists on the remote side
+			go func(name string) {
+				err := ll.mkdir(name)
+				if err != nil {
+					log.Err("localLister::Process : Failed to create directory %s [%s]", name, err.Error())
+					return
+				}
+
+				// push the directory to input pool for its listing
+				err = ll.Schedule(&WorkItem{
+					CompName: ll.GetName(),
+					Path:     name,
+				})
+				if err != nil {
+					log.Err("localLister::Process : Failed to schedule directory listing for %s [%s]", name, err.Error())
+					return
+				}
+			}(name)
+		} else {
+			info, err := entry.Info()
+			if err != nil {
+				// file got deleted after listing, mark it as failed so that the progress can complete
+				log.Err("localLister::Process : Failed to get info of %s [%s]", name, err.Error())
+				ll.GetStatsManager().AddStats(&StatsItem{
+					Component: SPLITTER,
+					Name:      name,
+					Success:   false,
+					Download:  false,
+				})
+				continue
+			}
+
+			// send file to the splitter's channel for chunking
+			err = ll.GetNext().Schedule(&WorkItem{
+				CompName: ll.GetNext().GetName(),
+				Path:     name,
+				DataLen:  uint64(info.Size()),
+				Mode:     info.Mode().Perm(),
+				Atime:    getAccessTime(info),
+				Mtime:    info.ModTime(),
+			})
+			if err != nil {
+				log.Err("localLister::Process : Failed to schedule file %s for processing [%s]", name, err.Error())
+				return 0, err
+			}
+		}
+	}
+
+	log.Debug("localLister::Process : local listing done for %s", relPath)
+	return len(eligible), nil
+}
+
+func (ll *localLister) mkdir(name string) error {
+	log.Debug("localLister::mkdir : Creating remote path: %s, mode %v", name, ll.defaultPermission)
+	err := ll.GetRemote().CreateDir(internal.CreateDirOptions{
+		Name: name,
+		Mode: ll.defaultPermission,
+	})
+	if err != nil && (os.IsExist(err) || errors.Is(err, syscall.EEXIST)) {
+		// directory is already present in the container
+		err = nil
+	}
+
+	// send stats for dir creation
+	ll.GetStatsManager().AddStats(&StatsItem{
+		Component: LISTER,
+		Name:      name,
+		Dir:       true,
+		Success:   err == nil,
+		Download:  false,
+	})
+	return err
+}
+
+// --------------------------------------------------------------------------------------------------------
+
+// direction in which a file needs to be transferred to bring both the sides in sync
+type syncAction int
+
+const (
+	syncActionNone syncAction = iota
+	syncActionDownload
+	syncActionUpload
+	syncActionDeleteLocal  // synced earlier and since deleted from container
+	syncActionDeleteRemote // synced earlier and since deleted from local path
+)
+
+// syncEntry is a file or directory as seen on local path and in container, nil if absent on that side
+type syncEntry struct {
+	name   string
+	local  os.FileInfo
+	remote *internal.ObjAttr
+}
+
+type syncLister struct {
+	lister
+	state       *syncState     // state of the files when they were last in sync
+	policy      ConflictPolicy // policy to resolve files changed on both sides
+	listBlocked bool
+}
+
+type syncListerOptions struct {
+	path              string
+	workerCount       uint32
+	defaultPermission os.FileMode
+	remote            internal.Component
+	statsMgr          *StatsManager
+	state             *syncState
+	policy            ConflictPolicy
+}
+
+func newSyncLister(opts *syncListerOptions) (*syncLister, error) {
+	if opts == nil || opts.path == "" || opts.remote == nil || opts.statsMgr == nil || opts.state == nil || opts.workerCount == 0 {
+		log.Err("lister::NewSyncLister : invalid parameters sent to create sync lister")
+		return nil, fmt.Errorf("invalid parameters sent to create sync lister")
+	}
+
+	log.Debug("lister::NewSyncLister : create new sync lister for %s, default permission %v, workers %v, conflict policy %v",
+		opts.path, opts.defaultPermission, opts.workerCount, opts.policy.String())
+
+	sl := &syncLister{
+		lister: lister{
+			path:              opts.path,
+			defaultPermission: opts.defaultPermission,
+		},
+		state:       opts.state,
+		policy:      opts.policy,
+		listBlocked: false,
+	}
+
+	sl.SetName(LISTER)
+	sl.SetWorkerCount(opts.workerCount)
+	sl.SetRemote(opts.remote)
+	sl.SetStatsManager(opts.statsMgr)
+	sl.Init()
+	return sl, nil
+}
+
+func (sl *syncLister) Init() {
+	sl.SetThreadPool(NewThreadPool(sl.GetWorkerCount(), sl.Process))
+	if sl.GetThreadPool() == nil {
+		log.Err("syncLister::Init : fail to init thread pool")
+	}
+}
+
+func (sl *syncLister) Start(ctx context.Context) {
+	log.Debug("syncLister::Start : start sync lister for %s", sl.path)
+	sl.GetThreadPool().Start(ctx)
+	_ = sl.Schedule(&WorkItem{CompName: sl.GetName()})
+}
+
+func (sl *syncLister) Stop() {
+	log.Debug("syncLister::Stop : stop sync lister for %s", sl.path)
+	if sl.GetThreadPool() != nil {
+		sl.GetThreadPool().Stop()
+	}
+	log.Debug("syncLister::Stop : stop successful")
+}
+
+// list the directory on both the sides and decide the direction of transfer for each file
+func (sl *syncLister) Process(item *WorkItem) (int, error) {
+	relPath := item.Path
+
+	log.Debug("syncLister::Process : Reading dir %s", relPath)
+
+	// this block will be executed only in the first list call for the remote directory
+	if !sl.listBlocked {
+		log.Debug("syncLister::Process : Waiting for block-list-on-mount-sec before making the list call")
+		err := waitForListTimeout()
+		if err != nil {
+			log.Err("syncLister::Process : unable to unmarshal block-list-on-mount-sec [%s]", err.Error())
+			return 0, err
+		}
+		sl.listBlocked = true
+	}
+
+	entries, err := sl.listEntries(relPath)
+	if err != nil {
+		log.Err("syncLister::Process : Listing failed for %s [%s]", relPath, err.Error())
+		return 0, err
+	}
+
+	// send number of items listed in this directory to stats manager
+	sl.GetStatsManager().AddStats(&StatsItem{
+		Component:   LISTER,
+		Name:        relPath,
+		ListerCount: uint64(len(entries)),
+	})
+
+	for _, entry := range entries {
+		localIsDir := entry.local != nil && entry.local.IsDir()
+		remoteIsDir := entry.remote != nil && entry.remote.IsDir()
+
+		if (entry.local != nil && entry.remote != nil) && localIsDir != remoteIsDir {
+			log.Err("syncLister::Process : %s is a file on one side and a directory on the other, skipping it", entry.name)
+			sl.sendStatus(entry.name, false, false)
+			continue
+		}
+
+		if localIsDir || remoteIsDir {
+			// make sure the directory exists on both sides and then list it
+			go func(entry *syncEntry) {
+				err := sl.mkdir(entry)
+				if err != nil {
+					log.Err("syncLister::Process : Failed to create directory %s [%s]", entry.name, err.Error())
+					return
+				}
+
+				err = sl.Schedule(&WorkItem{
+					CompName: sl.GetName(),
+					Path:     entry.name,
+				})
+				if err != nil {
+					log.Err("syncLister::Process : Failed to schedule directory listing for %s [%s]", entry.name, err.Error())
+					return
+				}
+			}(entry)
+			continue
+		}
+
+		switch sl.getSyncAction(entry) {
+		case syncActionDownload:
+			fileMode := sl.defaultPermission
+			if !entry.remote.IsModeDefault() {
+				fileMode = entry.remote.Mode
+			}
+
+			err = sl.GetNext().Schedule(&WorkItem{
+				CompName: sl.GetNext().GetName(),
+				Path:     entry.name,
+				DataLen:  uint64(entry.remote.Size),
+				Mode:     fileMode,
+				Atime:    entry.remote.Atime,
+				Mtime:    entry.remote.Mtime,
+				MD5:      entry.remote.MD5,
+				Download: true,
+			})
+
+		case syncActionUpload:
+			err = sl.GetNext().Schedule(&WorkItem{
+				CompName: sl.GetNext().GetName(),
+				Path:     entry.name,
+				DataLen:  uint64(entry.local.Size()),
+				Mode:     entry.local.Mode().Perm(),
+				Atime:    getAccessTime(entry.local),
+				Mtime:    entry.local.ModTime(),
+				Download: false,
+			})
+
+		case syncActionDeleteLocal:
+			sl.deleteFile(entry, true)
+
+		case syncActionDeleteRemote:
+			sl.deleteFile(entry, false)
+
+		default:
+			log.Debug("syncLister::Process : %s is in sync", entry.name)
+			sl.state.set(entry.name, newSyncRecord(entry.local, entry.remote))
+			sl.sendStatus(entry.name, true, true)
+		}
+
+		if err != nil {
+			log.Err("syncLister::Process : Failed to schedule file %s for processing [%s]", entry.name, err.Error())
+			return 0, err
+		}
+	}
+
+	log.Debug("syncLister::Process : listing done for %s", relPath)
+	return len(entries), nil
+}
+
+// listEntries merges the listing of the directory from local path and container
+func (sl *syncLister) listEntries(relPath string) ([]*syncEntry, error) {
+	entries := make(map[string]*syncEntry)
+
+	marker := ""
+	for {
+		remoteEntries, newMarker, err := sl.GetRemote().StreamDir(internal.StreamDirOptions{
+			Name:  relPath,
+			Token: marker,
+		})
+		if err != nil {
+			log.Err("syncLister::listEntries : Remote listing failed for %s [%s]", relPath, err.Error())
+			return nil, err
+		}
+
+		for _, attr := range remoteEntries {
+			if attr.IsSymlink() {
+				log.Debug("syncLister::listEntries : Skipping symlink %s", attr.Path)
+				continue
+			}
+			entries[attr.Path] = &syncEntry{name: attr.Path, remote: attr}
+		}
+
+		marker = newMarker
+		if len(newMarker) == 0 {
+			break
+		}
+	}
+
+	localEntries, err := os.ReadDir(filepath.Join(sl.path, relPath))
+	if err != nil && !os.IsNotExist(err) {
+		log.Err("syncLister::listEntries : Local listing failed for %s [%s]", relPath, err.Error())
+		return nil, err
+	}
+
+	for _, dirEntry := range localEntries {
+		name := filepath.Join(relPath, dirEntry.Name())
+		if name == SYNC_STATE_FILE || name == SYNC_STATE_FILE+".tmp" {
+			continue
+		}
+
+		// only directories and regular files are synced
+		if !dirEntry.IsDir() && !dirEntry.Type().IsRegular() {
+			log.Debug("syncLister::listEntries : Skipping non regular file %s", name)
+			continue
+		}
+
+		info, err := dirEntry.Info()
+		if err != nil {
+			log.Err("syncLister::listEntries : Failed to get info of %s [%s]", name, err.Error())
+			continue
+		}
+
+		entry, ok := entries[name]
+		if !ok {
+			entry = &syncEntry{name: name}
+			entries[name] = entry
+		}
+		entry.local = info
+	}
+
+	list := make([]*syncEntry, 0, len(entries))
+	for _, entry := range entries {
+		list = append(list, entry)
+	}
+
+	return list, nil
+}
+
+// getSyncAction compares the file on both the sides against the last synced state to find the direction of transfer
+func (sl *syncLister) getSyncAction(entry *syncEntry) syncAction {
+	rec := sl.state.get(entry.name)
+
+	// a file missing on one side is new on the other unless it was synced before, in which case it was deleted.
+	// A deleted file changed on the other side since the last sync is transferred again so that no update is lost.
+	if entry.local == nil && entry.remote == nil {
+		return syncActionNone
+	} else if entry.local == nil {
+		if rec != nil && !rec.remoteChanged(entry.remote) {
+			return syncActionDeleteRemote
+		}
+		return syncActionDownload
+	} else if entry.remote == nil {
+		if rec != nil && !rec.localChanged(entry.local) {
+			return syncActionDeleteLocal
+		}
+		return syncActionUpload
+	}
+
+	if rec == nil {
+		// file was never synced, if the content is same on both sides there is nothing to do
+		if sl.isContentSame(entry) {
+			return syncActionNone
+		}
+		return sl.resolveConflict(entry)
+	}
+
+	localChanged := rec.localChanged(entry.local)
+	remoteChanged := rec.remoteChanged(entry.remote)
+
+	switch {
+	case localChanged && remoteChanged:
+		log.Info("syncLister::getSyncAction : %s has changed on both local path and container", entry.name)
+		return sl.resolveConflict(entry)
+	case localChanged:
+		return syncActionUpload
+	case remoteChanged:
+		return syncActionDownload
+	default:
+		return syncActionNone
+	}
+}
+
+// isContentSame checks if a file never synced before has the same content on both the sides
+func (sl *syncLister) isContentSame(entry *syncEntry) bool {
+	if entry.local.Size() != entry.remote.Size {
+		return false
+	}
+
+	if entry.local.ModTime().Equal(entry.remote.Mtime) {
+		return true
+	}
+
+	if entry.remote.MD5 == nil {
+		return false
+	}
+
+	fh, err := os.Open(filepath.Join(sl.path, entry.name))
+	if err != nil {
+		log.Err("syncLister::isContentSame : Failed to open %s [%s]", entry.name, err.Error())
+		return false
+	}
+	defer fh.Close()
+
+	localMD5, err := common.GetMD5(fh)
+	if err != nil {
+		log.Err("syncLister::isContentSame : Failed to generate MD5Sum for %s [%s]", entry.name, err.Error())
+		return false
+	}
+
+	return bytes.Equal(localMD5, entry.remote.MD5)
+}
+
+// resolveConflict decides the direction of transfer for a file changed on both the sides
+func (sl *syncLister) resolveConflict(entry *syncEntry) syncAction {
+	switch sl.policy {
+	case EConflictPolicy.LOCAL_WINS():
+		return syncActionUpload
+	case EConflictPolicy.REMOTE_WINS():
+		return syncActionDownload
+	default:
+		if entry.local.ModTime().After(entry.remote.Mtime) {
+			return syncActionUpload
+		}
+		return syncActionDownload
+	}
+}
+
+// deleteFile propagates the deletion of a synced file to the other side and forgets its state
+func (sl *syncLister) deleteFile(entry *syncEntry, local bool) {
+	var err error
+	if local {
+		log.Info("syncLister::deleteFile : %s was deleted from container, deleting local file", entry.name)
+		err = os.Remove(filepath.Join(sl.path, entry.name))
+		if os.IsNotExist(err) {
+			err = nil
+		}
+	} else {
+		log.Info("syncLister::deleteFile : %s was deleted from local path, deleting blob", entry.name)
+		err = sl.GetRemote().DeleteFile(internal.DeleteFileOptions{Name: entry.name})
+		if err != nil && (os.IsNotExist(err) || errors.Is(err, syscall.ENOENT)) {
+			err = nil
+		}
+	}
+
+	if err != nil {
+		log.Err("syncLister::deleteFile : Failed to delete %s [%s]", entry.name, err.Error())
+	} else {
+		sl.state.remove(entry.name)
+	}
+	sl.sendStatus(entry.name, err == nil, local)
+}
+
+// mkdir creates the directory on the side where it is missing
+func (sl *syncLister) mkdir(entry *syncEntry) error {
+	var err error
+	if entry.local == nil {
+		log.Debug("syncLister::mkdir : Creating local path: %s, mode %v", entry.name, sl.defaultPermission)
+		err = os.MkdirAll(filepath.Join(sl.path, entry.name), sl.defaultPermission)
+	} else if entry.remote == nil {
+		log.Debug("syncLister::mkdir : Creating remote path: %s, mode %v", entry.name, sl.defaultPermission)
+		err = sl.GetRemote().CreateDir(internal.CreateDirOptions{
+			Name: entry.name,
+			Mode: sl.defaultPermission,
+		})
+		if err != nil && (os.IsExist(err) || errors.Is(err, syscall.EEXIST)) {
+			err = nil
+		}
+	}
+
+	// send stats for dir creation
+	sl.GetStatsManager().AddStats(&StatsItem{
+		Component: LISTER,
+		Name:      entry.name,
+		Dir:       true,
+		Success:   err == nil,
+		Download:  entry.local == nil,
+	})
+	return err
+}
+
+// send the status of a file which is not sent for transfer to stats manager
+func (sl *syncLister) sendStatus(path string, isSuccess bool, isDownload bool) {
+	sl.GetStatsManager().AddStats(&StatsItem{
+		Component: SPLITTER,
+		Name:      path,
+		Success:   isSuccess,
+		Download:  isDownload,
+	})
+}
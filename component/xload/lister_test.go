@@ -294,6 +294,233 @@ func (suite *listTestSuite) TestListerMkdir() {
 	suite.assert.Len(entries, 5)
 }
 
+func (suite *listTestSuite) TestNewLocalLister() {
+	ll, err := newLocalLister(nil)
+	suite.assert.Error(err)
+	suite.assert.Nil(ll)
+	suite.assert.Contains(err.Error(), "invalid parameters sent to create local lister")
+
+	ll, err = newLocalLister(&localListerOptions{
+		path:              "home/user/random_path",
+		workerCount:       4,
+		defaultPermission: common.DefaultFilePermissionBits,
+		remote:            lb,
+		statsMgr:          nil,
+	})
+	suite.assert.Error(err)
+	suite.assert.Nil(ll)
+	suite.assert.Contains(err.Error(), "invalid parameters sent to create local lister")
+
+	statsMgr, err := NewStatsManager(1, false, nil)
+	suite.assert.NoError(err)
+	suite.assert.NotNil(statsMgr)
+
+	ll, err = newLocalLister(&localListerOptions{
+		path:              "home/user/random_path",
+		workerCount:       4,
+		defaultPermission: common.DefaultFilePermissionBits,
+		remote:            lb,
+		statsMgr:          statsMgr,
+	})
+	suite.assert.NoError(err)
+	suite.assert.NotNil(ll)
+}
+
+func (suite *listTestSuite) TestLocalListerStartStop() {
+	tl, err := setupTestLister()
+	suite.assert.NoError(err)
+	suite.assert.NotNil(tl)
+
+	defer func() {
+		err = tl.cleanup()
+		suite.assert.NoError(err)
+	}()
+
+	suite.createDirsAndFiles(tl.path)
+	err = os.Symlink(filepath.Join(tl.path, "file_1"), filepath.Join(tl.path, "link_1"))
+	suite.assert.NoError(err)
+
+	ll, err := newLocalLister(&localListerOptions{
+		path:              tl.path,
+		workerCount:       4,
+		defaultPermission: common.DefaultFilePermissionBits,
+		remote:            lb,
+		statsMgr:          tl.stMgr,
+	})
+	suite.assert.NoError(err)
+	suite.assert.NotNil(ll)
+
+	testComp := getTestcomponent()
+	ll.SetNext(testComp)
+
+	ll.Start(context.TODO())
+	time.Sleep(2 * time.Second)
+	ll.Stop()
+
+	// symlink is skipped, all regular files are sent for upload
+	suite.assert.Equal(int64(60), testComp.ctr.Load())
+}
+
+func (suite *listTestSuite) TestLocalListerMkdir() {
+	tl, err := setupTestLister()
+	suite.assert.NoError(err)
+	suite.assert.NotNil(tl)
+
+	defer func() {
+		err = tl.cleanup()
+		suite.assert.NoError(err)
+	}()
+
+	ll, err := newLocalLister(&localListerOptions{
+		path:              tl.path,
+		workerCount:       4,
+		defaultPermission: common.DefaultFilePermissionBits,
+		remote:            lb,
+		statsMgr:          tl.stMgr,
+	})
+	suite.assert.NoError(err)
+	suite.assert.NotNil(ll)
+
+	dirName := fmt.Sprintf("xlocal_%v", randomString(8))
+	defer os.RemoveAll(filepath.Join(lb_path, dirName))
+
+	err = ll.mkdir(dirName)
+	suite.assert.NoError(err)
+
+	// directory already present in container is not an error
+	err = ll.mkdir(dirName)
+	suite.assert.NoError(err)
+
+	f, err := os.Stat(filepath.Join(lb_path, dirName))
+	suite.assert.NoError(err)
+	suite.assert.True(f.IsDir())
+}
+
+func (suite *listTestSuite) TestNewSyncLister() {
+	sl, err := newSyncLister(nil)
+	suite.assert.Error(err)
+	suite.assert.Nil(sl)
+	suite.assert.Contains(err.Error(), "invalid parameters sent to create sync lister")
+
+	statsMgr, err := NewStatsManager(1, false, nil)
+	suite.assert.NoError(err)
+	suite.assert.NotNil(statsMgr)
+
+	sl, err = newSyncLister(&syncListerOptions{
+		path:              "home/user/random_path",
+		workerCount:       4,
+		defaultPermission: common.DefaultFilePermissionBits,
+		remote:            lb,
+		statsMgr:          statsMgr,
+	})
+	suite.assert.Error(err)
+	suite.assert.Nil(sl)
+	suite.assert.Contains(err.Error(), "invalid parameters sent to create sync lister")
+
+	sl, err = newSyncLister(&syncListerOptions{
+		path:              "home/user/random_path",
+		workerCount:       4,
+		defaultPermission: common.DefaultFilePermissionBits,
+		remote:            lb,
+		statsMgr:          statsMgr,
+		state:             &syncState{records: make(map[string]*syncRecord)},
+		policy:            EConflictPolicy.NEWEST_WINS(),
+	})
+	suite.assert.NoError(err)
+	suite.assert.NotNil(sl)
+}
+
+func (suite *listTestSuite) TestGetSyncAction() {
+	tl, err := setupTestLister()
+	suite.assert.NoError(err)
+	suite.assert.NotNil(tl)
+
+	defer func() {
+		err = tl.cleanup()
+		suite.assert.NoError(err)
+	}()
+
+	sl, err := newSyncLister(&syncListerOptions{
+		path:              tl.path,
+		workerCount:       1,
+		defaultPermission: common.DefaultFilePermissionBits,
+		remote:            lb,
+		statsMgr:          tl.stMgr,
+		state:             &syncState{records: make(map[string]*syncRecord)},
+		policy:            EConflictPolicy.NEWEST_WINS(),
+	})
+	suite.assert.NoError(err)
+
+	name := "sync_file"
+	err = os.WriteFile(filepath.Join(tl.path, name), []byte("local data"), 0666)
+	suite.assert.NoError(err)
+
+	localTime := time.Now().Add(-1 * time.Hour)
+	err = os.Chtimes(filepath.Join(tl.path, name), localTime, localTime)
+	suite.assert.NoError(err)
+
+	local, err := os.Stat(filepath.Join(tl.path, name))
+	suite.assert.NoError(err)
+
+	remoteNewer := &internal.ObjAttr{Path: name, Size: 11, Mtime: localTime.Add(time.Minute), ETag: "etag1"}
+	remoteOlder := &internal.ObjAttr{Path: name, Size: 11, Mtime: localTime.Add(-time.Minute), ETag: "etag1"}
+
+	// file present only on one side
+	suite.assert.Equal(syncActionNone, sl.getSyncAction(&syncEntry{name: name}))
+	suite.assert.Equal(syncActionUpload, sl.getSyncAction(&syncEntry{name: name, local: local}))
+	suite.assert.Equal(syncActionDownload, sl.getSyncAction(&syncEntry{name: name, remote: remoteNewer}))
+
+	// never synced, content same on both sides
+	sameTime := &internal.ObjAttr{Path: name, Size: local.Size(), Mtime: local.ModTime()}
+	suite.assert.Equal(syncActionNone, sl.getSyncAction(&syncEntry{name: name, local: local, remote: sameTime}))
+
+	md5sum, err := computeMD5(filepath.Join(tl.path, name))
+	suite.assert.NoError(err)
+	sameMD5 := &internal.ObjAttr{Path: name, Size: local.Size(), Mtime: localTime.Add(time.Minute), MD5: md5sum}
+	suite.assert.Equal(syncActionNone, sl.getSyncAction(&syncEntry{name: name, local: local, remote: sameMD5}))
+
+	// never synced and content differs, newest wins
+	suite.assert.Equal(syncActionDownload, sl.getSyncAction(&syncEntry{name: name, local: local, remote: remoteNewer}))
+	suite.assert.Equal(syncActionUpload, sl.getSyncAction(&syncEntry{name: name, local: local, remote: remoteOlder}))
+
+	// synced earlier and only one side has changed
+	sl.state.set(name, newSyncRecord(local, remoteOlder))
+	suite.assert.Equal(syncActionNone, sl.getSyncAction(&syncEntry{name: name, local: local, remote: remoteOlder}))
+
+	remoteChanged := &internal.ObjAttr{Path: name, Size: 11, Mtime: localTime.Add(-time.Minute), ETag: "etag2"}
+	suite.assert.Equal(syncActionDownload, sl.getSyncAction(&syncEntry{name: name, local: local, remote: remoteChanged}))
+
+	sl.state.set(name, &syncRecord{ETag: "etag1", RemoteSize: 11, RemoteMtime: remoteNewer.Mtime, LocalSize: 5, LocalMtime: localTime})
+	suite.assert.Equal(syncActionUpload, sl.getSyncAction(&syncEntry{name: name, local: local, remote: remoteNewer}))
+
+	// both sides changed, conflict is resolved as per the policy
+	sl.state.set(name, &syncRecord{ETag: "etag0", RemoteSize: 11, LocalSize: 5, LocalMtime: localTime})
+	entry := &syncEntry{name: name, local: local, remote: remoteNewer}
+	suite.assert.Equal(syncActionDownload, sl.getSyncAction(entry))
+
+	sl.policy = EConflictPolicy.LOCAL_WINS()
+	suite.assert.Equal(syncActionUpload, sl.getSyncAction(entry))
+
+	sl.policy = EConflictPolicy.REMOTE_WINS()
+	suite.assert.Equal(syncActionDownload, sl.getSyncAction(&syncEntry{name: name, local: local, remote: remoteOlder}))
+
+	// synced earlier and deleted from one side since, unless the other side has changed after the sync
+	sl.state.set(name, newSyncRecord(local, remoteOlder))
+	suite.assert.Equal(syncActionDeleteRemote, sl.getSyncAction(&syncEntry{name: name, remote: remoteOlder}))
+	suite.assert.Equal(syncActionDeleteLocal, sl.getSyncAction(&syncEntry{name: name, local: local}))
+	suite.assert.Equal(syncActionDownload, sl.getSyncAction(&syncEntry{name: name, remote: remoteChanged}))
+
+	sl.state.set(name, &syncRecord{ETag: "etag1", RemoteSize: 11, RemoteMtime: remoteOlder.Mtime, LocalSize: 5, LocalMtime: localTime})
+	suite.assert.Equal(syncActionUpload, sl.getSyncAction(&syncEntry{name: name, local: local}))
+
+	// deletion is propagated and the record is forgotten
+	sl.state.set(name, newSyncRecord(local, remoteOlder))
+	sl.deleteFile(&syncEntry{name: name, local: local}, true)
+	_, err = os.Stat(filepath.Join(tl.path, name))
+	suite.assert.True(os.IsNotExist(err))
+	suite.assert.Nil(sl.state.get(name))
+}
+
 func TestListSuite(t *testing.T) {
 	suite.Run(t, new(listTestSuite))
 }
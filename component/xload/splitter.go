@@ -36,6 +36,7 @@ package xload
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -50,6 +51,8 @@ import (
 // verify that the below types implement the xcomponent interfaces
 var _ XComponent = &splitter{}
 var _ XComponent = &downloadSplitter{}
+var _ XComponent = &uploadSplitter{}
+var _ XComponent = &syncSplitter{}
 
 type splitter struct {
 	XBase
@@ -57,6 +60,7 @@ type splitter struct {
 	path        string
 	fileLocks   *common.LockMap
 	validateMD5 bool
+	overwrite   bool // transfer the file even if the destination looks up to date
 }
 
 // --------------------------------------------------------------------------------------------------------
@@ -143,7 +147,7 @@ func (ds *downloadSplitter) Process(item *WorkItem) (int, error) {
 		if isDir {
 			log.Err("downloadSplitter::Process : %s is a directory", item.Path)
 			return -1, fmt.Errorf("%s is a directory", item.Path)
-		} else if item.DataLen == uint64(size) {
+		} else if item.DataLen == uint64(size) && !ds.overwrite {
 			log.Debug("downloadSplitter::Process : %s will be served from local path, priority %v", item.Path, item.Priority)
 			return int(size), nil
 		}
@@ -333,3 +337,384 @@ func (ds *downloadSplitter) checkConsistency(item *WorkItem) error {
 
 	return nil
 }
+
+// --------------------------------------------------------------------------------------------------------
+
+type uploadSplitter struct {
+	splitter
+}
+
+type uploadSplitterOptions struct {
+	blockPool   *BlockPool
+	path        string
+	workerCount uint32
+	remote      internal.Component
+	statsMgr    *StatsManager
+	fileLocks   *common.LockMap
+}
+
+func newUploadSplitter(opts *uploadSplitterOptions) (*uploadSplitter, error) {
+	if opts == nil || opts.blockPool == nil || opts.path == "" || opts.remote == nil || opts.statsMgr == nil || opts.fileLocks == nil || opts.workerCount == 0 {
+		log.Err("splitter::NewUploadSplitter : invalid parameters sent to create upload splitter")
+		return nil, fmt.Errorf("invalid parameters sent to create upload splitter")
+	}
+
+	log.Debug("splitter::NewUploadSplitter : create new upload splitter for %s, block size %v, workers %v", opts.path, opts.blockPool.GetBlockSize(), opts.workerCount)
+
+	us := &uploadSplitter{
+		splitter: splitter{
+			blockPool: opts.blockPool,
+			path:      opts.path,
+			fileLocks: opts.fileLocks,
+		},
+	}
+
+	us.SetName(SPLITTER)
+	us.SetWorkerCount(opts.workerCount)
+	us.SetRemote(opts.remote)
+	us.SetStatsManager(opts.statsMgr)
+	us.Init()
+	return us, nil
+}
+
+func (us *uploadSplitter) Init() {
+	us.SetThreadPool(NewThreadPool(us.GetWorkerCount(), us.Process))
+	if us.GetThreadPool() == nil {
+		log.Err("uploadSplitter::Init : fail to init thread pool")
+	}
+}
+
+func (us *uploadSplitter) Start(ctx context.Context) {
+	log.Debug("uploadSplitter::Start : start upload splitter for %s", us.path)
+	us.GetThreadPool().Start(ctx)
+}
+
+func (us *uploadSplitter) Stop() {
+	log.Debug("uploadSplitter::Stop : stop upload splitter for %s", us.path)
+	if us.GetThreadPool() != nil {
+		us.GetThreadPool().Stop()
+	}
+	log.Debug("uploadSplitter::Stop : stop successful")
+}
+
+// read the local file in chunks, stage them as blocks and then commit the block list
+func (us *uploadSplitter) Process(item *WorkItem) (int, error) {
+	log.Debug("uploadSplitter::Process : Splitting data for %s, size %v, mode %v, priority %v, access time %v, modified time %v", item.Path, item.DataLen,
+		item.Mode, item.Priority, item.Atime.Format(time.DateTime), item.Mtime.Format(time.DateTime))
+
+	var err error
+	localPath := filepath.Join(us.path, item.Path)
+
+	// take a lock on the file so that the same file is not uploaded in parallel by two threads
+	flock := us.fileLocks.Get(item.Path)
+	flock.Lock()
+	defer flock.Unlock()
+
+	item.FileHandle, err = os.OpenFile(localPath, os.O_RDONLY, 0)
+	if err != nil {
+		log.Err("uploadSplitter::Process : Failed to open file %s [%s]", item.Path, err.Error())
+		us.sendStatus(item.Path, false)
+		return -1, fmt.Errorf("failed to open file %s [%s]", item.Path, err.Error())
+	}
+
+	defer item.FileHandle.Close()
+
+	// file may have changed after it was listed, so refresh its size and modified time
+	info, err := item.FileHandle.Stat()
+	if err != nil {
+		log.Err("uploadSplitter::Process : Failed to stat file %s [%s]", item.Path, err.Error())
+		us.sendStatus(item.Path, false)
+		return -1, fmt.Errorf("failed to stat file %s [%s]", item.Path, err.Error())
+	}
+
+	if info.IsDir() {
+		log.Err("uploadSplitter::Process : %s is a directory", item.Path)
+		us.sendStatus(item.Path, false)
+		return -1, fmt.Errorf("%s is a directory", item.Path)
+	}
+
+	item.DataLen = uint64(info.Size())
+	item.Mtime = info.ModTime()
+
+	// skip the file if the container already has an up to date copy of it
+	attr, err := us.GetRemote().GetAttr(internal.GetAttrOptions{Name: item.Path})
+	if err == nil && !attr.IsDir() && attr.Size == int64(item.DataLen) && !attr.Mtime.Before(item.Mtime) && !us.overwrite {
+		log.Debug("uploadSplitter::Process : %s is already present in container, priority %v", item.Path, item.Priority)
+		us.sendStatus(item.Path, true)
+		return int(item.DataLen), nil
+	}
+
+	numBlocks := uint64(0)
+	if item.DataLen > 0 {
+		numBlocks = ((item.DataLen - 1) / us.blockPool.GetBlockSize()) + 1
+	}
+
+	blockList := make([]string, numBlocks)
+	offset := int64(0)
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+
+	responseChannel := make(chan *WorkItem, numBlocks)
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+
+	operationSuccess := true
+	go func() {
+		defer wg.Done()
+
+		for i := 0; i < int(numBlocks); i++ {
+			select {
+			case <-us.GetThreadPool().ctx.Done(): // check if the thread pool is closed
+				operationSuccess = false
+				cancel()
+				return
+			case respSplitItem := <-responseChannel:
+				if respSplitItem.Err != nil {
+					log.Err("uploadSplitter::Process : Failed to upload data for file %s", item.Path)
+					operationSuccess = false
+					cancel() // cancel the context to stop upload of other chunks
+				}
+
+				if respSplitItem.Block != nil {
+					us.blockPool.Release(respSplitItem.Block)
+				}
+			}
+		}
+	}()
+
+	for i := 0; i < int(numBlocks); i++ {
+		block := us.blockPool.GetBlock(item.Priority)
+		if block == nil {
+			responseChannel <- &WorkItem{Err: fmt.Errorf("failed to get block from pool for file %s, offset %v", item.Path, offset)}
+			offset += int64(us.blockPool.GetBlockSize())
+			continue
+		}
+
+		length := min(us.blockPool.GetBlockSize(), item.DataLen-uint64(offset))
+		n, err := item.FileHandle.ReadAt(block.Data[:length], offset)
+		if err != nil && !(err == io.EOF && uint64(n) == length) {
+			log.Err("uploadSplitter::Process : Failed to read data from file %s, offset %v [%s]", item.Path, offset, err.Error())
+			responseChannel <- &WorkItem{Block: block, Err: fmt.Errorf("failed to read file %s at offset %v [%s]", item.Path, offset, err.Error())}
+			offset += int64(us.blockPool.GetBlockSize())
+			continue
+		}
+
+		// send the disk read status to stats manager
+		us.GetStatsManager().AddStats(&StatsItem{
+			Component:        SPLITTER,
+			Name:             item.Path,
+			Success:          false,
+			Download:         false,
+			DiskIO:           true,
+			BytesTransferred: uint64(n),
+		})
+
+		block.Index = i
+		block.Offset = offset
+		block.Length = int64(n)
+		block.Id = common.GetBlockID(common.BlockIDLength)
+		blockList[i] = block.Id
+
+		splitItem := &WorkItem{
+			CompName:        us.GetNext().GetName(),
+			Path:            item.Path,
+			DataLen:         item.DataLen,
+			FileHandle:      item.FileHandle,
+			Block:           block,
+			ResponseChannel: responseChannel,
+			Download:        false,
+			Priority:        item.Priority,
+			Ctx:             ctx,
+		}
+
+		err = us.GetNext().Schedule(splitItem)
+		if err != nil {
+			log.Err("uploadSplitter::Process : Failed to schedule upload for %s [%s]", item.Path, err.Error())
+			responseChannel <- &WorkItem{Block: block, Err: fmt.Errorf("failed to schedule upload for %s [%s]", item.Path, err.Error())}
+		}
+
+		offset += int64(us.blockPool.GetBlockSize())
+	}
+
+	wg.Wait()
+
+	if operationSuccess {
+		// all blocks are staged, commit them to create the blob
+		err = us.GetRemote().CommitData(internal.CommitDataOptions{
+			Name:      item.Path,
+			List:      blockList,
+			BlockSize: us.blockPool.GetBlockSize(),
+		})
+		if err != nil {
+			log.Err("uploadSplitter::Process : Failed to commit block list for %s [%s]", item.Path, err.Error())
+			operationSuccess = false
+		}
+	}
+
+	// send the upload status to stats manager
+	us.sendStatus(item.Path, operationSuccess)
+
+	if !operationSuccess {
+		log.Err("uploadSplitter::Process : Failed to upload data for file %s", item.Path)
+		return -1, fmt.Errorf("failed to upload data for file %s", item.Path)
+	}
+
+	log.Debug("uploadSplitter::Process : Upload completed for file %s, priority %v", item.Path, item.Priority)
+	return int(item.DataLen), nil
+}
+
+// send the upload status of the file to stats manager
+func (us *uploadSplitter) sendStatus(path string, isSuccess bool) {
+	us.GetStatsManager().AddStats(&StatsItem{
+		Component: SPLITTER,
+		Name:      path,
+		Success:   isSuccess,
+		Download:  false,
+	})
+}
+
+// --------------------------------------------------------------------------------------------------------
+
+// syncSplitter transfers a file in the direction decided by the sync lister and records the synced state
+type syncSplitter struct {
+	splitter
+	downloader *downloadSplitter
+	uploader   *uploadSplitter
+	state      *syncState
+}
+
+type syncSplitterOptions struct {
+	blockPool   *BlockPool
+	path        string
+	workerCount uint32
+	remote      internal.Component
+	statsMgr    *StatsManager
+	fileLocks   *common.LockMap
+	validateMD5 bool
+	state       *syncState
+}
+
+func newSyncSplitter(opts *syncSplitterOptions) (*syncSplitter, error) {
+	if opts == nil || opts.blockPool == nil || opts.path == "" || opts.remote == nil || opts.statsMgr == nil || opts.fileLocks == nil || opts.state == nil || opts.workerCount == 0 {
+		log.Err("splitter::NewSyncSplitter : invalid parameters sent to create sync splitter")
+		return nil, fmt.Errorf("invalid parameters sent to create sync splitter")
+	}
+
+	log.Debug("splitter::NewSyncSplitter : create new sync splitter for %s, block size %v, workers %v", opts.path, opts.blockPool.GetBlockSize(), opts.workerCount)
+
+	ds, err := newDownloadSplitter(&downloadSplitterOptions{
+		blockPool:   opts.blockPool,
+		path:        opts.path,
+		workerCount: opts.workerCount,
+		remote:      opts.remote,
+		statsMgr:    opts.statsMgr,
+		fileLocks:   opts.fileLocks,
+		validateMD5: opts.validateMD5,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	us, err := newUploadSplitter(&uploadSplitterOptions{
+		blockPool:   opts.blockPool,
+		path:        opts.path,
+		workerCount: opts.workerCount,
+		remote:      opts.remote,
+		statsMgr:    opts.statsMgr,
+		fileLocks:   opts.fileLocks,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// sync lister has already compared both the sides, so the transfer shall not be skipped
+	ds.overwrite = true
+	us.overwrite = true
+
+	ss := &syncSplitter{
+		splitter: splitter{
+			blockPool:   opts.blockPool,
+			path:        opts.path,
+			fileLocks:   opts.fileLocks,
+			validateMD5: opts.validateMD5,
+			overwrite:   true,
+		},
+		downloader: ds,
+		uploader:   us,
+		state:      opts.state,
+	}
+
+	ss.SetName(SPLITTER)
+	ss.SetWorkerCount(opts.workerCount)
+	ss.SetRemote(opts.remote)
+	ss.SetStatsManager(opts.statsMgr)
+	ss.Init()
+	return ss, nil
+}
+
+func (ss *syncSplitter) Init() {
+	ss.SetThreadPool(NewThreadPool(ss.GetWorkerCount(), ss.Process))
+	if ss.GetThreadPool() == nil {
+		log.Err("syncSplitter::Init : fail to init thread pool")
+	}
+
+	// downloader and uploader run on the thread pool of sync splitter
+	ss.downloader.SetThreadPool(ss.GetThreadPool())
+	ss.uploader.SetThreadPool(ss.GetThreadPool())
+}
+
+func (ss *syncSplitter) SetNext(next XComponent) {
+	ss.XBase.SetNext(next)
+	ss.downloader.SetNext(next)
+	ss.uploader.SetNext(next)
+}
+
+func (ss *syncSplitter) Start(ctx context.Context) {
+	log.Debug("syncSplitter::Start : start sync splitter for %s", ss.path)
+	ss.GetThreadPool().Start(ctx)
+}
+
+func (ss *syncSplitter) Stop() {
+	log.Debug("syncSplitter::Stop : stop sync splitter for %s", ss.path)
+	if ss.GetThreadPool() != nil {
+		ss.GetThreadPool().Stop()
+	}
+	log.Debug("syncSplitter::Stop : stop successful")
+}
+
+// transfer the file in the direction set in the work item and record its state on success
+func (ss *syncSplitter) Process(item *WorkItem) (int, error) {
+	var n int
+	var err error
+
+	if item.Download {
+		n, err = ss.downloader.Process(item)
+	} else {
+		n, err = ss.uploader.Process(item)
+	}
+
+	if err != nil {
+		return n, err
+	}
+
+	ss.updateState(item.Path)
+	return n, nil
+}
+
+// updateState records the current state of the file on both the sides after a successful transfer
+func (ss *syncSplitter) updateState(name string) {
+	info, err := os.Stat(filepath.Join(ss.path, name))
+	if err != nil {
+		log.Err("syncSplitter::updateState : Failed to stat %s [%s]", name, err.Error())
+		return
+	}
+
+	attr, err := ss.GetRemote().GetAttr(internal.GetAttrOptions{Name: name})
+	if err != nil {
+		log.Err("syncSplitter::updateState : Failed to get attr of %s [%s]", name, err.Error())
+		return
+	}
+
+	ss.state.set(name, newSyncRecord(info, attr))
+}
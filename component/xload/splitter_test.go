@@ -313,11 +313,296 @@ func (suite *splitterTestSuite) TestSplitterConsistency() {
 	validateMD5(ts.path, remote_path, suite.assert)
 }
 
+func (suite *splitterTestSuite) TestNewUploadSplitter() {
+	us, err := newUploadSplitter(nil)
+	suite.assert.Error(err)
+	suite.assert.Nil(us)
+	suite.assert.Contains(err.Error(), "invalid parameters sent to create upload splitter")
+
+	us, err = newUploadSplitter(&uploadSplitterOptions{})
+	suite.assert.Error(err)
+	suite.assert.Nil(us)
+	suite.assert.Contains(err.Error(), "invalid parameters sent to create upload splitter")
+
+	statsMgr, err := NewStatsManager(1, false, nil)
+	suite.assert.NoError(err)
+	suite.assert.NotNil(statsMgr)
+
+	us, err = newUploadSplitter(&uploadSplitterOptions{
+		blockPool:   NewBlockPool(1, 1, context.TODO()),
+		path:        "/home/user/random_path",
+		workerCount: 4,
+		remote:      remote,
+		statsMgr:    statsMgr,
+		fileLocks:   common.NewLockMap(),
+	})
+	suite.assert.NoError(err)
+	suite.assert.NotNil(us)
+}
+
+func (suite *splitterTestSuite) TestUploadProcessErrors() {
+	ts, err := setupTestSplitter()
+	suite.assert.NoError(err)
+	suite.assert.NotNil(ts)
+
+	defer func() {
+		err = ts.cleanup()
+		suite.assert.NoError(err)
+	}()
+
+	us, err := newUploadSplitter(&uploadSplitterOptions{ts.blockPool, ts.path, 4, remote, ts.stMgr, ts.locks})
+	suite.assert.NoError(err)
+	suite.assert.NotNil(us)
+
+	n, err := us.Process(&WorkItem{})
+	suite.assert.Error(err)
+	suite.assert.Contains(err.Error(), "is a directory")
+	suite.assert.Equal(-1, n)
+
+	n, err = us.Process(&WorkItem{Path: "file_not_present"})
+	suite.assert.Error(err)
+	suite.assert.Contains(err.Error(), "failed to open file")
+	suite.assert.Equal(-1, n)
+}
+
+func (suite *splitterTestSuite) TestUploadFilePresentInContainer() {
+	ts, err := setupTestSplitter()
+	suite.assert.NoError(err)
+	suite.assert.NotNil(ts)
+
+	defer func() {
+		err = ts.cleanup()
+		suite.assert.NoError(err)
+	}()
+
+	us, err := newUploadSplitter(&uploadSplitterOptions{ts.blockPool, ts.path, 4, remote, ts.stMgr, ts.locks})
+	suite.assert.NoError(err)
+	suite.assert.NotNil(us)
+
+	// local copy is older than the one in container, so no data is uploaded
+	fileName := "file_4"
+	cpCmd := exec.Command("cp", filepath.Join(remote_path, fileName), ts.path)
+	_, err = cpCmd.Output()
+	suite.assert.NoError(err)
+
+	oldTime := time.Now().Add(-1 * time.Hour)
+	err = os.Chtimes(filepath.Join(ts.path, fileName), oldTime, oldTime)
+	suite.assert.NoError(err)
+
+	n, err := us.Process(&WorkItem{Path: fileName})
+	suite.assert.NoError(err)
+	suite.assert.Equal(36, n)
+}
+
+func (suite *splitterTestSuite) TestUploadSplitterStartStop() {
+	ts, err := setupTestSplitter()
+	suite.assert.NoError(err)
+	suite.assert.NotNil(ts)
+
+	defer func() {
+		err = ts.cleanup()
+		suite.assert.NoError(err)
+	}()
+
+	// data to be uploaded is present in local path and the container is empty
+	uploadPath := filepath.Join("/tmp/", fmt.Sprintf("xupload_%v", randomString(8)))
+	err = os.MkdirAll(uploadPath, 0777)
+	suite.assert.NoError(err)
+	defer os.RemoveAll(uploadPath)
+
+	cfg := fmt.Sprintf("loopbackfs:\n  path: %s\n", uploadPath)
+	err = config.ReadConfigFromReader(strings.NewReader(cfg))
+	suite.assert.NoError(err)
+
+	container := loopback.NewLoopbackFSComponent()
+	err = container.Configure(true)
+	suite.assert.NoError(err)
+
+	createTestDirsAndFiles(ts.path, suite.assert)
+
+	ll, err := newLocalLister(&localListerOptions{
+		path:              ts.path,
+		workerCount:       4,
+		defaultPermission: common.DefaultFilePermissionBits,
+		remote:            container,
+		statsMgr:          ts.stMgr,
+	})
+	suite.assert.NoError(err)
+	suite.assert.NotNil(ll)
+
+	us, err := newUploadSplitter(&uploadSplitterOptions{ts.blockPool, ts.path, 4, container, ts.stMgr, ts.locks})
+	suite.assert.NoError(err)
+	suite.assert.NotNil(us)
+
+	rdm, err := newRemoteDataManager(&remoteDataManagerOptions{
+		workerCount: 8,
+		remote:      container,
+		statsMgr:    ts.stMgr,
+	})
+	suite.assert.NoError(err)
+	suite.assert.NotNil(rdm)
+
+	// create chain
+	ll.SetNext(us)
+	us.SetNext(rdm)
+
+	// start components
+	rdm.Start(context.TODO())
+	us.Start(context.TODO())
+	ll.Start(context.TODO())
+
+	time.Sleep(5 * time.Second)
+
+	// stop comoponents
+	ll.Stop()
+
+	validateMD5(uploadPath, ts.path, suite.assert)
+}
+
+func (suite *splitterTestSuite) TestNewSyncSplitter() {
+	ss, err := newSyncSplitter(nil)
+	suite.assert.Error(err)
+	suite.assert.Nil(ss)
+	suite.assert.Contains(err.Error(), "invalid parameters sent to create sync splitter")
+
+	statsMgr, err := NewStatsManager(1, false, nil)
+	suite.assert.NoError(err)
+	suite.assert.NotNil(statsMgr)
+
+	ss, err = newSyncSplitter(&syncSplitterOptions{
+		blockPool:   NewBlockPool(1, 1, context.TODO()),
+		path:        "/home/user/random_path",
+		workerCount: 4,
+		remote:      remote,
+		statsMgr:    statsMgr,
+		fileLocks:   common.NewLockMap(),
+		state:       &syncState{records: make(map[string]*syncRecord)},
+	})
+	suite.assert.NoError(err)
+	suite.assert.NotNil(ss)
+	suite.assert.True(ss.downloader.overwrite)
+	suite.assert.True(ss.uploader.overwrite)
+	suite.assert.Equal(ss.GetThreadPool(), ss.downloader.GetThreadPool())
+	suite.assert.Equal(ss.GetThreadPool(), ss.uploader.GetThreadPool())
+}
+
+func (suite *splitterTestSuite) TestSyncSplitterStartStop() {
+	ts, err := setupTestSplitter()
+	suite.assert.NoError(err)
+	suite.assert.NotNil(ts)
+
+	defer func() {
+		err = ts.cleanup()
+		suite.assert.NoError(err)
+	}()
+
+	containerPath := filepath.Join("/tmp/", fmt.Sprintf("xsync_%v", randomString(8)))
+	err = os.MkdirAll(containerPath, 0777)
+	suite.assert.NoError(err)
+	defer os.RemoveAll(containerPath)
+
+	cfg := fmt.Sprintf("loopbackfs:\n  path: %s\n", containerPath)
+	err = config.ReadConfigFromReader(strings.NewReader(cfg))
+	suite.assert.NoError(err)
+
+	container := loopback.NewLoopbackFSComponent()
+	err = container.Configure(true)
+	suite.assert.NoError(err)
+
+	// container holds dir_0 and local path holds dir_1, both hold the files in root
+	createTestFiles(containerPath, suite.assert)
+	err = os.MkdirAll(filepath.Join(containerPath, "dir_0"), 0777)
+	suite.assert.NoError(err)
+	createTestFiles(filepath.Join(containerPath, "dir_0"), suite.assert)
+
+	err = os.MkdirAll(filepath.Join(ts.path, "dir_1"), 0777)
+	suite.assert.NoError(err)
+	createTestFiles(filepath.Join(ts.path, "dir_1"), suite.assert)
+
+	// local copy of file_4 is newer and local copy of file_3 is older than the one in container
+	err = os.WriteFile(filepath.Join(ts.path, "file_4"), []byte(randomString(20)), 0666)
+	suite.assert.NoError(err)
+
+	err = os.WriteFile(filepath.Join(ts.path, "file_3"), []byte(randomString(20)), 0666)
+	suite.assert.NoError(err)
+	oldTime := time.Now().Add(-1 * time.Hour)
+	err = os.Chtimes(filepath.Join(ts.path, "file_3"), oldTime, oldTime)
+	suite.assert.NoError(err)
+
+	localFile4, err := computeMD5(filepath.Join(ts.path, "file_4"))
+	suite.assert.NoError(err)
+	remoteFile3, err := computeMD5(filepath.Join(containerPath, "file_3"))
+	suite.assert.NoError(err)
+
+	state, err := newSyncState(ts.path)
+	suite.assert.NoError(err)
+
+	sl, err := newSyncLister(&syncListerOptions{
+		path:              ts.path,
+		workerCount:       4,
+		defaultPermission: common.DefaultFilePermissionBits,
+		remote:            container,
+		statsMgr:          ts.stMgr,
+		state:             state,
+		policy:            EConflictPolicy.NEWEST_WINS(),
+	})
+	suite.assert.NoError(err)
+	suite.assert.NotNil(sl)
+
+	ss, err := newSyncSplitter(&syncSplitterOptions{ts.blockPool, ts.path, 4, container, ts.stMgr, ts.locks, false, state})
+	suite.assert.NoError(err)
+	suite.assert.NotNil(ss)
+
+	rdm, err := newRemoteDataManager(&remoteDataManagerOptions{
+		workerCount: 8,
+		remote:      container,
+		statsMgr:    ts.stMgr,
+	})
+	suite.assert.NoError(err)
+	suite.assert.NotNil(rdm)
+
+	// create chain
+	sl.SetNext(ss)
+	ss.SetNext(rdm)
+
+	// start components
+	rdm.Start(context.TODO())
+	ss.Start(context.TODO())
+	sl.Start(context.TODO())
+
+	time.Sleep(5 * time.Second)
+
+	// stop comoponents
+	sl.Stop()
+
+	// both sides hold the same data now
+	validateMD5(ts.path, containerPath, suite.assert)
+	validateMD5(containerPath, ts.path, suite.assert)
+
+	l, err := computeMD5(filepath.Join(containerPath, "file_4"))
+	suite.assert.NoError(err)
+	suite.assert.Equal(localFile4, l)
+
+	r, err := computeMD5(filepath.Join(ts.path, "file_3"))
+	suite.assert.NoError(err)
+	suite.assert.Equal(remoteFile3, r)
+
+	// every file synced is recorded in the state
+	suite.assert.NotNil(state.get("file_0"))
+	suite.assert.NotNil(state.get("dir_0/file_4"))
+	suite.assert.NotNil(state.get("dir_1/file_4"))
+}
+
 func validateMD5(localPath string, remotePath string, assert *assert.Assertions) {
 	entries, err := os.ReadDir(remotePath)
 	assert.NoError(err)
 
 	for _, entry := range entries {
+		// sync state is persisted in the local path while the sync is running
+		if entry.Name() == SYNC_STATE_FILE || entry.Name() == SYNC_STATE_FILE+".tmp" {
+			continue
+		}
+
 		localFile := filepath.Join(localPath, entry.Name())
 		remoteFile := filepath.Join(remotePath, entry.Name())
 
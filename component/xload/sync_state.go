@@ -0,0 +1,210 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package xload
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-storage-fuse/v2/common/log"
+	"github.com/Azure/azure-storage-fuse/v2/internal"
+)
+
+const (
+	SYNC_STATE_FILE = ".xload_sync_state.json" // file in xload path where the state of last sync is persisted
+
+	syncStateSaveInterval = 5 * time.Second // state is persisted at most this often while the sync is running
+)
+
+// syncRecord holds the state of a file on both the sides when it was last in sync
+type syncRecord struct {
+	ETag        string    `json:"etag,omitempty"`
+	RemoteSize  int64     `json:"remote_size"`
+	RemoteMtime time.Time `json:"remote_mtime"`
+	LocalSize   int64     `json:"local_size"`
+	LocalMtime  time.Time `json:"local_mtime"`
+}
+
+// localChanged checks if the local file differs from the one last synced
+func (rec *syncRecord) localChanged(local os.FileInfo) bool {
+	return local.Size() != rec.LocalSize || !local.ModTime().Equal(rec.LocalMtime)
+}
+
+// remoteChanged checks if the blob differs from the one last synced
+func (rec *syncRecord) remoteChanged(remote *internal.ObjAttr) bool {
+	return remote.ETag != rec.ETag || remote.Size != rec.RemoteSize || !remote.Mtime.Equal(rec.RemoteMtime)
+}
+
+// syncState is the persisted view of the files synced so far, used to find out which side has changed.
+// It is saved periodically as files get synced, so that a crash does not make the next sync compare every file afresh.
+type syncState struct {
+	sync.RWMutex
+	saveLock sync.Mutex             // serializes writing of the state file
+	path     string                 // path of the json file holding the state, state is kept only in memory if empty
+	records  map[string]*syncRecord // last synced state of each file
+	dirty    bool                   // records changed since the last save
+	lastSave time.Time
+}
+
+// newSyncState loads the state of last sync from the given xload path
+func newSyncState(basePath string) (*syncState, error) {
+	ss := &syncState{
+		path:    filepath.Join(basePath, SYNC_STATE_FILE),
+		records: make(map[string]*syncRecord),
+	}
+
+	data, err := os.ReadFile(ss.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			log.Info("syncState::newSyncState : no previous sync state found at %s", ss.path)
+			return ss, nil
+		}
+
+		log.Err("syncState::newSyncState : failed to read sync state %s [%s]", ss.path, err.Error())
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, &ss.records)
+	if err != nil {
+		// a corrupt state only means all the files are compared afresh, so do not fail the mount
+		log.Err("syncState::newSyncState : failed to parse sync state %s, ignoring it [%s]", ss.path, err.Error())
+		ss.records = make(map[string]*syncRecord)
+	}
+
+	log.Info("syncState::newSyncState : loaded %v records from %s", len(ss.records), ss.path)
+	return ss, nil
+}
+
+// get returns a copy of the record for the given file, nil if the file was never synced
+func (ss *syncState) get(name string) *syncRecord {
+	ss.RLock()
+	defer ss.RUnlock()
+
+	rec, ok := ss.records[name]
+	if !ok {
+		return nil
+	}
+
+	copyRec := *rec
+	return &copyRec
+}
+
+// set updates the record for the given file
+func (ss *syncState) set(name string, rec *syncRecord) {
+	ss.Lock()
+	ss.records[name] = rec
+	ss.dirty = true
+	ss.Unlock()
+
+	ss.saveIfDue()
+}
+
+// remove forgets the record of a file deleted from both the sides
+func (ss *syncState) remove(name string) {
+	ss.Lock()
+	delete(ss.records, name)
+	ss.dirty = true
+	ss.Unlock()
+
+	ss.saveIfDue()
+}
+
+// saveIfDue persists the state if it has changed and was not saved in a while
+func (ss *syncState) saveIfDue() {
+	ss.RLock()
+	due := ss.dirty && time.Since(ss.lastSave) >= syncStateSaveInterval
+	ss.RUnlock()
+
+	if due {
+		_ = ss.save()
+	}
+}
+
+// save persists the state to disk, it is first written to a temp file and then renamed
+func (ss *syncState) save() error {
+	if ss.path == "" {
+		return nil
+	}
+
+	ss.saveLock.Lock()
+	defer ss.saveLock.Unlock()
+
+	ss.Lock()
+	data, err := json.Marshal(ss.records)
+	ss.dirty = false
+	ss.lastSave = time.Now()
+	ss.Unlock()
+
+	defer func() {
+		if err != nil {
+			ss.Lock()
+			ss.dirty = true
+			ss.Unlock()
+		}
+	}()
+
+	if err != nil {
+		log.Err("syncState::save : failed to marshal sync state [%s]", err.Error())
+		return err
+	}
+
+	tmpPath := ss.path + ".tmp"
+	err = os.WriteFile(tmpPath, data, 0644)
+	if err != nil {
+		log.Err("syncState::save : failed to write sync state %s [%s]", tmpPath, err.Error())
+		return err
+	}
+
+	err = os.Rename(tmpPath, ss.path)
+	if err != nil {
+		log.Err("syncState::save : failed to rename sync state %s [%s]", tmpPath, err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// newSyncRecord creates the record from the current state of the file on both the sides
+func newSyncRecord(local os.FileInfo, remote *internal.ObjAttr) *syncRecord {
+	return &syncRecord{
+		ETag:        remote.ETag,
+		RemoteSize:  remote.Size,
+		RemoteMtime: remote.Mtime,
+		LocalSize:   local.Size(),
+		LocalMtime:  local.ModTime(),
+	}
+}
@@ -0,0 +1,146 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package xload
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type syncStateTestSuite struct {
+	suite.Suite
+	assert *assert.Assertions
+	path   string
+}
+
+func (suite *syncStateTestSuite) SetupTest() {
+	suite.assert = assert.New(suite.T())
+	suite.path = filepath.Join("/tmp/", fmt.Sprintf("xsync_%v", randomString(8)))
+	err := os.MkdirAll(suite.path, 0777)
+	suite.assert.NoError(err)
+}
+
+func (suite *syncStateTestSuite) TearDownTest() {
+	err := os.RemoveAll(suite.path)
+	suite.assert.NoError(err)
+}
+
+func (suite *syncStateTestSuite) TestNoPreviousState() {
+	ss, err := newSyncState(suite.path)
+	suite.assert.NoError(err)
+	suite.assert.NotNil(ss)
+	suite.assert.Empty(ss.records)
+	suite.assert.Nil(ss.get("file_0"))
+}
+
+func (suite *syncStateTestSuite) TestSaveAndLoad() {
+	ss, err := newSyncState(suite.path)
+	suite.assert.NoError(err)
+
+	mtime := time.Now().Add(-1 * time.Hour).UTC()
+	ss.set("dir_0/file_0", &syncRecord{
+		ETag:        "etag0",
+		RemoteSize:  10,
+		RemoteMtime: mtime,
+		LocalSize:   10,
+		LocalMtime:  mtime,
+	})
+
+	// modifying the returned record should not change the state
+	rec := ss.get("dir_0/file_0")
+	suite.assert.NotNil(rec)
+	rec.ETag = "changed"
+	suite.assert.Equal("etag0", ss.get("dir_0/file_0").ETag)
+
+	err = ss.save()
+	suite.assert.NoError(err)
+
+	_, err = os.Stat(filepath.Join(suite.path, SYNC_STATE_FILE+".tmp"))
+	suite.assert.True(os.IsNotExist(err))
+
+	loaded, err := newSyncState(suite.path)
+	suite.assert.NoError(err)
+	suite.assert.Len(loaded.records, 1)
+
+	rec = loaded.get("dir_0/file_0")
+	suite.assert.NotNil(rec)
+	suite.assert.Equal("etag0", rec.ETag)
+	suite.assert.EqualValues(10, rec.RemoteSize)
+	suite.assert.True(mtime.Equal(rec.RemoteMtime))
+	suite.assert.True(mtime.Equal(rec.LocalMtime))
+}
+
+func (suite *syncStateTestSuite) TestIncrementalSave() {
+	ss, err := newSyncState(suite.path)
+	suite.assert.NoError(err)
+
+	// first change is persisted right away, later ones once the save interval has passed
+	ss.set("file_0", &syncRecord{ETag: "etag0"})
+	ss.set("file_1", &syncRecord{ETag: "etag1"})
+
+	loaded, err := newSyncState(suite.path)
+	suite.assert.NoError(err)
+	suite.assert.Len(loaded.records, 1)
+	suite.assert.NotNil(loaded.get("file_0"))
+	suite.assert.True(ss.dirty)
+
+	ss.lastSave = time.Now().Add(-syncStateSaveInterval)
+	ss.remove("file_0")
+	suite.assert.False(ss.dirty)
+
+	loaded, err = newSyncState(suite.path)
+	suite.assert.NoError(err)
+	suite.assert.Len(loaded.records, 1)
+	suite.assert.NotNil(loaded.get("file_1"))
+}
+
+func (suite *syncStateTestSuite) TestCorruptState() {
+	err := os.WriteFile(filepath.Join(suite.path, SYNC_STATE_FILE), []byte("{not json"), 0644)
+	suite.assert.NoError(err)
+
+	ss, err := newSyncState(suite.path)
+	suite.assert.NoError(err)
+	suite.assert.NotNil(ss)
+	suite.assert.Empty(ss.records)
+}
+
+func TestSyncStateSuite(t *testing.T) {
+	suite.Run(t, new(syncStateTestSuite))
+}
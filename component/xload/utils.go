@@ -38,7 +38,8 @@ import (
 	"math"
 	"os"
 	"reflect"
-
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/Azure/azure-storage-fuse/v2/common/log"
@@ -107,6 +108,40 @@ func (m *Mode) Parse(s string) error {
 	return err
 }
 
+// conflict policy enum, used in sync mode when a file has changed on both local path and container
+type ConflictPolicy int
+
+var EConflictPolicy = ConflictPolicy(0).INVALID_POLICY()
+
+func (ConflictPolicy) INVALID_POLICY() ConflictPolicy {
+	return ConflictPolicy(0)
+}
+
+func (ConflictPolicy) REMOTE_WINS() ConflictPolicy {
+	return ConflictPolicy(1)
+}
+
+func (ConflictPolicy) LOCAL_WINS() ConflictPolicy {
+	return ConflictPolicy(2)
+}
+
+func (ConflictPolicy) NEWEST_WINS() ConflictPolicy {
+	return ConflictPolicy(3)
+}
+
+func (p ConflictPolicy) String() string {
+	return enum.StringInt(p, reflect.TypeOf(p))
+}
+
+// Parse accepts both remote-wins and remote_wins forms of the policy name
+func (p *ConflictPolicy) Parse(s string) error {
+	enumVal, err := enum.ParseInt(reflect.TypeOf(p), strings.ReplaceAll(s, "-", "_"), true, false)
+	if enumVal != nil {
+		*p = enumVal.(ConflictPolicy)
+	}
+	return err
+}
+
 func RoundFloat(val float64, precision int) float64 {
 	ratio := math.Pow10(precision)
 	return math.Round(val*ratio) / ratio
@@ -122,3 +157,12 @@ func isFilePresent(localPath string) (bool, bool, int64) {
 		return true, fileInfo.IsDir(), fileInfo.Size()
 	}
 }
+
+// returns the last access time of the file, falling back to modified time if it is not available
+func getAccessTime(info os.FileInfo) time.Time {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || stat == nil {
+		return info.ModTime()
+	}
+	return time.Unix(int64(stat.Atim.Sec), int64(stat.Atim.Nsec))
+}
@@ -96,6 +96,34 @@ func (suite *utilsTestSuite) TestModeString() {
 	}
 }
 
+func (suite *utilsTestSuite) TestConflictPolicyParse() {
+	policies := []struct {
+		val    string
+		policy ConflictPolicy
+	}{
+		{val: "remote-wins", policy: EConflictPolicy.REMOTE_WINS()},
+		{val: "local-wins", policy: EConflictPolicy.LOCAL_WINS()},
+		{val: "newest-wins", policy: EConflictPolicy.NEWEST_WINS()},
+		{val: "Remote_Wins", policy: EConflictPolicy.REMOTE_WINS()},
+		{val: "LOCAL_WINS", policy: EConflictPolicy.LOCAL_WINS()},
+		{val: "oldest-wins", policy: EConflictPolicy.INVALID_POLICY()},
+	}
+
+	for i, p := range policies {
+		var policy ConflictPolicy
+		err := policy.Parse(p.val)
+		if i < len(policies)-1 {
+			suite.assert.NoError(err)
+		} else {
+			suite.assert.Error(err)
+		}
+
+		suite.assert.Equal(p.policy, policy)
+	}
+
+	suite.assert.Equal("NEWEST_WINS", EConflictPolicy.NEWEST_WINS().String())
+}
+
 func (suite *utilsTestSuite) TestRoundFloat() {
 	values := []struct {
 		val       float64
@@ -67,6 +67,8 @@ type Xload struct {
 	poolSize          uint32             // Number of blocks in the pool
 	poolctx           context.Context    // context for the thread pool
 	poolCancelFunc    context.CancelFunc // cancel function for the thread pool
+	conflictPolicy    ConflictPolicy     // policy to resolve files changed on both sides in sync mode
+	syncState         *syncState         // state of the files when they were last synced
 }
 
 // Structure defining your config parameters
@@ -79,6 +81,7 @@ type XloadOptions struct {
 	CleanupOnStart bool    `config:"cleanup-on-start" yaml:"cleanup-on-start,omitempty"`
 	Workers        int32   `config:"workers" yaml:"workers,omitempty"`
 	PoolSize       uint32  `config:"pool-size" yaml:"pool-size,omitempty"`
+	ConflictPolicy string  `config:"conflict-policy" yaml:"conflict-policy,omitempty"`
 	// TODO:: xload : add parallelism parameter
 }
 
@@ -110,24 +113,40 @@ func (xl *Xload) Priority() internal.ComponentPriority {
 func (xl *Xload) Configure(_ bool) error {
 	log.Trace("Xload::Configure : %s", xl.Name())
 
-	// xload component should be used only in readonly mode
-	var readonly bool
-	err := config.UnmarshalKey("read-only", &readonly)
+	conf := XloadOptions{}
+	err := config.UnmarshalKey(xl.Name(), &conf)
 	if err != nil {
-		log.Err("Xload::Configure : config error [unable to obtain read-only]")
-		return fmt.Errorf("config error in %s [%s]", xl.Name(), err.Error())
+		log.Err("Xload::Configure : config error [invalid config attributes]")
+		return fmt.Errorf("Xload: config error [invalid config attributes]")
 	}
 
-	if !readonly {
-		log.Err("Xload::Configure : Xload component should be used only in read-only mode")
-		return fmt.Errorf("Xload component should be used in only in read-only mode")
+	var mode = EMode.PRELOAD() // using preload as the default mode
+	if len(conf.Mode) > 0 {
+		err = mode.Parse(conf.Mode)
+		if err != nil {
+			log.Err("Xload::Configure : Failed to parse mode %s [%s]", conf.Mode, err.Error())
+			return fmt.Errorf("invalid mode in xload : %s", conf.Mode)
+		}
+
+		if mode == EMode.INVALID_MODE() {
+			log.Err("Xload::Configure : Invalid mode : %s", conf.Mode)
+			return fmt.Errorf("invalid mode in xload : %s", conf.Mode)
+		}
 	}
 
-	conf := XloadOptions{}
-	err = config.UnmarshalKey(xl.Name(), &conf)
-	if err != nil {
-		log.Err("Xload::Configure : config error [invalid config attributes]")
-		return fmt.Errorf("Xload: config error [invalid config attributes]")
+	// in preload mode xload component should be used only in readonly mode
+	if mode == EMode.PRELOAD() {
+		var readonly bool
+		err = config.UnmarshalKey("read-only", &readonly)
+		if err != nil {
+			log.Err("Xload::Configure : config error [unable to obtain read-only]")
+			return fmt.Errorf("config error in %s [%s]", xl.Name(), err.Error())
+		}
+
+		if !readonly {
+			log.Err("Xload::Configure : Xload component should be used only in read-only mode")
+			return fmt.Errorf("Xload component should be used in only in read-only mode")
+		}
 	}
 
 	blockSize := (float64)(defaultBlockSize) // 16 MB as default block size
@@ -179,27 +198,24 @@ func (xl *Xload) Configure(_ bool) error {
 			}
 		}
 
-		if !common.IsDirectoryEmpty(xl.path) {
+		// in upload and sync mode the local path holds user data, so it need not be empty
+		if mode == EMode.PRELOAD() && !common.IsDirectoryEmpty(xl.path) {
 			log.Err("Xload::Configure : config error %s directory is not empty", xl.path)
 			return fmt.Errorf("config error in %s [temp directory not empty]", xl.Name())
 		}
 	}
 
-	var mode = EMode.PRELOAD() // using preload as the default mode
-	if len(conf.Mode) > 0 {
-		err = mode.Parse(conf.Mode)
-		if err != nil {
-			log.Err("Xload::Configure : Failed to parse mode %s [%s]", conf.Mode, err.Error())
-			return fmt.Errorf("invalid mode in xload : %s", conf.Mode)
-		}
-
-		if mode == EMode.INVALID_MODE() {
-			log.Err("Xload::Configure : Invalid mode : %s", conf.Mode)
-			return fmt.Errorf("invalid mode in xload : %s", conf.Mode)
+	var policy = EConflictPolicy.NEWEST_WINS() // using newest-wins as the default conflict policy
+	if len(conf.ConflictPolicy) > 0 {
+		err = policy.Parse(conf.ConflictPolicy)
+		if err != nil || policy == EConflictPolicy.INVALID_POLICY() {
+			log.Err("Xload::Configure : Invalid conflict policy : %s", conf.ConflictPolicy)
+			return fmt.Errorf("invalid conflict-policy in xload : %s", conf.ConflictPolicy)
 		}
 	}
 
 	xl.mode = mode
+	xl.conflictPolicy = policy
 	xl.exportProgress = conf.ExportProgress
 	xl.validateMD5 = conf.ValidateMD5
 
@@ -228,8 +244,8 @@ func (xl *Xload) Configure(_ bool) error {
 
 	xl.poolctx, xl.poolCancelFunc = context.WithCancel(context.Background())
 
-	log.Crit("Xload::Configure : block size %v, mode %v, path %v, default permission %v, export progress %v, validate md5 %v", xl.blockSize,
-		xl.mode.String(), xl.path, xl.defaultPermission, xl.exportProgress, xl.validateMD5)
+	log.Crit("Xload::Configure : block size %v, mode %v, path %v, default permission %v, export progress %v, validate md5 %v, conflict policy %v", xl.blockSize,
+		xl.mode.String(), xl.path, xl.defaultPermission, xl.exportProgress, xl.validateMD5, xl.conflictPolicy.String())
 
 	return nil
 }
@@ -264,10 +280,18 @@ func (xl *Xload) Start(ctx context.Context) error {
 		}
 	case EMode.UPLOAD():
 		// Start uploader here
-		return fmt.Errorf("uploader is currently unsupported")
+		err = xl.createUploader()
+		if err != nil {
+			log.Err("Xload::Start : Failed to start uploader [%s]", err.Error())
+			return err
+		}
 	case EMode.SYNC():
 		//Start syncer here
-		return fmt.Errorf("sync is currently unsupported")
+		err = xl.createSyncer()
+		if err != nil {
+			log.Err("Xload::Start : Failed to start syncer [%s]", err.Error())
+			return err
+		}
 	default:
 		log.Err("Xload::Start : Invalid mode : %s", xl.mode.String())
 		return fmt.Errorf("invalid mode in xload : %s", xl.mode.String())
@@ -303,6 +327,18 @@ func (xl *Xload) Stop() error {
 		log.Warn("Xload::Stop : Stop timeout")
 	}
 
+	if xl.syncState != nil {
+		err := xl.syncState.save()
+		if err != nil {
+			log.Err("Xload::Stop : failed to save sync state [%s]", err.Error())
+		}
+	}
+
+	// in upload and sync mode the local path holds user data, so do not delete it
+	if xl.mode != EMode.PRELOAD() {
+		return nil
+	}
+
 	// TODO:: xload : should we delete the files from local path
 	err := common.TempCacheCleanup(xl.path)
 	if err != nil {
@@ -356,6 +392,103 @@ func (xl *Xload) createDownloader() error {
 	return nil
 }
 
+func (xl *Xload) createUploader() error {
+	log.Trace("Xload::createUploader : Starting uploader")
+
+	// Create local lister pool to list local files
+	ll, err := newLocalLister(&localListerOptions{
+		path:              xl.path,
+		workerCount:       uint32(math.Max(math.Min(float64(runtime.NumCPU()/2), float64(MAX_LISTER)), 1)),
+		defaultPermission: xl.defaultPermission,
+		remote:            xl.NextComponent(),
+		statsMgr:          xl.statsMgr,
+	})
+	if err != nil {
+		log.Err("Xload::createUploader : Unable to create local lister [%s]", err.Error())
+		return err
+	}
+
+	us, err := newUploadSplitter(&uploadSplitterOptions{
+		blockPool:   xl.blockPool,
+		path:        xl.path,
+		workerCount: uint32(math.Min(float64(runtime.NumCPU()), float64(MAX_DATA_SPLITTER))),
+		remote:      xl.NextComponent(),
+		statsMgr:    xl.statsMgr,
+		fileLocks:   xl.fileLocks,
+	})
+	if err != nil {
+		log.Err("Xload::createUploader : Unable to create upload splitter [%s]", err.Error())
+		return err
+	}
+
+	rdm, err := newRemoteDataManager(&remoteDataManagerOptions{
+		workerCount: xl.workerCount,
+		remote:      xl.NextComponent(),
+		statsMgr:    xl.statsMgr,
+	})
+	if err != nil {
+		log.Err("Xload::createUploader : failed to create remote data manager [%s]", err.Error())
+		return err
+	}
+
+	xl.comps = []XComponent{ll, us, rdm}
+	return nil
+}
+
+func (xl *Xload) createSyncer() error {
+	log.Trace("Xload::createSyncer : Starting syncer")
+
+	state, err := newSyncState(xl.path)
+	if err != nil {
+		log.Err("Xload::createSyncer : Unable to load sync state [%s]", err.Error())
+		return err
+	}
+
+	// Create sync lister pool to list both local and remote files
+	sl, err := newSyncLister(&syncListerOptions{
+		path:              xl.path,
+		workerCount:       uint32(math.Max(math.Min(float64(runtime.NumCPU()/2), float64(MAX_LISTER)), 1)),
+		defaultPermission: xl.defaultPermission,
+		remote:            xl.NextComponent(),
+		statsMgr:          xl.statsMgr,
+		state:             state,
+		policy:            xl.conflictPolicy,
+	})
+	if err != nil {
+		log.Err("Xload::createSyncer : Unable to create sync lister [%s]", err.Error())
+		return err
+	}
+
+	ss, err := newSyncSplitter(&syncSplitterOptions{
+		blockPool:   xl.blockPool,
+		path:        xl.path,
+		workerCount: uint32(math.Min(float64(runtime.NumCPU()), float64(MAX_DATA_SPLITTER))),
+		remote:      xl.NextComponent(),
+		statsMgr:    xl.statsMgr,
+		fileLocks:   xl.fileLocks,
+		validateMD5: xl.validateMD5,
+		state:       state,
+	})
+	if err != nil {
+		log.Err("Xload::createSyncer : Unable to create sync splitter [%s]", err.Error())
+		return err
+	}
+
+	rdm, err := newRemoteDataManager(&remoteDataManagerOptions{
+		workerCount: xl.workerCount,
+		remote:      xl.NextComponent(),
+		statsMgr:    xl.statsMgr,
+	})
+	if err != nil {
+		log.Err("Xload::createSyncer : failed to create remote data manager [%s]", err.Error())
+		return err
+	}
+
+	xl.syncState = state
+	xl.comps = []XComponent{sl, ss, rdm}
+	return nil
+}
+
 func (xl *Xload) createChain() error {
 	if len(xl.comps) == 0 {
 		log.Err("Xload::createChain : no component initialized in xload")
@@ -456,6 +589,11 @@ func (xl *Xload) OpenFile(options internal.OpenFileOptions) (*handlemap.Handle,
 
 	filePresent, _, _ := isFilePresent(localPath)
 
+	// in upload and sync mode files missing from local path are served by the next component
+	if !filePresent && xl.mode != EMode.PRELOAD() {
+		return xl.NextComponent().OpenFile(options)
+	}
+
 	// if file is not present, send it to splitter for downloading on priority
 	if !filePresent {
 		err := xl.downloadFile(options.Name)
@@ -493,6 +631,11 @@ func (xl *Xload) OpenFile(options internal.OpenFileOptions) (*handlemap.Handle,
 }
 
 func (xl *Xload) ReleaseFile(options internal.ReleaseFileOptions) error {
+	// handles not served from local path belong to the next component
+	if !options.Handle.Cached() {
+		return xl.NextComponent().ReleaseFile(options)
+	}
+
 	// Lock the file so that while close is in progress no one can open the file again
 	flock := xl.fileLocks.Get(options.Handle.Path)
 	flock.Lock()
@@ -306,7 +306,7 @@ func (suite *xloadTestSuite) TestUnsupportedModes() {
 	defer suite.cleanupTest(false)
 	suite.cleanupTest(false) // teardown the default xload generated
 
-	modes := []string{"upload", "sync", "invalid_mode"}
+	modes := []string{"invalid_mode"}
 	blockSize := float64(0.001)
 	for _, m := range modes {
 		testConfig := fmt.Sprintf("xload:\n  path: %s\n  mode: %s\n  block-size-mb: %v\n\nloopbackfs:\n  path: %s\n\nread-only: true", suite.local_path, m, blockSize, suite.fake_storage_path)
@@ -548,6 +548,159 @@ func (suite *xloadTestSuite) TestOpenFileWithDownload() {
 	suite.validateMD5WithOpenFile(suite.local_path, suite.fake_storage_path)
 }
 
+func (suite *xloadTestSuite) TestConfigUploadMode() {
+	defer suite.cleanupTest(false)
+	suite.cleanupTest(false) // teardown the default xload generated
+
+	// upload mode can be used with a non-empty local path and a read-write mount
+	err := os.MkdirAll(suite.local_path, 0777)
+	suite.assert.NoError(err)
+	createTestDirsAndFiles(suite.local_path, suite.assert)
+
+	testConfig := fmt.Sprintf("xload:\n  path: %s\n  mode: upload\n\nloopbackfs:\n  path: %s", suite.local_path, suite.fake_storage_path)
+	err = suite.setupTestHelper(testConfig, false)
+	suite.assert.NoError(err)
+	suite.assert.Equal(EMode.UPLOAD(), suite.xload.mode)
+
+	// preload mode still needs read-only mount
+	testConfig = fmt.Sprintf("xload:\n  path: %s\n  mode: preload\n\nloopbackfs:\n  path: %s", suite.local_path, suite.fake_storage_path)
+	err = suite.setupTestHelper(testConfig, false)
+	suite.assert.Error(err)
+	suite.assert.Contains(err.Error(), "read-only")
+}
+
+func (suite *xloadTestSuite) TestConfigConflictPolicy() {
+	defer suite.cleanupTest(false)
+	suite.cleanupTest(false) // teardown the default xload generated
+
+	suite.assert.Equal(EConflictPolicy.NEWEST_WINS(), suite.xload.conflictPolicy)
+
+	testConfig := fmt.Sprintf("xload:\n  path: %s\n  mode: sync\n  conflict-policy: remote-wins\n\nloopbackfs:\n  path: %s", suite.local_path, suite.fake_storage_path)
+	err := suite.setupTestHelper(testConfig, false)
+	suite.assert.NoError(err)
+	suite.assert.Equal(EMode.SYNC(), suite.xload.mode)
+	suite.assert.Equal(EConflictPolicy.REMOTE_WINS(), suite.xload.conflictPolicy)
+
+	testConfig = fmt.Sprintf("xload:\n  path: %s\n  mode: sync\n  conflict-policy: random\n\nloopbackfs:\n  path: %s", suite.local_path, suite.fake_storage_path)
+	err = suite.setupTestHelper(testConfig, false)
+	suite.assert.Error(err)
+	suite.assert.Contains(err.Error(), "invalid conflict-policy")
+}
+
+func (suite *xloadTestSuite) TestCreateUploader() {
+	defer suite.cleanupTest(false)
+	suite.cleanupTest(false) // teardown the default xload generated
+
+	xl := &Xload{}
+	err := xl.createUploader()
+	suite.assert.Error(err)
+	suite.assert.Contains(err.Error(), "invalid parameters sent to create local lister")
+	suite.assert.Empty(xl.comps)
+
+	xl.path = suite.local_path
+	xl.workerCount = 4
+	xl.SetNextComponent(xl)
+	xl.statsMgr = &StatsManager{}
+	err = xl.createUploader()
+	suite.assert.Error(err)
+	suite.assert.Contains(err.Error(), "invalid parameters sent to create upload splitter")
+	suite.assert.Empty(xl.comps)
+
+	xl.blockPool = &BlockPool{}
+	xl.fileLocks = common.NewLockMap()
+	err = xl.createUploader()
+	suite.assert.NoError(err)
+	suite.assert.Len(xl.comps, 3)
+
+	err = xl.createChain()
+	suite.assert.NoError(err)
+	suite.assert.NotNil(xl.comps[0].GetNext())
+	suite.assert.NotNil(xl.comps[1].GetNext())
+	suite.assert.Nil(xl.comps[2].GetNext())
+}
+
+func (suite *xloadTestSuite) TestXloadUploadStartStop() {
+	defer suite.cleanupTest(false)
+	config.ResetConfig()
+
+	err := os.MkdirAll(suite.local_path, 0777)
+	suite.assert.NoError(err)
+	createTestDirsAndFiles(suite.local_path, suite.assert)
+	err = os.MkdirAll(suite.fake_storage_path, 0777)
+	suite.assert.NoError(err)
+
+	blockSize := (float64)(0.00001)
+	testConfig := fmt.Sprintf("xload:\n  path: %s\n  mode: upload\n  block-size-mb: %v\n\nloopbackfs:\n  path: %s", suite.local_path, blockSize, suite.fake_storage_path)
+	err = suite.setupTestHelper(testConfig, true) // setup a new xload with a custom config (teardown will occur after the test as usual)
+	suite.assert.NoError(err)
+	suite.assert.Equal(EMode.UPLOAD(), suite.xload.mode)
+
+	time.Sleep(5 * time.Second)
+
+	validateMD5(suite.fake_storage_path, suite.local_path, suite.assert)
+
+	// file not present in local path is served by the next component
+	err = os.WriteFile(filepath.Join(suite.fake_storage_path, "remote_only"), []byte("remote"), 0777)
+	suite.assert.NoError(err)
+
+	fh, err := suite.xload.OpenFile(internal.OpenFileOptions{Name: "remote_only", Flags: os.O_RDONLY, Mode: common.DefaultFilePermissionBits})
+	suite.assert.NoError(err)
+	suite.assert.NotNil(fh)
+	suite.assert.False(fh.Cached())
+
+	err = suite.xload.ReleaseFile(internal.ReleaseFileOptions{Handle: fh})
+	suite.assert.NoError(err)
+
+	// local data is retained on stop in upload mode
+	err = suite.loopback.Stop()
+	suite.assert.NoError(err)
+	err = suite.xload.Stop()
+	suite.assert.NoError(err)
+	_, err = os.Stat(filepath.Join(suite.local_path, "file_4"))
+	suite.assert.NoError(err)
+}
+
+func (suite *xloadTestSuite) TestXloadSyncStartStop() {
+	defer suite.cleanupTest(false)
+	config.ResetConfig()
+
+	err := os.MkdirAll(suite.local_path, 0777)
+	suite.assert.NoError(err)
+	err = os.MkdirAll(filepath.Join(suite.local_path, "local_dir"), 0777)
+	suite.assert.NoError(err)
+	createTestFiles(filepath.Join(suite.local_path, "local_dir"), suite.assert)
+
+	err = os.MkdirAll(suite.fake_storage_path, 0777)
+	suite.assert.NoError(err)
+	createTestDirsAndFiles(suite.fake_storage_path, suite.assert)
+
+	blockSize := (float64)(0.00001)
+	testConfig := fmt.Sprintf("xload:\n  path: %s\n  mode: sync\n  block-size-mb: %v\n\nloopbackfs:\n  path: %s", suite.local_path, blockSize, suite.fake_storage_path)
+	err = suite.setupTestHelper(testConfig, true) // setup a new xload with a custom config (teardown will occur after the test as usual)
+	suite.assert.NoError(err)
+	suite.assert.Equal(EMode.SYNC(), suite.xload.mode)
+
+	time.Sleep(5 * time.Second)
+
+	err = suite.loopback.Stop()
+	suite.assert.NoError(err)
+	err = suite.xload.Stop()
+	suite.assert.NoError(err)
+
+	validateMD5(suite.local_path, suite.fake_storage_path, suite.assert)
+
+	// sync state is persisted in local path and is not uploaded to the container
+	_, err = os.Stat(filepath.Join(suite.local_path, SYNC_STATE_FILE))
+	suite.assert.NoError(err)
+	_, err = os.Stat(filepath.Join(suite.fake_storage_path, SYNC_STATE_FILE))
+	suite.assert.True(os.IsNotExist(err))
+
+	state, err := newSyncState(suite.local_path)
+	suite.assert.NoError(err)
+	suite.assert.NotNil(state.get("local_dir/file_4"))
+	suite.assert.NotNil(state.get("dir_1/file_4"))
+}
+
 func (suite *xloadTestSuite) validateMD5WithOpenFile(localPath string, remotePath string) {
 	entries, err := os.ReadDir(remotePath)
 	suite.assert.NoError(err)
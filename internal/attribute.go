@@ -81,6 +81,8 @@ type ObjAttr struct {
 	MD5      []byte             // MD5 of the blob as per last GetAttr
 	ETag     string             // ETag of the blob as per last GetAttr
 	Metadata map[string]*string // extra information to preserve
+	Tags     map[string]string  // blob index tags, nil when not retrieved
+	Nlink    uint64             // number of hard links, 0 means storage does not track it
 }
 
 // IsDir : Test blob is a directory or not
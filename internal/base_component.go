@@ -179,6 +179,20 @@ func (base *BaseComponent) ReleaseFile(options ReleaseFileOptions) error {
 	return nil
 }
 
+func (base *BaseComponent) LockFile(options LockFileOptions) error {
+	if base.next != nil {
+		return base.next.LockFile(options)
+	}
+	return nil
+}
+
+func (base *BaseComponent) UnlockFile(options UnlockFileOptions) error {
+	if base.next != nil {
+		return base.next.UnlockFile(options)
+	}
+	return nil
+}
+
 func (base *BaseComponent) RenameFile(options RenameFileOptions) error {
 	if base.next != nil {
 		return base.next.RenameFile(options)
@@ -214,6 +228,28 @@ func (base *BaseComponent) TruncateFile(options TruncateFileOptions) error {
 	return nil
 }
 
+func (base *BaseComponent) Fallocate(options FallocateOptions) error {
+	if base.next != nil {
+		return base.next.Fallocate(options)
+	}
+	return nil
+}
+
+func (base *BaseComponent) Lseek(options LseekOptions) (int64, error) {
+	if base.next != nil {
+		return base.next.Lseek(options)
+	}
+	return 0, nil
+}
+
+func (base *BaseComponent) CopyFileRange(options CopyFileRangeOptions) (int64, error) {
+	if base.next != nil {
+		return base.next.CopyFileRange(options)
+	}
+	// Nothing copied would be read as end of source, so let the caller fall back instead
+	return 0, syscall.ENOTSUP
+}
+
 func (base *BaseComponent) CopyToFile(options CopyToFileOptions) error {
 	if base.next != nil {
 		return base.next.CopyToFile(options)
@@ -271,6 +307,14 @@ func (base *BaseComponent) ReadLink(options ReadLinkOptions) (string, error) {
 	return "", nil
 }
 
+// Hard link operations
+func (base *BaseComponent) CreateHardLink(options CreateHardLinkOptions) error {
+	if base.next != nil {
+		return base.next.CreateHardLink(options)
+	}
+	return nil
+}
+
 // Filesystem level operations
 func (base *BaseComponent) GetAttr(options GetAttrOptions) (*ObjAttr, error) {
 	if base.next != nil {
@@ -300,6 +344,35 @@ func (base *BaseComponent) Chown(options ChownOptions) error {
 	return nil
 }
 
+// Extended attribute operations
+func (base *BaseComponent) GetXattr(options GetXattrOptions) ([]byte, error) {
+	if base.next != nil {
+		return base.next.GetXattr(options)
+	}
+	return nil, nil
+}
+
+func (base *BaseComponent) SetXattr(options SetXattrOptions) error {
+	if base.next != nil {
+		return base.next.SetXattr(options)
+	}
+	return nil
+}
+
+func (base *BaseComponent) ListXattr(options ListXattrOptions) ([]string, error) {
+	if base.next != nil {
+		return base.next.ListXattr(options)
+	}
+	return nil, nil
+}
+
+func (base *BaseComponent) RemoveXattr(options RemoveXattrOptions) error {
+	if base.next != nil {
+		return base.next.RemoveXattr(options)
+	}
+	return nil
+}
+
 func (base *BaseComponent) FileUsed(name string) error {
 	if base.next != nil {
 		return base.next.FileUsed(name)
@@ -65,6 +65,14 @@ func (ComponentPriority) LevelTwo() ComponentPriority {
 	return ComponentPriority(300)
 }
 
+func (ComponentPriority) LevelThree() ComponentPriority {
+	return ComponentPriority(200)
+}
+
+func (ComponentPriority) LevelFour() ComponentPriority {
+	return ComponentPriority(150)
+}
+
 // Component : Base internal for every component to participate in pipeline
 type Component interface {
 	// Pipeline participation related methods
@@ -119,6 +127,13 @@ type Component interface {
 	// handle.
 	ReleaseFile(ReleaseFileOptions) error
 
+	//LockFile: Implementation expectations:
+	//1. takes an exclusive lock on the file for the handle, which is visible to other nodes using the same storage
+	//2. must return EWOULDBLOCK if another node holds the lock, a component without cross node locking returns nil
+	LockFile(LockFileOptions) error
+	// UnlockFile drops the lock taken by LockFile for the handle, the file stays locked while other handles hold it.
+	UnlockFile(UnlockFileOptions) error
+
 	RenameFile(RenameFileOptions) error
 
 	CopyToFile(CopyToFileOptions) error
@@ -131,6 +146,12 @@ type Component interface {
 	CreateLink(CreateLinkOptions) error
 	ReadLink(ReadLinkOptions) (string, error)
 
+	// Hard link operations
+	//CreateHardLink: Implementation expectations:
+	//1. must return ENOENT if target does not exist and EEXIST if name already exists
+	//2. must return EPERM if target is a directory
+	CreateHardLink(CreateHardLinkOptions) error
+
 	// Filesystem level operations
 	//GetAttr: Implementation expectations:
 	//1. must return ErrNotExist for absence of a file/directory/symlink
@@ -142,6 +163,30 @@ type Component interface {
 	Chown(ChownOptions) error
 	TruncateFile(TruncateFileOptions) error
 
+	//Fallocate: Implementation expectations:
+	//1. must return ENOTSUP for modes that can not be honoured
+	//2. punched range reads back as zeros and file size does not change
+	Fallocate(FallocateOptions) error
+
+	//Lseek: Implementation expectations:
+	//1. must return ENXIO if offset is at or beyond the end of file
+	//2. a component that does not track holes reports the whole file as data
+	Lseek(LseekOptions) (int64, error)
+
+	//CopyFileRange: Implementation expectations:
+	//1. must return ENOTSUP if the range can not be copied within storage, caller then falls back to read and write
+	//2. returns bytes copied which is less than requested length only if source ends before the range
+	CopyFileRange(CopyFileRangeOptions) (int64, error)
+
+	// Extended attribute operations
+	//GetXattr, RemoveXattr: Implementation expectations:
+	//1. must return ENODATA if the attribute is not set on the path
+	//2. must return ENOTSUP for attribute namespaces that are not supported
+	GetXattr(GetXattrOptions) ([]byte, error)
+	SetXattr(SetXattrOptions) error
+	ListXattr(ListXattrOptions) ([]string, error)
+	RemoveXattr(RemoveXattrOptions) error
+
 	GetFileBlockOffsets(options GetFileBlockOffsetsOptions) (*common.BlockOffsetList, error)
 
 	FileUsed(name string) error
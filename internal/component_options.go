@@ -106,13 +106,28 @@ type ReadFileOptions struct {
 	Handle *handlemap.Handle
 }
 
+// IOPriority : Priority of a storage request, lower values are served first when workers or rate limits are contended
+type IOPriority int
+
+const (
+	// Request a user is waiting on, the default when no priority is given
+	IOPriorityForeground IOPriority = iota
+	// Listing of a directory
+	IOPriorityListing
+	// Read ahead of what the user has asked for
+	IOPriorityPrefetch
+	// Upload running in background of the user writes
+	IOPriorityUpload
+)
+
 type ReadInBufferOptions struct {
-	Handle *handlemap.Handle
-	Offset int64
-	Etag   *string
-	Data   []byte
-	Path   string
-	Size   int64
+	Handle   *handlemap.Handle
+	Offset   int64
+	Etag     *string
+	Data     []byte
+	Path     string
+	Size     int64
+	Priority IOPriority
 }
 
 type WriteFileOptions struct {
@@ -135,6 +150,54 @@ type TruncateFileOptions struct {
 	BlockSize int64
 }
 
+// Modes accepted in FallocateOptions, these match FALLOC_FL_KEEP_SIZE and FALLOC_FL_PUNCH_HOLE of fallocate(2)
+const (
+	FallocKeepSize  = 0x1
+	FallocPunchHole = 0x2
+)
+
+type FallocateOptions struct {
+	Handle *handlemap.Handle
+	Name   string
+	Mode   uint32
+	Offset int64
+	Length int64
+}
+
+// Whence accepted in LseekOptions, these match SEEK_DATA and SEEK_HOLE of lseek(2)
+const (
+	SeekData = 3
+	SeekHole = 4
+)
+
+type LseekOptions struct {
+	Handle *handlemap.Handle
+	Name   string
+	Offset int64
+	Whence int
+}
+
+type LockFileOptions struct {
+	Handle *handlemap.Handle
+	Name   string
+}
+
+type UnlockFileOptions struct {
+	Handle *handlemap.Handle
+	Name   string
+}
+
+type CopyFileRangeOptions struct {
+	SrcHandle *handlemap.Handle
+	SrcName   string
+	SrcOffset int64
+	DstHandle *handlemap.Handle
+	DstName   string
+	DstOffset int64
+	Length    int64
+	BlockSize int64 // Size each block of destination shall have, 0 if caller does not care about the layout
+}
+
 type CopyToFileOptions struct {
 	Name   string
 	Offset int64
@@ -170,6 +233,11 @@ type CreateLinkOptions struct {
 	Target string
 }
 
+type CreateHardLinkOptions struct {
+	Name   string
+	Target string
+}
+
 type ReadLinkOptions struct {
 	Name string
 	Size int64
@@ -191,11 +259,39 @@ type ChownOptions struct {
 	Group int
 }
 
+// Flags accepted in SetXattrOptions, these match XATTR_CREATE and XATTR_REPLACE of setxattr(2)
+const (
+	XattrCreate  = 0x1
+	XattrReplace = 0x2
+)
+
+type GetXattrOptions struct {
+	Name string
+	Attr string
+}
+
+type SetXattrOptions struct {
+	Name  string
+	Attr  string
+	Value []byte
+	Flags int
+}
+
+type ListXattrOptions struct {
+	Name string
+}
+
+type RemoveXattrOptions struct {
+	Name string
+	Attr string
+}
+
 type StageDataOptions struct {
-	Name   string
-	Id     string
-	Data   []byte
-	Offset uint64
+	Name     string
+	Id       string
+	Data     []byte
+	Offset   uint64
+	Priority IOPriority
 }
 
 type CommitDataOptions struct {
@@ -203,6 +299,7 @@ type CommitDataOptions struct {
 	List      []string
 	BlockSize uint64
 	NewETag   *string
+	Metadata  map[string]*string // Metadata to set on the blob, nil to leave it empty
 }
 
 type CommittedBlock struct {
@@ -55,6 +55,7 @@ const (
 	HandleFlagDirty          // File has been modified with write operation or is a new file
 	HandleFlagFSynced        // User has called fsync on the file explicitly
 	HandleFlagCached         // File is cached in the local system by blobfuse2
+	HandleFlagLocked         // Handle holds the cross node lock of the file from open till release
 )
 
 // Structure to hold in memory cache for streaming layer
@@ -161,6 +161,21 @@ func (mr *MockComponentMockRecorder) CopyFromFile(arg0 interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CopyFromFile", reflect.TypeOf((*MockComponent)(nil).CopyFromFile), arg0)
 }
 
+// CopyFileRange mocks base method.
+func (m *MockComponent) CopyFileRange(arg0 CopyFileRangeOptions) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CopyFileRange", arg0)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CopyFileRange indicates an expected call of CopyFileRange.
+func (mr *MockComponentMockRecorder) CopyFileRange(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CopyFileRange", reflect.TypeOf((*MockComponent)(nil).CopyFileRange), arg0)
+}
+
 // CopyToFile mocks base method.
 func (m *MockComponent) CopyToFile(arg0 CopyToFileOptions) error {
 	m.ctrl.T.Helper()
@@ -204,6 +219,20 @@ func (mr *MockComponentMockRecorder) CreateFile(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateFile", reflect.TypeOf((*MockComponent)(nil).CreateFile), arg0)
 }
 
+// CreateHardLink mocks base method.
+func (m *MockComponent) CreateHardLink(arg0 CreateHardLinkOptions) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateHardLink", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateHardLink indicates an expected call of CreateHardLink.
+func (mr *MockComponentMockRecorder) CreateHardLink(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateHardLink", reflect.TypeOf((*MockComponent)(nil).CreateHardLink), arg0)
+}
+
 // CreateLink mocks base method.
 func (m *MockComponent) CreateLink(arg0 CreateLinkOptions) error {
 	m.ctrl.T.Helper()
@@ -274,6 +303,20 @@ func (mr *MockComponentMockRecorder) SyncFile(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SyncFile", reflect.TypeOf((*MockComponent)(nil).SyncFile), arg0)
 }
 
+// Fallocate mocks base method.
+func (m *MockComponent) Fallocate(arg0 FallocateOptions) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Fallocate", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Fallocate indicates an expected call of Fallocate.
+func (mr *MockComponentMockRecorder) Fallocate(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Fallocate", reflect.TypeOf((*MockComponent)(nil).Fallocate), arg0)
+}
+
 // FlushFile mocks base method.
 func (m *MockComponent) FlushFile(arg0 FlushFileOptions) error {
 	m.ctrl.T.Helper()
@@ -318,6 +361,64 @@ func (mr *MockComponentMockRecorder) GetFileBlockOffsets(arg0 interface{}) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFileBlockOffsets", reflect.TypeOf((*MockComponent)(nil).GetFileBlockOffsets), arg0)
 }
 
+// GetXattr mocks base method.
+func (m *MockComponent) GetXattr(arg0 GetXattrOptions) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetXattr", arg0)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetXattr indicates an expected call of GetXattr.
+func (mr *MockComponentMockRecorder) GetXattr(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetXattr", reflect.TypeOf((*MockComponent)(nil).GetXattr), arg0)
+}
+
+// SetXattr mocks base method.
+func (m *MockComponent) SetXattr(arg0 SetXattrOptions) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetXattr", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetXattr indicates an expected call of SetXattr.
+func (mr *MockComponentMockRecorder) SetXattr(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetXattr", reflect.TypeOf((*MockComponent)(nil).SetXattr), arg0)
+}
+
+// ListXattr mocks base method.
+func (m *MockComponent) ListXattr(arg0 ListXattrOptions) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListXattr", arg0)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListXattr indicates an expected call of ListXattr.
+func (mr *MockComponentMockRecorder) ListXattr(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListXattr", reflect.TypeOf((*MockComponent)(nil).ListXattr), arg0)
+}
+
+// RemoveXattr mocks base method.
+func (m *MockComponent) RemoveXattr(arg0 RemoveXattrOptions) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveXattr", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveXattr indicates an expected call of RemoveXattr.
+func (mr *MockComponentMockRecorder) RemoveXattr(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveXattr", reflect.TypeOf((*MockComponent)(nil).RemoveXattr), arg0)
+}
+
 // IsDirEmpty mocks base method.
 func (m *MockComponent) IsDirEmpty(arg0 IsDirEmptyOptions) bool {
 	m.ctrl.T.Helper()
@@ -332,6 +433,35 @@ func (mr *MockComponentMockRecorder) IsDirEmpty(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsDirEmpty", reflect.TypeOf((*MockComponent)(nil).IsDirEmpty), arg0)
 }
 
+// LockFile mocks base method.
+func (m *MockComponent) LockFile(arg0 LockFileOptions) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LockFile", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LockFile indicates an expected call of LockFile.
+func (mr *MockComponentMockRecorder) LockFile(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockFile", reflect.TypeOf((*MockComponent)(nil).LockFile), arg0)
+}
+
+// Lseek mocks base method.
+func (m *MockComponent) Lseek(arg0 LseekOptions) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Lseek", arg0)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Lseek indicates an expected call of Lseek.
+func (mr *MockComponentMockRecorder) Lseek(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Lseek", reflect.TypeOf((*MockComponent)(nil).Lseek), arg0)
+}
+
 // Name mocks base method.
 func (m *MockComponent) Name() string {
 	m.ctrl.T.Helper()
@@ -601,6 +731,20 @@ func (mr *MockComponentMockRecorder) UnlinkFile(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnlinkFile", reflect.TypeOf((*MockComponent)(nil).UnlinkFile), arg0)
 }
 
+// UnlockFile mocks base method.
+func (m *MockComponent) UnlockFile(arg0 UnlockFileOptions) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnlockFile", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UnlockFile indicates an expected call of UnlockFile.
+func (mr *MockComponentMockRecorder) UnlockFile(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnlockFile", reflect.TypeOf((*MockComponent)(nil).UnlockFile), arg0)
+}
+
 // WriteFile mocks base method.
 func (m *MockComponent) WriteFile(arg0 *WriteFileOptions) (int, error) {
 	m.ctrl.T.Helper()
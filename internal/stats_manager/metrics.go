@@ -0,0 +1,397 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package stats_manager
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Azure/azure-storage-fuse/v2/common/log"
+)
+
+// Metrics of the mount process are kept in memory and served on /metrics when a metrics address is configured.
+// Unlike the stats pushed to health-monitor these do not depend on monitoring being enabled.
+const (
+	metricsPath         = "/metrics"
+	contentTypeText     = "text/plain; version=0.0.4; charset=utf-8"
+	contentTypeOpenMetr = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+// Upper bounds in seconds of the operation latency buckets
+var latencyBuckets = []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+type metricKey struct {
+	component string
+	label     string
+}
+
+type histogram struct {
+	mtx     sync.Mutex
+	buckets []uint64 // count of observations in each bucket, not cumulative
+	count   uint64
+	sum     float64
+}
+
+type metricsRegistry struct {
+	enabled atomic.Bool
+	mtx     sync.RWMutex
+
+	latency    map[metricKey]*histogram    // operation durations
+	bytes      map[metricKey]*atomic.Int64 // bytes by direction
+	cache      map[metricKey]*atomic.Int64 // cache lookups by result
+	stats      map[metricKey]*atomic.Int64 // numeric values pushed through UpdateStats
+	gaugeFuncs map[metricKey]func() int64  // values read at scrape time
+
+	server  *http.Server
+	address string // address the server listens on
+}
+
+var metrics metricsRegistry
+
+// StartMetricsServer : Serve metrics of this process on the given address
+func StartMetricsServer(address string) error {
+	ln, err := net.Listen("tcp", address)
+	if err != nil {
+		log.Err("stats_manager::StartMetricsServer : Failed to listen on %s [%v]", address, err)
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(metricsPath, serveMetrics)
+
+	metrics.mtx.Lock()
+	metrics.server = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	metrics.address = ln.Addr().String()
+	server := metrics.server
+	metrics.mtx.Unlock()
+
+	metrics.enabled.Store(true)
+	log.Info("stats_manager::StartMetricsServer : Serving metrics on http://%s%s", metrics.address, metricsPath)
+
+	go func() {
+		err := server.Serve(ln)
+		if err != nil && err != http.ErrServerClosed {
+			log.Err("stats_manager::StartMetricsServer : Metrics server stopped [%v]", err)
+		}
+	}()
+
+	return nil
+}
+
+// StopMetricsServer : Stop serving metrics, collection stops as well
+func StopMetricsServer() {
+	metrics.enabled.Store(false)
+
+	metrics.mtx.Lock()
+	server := metrics.server
+	metrics.server = nil
+	metrics.mtx.Unlock()
+
+	if server != nil {
+		_ = server.Close()
+	}
+}
+
+// MetricsEnabled : Whether metrics are being collected
+func MetricsEnabled() bool {
+	return metrics.enabled.Load()
+}
+
+// TimeOperation : Count the operation and record its latency, call the returned func once it completes
+//
+//	defer azStatsCollector.TimeOperation("ReadInBuffer")()
+func (sc *StatsCollector) TimeOperation(op string) func() {
+	if sc == nil || !metrics.enabled.Load() {
+		return func() {}
+	}
+
+	start := time.Now()
+	return func() {
+		metrics.getHistogram(metricKey{sc.name, op}).observe(time.Since(start).Seconds())
+	}
+}
+
+// AddBytes : Count bytes moved by the component in the given direction e.g. read, write
+func (sc *StatsCollector) AddBytes(direction string, n int64) {
+	if sc == nil || !metrics.enabled.Load() || n <= 0 {
+		return
+	}
+	getValue(metrics.bytes, metricKey{sc.name, direction}).Add(n)
+}
+
+// CacheLookup : Count a hit or miss of the component's cache
+func (sc *StatsCollector) CacheLookup(hit bool) {
+	if sc == nil || !metrics.enabled.Load() {
+		return
+	}
+
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	getValue(metrics.cache, metricKey{sc.name, result}).Add(1)
+}
+
+// GaugeFunc : Expose a stat of the component whose value is read when metrics are scraped
+func (sc *StatsCollector) GaugeFunc(key string, fn func() int64) {
+	if sc == nil || !metrics.enabled.Load() {
+		return
+	}
+
+	metrics.mtx.Lock()
+	defer metrics.mtx.Unlock()
+	metrics.gaugeFuncs[metricKey{sc.name, key}] = fn
+}
+
+// updateMetric : Mirror a stat update of the component, only integer values are exposed
+func (sc *StatsCollector) updateMetric(op string, key string, val any) {
+	v, ok := val.(int64)
+	if !ok {
+		return
+	}
+
+	stat := getValue(metrics.stats, metricKey{sc.name, key})
+	switch op {
+	case Increment:
+		stat.Add(v)
+	case Decrement:
+		stat.Add(-v)
+	case Replace:
+		stat.Store(v)
+	}
+}
+
+// dropGaugeFuncs : Forget scrape time stats of the component as what they read may be gone
+func (sc *StatsCollector) dropGaugeFuncs() {
+	if sc == nil {
+		return
+	}
+
+	metrics.mtx.Lock()
+	defer metrics.mtx.Unlock()
+
+	for key := range metrics.gaugeFuncs {
+		if key.component == sc.name {
+			delete(metrics.gaugeFuncs, key)
+		}
+	}
+}
+
+func getValue(m map[metricKey]*atomic.Int64, key metricKey) *atomic.Int64 {
+	metrics.mtx.RLock()
+	v, ok := m[key]
+	metrics.mtx.RUnlock()
+	if ok {
+		return v
+	}
+
+	metrics.mtx.Lock()
+	defer metrics.mtx.Unlock()
+	if v, ok = m[key]; !ok {
+		v = &atomic.Int64{}
+		m[key] = v
+	}
+	return v
+}
+
+func (mr *metricsRegistry) getHistogram(key metricKey) *histogram {
+	mr.mtx.RLock()
+	h, ok := mr.latency[key]
+	mr.mtx.RUnlock()
+	if ok {
+		return h
+	}
+
+	mr.mtx.Lock()
+	defer mr.mtx.Unlock()
+	if h, ok = mr.latency[key]; !ok {
+		h = &histogram{buckets: make([]uint64, len(latencyBuckets))}
+		mr.latency[key] = h
+	}
+	return h
+}
+
+func (h *histogram) observe(v float64) {
+	idx := sort.SearchFloat64s(latencyBuckets, v)
+
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	if idx < len(h.buckets) {
+		h.buckets[idx]++
+	}
+	h.count++
+	h.sum += v
+}
+
+// serveMetrics : Write all metrics in Prometheus text format, or OpenMetrics if the scraper asks for it
+func serveMetrics(w http.ResponseWriter, r *http.Request) {
+	openMetrics := strings.Contains(r.Header.Get("Accept"), "application/openmetrics-text")
+	if openMetrics {
+		w.Header().Set("Content-Type", contentTypeOpenMetr)
+	} else {
+		w.Header().Set("Content-Type", contentTypeText)
+	}
+
+	err := metrics.write(w, openMetrics)
+	if err != nil {
+		log.Err("stats_manager::serveMetrics : Failed to write metrics [%v]", err)
+	}
+}
+
+func (mr *metricsRegistry) write(w io.Writer, openMetrics bool) error {
+	mr.mtx.RLock()
+	defer mr.mtx.RUnlock()
+
+	var sb strings.Builder
+
+	// Operation counts are the same as the count of their latency histogram
+	writeHeader(&sb, "blobfuse2_operations", "counter", "Operations served by a component.", openMetrics)
+	for _, key := range sortedKeys(mr.latency) {
+		h := mr.latency[key]
+		h.mtx.Lock()
+		count := h.count
+		h.mtx.Unlock()
+		fmt.Fprintf(&sb, "blobfuse2_operations_total{component=%s,operation=%s} %d\n", quote(key.component), quote(key.label), count)
+	}
+
+	writeHeader(&sb, "blobfuse2_operation_duration_seconds", "histogram", "Latency of operations served by a component.", openMetrics)
+	for _, key := range sortedKeys(mr.latency) {
+		writeHistogram(&sb, key, mr.latency[key])
+	}
+
+	writeHeader(&sb, "blobfuse2_bytes", "counter", "Bytes read or written by a component.", openMetrics)
+	for _, key := range sortedKeys(mr.bytes) {
+		fmt.Fprintf(&sb, "blobfuse2_bytes_total{component=%s,direction=%s} %d\n", quote(key.component), quote(key.label), mr.bytes[key].Load())
+	}
+
+	writeHeader(&sb, "blobfuse2_cache_lookups", "counter", "Cache lookups of a component by result.", openMetrics)
+	for _, key := range sortedKeys(mr.cache) {
+		fmt.Fprintf(&sb, "blobfuse2_cache_lookups_total{component=%s,result=%s} %d\n", quote(key.component), quote(key.label), mr.cache[key].Load())
+	}
+
+	writeHeader(&sb, "blobfuse2_component_stat", "gauge", "Stats reported by a component.", openMetrics)
+	values := make(map[metricKey]int64, len(mr.stats)+len(mr.gaugeFuncs))
+	for key, v := range mr.stats {
+		values[key] = v.Load()
+	}
+	for key, fn := range mr.gaugeFuncs {
+		values[key] = fn()
+	}
+	for _, key := range sortedKeys(values) {
+		fmt.Fprintf(&sb, "blobfuse2_component_stat{component=%s,stat=%s} %d\n", quote(key.component), quote(key.label), values[key])
+	}
+
+	if openMetrics {
+		sb.WriteString("# EOF\n")
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// writeHeader : In OpenMetrics a counter family is named without its _total suffix
+func writeHeader(sb *strings.Builder, family string, metricType string, help string, openMetrics bool) {
+	if metricType == "counter" && !openMetrics {
+		family += "_total"
+	}
+	fmt.Fprintf(sb, "# HELP %s %s\n# TYPE %s %s\n", family, help, family, metricType)
+}
+
+func writeHistogram(sb *strings.Builder, key metricKey, h *histogram) {
+	h.mtx.Lock()
+	buckets := append([]uint64(nil), h.buckets...)
+	count, sum := h.count, h.sum
+	h.mtx.Unlock()
+
+	labels := fmt.Sprintf("component=%s,operation=%s", quote(key.component), quote(key.label))
+
+	cumulative := uint64(0)
+	for i, bound := range latencyBuckets {
+		cumulative += buckets[i]
+		fmt.Fprintf(sb, "blobfuse2_operation_duration_seconds_bucket{%s,le=\"%s\"} %d\n", labels, formatFloat(bound), cumulative)
+	}
+	fmt.Fprintf(sb, "blobfuse2_operation_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, count)
+	fmt.Fprintf(sb, "blobfuse2_operation_duration_seconds_sum{%s} %s\n", labels, formatFloat(sum))
+	fmt.Fprintf(sb, "blobfuse2_operation_duration_seconds_count{%s} %d\n", labels, count)
+}
+
+func sortedKeys[V any](m map[metricKey]V) []metricKey {
+	keys := make([]metricKey, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].component != keys[j].component {
+			return keys[i].component < keys[j].component
+		}
+		return keys[i].label < keys[j].label
+	})
+	return keys
+}
+
+// quote : Label value with backslash, double quote and line feed escaped
+func quote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func init() {
+	metrics.latency = make(map[metricKey]*histogram)
+	metrics.bytes = make(map[metricKey]*atomic.Int64)
+	metrics.cache = make(map[metricKey]*atomic.Int64)
+	metrics.stats = make(map[metricKey]*atomic.Int64)
+	metrics.gaugeFuncs = make(map[metricKey]func() int64)
+}
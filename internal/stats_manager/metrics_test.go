@@ -0,0 +1,145 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package stats_manager
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type metricsTestSuite struct {
+	suite.Suite
+	assert *assert.Assertions
+}
+
+func (suite *metricsTestSuite) SetupTest() {
+	suite.assert = assert.New(suite.T())
+}
+
+func (suite *metricsTestSuite) TearDownTest() {
+	StopMetricsServer()
+}
+
+func (suite *metricsTestSuite) scrape(address string, accept string) (string, string) {
+	req, err := http.NewRequest(http.MethodGet, "http://"+address+metricsPath, nil)
+	suite.assert.NoError(err)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	suite.assert.NoError(err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	suite.assert.NoError(err)
+	return string(body), resp.Header.Get("Content-Type")
+}
+
+func (suite *metricsTestSuite) TestDisabled() {
+	sc := &StatsCollector{name: "disabled"}
+	suite.assert.False(MetricsEnabled())
+
+	sc.TimeOperation("ReadInBuffer")()
+	sc.AddBytes(BytesRead, 10)
+	sc.CacheLookup(true)
+	sc.UpdateStats(Increment, "OpenFileHandles", int64(1))
+
+	var sb strings.Builder
+	suite.assert.NoError(metrics.write(&sb, false))
+	suite.assert.NotContains(sb.String(), `component="disabled"`)
+}
+
+func (suite *metricsTestSuite) TestServeMetrics() {
+	err := StartMetricsServer("127.0.0.1:0")
+	suite.assert.NoError(err)
+	suite.assert.True(MetricsEnabled())
+
+	sc := &StatsCollector{name: "test_comp"}
+
+	done := sc.TimeOperation("ReadInBuffer")
+	time.Sleep(2 * time.Millisecond)
+	done()
+	sc.AddBytes(BytesRead, 4096)
+	sc.AddBytes(BytesWritten, 0)
+	sc.CacheLookup(true)
+	sc.CacheLookup(false)
+	sc.CacheLookup(false)
+	sc.UpdateStats(Increment, "OpenFileHandles", int64(2))
+	sc.UpdateStats(Decrement, "OpenFileHandles", int64(1))
+	sc.UpdateStats(Replace, "Cache Usage", "10 MB")
+	sc.GaugeFunc("Pool \"Usage\"", func() int64 { return 42 })
+
+	body, contentType := suite.scrape(metrics.address, "")
+	suite.assert.Equal(contentTypeText, contentType)
+	suite.assert.Contains(body, "# TYPE blobfuse2_operations_total counter")
+	suite.assert.Contains(body, `blobfuse2_operations_total{component="test_comp",operation="ReadInBuffer"} 1`)
+	suite.assert.Contains(body, `blobfuse2_operation_duration_seconds_bucket{component="test_comp",operation="ReadInBuffer",le="0.001"} 0`)
+	suite.assert.Contains(body, `blobfuse2_operation_duration_seconds_bucket{component="test_comp",operation="ReadInBuffer",le="+Inf"} 1`)
+	suite.assert.Contains(body, `blobfuse2_operation_duration_seconds_count{component="test_comp",operation="ReadInBuffer"} 1`)
+	suite.assert.Contains(body, `blobfuse2_bytes_total{component="test_comp",direction="read"} 4096`)
+	suite.assert.NotContains(body, `direction="write"`)
+	suite.assert.Contains(body, `blobfuse2_cache_lookups_total{component="test_comp",result="hit"} 1`)
+	suite.assert.Contains(body, `blobfuse2_cache_lookups_total{component="test_comp",result="miss"} 2`)
+	suite.assert.Contains(body, `blobfuse2_component_stat{component="test_comp",stat="OpenFileHandles"} 1`)
+	suite.assert.Contains(body, `blobfuse2_component_stat{component="test_comp",stat="Pool \"Usage\""} 42`)
+	suite.assert.NotContains(body, "Cache Usage")
+	suite.assert.NotContains(body, "# EOF")
+
+	body, contentType = suite.scrape(metrics.address, "application/openmetrics-text; version=1.0.0")
+	suite.assert.Equal(contentTypeOpenMetr, contentType)
+	suite.assert.Contains(body, "# TYPE blobfuse2_operations counter")
+	suite.assert.True(strings.HasSuffix(body, "# EOF\n"))
+
+	// Scrape time stats go away with the collector
+	sc.Destroy()
+	body, _ = suite.scrape(metrics.address, "")
+	suite.assert.NotContains(body, "Pool")
+}
+
+func (suite *metricsTestSuite) TestInvalidAddress() {
+	err := StartMetricsServer("invalid:address:port")
+	suite.assert.Error(err)
+	suite.assert.False(MetricsEnabled())
+}
+
+func TestMetrics(t *testing.T) {
+	suite.Run(t, new(metricsTestSuite))
+}
@@ -38,4 +38,8 @@ const (
 	Increment = "increment"
 	Decrement = "decrement"
 	Replace   = "replace"
+
+	// Directions of bytes counted in metrics
+	BytesRead    = "read"
+	BytesWritten = "write"
 )
@@ -52,6 +52,7 @@ type StatsCollector struct {
 	channel    chan ChannelMsg
 	workerDone sync.WaitGroup
 	compIdx    int
+	name       string
 }
 
 type PipeMsg struct {
@@ -95,7 +96,7 @@ type statsManagerOpt struct {
 var stMgrOpt statsManagerOpt
 
 func NewStatsCollector(componentName string) *StatsCollector {
-	sc := &StatsCollector{}
+	sc := &StatsCollector{name: componentName}
 
 	if common.MonitorBfs() {
 		sc.channel = make(chan ChannelMsg, 10000)
@@ -135,6 +136,8 @@ func (sc *StatsCollector) Init() {
 }
 
 func (sc *StatsCollector) Destroy() {
+	sc.dropGaugeFuncs()
+
 	if common.MonitorBfs() {
 		close(sc.channel)
 		sc.workerDone.Wait()
@@ -168,6 +171,10 @@ func (sc *StatsCollector) PushEvents(op string, path string, mp map[string]any)
 }
 
 func (sc *StatsCollector) UpdateStats(op string, key string, val any) {
+	if MetricsEnabled() {
+		sc.updateMetric(op, key, val)
+	}
+
 	if common.MonitorBfs() {
 		st := Stats{
 			Timestamp: time.Now().Format(time.RFC3339),